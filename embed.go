@@ -0,0 +1,20 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// 内嵌引擎自带的着色器, 字体, 地面模型, 错误贴图和默认场景, 没有 resource 目录时也可以运行
+//
+//go:embed resource/shader resource/font resource/model/ground resource/texture resource/default
+var embedded embed.FS
+
+// defaultResource 内嵌资源, 路径相对于 resource 目录
+func defaultResource() fs.FS {
+	sub, err := fs.Sub(embedded, "resource")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+)
+
+const (
+	defaultMinDistance = 1
+	defaultMaxVoices   = 8
+)
+
+// Range 随机取值的范围
+type Range struct {
+	Min float32
+	Max float32
+}
+
+func newRange(xmlRange config.XmlRange, value float32) Range {
+	if xmlRange.Min == 0 && xmlRange.Max == 0 {
+		return Range{Min: value, Max: value}
+	}
+	if xmlRange.Max < xmlRange.Min {
+		return Range{Min: xmlRange.Min, Max: xmlRange.Min}
+	}
+	return Range{Min: xmlRange.Min, Max: xmlRange.Max}
+}
+
+func (r Range) random(rnd *rand.Rand) float32 {
+	return r.Min + (r.Max-r.Min)*rnd.Float32()
+}
+
+// Event 命名的音频事件
+type Event struct {
+	Name   string
+	Clips  []*Clip
+	Volume Range
+	Pitch  Range
+	// 小于 MinDistance 时音量不衰减, 超过 MaxDistance 听不到, MaxDistance 为 0 表示二维声音
+	MinDistance float32
+	MaxDistance float32
+	Loop        bool
+	MaxVoices   int
+}
+
+// Spatial 是否按位置衰减和声像
+func (e *Event) Spatial() bool {
+	return e.MaxDistance > 0
+}
+
+// Bank 音频事件库, 多个事件引用同一文件时共享片段
+type Bank struct {
+	events map[string]*Event
+}
+
+// LoadBank 读取事件库文件并解码所有片段, 加载失败的片段和没有片段的事件只记录错误
+func LoadBank(file string) (*Bank, error) {
+	xmlBank, err := config.LoadAudioBank(file)
+	if err != nil {
+		return nil, err
+	}
+
+	bank := &Bank{events: make(map[string]*Event)}
+	clips := make(map[string]*Clip)
+	dir := filepath.Dir(file)
+	for _, xmlEvent := range xmlBank.Events {
+		event := &Event{
+			Name:        xmlEvent.Name,
+			Volume:      newRange(xmlEvent.Volume, 1),
+			Pitch:       newRange(xmlEvent.Pitch, 1),
+			MinDistance: xmlEvent.MinDistance,
+			MaxDistance: xmlEvent.MaxDistance,
+			Loop:        xmlEvent.Loop,
+			MaxVoices:   xmlEvent.MaxVoices,
+		}
+		if event.MinDistance <= 0 {
+			event.MinDistance = defaultMinDistance
+		}
+		if event.MaxVoices <= 0 {
+			event.MaxVoices = defaultMaxVoices
+		}
+		for _, name := range xmlEvent.Clips {
+			path := filepath.Join(dir, name)
+			clip, ok := clips[path]
+			if !ok {
+				if clip, err = LoadWAV(path); err != nil {
+					logger.Error(err)
+					continue
+				}
+				clips[path] = clip
+			}
+			event.Clips = append(event.Clips, clip)
+		}
+		if len(event.Clips) == 0 {
+			logger.Error(fmt.Errorf("audio event %s has no clips", event.Name))
+			continue
+		}
+		bank.events[event.Name] = event
+	}
+	return bank, nil
+}
+
+func (b *Bank) Event(name string) (*Event, bool) {
+	if b == nil {
+		return nil, false
+	}
+	event, ok := b.events[name]
+	return event, ok
+}
+
+// Events 按名称排序的所有事件名
+func (b *Bank) Events() []string {
+	if b == nil {
+		return nil
+	}
+	names := make([]string, 0, len(b.events))
+	for name := range b.events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
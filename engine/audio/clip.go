@@ -0,0 +1,125 @@
+// Package audio 音频事件系统
+// 事件在事件库文件中定义, 游戏代码按名称播放, 由系统选择片段, 随机音量音调, 按距离衰减和剔除
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// Clip 解码后的音频片段, 采样按声道交错存储, 取值 [-1, 1]
+type Clip struct {
+	Name       string
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// Frames 每个声道的采样数
+func (c *Clip) Frames() int {
+	if c.Channels == 0 {
+		return 0
+	}
+	return len(c.Samples) / c.Channels
+}
+
+// Duration 时长(秒)
+func (c *Clip) Duration() float32 {
+	if c.SampleRate == 0 {
+		return 0
+	}
+	return float32(c.Frames()) / float32(c.SampleRate)
+}
+
+// LoadWAV 读取 PCM (8/16/24/32 位整数) 或 32 位浮点的 WAV 文件
+func LoadWAV(file string) (*Clip, error) {
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	clip, err := decodeWAV(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	clip.Name = file
+	return clip, nil
+}
+
+const (
+	wavFormatPCM   = 1
+	wavFormatFloat = 3
+)
+
+func decodeWAV(data []byte) (*Clip, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a wav file")
+	}
+
+	var format, channels, bits int
+	var sampleRate int
+	var samples []byte
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8 : min(pos+8+size, len(data))]
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, fmt.Errorf("invalid fmt chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:2]))
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			// WAVE_FORMAT_EXTENSIBLE 的实际格式在子格式 GUID 的前两个字节
+			if format == 0xfffe && len(body) >= 26 {
+				format = int(binary.LittleEndian.Uint16(body[24:26]))
+			}
+		case "data":
+			samples = body
+		}
+		// 块按偶数字节对齐
+		pos += 8 + size + size&1
+	}
+
+	if channels == 0 || sampleRate == 0 {
+		return nil, fmt.Errorf("missing fmt chunk")
+	}
+	if format != wavFormatPCM && !(format == wavFormatFloat && bits == 32) {
+		return nil, fmt.Errorf("unsupported wav format %d (%d bits)", format, bits)
+	}
+	bytesPerSample := bits / 8
+	if bytesPerSample < 1 || bytesPerSample > 4 {
+		return nil, fmt.Errorf("unsupported sample size %d bits", bits)
+	}
+
+	count := len(samples) / bytesPerSample
+	count -= count % channels
+	clip := &Clip{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Samples:    make([]float32, count),
+	}
+	for i := 0; i < count; i++ {
+		b := samples[i*bytesPerSample:]
+		var v float32
+		switch {
+		case format == wavFormatFloat:
+			v = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case bytesPerSample == 1:
+			// 8 位为无符号
+			v = (float32(b[0]) - 128) / 128
+		case bytesPerSample == 2:
+			v = float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		case bytesPerSample == 3:
+			v = float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+		default:
+			v = float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+		}
+		clip.Samples[i] = v
+	}
+	return clip, nil
+}
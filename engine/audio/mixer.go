@@ -0,0 +1,227 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Voice 正在播放的事件实例
+type Voice struct {
+	Event    *Event
+	Position mgl32.Vec3
+
+	clip   *Clip
+	volume float32
+	pitch  float32
+	// 片段中的播放位置(帧), 按音调和采样率推进
+	cursor  float64
+	stopped bool
+	// 超出可听范围, 只推进播放位置, 不参与混音
+	culled bool
+}
+
+func (v *Voice) Stop() {
+	v.stopped = true
+}
+
+func (v *Voice) Playing() bool {
+	return v != nil && !v.stopped
+}
+
+// Culled 是否因超出可听范围而静音
+func (v *Voice) Culled() bool {
+	return v.culled
+}
+
+// Listener 听者, 通常跟随相机
+type Listener struct {
+	Position mgl32.Vec3
+	// 右方向, 用于左右声像
+	Right mgl32.Vec3
+}
+
+// Mixer 把所有声音混合为交错的立体声浮点采样
+type Mixer struct {
+	SampleRate int
+	Volume     float32
+	Listener   Listener
+
+	voices []*Voice
+}
+
+func NewMixer(sampleRate int) *Mixer {
+	return &Mixer{
+		SampleRate: sampleRate,
+		Volume:     1,
+		Listener:   Listener{Right: mgl32.Vec3{1, 0, 0}},
+		voices:     make([]*Voice, 0),
+	}
+}
+
+// Add 添加声音, 同一事件超过最大数量时停止最早的
+func (m *Mixer) Add(voice *Voice) {
+	count := 0
+	for _, v := range m.voices {
+		if v.Event == voice.Event && !v.stopped {
+			count++
+		}
+	}
+	for _, v := range m.voices {
+		if count < voice.Event.MaxVoices {
+			break
+		}
+		if v.Event == voice.Event && !v.stopped {
+			v.stopped = true
+			count--
+		}
+	}
+	m.voices = append(m.voices, voice)
+}
+
+// Audible 位置是否在事件的可听范围内
+func (m *Mixer) Audible(event *Event, position mgl32.Vec3) bool {
+	return !event.Spatial() || position.Sub(m.Listener.Position).Len() < event.MaxDistance
+}
+
+// gains 声音的左右声道增益, 二维声音不衰减也不做声像
+func (m *Mixer) gains(v *Voice) (float32, float32, bool) {
+	gain := v.volume * m.Volume
+	if !v.Event.Spatial() {
+		return gain, gain, true
+	}
+
+	offset := v.Position.Sub(m.Listener.Position)
+	distance := offset.Len()
+	if distance >= v.Event.MaxDistance {
+		return 0, 0, false
+	}
+	// 在 MinDistance 和 MaxDistance 之间线性衰减
+	if distance > v.Event.MinDistance {
+		gain *= (v.Event.MaxDistance - distance) / max(v.Event.MaxDistance-v.Event.MinDistance, 0.0001)
+	}
+
+	// 等功率声像
+	var pan float32
+	if distance > 0.0001 {
+		pan = mgl32.Clamp(offset.Mul(1/distance).Dot(m.Listener.Right), -1, 1)
+	}
+	angle := float64(pan+1) * math.Pi / 4
+	return gain * float32(math.Cos(angle)), gain * float32(math.Sin(angle)), true
+}
+
+// Mix 把所有声音混合到 out (交错的立体声), 移除已结束的声音
+func (m *Mixer) Mix(out []float32) {
+	for i := range out {
+		out[i] = 0
+	}
+	frames := len(out) / 2
+
+	active := m.voices[:0]
+	for _, v := range m.voices {
+		if v.stopped {
+			continue
+		}
+		left, right, audible := m.gains(v)
+		v.culled = !audible
+		if audible {
+			m.mixVoice(v, out, left, right)
+		} else {
+			m.advance(v, frames)
+		}
+		if !v.stopped {
+			active = append(active, v)
+		}
+	}
+	for i := len(active); i < len(m.voices); i++ {
+		m.voices[i] = nil
+	}
+	m.voices = active
+}
+
+// step 每个输出帧在片段中前进的帧数
+func (m *Mixer) step(v *Voice) float64 {
+	return float64(v.clip.SampleRate) / float64(m.SampleRate) * float64(v.pitch)
+}
+
+// advance 不混音, 只推进播放位置, 使被剔除的声音回到范围内时位置正确
+func (m *Mixer) advance(v *Voice, frames int) {
+	v.cursor += m.step(v) * float64(frames)
+	length := float64(v.clip.Frames())
+	if v.cursor < length {
+		return
+	}
+	if v.Event.Loop && length > 0 {
+		v.cursor = math.Mod(v.cursor, length)
+		return
+	}
+	v.stopped = true
+}
+
+// mixVoice 线性插值重采样, 单声道按声像分配, 多声道取前两个声道; 空间声音先混为单声道再声像
+func (m *Mixer) mixVoice(v *Voice, out []float32, left, right float32) {
+	clip := v.clip
+	length := clip.Frames()
+	if length == 0 {
+		v.stopped = true
+		return
+	}
+	channels := clip.Channels
+	step := m.step(v)
+	sample := func(frame, channel int) float32 {
+		if frame >= length {
+			if !v.Event.Loop {
+				return 0
+			}
+			frame %= length
+		}
+		return clip.Samples[frame*channels+channel]
+	}
+
+	for i := 0; i < len(out)/2; i++ {
+		if v.cursor >= float64(length) {
+			if !v.Event.Loop {
+				v.stopped = true
+				return
+			}
+			v.cursor = math.Mod(v.cursor, float64(length))
+		}
+		frame := int(v.cursor)
+		t := float32(v.cursor - float64(frame))
+
+		var l, r float32
+		if channels == 1 || v.Event.Spatial() {
+			var mono float32
+			for c := 0; c < channels; c++ {
+				mono += sample(frame, c) + (sample(frame+1, c)-sample(frame, c))*t
+			}
+			mono /= float32(channels)
+			l, r = mono, mono
+		} else {
+			l = sample(frame, 0) + (sample(frame+1, 0)-sample(frame, 0))*t
+			r = sample(frame, 1) + (sample(frame+1, 1)-sample(frame, 1))*t
+		}
+		out[i*2] += l * left
+		out[i*2+1] += r * right
+		v.cursor += step
+	}
+}
+
+// Voices 正在播放和被剔除的声音数
+func (m *Mixer) Voices() (playing, culled int) {
+	for _, v := range m.voices {
+		if v.culled {
+			culled++
+		} else {
+			playing++
+		}
+	}
+	return playing, culled
+}
+
+// StopAll 停止所有声音
+func (m *Mixer) StopAll() {
+	for _, v := range m.voices {
+		v.stopped = true
+	}
+}
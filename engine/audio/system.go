@@ -0,0 +1,156 @@
+package audio
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	sampleRate = 44100
+	// 音频队列保持的时长(秒), 每帧补足, 过小会断音, 过大会增加延迟
+	queueLatency = 0.1
+)
+
+// System 音频系统, 每帧由 Update 混音并提交到 SDL 音频队列
+// 打开音频设备失败时仍然可以播放事件, 只是没有声音输出
+type System struct {
+	Bank  *Bank
+	mixer *Mixer
+
+	device sdl.AudioDeviceID
+	buffer []float32
+	rnd    *rand.Rand
+}
+
+func NewSystem() *System {
+	s := &System{
+		mixer: NewMixer(sampleRate),
+		rnd:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if err := s.openDevice(); err != nil {
+		logger.Error(err)
+	}
+	return s
+}
+
+func (s *System) openDevice() error {
+	if err := sdl.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		return err
+	}
+	desired := sdl.AudioSpec{
+		Freq:     sampleRate,
+		Format:   sdl.AUDIO_F32SYS,
+		Channels: 2,
+		Samples:  1024,
+	}
+	var obtained sdl.AudioSpec
+	device, err := sdl.OpenAudioDevice("", false, &desired, &obtained, 0)
+	if err != nil {
+		return fmt.Errorf("open audio device: %w", err)
+	}
+	s.device = device
+	sdl.PauseAudioDevice(s.device, false)
+	return nil
+}
+
+// LoadBank 读取事件库, 替换当前的事件库并停止所有声音
+func (s *System) LoadBank(file string) error {
+	bank, err := LoadBank(file)
+	if err != nil {
+		return err
+	}
+	s.mixer.StopAll()
+	s.Bank = bank
+	return nil
+}
+
+// SetListener 设置听者位置和朝向
+func (s *System) SetListener(position, front, up mgl32.Vec3) {
+	s.mixer.Listener.Position = position
+	if right := front.Cross(up); right.Len() > 0 {
+		s.mixer.Listener.Right = right.Normalize()
+	}
+}
+
+// Play 在 position 处播放事件, 非循环的空间声音在可听范围外时直接丢弃, 返回 nil
+func (s *System) Play(name string, position mgl32.Vec3) *Voice {
+	event, ok := s.Bank.Event(name)
+	if !ok {
+		logger.Warn(fmt.Sprintf("audio event %s not found", name))
+		return nil
+	}
+	if !event.Loop && !s.mixer.Audible(event, position) {
+		return nil
+	}
+	voice := &Voice{
+		Event:    event,
+		Position: position,
+		clip:     event.Clips[s.rnd.Intn(len(event.Clips))],
+		volume:   event.Volume.random(s.rnd),
+		pitch:    max(event.Pitch.random(s.rnd), 0.01),
+	}
+	s.mixer.Add(voice)
+	return voice
+}
+
+// Play2D 播放不随位置变化的事件, 用于界面音效和音乐
+func (s *System) Play2D(name string) *Voice {
+	return s.Play(name, s.mixer.Listener.Position)
+}
+
+func (s *System) StopAll() {
+	s.mixer.StopAll()
+}
+
+// SetVolume 主音量
+func (s *System) SetVolume(volume float32) {
+	s.mixer.Volume = max(volume, 0)
+}
+
+func (s *System) Volume() float32 {
+	return s.mixer.Volume
+}
+
+// Voices 正在播放和被距离剔除的声音数
+func (s *System) Voices() (playing, culled int) {
+	return s.mixer.Voices()
+}
+
+// Update 混音并补足音频队列, 没有设备时按 elapsed 推进所有声音
+func (s *System) Update(elapsed float64) {
+	frames := int(elapsed * sampleRate)
+	if s.device != 0 {
+		queued := int(sdl.GetQueuedAudioSize(s.device)) / (2 * 4)
+		frames = max(int(queueLatency*sampleRate)-queued, 0)
+	}
+	if frames == 0 {
+		return
+	}
+	if cap(s.buffer) < frames*2 {
+		s.buffer = make([]float32, frames*2)
+	}
+	s.buffer = s.buffer[:frames*2]
+	s.mixer.Mix(s.buffer)
+
+	if s.device == 0 {
+		return
+	}
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&s.buffer[0])), len(s.buffer)*4)
+	if err := sdl.QueueAudio(s.device, data); err != nil {
+		logger.Error(err)
+	}
+}
+
+func (s *System) Dispose() {
+	if s.device != 0 {
+		sdl.CloseAudioDevice(s.device)
+		s.device = 0
+	}
+	sdl.QuitSubSystem(sdl.INIT_AUDIO)
+}
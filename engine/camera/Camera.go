@@ -2,6 +2,7 @@ package camera
 
 import (
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
 )
 
 const (
@@ -20,7 +21,22 @@ const (
 	ZOOM                = 45.0
 )
 
+// Mode 相机的鼠标键盘控制方式
+type Mode int
+
+const (
+	// ModeOrbit 绕目标旋转, 平移和缩放
+	ModeOrbit Mode = iota
+	// ModeFly WASD 移动, 鼠标转动视角
+	ModeFly
+)
+
+var ModeNames = []string{"Orbit", "Fly"}
+
 type Camera struct {
+	// 场景中相机的名称, 用于切换相机
+	Name string
+
 	// camera attributes
 	Position mgl32.Vec3
 	Target   mgl32.Vec3
@@ -33,23 +49,92 @@ type Camera struct {
 	// camera options
 	MovementSpeed    float32
 	MouseSensitivity float32
-	Zoom             float32
+
+	// 透视投影的垂直视角(度), 近远裁剪面和宽高比, 通过 SetFov, SetClip, SetAspect 修改
+	fov    float32
+	near   float32
+	far    float32
+	aspect float32
+	// 正交投影及其视口高度的一半(世界单位)
+	Orthographic bool
+	OrthoSize    float32
+
+	// 清屏和后处理设置
+	Settings Settings
+	// 相机震动, 叠加在观察矩阵上
+	Shake *Shake
+	// 当前的控制方式及其参数
+	Mode  Mode
+	Orbit *Orbit
+	Fly   *Fly
+	// 加速和减速修饰键的倍率
+	Modifier SpeedModifier
+	// 跟随对象, 设置目标时代替鼠标键盘控制
+	Follow *Follow
+	// 飞行路径, 播放时代替跟随和鼠标键盘控制
+	Path *CameraPath
+	// 缩放到范围和切换相机时平滑过渡的时间, 秒, 不大于 0 时立即跳转
+	TransitionTime float32
+
+	frameRequest FrameTarget
+	transition   *transition
 }
 
 func (c *Camera) Init(position mgl32.Vec3, target mgl32.Vec3) {
 	c.Position = position
 	c.Target = target
 
-	c.Front = c.Target.Sub(c.Position).Normalize()
-	c.Up = mgl32.Vec3{0.0, 1.0, 0.0}
 	c.WorldUp = mgl32.Vec3{0.0, 1.0, 0.0}
+	c.updateVectors()
 	c.MovementSpeed = SPEED
 
-	c.Zoom = ZOOM
+	c.fov = config.Config.FieldOfView
+	c.near, c.far = config.Config.ClipNear, config.Config.ClipFar
+	c.aspect = float32(config.Config.WindowWidth) / float32(config.Config.WindowHeight)
+	c.OrthoSize = max(c.Target.Sub(c.Position).Len()*c.halfFovTan(), minOrthoSize)
+	c.Shake = NewShake()
+	c.Orbit = NewOrbit()
+	c.Fly = NewFly(nil)
+	c.Modifier = NewSpeedModifier()
+	c.Follow = NewFollow(nil)
+	c.Path = NewCameraPath(nil)
+	c.TransitionTime = DefaultTransitionTime
+}
+
+// ToggleMode 在环绕和飞行之间切换
+func (c *Camera) ToggleMode() {
+	c.Fly.Stop()
+	c.Orbit.Stop()
+	c.Mode = (c.Mode + 1) % Mode(len(ModeNames))
+}
+
+// updateVectors 根据位置和目标重新计算相机的前, 右, 上方向
+func (c *Camera) updateVectors() {
+	c.Front = c.Target.Sub(c.Position).Normalize()
+	right := c.Front.Cross(c.WorldUp)
+	if right.Len() < 1e-6 {
+		// 正对上方或下方时保留原来的右方向
+		right = c.Right
+	}
+	c.Right = right.Normalize()
+	c.Up = c.Right.Cross(c.Front).Normalize()
 }
 
+// GetViewMatrix 观察矩阵, 包含当前的相机震动
 func (c *Camera) GetViewMatrix() mgl32.Mat4 {
-	return mgl32.LookAtV(c.Position, c.Target, c.Up)
+	view := mgl32.LookAtV(c.Position, c.Target, c.Up)
+	if c.Shake != nil {
+		view = c.Shake.Apply(view)
+	}
+	return view
+}
+
+// Update 推进随时间变化的相机效果
+func (c *Camera) Update(elapsed float64) {
+	if c.Shake != nil {
+		c.Shake.Update(elapsed)
+	}
+	c.updateTransition(elapsed)
 }
 
 func (c *Camera) ProcessKeyboard(direction int, deltaTime float64) {
@@ -69,11 +154,5 @@ func (c *Camera) ProcessKeyboard(direction int, deltaTime float64) {
 }
 
 func (c *Camera) ProcessMouseScroll(yOffset float32) {
-	c.Zoom -= yOffset
-	if c.Zoom < 1.0 {
-		c.Zoom = 1.0
-	}
-	if c.Zoom > 45.0 {
-		c.Zoom = 45.0
-	}
+	c.SetFov(mgl32.Clamp(c.fov-yOffset, 1, ZOOM))
 }
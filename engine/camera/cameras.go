@@ -0,0 +1,105 @@
+package camera
+
+import (
+	"fmt"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+)
+
+// DefaultCameraName 场景配置中 <camera> 的名称为空时使用
+const DefaultCameraName = "Editor"
+
+// NewCamera 按场景的相机配置创建相机
+func NewCamera(xmlCamera config.XmlCamera) *Camera {
+	c := new(Camera)
+	c.Init(xmlCamera.XMLPosition.XYZ(), xmlCamera.XMLTarget.XYZ())
+	c.Name = xmlCamera.Name
+	c.Settings = NewSettings(xmlCamera)
+	c.LoadProjection(xmlCamera)
+	c.Fly = NewFly(xmlCamera.XMLFly)
+	c.Follow = NewFollow(xmlCamera.XMLFollow)
+	c.Path = NewCameraPath(xmlCamera.XMLPath)
+	if smoothing := xmlCamera.XMLSmoothing; smoothing != nil {
+		c.Orbit.Damping = smoothing.Orbit
+		c.TransitionTime = smoothing.Transition
+	}
+	c.loadControls(xmlCamera.XMLControls)
+	return c
+}
+
+// Cameras 场景中登记的相机, 同一时间只有一个相机用于绘制和接收输入
+type Cameras struct {
+	list   []*Camera
+	active int
+}
+
+func NewCameras() *Cameras {
+	return &Cameras{}
+}
+
+// Add 登记相机, 名称为空或重复时自动编号, 第一个登记的相机为当前相机
+func (s *Cameras) Add(c *Camera) {
+	name := c.Name
+	if name == "" {
+		name = fmt.Sprintf("Camera %d", len(s.list))
+	}
+	for i := 1; s.Get(name) != nil; i++ {
+		name = fmt.Sprintf("%s %d", c.Name, i)
+	}
+	c.Name = name
+	s.list = append(s.list, c)
+}
+
+// Remove 移除相机, 不能移除最后一个相机
+func (s *Cameras) Remove(name string) bool {
+	if len(s.list) <= 1 {
+		return false
+	}
+	for i, c := range s.list {
+		if c.Name != name {
+			continue
+		}
+		s.list = append(s.list[:i], s.list[i+1:]...)
+		if s.active > i || s.active == len(s.list) {
+			s.active--
+		}
+		return true
+	}
+	return false
+}
+
+func (s *Cameras) List() []*Camera {
+	return s.list
+}
+
+func (s *Cameras) Get(name string) *Camera {
+	for _, c := range s.list {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Active 当前相机, 没有登记相机时返回 nil
+func (s *Cameras) Active() *Camera {
+	if len(s.list) == 0 {
+		return nil
+	}
+	return s.list[s.active]
+}
+
+// SetActive 按名称切换当前相机, 切换时清除原相机的飞行速度
+func (s *Cameras) SetActive(name string) bool {
+	for i, c := range s.list {
+		if c.Name != name {
+			continue
+		}
+		if i != s.active {
+			s.list[s.active].Fly.Stop()
+			s.active = i
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package camera
+
+import "github.com/huangxiaobo/toy-engine/engine/config"
+
+const (
+	DefaultFastMultiplier = 4.0
+	DefaultSlowMultiplier = 0.25
+)
+
+// SpeedModifier 速度修饰键的倍率, 按住加速键时控制速度乘以 Fast, 按住减速键时乘以 Slow
+type SpeedModifier struct {
+	Fast float32
+	Slow float32
+}
+
+func NewSpeedModifier() SpeedModifier {
+	return SpeedModifier{Fast: DefaultFastMultiplier, Slow: DefaultSlowMultiplier}
+}
+
+// Scale 按下的修饰键对应的倍率, 同时按下时相乘
+func (m SpeedModifier) Scale(fast, slow bool) float32 {
+	scale := float32(1)
+	if fast {
+		scale *= m.Fast
+	}
+	if slow {
+		scale *= m.Slow
+	}
+	return scale
+}
+
+// speedScale 输入中的速度倍率, 不大于 0 时为 1
+func speedScale(speed float32) float32 {
+	if speed <= 0 {
+		return 1
+	}
+	return speed
+}
+
+// loadControls 使用配置中的灵敏度和修饰键倍率, 为 0 的项保持默认值
+func (c *Camera) loadControls(xmlControls *config.XmlCameraControls) {
+	if xmlControls == nil {
+		return
+	}
+	if xmlControls.Rotate > 0 {
+		c.Orbit.RotateSpeed = xmlControls.Rotate
+	}
+	if xmlControls.Pan > 0 {
+		c.Orbit.PanSpeed = xmlControls.Pan
+	}
+	if xmlControls.Zoom > 0 {
+		c.Orbit.ZoomSpeed = min(xmlControls.Zoom, 0.9)
+	}
+	if xmlControls.Look > 0 {
+		c.Fly.LookSpeed = xmlControls.Look
+	}
+	if xmlControls.Fast > 0 {
+		c.Modifier.Fast = xmlControls.Fast
+	}
+	if xmlControls.Slow > 0 {
+		c.Modifier.Slow = xmlControls.Slow
+	}
+}
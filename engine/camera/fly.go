@@ -0,0 +1,107 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+)
+
+const (
+	DefaultFlySpeed        = 5.0
+	DefaultFlyAcceleration = 20.0
+	// 滚轮每格调整速度的比例
+	flySpeedStep = 1.2
+	minFlySpeed  = 0.1
+	maxFlySpeed  = 1000
+)
+
+// FlyInput 一帧内的飞行输入, Move 分量为 -1 到 1, 分别为右, 上, 前, Look 为光标移动的像素数
+type FlyInput struct {
+	Move  mgl32.Vec3
+	Look  [2]float32
+	Wheel float32
+	// 速度修饰键的倍率, 作用于移动速度, 不大于 0 时为 1
+	Speed float32
+}
+
+// Fly 第一人称飞行控制, 按相机朝向移动, 速度按加速度逐渐变化
+type Fly struct {
+	// 最大速度, 单位/秒, 滚轮调整
+	Speed float32
+	// 加速和减速的加速度, 单位/秒², 不大于 0 时立即到达目标速度
+	Acceleration float32
+	// 每像素转动的角度, 度
+	LookSpeed float32
+
+	velocity mgl32.Vec3
+}
+
+func NewFly(xmlFly *config.XmlCameraFly) *Fly {
+	f := &Fly{
+		Speed:        DefaultFlySpeed,
+		Acceleration: DefaultFlyAcceleration,
+		LookSpeed:    0.2,
+	}
+	if xmlFly != nil {
+		if xmlFly.Speed > 0 {
+			f.Speed = xmlFly.Speed
+		}
+		f.Acceleration = xmlFly.Acceleration
+	}
+	return f
+}
+
+// Stop 清除当前速度, 切换模式时调用
+func (f *Fly) Stop() {
+	f.velocity = mgl32.Vec3{}
+}
+
+// Update 根据输入转动视角并移动相机, 目标点随相机一起移动, 保持与相机的距离
+func (f *Fly) Update(c *Camera, input FlyInput, elapsed float64) {
+	dt := float32(elapsed)
+	if input.Wheel != 0 {
+		f.Speed *= float32(math.Pow(flySpeedStep, float64(input.Wheel)))
+		f.Speed = mgl32.Clamp(f.Speed, minFlySpeed, maxFlySpeed)
+	}
+
+	distance := c.Target.Sub(c.Position).Len()
+	if distance == 0 {
+		distance = 1
+	}
+	if input.Look != [2]float32{} {
+		yaw := math.Atan2(float64(c.Front.X()), float64(c.Front.Z()))
+		pitch := math.Asin(float64(mgl32.Clamp(c.Front.Y(), -1, 1)))
+		yaw -= float64(mgl32.DegToRad(input.Look[0] * f.LookSpeed))
+		pitch -= float64(mgl32.DegToRad(input.Look[1] * f.LookSpeed))
+		limit := float64(mgl32.DegToRad(maxOrbitPitch))
+		pitch = math.Max(math.Min(pitch, limit), -limit)
+
+		front := mgl32.Vec3{
+			float32(math.Cos(pitch) * math.Sin(yaw)),
+			float32(math.Sin(pitch)),
+			float32(math.Cos(pitch) * math.Cos(yaw)),
+		}
+		c.Target = c.Position.Add(front.Mul(distance))
+	}
+	c.updateVectors()
+
+	// 上下沿世界的竖直方向移动, 不随俯仰变化
+	wish := c.Right.Mul(input.Move.X()).Add(c.WorldUp.Mul(input.Move.Y())).Add(c.Front.Mul(input.Move.Z()))
+	if wish.Len() > 1 {
+		wish = wish.Normalize()
+	}
+	wish = wish.Mul(f.Speed * speedScale(input.Speed))
+
+	diff := wish.Sub(f.velocity)
+	step := f.Acceleration * dt
+	if f.Acceleration <= 0 || diff.Len() <= step {
+		f.velocity = wish
+	} else {
+		f.velocity = f.velocity.Add(diff.Normalize().Mul(step))
+	}
+
+	move := f.velocity.Mul(dt)
+	c.Position = c.Position.Add(move)
+	c.Target = c.Target.Add(move)
+}
@@ -0,0 +1,111 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+)
+
+// Follow 跟随场景对象, Target 为空时不跟随
+// 相机位置为目标位置加上 Offset, 按 Damping 逐渐跟上, 开启 LookAt 时看向目标位置加上 LookOffset
+type Follow struct {
+	// 跟随对象的名称或 Id
+	Target string
+	// 相机相对目标的偏移, Local 为 true 时随目标旋转
+	Offset mgl32.Vec3
+	Local  bool
+	// 位置和观察点的阻尼, 越大跟得越紧, 不大于 0 时没有延迟
+	Damping     float32
+	LookDamping float32
+	LookAt      bool
+	LookOffset  mgl32.Vec3
+
+	// 上一帧是否在跟随, 开始跟随时直接移动到目标位置
+	following bool
+}
+
+func NewFollow(xmlFollow *config.XmlCameraFollow) *Follow {
+	f := &Follow{
+		Offset:      mgl32.Vec3{0, 3, 8},
+		Damping:     5,
+		LookDamping: 10,
+		LookAt:      true,
+	}
+	if xmlFollow == nil {
+		return f
+	}
+	f.Target = xmlFollow.Target
+	if xmlFollow.XMLOffset != nil {
+		f.Offset = xmlFollow.XMLOffset.XYZ()
+	}
+	if xmlFollow.XMLLookOffset != nil {
+		f.LookOffset = xmlFollow.XMLLookOffset.XYZ()
+	}
+	f.Local = xmlFollow.Local
+	f.Damping = xmlFollow.Damping
+	f.LookDamping = xmlFollow.LookDamping
+	f.LookAt = !xmlFollow.FreeLook
+	return f
+}
+
+// Active 是否设置了跟随对象
+func (f *Follow) Active() bool {
+	return f.Target != ""
+}
+
+// Stop 目标不存在或停止跟随时调用, 下次跟随时重新对齐
+func (f *Follow) Stop() {
+	f.following = false
+}
+
+// Update 根据目标的模型矩阵移动相机, 不开启 LookAt 时观察方向保持不变
+func (f *Follow) Update(c *Camera, targetMatrix mgl32.Mat4, elapsed float64) {
+	position := targetMatrix.Col(3).Vec3()
+	offset := f.Offset
+	if f.Local {
+		offset = rotation(targetMatrix).Mul4x1(offset.Vec4(0)).Vec3()
+	}
+
+	desired := position.Add(offset)
+	lookAt := position.Add(f.LookOffset)
+	direction := c.Target.Sub(c.Position)
+	if !f.following {
+		c.Position = desired
+		if f.LookAt {
+			c.Target = lookAt
+		}
+	} else {
+		c.Position = damp(c.Position, desired, f.Damping, elapsed)
+		if f.LookAt {
+			c.Target = damp(c.Target, lookAt, f.LookDamping, elapsed)
+		}
+	}
+	if !f.LookAt {
+		c.Target = c.Position.Add(direction)
+	}
+	f.following = true
+	c.updateVectors()
+}
+
+// damp 指数衰减地从 current 移向 target, 与帧率无关
+func damp(current, target mgl32.Vec3, damping float32, elapsed float64) mgl32.Vec3 {
+	if damping <= 0 {
+		return target
+	}
+	t := 1 - float32(math.Exp(-float64(damping)*elapsed))
+	return current.Add(target.Sub(current).Mul(t))
+}
+
+// rotation 去掉平移和缩放的模型矩阵
+func rotation(m mgl32.Mat4) mgl32.Mat4 {
+	r := mgl32.Ident4()
+	for i := 0; i < 3; i++ {
+		axis := m.Col(i).Vec3()
+		if axis.Len() > 0 {
+			axis = axis.Normalize()
+		}
+		r.SetCol(i, axis.Vec4(0))
+	}
+	return r
+}
@@ -0,0 +1,137 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	DefaultOrbitDamping = 15.0
+	// 俯仰角的范围, 避免越过正上方和正下方时视图翻转
+	maxOrbitPitch = 89.0
+	// 剩余的旋转, 缩放和平移小于该值时停止惯性
+	orbitRestEpsilon = 1e-4
+)
+
+// MouseInput 一帧内视口中的鼠标输入, Delta 为光标移动的像素数, Wheel 向上滚动为正
+type MouseInput struct {
+	Delta [2]float32
+	Wheel float32
+	// 按住左键拖动旋转, 按住右键拖动平移
+	Rotate bool
+	Pan    bool
+	// 速度修饰键的倍率, 作用于旋转, 平移和缩放, 不大于 0 时为 1
+	Speed float32
+}
+
+// Orbit 环绕相机控制, 相机始终看向 Target, 在以 Target 为中心的球面上移动
+type Orbit struct {
+	// 每像素旋转的角度, 度
+	RotateSpeed float32
+	// 每像素平移的距离与相机到目标距离的比例
+	PanSpeed float32
+	// 滚轮每格缩放的比例
+	ZoomSpeed   float32
+	MinDistance float32
+	MaxDistance float32
+	// 惯性阻尼, 输入先累积, 每秒按该速率指数衰减地应用到相机, 松开鼠标后继续滑动一段
+	// 越大响应越快, 不大于 0 时立即响应
+	Damping float32
+
+	// 还没有应用到相机的旋转(弧度), 缩放(滚轮格数)和平移(世界单位)
+	pending orbitStep
+}
+
+type orbitStep struct {
+	yaw, pitch, zoom float64
+	pan              mgl32.Vec3
+}
+
+func (s orbitStep) scale(t float64) orbitStep {
+	return orbitStep{s.yaw * t, s.pitch * t, s.zoom * t, s.pan.Mul(float32(t))}
+}
+
+func (s orbitStep) sub(o orbitStep) orbitStep {
+	return orbitStep{s.yaw - o.yaw, s.pitch - o.pitch, s.zoom - o.zoom, s.pan.Sub(o.pan)}
+}
+
+func (s orbitStep) idle() bool {
+	return math.Abs(s.yaw) < orbitRestEpsilon && math.Abs(s.pitch) < orbitRestEpsilon &&
+		math.Abs(s.zoom) < orbitRestEpsilon && s.pan.Len() < orbitRestEpsilon
+}
+
+func NewOrbit() *Orbit {
+	return &Orbit{
+		RotateSpeed: 0.3,
+		PanSpeed:    0.0015,
+		ZoomSpeed:   0.1,
+		MinDistance: 0.1,
+		MaxDistance: 1000,
+		Damping:     DefaultOrbitDamping,
+	}
+}
+
+// Stop 丢弃剩余的惯性, 切换模式或由路径, 跟随接管相机时调用
+func (o *Orbit) Stop() {
+	o.pending = orbitStep{}
+}
+
+// Update 根据鼠标输入旋转, 平移和缩放相机, 开启阻尼时输入在之后几帧内平滑地应用
+func (o *Orbit) Update(c *Camera, input MouseInput, elapsed float64) {
+	speed := speedScale(input.Speed)
+	if input.Rotate {
+		o.pending.yaw -= float64(mgl32.DegToRad(input.Delta[0] * o.RotateSpeed * speed))
+		o.pending.pitch += float64(mgl32.DegToRad(input.Delta[1] * o.RotateSpeed * speed))
+	}
+	o.pending.zoom += float64(input.Wheel * speed)
+	if input.Pan {
+		// 光标向右拖动时场景跟随光标, 相机和目标向左移动
+		pan := c.Right.Mul(-input.Delta[0]).Add(c.Up.Mul(input.Delta[1])).Mul(c.viewDistance() * o.PanSpeed * speed)
+		o.pending.pan = o.pending.pan.Add(pan)
+	}
+	if o.pending.idle() {
+		o.pending = orbitStep{}
+		return
+	}
+
+	step := o.pending
+	if o.Damping > 0 {
+		step = step.scale(1 - math.Exp(-float64(o.Damping)*elapsed))
+	}
+	o.pending = o.pending.sub(step)
+	o.apply(c, step)
+}
+
+// apply 把一步旋转, 缩放和平移应用到相机
+func (o *Orbit) apply(c *Camera, step orbitStep) {
+	offset := c.Position.Sub(c.Target)
+	distance := offset.Len()
+	if distance == 0 {
+		offset, distance = mgl32.Vec3{0, 0, 1}, 1
+	}
+	// 球坐标, yaw 绕 Y 轴从 +Z 开始, pitch 为与水平面的夹角
+	yaw := math.Atan2(float64(offset.X()), float64(offset.Z())) + step.yaw
+	pitch := math.Asin(float64(mgl32.Clamp(offset.Y()/distance, -1, 1))) + step.pitch
+	limit := float64(mgl32.DegToRad(maxOrbitPitch))
+	pitch = math.Max(math.Min(pitch, limit), -limit)
+
+	if step.zoom != 0 {
+		scale := float32(math.Pow(float64(1-o.ZoomSpeed), step.zoom))
+		if c.Orthographic {
+			// 正交投影中距离不影响大小, 缩放视口
+			c.OrthoSize = mgl32.Clamp(c.OrthoSize*scale, o.MinDistance*c.halfFovTan(), o.MaxDistance*c.halfFovTan())
+		} else {
+			distance = mgl32.Clamp(distance*scale, o.MinDistance, o.MaxDistance)
+		}
+	}
+
+	direction := mgl32.Vec3{
+		float32(math.Cos(pitch) * math.Sin(yaw)),
+		float32(math.Sin(pitch)),
+		float32(math.Cos(pitch) * math.Cos(yaw)),
+	}
+	c.Position = c.Target.Add(direction.Mul(distance)).Add(step.pan)
+	c.Target = c.Target.Add(step.pan)
+	c.updateVectors()
+}
@@ -0,0 +1,284 @@
+package camera
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/debugdraw"
+	"github.com/huangxiaobo/toy-engine/engine/spline"
+)
+
+// Easing 关键帧到下一个关键帧之间的时间曲线
+type Easing int
+
+const (
+	EaseLinear Easing = iota
+	EaseIn
+	EaseOut
+	EaseInOut
+)
+
+var EasingNames = []string{"Linear", "In", "Out", "InOut"}
+
+const (
+	pathKeySize       = 0.3
+	pathSamplesPerKey = 16
+)
+
+var (
+	pathColor    = mgl32.Vec3{1, 0.8, 0.2}
+	pathKeyColor = mgl32.Vec3{1, 0.4, 0.1}
+)
+
+// ParseEasing 按名称查找时间曲线, 不区分大小写, 未知名称为线性
+func ParseEasing(name string) Easing {
+	for i, easingName := range EasingNames {
+		if strings.EqualFold(name, easingName) {
+			return Easing(i)
+		}
+	}
+	return EaseLinear
+}
+
+// Apply 把 [0, 1] 的时间映射到 [0, 1]
+func (e Easing) Apply(t float32) float32 {
+	switch e {
+	case EaseIn:
+		return t * t
+	case EaseOut:
+		return t * (2 - t)
+	case EaseInOut:
+		return t * t * (3 - 2*t)
+	}
+	return t
+}
+
+// Keyframe 相机路径的关键帧, Easing 作用于到下一个关键帧的一段
+type Keyframe struct {
+	Time     float32
+	Position mgl32.Vec3
+	Target   mgl32.Vec3
+	Easing   Easing
+}
+
+// CameraPath 相机的飞行路径, 位置和观察点分别沿经过关键帧的 Catmull-Rom 样条移动
+// 播放时代替鼠标键盘和跟随控制相机
+type CameraPath struct {
+	// 按时间排序的关键帧
+	Keyframes []Keyframe
+	Loop      bool
+	// 播放速度倍率
+	Speed float32
+
+	// 当前播放时间, 秒
+	Time    float32
+	Playing bool
+	// 暂停时拖动了时间, 下一帧把该时间的位置应用到相机
+	seeked bool
+	// 在视口中绘制路径和关键帧
+	Show bool
+}
+
+func NewCameraPath(xmlPath *config.XmlCameraPath) *CameraPath {
+	p := &CameraPath{Speed: 1}
+	if xmlPath == nil {
+		return p
+	}
+	p.Loop = xmlPath.Loop
+	if xmlPath.Speed > 0 {
+		p.Speed = xmlPath.Speed
+	}
+	for _, key := range xmlPath.Keys {
+		p.Keyframes = append(p.Keyframes, Keyframe{
+			Time:     key.Time,
+			Position: key.XMLPosition.XYZ(),
+			Target:   key.XMLTarget.XYZ(),
+			Easing:   ParseEasing(key.Easing),
+		})
+	}
+	p.sort()
+	p.Playing = xmlPath.AutoPlay && len(p.Keyframes) > 1
+	return p
+}
+
+func (p *CameraPath) sort() {
+	sort.SliceStable(p.Keyframes, func(i, j int) bool {
+		return p.Keyframes[i].Time < p.Keyframes[j].Time
+	})
+}
+
+// Duration 最后一个关键帧的时间
+func (p *CameraPath) Duration() float32 {
+	if len(p.Keyframes) == 0 {
+		return 0
+	}
+	return p.Keyframes[len(p.Keyframes)-1].Time
+}
+
+// AddKeyframe 在 time 处用相机当前的位置和观察点添加关键帧, 已有同一时间的关键帧时替换, 返回关键帧的下标
+func (p *CameraPath) AddKeyframe(time float32, c *Camera) int {
+	key := Keyframe{Time: max(time, 0), Position: c.Position, Target: c.Target, Easing: EaseInOut}
+	for i := range p.Keyframes {
+		if p.Keyframes[i].Time == key.Time {
+			key.Easing = p.Keyframes[i].Easing
+			p.Keyframes[i] = key
+			return i
+		}
+	}
+	p.Keyframes = append(p.Keyframes, key)
+	p.sort()
+	for i := range p.Keyframes {
+		if p.Keyframes[i].Time == key.Time {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *CameraPath) RemoveKeyframe(i int) {
+	if i < 0 || i >= len(p.Keyframes) {
+		return
+	}
+	p.Keyframes = append(p.Keyframes[:i], p.Keyframes[i+1:]...)
+}
+
+// SetKeyframeTime 修改关键帧的时间并重新排序, 返回关键帧新的下标
+func (p *CameraPath) SetKeyframeTime(i int, time float32) int {
+	if i < 0 || i >= len(p.Keyframes) {
+		return i
+	}
+	key := &p.Keyframes[i]
+	key.Time = max(time, 0)
+	moved := *key
+	p.sort()
+	for j := range p.Keyframes {
+		if p.Keyframes[j] == moved {
+			return j
+		}
+	}
+	return i
+}
+
+// Play 从当前时间开始播放, 已经播放到末尾时从头开始
+func (p *CameraPath) Play() {
+	if len(p.Keyframes) < 2 {
+		return
+	}
+	if p.Time >= p.Duration() {
+		p.Time = p.Keyframes[0].Time
+	}
+	p.Playing = true
+}
+
+// Seek 跳到时间 t, 暂停时相机也移动到该位置
+func (p *CameraPath) Seek(t float32) {
+	p.Time = mgl32.Clamp(t, 0, p.Duration())
+	p.seeked = true
+}
+
+// TakeSeek 返回并清除拖动标记
+func (p *CameraPath) TakeSeek() bool {
+	seeked := p.seeked
+	p.seeked = false
+	return seeked
+}
+
+func (p *CameraPath) Pause() {
+	p.Playing = false
+}
+
+// Stop 停止播放, 相机回到开头
+func (p *CameraPath) Stop() {
+	p.Playing = false
+	p.Seek(0)
+}
+
+// Update 推进播放时间, 不循环时到末尾停止
+func (p *CameraPath) Update(elapsed float64) {
+	if !p.Playing {
+		return
+	}
+	if len(p.Keyframes) < 2 {
+		p.Playing = false
+		return
+	}
+	p.Time += float32(elapsed) * p.Speed
+	duration := p.Duration()
+	if p.Time < duration {
+		return
+	}
+	start := p.Keyframes[0].Time
+	if span := duration - start; p.Loop && span > 0 {
+		p.Time = start + float32(math.Mod(float64(p.Time-start), float64(span)))
+		return
+	}
+	p.Time = duration
+	p.Playing = false
+}
+
+// Sample 时间 t 处的相机位置和观察点
+func (p *CameraPath) Sample(t float32) (mgl32.Vec3, mgl32.Vec3, bool) {
+	n := len(p.Keyframes)
+	if n == 0 {
+		return mgl32.Vec3{}, mgl32.Vec3{}, false
+	}
+	if n == 1 || t <= p.Keyframes[0].Time {
+		return p.Keyframes[0].Position, p.Keyframes[0].Target, true
+	}
+	if t >= p.Keyframes[n-1].Time {
+		return p.Keyframes[n-1].Position, p.Keyframes[n-1].Target, true
+	}
+
+	i := sort.Search(n, func(i int) bool { return p.Keyframes[i].Time > t }) - 1
+	from, to := p.Keyframes[i], p.Keyframes[i+1]
+	var f float32
+	if span := to.Time - from.Time; span > 0 {
+		f = from.Easing.Apply((t - from.Time) / span)
+	}
+
+	positions := spline.Spline{Points: make([]mgl32.Vec3, n)}
+	targets := spline.Spline{Points: make([]mgl32.Vec3, n)}
+	for j, key := range p.Keyframes {
+		positions.Points[j] = key.Position
+		targets.Points[j] = key.Target
+	}
+	return positions.Evaluate(float32(i) + f), targets.Evaluate(float32(i) + f), true
+}
+
+// Apply 把当前时间的位置和观察点应用到相机
+func (p *CameraPath) Apply(c *Camera) {
+	position, target, ok := p.Sample(p.Time)
+	if !ok {
+		return
+	}
+	c.Position, c.Target = position, target
+	c.updateVectors()
+}
+
+// Draw 绘制路径曲线和关键帧, 关键帧处画出到观察点的连线
+func (p *CameraPath) Draw(dd *debugdraw.DebugDraw) {
+	n := len(p.Keyframes)
+	if n == 0 {
+		return
+	}
+	for _, key := range p.Keyframes {
+		dd.AddCross(key.Position, pathKeySize, pathKeyColor)
+		if direction := key.Target.Sub(key.Position); direction.Len() > 0 {
+			dd.AddLine(key.Position, key.Position.Add(direction.Normalize()), pathKeyColor)
+		}
+	}
+	start, duration := p.Keyframes[0].Time, p.Duration()
+	if duration <= start {
+		return
+	}
+	steps := (n - 1) * pathSamplesPerKey
+	prev, _, _ := p.Sample(start)
+	for i := 1; i <= steps; i++ {
+		position, _, _ := p.Sample(start + (duration-start)*float32(i)/float32(steps))
+		dd.AddLine(prev, position, pathColor)
+		prev = position
+	}
+}
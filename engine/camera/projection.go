@@ -0,0 +1,173 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+)
+
+const (
+	// 缩放到范围时在包围球外留出的比例
+	frameMargin  = 1.1
+	minOrthoSize = 0.01
+	minFov       = 1
+	maxFov       = 170
+	minClipNear  = 0.0001
+)
+
+// Projection 投影矩阵, 透视投影的垂直视角为 Fov, 正交投影的视口高度为 2 * OrthoSize
+func (c *Camera) Projection() mgl32.Mat4 {
+	if c.Orthographic {
+		h := c.OrthoSize
+		w := h * c.aspect
+		return mgl32.Ortho(-w, w, -h, h, c.near, c.far)
+	}
+	return mgl32.Perspective(mgl32.DegToRad(c.fov), c.aspect, c.near, c.far)
+}
+
+// Fov 透视投影的垂直视角, 度
+func (c *Camera) Fov() float32 {
+	return c.fov
+}
+
+func (c *Camera) SetFov(fov float32) {
+	c.fov = mgl32.Clamp(fov, minFov, maxFov)
+}
+
+func (c *Camera) Near() float32 {
+	return c.near
+}
+
+func (c *Camera) Far() float32 {
+	return c.far
+}
+
+// SetClip 设置近远裁剪面, 近裁剪面必须大于 0, 远裁剪面必须大于近裁剪面
+func (c *Camera) SetClip(near, far float32) {
+	c.near = max(near, minClipNear)
+	c.far = max(far, c.near*2)
+}
+
+// Aspect 视口的宽高比
+func (c *Camera) Aspect() float32 {
+	return c.aspect
+}
+
+// SetAspect 设置宽高比, 窗口最小化时高度为 0, 这时保持原来的值
+func (c *Camera) SetAspect(aspect float32) {
+	if aspect > 0 && !math.IsInf(float64(aspect), 0) {
+		c.aspect = aspect
+	}
+}
+
+// SetViewport 按视口的像素大小设置宽高比
+func (c *Camera) SetViewport(width, height int32) {
+	if height > 0 {
+		c.SetAspect(float32(width) / float32(height))
+	}
+}
+
+// LoadProjection 使用场景相机配置中的视角和裁剪面, 没有配置的项保持全局配置的默认值
+func (c *Camera) LoadProjection(xmlCamera config.XmlCamera) {
+	if xmlCamera.XMLFov > 0 {
+		c.SetFov(xmlCamera.XMLFov)
+	}
+	near, far := c.near, c.far
+	if xmlCamera.XMLNear > 0 {
+		near = xmlCamera.XMLNear
+	}
+	if xmlCamera.XMLFar > 0 {
+		far = xmlCamera.XMLFar
+	}
+	c.SetClip(near, far)
+}
+
+// SetOrthographic 切换投影方式, 正交视口的大小与切换前目标处的透视视口相同
+func (c *Camera) SetOrthographic(orthographic bool) {
+	if orthographic == c.Orthographic {
+		return
+	}
+	distance := c.Target.Sub(c.Position).Len()
+	if orthographic {
+		c.OrthoSize = max(distance*c.halfFovTan(), minOrthoSize)
+	} else if distance > 0 {
+		// 保持目标处的视口大小, 相机沿视线移动
+		distance = c.OrthoSize / c.halfFovTan()
+		c.Position = c.Target.Sub(c.Front.Mul(distance))
+	}
+	c.Orthographic = orthographic
+}
+
+// viewDistance 透视投影下与当前视口大小相同的相机距离, 用于按视口大小缩放平移速度
+func (c *Camera) viewDistance() float32 {
+	if c.Orthographic {
+		return c.OrthoSize / c.halfFovTan()
+	}
+	return c.Target.Sub(c.Position).Len()
+}
+
+func (c *Camera) halfFovTan() float32 {
+	return float32(math.Tan(float64(mgl32.DegToRad(c.fov)) / 2))
+}
+
+// ScreenPointToRay 视口中一点对应的世界空间射线, x, y 为以视口左上角为原点的坐标, 与 viewportSize 的单位相同
+// 射线从近裁剪面出发, 正交投影时各点的射线互相平行
+func (c *Camera) ScreenPointToRay(x, y float32, viewportSize [2]float32) (geometry.Ray, bool) {
+	if viewportSize[0] <= 0 || viewportSize[1] <= 0 {
+		return geometry.Ray{}, false
+	}
+	ndcX := 2*x/viewportSize[0] - 1
+	ndcY := 1 - 2*y/viewportSize[1]
+
+	viewProjection := c.Projection().Mul4(c.GetViewMatrix())
+	if viewProjection.Det() == 0 {
+		return geometry.Ray{}, false
+	}
+	inverse := viewProjection.Inv()
+	near := mgl32.TransformCoordinate(mgl32.Vec3{ndcX, ndcY, -1}, inverse)
+	far := mgl32.TransformCoordinate(mgl32.Vec3{ndcX, ndcY, 1}, inverse)
+	direction := far.Sub(near)
+	if direction.Len() == 0 {
+		return geometry.Ray{}, false
+	}
+	return geometry.Ray{Origin: near, Direction: direction.Normalize()}, true
+}
+
+// FrameBounds 保持观察方向, 在 TransitionTime 内移动相机使包围盒完整地显示在视口中
+func (c *Camera) FrameBounds(bounds geometry.AABB) {
+	if bounds.IsEmpty() {
+		return
+	}
+	sphere := bounds.BoundingSphere()
+	radius := max(sphere.Radius*frameMargin, minOrthoSize)
+
+	// 按垂直和水平视角中较小的一个计算
+	halfTan := c.halfFovTan() * min(c.aspect, 1)
+	distance := radius / float32(math.Sin(math.Atan(float64(halfTan))))
+	c.moveTo(sphere.Center.Sub(c.Front.Mul(distance)), sphere.Center, radius/min(c.aspect, 1), c.TransitionTime)
+}
+
+// FrameTarget 缩放到范围的对象
+type FrameTarget int
+
+const (
+	FrameNone FrameTarget = iota
+	// FrameAll 场景中所有有包围盒的对象
+	FrameAll
+	// FrameSelection 选中的对象, 没有选中时同 FrameAll
+	FrameSelection
+)
+
+// RequestFrame 请求在下一帧缩放到范围, 包围盒由 World 计算
+func (c *Camera) RequestFrame(target FrameTarget) {
+	c.frameRequest = target
+}
+
+// TakeFrameRequest 返回并清除缩放请求
+func (c *Camera) TakeFrameRequest() (FrameTarget, bool) {
+	target := c.frameRequest
+	c.frameRequest = FrameNone
+	return target, target != FrameNone
+}
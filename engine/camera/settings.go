@@ -0,0 +1,51 @@
+package camera
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+)
+
+// Settings 相机自己的清屏和后处理设置, 未设置的项使用全局配置
+// 例如小地图相机关闭泛光, 画中画相机使用不同的背景色
+type Settings struct {
+	// 清屏颜色, 为空时使用全局背景色(开启雾时为雾的颜色)
+	ClearColor *mgl32.Vec3
+	// 关闭全部后处理效果, 多重采样仍然生效
+	DisablePostProcess bool
+	// 按效果名覆盖效果链中的开关
+	PostEffects map[string]bool
+}
+
+func NewSettings(xmlCamera config.XmlCamera) Settings {
+	s := Settings{PostEffects: make(map[string]bool)}
+	if xmlCamera.XMLClearColor != nil {
+		clearColor := xmlCamera.XMLClearColor.RGB()
+		s.ClearColor = &clearColor
+	}
+	if postProcess := xmlCamera.XMLPostProcess; postProcess != nil {
+		s.DisablePostProcess = postProcess.Disable
+		for _, effect := range postProcess.Effects {
+			s.PostEffects[effect.Name] = effect.Enable
+		}
+	}
+	return s
+}
+
+// Background 相机的清屏颜色
+func (s *Settings) Background() mgl32.Vec3 {
+	if s.ClearColor != nil {
+		return *s.ClearColor
+	}
+	return config.BackgroundColor()
+}
+
+// EffectEnabled 效果对该相机是否启用, enabled 为效果链中的开关
+func (s *Settings) EffectEnabled(name string, enabled bool) bool {
+	if s.DisablePostProcess {
+		return false
+	}
+	if override, ok := s.PostEffects[name]; ok {
+		return override
+	}
+	return enabled
+}
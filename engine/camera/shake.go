@@ -0,0 +1,168 @@
+package camera
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// 每个分量使用不同的噪声种子, 避免各轴同步抖动
+const (
+	shakeSeedOffset = 1
+	shakeSeedAngle  = 4
+	// 每个定时震动占用的种子个数
+	shakeSeedStride = 8
+)
+
+// Shake 基于创伤值(trauma)的相机震动
+// 事件通过 AddTrauma 或 AddImpulse 增加创伤值, 创伤值随时间衰减, 震动强度为创伤值的平方
+// Start 添加固定振幅, 频率和时长的定时震动, 与创伤值的震动叠加
+// 位移和旋转由 Perlin 噪声驱动, 作为观察空间中的后变换叠加到相机上, 不改变相机本身的位置
+type Shake struct {
+	Trauma float32
+	// 每秒衰减的创伤值
+	Decay float32
+	// 最大位移(观察空间)
+	MaxOffset mgl32.Vec3
+	// 最大旋转角度(度), 分别绕 X(俯仰), Y(偏航), Z(翻滚)
+	MaxAngle mgl32.Vec3
+	// 噪声频率, 越大抖动越快
+	Frequency float32
+
+	time   float32
+	pulses []shakePulse
+	seed   uint32
+}
+
+// shakePulse 定时震动, 强度在持续时间内从 amplitude 平滑地减到 0
+type shakePulse struct {
+	amplitude, frequency float32
+	duration, elapsed    float32
+	seed                 uint32
+}
+
+func NewShake() *Shake {
+	return &Shake{
+		Decay:     1,
+		MaxOffset: mgl32.Vec3{0.3, 0.3, 0.1},
+		MaxAngle:  mgl32.Vec3{2, 2, 4},
+		Frequency: 15,
+	}
+}
+
+// AddTrauma 增加创伤值, 结果限制在 [0, 1]
+func (s *Shake) AddTrauma(amount float32) {
+	s.Trauma = mgl32.Clamp(s.Trauma+amount, 0, 1)
+}
+
+// AddImpulse 在 source 处发生强度为 strength 的冲击, 创伤值随到 listener 的距离在 radius 内衰减
+func (s *Shake) AddImpulse(source, listener mgl32.Vec3, strength, radius float32) {
+	if radius <= 0 {
+		return
+	}
+	falloff := 1 - source.Sub(listener).Len()/radius
+	if falloff <= 0 {
+		return
+	}
+	s.AddTrauma(strength * falloff * falloff)
+}
+
+// Start 开始一段持续 duration 秒的震动, amplitude 为相对 MaxOffset 和 MaxAngle 的强度, 1 为最大
+// frequency 为噪声频率, 不大于 0 时使用 Frequency
+func (s *Shake) Start(amplitude, frequency, duration float32) {
+	if amplitude <= 0 || duration <= 0 {
+		return
+	}
+	if frequency <= 0 {
+		frequency = s.Frequency
+	}
+	s.seed++
+	s.pulses = append(s.pulses, shakePulse{
+		amplitude: amplitude,
+		frequency: frequency,
+		duration:  duration,
+		seed:      s.seed * shakeSeedStride,
+	})
+}
+
+// Stop 清除所有震动
+func (s *Shake) Stop() {
+	s.Trauma = 0
+	s.pulses = s.pulses[:0]
+}
+
+func (s *Shake) Update(elapsed float64) {
+	s.time += float32(elapsed)
+	s.Trauma = max(s.Trauma-s.Decay*float32(elapsed), 0)
+	pulses := s.pulses[:0]
+	for _, pulse := range s.pulses {
+		pulse.elapsed += float32(elapsed)
+		if pulse.elapsed < pulse.duration {
+			pulses = append(pulses, pulse)
+		}
+	}
+	s.pulses = pulses
+}
+
+// Intensity 当前创伤值的震动强度, 不包括定时震动
+func (s *Shake) Intensity() float32 {
+	return s.Trauma * s.Trauma
+}
+
+// intensity 定时震动当前的强度
+func (p shakePulse) intensity() float32 {
+	fade := 1 - p.elapsed/p.duration
+	return p.amplitude * fade * fade
+}
+
+// Apply 把当前震动作为后变换叠加到观察矩阵上
+func (s *Shake) Apply(view mgl32.Mat4) mgl32.Mat4 {
+	intensity := s.Intensity()
+	if intensity <= 0 && len(s.pulses) == 0 {
+		return view
+	}
+	var offset, angle mgl32.Vec3
+	s.add(&offset, &angle, intensity, s.time*s.Frequency, 0)
+	for _, pulse := range s.pulses {
+		s.add(&offset, &angle, pulse.intensity(), pulse.elapsed*pulse.frequency, pulse.seed)
+	}
+	for i := range angle {
+		angle[i] = mgl32.DegToRad(angle[i])
+	}
+	shake := mgl32.Translate3D(offset.X(), offset.Y(), offset.Z())
+	shake = shake.Mul4(mgl32.HomogRotate3DZ(angle.Z()))
+	shake = shake.Mul4(mgl32.HomogRotate3DX(angle.X()))
+	shake = shake.Mul4(mgl32.HomogRotate3DY(angle.Y()))
+	return shake.Mul4(view)
+}
+
+// add 累加强度为 intensity 的一组噪声位移和旋转(度)
+func (s *Shake) add(offset, angle *mgl32.Vec3, intensity, t float32, seed uint32) {
+	if intensity <= 0 {
+		return
+	}
+	for i := 0; i < 3; i++ {
+		offset[i] += s.MaxOffset[i] * intensity * noise(t, seed+uint32(shakeSeedOffset+i))
+		angle[i] += s.MaxAngle[i] * intensity * noise(t, seed+uint32(shakeSeedAngle+i))
+	}
+}
+
+// noise 一维 Perlin 梯度噪声, 返回值约在 [-1, 1]
+func noise(x float32, seed uint32) float32 {
+	i := float32(math.Floor(float64(x)))
+	f := x - i
+	g0 := gradient(int32(i), seed) * f
+	g1 := gradient(int32(i)+1, seed) * (f - 1)
+	u := f * f * f * (f*(f*6-15) + 10)
+	// 一维梯度噪声的幅度最大为 0.5
+	return 2 * (g0 + u*(g1-g0))
+}
+
+// gradient 整数格点上的伪随机梯度, 取值 [-1, 1]
+func gradient(i int32, seed uint32) float32 {
+	h := uint32(i)*0x27d4eb2d ^ seed*0x9e3779b9
+	h ^= h >> 15
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	return float32(h&0xffff)/0xffff*2 - 1
+}
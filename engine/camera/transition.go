@@ -0,0 +1,72 @@
+package camera
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const DefaultTransitionTime = 0.4
+
+// transition 相机在一段时间内平滑地移动到新的位置, 观察点和正交视口大小
+type transition struct {
+	fromPosition, toPosition   mgl32.Vec3
+	fromTarget, toTarget       mgl32.Vec3
+	fromOrthoSize, toOrthoSize float32
+	duration, elapsed          float32
+}
+
+// MoveTo 在 duration 秒内平滑地移动到新的位置和观察点, duration 不大于 0 时立即移动
+func (c *Camera) MoveTo(position, target mgl32.Vec3, duration float32) {
+	c.moveTo(position, target, c.OrthoSize, duration)
+}
+
+func (c *Camera) moveTo(position, target mgl32.Vec3, orthoSize, duration float32) {
+	if duration <= 0 {
+		c.transition = nil
+		c.Position, c.Target, c.OrthoSize = position, target, orthoSize
+		c.updateVectors()
+		return
+	}
+	c.transition = &transition{
+		fromPosition:  c.Position,
+		toPosition:    position,
+		fromTarget:    c.Target,
+		toTarget:      target,
+		fromOrthoSize: c.OrthoSize,
+		toOrthoSize:   orthoSize,
+		duration:      duration,
+	}
+}
+
+// BlendFrom 从另一个相机的位置和观察点过渡到本相机当前的位置, 切换相机时调用
+func (c *Camera) BlendFrom(from *Camera) {
+	position, target := c.Position, c.Target
+	c.Position, c.Target = from.Position, from.Target
+	c.updateVectors()
+	c.MoveTo(position, target, c.TransitionTime)
+}
+
+// Transitioning 是否正在过渡, 过渡期间不响应鼠标键盘控制
+func (c *Camera) Transitioning() bool {
+	return c.transition != nil
+}
+
+// StopTransition 停在当前位置, 由路径或跟随接管相机时调用
+func (c *Camera) StopTransition() {
+	c.transition = nil
+}
+
+func (c *Camera) updateTransition(elapsed float64) {
+	t := c.transition
+	if t == nil {
+		return
+	}
+	t.elapsed += float32(elapsed)
+	f := EaseInOut.Apply(min(t.elapsed/t.duration, 1))
+	c.Position = t.fromPosition.Add(t.toPosition.Sub(t.fromPosition).Mul(f))
+	c.Target = t.fromTarget.Add(t.toTarget.Sub(t.fromTarget).Mul(f))
+	c.OrthoSize = t.fromOrthoSize + (t.toOrthoSize-t.fromOrthoSize)*f
+	c.updateVectors()
+	if t.elapsed >= t.duration {
+		c.transition = nil
+	}
+}
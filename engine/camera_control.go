@@ -0,0 +1,273 @@
+package engine
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/camera"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/input"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+)
+
+// 相机控制使用的输入动作, 绑定可以在 Input Bindings 窗口中修改并保存
+const (
+	ActionMoveForward        = "MoveForward"
+	ActionMoveRight          = "MoveRight"
+	ActionMoveUp             = "MoveUp"
+	ActionLookX              = "LookX"
+	ActionLookY              = "LookY"
+	ActionCameraOrbit        = "CameraOrbit"
+	ActionCameraPan          = "CameraPan"
+	ActionCameraZoom         = "CameraZoom"
+	ActionToggleCameraMode   = "ToggleCameraMode"
+	ActionToggleOrthographic = "ToggleOrthographic"
+	ActionFrameSelection     = "FrameSelection"
+	ActionCaptureMouse       = "CaptureMouse"
+	ActionSpeedFast          = "SpeedFast"
+	ActionSpeedSlow          = "SpeedSlow"
+)
+
+const (
+	defaultInputBindingsFile = "input.xml"
+	// 手柄右摇杆推到底时相当于每秒移动光标的像素数
+	gamepadLookSpeed = 600
+	// 手指间距每增加多少像素相当于滚轮向上一格
+	pinchPixelsPerWheel = 40
+)
+
+// defaultActions 默认的按键绑定, 绑定文件中的同名动作替换它们
+func defaultActions() []*input.Action {
+	return []*input.Action{
+		{Name: ActionMoveForward, Bindings: []input.Binding{
+			input.KeyBinding(input.KeyW, 1), input.KeyBinding(input.KeyS, -1), input.GamepadAxisBinding(input.GamepadLeftY, -1),
+		}},
+		{Name: ActionMoveRight, Bindings: []input.Binding{
+			input.KeyBinding(input.KeyD, 1), input.KeyBinding(input.KeyA, -1), input.GamepadAxisBinding(input.GamepadLeftX, 1),
+		}},
+		{Name: ActionMoveUp, Bindings: []input.Binding{
+			input.KeyBinding(input.KeyE, 1), input.KeyBinding(input.KeyQ, -1),
+			input.GamepadAxisBinding(input.GamepadTriggerRight, 1), input.GamepadAxisBinding(input.GamepadTriggerLeft, -1),
+		}},
+		{Name: ActionLookX, Bindings: []input.Binding{input.GamepadAxisBinding(input.GamepadRightX, gamepadLookSpeed)}},
+		{Name: ActionLookY, Bindings: []input.Binding{input.GamepadAxisBinding(input.GamepadRightY, gamepadLookSpeed)}},
+		{Name: ActionCameraOrbit, Bindings: []input.Binding{input.MouseBinding(input.MouseLeft)}},
+		{Name: ActionCameraPan, Bindings: []input.Binding{input.MouseBinding(input.MouseRight)}},
+		{Name: ActionCameraZoom, Bindings: []input.Binding{input.MouseAxisBinding(input.MouseWheel, 1)}},
+		{Name: ActionToggleCameraMode, Bindings: []input.Binding{input.KeyBinding(input.KeyC, 1), input.GamepadBinding(input.GamepadY, 1)}},
+		{Name: ActionToggleOrthographic, Bindings: []input.Binding{input.KeyBinding(input.KeyKeypad5, 1)}},
+		{Name: ActionFrameSelection, Bindings: []input.Binding{input.KeyBinding(input.KeyHome, 1)}},
+		{Name: ActionCaptureMouse, Bindings: []input.Binding{input.KeyBinding(input.KeyG, 1)}},
+		{Name: ActionSpeedFast, Bindings: []input.Binding{
+			input.KeyBinding(input.KeyLeftShift, 1), input.KeyBinding(input.KeyRightShift, 1), input.GamepadBinding(input.GamepadRightShoulder, 1),
+		}},
+		{Name: ActionSpeedSlow, Bindings: []input.Binding{
+			input.KeyBinding(input.KeyLeftCtrl, 1), input.KeyBinding(input.KeyRightCtrl, 1), input.GamepadBinding(input.GamepadLeftShoulder, 1),
+		}},
+	}
+}
+
+// updateCamera 按输入动作控制相机, 默认绑定如下
+// C 在环绕和飞行模式之间切换, 小键盘 5 切换正交投影, Home 缩放到选中的对象, 没有选中时缩放到整个场景
+// G 捕获鼠标, 隐藏光标后移动鼠标直接转动视角, Escape 释放
+// 环绕模式左键拖动旋转, 右键拖动平移, 滚轮缩放
+// 飞行模式 WASD 前后左右, E/Q 上下, 左键或右键拖动转动视角, 滚轮调整速度
+// 手柄左摇杆移动, 右摇杆转动, 扳机升降, Y 切换模式
+// 按住 Shift 或手柄右肩键加速, Ctrl 或左肩键减速, 倍率在相机的 <controls> 中设置
+// 触摸屏和触控板单指拖动与左键相同, 双指拖动平移(飞行模式转动视角), 双指张开捏合与滚轮相同
+// 编辑工具打开或按在手柄和灯光图标上时左键留给工具, 播放路径, 跟随对象或平滑过渡时不响应输入
+func (w *World) updateCamera(elapsed float64) {
+	in, actions := w.Input, w.Actions
+	if !actions.Down(ActionCameraOrbit) {
+		w.orbitRotating = false
+	}
+	keyboard := !in.KeyboardCaptured()
+	if keyboard && !in.CtrlDown() && actions.Pressed(ActionToggleCameraMode) {
+		w.Camera.ToggleMode()
+	}
+	if keyboard && actions.Pressed(ActionToggleOrthographic) {
+		w.Camera.SetOrthographic(!w.Camera.Orthographic)
+	}
+	if keyboard && actions.Pressed(ActionFrameSelection) {
+		w.Camera.RequestFrame(camera.FrameSelection)
+	}
+	if keyboard && actions.Pressed(ActionCaptureMouse) {
+		w.SetRelativeMouse(!w.RelativeMouse())
+	}
+	if target, ok := w.Camera.TakeFrameRequest(); ok {
+		w.frame(target)
+	}
+	if path := w.Camera.Path; path.Playing || path.TakeSeek() {
+		w.stopCameraControls()
+		path.Update(elapsed)
+		path.Apply(w.Camera)
+		return
+	}
+	if w.updateFollow(elapsed) {
+		w.stopCameraControls()
+		return
+	}
+	if w.Camera.Transitioning() {
+		w.Camera.Orbit.Stop()
+		w.Camera.Fly.Stop()
+		return
+	}
+
+	speed := w.Camera.Modifier.Scale(actions.Down(ActionSpeedFast), actions.Down(ActionSpeedSlow))
+	mouse := camera.MouseInput{Delta: in.MouseDelta(), Wheel: actions.Value(ActionCameraZoom), Speed: speed}
+	if in.MouseCaptured() {
+		mouse = camera.MouseInput{Speed: speed}
+	} else if actions.Pressed(ActionCameraOrbit) {
+		w.orbitRotating = !w.editingWithMouse()
+	}
+	mouse.Rotate = w.orbitRotating || in.RelativeMouse()
+	mouse.Pan = actions.Down(ActionCameraPan) && !in.MouseCaptured()
+	// 多指手势时第一个手指模拟的左键不再旋转
+	if in.TouchFingers() >= 2 && !in.MouseCaptured() {
+		mouse.Rotate, mouse.Pan = false, true
+		mouse.Delta = in.TouchPan()
+		mouse.Wheel += in.Pinch() / pinchPixelsPerWheel
+	}
+	// 摇杆转动与拖动光标相同
+	look := [2]float32{actions.Value(ActionLookX) * float32(elapsed), actions.Value(ActionLookY) * float32(elapsed)}
+	if look != ([2]float32{}) {
+		mouse.Rotate = true
+		mouse.Delta = [2]float32{mouse.Delta[0] + look[0], mouse.Delta[1] + look[1]}
+	}
+
+	if w.Camera.Mode == camera.ModeFly {
+		fly := camera.FlyInput{Wheel: mouse.Wheel, Speed: speed}
+		if mouse.Rotate || mouse.Pan {
+			fly.Look = mouse.Delta
+		}
+		if keyboard {
+			fly.Move = mgl32.Vec3{
+				mgl32.Clamp(actions.Value(ActionMoveRight), -1, 1),
+				mgl32.Clamp(actions.Value(ActionMoveUp), -1, 1),
+				mgl32.Clamp(actions.Value(ActionMoveForward), -1, 1),
+			}
+		}
+		w.Camera.Fly.Update(w.Camera, fly, elapsed)
+		return
+	}
+	w.Camera.Orbit.Update(w.Camera, mouse, elapsed)
+}
+
+// SetRelativeMouse 捕获或释放鼠标, 捕获时光标隐藏, 鼠标移动直接转动相机, 按 Escape 或窗口失去焦点时释放
+func (w *World) SetRelativeMouse(enabled bool) {
+	w.platform.SetRelativeMouse(enabled)
+}
+
+// RelativeMouse 鼠标是否被捕获
+func (w *World) RelativeMouse() bool {
+	return w.platform.RelativeMouse()
+}
+
+// stopCameraControls 路径或跟随接管相机时停止过渡和鼠标键盘控制的惯性
+func (w *World) stopCameraControls() {
+	w.Camera.StopTransition()
+	w.Camera.Orbit.Stop()
+	w.Camera.Fly.Stop()
+}
+
+// editingWithMouse 左键是否被编辑工具使用
+func (w *World) editingWithMouse() bool {
+	return w.measureTool.Active || w.paintTool.Active || w.sculptTool.Active ||
+		w.splineTool.Active || w.placeTool.Active ||
+		w.gizmoTool.Hovered() || w.gizmoTool.Dragging() || w.lightGizmos.Hovered()
+}
+
+// ZoomToExtent 移动相机显示整个场景
+func (w *World) ZoomToExtent() {
+	w.frame(camera.FrameAll)
+}
+
+// ZoomToSelection 移动相机显示选中的模型, 没有选中时显示整个场景
+func (w *World) ZoomToSelection() {
+	w.frame(camera.FrameSelection)
+}
+
+func (w *World) frame(target camera.FrameTarget) {
+	if target == camera.FrameSelection {
+		if boundedObj, ok := w.uiWindowMain.SelectedModel().(model.BoundedObj); ok {
+			w.Camera.FrameBounds(boundedObj.WorldBounds())
+			return
+		}
+	}
+	w.Camera.FrameBounds(w.sceneBounds())
+}
+
+// sceneBounds 所有有包围盒的对象的包围盒, 不包括地面网格等无限大的对象
+func (w *World) sceneBounds() geometry.AABB {
+	bounds := geometry.NewAABB()
+	for _, renderObj := range w.renderObjs {
+		if boundedObj, ok := renderObj.(model.BoundedObj); ok {
+			bounds = bounds.Union(boundedObj.WorldBounds())
+		}
+	}
+	return bounds
+}
+
+// AddCamera 登记一个可切换的相机, 名称重复时自动编号
+func (w *World) AddCamera(c *camera.Camera) {
+	w.Cameras.Add(c)
+}
+
+// SetActiveCamera 按名称切换用于绘制和接收输入的相机
+func (w *World) SetActiveCamera(name string) bool {
+	if !w.Cameras.SetActive(name) {
+		return false
+	}
+	w.syncActiveCamera()
+	return true
+}
+
+// syncActiveCamera 使用 Cameras 中的当前相机, 切换时新相机从上一个相机的位置平滑过渡过去
+func (w *World) syncActiveCamera() {
+	active := w.Cameras.Active()
+	if w.Camera != nil && active != w.Camera {
+		active.BlendFrom(w.Camera)
+	}
+	w.Camera = active
+}
+
+// Shake 让当前相机震动 duration 秒, amplitude 为 0 到 1 的强度, frequency 为抖动频率, 不大于 0 时使用相机的设置
+func (w *World) Shake(amplitude, frequency, duration float32) {
+	w.Camera.Shake.Start(amplitude, frequency, duration)
+}
+
+// updateFollow 跟随对象, 使用对象上一帧的变换, 没有设置或找不到对象时返回 false
+func (w *World) updateFollow(elapsed float64) bool {
+	follow := w.Camera.Follow
+	if !follow.Active() {
+		follow.Stop()
+		return false
+	}
+	e, ok := w.FindByName(follow.Target)
+	if !ok {
+		e, ok = w.FindById(follow.Target)
+	}
+	if !ok {
+		follow.Stop()
+		return false
+	}
+
+	var matrix mgl32.Mat4
+	if transformObj, ok := e.Obj.(model.TransformObj); ok {
+		matrix = transformObj.ModelMatrix()
+	} else if bounds, ok := e.Bounds(); ok {
+		matrix = mgl32.Translate3D(bounds.Center().Elem())
+	} else {
+		follow.Stop()
+		return false
+	}
+	follow.Update(w.Camera, matrix, elapsed)
+	return true
+}
+
+// drawCameraPaths 绘制开启显示的相机路径, 当前相机正在播放的路径不绘制
+func (w *World) drawCameraPaths() {
+	for _, c := range w.Cameras.List() {
+		if c.Path.Show && !(c == w.Camera && c.Path.Playing) {
+			c.Path.Draw(w.DebugDraw)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shortcut"
+)
+
+// 编辑器命令的名称, 可以通过 World.Commands.Run 执行
+const (
+	CommandSaveScene       = "Save Scene"
+	CommandDeleteSelection = "Delete Selection"
+	CommandToggleWireframe = "Toggle Wireframe"
+	CommandCycleShading    = "Cycle Shading"
+	CommandMeasureTool     = "Measure Tool"
+	CommandZoomExtents     = "Zoom Extents"
+	CommandCommandPalette  = "Command Palette"
+)
+
+// initCommands 登记编辑器命令及其快捷键, 在界面创建之后调用
+func (w *World) initCommands() {
+	w.Commands = shortcut.NewRegistry()
+	for _, cmd := range []*shortcut.Command{
+		{Name: CommandSaveScene, Shortcut: shortcut.MustParse("Ctrl+S"), Run: func() {
+			if err := w.SaveScene(); err != nil {
+				logger.Error(err)
+			}
+		}},
+		{Name: CommandDeleteSelection, Shortcut: shortcut.MustParse("Delete"), Run: w.DeleteSelection, Enabled: func() bool {
+			_, ok := w.uiWindowMain.SelectedModel().(model.RenderObj)
+			return ok
+		}},
+		{Name: CommandToggleWireframe, Shortcut: shortcut.MustParse("F"), Run: w.uiWindowMain.ToggleWireframe},
+		{Name: CommandCycleShading, Shortcut: shortcut.MustParse("Z"), Run: w.uiWindowMain.CycleShading},
+		{Name: CommandMeasureTool, Shortcut: shortcut.MustParse("M"), Run: w.uiWindowMain.ToggleMeasure},
+		{Name: CommandZoomExtents, Run: w.ZoomToExtent},
+		{Name: CommandCommandPalette, Shortcut: shortcut.MustParse("Ctrl+Space"), Run: w.uiWindowMain.ToggleCommandPalette},
+	} {
+		if err := w.Commands.Register(cmd); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+	w.uiWindowMain.SetCommands(w.Commands)
+}
+
+// DeleteSelection 从场景中移除并释放模型面板中选中的对象
+func (w *World) DeleteSelection() {
+	obj, ok := w.uiWindowMain.SelectedModel().(model.RenderObj)
+	if !ok || !w.RemoveObject(obj) {
+		return
+	}
+	if m, ok := obj.(*model.Model); ok && w.paintTool.Target() == m {
+		w.paintTool.SetTarget(nil)
+	}
+	if disposable, ok := obj.(interface{ Dispose() }); ok {
+		disposable.Dispose()
+	}
+}
+
+// SaveScene 把场景写回场景文件, 模型的位置和缩放, 放置和删除的模型以及编辑器相机的位置使用当前的状态
+// 样条工具生成的对象不保存
+func (w *World) SaveScene() error {
+	xmlWorld := *w.xmlWorld
+	xmlWorld.XMLModels.XMLModels = append([]config.XmlModel(nil), w.xmlWorld.XMLModels.XMLModels...)
+	for i := range xmlWorld.XMLModels.XMLModels {
+		xmlModel := &xmlWorld.XMLModels.XMLModels[i]
+		if xmlModel.Id == "" {
+			continue
+		}
+		if e, ok := w.FindById(xmlModel.Id); ok {
+			if m, ok := e.Obj.(*model.Model); ok {
+				xmlModel.Position = config.XmlXYZ{X: m.Position.X(), Y: m.Position.Y(), Z: m.Position.Z()}
+				xmlModel.Scale = config.XmlXYZ{X: m.Scale.X(), Y: m.Scale.Y(), Z: m.Scale.Z()}
+			}
+		}
+	}
+	if editor := w.Cameras.Get(xmlWorld.XMLCamera.Name); editor != nil {
+		xmlWorld.XMLCamera.XMLPosition = config.XmlXYZ{X: editor.Position.X(), Y: editor.Position.Y(), Z: editor.Position.Z()}
+		xmlWorld.XMLCamera.XMLTarget = config.XmlXYZ{X: editor.Target.X(), Y: editor.Target.Y(), Z: editor.Target.Z()}
+	}
+	if err := config.SaveWorld(w.worldFile, &xmlWorld); err != nil {
+		return err
+	}
+	logger.Info("saved scene " + w.worldFile)
+	return nil
+}
+
+// removeXmlModel 从场景配置中移除 Id 为 id 的模型, 保存场景时不再写入
+func (w *World) removeXmlModel(id string) {
+	if id == "" {
+		return
+	}
+	models := w.xmlWorld.XMLModels.XMLModels
+	for i := range models {
+		if models[i].Id == id {
+			w.xmlWorld.XMLModels.XMLModels = append(models[:i], models[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/xml"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// XmlAudio 音频设置, bank 为相对于 resource 的事件库文件
+type XmlAudio struct {
+	Bank string `xml:"bank"`
+}
+
+// XmlAudioBank 音频事件库, 游戏代码按事件名播放而不直接管理音频数据
+type XmlAudioBank struct {
+	XMLName xml.Name        `xml:"bank"`
+	Events  []XmlAudioEvent `xml:"event"`
+}
+
+// XmlAudioEvent 命名的音频事件, 每次播放随机选择一个片段, 音量和音调在范围内随机
+type XmlAudioEvent struct {
+	Name        string   `xml:"name,attr"`
+	Clips       []string `xml:"clip"` // 相对于事件库文件的 WAV 文件
+	Volume      XmlRange `xml:"volume"`
+	Pitch       XmlRange `xml:"pitch"`
+	MinDistance float32  `xml:"mindistance"` // 小于该距离时音量不衰减
+	MaxDistance float32  `xml:"maxdistance"` // 可听范围, 0 表示不随距离衰减的二维声音
+	Loop        bool     `xml:"loop"`
+	MaxVoices   int      `xml:"maxvoices"` // 同时播放的最大数量, 超出时停止最早的
+}
+
+// XmlRange 取值范围, 都为 0 时使用默认值
+type XmlRange struct {
+	Min float32 `xml:"min,attr"`
+	Max float32 `xml:"max,attr"`
+}
+
+func LoadAudioBank(file string) (*XmlAudioBank, error) {
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	bank := &XmlAudioBank{}
+	if err := xml.Unmarshal(data, bank); err != nil {
+		return nil, err
+	}
+	return bank, nil
+}
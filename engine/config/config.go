@@ -2,16 +2,188 @@ package config
 
 import "github.com/go-gl/mathgl/mgl32"
 
+// ShadingMode 视口着色模式
+type ShadingMode int32
+
+const (
+	ShadingWireframe ShadingMode = iota // 线框
+	ShadingSolid                        // 纯色, 不计算光照
+	ShadingMaterial                     // 材质预览, 计算光照但不做屏幕空间效果
+	ShadingRendered                     // 完整渲染
+)
+
+var ShadingModeNames = []string{"Wireframe", "Solid", "Material", "Rendered"}
+
+// DebugView 调试视图, 除线框外替换对象自身的着色
+type DebugView int32
+
+const (
+	DebugViewNone      DebugView = iota
+	DebugViewNormals             // 世界空间法线
+	DebugViewUVChecker           // 按第一套UV绘制棋盘格
+	DebugViewDepth               // 线性深度
+	DebugViewOverdraw            // 每个像素被绘制的次数
+	DebugViewLighting            // 白色材质, 只显示光照
+	DebugViewWireframe           // 在正常渲染上叠加线框
+)
+
+var DebugViewNames = []string{"None", "Normals", "UV Checker", "Depth", "Overdraw", "Lighting Only", "Wireframe"}
+
+// AntiAliasing 抗锯齿方式
+type AntiAliasing int32
+
+const (
+	AntiAliasingNone AntiAliasing = iota
+	AntiAliasingMSAA              // 多重采样, 需要窗口或离屏缓冲支持
+	AntiAliasingFXAA              // 后处理快速近似抗锯齿
+)
+
+var AntiAliasingNames = []string{"None", "MSAA", "FXAA"}
+
+// FogMode 雾浓度随距离变化的方式
+type FogMode int32
+
+const (
+	FogLinear FogMode = iota // 在 Start 和 End 之间线性变化
+	FogExp                   // 指数
+	FogExp2                  // 指数平方
+)
+
+var FogModeNames = []string{"Linear", "Exp", "Exp2"}
+
+// FogConfig 距离雾和高度雾参数
+type FogConfig struct {
+	Enable        bool
+	Mode          FogMode
+	Color         mgl32.Vec3
+	Density       float32 // 指数雾浓度
+	Start         float32 // 雾开始的距离
+	End           float32 // 线性雾完全覆盖的距离
+	Height        float32 // 高度雾基准高度, 低于此高度雾浓度不衰减
+	HeightFalloff float32 // 高于基准高度后的衰减速率, 0 表示不使用高度雾
+}
+
+// SSAOConfig 屏幕空间环境光遮蔽参数
+type SSAOConfig struct {
+	Enable     bool
+	Radius     float32 // 采样半球半径(观察空间)
+	Bias       float32 // 深度比较偏移, 防止自遮挡产生条纹
+	KernelSize int32   // 采样核数量
+}
+
+// OutlineConfig 选中对象的轮廓
+type OutlineConfig struct {
+	Enable bool
+	Color  mgl32.Vec4
+	Width  float32 // 像素宽度
+}
+
+// BoundsConfig 包围体可视化
+type BoundsConfig struct {
+	// 绘制对象的包围盒
+	Show bool
+	// 同时绘制包围球
+	Spheres bool
+	// 模型按网格分别绘制
+	Meshes bool
+}
+
+// TextureConfig 贴图的默认采样设置, 调用方没有单独指定时使用
+type TextureConfig struct {
+	// 各向异性过滤的级别, 不大于 1 时不使用
+	Anisotropy float32
+	// 颜色贴图按 sRGB 上传, 采样时转换为线性颜色
+	SRGB bool
+	// 上传后生成 mipmap
+	Mipmap bool
+}
+
 var Config = struct {
 	WindowWidth  int32
 	WindowHeight int32
 	ClearColor   mgl32.Vec4
+	// 相机默认的垂直视角(度)和近远裁剪面, 场景的相机配置可以覆盖
+	FieldOfView  float32
 	ClipNear     float32
 	ClipFar      float32
+	ShadingMode  ShadingMode
+	DebugView    DebugView
+	SSAO         SSAOConfig
+	Fog          FogConfig
+	AntiAliasing AntiAliasing
+	MSAASamples  int32
+	// 界面缩放, 0表示跟随显示器缩放
+	UIScale float32
+	// 屏幕中心显示十字准星
+	Crosshair bool
+	// 在视口中描出模型列表中选中的对象
+	Outline OutlineConfig
+
+	// 剔除视锥外的对象
+	FrustumCulling bool
+	// 使用硬件遮挡查询剔除被遮挡的对象
+	OcclusionCulling bool
+	// 冻结剔除使用的视锥, 相机移动时仍按冻结时的位置剔除, 用于检查剔除结果
+	FreezeCulling bool
+	// 绘制包围体, 被剔除的对象使用不同颜色
+	Bounds BoundsConfig
+
+	// 模型在后台任务中加载, 启动时不等待, 加载完成前显示进度
+	AsyncLoading bool
+	// 修改着色器源文件后自动重新编译, 修改模型文件或场景文件后重新加载模型
+	HotReload bool
+	// 贴图的默认采样设置
+	Texture TextureConfig
 }{
 	WindowWidth:  1200.0,
 	WindowHeight: 800.0,
 	ClearColor:   mgl32.Vec4{0.0, 0.0, 0.0, 0.0},
+	FieldOfView:  45,
 	ClipNear:     0.1,
 	ClipFar:      500,
+	ShadingMode:  ShadingRendered,
+	SSAO: SSAOConfig{
+		Enable:     true,
+		Radius:     0.5,
+		Bias:       0.025,
+		KernelSize: 32,
+	},
+	Fog: FogConfig{
+		Mode:    FogExp,
+		Color:   mgl32.Vec3{0.5, 0.55, 0.6},
+		Density: 0.01,
+		Start:   20,
+		End:     200,
+	},
+	AntiAliasing: AntiAliasingMSAA,
+	MSAASamples:  4,
+	Outline: OutlineConfig{
+		Enable: true,
+		Color:  mgl32.Vec4{1, 0.6, 0.1, 1},
+		Width:  3,
+	},
+
+	FrustumCulling: true,
+	Texture: TextureConfig{
+		Anisotropy: 8,
+		Mipmap:     true,
+	},
+}
+
+// BackgroundColor 清屏颜色, 开启雾时使用雾的颜色, 远处的物体与背景融为一体
+func BackgroundColor() mgl32.Vec3 {
+	if Config.Fog.Enable {
+		return Config.Fog.Color
+	}
+	return Config.ClearColor.Vec3()
+}
+
+// SSAOActive 仅在完整渲染模式下计算环境光遮蔽
+func SSAOActive() bool {
+	return Config.SSAO.Enable && Config.ShadingMode == ShadingRendered && !DebugViewReplacesShading()
+}
+
+// DebugViewReplacesShading 调试视图是否替换对象自身的着色, 此时不做屏幕空间效果和后处理
+func DebugViewReplacesShading() bool {
+	return Config.DebugView != DebugViewNone && Config.DebugView != DebugViewWireframe
 }
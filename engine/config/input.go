@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding/xml"
+	"os"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// XmlInput 输入设置, bindings 为相对于 resource 的按键绑定文件, 没有配置时使用默认绑定
+type XmlInput struct {
+	Bindings string `xml:"bindings"`
+}
+
+// XmlInputMap 按键绑定文件, 文件中的动作替换同名的默认动作
+type XmlInputMap struct {
+	XMLName xml.Name         `xml:"input"`
+	Actions []XmlInputAction `xml:"action"`
+}
+
+type XmlInputAction struct {
+	Name     string            `xml:"name,attr"`
+	Bindings []XmlInputBinding `xml:"bind"`
+}
+
+// XmlInputBinding 一个绑定, device 为 key, mouse, mouseaxis, gamepad 或 gamepadaxis, 内容为按键或轴的名称
+// scale 为作为轴使用时的值或倍率, 0 表示 1
+type XmlInputBinding struct {
+	Device string  `xml:"device,attr"`
+	Scale  float32 `xml:"scale,attr,omitempty"`
+	Name   string  `xml:",chardata"`
+}
+
+func LoadInputMap(file string) (*XmlInputMap, error) {
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	inputMap := &XmlInputMap{}
+	if err := xml.Unmarshal(data, inputMap); err != nil {
+		return nil, err
+	}
+	return inputMap, nil
+}
+
+// SaveInputMap 把绑定写到本地文件, 资源路径写到优先级最高的资源目录
+func SaveInputMap(file string, inputMap *XmlInputMap) error {
+	data, err := xml.MarshalIndent(inputMap, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(vfs.WritePath(file), append([]byte(xml.Header), data...), 0644)
+}
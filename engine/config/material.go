@@ -0,0 +1,33 @@
+package config
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// ReadMaterial 读取单独的材质文件, .json 按 JSON 解析, 其他按 XML 解析, 根元素为 <material>
+// JSON 的字段名与 XML 的元素名相同, 例如 {"diffuse": {"r": 1, "g": 0.5, "b": 0}, "shininess": 32}
+func ReadMaterial(file string) (XmlMaterial, error) {
+	var xmlMaterial XmlMaterial
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return xmlMaterial, err
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = json.Unmarshal(data, &xmlMaterial)
+	} else {
+		err = xml.Unmarshal(data, &xmlMaterial)
+	}
+	if err != nil {
+		return xmlMaterial, fmt.Errorf("%s: %w", file, err)
+	}
+	if xmlMaterial.Ref != "" {
+		return xmlMaterial, fmt.Errorf("%s: a material file cannot reference another material", file)
+	}
+	return xmlMaterial, nil
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/xml"
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
-	"io/ioutil"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+	"os"
+	"strings"
 )
 
 type XmlRGB struct {
@@ -40,8 +43,85 @@ func (xyz *XmlXYZ) XYZW() mgl32.Vec4 {
 type XmlTarget = XmlXYZ
 
 type XmlCamera struct {
+	Name string `xml:"name,attr"`
+
 	XMLPosition XmlXYZ `xml:"position"`
 	XMLTarget   XmlXYZ `xml:"target"`
+
+	XMLClearColor  *XmlRGB               `xml:"clearcolor"`
+	XMLPostProcess *XmlCameraPostProcess `xml:"postprocess"`
+	XMLFly         *XmlCameraFly         `xml:"fly"`
+	XMLFollow      *XmlCameraFollow      `xml:"follow"`
+	XMLPath        *XmlCameraPath        `xml:"path"`
+	XMLSmoothing   *XmlCameraSmoothing   `xml:"smoothing"`
+	XMLControls    *XmlCameraControls    `xml:"controls"`
+
+	// 垂直视角(度)和近远裁剪面, 为 0 时使用全局配置
+	XMLFov  float32 `xml:"fov"`
+	XMLNear float32 `xml:"near"`
+	XMLFar  float32 `xml:"far"`
+}
+
+// XmlCameraFollow 跟随对象, target 为对象的名称或 Id, damping 为 0 时没有延迟
+// local 为 true 时偏移随对象旋转, freelook 为 true 时不看向对象, 保持观察方向
+type XmlCameraFollow struct {
+	Target        string  `xml:"target"`
+	XMLOffset     *XmlXYZ `xml:"offset"`
+	Local         bool    `xml:"local"`
+	Damping       float32 `xml:"damping"`
+	LookDamping   float32 `xml:"lookdamping"`
+	XMLLookOffset *XmlXYZ `xml:"lookoffset"`
+	FreeLook      bool    `xml:"freelook"`
+}
+
+// XmlCameraPath 相机路径, 关键帧按 time(秒) 排序, easing 为 linear, in, out 或 inout
+type XmlCameraPath struct {
+	Loop     bool           `xml:"loop,attr"`
+	Speed    float32        `xml:"speed,attr"`
+	AutoPlay bool           `xml:"autoplay,attr"`
+	Keys     []XmlCameraKey `xml:"key"`
+}
+
+type XmlCameraKey struct {
+	Time        float32 `xml:"time,attr"`
+	Easing      string  `xml:"easing,attr"`
+	XMLPosition XmlXYZ  `xml:"position"`
+	XMLTarget   XmlXYZ  `xml:"target"`
+}
+
+// XmlCameraSmoothing 环绕控制的惯性阻尼(0 为立即响应)和缩放到范围, 切换相机的过渡时间(秒, 0 为立即跳转)
+type XmlCameraSmoothing struct {
+	Orbit      float32 `xml:"orbit"`
+	Transition float32 `xml:"transition"`
+}
+
+// XmlCameraControls 鼠标灵敏度和速度修饰键的倍率, 为 0 的项使用默认值
+// rotate, look 为每像素转动的角度(度), pan 为每像素平移的距离与相机到目标距离的比例, zoom 为滚轮每格缩放的比例
+// 按住 Shift 时速度乘以 fast, 按住 Ctrl 时乘以 slow
+type XmlCameraControls struct {
+	Rotate float32 `xml:"rotate"`
+	Pan    float32 `xml:"pan"`
+	Zoom   float32 `xml:"zoom"`
+	Look   float32 `xml:"look"`
+	Fast   float32 `xml:"fast"`
+	Slow   float32 `xml:"slow"`
+}
+
+// XmlCameraFly 飞行模式的最大速度(单位/秒)和加速度(单位/秒²)
+type XmlCameraFly struct {
+	Speed        float32 `xml:"speed"`
+	Acceleration float32 `xml:"acceleration"`
+}
+
+// XmlCameraPostProcess 相机的后处理设置, disable 关闭全部效果, effect 按名称覆盖单个效果
+type XmlCameraPostProcess struct {
+	Disable bool              `xml:"disable,attr"`
+	Effects []XmlCameraEffect `xml:"effect"`
+}
+
+type XmlCameraEffect struct {
+	Name   string `xml:"name,attr"`
+	Enable bool   `xml:"enable,attr"`
 }
 
 type XmlLightDiffuse struct {
@@ -64,6 +144,20 @@ type XmlLight struct {
 	XMLLightDiffuse  XmlLightDiffuse  `xml:"diffuse"`
 	XMLLightAmbient  XmlLightAmbient  `xml:"ambient"`
 	XMLLightSpecular XmlLightSpecular `xml:"specular"`
+	XMLShadow        *XmlShadow       `xml:"shadow"`
+}
+
+// XmlShadow 点光源的全向阴影
+type XmlShadow struct {
+	XMLEnable     bool    `xml:"enable"`
+	XMLResolution int32   `xml:"resolution"` // 立方体贴图每个面的像素尺寸
+	XMLBias       float32 `xml:"bias"`       // 距离比较的偏移, 防止自阴影条纹
+	XMLFar        float32 `xml:"far"`        // 阴影的最远距离
+}
+
+// XmlEnvironment 环境贴图, 等距柱状投影的 Radiance .hdr 文件
+type XmlEnvironment struct {
+	File string `xml:"file"`
 }
 
 type XmlLights struct {
@@ -75,15 +169,120 @@ type XmlMesh struct {
 	File string `xml:"file"` // Mesh file
 }
 type XmlShader struct {
-	VertFile string `xml:"vert"`
-	FragFile string `xml:"frag"`
+	// 内置着色器的名称, 例如 <shader builtin="pbr"/>, 指定时不需要 vert 和 frag
+	Builtin  string `xml:"builtin,attr,omitempty"`
+	VertFile string `xml:"vert,omitempty"`
+	FragFile string `xml:"frag,omitempty"`
+	// 编译时插入的宏, 同一份源码按宏的组合编译为不同的变体
+	Defines []XmlDefine `xml:"define"`
+}
+
+// XmlDefine 着色器宏, value 为空时只定义名称
+type XmlDefine struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr,omitempty"`
+}
+
+type XmlDetail struct {
+	Albedo string  `xml:"albedo"`
+	Normal string  `xml:"normal"`
+	Tiling float32 `xml:"tiling"`
+	UVSet  int32   `xml:"uvset"`
+}
+
+// XmlEmissive 自发光, 颜色乘以强度后叠加到光照结果上, 超过 Bloom 阈值的部分产生辉光
+type XmlEmissive struct {
+	Color     XmlRGB  `xml:"color"`
+	Intensity float32 `xml:"intensity"` // 默认为1
+	Texture   string  `xml:"texture"`   // 自发光贴图, 相对于模型目录, 与颜色相乘
 }
 
 type XmlMaterial struct {
-	AmbientColor  XmlRGB  `xml:"ambient"`
-	DiffuseColor  XmlRGB  `xml:"diffuse"`
-	SpecularColor XmlRGB  `xml:"specular"`
+	// 引用材质文件中定义的共享材质, 例如 <material ref="brick"/>, 指定时只使用 tint, roughness 和 opacity
+	Ref string `xml:"ref,attr,omitempty" json:"-"`
+	// 材质实例覆盖的属性: 与漫反射颜色相乘的颜色和 0 到 1 的粗糙度, 与 opacity 一起覆盖引用的材质或这里定义的材质
+	Tint      *XmlRGB  `xml:"tint" json:"-"`
+	Roughness *float32 `xml:"roughness" json:"-"`
+
+	AmbientColor  XmlRGB  `xml:"ambient" json:"ambient"`
+	DiffuseColor  XmlRGB  `xml:"diffuse" json:"diffuse"`
+	SpecularColor XmlRGB  `xml:"specular" json:"specular"`
 	Shininess     float32 `xml:"shininess"`
+
+	Blend   string   `xml:"blend"`   // opaque 或 alpha
+	Opacity *float32 `xml:"opacity"` // 不透明度, 默认为1
+
+	Detail   *XmlDetail   `xml:"detail"`
+	Emissive *XmlEmissive `xml:"emissive"`
+	Flipbook *XmlFlipbook `xml:"flipbook"`
+	UV       *XmlUV       `xml:"uv"`
+}
+
+// XmlUV 纹理坐标变换, rotation 和 spin 单位为度, scroll 和 spin 为每秒的变化量
+type XmlUV struct {
+	Offset   XmlXYZ  `xml:"offset"`
+	Scale    *XmlXYZ `xml:"scale"`
+	Rotation float32 `xml:"rotation"`
+	Scroll   XmlXYZ  `xml:"scroll"`
+	Spin     float32 `xml:"spin"`
+}
+
+// XmlFlipbook 序列帧动画, loop 为 loop, once 或 pingpong
+type XmlFlipbook struct {
+	Texture string  `xml:"texture"`
+	Columns int32   `xml:"columns"`
+	Rows    int32   `xml:"rows"`
+	Frames  int32   `xml:"frames"`
+	FPS     float32 `xml:"fps"`
+	Loop    string  `xml:"loop"`
+	Blend   bool    `xml:"blend"`
+}
+
+// XmlNormalize 导入时将模型平移到包围盒中心并缩放到指定大小
+type XmlNormalize struct {
+	Size float32 `xml:"size"` // 包围盒最长边的目标长度
+}
+
+// XmlMaterialSlot 覆盖模型文件中指定材质槽的材质
+type XmlMaterialSlot struct {
+	Slot int    `xml:"slot,attr"`
+	Name string `xml:"name,attr"`
+	XmlMaterial
+}
+
+// XmlTerrain 地形高度图网格
+type XmlTerrain struct {
+	Size     int     `xml:"size"`     // 每边顶点数
+	CellSize float32 `xml:"cellsize"` // 顶点间距
+
+	LOD *XmlTerrainLOD `xml:"lod"`
+}
+
+// XmlTerrainLOD 地形分块细节层级
+type XmlTerrainLOD struct {
+	Disable   bool    `xml:"disable"`
+	ChunkSize int     `xml:"chunksize"` // 每块的格子数
+	Levels    int     `xml:"levels"`
+	Distance  float32 `xml:"distance"` // 第一级切换距离, 之后每级翻倍
+	Skirt     float32 `xml:"skirt"`    // 裙边深度
+}
+
+// XmlReflection 地面平面反射
+type XmlReflection struct {
+	Enable     bool    `xml:"enable"`
+	Strength   float32 `xml:"strength"`   // 反射强度
+	Blur       float32 `xml:"blur"`       // 模糊半径(像素)
+	Fresnel    float32 `xml:"fresnel"`    // 菲涅尔效果的权重, 0 表示各角度反射强度相同
+	Resolution float32 `xml:"resolution"` // 反射纹理相对屏幕的分辨率
+}
+
+// XmlLightmap 静态模型的光照贴图, 由 -bake 烘焙, 替代点光源的环境光项
+type XmlLightmap struct {
+	Texture   string  `xml:"texture"`   // 相对于模型目录, 默认 lightmap_<id>.png
+	Size      int32   `xml:"size"`      // 贴图边长, 默认 512
+	Intensity float32 `xml:"intensity"` // 默认为1
+	Samples   int32   `xml:"samples"`   // 每个像素的半球采样数, 默认 64
+	FileUV    bool    `xml:"fileuv"`    // 使用模型文件中的第二套UV, 否则加载时按三角形重新打包
 }
 
 type XmlModel struct {
@@ -91,12 +290,119 @@ type XmlModel struct {
 
 	Name            string      `xml:"name"`
 	Id              string      `xml:"id"`
+	Tags            []string    `xml:"tags>tag"` // 查询用的标签, 见 World.FindByTag
 	Position        XmlXYZ      `xml:"position"`
 	Scale           XmlXYZ      `xml:"scale"`
 	Mesh            XmlMesh     `xml:"mesh"`
 	Shader          XmlShader   `xml:"shader"`
 	GammaCorrection bool        `xml:"gammacorrection"`
 	Material        XmlMaterial `xml:"material"`
+
+	Materials  []XmlMaterialSlot `xml:"materials>material"`
+	Normalize  *XmlNormalize     `xml:"normalize"`
+	Terrain    *XmlTerrain       `xml:"terrain"`
+	Reflection *XmlReflection    `xml:"reflection"`
+	Billboard  *XmlBillboard     `xml:"billboard"`
+	Water      *XmlWater         `xml:"water"`
+	Vegetation *XmlVegetation    `xml:"vegetation"`
+	Crowd      *XmlCrowd         `xml:"crowd"`
+	Lightmap   *XmlLightmap      `xml:"lightmap"`
+
+	// 绘制顺序, 小的先绘制; alwaysontop 关闭深度测试绘制在最上层
+	RenderOrder int  `xml:"renderorder"`
+	AlwaysOnTop bool `xml:"alwaysontop"`
+	// 绘制阶段, 例如 DepthPrepass, Shadow, Main, Outline, 为空时为 Shadow 和 Main
+	Passes []string `xml:"passes>pass"`
+	// 自定义着色器的参数, 绘制时按名称设置 uniform
+	Params []XmlParam `xml:"params>param"`
+}
+
+// XmlParam 着色器参数, type 为 float, int, bool, vec2, vec3, vec4, color 或 texture
+// 例如 <param name="gRimColor" type="color" value="1 0.5 0"/>, <param name="gNoise" type="texture" value="noise.png"/>
+// 贴图路径相对于模型目录, srgb 为 true 时按颜色贴图上传
+type XmlParam struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	SRGB  bool   `xml:"srgb,attr,omitempty"`
+}
+
+// XmlVegetation 植被, mesh 为空时使用内置的草丛网格, 贴图和密度图相对于模型目录
+type XmlVegetation struct {
+	Size        float32      `xml:"size"`       // 散布区域半宽, 以 position 为中心
+	Density     float32      `xml:"density"`    // 每平方单位的实例数
+	DensityMap  string       `xml:"densitymap"` // 灰度图, 白色为最大密度
+	Seed        int64        `xml:"seed"`
+	ScaleRange  XmlRange     `xml:"scale"`
+	Texture     string       `xml:"texture"`
+	Color       *XmlRGB      `xml:"color"`
+	AlphaCutoff float32      `xml:"alphacutoff"`
+	Wind        *XmlWind     `xml:"wind"`
+	Fade        *XmlRange    `xml:"fade"` // 开始淡出和完全消失的距离
+	Impostor    *XmlImpostor `xml:"impostor"`
+}
+
+// XmlImpostor 远处的实例替换为烘焙的面向相机的四边形
+type XmlImpostor struct {
+	Distance   float32 `xml:"distance"`   // 完全替换为替身的距离
+	Blend      float32 `xml:"blend"`      // 在此之前的过渡距离
+	Frames     int     `xml:"frames"`     // 绕 Y 轴烘焙的视角数
+	Resolution int32   `xml:"resolution"` // 每个视角的像素尺寸
+}
+
+// XmlCrowd 蒙皮角色群, 使用内置的人形角色和行走动画
+type XmlCrowd struct {
+	Size    float32  `xml:"size"` // 活动区域半宽, 以 position 为中心
+	Count   int      `xml:"count"`
+	Seed    int64    `xml:"seed"`
+	Scale   float32  `xml:"scale"`
+	Speed   XmlRange `xml:"speed"`   // 动画速度的随机范围
+	InPlace bool     `xml:"inplace"` // 原地行走
+}
+
+// XmlWind 风, direction 使用 x 和 z
+type XmlWind struct {
+	Direction XmlXYZ  `xml:"direction"`
+	Strength  float32 `xml:"strength"`
+	Speed     float32 `xml:"speed"`
+	Frequency float32 `xml:"frequency"` // 相位沿风向变化的频率, 决定阵风的尺度
+}
+
+// XmlWater 水面, 反射参数使用模型的 reflection, 法线贴图相对于模型目录
+type XmlWater struct {
+	Size         float32   `xml:"size"`       // 半宽
+	Resolution   int       `xml:"resolution"` // 每边格子数
+	ShallowColor *XmlRGB   `xml:"shallowcolor"`
+	DeepColor    *XmlRGB   `xml:"deepcolor"`
+	Clarity      float32   `xml:"clarity"` // 颜色达到深水颜色的深度
+	Refraction   *bool     `xml:"refraction"`
+	Distortion   float32   `xml:"distortion"`
+	NormalMap    string    `xml:"normalmap"`
+	NormalTiling float32   `xml:"normaltiling"`
+	NormalScroll XmlXYZ    `xml:"normalscroll"` // x, z 为滚动速度
+	Waves        []XmlWave `xml:"waves>wave"`
+}
+
+// XmlWave Gerstner 波, direction 使用 x 和 z
+type XmlWave struct {
+	Direction  XmlXYZ  `xml:"direction"`
+	Amplitude  float32 `xml:"amplitude"`
+	Wavelength float32 `xml:"wavelength"`
+	Steepness  float32 `xml:"steepness"` // 0 为正弦波, 1 为最尖的波峰
+	Speed      float32 `xml:"speed"`     // 相对于深水波速的倍数
+}
+
+// XmlBillboard 公告板, 贴图相对于模型目录, axis 非零时绕该轴旋转
+type XmlBillboard struct {
+	Texture string  `xml:"texture"`
+	Width   float32 `xml:"width"`
+	Height  float32 `xml:"height"`
+	Axis    XmlXYZ  `xml:"axis"`
+	Color   *XmlRGB `xml:"color"`
+	Blend   bool    `xml:"blend"`
+
+	// 贴图为图集时按序列帧播放, 忽略其中的 texture
+	Flipbook *XmlFlipbook `xml:"flipbook"`
 }
 
 type XmlModels struct {
@@ -105,22 +411,102 @@ type XmlModels struct {
 }
 
 type XmlWindow struct {
-	XMLName   xml.Name `xml:"window"`
-	XMLWidth  int32    `xml:"width"`
-	XMLHeight int32    `xml:"height"`
+	XMLName    xml.Name `xml:"window"`
+	XMLWidth   int32    `xml:"width"`
+	XMLHeight  int32    `xml:"height"`
+	XMLUIScale float32  `xml:"uiscale"`
+}
+
+type XmlSSAO struct {
+	XMLEnable     bool    `xml:"enable"`
+	XMLRadius     float32 `xml:"radius"`
+	XMLBias       float32 `xml:"bias"`
+	XMLKernelSize int32   `xml:"kernelsize"`
+}
+
+type XmlFog struct {
+	XMLEnable        bool    `xml:"enable"`
+	XMLMode          string  `xml:"mode"`
+	XMLColor         *XmlRGB `xml:"color"`
+	XMLDensity       float32 `xml:"density"`
+	XMLStart         float32 `xml:"start"`
+	XMLEnd           float32 `xml:"end"`
+	XMLHeight        float32 `xml:"height"`
+	XMLHeightFalloff float32 `xml:"heightfalloff"`
+}
+
+type XmlAntiAliasing struct {
+	XMLMode    string `xml:"mode"`
+	XMLSamples int32  `xml:"samples"`
+}
+
+type XmlRender struct {
+	XMLSSAO         *XmlSSAO         `xml:"ssao"`
+	XMLFog          *XmlFog          `xml:"fog"`
+	XMLAntiAliasing *XmlAntiAliasing `xml:"antialiasing"`
 }
 
 type XmlWorld struct {
 	XMLName   xml.Name  `xml:"world"`
 	XMLWindow XmlWindow `xml:"window"`
+	XMLRender XmlRender `xml:"render"`
 	XMLCamera XmlCamera `xml:"camera"`
 	XMLLights XmlLights `xml:"lights"`
 	XMLModels XmlModels `xml:"models"`
+	// 其他可切换的相机, 如游戏相机和过场相机
+	XMLCameras []XmlCamera `xml:"cameras>camera"`
+
+	XMLEnvironment XmlEnvironment `xml:"environment"`
+	XMLAudio       XmlAudio       `xml:"audio"`
+	XMLInput       XmlInput       `xml:"input"`
+	XMLProfiler    *XmlProfiler   `xml:"profiler"`
+}
+
+// XmlProfiler 性能分析, 范围连续 frames 帧超出预算时报警
+type XmlProfiler struct {
+	XMLEnable  bool        `xml:"enable"`
+	XMLFrames  int         `xml:"frames"`
+	XMLBudgets []XmlBudget `xml:"budget"`
 }
 
+// XmlBudget 命名范围的每帧时间预算, 毫秒
+type XmlBudget struct {
+	Scope string  `xml:"scope,attr"`
+	Ms    float32 `xml:"ms,attr"`
+}
+
+// SaveWorld 把场景写到本地文件, 资源路径写到优先级最高的资源目录
+func SaveWorld(file string, xmlWorld *XmlWorld) error {
+	data, err := xml.MarshalIndent(xmlWorld, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(vfs.WritePath(file), append([]byte(xml.Header), data...), 0644)
+}
+
+// ReadWorld 读取场景文件, 不修改全局配置, 用于热重载
+func ReadWorld(file string) (*XmlWorld, error) {
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	xmlWorld := &XmlWorld{}
+	if err := xml.Unmarshal(data, xmlWorld); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return xmlWorld, nil
+}
+
+// DefaultWorldFile 内嵌的默认场景, 找不到场景文件时使用
+const DefaultWorldFile = "./resource/default/world.xml"
+
 func InitXML(file string) *XmlWorld {
 
-	data, err := ioutil.ReadFile("./resource/world.xml")
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("%v, fall back to %s", err, DefaultWorldFile))
+		data, err = vfs.ReadFile(DefaultWorldFile)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -134,6 +520,56 @@ func InitXML(file string) *XmlWorld {
 
 	Config.WindowWidth = xmlWorld.XMLWindow.XMLWidth
 	Config.WindowHeight = xmlWorld.XMLWindow.XMLHeight
+	if xmlWorld.XMLWindow.XMLUIScale > 0 {
+		Config.UIScale = xmlWorld.XMLWindow.XMLUIScale
+	}
+
+	if xmlSSAO := xmlWorld.XMLRender.XMLSSAO; xmlSSAO != nil {
+		Config.SSAO.Enable = xmlSSAO.XMLEnable
+		if xmlSSAO.XMLRadius > 0 {
+			Config.SSAO.Radius = xmlSSAO.XMLRadius
+		}
+		if xmlSSAO.XMLBias > 0 {
+			Config.SSAO.Bias = xmlSSAO.XMLBias
+		}
+		if xmlSSAO.XMLKernelSize > 0 {
+			Config.SSAO.KernelSize = xmlSSAO.XMLKernelSize
+		}
+	}
+
+	if xmlFog := xmlWorld.XMLRender.XMLFog; xmlFog != nil {
+		Config.Fog.Enable = xmlFog.XMLEnable
+		for i, name := range FogModeNames {
+			if strings.EqualFold(name, xmlFog.XMLMode) {
+				Config.Fog.Mode = FogMode(i)
+			}
+		}
+		if xmlFog.XMLColor != nil {
+			Config.Fog.Color = xmlFog.XMLColor.RGB()
+		}
+		if xmlFog.XMLDensity > 0 {
+			Config.Fog.Density = xmlFog.XMLDensity
+		}
+		if xmlFog.XMLStart > 0 {
+			Config.Fog.Start = xmlFog.XMLStart
+		}
+		if xmlFog.XMLEnd > 0 {
+			Config.Fog.End = xmlFog.XMLEnd
+		}
+		Config.Fog.Height = xmlFog.XMLHeight
+		Config.Fog.HeightFalloff = xmlFog.XMLHeightFalloff
+	}
+
+	if xmlAA := xmlWorld.XMLRender.XMLAntiAliasing; xmlAA != nil {
+		for i, name := range AntiAliasingNames {
+			if strings.EqualFold(name, xmlAA.XMLMode) {
+				Config.AntiAliasing = AntiAliasing(i)
+			}
+		}
+		if xmlAA.XMLSamples > 0 {
+			Config.MSAASamples = xmlAA.XMLSamples
+		}
+	}
 
 	return xmlWorld
 }
@@ -0,0 +1,182 @@
+package debugdraw
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/rhi"
+)
+
+// sphereSegments 包围球每个圆的分段数
+const sphereSegments = 32
+
+type vertex struct {
+	Position mgl32.Vec3
+	Color    mgl32.Vec3
+}
+
+// DebugDraw 调试线段渲染, 每帧收集线段, Flush 时一次性绘制并清空
+// 普通线段参与深度测试, Overlay 线段始终绘制在最上层
+type DebugDraw struct {
+	lines   []vertex
+	overlay []vertex
+
+	device          rhi.Device
+	linePipeline    rhi.Pipeline
+	overlayPipeline rhi.Pipeline
+	lineBuffer      rhi.Buffer
+	overlayBuffer   rhi.Buffer
+}
+
+func NewDebugDraw(device rhi.Device) (*DebugDraw, error) {
+	d := &DebugDraw{
+		lines:   make([]vertex, 0),
+		overlay: make([]vertex, 0),
+		device:  device,
+	}
+
+	var dummy vertex
+	desc := rhi.PipelineDesc{
+		VertFilePath: "./resource/shader/debug_line.vert",
+		FragFilePath: "./resource/shader/debug_line.frag",
+		Layout: rhi.VertexLayout{
+			Stride: int32(unsafe.Sizeof(dummy)),
+			Attributes: []rhi.VertexAttribute{
+				{Location: 0, Components: 3, Type: rhi.AttribFloat, Offset: 0},
+				{Location: 1, Components: 3, Type: rhi.AttribFloat, Offset: int(unsafe.Offsetof(dummy.Color))},
+			},
+		},
+		Topology:   rhi.TopologyLines,
+		DepthTest:  true,
+		DepthWrite: true,
+	}
+	var err error
+	if d.linePipeline, err = device.NewPipeline(desc); err != nil {
+		return nil, err
+	}
+	desc.DepthTest = false
+	if d.overlayPipeline, err = device.NewPipeline(desc); err != nil {
+		return nil, err
+	}
+
+	bufferDesc := rhi.BufferDesc{Type: rhi.BufferVertex, Usage: rhi.UsageStream}
+	if d.lineBuffer, err = device.NewBuffer(bufferDesc); err != nil {
+		return nil, err
+	}
+	if d.overlayBuffer, err = device.NewBuffer(bufferDesc); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DebugDraw) AddLine(from, to, color mgl32.Vec3) {
+	d.lines = append(d.lines, vertex{from, color}, vertex{to, color})
+}
+
+func (d *DebugDraw) AddOverlayLine(from, to, color mgl32.Vec3) {
+	d.overlay = append(d.overlay, vertex{from, color}, vertex{to, color})
+}
+
+// AddCross 在指定位置绘制三轴十字标记
+func (d *DebugDraw) AddCross(center mgl32.Vec3, size float32, color mgl32.Vec3) {
+	for i := 0; i < 3; i++ {
+		var axis mgl32.Vec3
+		axis[i] = size / 2
+		d.AddOverlayLine(center.Sub(axis), center.Add(axis), color)
+	}
+}
+
+// AddAABB 绘制包围盒线框
+func (d *DebugDraw) AddAABB(b geometry.AABB, color mgl32.Vec3) {
+	d.lines = appendAABB(d.lines, b, color)
+}
+
+// AddOverlayAABB 绘制在最上层的包围盒线框
+func (d *DebugDraw) AddOverlayAABB(b geometry.AABB, color mgl32.Vec3) {
+	d.overlay = appendAABB(d.overlay, b, color)
+}
+
+func appendAABB(vertices []vertex, b geometry.AABB, color mgl32.Vec3) []vertex {
+	if b.IsEmpty() {
+		return vertices
+	}
+	corners := b.Corners()
+	// Corners 按位索引, 第 axis 位为 1 表示该轴取最大值, 相差一位的两个顶点构成一条边
+	for i := 0; i < 8; i++ {
+		for axis := 0; axis < 3; axis++ {
+			if j := i | 1<<axis; j != i {
+				vertices = append(vertices, vertex{corners[i], color}, vertex{corners[j], color})
+			}
+		}
+	}
+	return vertices
+}
+
+// AddSphere 绘制包围球, 由三个坐标平面上的圆组成
+func (d *DebugDraw) AddSphere(s geometry.Sphere, color mgl32.Vec3) {
+	if s.Radius <= 0 {
+		return
+	}
+	for axis := 0; axis < 3; axis++ {
+		u, v := (axis+1)%3, (axis+2)%3
+		var prev mgl32.Vec3
+		for i := 0; i <= sphereSegments; i++ {
+			angle := float64(i) / sphereSegments * 2 * math.Pi
+			var p mgl32.Vec3
+			p[u] = float32(math.Cos(angle)) * s.Radius
+			p[v] = float32(math.Sin(angle)) * s.Radius
+			p = p.Add(s.Center)
+			if i > 0 {
+				d.AddLine(prev, p, color)
+			}
+			prev = p
+		}
+	}
+}
+
+// AddFrustum 绘制 projection * view 对应的视锥线框
+func (d *DebugDraw) AddFrustum(viewProjection mgl32.Mat4, color mgl32.Vec3) {
+	corners := geometry.FrustumCorners(viewProjection)
+	for i := 0; i < 4; i++ {
+		next := (i + 1) % 4
+		d.AddLine(corners[i], corners[next], color)
+		d.AddLine(corners[4+i], corners[4+next], color)
+		d.AddLine(corners[i], corners[4+i], color)
+	}
+}
+
+// Flush 绘制并清空本帧收集的线段
+func (d *DebugDraw) Flush(projection, view mgl32.Mat4) {
+	if len(d.lines) == 0 && len(d.overlay) == 0 {
+		return
+	}
+
+	d.draw(d.linePipeline, d.lineBuffer, d.lines, projection, view)
+	d.draw(d.overlayPipeline, d.overlayBuffer, d.overlay, projection, view)
+
+	d.lines = d.lines[:0]
+	d.overlay = d.overlay[:0]
+}
+
+func (d *DebugDraw) draw(pipeline rhi.Pipeline, buffer rhi.Buffer, vertices []vertex, projection, view mgl32.Mat4) {
+	if len(vertices) == 0 {
+		return
+	}
+	buffer.Upload(unsafe.Pointer(&vertices[0]), len(vertices)*int(unsafe.Sizeof(vertices[0])))
+	pipeline.SetUniform("projection", projection)
+	pipeline.SetUniform("view", view)
+	d.device.Draw(rhi.DrawCall{
+		Pipeline:     pipeline,
+		VertexBuffer: buffer,
+		Count:        int32(len(vertices)),
+	})
+}
+
+func (d *DebugDraw) Dispose() {
+	d.linePipeline.Dispose()
+	d.overlayPipeline.Dispose()
+	d.lineBuffer.Dispose()
+	d.overlayBuffer.Dispose()
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/huangxiaobo/toy-engine/engine/event"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+)
+
+// dropImageExtensions 拖入窗口时作为贴图处理的扩展名, 其他文件作为模型加载
+var dropImageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".bmp": true, ".gif": true,
+	".dds": true, ".ktx": true, ".ktx2": true,
+}
+
+// initDrop 处理拖入窗口的文件: 贴图替换选中模型的漫反射贴图, 其他文件作为模型加入场景
+func (w *World) initDrop() {
+	event.Subscribe(w.Events, func(e event.FileDropped) {
+		var err error
+		if dropImageExtensions[strings.ToLower(filepath.Ext(e.Path))] {
+			err = w.dropTexture(e.Path)
+		} else {
+			err = w.dropModel(e.Path, e.Position)
+		}
+		if err != nil {
+			logger.Error(err)
+		}
+	})
+}
+
+// dropModel 加载模型文件, 放在光标指向的地面或地形上并选中, 没有指向地面时放在相机的目标点
+// 拖入的模型不在场景文件中, 保存场景时不写入
+func (w *World) dropModel(path string, cursor [2]float32) error {
+	m, err := model.NewModelFromFile(path, 0)
+	if err != nil {
+		return err
+	}
+	w.dropped++
+	m.Id = fmt.Sprintf("dropped-%d", w.dropped)
+
+	position := w.Camera.Target
+	displaySize := w.platform.DisplaySize()
+	if ray, ok := w.screenRay(cursor, displaySize, w.Camera.Projection(), w.Camera.GetViewMatrix()); ok {
+		if hit, ok := w.placementHit(ray); ok {
+			position = hit
+		}
+	}
+	placeOnSurface(m, position, 0)
+
+	w.addRenderObj(m, m.Name, m.Id, nil)
+	w.uiWindowMain.SelectModel(m)
+	logger.Info("dropped model " + path)
+	return nil
+}
+
+// dropTexture 把贴图设为模型面板中选中模型的漫反射贴图
+func (w *World) dropTexture(path string) error {
+	m, ok := w.uiWindowMain.SelectedModel().(*model.Model)
+	if !ok {
+		return fmt.Errorf("dropped texture %s: select a model first", path)
+	}
+	if err := m.SetDiffuseTexture(path); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("dropped texture %s on %s", path, m.Name))
+	return nil
+}
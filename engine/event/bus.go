@@ -0,0 +1,92 @@
+package event
+
+import (
+	"reflect"
+	"sync"
+)
+
+type handler struct {
+	fn func(any)
+}
+
+// Bus 按事件类型分发的发布订阅系统, 订阅者在发布时按订阅顺序同步调用
+// 事件类型为任意值类型, 例如 WindowResized, 用户代码可以定义自己的事件类型
+type Bus struct {
+	mu       sync.Mutex
+	handlers map[reflect.Type][]*handler
+	// Post 提交, 等待 Dispatch 在主线程发布的事件
+	queue []func()
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[reflect.Type][]*handler)}
+}
+
+// Subscription 一次订阅, 用于取消订阅
+type Subscription struct {
+	bus *Bus
+	t   reflect.Type
+	h   *handler
+}
+
+// Unsubscribe 取消订阅, 正在进行的发布中尚未调用的 fn 也不再调用
+func (s Subscription) Unsubscribe() {
+	if s.bus == nil {
+		return
+	}
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	handlers := s.bus.handlers[s.t]
+	for i, h := range handlers {
+		if h == s.h {
+			s.bus.handlers[s.t] = append(handlers[:i:i], handlers[i+1:]...)
+			break
+		}
+	}
+	s.h.fn = nil
+}
+
+// Subscribe 订阅类型为 T 的事件
+func Subscribe[T any](b *Bus, fn func(e T)) Subscription {
+	t := reflect.TypeFor[T]()
+	h := &handler{fn: func(e any) { fn(e.(T)) }}
+	b.mu.Lock()
+	b.handlers[t] = append(b.handlers[t], h)
+	b.mu.Unlock()
+	return Subscription{bus: b, t: t, h: h}
+}
+
+// Publish 立即在当前协程调用 T 的所有订阅者, 订阅者中可以发布其他事件和订阅, 取消订阅
+func Publish[T any](b *Bus, e T) {
+	b.mu.Lock()
+	handlers := append([]*handler(nil), b.handlers[reflect.TypeFor[T]()]...)
+	b.mu.Unlock()
+	for _, h := range handlers {
+		b.mu.Lock()
+		fn := h.fn
+		b.mu.Unlock()
+		if fn != nil {
+			fn(e)
+		}
+	}
+}
+
+// Post 提交事件, 在下一次 Dispatch 时发布, 可以在任意协程调用
+// 后台协程的事件(如资源重新加载完成)用它交给主线程, 订阅者中可以安全地调用 OpenGL
+func Post[T any](b *Bus, e T) {
+	b.mu.Lock()
+	b.queue = append(b.queue, func() { Publish(b, e) })
+	b.mu.Unlock()
+}
+
+// Dispatch 按提交顺序发布 Post 提交的事件, 由 World 每帧在主线程调用
+// 发布过程中新提交的事件留到下一次
+func (b *Bus) Dispatch() {
+	b.mu.Lock()
+	queue := b.queue
+	b.queue = nil
+	b.mu.Unlock()
+	for _, publish := range queue {
+		publish()
+	}
+}
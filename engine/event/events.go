@@ -0,0 +1,61 @@
+package event
+
+import "github.com/huangxiaobo/toy-engine/engine/input"
+
+// WindowResized 窗口大小改变, Size 为窗口坐标, FramebufferSize 为像素
+type WindowResized struct {
+	Size            [2]float32
+	FramebufferSize [2]float32
+}
+
+// Key 按键按下或松开, Repeat 为按住不放时系统产生的重复按下
+// 界面正在接收文本输入时也会发布, 需要时用 Input.KeyboardCaptured 判断
+type Key struct {
+	Key    input.Key
+	Down   bool
+	Repeat bool
+}
+
+// ObjectAdded 场景中加入了对象
+type ObjectAdded struct {
+	Id   string
+	Name string
+	Obj  interface{}
+}
+
+// ObjectRemoved 对象从场景中移除
+type ObjectRemoved struct {
+	Id   string
+	Name string
+	Obj  interface{}
+}
+
+// FileDropped 文件被拖放到窗口上, Path 为磁盘上的路径, Position 为松开时光标的窗口坐标
+type FileDropped struct {
+	Path     string
+	Position [2]float32
+}
+
+// AssetKind 重新加载的资源类型
+type AssetKind int
+
+const (
+	AssetShader AssetKind = iota
+	AssetModel
+	AssetTexture
+	AssetMaterial
+)
+
+// AssetReloaded 资源文件修改后重新加载完成, Path 为资源路径
+type AssetReloaded struct {
+	Kind AssetKind
+	Path string
+}
+
+// LoadProgress 异步加载的进度, Name 为刚加载完成(或失败)的对象, 全部完成时 Loaded == Total
+type LoadProgress struct {
+	Loaded int
+	Total  int
+	Name   string
+	Err    error
+}
@@ -0,0 +1,204 @@
+package framebuffer
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// ColorFormat 颜色附件格式
+type ColorFormat struct {
+	InternalFormat int32
+	Format         uint32
+	Type           uint32
+}
+
+var (
+	RGBA8   = ColorFormat{InternalFormat: gl.RGBA8, Format: gl.RGBA, Type: gl.UNSIGNED_BYTE}
+	RGBA16F = ColorFormat{InternalFormat: gl.RGBA16F, Format: gl.RGBA, Type: gl.FLOAT}
+	RGB16F  = ColorFormat{InternalFormat: gl.RGB16F, Format: gl.RGB, Type: gl.FLOAT}
+	R16F    = ColorFormat{InternalFormat: gl.R16F, Format: gl.RED, Type: gl.FLOAT}
+	RG16F   = ColorFormat{InternalFormat: gl.RG16F, Format: gl.RG, Type: gl.FLOAT}
+)
+
+// FrameBuffer 离屏渲染目标, 颜色附件和深度附件都使用纹理, 便于后续pass采样
+// 多重采样的渲染目标使用渲染缓冲作为附件, 需要 BlitTo 解析到普通渲染目标后才能采样
+type FrameBuffer struct {
+	Width   int32
+	Height  int32
+	Samples int32
+
+	Fbo           uint32
+	ColorTextures []uint32
+	DepthTexture  uint32
+
+	colorRenderBuffers []uint32
+	depthRenderBuffer  uint32
+
+	formats  []ColorFormat
+	hasDepth bool
+}
+
+func NewFrameBuffer(width, height int32, hasDepth bool, formats ...ColorFormat) (*FrameBuffer, error) {
+	fb := &FrameBuffer{
+		formats:  formats,
+		hasDepth: hasDepth,
+	}
+	if err := fb.Init(width, height); err != nil {
+		return nil, err
+	}
+	return fb, nil
+}
+
+// NewMultisampleFrameBuffer 多重采样渲染目标, 用于MSAA
+func NewMultisampleFrameBuffer(width, height, samples int32, formats ...ColorFormat) (*FrameBuffer, error) {
+	fb := &FrameBuffer{
+		Samples:  samples,
+		formats:  formats,
+		hasDepth: true,
+	}
+	if err := fb.Init(width, height); err != nil {
+		return nil, err
+	}
+	return fb, nil
+}
+
+func (fb *FrameBuffer) Init(width, height int32) error {
+	fb.Width = width
+	fb.Height = height
+
+	gl.GenFramebuffers(1, &fb.Fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.Fbo)
+
+	if fb.Samples > 0 {
+		return fb.initMultisample()
+	}
+
+	fb.ColorTextures = make([]uint32, len(fb.formats))
+	drawBuffers := make([]uint32, len(fb.formats))
+	for i, format := range fb.formats {
+		gl.GenTextures(1, &fb.ColorTextures[i])
+		gl.BindTexture(gl.TEXTURE_2D, fb.ColorTextures[i])
+		gl.TexImage2D(gl.TEXTURE_2D, 0, format.InternalFormat, width, height, 0, format.Format, format.Type, nil)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0+uint32(i), gl.TEXTURE_2D, fb.ColorTextures[i], 0)
+		drawBuffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+	}
+	if len(drawBuffers) > 0 {
+		gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+	} else {
+		gl.DrawBuffer(gl.NONE)
+		gl.ReadBuffer(gl.NONE)
+	}
+
+	if fb.hasDepth {
+		gl.GenTextures(1, &fb.DepthTexture)
+		gl.BindTexture(gl.TEXTURE_2D, fb.DepthTexture)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH_COMPONENT24, width, height, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, fb.DepthTexture, 0)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		return fmt.Errorf("framebuffer incomplete: 0x%x", status)
+	}
+	return nil
+}
+
+func (fb *FrameBuffer) initMultisample() error {
+	fb.colorRenderBuffers = make([]uint32, len(fb.formats))
+	drawBuffers := make([]uint32, len(fb.formats))
+	for i, format := range fb.formats {
+		gl.GenRenderbuffers(1, &fb.colorRenderBuffers[i])
+		gl.BindRenderbuffer(gl.RENDERBUFFER, fb.colorRenderBuffers[i])
+		gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, fb.Samples, uint32(format.InternalFormat), fb.Width, fb.Height)
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0+uint32(i), gl.RENDERBUFFER, fb.colorRenderBuffers[i])
+		drawBuffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+	}
+	if len(drawBuffers) > 0 {
+		gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+	}
+
+	if fb.hasDepth {
+		gl.GenRenderbuffers(1, &fb.depthRenderBuffer)
+		gl.BindRenderbuffer(gl.RENDERBUFFER, fb.depthRenderBuffer)
+		gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, fb.Samples, gl.DEPTH_COMPONENT24, fb.Width, fb.Height)
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, fb.depthRenderBuffer)
+	}
+	gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		return fmt.Errorf("multisample framebuffer incomplete: 0x%x", status)
+	}
+	return nil
+}
+
+// BlitTo 将颜色和深度复制到目标渲染目标, 多重采样的渲染目标在此解析
+func (fb *FrameBuffer) BlitTo(dst *FrameBuffer) {
+	mask := uint32(gl.COLOR_BUFFER_BIT)
+	if fb.hasDepth && dst.hasDepth {
+		mask |= gl.DEPTH_BUFFER_BIT
+	}
+
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fb.Fbo)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, dst.Fbo)
+	gl.BlitFramebuffer(0, 0, fb.Width, fb.Height, 0, 0, dst.Width, dst.Height, mask, gl.NEAREST)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+}
+
+// Resize 窗口大小变化时重建附件
+func (fb *FrameBuffer) Resize(width, height int32) error {
+	if width == fb.Width && height == fb.Height {
+		return nil
+	}
+	fb.Dispose()
+	return fb.Init(width, height)
+}
+
+// Bind 绑定为当前渲染目标, 同时设置视口
+func (fb *FrameBuffer) Bind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.Fbo)
+	gl.Viewport(0, 0, fb.Width, fb.Height)
+}
+
+func (fb *FrameBuffer) UnBind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+}
+
+func (fb *FrameBuffer) ColorTexture(index int) uint32 {
+	return fb.ColorTextures[index]
+}
+
+func (fb *FrameBuffer) Dispose() {
+	if len(fb.ColorTextures) > 0 {
+		gl.DeleteTextures(int32(len(fb.ColorTextures)), &fb.ColorTextures[0])
+		fb.ColorTextures = nil
+	}
+	if fb.DepthTexture != 0 {
+		gl.DeleteTextures(1, &fb.DepthTexture)
+		fb.DepthTexture = 0
+	}
+	if len(fb.colorRenderBuffers) > 0 {
+		gl.DeleteRenderbuffers(int32(len(fb.colorRenderBuffers)), &fb.colorRenderBuffers[0])
+		fb.colorRenderBuffers = nil
+	}
+	if fb.depthRenderBuffer != 0 {
+		gl.DeleteRenderbuffers(1, &fb.depthRenderBuffer)
+		fb.depthRenderBuffer = 0
+	}
+	if fb.Fbo != 0 {
+		gl.DeleteFramebuffers(1, &fb.Fbo)
+		fb.Fbo = 0
+	}
+}
@@ -0,0 +1,114 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// AABB 轴对齐包围盒
+type AABB struct {
+	Min mgl32.Vec3
+	Max mgl32.Vec3
+}
+
+// NewAABB 空包围盒, 通过 Extend 加入点
+func NewAABB() AABB {
+	inf := float32(math.Inf(1))
+	return AABB{
+		Min: mgl32.Vec3{inf, inf, inf},
+		Max: mgl32.Vec3{-inf, -inf, -inf},
+	}
+}
+
+func (b AABB) IsEmpty() bool {
+	return b.Min[0] > b.Max[0] || b.Min[1] > b.Max[1] || b.Min[2] > b.Max[2]
+}
+
+// Extend 扩展包围盒使其包含点 p
+func (b *AABB) Extend(p mgl32.Vec3) {
+	for i := 0; i < 3; i++ {
+		if p[i] < b.Min[i] {
+			b.Min[i] = p[i]
+		}
+		if p[i] > b.Max[i] {
+			b.Max[i] = p[i]
+		}
+	}
+}
+
+func (b AABB) Center() mgl32.Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+func (b AABB) Size() mgl32.Vec3 {
+	return b.Max.Sub(b.Min)
+}
+
+// MaxExtent 最长边的长度
+func (b AABB) MaxExtent() float32 {
+	size := b.Size()
+	return float32(math.Max(float64(size[0]), math.Max(float64(size[1]), float64(size[2]))))
+}
+
+// Corners 包围盒的8个顶点
+func (b AABB) Corners() [8]mgl32.Vec3 {
+	var corners [8]mgl32.Vec3
+	for i := 0; i < 8; i++ {
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<axis) != 0 {
+				corners[i][axis] = b.Max[axis]
+			} else {
+				corners[i][axis] = b.Min[axis]
+			}
+		}
+	}
+	return corners
+}
+
+// Transform 变换后重新计算的轴对齐包围盒
+func (b AABB) Transform(m mgl32.Mat4) AABB {
+	if b.IsEmpty() {
+		return b
+	}
+	result := NewAABB()
+	for _, corner := range b.Corners() {
+		result.Extend(mgl32.TransformCoordinate(corner, m))
+	}
+	return result
+}
+
+// Contains 点 p 是否在包围盒内(包括边界)
+func (b AABB) Contains(p mgl32.Vec3) bool {
+	for i := 0; i < 3; i++ {
+		if p[i] < b.Min[i] || p[i] > b.Max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Expand 每个方向向外扩展 amount 的包围盒
+func (b AABB) Expand(amount float32) AABB {
+	if b.IsEmpty() {
+		return b
+	}
+	offset := mgl32.Vec3{amount, amount, amount}
+	return AABB{Min: b.Min.Sub(offset), Max: b.Max.Add(offset)}
+}
+
+// Union 同时包含两个包围盒的包围盒
+func (b AABB) Union(other AABB) AABB {
+	if other.IsEmpty() {
+		return b
+	}
+	result := b
+	result.Extend(other.Min)
+	result.Extend(other.Max)
+	return result
+}
+
+// BoundingSphere 包围盒的外接球
+func (b AABB) BoundingSphere() Sphere {
+	return Sphere{Center: b.Center(), Radius: b.Size().Len() / 2}
+}
@@ -0,0 +1,92 @@
+package geometry
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Plane 平面 Normal·p + D = 0, 法线指向平面内侧
+type Plane struct {
+	Normal mgl32.Vec3
+	D      float32
+}
+
+// Distance 点到平面的有向距离, 正值表示在内侧
+func (p Plane) Distance(point mgl32.Vec3) float32 {
+	return p.Normal.Dot(point) + p.D
+}
+
+func (p Plane) normalize() Plane {
+	length := p.Normal.Len()
+	if length == 0 {
+		return p
+	}
+	return Plane{Normal: p.Normal.Mul(1 / length), D: p.D / length}
+}
+
+// Frustum 视锥体, 由 projection * view 提取的6个平面
+type Frustum struct {
+	Planes [6]Plane
+}
+
+// NewFrustum 从 projection * view 矩阵中提取视锥平面(Gribb-Hartmann)
+func NewFrustum(viewProjection mgl32.Mat4) Frustum {
+	row := func(i int) mgl32.Vec4 {
+		return viewProjection.Row(i)
+	}
+	planeFrom := func(v mgl32.Vec4) Plane {
+		return Plane{Normal: v.Vec3(), D: v.W()}.normalize()
+	}
+
+	var f Frustum
+	f.Planes[0] = planeFrom(row(3).Add(row(0))) // left
+	f.Planes[1] = planeFrom(row(3).Sub(row(0))) // right
+	f.Planes[2] = planeFrom(row(3).Add(row(1))) // bottom
+	f.Planes[3] = planeFrom(row(3).Sub(row(1))) // top
+	f.Planes[4] = planeFrom(row(3).Add(row(2))) // near
+	f.Planes[5] = planeFrom(row(3).Sub(row(2))) // far
+	return f
+}
+
+// FrustumCorners 视锥的8个角点, 前4个在近平面, 后4个在远平面, 均按左下, 右下, 右上, 左上排列
+func FrustumCorners(viewProjection mgl32.Mat4) [8]mgl32.Vec3 {
+	inv := viewProjection.Inv()
+	var corners [8]mgl32.Vec3
+	ndc := [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+	for i, z := range [2]float32{-1, 1} {
+		for j, xy := range ndc {
+			p := inv.Mul4x1(mgl32.Vec4{xy[0], xy[1], z, 1})
+			corners[i*4+j] = p.Vec3().Mul(1 / p.W())
+		}
+	}
+	return corners
+}
+
+// IntersectsAABB 包围盒是否与视锥相交或在视锥内
+func (f Frustum) IntersectsAABB(b AABB) bool {
+	if b.IsEmpty() {
+		return false
+	}
+	for _, plane := range f.Planes {
+		// 取法线方向上最远的顶点, 若它也在平面外侧则整个包围盒在外侧
+		var positive mgl32.Vec3
+		for axis := 0; axis < 3; axis++ {
+			if plane.Normal[axis] >= 0 {
+				positive[axis] = b.Max[axis]
+			} else {
+				positive[axis] = b.Min[axis]
+			}
+		}
+		if plane.Distance(positive) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsSphere 包围球是否与视锥相交或在视锥内
+func (f Frustum) IntersectsSphere(s Sphere) bool {
+	for _, plane := range f.Planes {
+		if plane.Distance(s.Center) < -s.Radius {
+			return false
+		}
+	}
+	return true
+}
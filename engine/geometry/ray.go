@@ -0,0 +1,115 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Ray 射线 Origin + t * Direction
+type Ray struct {
+	Origin    mgl32.Vec3
+	Direction mgl32.Vec3
+}
+
+func (r Ray) At(t float32) mgl32.Vec3 {
+	return r.Origin.Add(r.Direction.Mul(t))
+}
+
+// Transform 变换射线, 方向不做归一化, 因此变换前后的 t 值一致
+func (r Ray) Transform(m mgl32.Mat4) Ray {
+	return Ray{
+		Origin:    mgl32.TransformCoordinate(r.Origin, m),
+		Direction: mgl32.TransformNormal(r.Direction, m),
+	}
+}
+
+// IntersectTriangle Möller-Trumbore 射线三角形求交, 返回距离和重心坐标(u, v)
+// 交点 = (1-u-v)*a + u*b + v*c
+func (r Ray) IntersectTriangle(a, b, c mgl32.Vec3) (t, u, v float32, ok bool) {
+	const epsilon = 1e-7
+
+	edge1 := b.Sub(a)
+	edge2 := c.Sub(a)
+	p := r.Direction.Cross(edge2)
+	det := edge1.Dot(p)
+	if det > -epsilon && det < epsilon {
+		return 0, 0, 0, false
+	}
+	invDet := 1 / det
+
+	s := r.Origin.Sub(a)
+	u = s.Dot(p) * invDet
+	if u < 0 || u > 1 {
+		return 0, 0, 0, false
+	}
+
+	q := s.Cross(edge1)
+	v = r.Direction.Dot(q) * invDet
+	if v < 0 || u+v > 1 {
+		return 0, 0, 0, false
+	}
+
+	t = edge2.Dot(q) * invDet
+	if t < 0 {
+		return 0, 0, 0, false
+	}
+	return t, u, v, true
+}
+
+// IntersectAABB slab 法射线包围盒求交, 返回进入距离
+func (r Ray) IntersectAABB(b AABB) (float32, bool) {
+	if b.IsEmpty() {
+		return 0, false
+	}
+	tMin := float32(math.Inf(-1))
+	tMax := float32(math.Inf(1))
+	for i := 0; i < 3; i++ {
+		if r.Direction[i] == 0 {
+			if r.Origin[i] < b.Min[i] || r.Origin[i] > b.Max[i] {
+				return 0, false
+			}
+			continue
+		}
+		inv := 1 / r.Direction[i]
+		t1 := (b.Min[i] - r.Origin[i]) * inv
+		t2 := (b.Max[i] - r.Origin[i]) * inv
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > tMin {
+			tMin = t1
+		}
+		if t2 < tMax {
+			tMax = t2
+		}
+		if tMin > tMax || tMax < 0 {
+			return 0, false
+		}
+	}
+	if tMin < 0 {
+		return 0, true
+	}
+	return tMin, true
+}
+
+// IntersectSphere 射线与球求交, 返回进入距离, 起点在球内时为 0
+func (r Ray) IntersectSphere(s Sphere) (float32, bool) {
+	oc := r.Origin.Sub(s.Center)
+	b := oc.Dot(r.Direction)
+	c := oc.Dot(oc) - s.Radius*s.Radius
+	discriminant := b*b - c
+	if discriminant < 0 {
+		return 0, false
+	}
+	sqrt := float32(math.Sqrt(float64(discriminant)))
+	t := -b - sqrt
+	if t < 0 {
+		t = -b + sqrt
+		if t < 0 {
+			return 0, false
+		}
+		return 0, true
+	}
+	return t, true
+}
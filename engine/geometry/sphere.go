@@ -0,0 +1,22 @@
+package geometry
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Sphere 包围球
+type Sphere struct {
+	Center mgl32.Vec3
+	Radius float32
+}
+
+func (s Sphere) Contains(p mgl32.Vec3) bool {
+	return p.Sub(s.Center).Len() <= s.Radius
+}
+
+// IntersectsAABB 包围盒上离球心最近的点在球内
+func (s Sphere) IntersectsAABB(b AABB) bool {
+	var closest mgl32.Vec3
+	for i := 0; i < 3; i++ {
+		closest[i] = min(max(s.Center[i], b.Min[i]), b.Max[i])
+	}
+	return s.Contains(closest)
+}
@@ -0,0 +1,343 @@
+// Package gizmo 视口中的平移, 旋转和缩放手柄
+package gizmo
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/debugdraw"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+type Mode int
+
+const (
+	ModeTranslate Mode = iota
+	ModeRotate
+	ModeScale
+)
+
+var ModeNames = []string{"Move", "Rotate", "Scale"}
+
+// handle 手柄, 0~2 为 x, y, z 轴
+type handle int
+
+const (
+	handleNone    handle = -1
+	handleUniform handle = 3 // 缩放中心的方块, 等比缩放
+	handleRing    handle = 4 // 绕 Y 轴旋转的圆环, 模型只支持绕 Y 轴旋转
+)
+
+const (
+	// 手柄的拾取范围和箭头, 方块大小, 相对于手柄长度
+	pickTolerance = 0.08
+	arrowSize     = 0.15
+	boxSize       = 0.07
+	ringSegments  = 48
+	// 缩放的最小值, 避免缩放为 0 后无法再拖回
+	minScale = 0.001
+)
+
+var (
+	axes        = [3]mgl32.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	axisColors  = [3]mgl32.Vec3{{1, 0.2, 0.2}, {0.2, 1, 0.2}, {0.3, 0.4, 1}}
+	ringColor   = mgl32.Vec3{0.2, 1, 0.2}
+	centerColor = mgl32.Vec3{0.9, 0.9, 0.9}
+	activeColor = mgl32.Vec3{1, 1, 0}
+)
+
+// Tool 变换手柄, 在选中模型的原点绘制, 拖动手柄直接修改模型的 Position, Rotate 和 Scale
+// 光标射线由外部根据 Cursor 计算后通过 Update 传入
+type Tool struct {
+	Active bool
+	Mode   Mode
+	// 手柄长度与相机距离的比例, 使手柄在屏幕上大小不变
+	Size float32
+
+	target *model.Model
+
+	cursor    [2]float32
+	hasCursor bool
+	down      bool
+	pressed   bool
+
+	hover handle
+	drag  handle
+	// 开始拖动时的变换和拖动参数(轴上的位置, 距离或角度)
+	startPosition mgl32.Vec3
+	startScale    mgl32.Vec3
+	startRotate   float32
+	startParam    float32
+}
+
+func NewTool() *Tool {
+	return &Tool{
+		Size:  0.15,
+		hover: handleNone,
+		drag:  handleNone,
+	}
+}
+
+func (t *Tool) SetActive(active bool) {
+	t.Active = active
+	t.hasCursor = false
+	t.hover = handleNone
+	t.drag = handleNone
+}
+
+// SetTarget 设置要变换的对象, 只支持模型, 其他对象不显示手柄
+func (t *Tool) SetTarget(obj interface{}) {
+	m, _ := obj.(*model.Model)
+	if m != t.target {
+		t.drag = handleNone
+	}
+	t.target = m
+}
+
+// HandleInput 记录光标位置和鼠标左键状态, 需在 imgui.NewFrame 之后调用
+func (t *Tool) HandleInput() {
+	t.pressed = false
+	if !t.Active {
+		return
+	}
+	io := imgui.CurrentIO()
+	t.down = imgui.IsMouseDown(0)
+	// 拖动中光标移到界面上时继续拖动
+	if io.WantCaptureMouse() && t.drag == handleNone {
+		t.hasCursor = false
+		return
+	}
+	pos := imgui.MousePos()
+	t.cursor = [2]float32{pos.X, pos.Y}
+	t.hasCursor = true
+	t.pressed = imgui.IsMouseClicked(0)
+}
+
+// Cursor 有选中的模型且光标在视口中时返回窗口坐标
+func (t *Tool) Cursor() ([2]float32, bool) {
+	return t.cursor, t.Active && t.target != nil && t.hasCursor
+}
+
+// Dragging 是否正在拖动手柄
+func (t *Tool) Dragging() bool {
+	return t.drag != handleNone
+}
+
+// Hovered 光标是否悬停在手柄上
+func (t *Tool) Hovered() bool {
+	return t.hover != handleNone
+}
+
+// length 手柄在世界空间的长度
+func (t *Tool) length(eye mgl32.Vec3) float32 {
+	return max(t.target.Position.Sub(eye).Len()*t.Size, 0.001)
+}
+
+// Update 根据光标射线更新悬停的手柄, 按下时开始拖动, 拖动时修改模型的变换
+func (t *Tool) Update(ray geometry.Ray, eye mgl32.Vec3) {
+	if !t.Active || t.target == nil {
+		return
+	}
+	if !t.down {
+		t.drag = handleNone
+	}
+	length := t.length(eye)
+
+	if t.drag == handleNone {
+		t.hover = t.pick(ray, length)
+		if t.pressed && t.hover != handleNone {
+			if param, ok := t.param(t.hover, ray, eye); ok {
+				t.drag = t.hover
+				t.startParam = param
+				t.startPosition = t.target.Position
+				t.startScale = t.target.Scale
+				t.startRotate = t.target.Rotate
+			}
+		}
+		return
+	}
+
+	param, ok := t.param(t.drag, ray, eye)
+	if !ok {
+		return
+	}
+	switch {
+	case t.drag == handleRing:
+		t.target.SetRotate(t.startRotate + param - t.startParam)
+	case t.Mode == ModeTranslate:
+		t.target.SetPosition(t.startPosition.Add(axes[t.drag].Mul(param - t.startParam)))
+	case t.Mode == ModeScale:
+		factor := param / t.startParam
+		scale := t.startScale
+		if t.drag == handleUniform {
+			scale = scale.Mul(factor)
+		} else {
+			scale[t.drag] *= factor
+		}
+		for i := range scale {
+			scale[i] = max(scale[i], minScale)
+		}
+		t.target.SetScale(scale)
+	}
+}
+
+// pick 射线命中的手柄, 命中多个时取离射线最近的
+func (t *Tool) pick(ray geometry.Ray, length float32) handle {
+	center := t.target.Position
+	tolerance := length * pickTolerance
+
+	if t.Mode == ModeRotate {
+		p, ok := intersectPlane(ray, center, axes[1])
+		if ok && mgl32.Abs(p.Sub(center).Len()-length) < tolerance {
+			return handleRing
+		}
+		return handleNone
+	}
+
+	if t.Mode == ModeScale {
+		extent := mgl32.Vec3{length * boxSize, length * boxSize, length * boxSize}
+		box := geometry.AABB{Min: center.Sub(extent), Max: center.Add(extent)}
+		if _, ok := ray.IntersectAABB(box); ok {
+			return handleUniform
+		}
+	}
+
+	best := handleNone
+	bestDistance := tolerance
+	for i, axis := range axes {
+		s, distance, ok := closestOnAxis(ray, center, axis)
+		if !ok || s < 0 || s > length*(1+arrowSize) {
+			continue
+		}
+		if distance < bestDistance {
+			best, bestDistance = handle(i), distance
+		}
+	}
+	return best
+}
+
+// param 拖动参数: 轴上的位置, 光标到中心的距离(等比缩放)或绕 Y 轴的角度
+func (t *Tool) param(h handle, ray geometry.Ray, eye mgl32.Vec3) (float32, bool) {
+	center := t.target.Position
+	switch {
+	case h == handleRing:
+		p, ok := intersectPlane(ray, center, axes[1])
+		if !ok {
+			return 0, false
+		}
+		d := p.Sub(center)
+		// 右手系中绕 Y 轴的正方向为从 x 转向 -z
+		return float32(math.Atan2(float64(-d.Z()), float64(d.X()))), true
+	case h == handleUniform:
+		// 在面向相机的平面上取光标到中心的距离
+		p, ok := intersectPlane(ray, center, eye.Sub(center).Normalize())
+		if !ok {
+			return 0, false
+		}
+		distance := p.Sub(center).Len()
+		return distance, distance > 0.0001
+	default:
+		s, _, ok := closestOnAxis(ray, center, axes[h])
+		if t.Mode == ModeScale {
+			return s, ok && mgl32.Abs(s) > 0.0001
+		}
+		return s, ok
+	}
+}
+
+// closestOnAxis 轴(center + s * axis)与射线最近点在轴上的位置和两线的距离, 平行时返回 false
+func closestOnAxis(ray geometry.Ray, center, axis mgl32.Vec3) (s, distance float32, ok bool) {
+	w0 := center.Sub(ray.Origin)
+	b := axis.Dot(ray.Direction)
+	denom := 1 - b*b
+	if denom < 1e-6 {
+		return 0, 0, false
+	}
+	d := axis.Dot(w0)
+	e := ray.Direction.Dot(w0)
+	s = (b*e - d) / denom
+	r := (e - b*d) / denom
+	if r < 0 {
+		return 0, 0, false
+	}
+	distance = center.Add(axis.Mul(s)).Sub(ray.At(r)).Len()
+	return s, distance, true
+}
+
+// intersectPlane 射线与过 point 法线为 normal 的平面的交点
+func intersectPlane(ray geometry.Ray, point, normal mgl32.Vec3) (mgl32.Vec3, bool) {
+	denom := ray.Direction.Dot(normal)
+	if mgl32.Abs(denom) < 1e-6 {
+		return mgl32.Vec3{}, false
+	}
+	r := point.Sub(ray.Origin).Dot(normal) / denom
+	if r < 0 {
+		return mgl32.Vec3{}, false
+	}
+	return ray.At(r), true
+}
+
+func (t *Tool) color(h handle, base mgl32.Vec3) mgl32.Vec3 {
+	if t.drag == h || (t.drag == handleNone && t.hover == h) {
+		return activeColor
+	}
+	return base
+}
+
+// Draw 绘制当前模式的手柄, 始终绘制在最上层
+func (t *Tool) Draw(dd *debugdraw.DebugDraw, eye mgl32.Vec3) {
+	if !t.Active || t.target == nil {
+		return
+	}
+	center := t.target.Position
+	length := t.length(eye)
+
+	if t.Mode == ModeRotate {
+		t.drawRing(dd, center, length)
+		return
+	}
+
+	for i, axis := range axes {
+		color := t.color(handle(i), axisColors[i])
+		tip := center.Add(axis.Mul(length))
+		dd.AddOverlayLine(center, tip, color)
+		if t.Mode == ModeScale {
+			addBox(dd, tip, length*boxSize, color)
+			continue
+		}
+		// 箭头由指向尖端的四条线组成
+		back := tip.Sub(axis.Mul(length * arrowSize))
+		for j := 1; j < 3; j++ {
+			side := axes[(i+j)%3].Mul(length * arrowSize * 0.4)
+			dd.AddOverlayLine(back.Add(side), tip, color)
+			dd.AddOverlayLine(back.Sub(side), tip, color)
+		}
+	}
+	if t.Mode == ModeScale {
+		addBox(dd, center, length*boxSize, t.color(handleUniform, centerColor))
+	}
+}
+
+// drawRing 绕 Y 轴的圆环, 拖动时绘制从中心指向当前角度的线
+func (t *Tool) drawRing(dd *debugdraw.DebugDraw, center mgl32.Vec3, radius float32) {
+	color := t.color(handleRing, ringColor)
+	point := func(angle float64) mgl32.Vec3 {
+		return center.Add(mgl32.Vec3{float32(math.Cos(angle)) * radius, 0, -float32(math.Sin(angle)) * radius})
+	}
+	for i := 0; i < ringSegments; i++ {
+		a0 := float64(i) / ringSegments * 2 * math.Pi
+		a1 := float64(i+1) / ringSegments * 2 * math.Pi
+		dd.AddOverlayLine(point(a0), point(a1), color)
+	}
+	if t.drag == handleRing {
+		dd.AddOverlayLine(center, point(float64(t.startParam+t.target.Rotate-t.startRotate)), activeColor)
+	}
+}
+
+// addBox 以 center 为中心, 半边长为 half 的方块线框
+func addBox(dd *debugdraw.DebugDraw, center mgl32.Vec3, half float32, color mgl32.Vec3) {
+	extent := mgl32.Vec3{half, half, half}
+	dd.AddOverlayAABB(geometry.AABB{Min: center.Sub(extent), Max: center.Add(extent)}, color)
+}
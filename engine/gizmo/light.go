@@ -0,0 +1,152 @@
+package gizmo
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/debugdraw"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+const lightIconFile = "./resource/texture/light.png"
+
+var selectedLightColor = mgl32.Vec3{1, 0.6, 0.1}
+
+// Lights 在每个点光源的位置绘制图标和影响范围的线框球, 点击图标选中灯光
+// 光标射线由外部根据 Cursor 计算后通过 Update 传入, 与 Tool 相同
+type Lights struct {
+	Show bool
+	// 图标大小与相机距离的比例, 使图标在屏幕上大小不变
+	Size float32
+
+	icon *model.Billboard
+
+	cursor    [2]float32
+	hasCursor bool
+	pressed   bool
+
+	hover    *light.PointLight
+	selected *light.PointLight
+	clicked  *light.PointLight
+}
+
+func NewLights() (*Lights, error) {
+	icon, err := model.NewBillboard(lightIconFile, mgl32.Vec2{1, 1})
+	if err != nil {
+		return nil, err
+	}
+	return &Lights{
+		Show: true,
+		Size: 0.04,
+		icon: icon,
+	}, nil
+}
+
+// HandleInput 记录光标位置和点击, 需在 imgui.NewFrame 之后调用
+func (g *Lights) HandleInput() {
+	g.pressed = false
+	if !g.Show || imgui.CurrentIO().WantCaptureMouse() {
+		g.hasCursor = false
+		return
+	}
+	pos := imgui.MousePos()
+	g.cursor = [2]float32{pos.X, pos.Y}
+	g.hasCursor = true
+	g.pressed = imgui.IsMouseClicked(0)
+}
+
+// Cursor 光标在视口中时返回窗口坐标
+func (g *Lights) Cursor() ([2]float32, bool) {
+	return g.cursor, g.Show && g.hasCursor
+}
+
+// Hovered 光标是否悬停在灯光图标上
+func (g *Lights) Hovered() bool {
+	return g.hover != nil
+}
+
+// SetSelected 设置高亮的灯光, 可为 nil
+func (g *Lights) SetSelected(l *light.PointLight) {
+	g.selected = l
+}
+
+// TakeClick 返回本帧点击的灯光, 只返回一次
+func (g *Lights) TakeClick() (*light.PointLight, bool) {
+	l := g.clicked
+	g.clicked = nil
+	return l, l != nil
+}
+
+// size 图标在世界空间的大小
+func (g *Lights) size(position, eye mgl32.Vec3) float32 {
+	return max(position.Sub(eye).Len()*g.Size, 0.001)
+}
+
+// Update 根据光标射线更新悬停的灯光, 按下时记录点击的灯光
+func (g *Lights) Update(ray geometry.Ray, eye mgl32.Vec3, lights []*light.PointLight) {
+	g.hover = nil
+	if !g.Show {
+		return
+	}
+	var nearest float32
+	for _, l := range lights {
+		position := l.Position.Vec3()
+		sphere := geometry.Sphere{Center: position, Radius: g.size(position, eye) * 0.5}
+		if t, ok := ray.IntersectSphere(sphere); ok && (g.hover == nil || t < nearest) {
+			g.hover, nearest = l, t
+		}
+	}
+	if g.pressed && g.hover != nil {
+		g.clicked = g.hover
+	}
+}
+
+// Draw 绘制影响范围的线框球, 选中的灯光高亮
+func (g *Lights) Draw(dd *debugdraw.DebugDraw, lights []*light.PointLight) {
+	if !g.Show {
+		return
+	}
+	for _, l := range lights {
+		color := l.Color
+		if l == g.selected {
+			color = selectedLightColor
+		}
+		dd.AddSphere(geometry.Sphere{Center: l.Position.Vec3(), Radius: l.Radius()}, color)
+	}
+}
+
+// RenderIcons 在灯光位置绘制朝向相机的图标, 颜色为灯光颜色, 悬停和选中时高亮
+// 图标绘制在最上层, 灯光在模型内部时也能点击
+func (g *Lights) RenderIcons(projection, view mgl32.Mat4, eye mgl32.Vec3, lights []*light.PointLight) {
+	if !g.Show || len(lights) == 0 {
+		return
+	}
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+	g.icon.PreRender()
+	for _, l := range lights {
+		position := l.Position.Vec3()
+		size := g.size(position, eye)
+		color := l.Color
+		switch l {
+		case g.hover:
+			color = activeColor
+		case g.selected:
+			color = selectedLightColor
+		}
+		g.icon.Position = position
+		g.icon.Size = mgl32.Vec2{size, size}
+		g.icon.Color = color.Vec4(1)
+		g.icon.Render(projection, mgl32.Ident4(), view, &eye, nil)
+	}
+	g.icon.PostRender()
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+func (g *Lights) Dispose() {
+	g.icon.Dispose()
+}
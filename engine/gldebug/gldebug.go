@@ -0,0 +1,187 @@
+package gldebug
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+)
+
+// Severity 驱动调试消息的级别
+type Severity int32
+
+const (
+	SeverityNotification Severity = iota // 写入 Debug 日志
+	SeverityLow                          // 写入 Info 日志
+	SeverityMedium                       // 写入 Warn 日志
+	SeverityHigh                         // 写入 Error 日志
+)
+
+var SeverityNames = []string{"notification", "low", "medium", "high"}
+
+var (
+	// Enabled 调试模式: 创建调试上下文, 注册驱动的调试回调, Check 检查 glGetError
+	// 关闭时 Check 不调用 glGetError, 不影响性能
+	Enabled = false
+	// MinSeverity 写入日志的最低级别, 低于它的驱动消息被忽略, glGetError 的错误总是写入日志
+	MinSeverity = SeverityMedium
+)
+
+// maxRepeats 同一条驱动消息最多记录的次数, 每帧重复的消息不会刷屏
+const maxRepeats = 10
+
+type messageKey struct {
+	source, gltype, id uint32
+}
+
+var repeats = map[messageKey]int{}
+
+// ParseSeverity 按名称解析级别, 名称为 SeverityNames 之一
+func ParseSeverity(name string) (Severity, error) {
+	i := slices.Index(SeverityNames, strings.ToLower(strings.TrimSpace(name)))
+	if i < 0 {
+		return MinSeverity, fmt.Errorf("unknown GL debug severity %q, expected one of %s", name, strings.Join(SeverityNames, ", "))
+	}
+	return Severity(i), nil
+}
+
+// Init 注册驱动的调试回调, 在 gl.Init 之后的主线程调用
+// 需要 OpenGL 4.3, KHR_debug 或 ARB_debug_output, 都不支持时(例如 macOS)只能依靠 Check
+func Init() {
+	if !Enabled {
+		return
+	}
+	var flags int32
+	gl.GetIntegerv(gl.CONTEXT_FLAGS, &flags)
+	if flags&gl.CONTEXT_FLAG_DEBUG_BIT == 0 {
+		logger.Warn("OpenGL context is not a debug context, driver messages may be incomplete")
+	}
+
+	var major, minor int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+	switch {
+	case major > 4 || major == 4 && minor >= 3 || hasExtension("GL_KHR_debug"):
+		gl.Enable(gl.DEBUG_OUTPUT)
+		gl.DebugMessageCallback(callback, nil)
+	case hasExtension("GL_ARB_debug_output"):
+		gl.DebugMessageCallbackARB(callback, nil)
+	default:
+		logger.Warn("GL debug output is not supported, only glGetError is checked")
+		return
+	}
+	// 同步调用回调, 消息在出错的 GL 调用返回之前记录
+	gl.Enable(gl.DEBUG_OUTPUT_SYNCHRONOUS)
+	logger.Info("GL debug output enabled")
+}
+
+func hasExtension(name string) bool {
+	var n int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &n)
+	for i := int32(0); i < n; i++ {
+		if gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i))) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Check 读取并记录 glGetError 的所有错误, where 为出错位置的说明, 例如绘制阶段的名称
+// 只在调试模式下检查, 返回是否有错误
+func Check(where string) bool {
+	if !Enabled {
+		return false
+	}
+	failed := false
+	// 上下文丢失时 glGetError 可能一直返回错误, 限制读取次数
+	for i := 0; i < 16; i++ {
+		code := gl.GetError()
+		if code == gl.NO_ERROR {
+			break
+		}
+		failed = true
+		logger.Error(fmt.Sprintf("GL error %s after %s", errorName(code), where))
+	}
+	return failed
+}
+
+func errorName(code uint32) string {
+	switch code {
+	case gl.INVALID_ENUM:
+		return "INVALID_ENUM"
+	case gl.INVALID_VALUE:
+		return "INVALID_VALUE"
+	case gl.INVALID_OPERATION:
+		return "INVALID_OPERATION"
+	case gl.INVALID_FRAMEBUFFER_OPERATION:
+		return "INVALID_FRAMEBUFFER_OPERATION"
+	case gl.OUT_OF_MEMORY:
+		return "OUT_OF_MEMORY"
+	case gl.STACK_UNDERFLOW:
+		return "STACK_UNDERFLOW"
+	case gl.STACK_OVERFLOW:
+		return "STACK_OVERFLOW"
+	}
+	return fmt.Sprintf("0x%x", code)
+}
+
+// severities 驱动的级别对应的 Severity
+var severities = map[uint32]Severity{
+	gl.DEBUG_SEVERITY_NOTIFICATION: SeverityNotification,
+	gl.DEBUG_SEVERITY_LOW:          SeverityLow,
+	gl.DEBUG_SEVERITY_MEDIUM:       SeverityMedium,
+	gl.DEBUG_SEVERITY_HIGH:         SeverityHigh,
+}
+
+var sourceNames = map[uint32]string{
+	gl.DEBUG_SOURCE_API:             "api",
+	gl.DEBUG_SOURCE_WINDOW_SYSTEM:   "window-system",
+	gl.DEBUG_SOURCE_SHADER_COMPILER: "shader-compiler",
+	gl.DEBUG_SOURCE_THIRD_PARTY:     "third-party",
+	gl.DEBUG_SOURCE_APPLICATION:     "application",
+	gl.DEBUG_SOURCE_OTHER:           "other",
+}
+
+var typeNames = map[uint32]string{
+	gl.DEBUG_TYPE_ERROR:               "error",
+	gl.DEBUG_TYPE_DEPRECATED_BEHAVIOR: "deprecated",
+	gl.DEBUG_TYPE_UNDEFINED_BEHAVIOR:  "undefined-behavior",
+	gl.DEBUG_TYPE_PORTABILITY:         "portability",
+	gl.DEBUG_TYPE_PERFORMANCE:         "performance",
+	gl.DEBUG_TYPE_MARKER:              "marker",
+	gl.DEBUG_TYPE_PUSH_GROUP:          "push-group",
+	gl.DEBUG_TYPE_POP_GROUP:           "pop-group",
+	gl.DEBUG_TYPE_OTHER:               "other",
+}
+
+// callback 驱动的调试回调, 按级别过滤后写入日志
+func callback(source, gltype, id, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+	level, ok := severities[severity]
+	if !ok || level < MinSeverity {
+		return
+	}
+	key := messageKey{source, gltype, id}
+	repeats[key]++
+	switch n := repeats[key]; {
+	case n > maxRepeats:
+		return
+	case n == maxRepeats:
+		message += " (repeated, further messages suppressed)"
+	}
+
+	text := fmt.Sprintf("GL %s %s %d: %s", sourceNames[source], typeNames[gltype], id, strings.TrimSpace(message))
+	switch level {
+	case SeverityHigh:
+		logger.Error(text)
+	case SeverityMedium:
+		logger.Warn(text)
+	case SeverityLow:
+		logger.Info(text)
+	default:
+		logger.Debug(text)
+	}
+}
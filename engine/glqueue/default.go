@@ -0,0 +1,36 @@
+package glqueue
+
+import "time"
+
+// 引擎共用的命令队列, 由 World 在每帧开始时执行
+var defaultQueue = NewQueue()
+
+// Default 引擎共用的命令队列
+func Default() *Queue {
+	return defaultQueue
+}
+
+// Post 向默认队列提交命令
+func Post(fn func()) *Fence {
+	return defaultQueue.Post(fn)
+}
+
+// PostCallback 向默认队列提交命令, 执行完毕后在主线程调用 callback
+func PostCallback(fn func(), callback func()) *Fence {
+	return defaultQueue.PostCallback(fn, callback)
+}
+
+// PostFenced 向默认队列提交带 GPU 栅栏的命令
+func PostFenced(fn func(), callback func()) *Fence {
+	return defaultQueue.PostFenced(fn, callback)
+}
+
+// Call 向默认队列提交命令并等待执行完毕, 不能在主线程调用
+func Call(fn func()) {
+	defaultQueue.Call(fn)
+}
+
+// Execute 在主线程执行默认队列中的命令
+func Execute(budget time.Duration) {
+	defaultQueue.Execute(budget)
+}
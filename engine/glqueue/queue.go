@@ -0,0 +1,158 @@
+package glqueue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/huangxiaobo/toy-engine/engine/gldebug"
+)
+
+// Fence 命令完成的信号
+// 普通命令在主线程执行完毕后完成, 带 GPU 栅栏的命令在 GPU 执行完提交的 GL 命令后完成
+type Fence struct {
+	done chan struct{}
+	sync uintptr
+}
+
+func newFence() *Fence {
+	return &Fence{done: make(chan struct{})}
+}
+
+// Wait 阻塞等待命令完成, 不能在主线程调用, 否则命令永远不会执行
+func (f *Fence) Wait() {
+	<-f.done
+}
+
+// Done 命令是否已完成
+func (f *Fence) Done() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+type command struct {
+	fn       func()
+	fence    *Fence
+	gpu      bool
+	callback func()
+}
+
+// Queue 线程安全的 GL 命令队列
+// 任意协程提交的 GL 操作(缓冲上传, 纹理创建, 删除)由主线程每帧通过 Execute 执行
+type Queue struct {
+	mu       sync.Mutex
+	commands []command
+	// 等待 GPU 完成的栅栏, 只在主线程访问
+	pending []command
+}
+
+func NewQueue() *Queue {
+	return &Queue{}
+}
+
+// Post 提交命令, 返回命令在主线程执行完毕后完成的栅栏
+func (q *Queue) Post(fn func()) *Fence {
+	return q.post(command{fn: fn})
+}
+
+// PostCallback 提交命令, 执行完毕后在主线程调用 callback
+func (q *Queue) PostCallback(fn func(), callback func()) *Fence {
+	return q.post(command{fn: fn, callback: callback})
+}
+
+// PostFenced 提交命令并在其后插入 GPU 栅栏, GPU 执行完命令后才完成并调用 callback (可为 nil)
+// 用于上传的数据需要被 GPU 读取完毕后才能复用或释放的场景
+func (q *Queue) PostFenced(fn func(), callback func()) *Fence {
+	return q.post(command{fn: fn, gpu: true, callback: callback})
+}
+
+// Call 提交命令并等待执行完毕, 不能在主线程调用
+func (q *Queue) Call(fn func()) {
+	q.Post(fn).Wait()
+}
+
+func (q *Queue) post(cmd command) *Fence {
+	cmd.fence = newFence()
+	q.mu.Lock()
+	q.commands = append(q.commands, cmd)
+	q.mu.Unlock()
+	return cmd.fence
+}
+
+// Execute 在主线程执行已提交的命令并检查 GPU 栅栏, budget > 0 时超出时间预算的命令留到下一帧
+// 执行过程中新提交的命令同样留到下一帧
+func (q *Queue) Execute(budget time.Duration) {
+	q.poll()
+
+	q.mu.Lock()
+	commands := q.commands
+	q.commands = nil
+	q.mu.Unlock()
+
+	start := time.Now()
+	for i, cmd := range commands {
+		if budget > 0 && i > 0 && time.Since(start) > budget {
+			// 剩余命令放回队首, 保持提交顺序
+			q.mu.Lock()
+			q.commands = append(commands[i:len(commands):len(commands)], q.commands...)
+			q.mu.Unlock()
+			return
+		}
+		q.run(cmd)
+	}
+}
+
+// Flush 执行所有命令并等待所有 GPU 栅栏, 用于退出前清理
+func (q *Queue) Flush() {
+	for {
+		q.mu.Lock()
+		empty := len(q.commands) == 0
+		q.mu.Unlock()
+		if empty {
+			break
+		}
+		q.Execute(0)
+	}
+	for _, cmd := range q.pending {
+		gl.ClientWaitSync(cmd.fence.sync, gl.SYNC_FLUSH_COMMANDS_BIT, gl.TIMEOUT_IGNORED)
+	}
+	q.poll()
+}
+
+func (q *Queue) run(cmd command) {
+	cmd.fn()
+	gldebug.Check("queued GL command")
+	if cmd.gpu {
+		cmd.fence.sync = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+		q.pending = append(q.pending, cmd)
+		return
+	}
+	q.complete(cmd)
+}
+
+// poll 检查 GPU 栅栏是否已触发, 不阻塞
+func (q *Queue) poll() {
+	pending := q.pending[:0]
+	for _, cmd := range q.pending {
+		status := gl.ClientWaitSync(cmd.fence.sync, 0, 0)
+		if status == gl.ALREADY_SIGNALED || status == gl.CONDITION_SATISFIED {
+			gl.DeleteSync(cmd.fence.sync)
+			q.complete(cmd)
+			continue
+		}
+		pending = append(pending, cmd)
+	}
+	q.pending = pending
+}
+
+func (q *Queue) complete(cmd command) {
+	if cmd.callback != nil {
+		cmd.callback()
+	}
+	close(cmd.fence.done)
+}
@@ -0,0 +1,68 @@
+package engine
+
+// Hook 回调的句柄, 用于移除回调
+type Hook struct {
+	remove func()
+}
+
+// Remove 移除回调, 可以在回调中调用
+func (h Hook) Remove() {
+	if h.remove != nil {
+		h.remove()
+	}
+}
+
+type updateHook struct {
+	fn func(dt float64)
+}
+
+type guiHook struct {
+	fn func()
+}
+
+// OnUpdate 登记每帧调用的游戏逻辑, dt 为经过时间效果缩放的游戏时间(秒)
+// 在场景对象更新之前调用, 回调中修改的变换在这一帧生效
+func (w *World) OnUpdate(fn func(dt float64)) Hook {
+	h := &updateHook{fn: fn}
+	w.updateHooks = append(w.updateHooks, h)
+	return Hook{remove: func() {
+		h.fn = nil
+		w.updateHooks = without(w.updateHooks, h)
+	}}
+}
+
+// OnGUI 登记每帧绘制界面的回调, 在引擎的界面之后调用, 回调中可以直接使用 imgui 创建窗口
+func (w *World) OnGUI(fn func()) Hook {
+	h := &guiHook{fn: fn}
+	w.guiHooks = append(w.guiHooks, h)
+	return Hook{remove: func() {
+		h.fn = nil
+		w.guiHooks = without(w.guiHooks, h)
+	}}
+}
+
+// runUpdateHooks 按登记顺序调用 OnUpdate 的回调, 回调中登记的新回调从下一帧开始调用
+func (w *World) runUpdateHooks(dt float64) {
+	for _, h := range append([]*updateHook(nil), w.updateHooks...) {
+		if h.fn != nil {
+			h.fn(dt)
+		}
+	}
+}
+
+func (w *World) runGUIHooks() {
+	for _, h := range append([]*guiHook(nil), w.guiHooks...) {
+		if h.fn != nil {
+			h.fn()
+		}
+	}
+}
+
+func without[T comparable](list []T, item T) []T {
+	for i, other := range list {
+		if other == item {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/event"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// hotReloadInterval 检查资源文件修改的间隔
+const hotReloadInterval = 500 * time.Millisecond
+
+// hotReloadState 上次检查资源文件的时间
+type hotReloadState struct {
+	last time.Time
+	// 场景文件的修改时间, 第一次检查时记录
+	worldModTime time.Time
+}
+
+// hotReload 重新编译源文件修改过的着色器, 重新加载修改过的材质文件, 模型文件和场景文件中修改过的模型
+// 失败时继续使用原来的程序或模型并记录错误
+func (w *World) hotReload() {
+	if !config.Config.HotReload || time.Since(w.reload.last) < hotReloadInterval {
+		return
+	}
+	w.reload.last = time.Now()
+
+	for _, s := range shader.ReloadChanged() {
+		if s.Err != nil {
+			logger.Error(s.Err)
+			continue
+		}
+		logger.Info(fmt.Sprintf("reload shader %s, %s", s.VertFilePath, s.FragFilePath))
+		event.Publish(w.Events, event.AssetReloaded{Kind: event.AssetShader, Path: s.FragFilePath})
+	}
+
+	for _, file := range model.ReloadChangedMaterials() {
+		logger.Info(fmt.Sprintf("reload material %s", file))
+		event.Publish(w.Events, event.AssetReloaded{Kind: event.AssetMaterial, Path: file})
+	}
+
+	for _, obj := range w.renderObjs {
+		if m, ok := obj.(*model.Model); ok && m.SourcesChanged() {
+			w.reloadModel(m, m.Reload)
+		}
+	}
+	w.reloadWorldModels()
+}
+
+// reloadModel 调用 reload 重新加载模型并发布 AssetReloaded
+func (w *World) reloadModel(m *model.Model, reload func() error) {
+	if err := reload(); err != nil {
+		logger.Error(fmt.Errorf("reload model %s: %w", m.Name, err))
+		return
+	}
+	logger.Info(fmt.Sprintf("reload model %s", m.Name))
+	event.Publish(w.Events, event.AssetReloaded{Kind: event.AssetModel, Path: filepath.Join(m.BasePath, m.FileName)})
+}
+
+// reloadWorldModels 场景文件修改后, 按 Id 找到描述有变化的 Model 并在原地重新创建
+// 只比较位置和缩放以外的描述, 运行时的变换保持不变, 新增和删除的模型不处理
+func (w *World) reloadWorldModels() {
+	modTime, ok := vfs.ModTime(w.worldFile)
+	if !ok || modTime.Equal(w.reload.worldModTime) {
+		return
+	}
+	first := w.reload.worldModTime.IsZero()
+	w.reload.worldModTime = modTime
+	if first {
+		return
+	}
+
+	xmlWorld, err := config.ReadWorld(w.worldFile)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	models := w.xmlWorld.XMLModels.XMLModels
+	for _, xmlModel := range xmlWorld.XMLModels.XMLModels {
+		if xmlModel.XmlResourceClass != "Model" || xmlModel.Id == "" {
+			continue
+		}
+		i := slices.IndexFunc(models, func(old config.XmlModel) bool { return old.Id == xmlModel.Id })
+		if i < 0 || sameIgnoringTransform(models[i], xmlModel) {
+			continue
+		}
+		models[i] = xmlModel
+		e, ok := w.FindById(xmlModel.Id)
+		if !ok {
+			continue
+		}
+		if m, ok := e.Obj.(*model.Model); ok {
+			w.reloadModel(m, func() error { return m.ReloadXml(xmlModel) })
+		}
+	}
+}
+
+// sameIgnoringTransform 两个模型描述除位置和缩放外是否相同, 保存场景只改变位置和缩放
+func sameIgnoringTransform(a, b config.XmlModel) bool {
+	a.Position, b.Position = config.XmlXYZ{}, config.XmlXYZ{}
+	a.Scale, b.Scale = config.XmlXYZ{}, config.XmlXYZ{}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,185 @@
+package impostor
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+)
+
+const (
+	DefaultFrames     = 8
+	DefaultResolution = 128
+	MaxFrames         = 32
+	// 每帧四周留出的边距, 避免 mipmap 采样到相邻的帧
+	padding = 1.1
+)
+
+// Options 烘焙参数
+type Options struct {
+	// 绕 Y 轴均匀分布的视角数
+	Frames int
+	// 每帧的像素尺寸
+	Resolution int32
+	// 网格使用顶点颜色
+	VertexColor bool
+	AlphaCutoff float32
+}
+
+// Atlas 从多个水平视角正交渲染的模型, 按行排列在一张贴图中
+// 颜色附件 0 为反照率, 1 为模型空间法线, alpha 为覆盖率
+type Atlas struct {
+	Frames  int
+	Columns int
+	Rows    int
+
+	// 模型空间中四边形底边的中点, 四边形的宽和高
+	Base   mgl32.Vec3
+	Width  float32
+	Height float32
+
+	fb *framebuffer.FrameBuffer
+}
+
+// AlbedoTexture 反照率贴图
+func (a *Atlas) AlbedoTexture() uint32 {
+	return a.fb.ColorTexture(0)
+}
+
+// NormalTexture 模型空间法线贴图, 按 n*0.5+0.5 编码
+func (a *Atlas) NormalTexture() uint32 {
+	return a.fb.ColorTexture(1)
+}
+
+func (a *Atlas) Dispose() {
+	a.fb.Dispose()
+}
+
+// NewQuad 替身使用的四边形, x 在 [-0.5, 0.5], y 在 [0, 1], 实例化绘制
+func NewQuad() *mesh.Mesh {
+	m := &mesh.Mesh{
+		DrawMode: gl.TRIANGLE_STRIP,
+	}
+	for i, p := range []mgl32.Vec2{{-0.5, 0}, {0.5, 0}, {-0.5, 1}, {0.5, 1}} {
+		m.Vertices = append(m.Vertices, mesh.Vertex{
+			Position:  mgl32.Vec3{p.X(), p.Y(), 0},
+			Normal:    mgl32.Vec3{0, 0, 1},
+			TexCoords: mgl32.Vec2{p.X() + 0.5, p.Y()},
+		})
+		m.Indices = append(m.Indices, uint32(i))
+	}
+	m.ComputeBounds()
+	m.Setup()
+	return m
+}
+
+// Bake 把网格从 Frames 个水平视角渲染到图集, 第 i 帧的相机位于模型空间方向 (sin a, 0, cos a), a = i*2π/Frames
+// 调用前后的帧缓冲和视口保持不变
+func Bake(meshes []*mesh.Mesh, bounds geometry.AABB, opts Options) (*Atlas, error) {
+	frames := min(max(opts.Frames, 1), MaxFrames)
+	resolution := max(opts.Resolution, 16)
+	columns := int(math.Ceil(math.Sqrt(float64(frames))))
+	rows := (frames + columns - 1) / columns
+
+	fb, err := framebuffer.NewFrameBuffer(int32(columns)*resolution, int32(rows)*resolution, true,
+		framebuffer.RGBA8, framebuffer.RGBA8)
+	if err != nil {
+		return nil, err
+	}
+
+	// 水平方向取包围盒角点到中心的最大距离, 任意视角下模型都在帧内
+	center := bounds.Center()
+	var radius float32
+	for _, corner := range bounds.Corners() {
+		radius = max(radius, mgl32.Vec2{corner.X() - center.X(), corner.Z() - center.Z()}.Len())
+	}
+	radius = max(radius*padding, 0.0001)
+	halfHeight := max(bounds.Size().Y()*0.5*padding, 0.0001)
+	depth := bounds.Size().Len() + 1
+	atlas := &Atlas{
+		Frames:  frames,
+		Columns: columns,
+		Rows:    rows,
+		Base:    mgl32.Vec3{center.X(), center.Y() - halfHeight, center.Z()},
+		Width:   2 * radius,
+		Height:  2 * halfHeight,
+		fb:      fb,
+	}
+
+	bakeShader := &shader.Shader{
+		VertFilePath: "./resource/shader/impostor_bake.vert",
+		FragFilePath: "./resource/shader/impostor_bake.frag",
+	}
+	if err := bakeShader.Init(); err != nil {
+		fb.Dispose()
+		return nil, err
+	}
+	defer bakeShader.Dispose()
+
+	var lastViewport [4]int32
+	var lastFbo int32
+	gl.GetIntegerv(gl.VIEWPORT, &lastViewport[0])
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &lastFbo)
+	blend := gl.IsEnabled(gl.BLEND)
+	cullFace := gl.IsEnabled(gl.CULL_FACE)
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	var clearColor [4]float32
+	gl.GetFloatv(gl.COLOR_CLEAR_VALUE, &clearColor[0])
+
+	fb.Bind()
+	gl.Disable(gl.BLEND)
+	gl.Disable(gl.CULL_FACE)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Viewport(0, 0, fb.Width, fb.Height)
+	// 背景的颜色和法线取中间值, 减少 mipmap 在边缘混入的暗边
+	gl.ClearColor(0.5, 0.5, 0.5, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	program := bakeShader.Use()
+	projection := mgl32.Ortho(-radius, radius, -halfHeight, halfHeight, 0.01, 2*depth)
+	bakeShader.SetUniform("projection", projection)
+	bakeShader.SetUniform("gVertexColor", opts.VertexColor)
+	bakeShader.SetUniform("gAlphaCutoff", opts.AlphaCutoff)
+	for i := 0; i < frames; i++ {
+		angle := float64(i) * 2 * math.Pi / float64(frames)
+		sin, cos := math.Sincos(angle)
+		eye := center.Add(mgl32.Vec3{float32(sin), 0, float32(cos)}.Mul(depth))
+		bakeShader.SetUniform("view", mgl32.LookAtV(eye, center, mgl32.Vec3{0, 1, 0}))
+
+		col, row := int32(i%columns), int32(i/columns)
+		gl.Viewport(col*resolution, row*resolution, resolution, resolution)
+		for _, mi := range meshes {
+			bakeShader.SetUniform("gHasTexture", mi.HasTexture(texture.TextureDiffuse))
+			mi.Draw(program)
+		}
+	}
+	bakeShader.UnUse()
+
+	for _, tex := range fb.ColorTextures {
+		gl.BindTexture(gl.TEXTURE_2D, tex)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+		// 最小的 mipmap 层级每帧仍有 4 个像素, 不同的帧不会混在一起
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(max(math.Log2(float64(resolution))-2, 0)))
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(lastFbo))
+	gl.Viewport(lastViewport[0], lastViewport[1], lastViewport[2], lastViewport[3])
+	gl.ClearColor(clearColor[0], clearColor[1], clearColor[2], clearColor[3])
+	if blend {
+		gl.Enable(gl.BLEND)
+	}
+	if cullFace {
+		gl.Enable(gl.CULL_FACE)
+	}
+	if !depthTest {
+		gl.Disable(gl.DEPTH_TEST)
+	}
+	return atlas, nil
+}
@@ -0,0 +1,367 @@
+package input
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+)
+
+// Device 绑定使用的输入设备
+type Device int
+
+const (
+	DeviceKey Device = iota
+	DeviceMouse
+	DeviceMouseAxis
+	DeviceGamepad
+	DeviceGamepadAxis
+)
+
+// DeviceNames 配置文件中 bind 的 device 属性
+var DeviceNames = []string{"key", "mouse", "mouseaxis", "gamepad", "gamepadaxis"}
+
+// MouseAxis 鼠标的移动和滚轮, 值为这一帧的像素数或滚轮格数
+type MouseAxis int
+
+const (
+	MouseX MouseAxis = iota
+	MouseY
+	MouseWheel
+)
+
+// 轴绑定作为按键使用时, 绝对值超过该值视为按下
+const axisPressThreshold = 0.5
+
+// Binding 动作的一个输入, Code 为对应设备的按键或轴
+// 作为轴使用时, 按键按下的值为 Scale, 轴的值乘以 Scale
+type Binding struct {
+	Device Device
+	Code   int
+	Scale  float32
+}
+
+func KeyBinding(key Key, scale float32) Binding {
+	return Binding{Device: DeviceKey, Code: int(key), Scale: scale}
+}
+
+func MouseBinding(button MouseButton) Binding {
+	return Binding{Device: DeviceMouse, Code: int(button), Scale: 1}
+}
+
+func MouseAxisBinding(axis MouseAxis, scale float32) Binding {
+	return Binding{Device: DeviceMouseAxis, Code: int(axis), Scale: scale}
+}
+
+func GamepadBinding(button GamepadButton, scale float32) Binding {
+	return Binding{Device: DeviceGamepad, Code: int(button), Scale: scale}
+}
+
+func GamepadAxisBinding(axis GamepadAxis, scale float32) Binding {
+	return Binding{Device: DeviceGamepadAxis, Code: int(axis), Scale: scale}
+}
+
+// Name 输入的名称, 不包括设备, 用于配置文件
+func (b Binding) Name() string {
+	switch b.Device {
+	case DeviceKey:
+		return Key(b.Code).String()
+	case DeviceMouse:
+		return nameAt(MouseButtonNames, b.Code)
+	case DeviceMouseAxis:
+		return nameAt(MouseAxisNames, b.Code)
+	case DeviceGamepad:
+		return nameAt(GamepadButtonNames, b.Code)
+	case DeviceGamepadAxis:
+		return nameAt(GamepadAxisNames, b.Code)
+	}
+	return "?"
+}
+
+// String 界面中显示的名称, 如 W, Mouse Left, Pad LeftY, 负方向的绑定前面加 -
+func (b Binding) String() string {
+	var prefix string
+	switch b.Device {
+	case DeviceMouse, DeviceMouseAxis:
+		prefix = "Mouse "
+	case DeviceGamepad, DeviceGamepadAxis:
+		prefix = "Pad "
+	}
+	if b.Scale < 0 {
+		prefix = "-" + prefix
+	}
+	return prefix + b.Name()
+}
+
+func nameAt(names []string, i int) string {
+	if i < 0 || i >= len(names) {
+		return fmt.Sprint(i)
+	}
+	return names[i]
+}
+
+// ParseBinding 按配置文件中的设备和名称创建绑定, scale 为 0 时为 1
+func ParseBinding(device, name string, scale float32) (Binding, error) {
+	d, ok := parseName(DeviceNames, device)
+	if !ok {
+		return Binding{}, fmt.Errorf("unknown input device %q", device)
+	}
+	if scale == 0 {
+		scale = 1
+	}
+	b := Binding{Device: Device(d), Scale: scale}
+	name = strings.TrimSpace(name)
+	switch b.Device {
+	case DeviceKey:
+		var key Key
+		key, ok = ParseKey(name)
+		b.Code = int(key)
+	case DeviceMouse:
+		b.Code, ok = parseName(MouseButtonNames, name)
+	case DeviceMouseAxis:
+		b.Code, ok = parseName(MouseAxisNames, name)
+	case DeviceGamepad:
+		b.Code, ok = parseName(GamepadButtonNames, name)
+	case DeviceGamepadAxis:
+		b.Code, ok = parseName(GamepadAxisNames, name)
+	}
+	if !ok {
+		return Binding{}, fmt.Errorf("unknown %s input %q", device, name)
+	}
+	return b, nil
+}
+
+// Action 命名的动作, 任一绑定按下时动作按下, 作为轴时值为各绑定的值之和
+type Action struct {
+	Name     string
+	Bindings []Binding
+}
+
+func (a *Action) clone() *Action {
+	return &Action{Name: a.Name, Bindings: append([]Binding(nil), a.Bindings...)}
+}
+
+// ActionMap 把命名的动作和轴映射到键盘, 鼠标和手柄, 游戏代码按名称查询而不关心具体的按键
+type ActionMap struct {
+	input    *Input
+	actions  []*Action
+	defaults []*Action
+	// 为 true 时所有动作都不触发, 重新绑定时使用, 避免按下的新按键触发原来的动作
+	Suspended bool
+}
+
+// NewActionMap 使用 defaults 作为默认绑定, Reset 时恢复
+func NewActionMap(in *Input, defaults []*Action) *ActionMap {
+	m := &ActionMap{input: in, defaults: defaults}
+	m.Reset()
+	return m
+}
+
+// Reset 恢复默认绑定
+func (m *ActionMap) Reset() {
+	m.actions = m.actions[:0]
+	for _, action := range m.defaults {
+		m.actions = append(m.actions, action.clone())
+	}
+}
+
+func (m *ActionMap) Actions() []*Action {
+	return m.actions
+}
+
+// Action 按名称查找动作, 不存在时返回 nil
+func (m *ActionMap) Action(name string) *Action {
+	for _, action := range m.actions {
+		if action.Name == name {
+			return action
+		}
+	}
+	return nil
+}
+
+// Bind 为动作添加绑定, 动作不存在时创建
+func (m *ActionMap) Bind(name string, b Binding) {
+	action := m.Action(name)
+	if action == nil {
+		action = &Action{Name: name}
+		m.actions = append(m.actions, action)
+	}
+	action.Bindings = append(action.Bindings, b)
+}
+
+// Unbind 移除动作的第 i 个绑定
+func (m *ActionMap) Unbind(name string, i int) {
+	action := m.Action(name)
+	if action == nil || i < 0 || i >= len(action.Bindings) {
+		return
+	}
+	action.Bindings = append(action.Bindings[:i], action.Bindings[i+1:]...)
+}
+
+// Down 动作当前是否按下
+func (m *ActionMap) Down(name string) bool {
+	return m.any(name, m.input.bindingDown)
+}
+
+// Pressed 动作是否在这一帧按下
+func (m *ActionMap) Pressed(name string) bool {
+	return m.any(name, m.input.bindingPressed)
+}
+
+// Released 动作是否在这一帧松开
+func (m *ActionMap) Released(name string) bool {
+	return m.any(name, m.input.bindingReleased)
+}
+
+// Value 动作作为轴的值, 为各绑定的值之和, 鼠标轴为像素数, 不限制范围
+func (m *ActionMap) Value(name string) float32 {
+	action := m.Action(name)
+	if m.Suspended || action == nil {
+		return 0
+	}
+	var value float32
+	for _, b := range action.Bindings {
+		value += m.input.bindingValue(b)
+	}
+	return value
+}
+
+func (m *ActionMap) any(name string, test func(Binding) bool) bool {
+	action := m.Action(name)
+	if m.Suspended || action == nil {
+		return false
+	}
+	for _, b := range action.Bindings {
+		if test(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load 使用配置文件中的绑定, 配置中的动作替换同名的默认动作, 不认识的绑定跳过并返回错误
+func (m *ActionMap) Load(xmlMap *config.XmlInputMap) error {
+	var errs []string
+	for _, xmlAction := range xmlMap.Actions {
+		action := &Action{Name: xmlAction.Name}
+		for _, xmlBind := range xmlAction.Bindings {
+			b, err := ParseBinding(xmlBind.Device, xmlBind.Name, xmlBind.Scale)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", xmlAction.Name, err))
+				continue
+			}
+			action.Bindings = append(action.Bindings, b)
+		}
+		if existing := m.Action(action.Name); existing != nil {
+			existing.Bindings = action.Bindings
+		} else {
+			m.actions = append(m.actions, action)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("input bindings: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// XML 当前绑定的配置, 用于保存
+func (m *ActionMap) XML() *config.XmlInputMap {
+	xmlMap := &config.XmlInputMap{}
+	for _, action := range m.actions {
+		xmlAction := config.XmlInputAction{Name: action.Name}
+		for _, b := range action.Bindings {
+			xmlBind := config.XmlInputBinding{Device: DeviceNames[b.Device], Name: b.Name()}
+			if b.Scale != 1 {
+				xmlBind.Scale = b.Scale
+			}
+			xmlAction.Bindings = append(xmlAction.Bindings, xmlBind)
+		}
+		xmlMap.Actions = append(xmlMap.Actions, xmlAction)
+	}
+	return xmlMap
+}
+
+func (in *Input) bindingDown(b Binding) bool {
+	switch b.Device {
+	case DeviceKey:
+		return in.IsKeyDown(Key(b.Code))
+	case DeviceMouse:
+		return in.IsMouseDown(MouseButton(b.Code))
+	case DeviceGamepad:
+		return in.IsGamepadDown(GamepadButton(b.Code))
+	case DeviceGamepadAxis:
+		return in.GamepadAxis(GamepadAxis(b.Code))*b.Scale > axisPressThreshold
+	}
+	return false
+}
+
+func (in *Input) bindingPressed(b Binding) bool {
+	switch b.Device {
+	case DeviceKey:
+		return in.WasPressed(Key(b.Code))
+	case DeviceMouse:
+		return in.WasMousePressed(MouseButton(b.Code))
+	case DeviceGamepad:
+		return in.WasGamepadPressed(GamepadButton(b.Code))
+	}
+	return false
+}
+
+func (in *Input) bindingReleased(b Binding) bool {
+	switch b.Device {
+	case DeviceKey:
+		return in.WasReleased(Key(b.Code))
+	case DeviceMouse:
+		return in.WasMouseReleased(MouseButton(b.Code))
+	case DeviceGamepad:
+		return in.WasGamepadReleased(GamepadButton(b.Code))
+	}
+	return false
+}
+
+func (in *Input) bindingValue(b Binding) float32 {
+	switch b.Device {
+	case DeviceMouseAxis:
+		switch MouseAxis(b.Code) {
+		case MouseX:
+			return in.mouseDelta[0] * b.Scale
+		case MouseY:
+			return in.mouseDelta[1] * b.Scale
+		case MouseWheel:
+			return in.wheel[1] * b.Scale
+		}
+		return 0
+	case DeviceGamepadAxis:
+		return in.GamepadAxis(GamepadAxis(b.Code)) * b.Scale
+	}
+	if in.bindingDown(b) {
+		return b.Scale
+	}
+	return 0
+}
+
+// LastPressed 这一帧按下的按键, 鼠标键或手柄键, 以及推过一半的摇杆, 用于重新绑定
+func (in *Input) LastPressed() (Binding, bool) {
+	for i, pressed := range in.keys.pressed {
+		if pressed {
+			return KeyBinding(Key(i), 1), true
+		}
+	}
+	for i, pressed := range in.mouse.pressed {
+		if pressed {
+			return MouseBinding(MouseButton(i)), true
+		}
+	}
+	for i, pressed := range in.gamepad.pressed {
+		if pressed {
+			return GamepadBinding(GamepadButton(i), 1), true
+		}
+	}
+	for i := range in.gamepadAxes {
+		if value := in.GamepadAxis(GamepadAxis(i)); math.Abs(float64(value)) > axisPressThreshold {
+			return GamepadAxisBinding(GamepadAxis(i), float32(math.Copysign(1, float64(value)))), true
+		}
+	}
+	return Binding{}, false
+}
@@ -0,0 +1,435 @@
+package input
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Key 键盘按键, 取值为 SDL scancode, 与键盘布局无关
+type Key int
+
+const (
+	KeyA Key = 4 + iota
+	KeyB
+	KeyC
+	KeyD
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyJ
+	KeyK
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyS
+	KeyT
+	KeyU
+	KeyV
+	KeyW
+	KeyX
+	KeyY
+	KeyZ
+	Key1
+	Key2
+	Key3
+	Key4
+	Key5
+	Key6
+	Key7
+	Key8
+	Key9
+	Key0
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+	KeyTab
+	KeySpace
+)
+
+const (
+	KeyF1 Key = 58 + iota
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+const (
+	KeyInsert Key = 73 + iota
+	KeyHome
+	KeyPageUp
+	KeyDelete
+	KeyEnd
+	KeyPageDown
+	KeyRight
+	KeyLeft
+	KeyDown
+	KeyUp
+)
+
+const (
+	// 小键盘 5
+	KeyKeypad5 Key = 93
+)
+
+const (
+	KeyLeftCtrl Key = 224 + iota
+	KeyLeftShift
+	KeyLeftAlt
+	KeyLeftGUI
+	KeyRightCtrl
+	KeyRightShift
+	KeyRightAlt
+)
+
+// keyCount SDL scancode 的个数
+const keyCount = 512
+
+// MouseButton 鼠标按键
+type MouseButton int
+
+const (
+	MouseLeft MouseButton = iota
+	MouseRight
+	MouseMiddle
+	mouseButtonCount
+)
+
+// GamepadButton 手柄按键, 取值与 SDL GameController 相同
+type GamepadButton int
+
+const (
+	GamepadA GamepadButton = iota
+	GamepadB
+	GamepadX
+	GamepadY
+	GamepadBack
+	GamepadGuide
+	GamepadStart
+	GamepadLeftStick
+	GamepadRightStick
+	GamepadLeftShoulder
+	GamepadRightShoulder
+	GamepadDPadUp
+	GamepadDPadDown
+	GamepadDPadLeft
+	GamepadDPadRight
+	gamepadButtonCount
+)
+
+// GamepadAxis 手柄摇杆和扳机, 取值与 SDL GameController 相同
+type GamepadAxis int
+
+const (
+	GamepadLeftX GamepadAxis = iota
+	GamepadLeftY
+	GamepadRightX
+	GamepadRightY
+	GamepadTriggerLeft
+	GamepadTriggerRight
+	gamepadAxisCount
+)
+
+// GamepadDeadZone 摇杆绝对值小于该值时视为 0
+const GamepadDeadZone = 0.15
+
+// buttons 一组按键在一帧内的状态
+type buttons struct {
+	down     []bool
+	pressed  []bool
+	released []bool
+}
+
+func newButtons(count int) buttons {
+	return buttons{
+		down:     make([]bool, count),
+		pressed:  make([]bool, count),
+		released: make([]bool, count),
+	}
+}
+
+func (b *buttons) valid(i int) bool {
+	return i >= 0 && i < len(b.down)
+}
+
+func (b *buttons) set(i int, down bool) {
+	if !b.valid(i) || b.down[i] == down {
+		return
+	}
+	b.down[i] = down
+	if down {
+		b.pressed[i] = true
+	} else {
+		b.released[i] = true
+	}
+}
+
+func (b *buttons) copyFrom(o *buttons) {
+	copy(b.down, o.down)
+	copy(b.pressed, o.pressed)
+	copy(b.released, o.released)
+}
+
+func (b *buttons) clearEdges() {
+	clear(b.pressed)
+	clear(b.released)
+}
+
+// Input 每帧的键盘和鼠标状态, 由平台的事件循环写入, 在一帧之内保持不变
+// 同一帧内按下又松开的按键 WasPressed 和 WasReleased 都为 true, IsKeyDown 为 false
+type Input struct {
+	// 当前帧的状态, 查询使用
+	keys          buttons
+	mouse         buttons
+	gamepad       buttons
+	gamepadAxes   [gamepadAxisCount]float32
+	mousePosition [2]float32
+	mouseDelta    [2]float32
+	wheel         [2]float32
+	touchPan      [2]float32
+	pinch         float32
+
+	// 上一次 BeginFrame 之后收到的事件
+	nextKeys          buttons
+	nextMouse         buttons
+	nextGamepad       buttons
+	nextMousePosition [2]float32
+	nextMouseDelta    [2]float32
+	nextWheel         [2]float32
+	nextTouchPan      [2]float32
+	nextPinch         float32
+
+	// 触摸屏或触控板上按下的手指数
+	touchFingers int
+
+	mouseCaptured    bool
+	keyboardCaptured bool
+	// 相对鼠标模式, 光标隐藏并锁定在窗口内, 只有移动量
+	relativeMouse bool
+}
+
+func NewInput() *Input {
+	return &Input{
+		keys:      newButtons(keyCount),
+		mouse:     newButtons(int(mouseButtonCount)),
+		nextKeys:  newButtons(keyCount),
+		nextMouse: newButtons(int(mouseButtonCount)),
+
+		gamepad:     newButtons(int(gamepadButtonCount)),
+		nextGamepad: newButtons(int(gamepadButtonCount)),
+	}
+}
+
+// SetKey 平台收到按键按下或松开事件时调用, 按键重复不算新的按下
+func (in *Input) SetKey(key Key, down bool) {
+	in.nextKeys.set(int(key), down)
+}
+
+// SetMouseButton 平台收到鼠标按下或松开事件时调用
+func (in *Input) SetMouseButton(button MouseButton, down bool) {
+	in.nextMouse.set(int(button), down)
+}
+
+// SetGamepadButton 平台收到手柄按键事件时调用, 多个手柄的输入合并在一起
+func (in *Input) SetGamepadButton(button GamepadButton, down bool) {
+	in.nextGamepad.set(int(button), down)
+}
+
+// SetGamepadAxis 平台收到摇杆或扳机事件时调用, value 为 -1 到 1, 扳机为 0 到 1
+func (in *Input) SetGamepadAxis(axis GamepadAxis, value float32) {
+	if axis >= 0 && axis < gamepadAxisCount {
+		in.gamepadAxes[axis] = mgl32.Clamp(value, -1, 1)
+	}
+}
+
+// MoveMouse 平台收到鼠标移动事件时调用, x, y 为窗口坐标, dx, dy 为相对上一次事件的移动
+func (in *Input) MoveMouse(x, y, dx, dy float32) {
+	in.nextMousePosition = [2]float32{x, y}
+	in.nextMouseDelta[0] += dx
+	in.nextMouseDelta[1] += dy
+}
+
+// ScrollWheel 平台收到滚轮事件时调用, 向上和向右滚动为正
+func (in *Input) ScrollWheel(dx, dy float32) {
+	in.nextWheel[0] += dx
+	in.nextWheel[1] += dy
+}
+
+// SetTouchFingers 平台收到手指按下或抬起事件时调用, n 为当前按下的手指数
+func (in *Input) SetTouchFingers(n int) {
+	in.touchFingers = n
+}
+
+// Gesture 平台收到多指手势事件时调用, dx, dy 为手指中心移动的像素数(窗口坐标), pinch 为手指间距增加的像素数
+func (in *Input) Gesture(dx, dy, pinch float32) {
+	in.nextTouchPan[0] += dx
+	in.nextTouchPan[1] += dy
+	in.nextPinch += pinch
+}
+
+// BeginFrame 把上一帧之后收到的事件作为当前帧的状态, 平台处理完一帧的事件后调用
+func (in *Input) BeginFrame() {
+	in.keys.copyFrom(&in.nextKeys)
+	in.mouse.copyFrom(&in.nextMouse)
+	in.gamepad.copyFrom(&in.nextGamepad)
+	in.nextKeys.clearEdges()
+	in.nextMouse.clearEdges()
+	in.nextGamepad.clearEdges()
+	in.mousePosition = in.nextMousePosition
+	in.mouseDelta, in.nextMouseDelta = in.nextMouseDelta, [2]float32{}
+	in.wheel, in.nextWheel = in.nextWheel, [2]float32{}
+	in.touchPan, in.nextTouchPan = in.nextTouchPan, [2]float32{}
+	in.pinch, in.nextPinch = in.nextPinch, 0
+}
+
+// SetCapture 记录界面是否正在使用鼠标和键盘, 每帧界面开始前调用
+// 相对鼠标模式下光标不在界面上, 鼠标和键盘都交给场景
+func (in *Input) SetCapture(mouse, keyboard bool) {
+	if in.relativeMouse {
+		mouse, keyboard = false, false
+	}
+	in.mouseCaptured, in.keyboardCaptured = mouse, keyboard
+}
+
+// SetRelativeMouse 平台进入或退出相对鼠标模式时调用
+func (in *Input) SetRelativeMouse(enabled bool) {
+	in.relativeMouse = enabled
+}
+
+// RelativeMouse 是否处于相对鼠标模式, 这时 MousePosition 不变, 只有 MouseDelta 有意义
+func (in *Input) RelativeMouse() bool {
+	return in.relativeMouse
+}
+
+// MouseCaptured 鼠标是否在界面上, 这时场景不应响应鼠标
+func (in *Input) MouseCaptured() bool {
+	return in.mouseCaptured
+}
+
+// KeyboardCaptured 界面是否正在接收文本输入, 这时场景不应响应按键
+func (in *Input) KeyboardCaptured() bool {
+	return in.keyboardCaptured
+}
+
+// IsKeyDown 按键当前是否按下
+func (in *Input) IsKeyDown(key Key) bool {
+	return in.keys.valid(int(key)) && in.keys.down[key]
+}
+
+// WasPressed 按键是否在这一帧按下
+func (in *Input) WasPressed(key Key) bool {
+	return in.keys.valid(int(key)) && in.keys.pressed[key]
+}
+
+// WasReleased 按键是否在这一帧松开
+func (in *Input) WasReleased(key Key) bool {
+	return in.keys.valid(int(key)) && in.keys.released[key]
+}
+
+func (in *Input) CtrlDown() bool {
+	return in.IsKeyDown(KeyLeftCtrl) || in.IsKeyDown(KeyRightCtrl)
+}
+
+func (in *Input) ShiftDown() bool {
+	return in.IsKeyDown(KeyLeftShift) || in.IsKeyDown(KeyRightShift)
+}
+
+func (in *Input) AltDown() bool {
+	return in.IsKeyDown(KeyLeftAlt) || in.IsKeyDown(KeyRightAlt)
+}
+
+// Axis 按下 positive 为 1, 按下 negative 为 -1, 同时按下或都没有按下为 0
+func (in *Input) Axis(positive, negative Key) float32 {
+	var value float32
+	if in.IsKeyDown(positive) {
+		value++
+	}
+	if in.IsKeyDown(negative) {
+		value--
+	}
+	return value
+}
+
+// IsMouseDown 鼠标按键当前是否按下
+func (in *Input) IsMouseDown(button MouseButton) bool {
+	return in.mouse.valid(int(button)) && in.mouse.down[button]
+}
+
+// WasMousePressed 鼠标按键是否在这一帧按下
+func (in *Input) WasMousePressed(button MouseButton) bool {
+	return in.mouse.valid(int(button)) && in.mouse.pressed[button]
+}
+
+// WasMouseReleased 鼠标按键是否在这一帧松开
+func (in *Input) WasMouseReleased(button MouseButton) bool {
+	return in.mouse.valid(int(button)) && in.mouse.released[button]
+}
+
+func (in *Input) IsGamepadDown(button GamepadButton) bool {
+	return in.gamepad.valid(int(button)) && in.gamepad.down[button]
+}
+
+func (in *Input) WasGamepadPressed(button GamepadButton) bool {
+	return in.gamepad.valid(int(button)) && in.gamepad.pressed[button]
+}
+
+func (in *Input) WasGamepadReleased(button GamepadButton) bool {
+	return in.gamepad.valid(int(button)) && in.gamepad.released[button]
+}
+
+// GamepadAxis 摇杆或扳机的当前值, 死区内为 0
+func (in *Input) GamepadAxis(axis GamepadAxis) float32 {
+	if axis < 0 || axis >= gamepadAxisCount {
+		return 0
+	}
+	value := in.gamepadAxes[axis]
+	if value > -GamepadDeadZone && value < GamepadDeadZone {
+		return 0
+	}
+	return value
+}
+
+// MousePosition 光标的窗口坐标
+func (in *Input) MousePosition() [2]float32 {
+	return in.mousePosition
+}
+
+// MouseDelta 这一帧光标移动的像素数(窗口坐标)
+func (in *Input) MouseDelta() [2]float32 {
+	return in.mouseDelta
+}
+
+// Wheel 这一帧的垂直滚轮格数, 向上滚动为正
+func (in *Input) Wheel() float32 {
+	return in.wheel[1]
+}
+
+// WheelH 这一帧的水平滚轮格数, 向右滚动为正
+func (in *Input) WheelH() float32 {
+	return in.wheel[0]
+}
+
+// TouchFingers 触摸屏或触控板上当前按下的手指数
+func (in *Input) TouchFingers() int {
+	return in.touchFingers
+}
+
+// TouchPan 这一帧多指拖动时手指中心移动的像素数(窗口坐标)
+func (in *Input) TouchPan() [2]float32 {
+	return in.touchPan
+}
+
+// Pinch 这一帧手指间距增加的像素数(窗口坐标), 张开为正, 捏合为负
+func (in *Input) Pinch() float32 {
+	return in.pinch
+}
@@ -0,0 +1,87 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+)
+
+var keyNames = map[Key]string{
+	KeyEnter:      "Enter",
+	KeyEscape:     "Escape",
+	KeyBackspace:  "Backspace",
+	KeyTab:        "Tab",
+	KeySpace:      "Space",
+	KeyInsert:     "Insert",
+	KeyHome:       "Home",
+	KeyPageUp:     "PageUp",
+	KeyDelete:     "Delete",
+	KeyEnd:        "End",
+	KeyPageDown:   "PageDown",
+	KeyRight:      "Right",
+	KeyLeft:       "Left",
+	KeyDown:       "Down",
+	KeyUp:         "Up",
+	KeyKeypad5:    "Keypad5",
+	KeyLeftCtrl:   "LeftCtrl",
+	KeyLeftShift:  "LeftShift",
+	KeyLeftAlt:    "LeftAlt",
+	KeyLeftGUI:    "LeftGUI",
+	KeyRightCtrl:  "RightCtrl",
+	KeyRightShift: "RightShift",
+	KeyRightAlt:   "RightAlt",
+}
+
+func init() {
+	for k := KeyA; k <= KeyZ; k++ {
+		keyNames[k] = string(rune('A' + k - KeyA))
+	}
+	for k := Key1; k <= Key9; k++ {
+		keyNames[k] = string(rune('1' + k - Key1))
+	}
+	keyNames[Key0] = "0"
+	for k := KeyF1; k <= KeyF12; k++ {
+		keyNames[k] = fmt.Sprintf("F%d", k-KeyF1+1)
+	}
+}
+
+var (
+	MouseButtonNames   = []string{"Left", "Right", "Middle"}
+	MouseAxisNames     = []string{"X", "Y", "Wheel"}
+	GamepadButtonNames = []string{
+		"A", "B", "X", "Y", "Back", "Guide", "Start", "LeftStick", "RightStick",
+		"LeftShoulder", "RightShoulder", "DPadUp", "DPadDown", "DPadLeft", "DPadRight",
+	}
+	GamepadAxisNames = []string{"LeftX", "LeftY", "RightX", "RightY", "TriggerLeft", "TriggerRight"}
+)
+
+// String 按键名称, 没有名称的按键为 Key<scancode>
+func (k Key) String() string {
+	if name, ok := keyNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key%d", int(k))
+}
+
+// ParseKey 按名称查找按键, 不区分大小写, 也接受 Key<scancode>
+func ParseKey(name string) (Key, bool) {
+	for k, keyName := range keyNames {
+		if strings.EqualFold(name, keyName) {
+			return k, true
+		}
+	}
+	var code int
+	if _, err := fmt.Sscanf(name, "Key%d", &code); err == nil && code > 0 && code < keyCount {
+		return Key(code), true
+	}
+	return 0, false
+}
+
+// parseName 在 names 中按名称查找下标, 不区分大小写
+func parseName(names []string, name string) (int, bool) {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
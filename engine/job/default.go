@@ -0,0 +1,31 @@
+package job
+
+import "sync"
+
+var (
+	defaultSystem *System
+	defaultOnce   sync.Once
+)
+
+// Default 引擎共用的任务系统, 首次使用时创建
+func Default() *System {
+	defaultOnce.Do(func() {
+		defaultSystem = NewSystem(0)
+	})
+	return defaultSystem
+}
+
+// Schedule 向默认任务系统提交任务
+func Schedule(fn func(), deps ...*Handle) *Handle {
+	return Default().Schedule(fn, deps...)
+}
+
+// ParallelFor 使用默认任务系统并行执行 fn(begin, end)
+func ParallelFor(n int, fn func(begin, end int), deps ...*Handle) *Handle {
+	return Default().ParallelFor(n, fn, deps...)
+}
+
+// Wait 等待默认任务系统中的任务完成
+func Wait(handles ...*Handle) {
+	Default().Wait(handles...)
+}
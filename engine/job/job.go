@@ -0,0 +1,228 @@
+package job
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+)
+
+// Handle 任务句柄, 用于等待任务完成或作为其他任务的依赖
+type Handle struct {
+	done bool
+	// 任务或它的依赖 panic 时记录, Wait 在等待的协程中重新 panic
+	panic *PanicError
+	// 依赖该任务的后续任务
+	dependents []*task
+}
+
+// PanicError 任务中 recover 的 panic 和当时的调用栈
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("job panic: %v\n%s", e.Value, e.Stack)
+}
+
+type task struct {
+	fn      func()
+	handle  *Handle
+	pending int
+	// 依赖的任务 panic 时不再执行 fn, 把 panic 传给自己的句柄
+	panic *PanicError
+}
+
+// System 任务系统, 固定数量的工作协程从共享队列中取任务执行
+// 任务中不能调用 OpenGL, GL 上下文只在主线程有效
+type System struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*task
+	workers int
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewSystem 创建任务系统, workers <= 0 时使用 CPU 核数减一(主线程等待时也会执行任务)
+func NewSystem(workers int) *System {
+	if workers <= 0 {
+		workers = max(runtime.NumCPU()-1, 1)
+	}
+	s := &System{workers: workers}
+	s.cond = sync.NewCond(&s.mu)
+	s.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+	return s
+}
+
+// Workers 工作协程数量
+func (s *System) Workers() int {
+	return s.workers
+}
+
+// Schedule 提交任务, 所有依赖完成后才会执行
+func (s *System) Schedule(fn func(), deps ...*Handle) *Handle {
+	t := &task{fn: fn, handle: &Handle{}}
+
+	s.mu.Lock()
+	for _, dep := range deps {
+		if dep == nil {
+			continue
+		}
+		if !dep.done {
+			dep.dependents = append(dep.dependents, t)
+			t.pending++
+		} else if dep.panic != nil && t.panic == nil {
+			t.panic = dep.panic
+		}
+	}
+	if t.pending == 0 {
+		s.push(t)
+	}
+	s.mu.Unlock()
+	return t.handle
+}
+
+// ParallelFor 将 [0, n) 按批次分给工作协程执行 fn(begin, end), 返回所有批次完成的句柄
+func (s *System) ParallelFor(n int, fn func(begin, end int), deps ...*Handle) *Handle {
+	if n <= 0 {
+		return s.Schedule(func() {}, deps...)
+	}
+	// 批次数略多于工作协程数, 平衡各批次耗时不均
+	batches := min(n, (s.workers+1)*4)
+	size := (n + batches - 1) / batches
+
+	handles := make([]*Handle, 0, batches)
+	for begin := 0; begin < n; begin += size {
+		begin, end := begin, min(begin+size, n)
+		handles = append(handles, s.Schedule(func() { fn(begin, end) }, deps...))
+	}
+	return s.Schedule(func() {}, handles...)
+}
+
+// Wait 等待任务完成, 等待期间当前协程也执行队列中的任务, 任务中等待子任务不会死锁
+// 等待的任务 panic 时在当前协程以 *PanicError 重新 panic, 由调用方(如 resource.Cache.Load)转换为错误
+func (s *System) Wait(handles ...*Handle) {
+	s.mu.Lock()
+	for _, h := range handles {
+		for h != nil && !h.done {
+			if t := s.pop(); t != nil {
+				s.mu.Unlock()
+				s.run(t)
+				s.mu.Lock()
+				continue
+			}
+			s.cond.Wait()
+		}
+	}
+	var p *PanicError
+	for _, h := range handles {
+		if h != nil && h.panic != nil {
+			p = h.panic
+			break
+		}
+	}
+	s.mu.Unlock()
+	if p != nil {
+		panic(p)
+	}
+}
+
+// Run 提交任务并等待完成
+func (s *System) Run(fn func(), deps ...*Handle) {
+	s.Wait(s.Schedule(fn, deps...))
+}
+
+// Dispose 执行完队列中的任务后停止工作协程
+func (s *System) Dispose() {
+	s.mu.Lock()
+	s.stopped = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *System) worker() {
+	defer s.wg.Done()
+	s.mu.Lock()
+	for {
+		t := s.pop()
+		if t == nil {
+			if s.stopped {
+				s.mu.Unlock()
+				return
+			}
+			s.cond.Wait()
+			continue
+		}
+		s.mu.Unlock()
+		s.run(t)
+		s.mu.Lock()
+	}
+}
+
+// run 执行任务并完成句柄, 任务的 panic 记录到句柄上, 不会使工作协程退出
+func (s *System) run(t *task) {
+	p := t.panic
+	if p == nil {
+		p = call(t.fn)
+	}
+
+	s.mu.Lock()
+	t.handle.done = true
+	t.handle.panic = p
+	for _, dep := range t.handle.dependents {
+		if p != nil && dep.panic == nil {
+			dep.panic = p
+		}
+		dep.pending--
+		if dep.pending == 0 {
+			s.push(dep)
+		}
+	}
+	t.handle.dependents = nil
+	// 同时唤醒等待中的协程和空闲的工作协程
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// call 执行 fn 并 recover panic, 没有人等待的任务也会在日志中留下记录
+func call(fn func()) (p *PanicError) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		// 任务中 Wait 重新抛出的子任务 panic 已经记录过
+		if inner, ok := r.(*PanicError); ok {
+			p = inner
+			return
+		}
+		p = &PanicError{Value: r, Stack: debug.Stack()}
+		logger.Error(p)
+	}()
+	fn()
+	return nil
+}
+
+// push 和 pop 需持有 s.mu
+func (s *System) push(t *task) {
+	s.queue = append(s.queue, t)
+	s.cond.Signal()
+}
+
+func (s *System) pop() *task {
+	if len(s.queue) == 0 {
+		return nil
+	}
+	t := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	return t
+}
@@ -1,12 +1,10 @@
 package light
 
 import (
-	"github.com/go-gl/gl/v4.1-core/gl"
+	"math"
+
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/huangxiaobo/toy-engine/engine/config"
-	"github.com/huangxiaobo/toy-engine/engine/logger"
-	"github.com/huangxiaobo/toy-engine/engine/mesh"
-	"github.com/huangxiaobo/toy-engine/engine/shader"
 )
 
 type Attenuation struct {
@@ -25,18 +23,60 @@ type PointLight struct {
 	DiffuseColor     mgl32.Vec3
 	SpecularColor    mgl32.Vec3
 	Atten            *Attenuation
+	// 全向阴影, 未配置时关闭
+	Shadow *Shadow
+}
 
-	shader            *shader.Shader
-	projectionUniform int32
-	viewUniform       int32
-	modelUniform      int32
-	colorUniform      int32
+// DirectionLight 方向光(太阳), 加载 HDR 环境贴图时由贴图估计, Override 时使用手动设置的值
+type DirectionLight struct {
+	// 光线传播方向
+	Direction mgl32.Vec3
+	Color     mgl32.Vec3
+	Intensity float32
+	// 手动覆盖, 不再使用环境贴图的估计值
+	Override bool
+
+	estimate    SunEstimate
+	hasEstimate bool
+}
 
-	model  mgl32.Mat4
-	Meshes []*mesh.Mesh
+func NewDirectionLight() *DirectionLight {
+	return &DirectionLight{
+		Direction: mgl32.Vec3{-0.3, -1, -0.2}.Normalize(),
+		Color:     mgl32.Vec3{1, 1, 1},
+		Intensity: 1,
+	}
 }
 
-type DirectionLight struct {
+// SetEstimate 记录环境贴图的估计值, 未手动覆盖时立即应用
+func (l *DirectionLight) SetEstimate(estimate SunEstimate) {
+	l.estimate = estimate
+	l.hasEstimate = true
+	if !l.Override {
+		l.applyEstimate()
+	}
+}
+
+// SetOverride 切换手动覆盖, 关闭时恢复环境贴图的估计值
+func (l *DirectionLight) SetOverride(override bool) {
+	l.Override = override
+	if !override {
+		l.applyEstimate()
+	}
+}
+
+// Estimated 是否有环境贴图的估计值
+func (l *DirectionLight) Estimated() bool {
+	return l.hasEstimate
+}
+
+func (l *DirectionLight) applyEstimate() {
+	if !l.hasEstimate {
+		return
+	}
+	l.Direction = l.estimate.Direction
+	l.Color = l.estimate.Color
+	l.Intensity = l.estimate.Intensity
 }
 
 type Vertex struct {
@@ -60,7 +100,7 @@ func NewPointLight(xmlLight config.XmlLight) *PointLight {
 			Linear:   0.007,
 			Exp:      0.0002,
 		},
-		model: mgl32.Ident4(),
+		Shadow: NewShadow(xmlLight.XMLShadow),
 	}
 
 	// Atten参数参考表
@@ -69,38 +109,26 @@ func NewPointLight(xmlLight config.XmlLight) *PointLight {
 	// 325	    1.0	        0.014      0.0007
 	// 600	    1.0	        0.007      0.0002
 
-	l.Meshes = mesh.NewMeshPoint([]mgl32.Vec3{l.Position.Vec3()}...)
-	for _, m := range l.Meshes {
-		for i := range m.Vertices {
-			m.Vertices[i].Color[0] = l.Color.X()
-			m.Vertices[i].Color[1] = l.Color.Y()
-			m.Vertices[i].Color[2] = l.Color.Z()
-		}
-
-		m.Dispose()
-		m.Setup()
-	}
-
-	l.Init()
 	return l
 }
 
-func (l *PointLight) Init() {
-	l.shader = &shader.Shader{VertFilePath: "./resource/shader/light.vert", FragFilePath: "./resource/shader/light.frag"}
-	if err := l.shader.Init(); err != nil {
-		logger.Error(err)
-		return
+// Radius 光照影响的范围, Atten.Range 为 0 时取亮度衰减到 5/256 的距离, 与上面的参考表一致
+func (l *PointLight) Radius() float32 {
+	if l.Atten.Range > 0 {
+		return l.Atten.Range
 	}
-
-	program := l.shader.Use()
-
-	// Shader
-	l.projectionUniform = gl.GetUniformLocation(program, gl.Str("projection\x00"))
-	l.viewUniform = gl.GetUniformLocation(program, gl.Str("view\x00"))
-	l.modelUniform = gl.GetUniformLocation(program, gl.Str("model\x00"))
-	l.colorUniform = gl.GetUniformLocation(program, gl.Str("lightColor\x00"))
-
-	gl.BindFragDataLocation(program, 0, gl.Str("color\x00"))
+	brightest := max(l.DiffuseColor.X(), l.DiffuseColor.Y(), l.DiffuseColor.Z(), l.Color.X(), l.Color.Y(), l.Color.Z()) * max(l.DiffuseIntensity, l.AmbientIntensity)
+	c := l.Atten.Constant - 256.0/5*brightest
+	if c >= 0 {
+		return 0
+	}
+	if l.Atten.Exp <= 0 {
+		if l.Atten.Linear <= 0 {
+			return 0
+		}
+		return -c / l.Atten.Linear
+	}
+	return (-l.Atten.Linear + float32(math.Sqrt(float64(l.Atten.Linear*l.Atten.Linear-4*l.Atten.Exp*c)))) / (2 * l.Atten.Exp)
 }
 
 func (l *PointLight) SetPosition(p mgl32.Vec4) {
@@ -130,29 +158,7 @@ func (l *PointLight) SetAttenuation(rang, constant, linear, exp float32) {
 	l.Atten.Exp = exp
 }
 
+// Update 灯光绕 Y 轴旋转
 func (l *PointLight) Update(elapsed float64) {
-	l.model = l.model.Mul4(mgl32.HomogRotate3DY(float32(elapsed)))
 	l.Position = mgl32.HomogRotate3DY(float32(elapsed)).Mul4x1(l.Position)
 }
-
-func (l *PointLight) Render(projection mgl32.Mat4, view mgl32.Mat4, model mgl32.Mat4) {
-	model = model.Mul4(l.model)
-
-	// Shader
-	program := l.shader.Program
-	gl.UseProgram(program)
-
-	gl.UniformMatrix4fv(l.projectionUniform, 1, false, &(projection[0]))
-	gl.UniformMatrix4fv(l.viewUniform, 1, false, &(view[0]))
-	gl.UniformMatrix4fv(l.modelUniform, 1, false, &(model[0]))
-
-	gl.BindFragDataLocation(program, 0, gl.Str("color\x00"))
-
-	gl.PointSize(10)
-	for _, m := range l.Meshes {
-		m.Draw(program)
-	}
-
-	gl.UseProgram(0)
-
-}
@@ -0,0 +1,45 @@
+package light
+
+import "github.com/huangxiaobo/toy-engine/engine/config"
+
+const (
+	// MaxShadowLights 同时投射阴影的点光源数, 每个占用一个纹理单元
+	MaxShadowLights = 2
+
+	DefaultShadowResolution = 512
+	DefaultShadowBias       = 0.15
+	DefaultShadowFar        = 100
+)
+
+// Shadow 点光源的全向阴影, 立方体贴图的每个像素记录该方向上最近的表面到光源的距离除以 Far
+type Shadow struct {
+	Enable     bool
+	Resolution int32
+	Bias       float32
+	Far        float32
+
+	// 由 shadow 包每帧绘制, 0 表示没有可用的阴影贴图
+	CubeMap uint32
+}
+
+func NewShadow(xmlShadow *config.XmlShadow) *Shadow {
+	s := &Shadow{
+		Resolution: DefaultShadowResolution,
+		Bias:       DefaultShadowBias,
+		Far:        DefaultShadowFar,
+	}
+	if xmlShadow == nil {
+		return s
+	}
+	s.Enable = xmlShadow.XMLEnable
+	if xmlShadow.XMLResolution > 0 {
+		s.Resolution = xmlShadow.XMLResolution
+	}
+	if xmlShadow.XMLBias > 0 {
+		s.Bias = xmlShadow.XMLBias
+	}
+	if xmlShadow.XMLFar > 0 {
+		s.Far = xmlShadow.XMLFar
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package light
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+)
+
+// sunThreshold 亮度超过最亮像素该比例的区域视为太阳
+const sunThreshold = 0.5
+
+// SunEstimate 从环境贴图中提取的主方向光
+type SunEstimate struct {
+	// 光线传播方向, 从太阳指向场景
+	Direction mgl32.Vec3
+	// 归一化颜色, 最大分量为 1
+	Color     mgl32.Vec3
+	Intensity float32
+}
+
+// EstimateSun 从等距柱状投影(经纬度)的 HDR 环境贴图中估计太阳
+// 按立体角加权找出最亮的区域, 以亮度加权平均方向作为太阳方向, 区域的辐照度作为颜色和强度
+// 贴图上边为天顶 (+Y), 水平方向 u = 0 对应 -X, u = 0.5 对应 +X
+func EstimateSun(env *texture.HDRImage) (SunEstimate, bool) {
+	if env == nil || env.Width == 0 || env.Height == 0 {
+		return SunEstimate{}, false
+	}
+
+	luminance := make([]float32, env.Width*env.Height)
+	var peak float32
+	for y := 0; y < env.Height; y++ {
+		for x := 0; x < env.Width; x++ {
+			r, g, b := env.At(x, y)
+			l := 0.2126*r + 0.7152*g + 0.0722*b
+			luminance[y*env.Width+x] = l
+			peak = max(peak, l)
+		}
+	}
+	if peak <= 0 {
+		return SunEstimate{}, false
+	}
+
+	var direction, irradiance mgl32.Vec3
+	texelArea := float32(2*math.Pi/float64(env.Width)) * float32(math.Pi/float64(env.Height))
+	for y := 0; y < env.Height; y++ {
+		theta := (float64(y) + 0.5) / float64(env.Height) * math.Pi
+		sinTheta, cosTheta := math.Sincos(theta)
+		solidAngle := texelArea * float32(sinTheta)
+		for x := 0; x < env.Width; x++ {
+			l := luminance[y*env.Width+x]
+			if l < peak*sunThreshold {
+				continue
+			}
+			phi := (float64(x)+0.5)/float64(env.Width)*2*math.Pi - math.Pi
+			sinPhi, cosPhi := math.Sincos(phi)
+			dir := mgl32.Vec3{float32(sinTheta * cosPhi), float32(cosTheta), float32(sinTheta * sinPhi)}
+
+			direction = direction.Add(dir.Mul(l * solidAngle))
+			r, g, b := env.At(x, y)
+			irradiance = irradiance.Add(mgl32.Vec3{r, g, b}.Mul(solidAngle))
+		}
+	}
+	if direction.Len() == 0 {
+		return SunEstimate{}, false
+	}
+
+	intensity := max(irradiance.X(), irradiance.Y(), irradiance.Z())
+	return SunEstimate{
+		Direction: direction.Normalize().Mul(-1),
+		Color:     irradiance.Mul(1 / intensity),
+		Intensity: intensity,
+	}, true
+}
@@ -0,0 +1,389 @@
+package lightmap
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/job"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/terrain"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+const (
+	// Range 光照贴图能表示的最大辐照度, 与着色器中的 LIGHTMAP_RANGE 一致
+	Range = 4
+	// 未覆盖的像素从相邻像素扩展的次数, 覆盖三角形之间的间隔
+	dilatePasses = 2
+)
+
+// Baker 光照贴图烘焙, 对每个像素做余弦加权的半球采样:
+// 没有命中表面的方向计入天空光, 命中的表面计入它受到的点光源直接光照乘以表面颜色(一次反弹)
+// 界面通过 Request 发起烘焙, World 在下一帧的更新阶段执行
+type Baker struct {
+	// 天空光的辐照度, 为 0 时使用所有点光源环境光之和, 与不使用光照贴图时亮度接近
+	Sky mgl32.Vec3
+	// 计算一次反弹, 关闭时只有天空光的遮蔽
+	Bounce bool
+	// 最近一次烘焙的结果
+	Status string
+
+	requested bool
+}
+
+func NewBaker() *Baker {
+	return &Baker{Bounce: true}
+}
+
+// Request 请求在下一帧烘焙
+func (b *Baker) Request() {
+	b.requested = true
+}
+
+// TakeRequest 返回是否有烘焙请求, 只返回一次
+func (b *Baker) TakeRequest() bool {
+	requested := b.requested
+	b.requested = false
+	return requested
+}
+
+// Bake 为所有配置了光照贴图的模型烘焙, 结果写入 Lightmap.File 并替换当前的贴图, 返回写入的文件
+// 不透明的模型, 地形和地面参与遮挡和反弹, 灯光使用调用时的位置, 需在 GL 线程调用
+func (b *Baker) Bake(objs []model.RenderObj, lights []*light.PointLight) ([]string, error) {
+	start := time.Now()
+	s := b.newScene(objs, lights)
+
+	var files []string
+	for _, obj := range objs {
+		m, ok := obj.(*model.Model)
+		if !ok || m.Lightmap == nil {
+			continue
+		}
+		if len(m.Instances) > 0 {
+			logger.Warn(fmt.Sprintf("lightmap: model %s is instanced, skipped", m.Name))
+			continue
+		}
+		img := s.bake(m)
+		if err := writePNG(m.Lightmap.File, img); err != nil {
+			b.Status = err.Error()
+			return files, err
+		}
+		m.SetLightmapImage(img)
+		files = append(files, m.Lightmap.File)
+		logger.Info(fmt.Sprintf("lightmap: %s -> %s", m.Name, m.Lightmap.File))
+	}
+	b.Status = fmt.Sprintf("%d lightmaps, %d triangles, %.1fs", len(files), len(s.bvh.triangles), time.Since(start).Seconds())
+	return files, nil
+}
+
+// scene 烘焙时的世界空间三角形和灯光
+type scene struct {
+	bvh    *bvh
+	lights []*light.PointLight
+	sky    mgl32.Vec3
+	bounce bool
+	// 射线起点沿法线的偏移, 避免与自身相交
+	bias float32
+}
+
+func (b *Baker) newScene(objs []model.RenderObj, lights []*light.PointLight) *scene {
+	var triangles []triangle
+	addMesh := func(mi *mesh.Mesh, transform mgl32.Mat4, albedo mgl32.Vec3) {
+		if mi.DrawMode != gl.TRIANGLES {
+			return
+		}
+		for i := 0; i+2 < len(mi.Indices); i += 3 {
+			a := mgl32.TransformCoordinate(mi.Vertices[mi.Indices[i]].Position, transform)
+			b := mgl32.TransformCoordinate(mi.Vertices[mi.Indices[i+1]].Position, transform)
+			c := mgl32.TransformCoordinate(mi.Vertices[mi.Indices[i+2]].Position, transform)
+			if t, ok := newTriangle(a, b, c, albedo); ok {
+				triangles = append(triangles, t)
+			}
+		}
+	}
+
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *model.Model:
+			if o.Transparent() {
+				continue
+			}
+			transforms := []mgl32.Mat4{o.ModelMatrix()}
+			if len(o.Instances) > 0 {
+				transforms = transforms[:0]
+				for _, instance := range o.Instances {
+					transforms = append(transforms, o.ModelMatrix().Mul4(instance))
+				}
+			}
+			for _, transform := range transforms {
+				for _, mi := range o.Meshes {
+					addMesh(mi, transform, o.MeshMaterial(mi).DiffuseColor)
+				}
+			}
+		case *terrain.Terrain:
+			addMesh(o.Mesh, o.ModelMatrix(), o.Material.DiffuseColor)
+		case *model.Ground:
+			// 地面只绘制网格线, 按覆盖网格范围的水平面处理
+			y := o.Position.Y()
+			corners := [4]mgl32.Vec3{}
+			for i, corner := range [][2]float32{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+				corners[i] = mgl32.Vec3{corner[0] * mesh.GroundHalfWidth, y, corner[1] * mesh.GroundHalfWidth}
+			}
+			for _, tri := range [][3]int{{0, 2, 1}, {1, 2, 3}} {
+				if t, ok := newTriangle(corners[tri[0]], corners[tri[1]], corners[tri[2]], o.Material.DiffuseColor); ok {
+					triangles = append(triangles, t)
+				}
+			}
+		}
+	}
+
+	s := &scene{
+		bvh:    newBVH(triangles),
+		lights: lights,
+		sky:    b.Sky,
+		bounce: b.Bounce,
+	}
+	if s.sky == (mgl32.Vec3{}) {
+		for _, l := range lights {
+			s.sky = s.sky.Add(l.Color.Mul(l.AmbientIntensity))
+		}
+	}
+	s.bias = 1e-4
+	if bounds := s.bvh.bounds(); !bounds.IsEmpty() {
+		s.bias = max(bounds.MaxExtent()*1e-4, s.bias)
+	}
+	return s
+}
+
+// texel 光照贴图中被三角形覆盖的像素及其世界空间位置和法线
+type texel struct {
+	index    int
+	position mgl32.Vec3
+	normal   mgl32.Vec3
+}
+
+// bake 按第二套UV光栅化模型的三角形, 并行计算每个像素的辐照度
+func (s *scene) bake(m *model.Model) *image.RGBA {
+	size := int(m.Lightmap.Size)
+	transform := m.ModelMatrix()
+	normalMatrix := transform.Inv().Transpose()
+
+	var texels []texel
+	for _, mi := range m.Meshes {
+		if mi.DrawMode != gl.TRIANGLES {
+			continue
+		}
+		for i := 0; i+2 < len(mi.Indices); i += 3 {
+			v := [3]mesh.Vertex{mi.Vertices[mi.Indices[i]], mi.Vertices[mi.Indices[i+1]], mi.Vertices[mi.Indices[i+2]]}
+			texels = rasterize(texels, v, size, transform, normalMatrix)
+		}
+	}
+
+	samples := int(m.Lightmap.Samples)
+	irradiance := make([]mgl32.Vec3, len(texels))
+	job.Wait(job.ParallelFor(len(texels), func(begin, end int) {
+		rng := rand.New(rand.NewSource(int64(begin)))
+		for i := begin; i < end; i++ {
+			irradiance[i] = s.irradiance(texels[i].position, texels[i].normal, samples, rng)
+		}
+	}))
+
+	pixels := make([]mgl32.Vec3, size*size)
+	covered := make([]bool, size*size)
+	for i, t := range texels {
+		pixels[t.index] = irradiance[i]
+		covered[t.index] = true
+	}
+	dilate(pixels, covered, size)
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for i, p := range pixels {
+		img.Set(i%size, i/size, encode(p))
+	}
+	return img
+}
+
+// rasterize 像素中心在三角形内的像素, 按重心坐标插值位置和法线
+func rasterize(texels []texel, v [3]mesh.Vertex, size int, transform, normalMatrix mgl32.Mat4) []texel {
+	var p [3]mgl32.Vec2
+	for j := range p {
+		p[j] = v[j].TexCoords2.Mul(float32(size))
+	}
+	cross := func(a, b mgl32.Vec2) float32 {
+		return a.X()*b.Y() - a.Y()*b.X()
+	}
+	e1, e2 := p[1].Sub(p[0]), p[2].Sub(p[0])
+	area := cross(e1, e2)
+	if mgl32.Abs(area) < 1e-8 {
+		return texels
+	}
+
+	minX := max(int(math.Floor(float64(min(p[0].X(), p[1].X(), p[2].X())))), 0)
+	maxX := min(int(math.Ceil(float64(max(p[0].X(), p[1].X(), p[2].X())))), size-1)
+	minY := max(int(math.Floor(float64(min(p[0].Y(), p[1].Y(), p[2].Y())))), 0)
+	maxY := min(int(math.Ceil(float64(max(p[0].Y(), p[1].Y(), p[2].Y())))), size-1)
+
+	faceNormal := mgl32.TransformNormal(v[1].Position.Sub(v[0].Position).Cross(v[2].Position.Sub(v[0].Position)), normalMatrix)
+	const epsilon = -1e-4
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			c := mgl32.Vec2{float32(x) + 0.5, float32(y) + 0.5}.Sub(p[0])
+			w1 := cross(c, e2) / area
+			w2 := cross(e1, c) / area
+			w0 := 1 - w1 - w2
+			if w0 < epsilon || w1 < epsilon || w2 < epsilon {
+				continue
+			}
+			position := v[0].Position.Mul(w0).Add(v[1].Position.Mul(w1)).Add(v[2].Position.Mul(w2))
+			normal := v[0].Normal.Mul(w0).Add(v[1].Normal.Mul(w1)).Add(v[2].Normal.Mul(w2))
+			normal = mgl32.TransformNormal(normal, normalMatrix)
+			if normal.Len() == 0 {
+				normal = faceNormal
+			}
+			if normal.Len() == 0 {
+				continue
+			}
+			texels = append(texels, texel{
+				index:    y*size + x,
+				position: mgl32.TransformCoordinate(position, transform),
+				normal:   normal.Normalize(),
+			})
+		}
+	}
+	return texels
+}
+
+// irradiance 余弦加权的半球采样, 平均值即为辐照度
+func (s *scene) irradiance(position, normal mgl32.Vec3, samples int, rng *rand.Rand) mgl32.Vec3 {
+	origin := position.Add(normal.Mul(s.bias))
+	tangent, bitangent := basis(normal)
+	var sum mgl32.Vec3
+	for i := 0; i < samples; i++ {
+		r := math.Sqrt(rng.Float64())
+		phi := 2 * math.Pi * rng.Float64()
+		x, y := float32(r*math.Cos(phi)), float32(r*math.Sin(phi))
+		z := float32(math.Sqrt(max(1-r*r, 0)))
+		dir := tangent.Mul(x).Add(bitangent.Mul(y)).Add(normal.Mul(z))
+
+		ray := geometry.Ray{Origin: origin, Direction: dir}
+		t, tri, hit := s.bvh.intersect(ray, float32(math.Inf(1)), false)
+		if !hit {
+			sum = sum.Add(s.sky)
+			continue
+		}
+		if !s.bounce {
+			continue
+		}
+		// 模型的朝向不一定一致, 按双面处理
+		hitNormal := tri.normal
+		if hitNormal.Dot(dir) > 0 {
+			hitNormal = hitNormal.Mul(-1)
+		}
+		hitPosition := ray.At(t).Add(hitNormal.Mul(s.bias))
+		direct := s.direct(hitPosition, hitNormal)
+		sum = sum.Add(mgl32.Vec3{direct.X() * tri.albedo.X(), direct.Y() * tri.albedo.Y(), direct.Z() * tri.albedo.Z()})
+	}
+	return sum.Mul(1 / float32(max(samples, 1)))
+}
+
+// direct 点光源的直接光照, 与着色器的漫反射项和衰减一致, 射线检测阴影
+func (s *scene) direct(position, normal mgl32.Vec3) mgl32.Vec3 {
+	var sum mgl32.Vec3
+	for _, l := range s.lights {
+		toLight := l.Position.Vec3().Sub(position)
+		distance := toLight.Len()
+		if distance == 0 {
+			continue
+		}
+		dir := toLight.Mul(1 / distance)
+		factor := normal.Dot(dir)
+		if factor <= 0 {
+			continue
+		}
+		if _, _, hit := s.bvh.intersect(geometry.Ray{Origin: position, Direction: dir}, distance, true); hit {
+			continue
+		}
+		attenuation := l.Atten.Constant + l.Atten.Linear*distance + l.Atten.Exp*distance*distance
+		sum = sum.Add(l.Color.Mul(factor / max(attenuation, 1e-4)))
+	}
+	return sum
+}
+
+// basis 与法线垂直的两个单位向量
+func basis(normal mgl32.Vec3) (mgl32.Vec3, mgl32.Vec3) {
+	axis := mgl32.Vec3{1, 0, 0}
+	if mgl32.Abs(normal.X()) > 0.9 {
+		axis = mgl32.Vec3{0, 1, 0}
+	}
+	tangent := axis.Cross(normal).Normalize()
+	return tangent, normal.Cross(tangent)
+}
+
+// dilate 未覆盖的像素取相邻已覆盖像素的平均值, 避免双线性过滤在三角形边缘采样到黑色
+func dilate(pixels []mgl32.Vec3, covered []bool, size int) {
+	for pass := 0; pass < dilatePasses; pass++ {
+		next := make([]bool, len(covered))
+		copy(next, covered)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				if covered[y*size+x] {
+					continue
+				}
+				var sum mgl32.Vec3
+				count := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						nx, ny := x+dx, y+dy
+						if nx < 0 || ny < 0 || nx >= size || ny >= size || !covered[ny*size+nx] {
+							continue
+						}
+						sum = sum.Add(pixels[ny*size+nx])
+						count++
+					}
+				}
+				if count > 0 {
+					pixels[y*size+x] = sum.Mul(1 / float32(count))
+					next[y*size+x] = true
+				}
+			}
+		}
+		copy(covered, next)
+	}
+}
+
+// encode 按 sqrt(irradiance / Range) 编码, 暗部有更多精度
+func encode(irradiance mgl32.Vec3) color.RGBA {
+	channel := func(v float32) uint8 {
+		return uint8(math.Sqrt(float64(mgl32.Clamp(v/Range, 0, 1)))*255 + 0.5)
+	}
+	return color.RGBA{R: channel(irradiance.X()), G: channel(irradiance.Y()), B: channel(irradiance.Z()), A: 255}
+}
+
+func writePNG(file string, img image.Image) error {
+	file = vfs.WritePath(file)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
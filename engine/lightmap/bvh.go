@@ -0,0 +1,127 @@
+package lightmap
+
+import (
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+)
+
+// 叶子节点最多包含的三角形数
+const leafTriangles = 4
+
+// triangle 世界空间三角形及其表面的漫反射颜色
+type triangle struct {
+	a, b, c mgl32.Vec3
+	normal  mgl32.Vec3
+	albedo  mgl32.Vec3
+}
+
+func newTriangle(a, b, c, albedo mgl32.Vec3) (triangle, bool) {
+	normal := b.Sub(a).Cross(c.Sub(a))
+	if normal.Len() == 0 {
+		return triangle{}, false
+	}
+	return triangle{a: a, b: b, c: c, normal: normal.Normalize(), albedo: albedo}, true
+}
+
+func (t *triangle) centroid() mgl32.Vec3 {
+	return t.a.Add(t.b).Add(t.c).Mul(1.0 / 3)
+}
+
+type bvhNode struct {
+	bounds geometry.AABB
+	// 内部节点的两个子节点, 叶子节点为 -1
+	left, right int32
+	// 叶子节点的三角形区间
+	first, count int32
+}
+
+// bvh 三角形层次包围盒, 按质心最长轴的中位数划分, 用于烘焙时的射线求交
+type bvh struct {
+	nodes     []bvhNode
+	triangles []triangle
+}
+
+func newBVH(triangles []triangle) *bvh {
+	b := &bvh{triangles: triangles}
+	if len(triangles) > 0 {
+		b.build(0, len(triangles))
+	}
+	return b
+}
+
+func (b *bvh) build(first, count int) int32 {
+	bounds, centroids := geometry.NewAABB(), geometry.NewAABB()
+	triangles := b.triangles[first : first+count]
+	for i := range triangles {
+		bounds.Extend(triangles[i].a)
+		bounds.Extend(triangles[i].b)
+		bounds.Extend(triangles[i].c)
+		centroids.Extend(triangles[i].centroid())
+	}
+
+	index := int32(len(b.nodes))
+	b.nodes = append(b.nodes, bvhNode{bounds: bounds, left: -1, right: -1, first: int32(first), count: int32(count)})
+	if count <= leafTriangles {
+		return index
+	}
+
+	size := centroids.Size()
+	axis := 0
+	if size.Y() > size[axis] {
+		axis = 1
+	}
+	if size.Z() > size[axis] {
+		axis = 2
+	}
+	sort.Slice(triangles, func(i, j int) bool {
+		return triangles[i].centroid()[axis] < triangles[j].centroid()[axis]
+	})
+
+	half := count / 2
+	left := b.build(first, half)
+	right := b.build(first+half, count-half)
+	b.nodes[index].left, b.nodes[index].right, b.nodes[index].count = left, right, 0
+	return index
+}
+
+// bounds 所有三角形的包围盒
+func (b *bvh) bounds() geometry.AABB {
+	if len(b.nodes) == 0 {
+		return geometry.NewAABB()
+	}
+	return b.nodes[0].bounds
+}
+
+// intersect 射线在 maxDistance 之内最近的交点, 返回距离和三角形, anyHit 为 true 时找到任意交点即返回
+func (b *bvh) intersect(ray geometry.Ray, maxDistance float32, anyHit bool) (float32, *triangle, bool) {
+	if len(b.nodes) == 0 {
+		return 0, nil, false
+	}
+	var hit *triangle
+	nearest := maxDistance
+	stack := make([]int32, 0, 64)
+	stack = append(stack, 0)
+	for len(stack) > 0 {
+		node := &b.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if t, ok := ray.IntersectAABB(node.bounds); !ok || t > nearest {
+			continue
+		}
+		if node.left >= 0 {
+			stack = append(stack, node.left, node.right)
+			continue
+		}
+		for i := node.first; i < node.first+node.count; i++ {
+			tri := &b.triangles[i]
+			if t, _, _, ok := ray.IntersectTriangle(tri.a, tri.b, tri.c); ok && t > 0 && t < nearest {
+				nearest, hit = t, tri
+				if anyHit {
+					return nearest, hit, true
+				}
+			}
+		}
+	}
+	return nearest, hit, hit != nil
+}
@@ -0,0 +1,411 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// CacheDir 网格缓存目录, 为空时不使用缓存
+var CacheDir = filepath.Join("cache", "mesh")
+
+const (
+	cacheMagic = "TOYMESH\x00"
+	// 缓存格式或加载器的处理结果变化时增加版本号, 旧缓存自动失效
+	cacheVersion = 1
+	cacheExt     = ".toymesh"
+)
+
+// cachePath 模型文件对应的缓存文件
+func cachePath(path string) string {
+	h := fnv.New64a()
+	h.Write([]byte(filepath.ToSlash(filepath.Clean(path))))
+	return filepath.Join(CacheDir, fmt.Sprintf("%s_%016x%s", filepath.Base(path), h.Sum64(), cacheExt))
+}
+
+// fileHash 文件内容的哈希, 文件不存在时为 0
+func fileHash(path string) uint64 {
+	data, err := vfs.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+// materialHash base 中会被缓存的材质覆盖的属性, base 变化时缓存失效
+func materialHash(base *material.Material) uint64 {
+	var buf bytes.Buffer
+	w := &cacheWriter{w: bufio.NewWriter(&buf)}
+	w.material(base)
+	w.w.Flush()
+	h := fnv.New64a()
+	h.Write(buf.Bytes())
+	return h.Sum64()
+}
+
+// Key 模型文件和 base 对应的导入结果标识, 相同时加载结果相同
+func Key(path string, base *material.Material) string {
+	return fmt.Sprintf("%s#%016x", filepath.Clean(path), materialHash(base))
+}
+
+// ReadCache 读取模型文件的网格缓存, 缓存不存在, 版本不同或源文件已修改时返回 false
+// 缓存的材质在 base 的基础上覆盖
+func ReadCache(path string, base *material.Material) (*Scene, bool) {
+	if CacheDir == "" {
+		return nil, false
+	}
+	f, err := os.Open(cachePath(path))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false
+	}
+	r := &cacheReader{r: bufio.NewReader(f), remaining: info.Size()}
+	scene := r.scene(path, base)
+	if r.err != nil {
+		return nil, false
+	}
+	return scene, true
+}
+
+// WriteCache 把加载结果写入网格缓存, 有内嵌贴图的模型不缓存
+func WriteCache(path string, base *material.Material, scene *Scene) error {
+	if CacheDir == "" || len(scene.Images) > 0 {
+		return nil
+	}
+	if err := os.MkdirAll(CacheDir, 0755); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名, 写入中断时不会留下损坏的缓存
+	file := cachePath(path)
+	f, err := os.CreateTemp(CacheDir, filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	w := &cacheWriter{w: bufio.NewWriter(f)}
+	w.scene(path, base, scene)
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	if err := f.Close(); w.err == nil {
+		w.err = err
+	}
+	if w.err == nil {
+		w.err = os.Rename(f.Name(), file)
+	}
+	if w.err != nil {
+		os.Remove(f.Name())
+	}
+	return w.err
+}
+
+// cacheWriter 小端写入, 出错后忽略之后的写入, 最后检查 err
+type cacheWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *cacheWriter) bytes(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+func (w *cacheWriter) uint32(v uint32) {
+	w.bytes(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (w *cacheWriter) uint64(v uint64) {
+	w.bytes(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+func (w *cacheWriter) int(v int) {
+	w.uint32(uint32(int32(v)))
+}
+
+func (w *cacheWriter) float(v float32) {
+	w.uint32(math.Float32bits(v))
+}
+
+func (w *cacheWriter) floats(v []float32) {
+	for _, f := range v {
+		w.float(f)
+	}
+}
+
+func (w *cacheWriter) string(s string) {
+	w.int(len(s))
+	w.bytes([]byte(s))
+}
+
+func (w *cacheWriter) scene(path string, base *material.Material, scene *Scene) {
+	w.bytes([]byte(cacheMagic))
+	w.uint32(cacheVersion)
+	// 顶点结构变化时缓存失效
+	w.uint32(uint32(unsafe.Sizeof(mesh.Vertex{})))
+	w.string(path)
+	w.uint64(materialHash(base))
+	w.int(len(scene.Files))
+	for _, file := range scene.Files {
+		w.string(file)
+		w.uint64(fileHash(file))
+	}
+
+	w.int(len(scene.Materials))
+	for _, mat := range scene.Materials {
+		w.material(mat)
+	}
+
+	w.int(len(scene.Meshes))
+	for _, mi := range scene.Meshes {
+		w.string(mi.Name)
+		w.int(mi.MaterialIndex)
+		w.int(len(mi.Textures))
+		for _, tex := range mi.Textures {
+			w.string(tex.TextureType)
+			w.string(tex.Path)
+		}
+		// 顶点和索引按本机内存布局整块写入
+		w.int(len(mi.Vertices))
+		if len(mi.Vertices) > 0 {
+			w.bytes(unsafe.Slice((*byte)(unsafe.Pointer(&mi.Vertices[0])), len(mi.Vertices)*int(unsafe.Sizeof(mi.Vertices[0]))))
+		}
+		w.int(len(mi.Indices))
+		if len(mi.Indices) > 0 {
+			w.bytes(unsafe.Slice((*byte)(unsafe.Pointer(&mi.Indices[0])), len(mi.Indices)*4))
+		}
+	}
+
+	w.nodes(scene.Nodes)
+}
+
+// material 加载器从模型文件读取的材质属性, 其他属性来自 base
+func (w *cacheWriter) material(mat *material.Material) {
+	w.string(mat.Name)
+	w.floats(mat.AmbientColor[:])
+	w.floats(mat.DiffuseColor[:])
+	w.floats(mat.SpecularColor[:])
+	w.float(mat.Shininess)
+	w.int(int(mat.BlendMode))
+	w.float(mat.Opacity)
+	w.floats(mat.EmissiveColor[:])
+	w.float(mat.EmissiveIntensity)
+	w.string(mat.EmissiveMap)
+}
+
+func (w *cacheWriter) nodes(nodes []*Node) {
+	w.int(len(nodes))
+	for _, node := range nodes {
+		w.string(node.Name)
+		w.floats(node.Local[:])
+		w.floats(node.World[:])
+		w.int(len(node.Meshes))
+		for _, index := range node.Meshes {
+			w.int(index)
+		}
+		w.nodes(node.Children)
+	}
+}
+
+// cacheReader 与 cacheWriter 对应, 出错后之后的读取返回零值
+// remaining 为文件中未读的字节数, 分配内存前检查, 损坏或截断的缓存不会按错误的数量分配
+type cacheReader struct {
+	r         *bufio.Reader
+	remaining int64
+	err       error
+}
+
+// maxCacheCount 数量字段的上限, 防止损坏的缓存分配过多内存
+const maxCacheCount = 1 << 26
+
+func (r *cacheReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *cacheReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if int64(n) > r.remaining {
+		r.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	r.remaining -= int64(n)
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		r.fail(err)
+		return nil
+	}
+	return b
+}
+
+func (r *cacheReader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *cacheReader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (r *cacheReader) int() int {
+	return int(int32(r.uint32()))
+}
+
+func (r *cacheReader) count() int {
+	n := r.int()
+	if n < 0 || n > maxCacheCount {
+		r.fail(errors.New("invalid count"))
+		return 0
+	}
+	return n
+}
+
+// elements 读取元素数量, 每个元素 size 字节, 超过文件剩余字节数时失败
+func (r *cacheReader) elements(size int) int {
+	n := r.count()
+	if int64(n)*int64(size) > r.remaining {
+		r.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	return n
+}
+
+func (r *cacheReader) float() float32 {
+	return math.Float32frombits(r.uint32())
+}
+
+func (r *cacheReader) floats(v []float32) {
+	for i := range v {
+		v[i] = r.float()
+	}
+}
+
+func (r *cacheReader) string() string {
+	return string(r.bytes(r.count()))
+}
+
+func (r *cacheReader) scene(path string, base *material.Material) *Scene {
+	if string(r.bytes(len(cacheMagic))) != cacheMagic || r.uint32() != cacheVersion || r.uint32() != uint32(unsafe.Sizeof(mesh.Vertex{})) {
+		r.fail(errors.New("invalid header"))
+		return nil
+	}
+	if r.string() != path || r.uint64() != materialHash(base) {
+		r.fail(errors.New("stale cache"))
+		return nil
+	}
+	scene := &Scene{}
+	for i, n := 0, r.count(); i < n && r.err == nil; i++ {
+		file := r.string()
+		if r.uint64() != fileHash(file) {
+			r.fail(errors.New("stale cache"))
+			return nil
+		}
+		scene.Files = append(scene.Files, file)
+	}
+
+	for i, n := 0, r.count(); i < n && r.err == nil; i++ {
+		mat := *base
+		// 纹理坐标动画按材质独立累加
+		mat.UV = base.UV.Clone()
+		r.material(&mat)
+		scene.Materials = append(scene.Materials, &mat)
+	}
+
+	for i, n := 0, r.count(); i < n && r.err == nil; i++ {
+		name := r.string()
+		materialIndex := r.int()
+		var textures []texture.Texture
+		for j, m := 0, r.count(); j < m && r.err == nil; j++ {
+			tex := texture.Texture{TextureType: r.string()}
+			tex.Path = r.string()
+			textures = append(textures, tex)
+		}
+		vertices := make([]mesh.Vertex, r.elements(int(unsafe.Sizeof(mesh.Vertex{}))))
+		if len(vertices) > 0 {
+			b := r.bytes(len(vertices) * int(unsafe.Sizeof(vertices[0])))
+			copy(unsafe.Slice((*byte)(unsafe.Pointer(&vertices[0])), len(b)), b)
+		}
+		indices := make([]uint32, r.elements(4))
+		if len(indices) > 0 {
+			b := r.bytes(len(indices) * 4)
+			copy(unsafe.Slice((*byte)(unsafe.Pointer(&indices[0])), len(b)), b)
+		}
+		if r.err != nil {
+			return nil
+		}
+		for _, index := range indices {
+			if int(index) >= len(vertices) {
+				r.fail(errors.New("index out of range"))
+				return nil
+			}
+		}
+		ms := mesh.NewMesh(vertices, indices, textures)
+		ms.Name = name
+		ms.MaterialIndex = materialIndex
+		scene.Meshes = append(scene.Meshes, ms)
+	}
+
+	scene.Nodes = r.nodes(0)
+	return scene
+}
+
+func (r *cacheReader) material(mat *material.Material) {
+	mat.Name = r.string()
+	r.floats(mat.AmbientColor[:])
+	r.floats(mat.DiffuseColor[:])
+	r.floats(mat.SpecularColor[:])
+	mat.Shininess = r.float()
+	mat.BlendMode = material.BlendMode(r.int())
+	mat.Opacity = r.float()
+	r.floats(mat.EmissiveColor[:])
+	mat.EmissiveIntensity = r.float()
+	mat.EmissiveMap = r.string()
+}
+
+func (r *cacheReader) nodes(depth int) []*Node {
+	n := r.count()
+	if depth > 1024 {
+		r.fail(errors.New("node hierarchy too deep"))
+		return nil
+	}
+	var nodes []*Node
+	for i := 0; i < n && r.err == nil; i++ {
+		node := &Node{Name: r.string()}
+		r.floats(node.Local[:])
+		r.floats(node.World[:])
+		for j, m := 0, r.count(); j < m && r.err == nil; j++ {
+			node.Meshes = append(node.Meshes, r.int())
+		}
+		node.Children = r.nodes(depth + 1)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
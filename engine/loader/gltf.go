@@ -0,0 +1,840 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/job"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// IsGLTF 是否为 glTF 2.0 文件(.gltf 或 .glb)
+func IsGLTF(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return ext == ".gltf" || ext == ".glb"
+}
+
+// LoadGLTF 加载 glTF 2.0 文件, 不依赖 assimp
+// base 提供文件中没有的材质属性(环境光, UV 动画, 细节贴图等), 文件中的材质在它的基础上覆盖
+func LoadGLTF(path string, base *material.Material) (*Scene, error) {
+	data, err := vfs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseGLTF(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("gltf %s: %v", path, err)
+	}
+	scene, err := doc.build(base)
+	if err != nil {
+		return nil, fmt.Errorf("gltf %s: %v", path, err)
+	}
+	return scene, nil
+}
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A
+	glbChunkBIN  = 0x004E4942
+)
+
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+const (
+	modeTriangles     = 4
+	modeTriangleStrip = 5
+	modeTriangleFan   = 6
+)
+
+// supportedExtensions 可以出现在 extensionsRequired 中的扩展
+var supportedExtensions = []string{"KHR_materials_emissive_strength"}
+
+type gltfDocument struct {
+	Asset struct {
+		Version string `json:"version"`
+	} `json:"asset"`
+	ExtensionsRequired []string `json:"extensionsRequired"`
+	Scene              *int     `json:"scene"`
+	Scenes             []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []struct {
+		URI        string `json:"uri"`
+		ByteLength int    `json:"byteLength"`
+	} `json:"buffers"`
+	Materials []gltfMaterial `json:"materials"`
+	Textures  []struct {
+		Source *int `json:"source"`
+	} `json:"textures"`
+	Images []struct {
+		URI        string `json:"uri"`
+		BufferView *int   `json:"bufferView"`
+	} `json:"images"`
+
+	path    string
+	dir     string
+	buffers [][]byte
+	// 读取的外部缓冲文件
+	files []string
+}
+
+type gltfNode struct {
+	Name        string      `json:"name"`
+	Children    []int       `json:"children"`
+	Mesh        *int        `json:"mesh"`
+	Matrix      []float32   `json:"matrix"`
+	Translation *mgl32.Vec3 `json:"translation"`
+	Rotation    *mgl32.Vec4 `json:"rotation"`
+	Scale       *mgl32.Vec3 `json:"scale"`
+}
+
+type gltfMesh struct {
+	Name       string `json:"name"`
+	Primitives []struct {
+		Attributes map[string]int `json:"attributes"`
+		Indices    *int           `json:"indices"`
+		Material   *int           `json:"material"`
+		Mode       *int           `json:"mode"`
+	} `json:"primitives"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfTextureInfo struct {
+	Index    int `json:"index"`
+	TexCoord int `json:"texCoord"`
+}
+
+type gltfMaterial struct {
+	Name                 string `json:"name"`
+	PbrMetallicRoughness *struct {
+		BaseColorFactor  *mgl32.Vec4      `json:"baseColorFactor"`
+		BaseColorTexture *gltfTextureInfo `json:"baseColorTexture"`
+		MetallicFactor   *float32         `json:"metallicFactor"`
+		RoughnessFactor  *float32         `json:"roughnessFactor"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture   *gltfTextureInfo `json:"normalTexture"`
+	EmissiveTexture *gltfTextureInfo `json:"emissiveTexture"`
+	EmissiveFactor  mgl32.Vec3       `json:"emissiveFactor"`
+	AlphaMode       string           `json:"alphaMode"`
+	Extensions      struct {
+		EmissiveStrength *struct {
+			EmissiveStrength float32 `json:"emissiveStrength"`
+		} `json:"KHR_materials_emissive_strength"`
+	} `json:"extensions"`
+}
+
+// parseGLTF 解析 JSON(.gltf) 或二进制(.glb) 格式并读取所有缓冲
+func parseGLTF(path string, data []byte) (*gltfDocument, error) {
+	var bin []byte
+	jsonData := data
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == glbMagic {
+		var err error
+		if jsonData, bin, err = parseGLB(data); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := &gltfDocument{path: path, dir: filepath.Dir(path)}
+	if err := json.Unmarshal(jsonData, doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("unsupported version %q", doc.Asset.Version)
+	}
+	for _, ext := range doc.ExtensionsRequired {
+		if !slices.Contains(supportedExtensions, ext) {
+			return nil, fmt.Errorf("required extension %s is not supported", ext)
+		}
+	}
+
+	doc.buffers = make([][]byte, len(doc.Buffers))
+	for i, buffer := range doc.Buffers {
+		var err error
+		switch {
+		case buffer.URI == "" && i == 0 && bin != nil:
+			// GLB 的第一个没有 uri 的缓冲是 BIN 块
+			doc.buffers[i] = bin
+		case buffer.URI == "":
+			err = errors.New("buffer has no data")
+		default:
+			if !strings.HasPrefix(buffer.URI, "data:") {
+				doc.files = append(doc.files, doc.uriPath(buffer.URI))
+			}
+			doc.buffers[i], err = doc.readURI(buffer.URI)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("buffer %d: %v", i, err)
+		}
+		if len(doc.buffers[i]) < buffer.ByteLength {
+			return nil, fmt.Errorf("buffer %d: %d bytes, expected %d", i, len(doc.buffers[i]), buffer.ByteLength)
+		}
+	}
+	return doc, nil
+}
+
+// parseGLB 拆分 GLB 的 JSON 块和 BIN 块
+func parseGLB(data []byte) (jsonData, bin []byte, err error) {
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		return nil, nil, fmt.Errorf("unsupported glb version %d", version)
+	}
+	length := int(binary.LittleEndian.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, errors.New("truncated glb")
+	}
+	for offset := 12; offset+8 <= length; {
+		chunkLength := int(binary.LittleEndian.Uint32(data[offset:]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4:])
+		offset += 8
+		if offset+chunkLength > length {
+			return nil, nil, errors.New("truncated glb chunk")
+		}
+		chunk := data[offset : offset+chunkLength]
+		switch chunkType {
+		case glbChunkJSON:
+			jsonData = chunk
+		case glbChunkBIN:
+			if bin == nil {
+				bin = chunk
+			}
+		}
+		offset += chunkLength
+	}
+	if jsonData == nil {
+		return nil, nil, errors.New("glb has no json chunk")
+	}
+	return jsonData, bin, nil
+}
+
+// readURI 读取 data URI 或相对于模型文件的外部文件
+func (d *gltfDocument) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		header, payload, ok := strings.Cut(uri, ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, errors.New("unsupported data uri")
+		}
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	return vfs.ReadFile(d.uriPath(uri))
+}
+
+func (d *gltfDocument) uriPath(uri string) string {
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		uri = unescaped
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(uri))
+}
+
+// bufferView 缓冲视图的数据
+func (d *gltfDocument) bufferView(index int) ([]byte, *gltfBufferView, error) {
+	if index < 0 || index >= len(d.BufferViews) {
+		return nil, nil, fmt.Errorf("buffer view %d out of range", index)
+	}
+	view := &d.BufferViews[index]
+	if view.Buffer < 0 || view.Buffer >= len(d.buffers) {
+		return nil, nil, fmt.Errorf("buffer %d out of range", view.Buffer)
+	}
+	buffer := d.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteStride < 0 ||
+		view.ByteOffset > len(buffer) || view.ByteLength > len(buffer)-view.ByteOffset {
+		return nil, nil, fmt.Errorf("buffer view %d exceeds buffer", index)
+	}
+	return buffer[view.ByteOffset : view.ByteOffset+view.ByteLength], view, nil
+}
+
+func componentCount(accessorType string) int {
+	switch accessorType {
+	case "SCALAR":
+		return 1
+	case "VEC2":
+		return 2
+	case "VEC3":
+		return 3
+	case "VEC4", "MAT2":
+		return 4
+	case "MAT3":
+		return 9
+	case "MAT4":
+		return 16
+	}
+	return 0
+}
+
+func componentSize(componentType int) int {
+	switch componentType {
+	case componentByte, componentUnsignedByte:
+		return 1
+	case componentShort, componentUnsignedShort:
+		return 2
+	case componentUnsignedInt, componentFloat:
+		return 4
+	}
+	return 0
+}
+
+// readComponent 读取一个分量, 归一化的整数转换到 [0,1] 或 [-1,1]
+func readComponent(b []byte, componentType int, normalized bool) float32 {
+	switch componentType {
+	case componentFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	case componentUnsignedByte:
+		if normalized {
+			return float32(b[0]) / 255
+		}
+		return float32(b[0])
+	case componentByte:
+		if normalized {
+			return max(float32(int8(b[0]))/127, -1)
+		}
+		return float32(int8(b[0]))
+	case componentUnsignedShort:
+		v := binary.LittleEndian.Uint16(b)
+		if normalized {
+			return float32(v) / 65535
+		}
+		return float32(v)
+	case componentShort:
+		v := int16(binary.LittleEndian.Uint16(b))
+		if normalized {
+			return max(float32(v)/32767, -1)
+		}
+		return float32(v)
+	case componentUnsignedInt:
+		return float32(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+
+// maxZeroAccessorCount 没有缓冲视图的访问器的数量上限, 这种访问器全部为 0, 数量不受文件大小限制
+const maxZeroAccessorCount = 1 << 20
+
+// accessorData 校验过的访问器, data 为 nil 时所有元素为 0
+type accessorData struct {
+	*gltfAccessor
+	data        []byte
+	stride      int
+	comps, size int
+}
+
+// accessor 校验访问器的类型和数量, 数量不超过缓冲视图能容纳的元素数,
+// 调用方可以按 Count 分配内存, 损坏的文件返回错误而不是在分配或解析时 panic
+func (d *gltfDocument) accessor(index int) (*accessorData, error) {
+	if index < 0 || index >= len(d.Accessors) {
+		return nil, fmt.Errorf("accessor %d out of range", index)
+	}
+	acc := &d.Accessors[index]
+	if acc.Count < 0 || acc.ByteOffset < 0 {
+		return nil, fmt.Errorf("accessor %d: negative count or byte offset", index)
+	}
+	if len(acc.Sparse) > 0 {
+		return nil, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	comps, size := componentCount(acc.Type), componentSize(acc.ComponentType)
+	if comps == 0 || size == 0 {
+		return nil, fmt.Errorf("accessor %d: unsupported type %s/%d", index, acc.Type, acc.ComponentType)
+	}
+	if acc.BufferView == nil {
+		if acc.Count > maxZeroAccessorCount {
+			return nil, fmt.Errorf("accessor %d: count %d without buffer view", index, acc.Count)
+		}
+		return &accessorData{gltfAccessor: acc, comps: comps, size: size}, nil
+	}
+	data, view, err := d.bufferView(*acc.BufferView)
+	if err != nil {
+		return nil, err
+	}
+	elemSize := comps * size
+	stride := elemSize
+	if view.ByteStride > 0 {
+		stride = view.ByteStride
+	}
+	// 用除法比较, 避免巨大的 count 乘以步长后溢出
+	avail := len(data) - acc.ByteOffset - elemSize
+	if acc.Count > 0 && (avail < 0 || acc.Count-1 > avail/stride) {
+		return nil, fmt.Errorf("accessor %d exceeds buffer view", index)
+	}
+	return &accessorData{gltfAccessor: acc, data: data, stride: stride, comps: comps, size: size}, nil
+}
+
+// read 按元素读取校验过的访问器, 每个元素调用一次 fn, 分量转换为 float32
+func (acc *accessorData) read(fn func(i int, v []float32)) {
+	v := make([]float32, acc.comps)
+	for i := 0; i < acc.Count; i++ {
+		if acc.data != nil {
+			elem := acc.data[acc.ByteOffset+i*acc.stride:]
+			for c := range v {
+				v[c] = readComponent(elem[c*acc.size:], acc.ComponentType, acc.Normalized)
+			}
+		}
+		fn(i, v)
+	}
+}
+
+func (d *gltfDocument) readIndices(index int) ([]uint32, error) {
+	acc, err := d.accessor(index)
+	if err != nil {
+		return nil, err
+	}
+	switch acc.ComponentType {
+	case componentUnsignedByte, componentUnsignedShort, componentUnsignedInt:
+	default:
+		return nil, fmt.Errorf("accessor %d: invalid index type %d", index, acc.ComponentType)
+	}
+	if acc.Type != "SCALAR" {
+		return nil, fmt.Errorf("accessor %d: index type must be SCALAR, got %s", index, acc.Type)
+	}
+	indices := make([]uint32, acc.Count)
+	acc.read(func(i int, v []float32) {
+		indices[i] = uint32(v[0])
+	})
+	return indices, nil
+}
+
+// primitiveRef 节点引用的一个图元, 按节点树顺序收集后并行解析
+type primitiveRef struct {
+	node      *Node
+	mesh      int
+	primitive int
+}
+
+func (d *gltfDocument) build(base *material.Material) (*Scene, error) {
+	scene := &Scene{Images: make(map[string]*image.RGBA), Files: append([]string{d.path}, d.files...)}
+
+	materialTextures, embedded := d.collectTextures()
+	for i := range d.Materials {
+		scene.Materials = append(scene.Materials, d.material(&d.Materials[i], base))
+	}
+
+	var refs []primitiveRef
+	var visit func(index int, parent mgl32.Mat4, depth int) (*Node, error)
+	visit = func(index int, parent mgl32.Mat4, depth int) (*Node, error) {
+		if index < 0 || index >= len(d.Nodes) {
+			return nil, fmt.Errorf("node %d out of range", index)
+		}
+		if depth > len(d.Nodes) {
+			return nil, errors.New("node hierarchy has a cycle")
+		}
+		gNode := &d.Nodes[index]
+		node := &Node{Name: gNode.Name, Local: gNode.transform()}
+		node.World = parent.Mul4(node.Local)
+		if gNode.Mesh != nil {
+			if *gNode.Mesh < 0 || *gNode.Mesh >= len(d.Meshes) {
+				return nil, fmt.Errorf("mesh %d out of range", *gNode.Mesh)
+			}
+			for p := range d.Meshes[*gNode.Mesh].Primitives {
+				node.Meshes = append(node.Meshes, len(refs))
+				refs = append(refs, primitiveRef{node: node, mesh: *gNode.Mesh, primitive: p})
+			}
+		}
+		for _, child := range gNode.Children {
+			childNode, err := visit(child, node.World, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, childNode)
+		}
+		return node, nil
+	}
+	for _, root := range d.rootNodes() {
+		node, err := visit(root, mgl32.Ident4(), 0)
+		if err != nil {
+			return nil, err
+		}
+		scene.Nodes = append(scene.Nodes, node)
+	}
+
+	meshes := make([]*mesh.Mesh, len(refs))
+	errs := make([]error, len(refs))
+	job.Wait(job.ParallelFor(len(refs), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			meshes[i], errs[i] = d.primitive(refs[i], materialTextures)
+		}
+	}))
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	scene.Meshes = meshes
+
+	images := make([]*image.RGBA, len(embedded))
+	errs = make([]error, len(embedded))
+	job.Wait(job.ParallelFor(len(embedded), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			images[i], errs[i] = d.decodeImage(embedded[i])
+		}
+	}))
+	for i, index := range embedded {
+		if errs[i] != nil {
+			// 损坏的贴图使用错误贴图, 不影响模型加载
+			logger.Error(fmt.Errorf("gltf %s: image %d: %v", d.path, index, errs[i]))
+			images[i] = texture.ErrorImage()
+		}
+		scene.Images[d.imagePath(index)] = images[i]
+	}
+	return scene, nil
+}
+
+// rootNodes 默认场景的根节点, 没有场景时使用所有不是子节点的节点
+func (d *gltfDocument) rootNodes() []int {
+	if len(d.Scenes) > 0 {
+		index := 0
+		if d.Scene != nil && *d.Scene >= 0 && *d.Scene < len(d.Scenes) {
+			index = *d.Scene
+		}
+		return d.Scenes[index].Nodes
+	}
+	isChild := make([]bool, len(d.Nodes))
+	for _, node := range d.Nodes {
+		for _, child := range node.Children {
+			if child >= 0 && child < len(isChild) {
+				isChild[child] = true
+			}
+		}
+	}
+	var roots []int
+	for i := range d.Nodes {
+		if !isChild[i] {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+// transform 节点的局部变换, matrix 优先于 TRS
+func (n *gltfNode) transform() mgl32.Mat4 {
+	if len(n.Matrix) == 16 {
+		var m mgl32.Mat4
+		copy(m[:], n.Matrix)
+		return m
+	}
+	m := mgl32.Ident4()
+	if n.Translation != nil {
+		m = mgl32.Translate3D(n.Translation.X(), n.Translation.Y(), n.Translation.Z())
+	}
+	if r := n.Rotation; r != nil {
+		m = m.Mul4(mgl32.Quat{W: r.W(), V: mgl32.Vec3{r.X(), r.Y(), r.Z()}}.Normalize().Mat4())
+	}
+	if n.Scale != nil {
+		m = m.Mul4(mgl32.Scale3D(n.Scale.X(), n.Scale.Y(), n.Scale.Z()))
+	}
+	return m
+}
+
+// imagePath 贴图在网格 Textures 中的路径, 内嵌贴图使用 "文件#image序号"
+func (d *gltfDocument) imagePath(index int) string {
+	if d.external(index) {
+		return d.uriPath(d.Images[index].URI)
+	}
+	return fmt.Sprintf("%s#image%d", d.path, index)
+}
+
+// external 图片是否为外部文件, 否则内嵌在缓冲或 data URI 中
+func (d *gltfDocument) external(index int) bool {
+	img := d.Images[index]
+	return img.BufferView == nil && !strings.HasPrefix(img.URI, "data:")
+}
+
+func (d *gltfDocument) decodeImage(index int) (*image.RGBA, error) {
+	img := d.Images[index]
+	var data []byte
+	var err error
+	if img.BufferView != nil {
+		data, _, err = d.bufferView(*img.BufferView)
+	} else {
+		data, err = d.readURI(img.URI)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return texture.DecodeImage(bytes.NewReader(data))
+}
+
+// textureImage 纹理引用的图片序号
+func (d *gltfDocument) textureImage(info *gltfTextureInfo) (int, bool) {
+	if info == nil || info.Index < 0 || info.Index >= len(d.Textures) {
+		return 0, false
+	}
+	source := d.Textures[info.Index].Source
+	if source == nil || *source < 0 || *source >= len(d.Images) {
+		return 0, false
+	}
+	if info.TexCoord != 0 {
+		logger.Warn(fmt.Sprintf("gltf %s: texture %d uses TEXCOORD_%d, only TEXCOORD_0 is supported", d.path, info.Index, info.TexCoord))
+	}
+	return *source, true
+}
+
+// collectTextures 每个材质的网格贴图(基础颜色和法线), 以及需要解码的内嵌图片
+func (d *gltfDocument) collectTextures() ([][]texture.Texture, []int) {
+	materialTextures := make([][]texture.Texture, len(d.Materials))
+	var embedded []int
+	add := func(i int, info *gltfTextureInfo, textureType string) {
+		index, ok := d.textureImage(info)
+		if !ok {
+			return
+		}
+		materialTextures[i] = append(materialTextures[i], texture.Texture{TextureType: textureType, Path: d.imagePath(index)})
+		if !d.external(index) && !slices.Contains(embedded, index) {
+			embedded = append(embedded, index)
+		}
+	}
+	for i, gMaterial := range d.Materials {
+		if pbr := gMaterial.PbrMetallicRoughness; pbr != nil {
+			add(i, pbr.BaseColorTexture, texture.TextureDiffuse)
+		}
+		add(i, gMaterial.NormalTexture, texture.TextureNormal)
+	}
+	return materialTextures, embedded
+}
+
+// material 把金属度/粗糙度材质近似转换为引擎的 Phong 材质
+func (d *gltfDocument) material(gMaterial *gltfMaterial, base *material.Material) *material.Material {
+	mat := *base
+	// 纹理坐标动画按材质独立累加
+	mat.UV = base.UV.Clone()
+	if gMaterial.Name != "" {
+		mat.Name = gMaterial.Name
+	}
+
+	baseColor := mgl32.Vec4{1, 1, 1, 1}
+	metallic, roughness := float32(1), float32(1)
+	if pbr := gMaterial.PbrMetallicRoughness; pbr != nil {
+		if pbr.BaseColorFactor != nil {
+			baseColor = *pbr.BaseColorFactor
+		}
+		if pbr.MetallicFactor != nil {
+			metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			roughness = *pbr.RoughnessFactor
+		}
+	}
+	mat.DiffuseColor = baseColor.Vec3()
+	// 非金属的镜面反射率约为 0.04, 金属的镜面反射颜色为基础颜色
+	dielectric := mgl32.Vec3{0.04, 0.04, 0.04}
+	mat.SpecularColor = dielectric.Add(baseColor.Vec3().Sub(dielectric).Mul(metallic))
+	mat.Shininess = material.ShininessFromRoughness(roughness)
+
+	if strings.EqualFold(gMaterial.AlphaMode, "BLEND") {
+		mat.BlendMode = material.BlendAlpha
+		mat.Opacity = baseColor.W()
+	}
+
+	if emissive := gMaterial.EmissiveFactor; emissive.X() > 0 || emissive.Y() > 0 || emissive.Z() > 0 {
+		mat.EmissiveColor = emissive
+		mat.EmissiveIntensity = max(mat.EmissiveIntensity, 1)
+		if strength := gMaterial.Extensions.EmissiveStrength; strength != nil {
+			mat.EmissiveIntensity = strength.EmissiveStrength
+		}
+	}
+	// 自发光贴图按文件路径异步加载, 只支持外部文件
+	if index, ok := d.textureImage(gMaterial.EmissiveTexture); ok && d.external(index) {
+		if uri, err := url.PathUnescape(d.Images[index].URI); err == nil {
+			mat.EmissiveMap = filepath.FromSlash(uri)
+		}
+	}
+	return &mat
+}
+
+// primitive 解析一个图元, 把节点的变换烘焙到顶点
+func (d *gltfDocument) primitive(ref primitiveRef, materialTextures [][]texture.Texture) (*mesh.Mesh, error) {
+	gMesh := &d.Meshes[ref.mesh]
+	prim := &gMesh.Primitives[ref.primitive]
+	name := gMesh.Name
+	if len(gMesh.Primitives) > 1 {
+		name = fmt.Sprintf("%s_%d", name, ref.primitive)
+	}
+	fail := func(err error) (*mesh.Mesh, error) {
+		return nil, fmt.Errorf("mesh %q: %v", name, err)
+	}
+
+	position, ok := prim.Attributes["POSITION"]
+	if !ok {
+		return fail(errors.New("primitive has no POSITION"))
+	}
+	positionAccessor, err := d.accessor(position)
+	if err != nil {
+		return fail(err)
+	}
+	vertices := make([]mesh.Vertex, positionAccessor.Count)
+	// types 为属性允许的访问器类型, 回调按类型的分量数读取 v
+	attributes := []struct {
+		name  string
+		types []string
+		fn    func(i int, v []float32)
+	}{
+		{"POSITION", []string{"VEC3"}, func(i int, v []float32) { vertices[i].Position = mgl32.Vec3{v[0], v[1], v[2]} }},
+		{"NORMAL", []string{"VEC3"}, func(i int, v []float32) { vertices[i].Normal = mgl32.Vec3{v[0], v[1], v[2]} }},
+		{"TEXCOORD_0", []string{"VEC2"}, func(i int, v []float32) { vertices[i].TexCoords = mgl32.Vec2{v[0], v[1]} }},
+		{"TEXCOORD_1", []string{"VEC2"}, func(i int, v []float32) { vertices[i].TexCoords2 = mgl32.Vec2{v[0], v[1]} }},
+		{"COLOR_0", []string{"VEC3", "VEC4"}, func(i int, v []float32) { vertices[i].Color = mgl32.Vec3{v[0], v[1], v[2]} }},
+		// 副切线由法线和切线叉乘得到, w 表示方向
+		{"TANGENT", []string{"VEC4"}, func(i int, v []float32) {
+			vertices[i].Tangent = mgl32.Vec3{v[0], v[1], v[2]}
+			vertices[i].Bitangent = mgl32.Vec3{v[3], v[3], v[3]}
+		}},
+	}
+	for _, attribute := range attributes {
+		index, ok := prim.Attributes[attribute.name]
+		if !ok {
+			continue
+		}
+		acc, err := d.accessor(index)
+		if err != nil {
+			return fail(err)
+		}
+		if acc.Count != len(vertices) {
+			return fail(fmt.Errorf("%s accessor does not match POSITION", attribute.name))
+		}
+		if !slices.Contains(attribute.types, acc.Type) {
+			return fail(fmt.Errorf("%s accessor has type %s, expected %s", attribute.name, acc.Type, strings.Join(attribute.types, " or ")))
+		}
+		acc.read(attribute.fn)
+	}
+	_, hasTexCoords2 := prim.Attributes["TEXCOORD_1"]
+	_, hasTangents := prim.Attributes["TANGENT"]
+	for i := range vertices {
+		v := &vertices[i]
+		// 第二套UV, 没有时与第一套相同
+		if !hasTexCoords2 {
+			v.TexCoords2 = v.TexCoords
+		}
+		if hasTangents {
+			v.Bitangent = v.Normal.Cross(v.Tangent).Mul(v.Bitangent.X())
+		}
+	}
+
+	var indices []uint32
+	if prim.Indices != nil {
+		if indices, err = d.readIndices(*prim.Indices); err != nil {
+			return fail(err)
+		}
+		for _, index := range indices {
+			if int(index) >= len(vertices) {
+				return fail(fmt.Errorf("index %d out of range", index))
+			}
+		}
+	} else {
+		indices = make([]uint32, len(vertices))
+		for i := range indices {
+			indices[i] = uint32(i)
+		}
+	}
+
+	mode := modeTriangles
+	if prim.Mode != nil {
+		mode = *prim.Mode
+	}
+	switch mode {
+	case modeTriangles:
+		indices = indices[:len(indices)/3*3]
+	case modeTriangleStrip:
+		indices = stripToTriangles(indices)
+	case modeTriangleFan:
+		indices = fanToTriangles(indices)
+	default:
+		return fail(fmt.Errorf("primitive mode %d is not supported", mode))
+	}
+
+	bakeTransform(vertices, indices, ref.node.World)
+
+	var textures []texture.Texture
+	materialIndex := -1
+	if prim.Material != nil && *prim.Material >= 0 && *prim.Material < len(d.Materials) {
+		materialIndex = *prim.Material
+		textures = slices.Clone(materialTextures[materialIndex])
+	}
+	ms := mesh.NewMesh(vertices, indices, textures)
+	ms.Name = name
+	ms.MaterialIndex = materialIndex
+	return ms, nil
+}
+
+// bakeTransform 把节点变换应用到顶点, 镜像变换时翻转三角形绕序
+func bakeTransform(vertices []mesh.Vertex, indices []uint32, transform mgl32.Mat4) {
+	if transform == mgl32.Ident4() {
+		return
+	}
+	linear := transform.Mat3()
+	normalMatrix := linear.Inv().Transpose()
+	for i := range vertices {
+		v := &vertices[i]
+		v.Position = transform.Mul4x1(v.Position.Vec4(1)).Vec3()
+		v.Normal = normalize(normalMatrix.Mul3x1(v.Normal))
+		v.Tangent = normalize(linear.Mul3x1(v.Tangent))
+		v.Bitangent = normalize(linear.Mul3x1(v.Bitangent))
+	}
+	if linear.Det() < 0 {
+		for i := 0; i+2 < len(indices); i += 3 {
+			indices[i+1], indices[i+2] = indices[i+2], indices[i+1]
+		}
+	}
+}
+
+func normalize(v mgl32.Vec3) mgl32.Vec3 {
+	if v.Len() == 0 {
+		return v
+	}
+	return v.Normalize()
+}
+
+func stripToTriangles(strip []uint32) []uint32 {
+	var indices []uint32
+	for i := 0; i+2 < len(strip); i++ {
+		// 奇数三角形交换顶点保持绕序一致
+		if i%2 == 0 {
+			indices = append(indices, strip[i], strip[i+1], strip[i+2])
+		} else {
+			indices = append(indices, strip[i+1], strip[i], strip[i+2])
+		}
+	}
+	return indices
+}
+
+func fanToTriangles(fan []uint32) []uint32 {
+	var indices []uint32
+	for i := 1; i+1 < len(fan); i++ {
+		indices = append(indices, fan[0], fan[i], fan[i+1])
+	}
+	return indices
+}
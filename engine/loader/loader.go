@@ -0,0 +1,88 @@
+// Package loader 不依赖 assimp 的模型文件加载器
+package loader
+
+import (
+	"fmt"
+	"image"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+)
+
+// Scene 从模型文件加载的网格, 材质和节点树
+type Scene struct {
+	// 按节点树顺序排列, 节点的变换已经烘焙到顶点, 同一网格被多个节点引用时每个节点一份
+	Meshes []*mesh.Mesh
+	// 材质槽, 网格通过 MaterialIndex 引用, 自发光贴图路径相对于模型文件所在目录
+	Materials []*material.Material
+	// 根节点
+	Nodes []*Node
+	// 内嵌贴图(GLB 或 data URI)解码后的像素, 键为网格 Textures 中的 Path
+	Images map[string]*image.RGBA
+	// 加载时读取的文件(模型文件, 材质库, 外部缓冲), 用于判断网格缓存是否过期
+	Files []string
+}
+
+// Node 模型文件中的节点
+type Node struct {
+	Name  string
+	Local mgl32.Mat4 // 相对父节点的变换
+	World mgl32.Mat4 // 相对模型的变换
+	// 节点的网格在 Scene.Meshes 中的下标
+	Meshes   []int
+	Children []*Node
+}
+
+// Supported 是否有内置的加载器, 其他格式由 assimp 加载
+func Supported(file string) bool {
+	return IsGLTF(file) || IsObj(file)
+}
+
+// Load 按扩展名选择加载器
+func Load(path string, base *material.Material) (*Scene, error) {
+	switch {
+	case IsGLTF(path):
+		return LoadGLTF(path, base)
+	case IsObj(path):
+		return LoadObj(path, base)
+	}
+	return nil, fmt.Errorf("%s: unsupported model format", path)
+}
+
+// Clone 复制网格和材质, 节点树和内嵌贴图只读, 与原场景共享
+// 材质以 base 为基础, 只复制加载器从模型文件读取的属性
+func (s *Scene) Clone(base *material.Material) *Scene {
+	clone := &Scene{
+		Meshes:    make([]*mesh.Mesh, len(s.Meshes)),
+		Materials: make([]*material.Material, len(s.Materials)),
+		Nodes:     s.Nodes,
+		Images:    s.Images,
+		Files:     s.Files,
+	}
+	for i, mi := range s.Meshes {
+		ms := mesh.NewMesh(slices.Clone(mi.Vertices), slices.Clone(mi.Indices), slices.Clone(mi.Textures))
+		ms.Name = mi.Name
+		ms.DrawMode = mi.DrawMode
+		ms.MaterialIndex = mi.MaterialIndex
+		clone.Meshes[i] = ms
+	}
+	for i, src := range s.Materials {
+		mat := *base
+		// 纹理坐标动画按材质独立累加
+		mat.UV = base.UV.Clone()
+		mat.Name = src.Name
+		mat.AmbientColor = src.AmbientColor
+		mat.DiffuseColor = src.DiffuseColor
+		mat.SpecularColor = src.SpecularColor
+		mat.Shininess = src.Shininess
+		mat.BlendMode = src.BlendMode
+		mat.Opacity = src.Opacity
+		mat.EmissiveColor = src.EmissiveColor
+		mat.EmissiveIntensity = src.EmissiveIntensity
+		mat.EmissiveMap = src.EmissiveMap
+		clone.Materials[i] = &mat
+	}
+	return clone
+}
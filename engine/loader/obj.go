@@ -0,0 +1,502 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// IsObj 是否为 Wavefront OBJ 文件
+func IsObj(file string) bool {
+	return strings.EqualFold(filepath.Ext(file), ".obj")
+}
+
+// LoadObj 加载 Wavefront OBJ 文件和它引用的 .mtl 材质库
+// 每个对象(o)或组(g)中使用同一材质(usemtl)的面生成一个网格, 每个对象或组生成一个节点
+// 没有法线(vn)的面按平滑组(s)生成法线, base 提供 .mtl 中没有的材质属性
+func LoadObj(path string, base *material.Material) (*Scene, error) {
+	data, err := vfs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &objParser{
+		path:          path,
+		dir:           filepath.Dir(path),
+		base:          base,
+		materialIndex: make(map[string]int),
+		name:          strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		material:      -1,
+	}
+	if err := p.parse(data); err != nil {
+		return nil, fmt.Errorf("obj %s: %v", path, err)
+	}
+	p.generateNormals()
+
+	scene := &Scene{Materials: p.materials, Images: make(map[string]*image.RGBA), Files: append([]string{path}, p.mtlFiles...)}
+	nodes := make(map[string]*Node)
+	for _, group := range p.groups {
+		var textures []texture.Texture
+		if group.material >= 0 {
+			textures = slices.Clone(p.materialTextures[group.material])
+		}
+		ms := mesh.NewMesh(group.vertices, group.indices, textures)
+		ms.Name = group.name
+		// 同一对象使用多个材质时, 网格名称加上材质名称
+		if p.materialCount[group.name] > 1 {
+			ms.Name = group.name + "_" + p.materialName(group.material)
+		}
+		ms.MaterialIndex = group.material
+
+		node, ok := nodes[group.name]
+		if !ok {
+			node = &Node{Name: group.name, Local: mgl32.Ident4(), World: mgl32.Ident4()}
+			nodes[group.name] = node
+			scene.Nodes = append(scene.Nodes, node)
+		}
+		node.Meshes = append(node.Meshes, len(scene.Meshes))
+		scene.Meshes = append(scene.Meshes, ms)
+	}
+	return scene, nil
+}
+
+// objIndex 面顶点引用的位置, 纹理坐标和法线下标(从 0 开始, -1 表示没有)
+// 没有法线的顶点按 smooth 区分, 只有同一平滑组的面共享顶点
+type objIndex struct {
+	v, vt, vn int
+	smooth    int
+}
+
+// smoothKey 生成法线时累加面法线的位置和平滑组
+type smoothKey struct {
+	v, smooth int
+}
+
+// objGroup 对象或组中使用同一材质的面
+type objGroup struct {
+	name     string // 对象或组的名称
+	material int
+	vertices []mesh.Vertex
+	indices  []uint32
+	// 相同的位置/纹理坐标/法线组合共享一个顶点
+	cache map[objIndex]uint32
+	// 需要生成法线的顶点
+	generated map[uint32]smoothKey
+}
+
+type objParser struct {
+	path string
+	dir  string
+	base *material.Material
+
+	positions []mgl32.Vec3
+	colors    []mgl32.Vec3
+	texCoords []mgl32.Vec2
+	normals   []mgl32.Vec3
+
+	materials        []*material.Material
+	materialTextures [][]texture.Texture
+	materialIndex    map[string]int
+	// 引用的材质库, 包括读取失败的
+	mtlFiles []string
+
+	// 当前的对象或组名称和材质, 面在第一次使用时才创建网格, 没有面的对象不生成网格
+	name     string
+	material int
+	groups   []*objGroup
+	current  *objGroup
+	// 每个对象或组使用的材质数量
+	materialCount map[string]int
+
+	// 当前平滑组, 0 表示关闭(平直着色)
+	smooth int
+	// 已读取的面数, 平直着色的面使用 -(面序号+1) 作为各自的平滑组
+	faceCount int
+	// 按位置和平滑组累加的面法线
+	smoothNormals map[smoothKey]mgl32.Vec3
+}
+
+func (p *objParser) parse(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := p.statement(fields[0], fields[1:]); err != nil {
+			return fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *objParser) statement(keyword string, args []string) error {
+	switch keyword {
+	case "v":
+		v, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		p.positions = append(p.positions, mgl32.Vec3{v[0], v[1], v[2]})
+		// 扩展格式: v x y z r g b
+		color := mgl32.Vec3{}
+		if len(args) >= 6 {
+			if c, err := parseFloats(args[3:], 3); err == nil {
+				color = mgl32.Vec3{c[0], c[1], c[2]}
+			}
+		}
+		p.colors = append(p.colors, color)
+	case "vt":
+		v, err := parseFloats(args, 1)
+		if err != nil {
+			return err
+		}
+		if len(v) < 2 {
+			v = append(v, 0)
+		}
+		// 与 assimp 的 FlipUVs 一致, 纹理坐标原点在左上角
+		p.texCoords = append(p.texCoords, mgl32.Vec2{v[0], 1 - v[1]})
+	case "vn":
+		v, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		p.normals = append(p.normals, mgl32.Vec3{v[0], v[1], v[2]})
+	case "f":
+		return p.face(args)
+	case "mtllib":
+		for _, file := range args {
+			if err := p.loadMtl(filepath.Join(p.dir, filepath.FromSlash(file))); err != nil {
+				// 缺失的材质库使用默认材质, 不影响模型加载
+				logger.Warn(fmt.Sprintf("obj %s: %v", p.path, err))
+			}
+		}
+	case "usemtl":
+		name := strings.Join(args, " ")
+		index, ok := p.materialIndex[name]
+		if !ok {
+			logger.Warn(fmt.Sprintf("obj %s: material %q not found", p.path, name))
+			index = -1
+		}
+		p.material = index
+		p.current = nil
+	case "s":
+		p.smooth = 0
+		if len(args) > 0 && !strings.EqualFold(args[0], "off") {
+			// 无法解析的平滑组按关闭处理
+			p.smooth, _ = strconv.Atoi(args[0])
+		}
+	case "o", "g":
+		// 没有名称的组沿用当前名称
+		if len(args) > 0 {
+			p.name = strings.Join(args, " ")
+			p.current = nil
+		}
+	}
+	return nil
+}
+
+// selectGroup 之后的面加入当前对象和材质的网格, 同一对象中相同材质的面合并到一个网格
+func (p *objParser) selectGroup() {
+	for _, group := range p.groups {
+		if group.name == p.name && group.material == p.material {
+			p.current = group
+			return
+		}
+	}
+	p.current = &objGroup{name: p.name, material: p.material, cache: make(map[objIndex]uint32)}
+	p.groups = append(p.groups, p.current)
+	if p.materialCount == nil {
+		p.materialCount = make(map[string]int)
+	}
+	p.materialCount[p.name]++
+}
+
+func (p *objParser) materialName(index int) string {
+	if index < 0 {
+		return "default"
+	}
+	return p.materials[index].Name
+}
+
+// face 四边形和多边形按扇形三角化, 假定多边形是凸的
+func (p *objParser) face(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("face with %d vertices", len(args))
+	}
+	if p.current == nil {
+		p.selectGroup()
+	}
+	p.faceCount++
+	smooth := p.smooth
+	if smooth <= 0 {
+		smooth = -p.faceCount
+	}
+	indices := make([]objIndex, len(args))
+	for i, arg := range args {
+		index, err := p.parseIndex(arg)
+		if err != nil {
+			return err
+		}
+		if index.vn < 0 {
+			index.smooth = smooth
+		}
+		indices[i] = index
+	}
+	p.accumulateNormal(indices)
+	polygon := make([]uint32, len(indices))
+	for i, index := range indices {
+		polygon[i] = p.vertex(index)
+	}
+	for i := 1; i+1 < len(polygon); i++ {
+		p.current.indices = append(p.current.indices, polygon[0], polygon[i], polygon[i+1])
+	}
+	return nil
+}
+
+// parseIndex 解析 v, v/vt, v//vn 或 v/vt/vn
+// 负数下标相对于已读取的数据, -1 表示最后一个
+func (p *objParser) parseIndex(arg string) (objIndex, error) {
+	index := objIndex{vt: -1, vn: -1}
+	parts := strings.Split(arg, "/")
+	if len(parts) > 3 {
+		return index, fmt.Errorf("invalid face vertex %q", arg)
+	}
+	targets := []*int{&index.v, &index.vt, &index.vn}
+	counts := []int{len(p.positions), len(p.texCoords), len(p.normals)}
+	for i, part := range parts {
+		if part == "" {
+			if i == 0 {
+				return index, fmt.Errorf("invalid face vertex %q", arg)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return index, fmt.Errorf("invalid face vertex %q", arg)
+		}
+		resolved := n - 1
+		if n < 0 {
+			resolved = counts[i] + n
+		}
+		if n == 0 || resolved < 0 || resolved >= counts[i] {
+			return index, fmt.Errorf("index %d out of range in %q", n, arg)
+		}
+		*targets[i] = resolved
+	}
+	return index, nil
+}
+
+// vertex 当前段中与 index 对应的顶点, 没有时创建
+func (p *objParser) vertex(index objIndex) uint32 {
+	group := p.current
+	if i, ok := group.cache[index]; ok {
+		return i
+	}
+	v := mesh.Vertex{Position: p.positions[index.v], Color: p.colors[index.v]}
+	if index.vt >= 0 {
+		v.TexCoords = p.texCoords[index.vt]
+	}
+	// 第二套UV与第一套相同
+	v.TexCoords2 = v.TexCoords
+	if index.vn >= 0 {
+		v.Normal = p.normals[index.vn]
+	}
+	i := uint32(len(group.vertices))
+	if index.vn < 0 {
+		if group.generated == nil {
+			group.generated = make(map[uint32]smoothKey)
+		}
+		group.generated[i] = smoothKey{v: index.v, smooth: index.smooth}
+	}
+	group.vertices = append(group.vertices, v)
+	group.cache[index] = i
+	return i
+}
+
+// accumulateNormal 把多边形的面法线(Newell 方法, 长度与面积成正比)累加到没有法线的顶点
+func (p *objParser) accumulateNormal(polygon []objIndex) {
+	var normal mgl32.Vec3
+	for i, index := range polygon {
+		current := p.positions[index.v]
+		next := p.positions[polygon[(i+1)%len(polygon)].v]
+		normal[0] += (current.Y() - next.Y()) * (current.Z() + next.Z())
+		normal[1] += (current.Z() - next.Z()) * (current.X() + next.X())
+		normal[2] += (current.X() - next.X()) * (current.Y() + next.Y())
+	}
+	for _, index := range polygon {
+		if index.vn >= 0 {
+			continue
+		}
+		if p.smoothNormals == nil {
+			p.smoothNormals = make(map[smoothKey]mgl32.Vec3)
+		}
+		key := smoothKey{v: index.v, smooth: index.smooth}
+		p.smoothNormals[key] = p.smoothNormals[key].Add(normal)
+	}
+}
+
+// generateNormals 没有法线的顶点使用所在平滑组中相邻面法线的平均值
+func (p *objParser) generateNormals() {
+	for _, group := range p.groups {
+		for i, key := range group.generated {
+			if normal := p.smoothNormals[key]; normal.Len() > 0 {
+				group.vertices[i].Normal = normal.Normalize()
+			}
+		}
+	}
+}
+
+// loadMtl 读取 .mtl 材质库, 贴图路径相对于材质库所在目录
+func (p *objParser) loadMtl(path string) error {
+	p.mtlFiles = append(p.mtlFiles, path)
+	data, err := vfs.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// 自发光贴图路径相对于模型文件所在目录
+	rel, err := filepath.Rel(p.dir, filepath.Dir(path))
+	if err != nil {
+		rel = "."
+	}
+
+	var mat *material.Material
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		keyword, args := fields[0], fields[1:]
+		if keyword == "newmtl" {
+			mat = p.newMaterial(strings.Join(args, " "))
+			continue
+		}
+		if mat == nil {
+			continue
+		}
+		index := p.materialIndex[mat.Name]
+		fail := func(err error) error {
+			return fmt.Errorf("%s line %d: %v", path, lineNo, err)
+		}
+		switch strings.ToLower(keyword) {
+		case "ka", "kd", "ks", "ke":
+			v, err := parseFloats(args, 3)
+			if err != nil {
+				return fail(err)
+			}
+			color := mgl32.Vec3{v[0], v[1], v[2]}
+			switch strings.ToLower(keyword) {
+			case "ka":
+				mat.AmbientColor = color
+			case "kd":
+				mat.DiffuseColor = color
+			case "ks":
+				mat.SpecularColor = color
+			case "ke":
+				// 黑色表示不发光
+				if color.X() > 0 || color.Y() > 0 || color.Z() > 0 {
+					mat.EmissiveColor = color
+					mat.EmissiveIntensity = max(mat.EmissiveIntensity, 1)
+				}
+			}
+		case "ns":
+			v, err := parseFloats(args, 1)
+			if err != nil {
+				return fail(err)
+			}
+			mat.Shininess = v[0]
+		case "d", "tr":
+			v, err := parseFloats(args, 1)
+			if err != nil {
+				return fail(err)
+			}
+			opacity := v[0]
+			if strings.EqualFold(keyword, "tr") {
+				opacity = 1 - opacity
+			}
+			// 不透明度小于1的材质使用 alpha 混合
+			if opacity < 1 {
+				mat.Opacity = opacity
+				mat.BlendMode = material.BlendAlpha
+			}
+		case "map_kd":
+			p.addTexture(index, path, args, texture.TextureDiffuse)
+		case "map_ks":
+			p.addTexture(index, path, args, texture.TextureSpecular)
+		case "map_bump", "bump", "norm":
+			p.addTexture(index, path, args, texture.TextureNormal)
+		case "map_ke":
+			if file := mapFile(args); file != "" {
+				mat.EmissiveMap = filepath.Join(rel, file)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// newMaterial 在 base 的基础上创建材质槽, 同名材质使用后定义的
+func (p *objParser) newMaterial(name string) *material.Material {
+	mat := *p.base
+	// 纹理坐标动画按材质独立累加
+	mat.UV = p.base.UV.Clone()
+	mat.Name = name
+	if index, ok := p.materialIndex[name]; ok {
+		p.materials[index] = &mat
+		p.materialTextures[index] = nil
+		return &mat
+	}
+	p.materialIndex[name] = len(p.materials)
+	p.materials = append(p.materials, &mat)
+	p.materialTextures = append(p.materialTextures, nil)
+	return &mat
+}
+
+func (p *objParser) addTexture(index int, mtlPath string, args []string, textureType string) {
+	file := mapFile(args)
+	if file == "" {
+		return
+	}
+	path := filepath.Join(filepath.Dir(mtlPath), file)
+	p.materialTextures[index] = append(p.materialTextures[index], texture.Texture{TextureType: textureType, Path: path})
+}
+
+// mapFile 贴图语句中的文件名, 忽略 -bm 等选项, 文件名是最后一个参数
+func mapFile(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return filepath.FromSlash(strings.ReplaceAll(args[len(args)-1], "\\", "/"))
+}
+
+// parseFloats 解析至少 n 个浮点数
+func parseFloats(args []string, n int) ([]float32, error) {
+	if len(args) < n {
+		return nil, errors.New("not enough values")
+	}
+	values := make([]float32, 0, len(args))
+	for _, arg := range args {
+		v, err := strconv.ParseFloat(arg, 32)
+		if err != nil {
+			if len(values) >= n {
+				break
+			}
+			return nil, err
+		}
+		values = append(values, float32(v))
+	}
+	return values, nil
+}
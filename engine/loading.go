@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/event"
+	"github.com/huangxiaobo/toy-engine/engine/glqueue"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// loadingState 异步加载的模型数量
+type loadingState struct {
+	loaded int
+	total  int
+	// 最近加载完成的模型
+	last string
+}
+
+// loadModelAsync 在后台加载模型, 完成后加入场景并发布 LoadProgress
+func (w *World) loadModelAsync(xmlModel config.XmlModel) {
+	w.loading.total++
+	model.NewModelAsync(xmlModel, func(obj *model.Model, err error) {
+		w.loading.loaded++
+		w.loading.last = xmlModel.Name
+		if err != nil {
+			err = fmt.Errorf("model %s: %w", xmlModel.Name, err)
+			logger.Error(err)
+		} else {
+			w.addRenderObj(obj, obj.Name, obj.Id, xmlModel.Tags)
+		}
+		event.Publish(w.Events, event.LoadProgress{
+			Loaded: w.loading.loaded,
+			Total:  w.loading.total,
+			Name:   xmlModel.Name,
+			Err:    err,
+		})
+	})
+}
+
+// Loading 异步加载的进度, 没有进行中的加载时 loaded == total
+func (w *World) Loading() (loaded, total int) {
+	return w.loading.loaded, w.loading.total
+}
+
+// Loaded 异步加载是否全部完成
+func (w *World) Loaded() bool {
+	return w.loading.loaded >= w.loading.total
+}
+
+// WaitLoading 在主线程执行 GL 命令直到异步加载全部完成, 用于烘焙等需要完整场景的操作
+func (w *World) WaitLoading() {
+	for !w.Loaded() {
+		glqueue.Execute(glQueueBudget)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// drawLoadingProgress 加载未完成时在屏幕中央显示进度条
+func (w *World) drawLoadingProgress(displaySize [2]float32) {
+	if w.Loaded() {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0] / 2, Y: displaySize[1] / 2}, imgui.ConditionAlways, imgui.Vec2{X: 0.5, Y: 0.5})
+	imgui.SetNextWindowBgAlpha(0.8)
+	flags := imgui.WindowFlagsNoDecoration | imgui.WindowFlagsAlwaysAutoResize | imgui.WindowFlagsNoMove |
+		imgui.WindowFlagsNoSavedSettings | imgui.WindowFlagsNoFocusOnAppearing | imgui.WindowFlagsNoNav
+	if imgui.BeginV("Loading##progress", nil, flags) {
+		if w.loading.last == "" {
+			imgui.Text("Loading...")
+		} else {
+			imgui.Text("Loaded " + w.loading.last)
+		}
+		fraction := float32(w.loading.loaded) / float32(w.loading.total)
+		imgui.ProgressBarV(fraction, imgui.Vec2{X: 300}, fmt.Sprintf("%d / %d", w.loading.loaded, w.loading.total))
+	}
+	imgui.End()
+}
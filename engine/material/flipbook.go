@@ -0,0 +1,103 @@
+package material
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// LoopMode 序列帧播放到最后一帧后的行为
+type LoopMode int32
+
+const (
+	LoopRepeat   LoopMode = iota // 回到第一帧
+	LoopOnce                     // 停在最后一帧
+	LoopPingPong                 // 倒序播放回第一帧, 往复循环
+)
+
+var LoopModeNames = []string{"Loop", "Once", "PingPong"}
+
+// Flipbook 序列帧动画, 图集按网格排列, 第一帧在左上角, 按行从左到右
+// 用于火焰, 爆炸, 水面等效果, 由材质系统每帧推进
+type Flipbook struct {
+	Texture   string // 图集贴图路径
+	Columns   int32
+	Rows      int32
+	Frames    int32 // 帧数, 0 表示 Columns * Rows
+	FPS       float32
+	Loop      LoopMode
+	Blend     bool // 在相邻帧之间插值, 减少低帧率图集的跳变
+	TextureId uint32
+
+	time float32
+}
+
+func (f *Flipbook) Update(elapsed float64) {
+	f.time += float32(elapsed)
+}
+
+// Reset 从第一帧重新播放
+func (f *Flipbook) Reset() {
+	f.time = 0
+}
+
+func (f *Flipbook) FrameCount() int32 {
+	count := f.Frames
+	if count <= 0 || count > f.Columns*f.Rows {
+		count = f.Columns * f.Rows
+	}
+	return max(count, 1)
+}
+
+// Finished 单次播放是否已到最后一帧
+func (f *Flipbook) Finished() bool {
+	return f.Loop == LoopOnce && f.time*f.FPS >= float32(f.FrameCount()-1)
+}
+
+// Frame 当前帧, 下一帧和两帧之间的插值系数
+func (f *Flipbook) Frame() (int32, int32, float32) {
+	count := f.FrameCount()
+	if count == 1 || f.FPS <= 0 {
+		return 0, 0, 0
+	}
+
+	position := f.time * f.FPS
+	whole, fraction := math.Modf(float64(position))
+	step := int32(whole)
+	t := float32(fraction)
+	if !f.Blend {
+		t = 0
+	}
+
+	switch f.Loop {
+	case LoopOnce:
+		if step >= count-1 {
+			return count - 1, count - 1, 0
+		}
+		return step, step + 1, t
+	case LoopPingPong:
+		period := 2 * (count - 1)
+		frame := func(s int32) int32 {
+			s %= period
+			if s >= count {
+				return period - s
+			}
+			return s
+		}
+		return frame(step), frame(step + 1), t
+	default:
+		return step % count, (step + 1) % count, t
+	}
+}
+
+// FrameRect 第 frame 帧在图集中的纹理坐标, xy 为左上角偏移, zw 为大小
+func (f *Flipbook) FrameRect(frame int32) mgl32.Vec4 {
+	columns, rows := max(f.Columns, 1), max(f.Rows, 1)
+	scale := mgl32.Vec2{1 / float32(columns), 1 / float32(rows)}
+	return mgl32.Vec4{
+		float32(frame%columns) * scale.X(),
+		float32(frame/columns) * scale.Y(),
+		scale.X(),
+		scale.Y(),
+	}
+}
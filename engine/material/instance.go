@@ -0,0 +1,52 @@
+package material
+
+import "github.com/go-gl/mathgl/mgl32"
+
+// Instance 引用基础材质, 只覆盖少数属性, 多个对象共享基础材质的着色器和贴图, 只有覆盖的 uniform 不同
+// 绘制时从基础材质复制, 基础材质的修改(包括材质文件的热重载)立即生效
+type Instance struct {
+	Base *Material
+
+	// 覆盖的属性, 为空时使用基础材质的值
+	Tint      *mgl32.Vec3 // 与基础材质的漫反射颜色相乘
+	Roughness *float32    // 0 到 1, 换算为光泽后覆盖 Shininess
+	Opacity   *float32    // 覆盖不透明度, 小于 1 时使用 alpha 混合
+
+	resolved Material
+}
+
+// NewInstance 创建不覆盖任何属性的材质实例
+func NewInstance(base *Material) *Instance {
+	return &Instance{Base: base}
+}
+
+// Overrides 是否覆盖了任何属性, 没有覆盖时直接使用基础材质
+func (i *Instance) Overrides() bool {
+	return i.Tint != nil || i.Roughness != nil || i.Opacity != nil
+}
+
+// Material 基础材质加上覆盖的属性, 返回的材质属于实例, 下次调用时被覆盖, 不要保存或修改
+// 贴图, 序列帧和纹理坐标变换与基础材质共享
+func (i *Instance) Material() *Material {
+	i.resolved = *i.Base
+	if i.Tint != nil {
+		d := i.Base.DiffuseColor
+		i.resolved.DiffuseColor = mgl32.Vec3{d[0] * i.Tint[0], d[1] * i.Tint[1], d[2] * i.Tint[2]}
+	}
+	if i.Roughness != nil {
+		i.resolved.Shininess = ShininessFromRoughness(*i.Roughness)
+	}
+	if i.Opacity != nil {
+		i.resolved.Opacity = *i.Opacity
+		if *i.Opacity < 1 {
+			i.resolved.BlendMode = BlendAlpha
+		}
+	}
+	return &i.resolved
+}
+
+// ShininessFromRoughness 粗糙度转换为 Blinn-Phong 的光泽指数
+func ShininessFromRoughness(roughness float32) float32 {
+	alpha := max(roughness*roughness, 0.01)
+	return mgl32.Clamp(2/(alpha*alpha)-2, 1, 256)
+}
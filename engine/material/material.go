@@ -2,9 +2,63 @@ package material
 
 import "github.com/go-gl/mathgl/mgl32"
 
+// BlendMode 材质混合模式
+type BlendMode int32
+
+const (
+	BlendOpaque BlendMode = iota // 不透明
+	BlendAlpha                   // 按 Opacity 做 alpha 混合, 在不透明物体之后由远及近绘制
+)
+
+var BlendModeNames = []string{"Opaque", "Alpha"}
+
 type Material struct {
+	Name          string
 	AmbientColor  mgl32.Vec3 // 环境
 	DiffuseColor  mgl32.Vec3 // 漫反射
 	SpecularColor mgl32.Vec3 // 镜面反射
 	Shininess     float32    // 镜面反射光泽
+
+	BlendMode BlendMode
+	Opacity   float32 // 不透明度, 仅 BlendAlpha 时生效
+
+	// 自发光, 不受光照和阴影影响, 乘以强度后可超过 1, 经过 Bloom 产生辉光
+	EmissiveColor     mgl32.Vec3
+	EmissiveIntensity float32
+	EmissiveMap       string // 自发光贴图路径, 与 EmissiveColor 相乘
+	EmissiveTex       uint32
+
+	Detail   *Detail      // 细节贴图, 可为空
+	Flipbook *Flipbook    // 序列帧贴图, 与漫反射颜色和不透明度相乘, 可为空
+	UV       *UVTransform // 第一套纹理坐标的变换, 为空时不变换
+}
+
+// Update 推进材质上随时间变化的属性
+func (m *Material) Update(elapsed float64) {
+	if m.Flipbook != nil {
+		m.Flipbook.Update(elapsed)
+	}
+	if m.UV != nil {
+		m.UV.Update(elapsed)
+	}
+}
+
+// Emission 自发光颜色乘以强度
+func (m *Material) Emission() mgl32.Vec3 {
+	return m.EmissiveColor.Mul(m.EmissiveIntensity)
+}
+
+// Transparent 是否需要在透明队列中绘制
+func (m *Material) Transparent() bool {
+	return m.BlendMode == BlendAlpha
+}
+
+// Detail 以更高频率平铺的细节贴图, 叠加在基础颜色和法线上
+type Detail struct {
+	AlbedoMap string  // 细节颜色贴图路径, 0.5 灰度表示不改变颜色
+	NormalMap string  // 细节法线贴图路径(切线空间)
+	Tiling    float32 // 相对UV的平铺次数
+	UVSet     int32   // 使用的UV, 0 或 1
+	AlbedoTex uint32
+	NormalTex uint32
 }
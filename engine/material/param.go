@@ -0,0 +1,91 @@
+package material
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ParamType 着色器参数的类型
+type ParamType int32
+
+const (
+	ParamFloat ParamType = iota
+	ParamInt
+	ParamBool
+	ParamVec2
+	ParamVec3
+	ParamVec4
+	ParamTexture // 贴图路径, 绑定到 technique.TextureUnitParams 开始的纹理单元
+)
+
+var ParamTypeNames = []string{"float", "int", "bool", "vec2", "vec3", "vec4", "texture"}
+
+// paramComponents 数值参数的分量数
+var paramComponents = map[ParamType]int{
+	ParamFloat: 1, ParamInt: 1, ParamBool: 1, ParamVec2: 2, ParamVec3: 3, ParamVec4: 4,
+}
+
+// Param 自定义着色器的 uniform, 由 technique 在绘制时设置, 着色器中没有同名 uniform 时忽略
+type Param struct {
+	Name  string
+	Type  ParamType
+	Value mgl32.Vec4 // 数值参数, 按类型使用前几个分量, bool 非 0 为 true
+	// 贴图参数的路径(相对于模型目录), 是否按 sRGB 上传, 和加载后的贴图
+	Texture   string
+	SRGB      bool
+	TextureId uint32
+}
+
+// ParseParam 按类型名解析参数, value 为空格或逗号分隔的数值, 贴图参数为路径
+// 类型 color 按分量数解析为 vec3 或 vec4
+func ParseParam(name, typeName, value string) (Param, error) {
+	p := Param{Name: name}
+	if name == "" {
+		return p, fmt.Errorf("param has no name")
+	}
+	typeName = strings.ToLower(strings.TrimSpace(typeName))
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' || r == '\n' })
+
+	if typeName == "color" {
+		typeName = "vec3"
+		if len(fields) == 4 {
+			typeName = "vec4"
+		}
+	}
+	i := slices.Index(ParamTypeNames, typeName)
+	if i < 0 {
+		return p, fmt.Errorf("param %s: unknown type %q", name, typeName)
+	}
+	p.Type = ParamType(i)
+
+	if p.Type == ParamTexture {
+		p.Texture = strings.TrimSpace(value)
+		if p.Texture == "" {
+			return p, fmt.Errorf("param %s: texture path is empty", name)
+		}
+		return p, nil
+	}
+	if p.Type == ParamBool && len(fields) == 1 {
+		if b, err := strconv.ParseBool(fields[0]); err == nil {
+			if b {
+				p.Value[0] = 1
+			}
+			return p, nil
+		}
+	}
+	if n := paramComponents[p.Type]; len(fields) != n {
+		return p, fmt.Errorf("param %s: %s needs %d values, got %d", name, typeName, n, len(fields))
+	}
+	for j, field := range fields {
+		v, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return p, fmt.Errorf("param %s: %w", name, err)
+		}
+		p.Value[j] = float32(v)
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package material
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// UVTransform 材质的纹理坐标变换, 先缩放, 再绕 (0.5, 0.5) 旋转, 最后平移
+// 滚动和旋转速度随时间累加, 用于传送带, 流水, 全息等效果
+type UVTransform struct {
+	Offset   mgl32.Vec2
+	Scale    mgl32.Vec2
+	Rotation float32 // 弧度
+
+	ScrollSpeed   mgl32.Vec2 // 每秒平移
+	RotationSpeed float32    // 每秒旋转(弧度)
+
+	scroll mgl32.Vec2
+	spin   float32
+}
+
+func NewUVTransform() *UVTransform {
+	return &UVTransform{Scale: mgl32.Vec2{1, 1}}
+}
+
+// Clone 复制变换, 包括已累加的动画, nil 时返回 nil
+func (t *UVTransform) Clone() *UVTransform {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
+
+func (t *UVTransform) Update(elapsed float64) {
+	// 取小数部分避免长时间运行后精度下降, 旋转按整圈取余
+	t.scroll = t.scroll.Add(t.ScrollSpeed.Mul(float32(elapsed)))
+	t.scroll = mgl32.Vec2{fract(t.scroll.X()), fract(t.scroll.Y())}
+	t.spin = float32(math.Mod(float64(t.spin+t.RotationSpeed*float32(elapsed)), 2*math.Pi))
+}
+
+// Reset 清除累加的滚动和旋转
+func (t *UVTransform) Reset() {
+	t.scroll = mgl32.Vec2{}
+	t.spin = 0
+}
+
+// Matrix 齐次二维变换矩阵, nil 时为单位矩阵
+func (t *UVTransform) Matrix() mgl32.Mat3 {
+	if t == nil {
+		return mgl32.Ident3()
+	}
+	offset := t.Offset.Add(t.scroll)
+	m := mgl32.Translate2D(offset.X()+0.5, offset.Y()+0.5)
+	m = m.Mul3(mgl32.HomogRotate2D(t.Rotation + t.spin))
+	m = m.Mul3(mgl32.Translate2D(-0.5, -0.5))
+	return m.Mul3(mgl32.Scale2D(t.Scale.X(), t.Scale.Y()))
+}
+
+func fract(v float32) float32 {
+	return v - float32(math.Floor(float64(v)))
+}
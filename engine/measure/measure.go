@@ -0,0 +1,147 @@
+package measure
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/debugdraw"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+var (
+	lineColor  = mgl32.Vec3{1.0, 1.0, 0.0}
+	axisColors = [3]mgl32.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+)
+
+// Tool 测量工具, 开启后在场景中点击两个点, 显示两点的世界空间距离和各轴差值
+// 点击位置由外部拾取得到世界坐标后通过 AddPoint 传入
+type Tool struct {
+	Active bool
+
+	points []mgl32.Vec3
+
+	pending    bool
+	pendingPos [2]float32
+}
+
+func NewTool() *Tool {
+	return &Tool{
+		points: make([]mgl32.Vec3, 0, 2),
+	}
+}
+
+func (t *Tool) SetActive(active bool) {
+	t.Active = active
+	if !active {
+		t.Clear()
+	}
+}
+
+func (t *Tool) Clear() {
+	t.points = t.points[:0]
+	t.pending = false
+}
+
+// HandleInput 记录视口中的鼠标点击, 需在 imgui.NewFrame 之后调用
+func (t *Tool) HandleInput() {
+	if !t.Active {
+		return
+	}
+	io := imgui.CurrentIO()
+	if !io.WantCaptureKeyboard() && imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyEscape), false) {
+		t.Clear()
+	}
+	if io.WantCaptureMouse() || !imgui.IsMouseClicked(0) {
+		return
+	}
+	pos := imgui.MousePos()
+	t.pending = true
+	t.pendingPos = [2]float32{pos.X, pos.Y}
+}
+
+// PendingClick 尚未拾取的点击位置(窗口坐标), 取出后即清除
+func (t *Tool) PendingClick() ([2]float32, bool) {
+	if !t.pending {
+		return [2]float32{}, false
+	}
+	t.pending = false
+	return t.pendingPos, true
+}
+
+// AddPoint 添加测量点, 已有两个点时开始新的测量
+func (t *Tool) AddPoint(p mgl32.Vec3) {
+	if len(t.points) >= 2 {
+		t.points = t.points[:0]
+	}
+	t.points = append(t.points, p)
+}
+
+// Result 两点的距离和各轴差值, 不足两个点时 ok 为 false
+func (t *Tool) Result() (distance float32, delta mgl32.Vec3, ok bool) {
+	if len(t.points) < 2 {
+		return 0, mgl32.Vec3{}, false
+	}
+	delta = t.points[1].Sub(t.points[0])
+	return delta.Len(), delta, true
+}
+
+// Draw 绘制测量点, 连线和各轴分量
+func (t *Tool) Draw(dd *debugdraw.DebugDraw) {
+	if !t.Active {
+		return
+	}
+	for _, p := range t.points {
+		dd.AddCross(p, 1.0, lineColor)
+	}
+	_, delta, ok := t.Result()
+	if !ok {
+		return
+	}
+
+	from, to := t.points[0], t.points[1]
+	dd.AddOverlayLine(from, to, lineColor)
+
+	// 沿 x, y, z 依次走到终点
+	corner := from
+	for i := 0; i < 3; i++ {
+		var step mgl32.Vec3
+		step[i] = delta[i]
+		dd.AddOverlayLine(corner, corner.Add(step), axisColors[i])
+		corner = corner.Add(step)
+	}
+}
+
+// DrawLabels 在屏幕上标注测量结果, 需在 imgui.Render 之前调用
+func (t *Tool) DrawLabels(projection, view mgl32.Mat4, displaySize [2]float32) {
+	if !t.Active {
+		return
+	}
+	drawList := imgui.ForegroundDrawList()
+	textColor := imgui.PackedColorFromVec4(imgui.Vec4{X: 1, Y: 1, Z: 0, W: 1})
+
+	distance, delta, ok := t.Result()
+	if !ok {
+		drawList.AddText(imgui.Vec2{X: displaySize[0]/2 - 80, Y: 40}, textColor, "Measure: click two points")
+		return
+	}
+
+	mid := t.points[0].Add(t.points[1]).Mul(0.5)
+	screen, visible := worldToScreen(mid, projection, view, displaySize)
+	if !visible {
+		return
+	}
+	label := fmt.Sprintf("%.3f\ndx %.3f\ndy %.3f\ndz %.3f", distance, delta.X(), delta.Y(), delta.Z())
+	drawList.AddText(screen, textColor, label)
+}
+
+func worldToScreen(p mgl32.Vec3, projection, view mgl32.Mat4, displaySize [2]float32) (imgui.Vec2, bool) {
+	clip := projection.Mul4(view).Mul4x1(p.Vec4(1))
+	if clip.W() <= 0 {
+		return imgui.Vec2{}, false
+	}
+	ndc := clip.Vec3().Mul(1 / clip.W())
+	return imgui.Vec2{
+		X: (ndc.X()*0.5 + 0.5) * displaySize[0],
+		Y: (1 - (ndc.Y()*0.5 + 0.5)) * displaySize[1],
+	}, true
+}
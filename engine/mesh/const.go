@@ -1,3 +1,6 @@
 package mesh
 
 const GL_FLOAT32_SIZE = 4
+
+// InstanceMatrixLocation 实例变换矩阵的起始顶点属性位置, 占用 8~11
+const InstanceMatrixLocation = 8
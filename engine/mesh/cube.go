@@ -0,0 +1,37 @@
+package mesh
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// NewMeshCube [0, 1] 范围的单位立方体, 用于包围盒代理绘制
+func NewMeshCube() *Mesh {
+	m := GenCubeMesh()
+	m.Setup()
+	return m
+}
+
+func GenCubeMesh() *Mesh {
+	m := &Mesh{
+		DrawMode: gl.TRIANGLES,
+	}
+
+	for i := 0; i < 8; i++ {
+		m.Vertices = append(m.Vertices, Vertex{
+			Position: mgl32.Vec3{float32(i & 1), float32(i >> 1 & 1), float32(i >> 2 & 1)},
+		})
+	}
+
+	// 每个面两个三角形, 逆时针为正面
+	m.Indices = []uint32{
+		0, 2, 1, 1, 2, 3, // -z
+		4, 5, 6, 5, 7, 6, // +z
+		0, 1, 4, 1, 5, 4, // -y
+		2, 6, 3, 3, 6, 7, // +y
+		0, 4, 2, 2, 4, 6, // -x
+		1, 3, 5, 3, 7, 5, // +x
+	}
+
+	return m
+}
@@ -0,0 +1,50 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	grassBlades   = 3
+	grassSegments = 3
+	grassWidth    = 0.4
+)
+
+// NewMeshGrass 高度为 1 的草丛, 由绕 Y 轴均匀分布的交叉四边形组成, 每片分段以便在顶点着色器中弯曲
+// 顶点颜色从根部的深色过渡到顶部的浅色, 没有贴图时直接作为草的颜色
+func NewMeshGrass() *Mesh {
+	vertices := make([]Vertex, 0, grassBlades*(grassSegments+1)*2)
+	indices := make([]uint32, 0, grassBlades*grassSegments*6)
+	for b := 0; b < grassBlades; b++ {
+		angle := float64(b) * math.Pi / grassBlades
+		right := mgl32.Vec3{float32(math.Cos(angle)), 0, float32(math.Sin(angle))}
+		normal := mgl32.Vec3{-right.Z(), 0, right.X()}
+
+		base := uint32(len(vertices))
+		for s := 0; s <= grassSegments; s++ {
+			t := float32(s) / grassSegments
+			// 顶部收窄
+			half := grassWidth / 2 * (1 - 0.6*t)
+			color := mgl32.Vec3{0.15, 0.35, 0.08}.Add(mgl32.Vec3{0.25, 0.35, 0.1}.Mul(t))
+			for _, side := range []float32{-1, 1} {
+				vertices = append(vertices, Vertex{
+					Position:  right.Mul(side * half).Add(mgl32.Vec3{0, t, 0}),
+					Color:     color,
+					Normal:    normal,
+					TexCoords: mgl32.Vec2{(side + 1) / 2, 1 - t},
+					Tangent:   right,
+					Bitangent: mgl32.Vec3{0, 1, 0},
+				})
+			}
+		}
+		for s := uint32(0); s < grassSegments; s++ {
+			i0 := base + s*2
+			indices = append(indices, i0, i0+1, i0+2, i0+1, i0+3, i0+2)
+		}
+	}
+	m := NewMesh(vertices, indices, nil)
+	m.Setup()
+	return m
+}
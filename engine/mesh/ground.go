@@ -5,6 +5,13 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 )
 
+const (
+	groundGridNum   = 10
+	groundGridStrip = 5
+	// GroundHalfWidth 地面网格的半宽
+	GroundHalfWidth = groundGridNum * groundGridStrip / 2
+)
+
 func NewMeshGround() []Mesh {
 
 	meshes := GenGroundMesh()
@@ -23,9 +30,9 @@ func GenGroundMesh() []Mesh {
 		DrawMode: gl.LINES,
 	}
 
-	var gridNum int32 = 10
-	var gridStrip float32 = 5
-	var gridWidth = float32(gridNum) * gridStrip / 2
+	var gridNum int32 = groundGridNum
+	var gridStrip float32 = groundGridStrip
+	var gridWidth float32 = GroundHalfWidth
 
 	// draw grid
 	var i uint32 = 0
@@ -92,3 +99,56 @@ func GenGroundMesh() []Mesh {
 	meshes = append(meshes, m)
 	return meshes
 }
+
+// NewMeshGroundPlane 覆盖地面网格的水平面, 用于绘制平面反射
+func NewMeshGroundPlane() *Mesh {
+	vertices := make([]Vertex, 0, 4)
+	for _, corner := range [][2]float32{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+		vertices = append(vertices, Vertex{
+			Position:  mgl32.Vec3{corner[0] * GroundHalfWidth, 0, corner[1] * GroundHalfWidth},
+			Color:     mgl32.Vec3{1, 1, 1},
+			Normal:    mgl32.Vec3{0, 1, 0},
+			TexCoords: mgl32.Vec2{(corner[0] + 1) / 2, (corner[1] + 1) / 2},
+			Tangent:   mgl32.Vec3{1, 0, 0},
+			Bitangent: mgl32.Vec3{0, 0, 1},
+		})
+	}
+	// 逆时针, 法线朝上
+	m := NewMesh(vertices, []uint32{0, 2, 1, 1, 2, 3}, nil)
+	m.Setup()
+	return m
+}
+
+// NewMeshPlaneGrid 以原点为中心, 半宽 halfWidth 的水平网格, 每边 cells 个格子, 用于顶点动画的水面
+func NewMeshPlaneGrid(halfWidth float32, cells int) *Mesh {
+	cells = max(cells, 1)
+	vertices := make([]Vertex, 0, (cells+1)*(cells+1))
+	for z := 0; z <= cells; z++ {
+		for x := 0; x <= cells; x++ {
+			u, v := float32(x)/float32(cells), float32(z)/float32(cells)
+			vertices = append(vertices, Vertex{
+				Position:  mgl32.Vec3{(u*2 - 1) * halfWidth, 0, (v*2 - 1) * halfWidth},
+				Color:     mgl32.Vec3{1, 1, 1},
+				Normal:    mgl32.Vec3{0, 1, 0},
+				TexCoords: mgl32.Vec2{u, v},
+				Tangent:   mgl32.Vec3{1, 0, 0},
+				Bitangent: mgl32.Vec3{0, 0, 1},
+			})
+		}
+	}
+
+	indices := make([]uint32, 0, cells*cells*6)
+	for z := 0; z < cells; z++ {
+		for x := 0; x < cells; x++ {
+			i0 := uint32(z*(cells+1) + x)
+			i1 := i0 + 1
+			i2 := i0 + uint32(cells+1)
+			i3 := i2 + 1
+			// 逆时针, 法线朝上
+			indices = append(indices, i0, i2, i1, i1, i2, i3)
+		}
+	}
+	m := NewMesh(vertices, indices, nil)
+	m.Setup()
+	return m
+}
@@ -0,0 +1,64 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	// LightmapPadding 光照贴图中三角形到格子边缘的间隔, 像素, 避免双线性过滤混入相邻三角形
+	LightmapPadding = 1.5
+	// 每个格子至少占用的像素数, 再小烘焙结果就只剩下间隔
+	minLightmapCell = 6
+)
+
+// PackLightmapUV 为网格生成不重叠的第二套UV, 所有网格共用一张 size x size 的光照贴图
+// 每两个三角形共用一个正方形格子, 分别占据对角线两侧, 三角形的形状不保持
+// 三角形之间共享的顶点拆分为独立的顶点, 只处理 TRIANGLES 网格, 需在 Setup 之前调用
+// 返回每个格子的像素数, 小于 minLightmapCell 时返回 false, 这时 UV 仍然生成
+func PackLightmapUV(meshes []*Mesh, size int32) (float32, bool) {
+	triangles := 0
+	for _, m := range meshes {
+		if m.DrawMode == gl.TRIANGLES {
+			triangles += len(m.Indices) / 3
+		}
+	}
+	if triangles == 0 || size <= 0 {
+		return 0, false
+	}
+
+	grid := int(math.Ceil(math.Sqrt(float64((triangles + 1) / 2))))
+	cell := 1 / float32(grid)
+	pad := LightmapPadding / float32(size)
+
+	k := 0
+	for _, m := range meshes {
+		if m.DrawMode != gl.TRIANGLES {
+			continue
+		}
+		count := len(m.Indices) / 3 * 3
+		vertices := make([]Vertex, count)
+		indices := make([]uint32, count)
+		for i := 0; i < count; i += 3 {
+			index := k / 2
+			origin := mgl32.Vec2{float32(index%grid) * cell, float32(index/grid) * cell}
+			// 偶数三角形在对角线左下方, 奇数三角形在右上方
+			corners := [3]mgl32.Vec2{{pad, pad}, {cell - 2*pad, pad}, {pad, cell - 2*pad}}
+			if k%2 == 1 {
+				corners = [3]mgl32.Vec2{{cell - pad, cell - pad}, {2 * pad, cell - pad}, {cell - pad, 2 * pad}}
+			}
+			for j := 0; j < 3; j++ {
+				vertices[i+j] = m.Vertices[m.Indices[i+j]]
+				vertices[i+j].TexCoords2 = origin.Add(corners[j])
+				indices[i+j] = uint32(i + j)
+			}
+			k++
+		}
+		m.Vertices, m.Indices = vertices, indices
+	}
+
+	texels := float32(size) * cell
+	return texels, texels >= minLightmapCell
+}
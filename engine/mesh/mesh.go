@@ -3,6 +3,7 @@ package mesh
 import (
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
 	"github.com/huangxiaobo/toy-engine/engine/texture"
 	"strconv"
 	"sync"
@@ -16,6 +17,8 @@ type Vertex struct {
 	TexCoords mgl32.Vec2
 	Tangent   mgl32.Vec3
 	Bitangent mgl32.Vec3
+	// 第二套UV, 用于光照贴图和细节贴图
+	TexCoords2 mgl32.Vec2
 }
 
 type Mesh struct {
@@ -29,9 +32,20 @@ type Mesh struct {
 
 	DrawMode uint32
 
+	// 材质槽索引, 对应 Model.Materials
+	MaterialIndex int
+
+	// 模型空间包围体, 由 ComputeBounds 计算
+	Bounds         geometry.AABB
+	BoundingSphere geometry.Sphere
+
 	vao uint32
 	vbo uint32
 	ebo uint32
+
+	// 实例化绘制的每实例变换矩阵
+	instanceVbo   uint32
+	instanceCount int32
 }
 
 func NewMesh(v []Vertex, i []uint32, t []texture.Texture) *Mesh {
@@ -41,10 +55,21 @@ func NewMesh(v []Vertex, i []uint32, t []texture.Texture) *Mesh {
 		Textures: t,
 		DrawMode: gl.TRIANGLES,
 	}
+	// 加载时计算包围体, 修改顶点后需要重新调用 ComputeBounds
+	m.ComputeBounds()
 	//m.Setup()
 	return m
 }
 
+// ComputeBounds 根据顶点计算包围盒和包围球
+func (m *Mesh) ComputeBounds() {
+	m.Bounds = geometry.NewAABB()
+	for _, v := range m.Vertices {
+		m.Bounds.Extend(v.Position)
+	}
+	m.BoundingSphere = m.Bounds.BoundingSphere()
+}
+
 func (m *Mesh) Setup() {
 	// size of the Vertex struct
 	dummy := m.Vertices[0]
@@ -89,18 +114,94 @@ func (m *Mesh) Setup() {
 	// Vertex Bitangent
 	gl.EnableVertexAttribArray(5)
 	gl.VertexAttribPointer(5, 3, gl.FLOAT, false, structSize32, unsafe.Pointer(unsafe.Offsetof(dummy.Bitangent)))
+	// Vertex Texture Coords 2
+	gl.EnableVertexAttribArray(6)
+	gl.VertexAttribPointer(6, 2, gl.FLOAT, false, structSize32, unsafe.Pointer(unsafe.Offsetof(dummy.TexCoords2)))
 
 	// Unbind the buffer
 	gl.BindVertexArray(0)
 }
 
+// UpdateVertices 将修改后的 Vertices[first:first+count] 重新上传到顶点缓冲
+func (m *Mesh) UpdateVertices(first, count int) {
+	if count <= 0 || first < 0 || first+count > len(m.Vertices) {
+		return
+	}
+	structSize := int(unsafe.Sizeof(m.Vertices[0]))
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gl.BufferSubData(gl.ARRAY_BUFFER, first*structSize, count*structSize, gl.Ptr(&m.Vertices[first]))
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+// SetInstances 上传每实例的变换矩阵, mat4 占用 InstanceMatrixLocation 开始的4个顶点属性
+func (m *Mesh) SetInstances(transforms []mgl32.Mat4) {
+	if m.instanceVbo == 0 {
+		gl.GenBuffers(1, &m.instanceVbo)
+
+		gl.BindVertexArray(m.vao)
+		gl.BindBuffer(gl.ARRAY_BUFFER, m.instanceVbo)
+		stride := int32(unsafe.Sizeof(mgl32.Mat4{}))
+		for i := uint32(0); i < 4; i++ {
+			location := InstanceMatrixLocation + i
+			gl.EnableVertexAttribArray(location)
+			gl.VertexAttribPointer(location, 4, gl.FLOAT, false, stride, gl.PtrOffset(int(i)*4*GL_FLOAT32_SIZE))
+			gl.VertexAttribDivisor(location, 1)
+		}
+		gl.BindVertexArray(0)
+	}
+
+	m.instanceCount = int32(len(transforms))
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.instanceVbo)
+	if len(transforms) > 0 {
+		gl.BufferData(gl.ARRAY_BUFFER, len(transforms)*int(unsafe.Sizeof(transforms[0])), gl.Ptr(transforms), gl.DYNAMIC_DRAW)
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+func (m *Mesh) HasTexture(textureType string) bool {
+	for _, tex := range m.Textures {
+		if tex.TextureType == textureType {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mesh) InstanceCount() int32 {
+	return m.instanceCount
+}
+
 func (m *Mesh) Dispose() {
 	gl.DeleteVertexArrays(1, &m.vao)
 	gl.DeleteBuffers(1, &m.vbo)
 	gl.DeleteBuffers(1, &m.ebo)
+	if m.instanceVbo != 0 {
+		gl.DeleteBuffers(1, &m.instanceVbo)
+		m.instanceVbo = 0
+	}
 }
 
 func (m *Mesh) Draw(program uint32) {
+	m.draw(program, 0, int32(len(m.Indices)), 0)
+}
+
+// DrawRange 只绘制从 first 开始的 count 个索引, 用于共享顶点缓冲的分块网格
+func (m *Mesh) DrawRange(program uint32, first, count int32) {
+	if count <= 0 {
+		return
+	}
+	m.draw(program, first, count, 0)
+}
+
+// DrawInstanced 使用 SetInstances 上传的变换矩阵一次绘制所有实例
+func (m *Mesh) DrawInstanced(program uint32) {
+	if m.instanceCount == 0 {
+		return
+	}
+	m.draw(program, 0, int32(len(m.Indices)), m.instanceCount)
+}
+
+func (m *Mesh) draw(program uint32, first, count, instanceCount int32) {
 	// Bind appropriate textures
 	var (
 		materialNr uint64
@@ -149,7 +250,11 @@ func (m *Mesh) Draw(program uint32) {
 
 	// Draw mesh
 	gl.BindVertexArray(m.vao)
-	gl.DrawElements(m.DrawMode, int32(len(m.Indices)), gl.UNSIGNED_INT, gl.PtrOffset(0))
+	if instanceCount > 0 {
+		gl.DrawElementsInstanced(m.DrawMode, count, gl.UNSIGNED_INT, gl.PtrOffset(int(first)*GL_FLOAT32_SIZE), instanceCount)
+	} else {
+		gl.DrawElements(m.DrawMode, count, gl.UNSIGNED_INT, gl.PtrOffset(int(first)*GL_FLOAT32_SIZE))
+	}
 	gl.BindVertexArray(0)
 
 	// Always good practice to set everything back to default once configured.
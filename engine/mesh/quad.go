@@ -0,0 +1,40 @@
+package mesh
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// NewMeshQuad 全屏四边形, 用于屏幕空间的pass
+func NewMeshQuad() *Mesh {
+	m := GenQuadMesh()
+	m.Setup()
+	return m
+}
+
+func GenQuadMesh() *Mesh {
+	m := &Mesh{
+		DrawMode: gl.TRIANGLE_STRIP,
+	}
+
+	for i, vn := range [][]float32{
+		{-1.0, -1.0, 0.0, 0.0},
+		{+1.0, -1.0, 1.0, 0.0},
+		{-1.0, +1.0, 0.0, 1.0},
+		{+1.0, +1.0, 1.0, 1.0},
+	} {
+		v := Vertex{
+			Position:  mgl32.Vec3{vn[0], vn[1], 0.0},
+			Color:     mgl32.Vec3{0, 0, 0},
+			Normal:    mgl32.Vec3{0.0, 0.0, 1.0},
+			TexCoords: mgl32.Vec2{vn[2], vn[3]},
+			Tangent:   mgl32.Vec3{0.0, 0.0, 0.0},
+			Bitangent: mgl32.Vec3{0.0, 0.0, 0.0},
+		}
+
+		m.Vertices = append(m.Vertices, v)
+		m.Indices = append(m.Indices, uint32(i))
+	}
+
+	return m
+}
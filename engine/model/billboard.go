@@ -0,0 +1,193 @@
+package model
+
+import (
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// billboardCorners 四边形的两个三角形, 以中心为原点的单位大小
+var billboardCorners = []float32{
+	-0.5, -0.5,
+	0.5, -0.5,
+	0.5, 0.5,
+	-0.5, -0.5,
+	0.5, 0.5,
+	-0.5, 0.5,
+}
+
+// Billboard 始终朝向相机的贴图四边形, 用于灯光图标, 粒子, 远处的树木和标签
+// 顶点位置在着色器中由相机的右和上方向展开, Axis 非零时绕该轴旋转(柱形公告板), 例如树木锁定 Y 轴
+type Billboard struct {
+	Name string
+	Id   string
+
+	RenderPriority
+
+	Position mgl32.Vec3
+	// 世界空间中的宽和高
+	Size mgl32.Vec2
+	// 锁定的旋转轴, 零向量表示完全朝向相机
+	Axis mgl32.Vec3
+	// 与贴图相乘的颜色
+	Color mgl32.Vec4
+	// 开启时作为透明对象混合绘制, 否则按 AlphaCutoff 镂空
+	Blend       bool
+	AlphaCutoff float32
+	// 贴图为图集时按序列帧播放, 用于粒子和动画图标, 可为空
+	Flipbook *material.Flipbook
+
+	texture uint32
+	shader  *shader.Shader
+	vao     uint32
+	vbo     uint32
+
+	lastPolygonMode [2]int32
+}
+
+// NewBillboard 创建公告板, textureFile 为空时只使用 Color
+func NewBillboard(textureFile string, size mgl32.Vec2) (*Billboard, error) {
+	b := &Billboard{
+		Size:        size,
+		Color:       mgl32.Vec4{1, 1, 1, 1},
+		AlphaCutoff: 0.5,
+		shader: &shader.Shader{
+			VertFilePath: "./resource/shader/billboard.vert",
+			FragFilePath: "./resource/shader/billboard.frag",
+		},
+	}
+	if err := b.shader.Init(); err != nil {
+		return nil, err
+	}
+
+	gl.GenVertexArrays(1, &b.vao)
+	gl.BindVertexArray(b.vao)
+	gl.GenBuffers(1, &b.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(billboardCorners)*4, gl.Ptr(billboardCorners), gl.STATIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 2*4, gl.PtrOffset(0))
+	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	if textureFile != "" {
+		opts := texture.ColorOptions()
+		opts.WrapS, opts.WrapT = gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE
+		texture.NewTextureAsync(opts, textureFile,
+			func(id uint32, err error) {
+				if err != nil {
+					logger.Error(err)
+					return
+				}
+				b.texture = id
+			})
+	}
+	return b, nil
+}
+
+// NewBillboardFromXml 按场景配置创建公告板, 贴图相对于 resource/model/<name>
+func NewBillboardFromXml(xmlModel config.XmlModel) (*Billboard, error) {
+	xmlBillboard := xmlModel.Billboard
+	if xmlBillboard == nil {
+		xmlBillboard = &config.XmlBillboard{Width: 1, Height: 1}
+	}
+	file := ""
+	if xmlBillboard.Texture != "" {
+		file = filepath.Join(vfs.Path("model", xmlModel.Name), xmlBillboard.Texture)
+	}
+	b, err := NewBillboard(file, mgl32.Vec2{xmlBillboard.Width, xmlBillboard.Height})
+	if err != nil {
+		return nil, err
+	}
+	b.Name = xmlModel.Name
+	b.Id = xmlModel.Id
+	b.RenderPriority = NewRenderPriority(xmlModel)
+	b.Position = xmlModel.Position.XYZ()
+	b.Axis = xmlBillboard.Axis.XYZ()
+	b.Blend = xmlBillboard.Blend
+	b.Flipbook = newFlipbook(xmlBillboard.Flipbook)
+	if xmlBillboard.Color != nil {
+		b.Color = xmlBillboard.Color.RGBA()
+	}
+	return b, nil
+}
+
+func (b *Billboard) Dispose() {
+	gl.DeleteVertexArrays(1, &b.vao)
+	gl.DeleteBuffers(1, &b.vbo)
+	if b.texture != 0 {
+		gl.DeleteTextures(1, &b.texture)
+		b.texture = 0
+	}
+	b.shader.Dispose()
+}
+
+func (b *Billboard) Transparent() bool {
+	return b.Blend
+}
+
+// WorldBounds 任意朝向下都能包住四边形的包围盒
+func (b *Billboard) WorldBounds() geometry.AABB {
+	r := b.Size.Len() / 2
+	extent := mgl32.Vec3{r, r, r}
+	return geometry.AABB{Min: b.Position.Sub(extent), Max: b.Position.Add(extent)}
+}
+
+func (b *Billboard) Update(elapsed float64) {
+	if b.Flipbook != nil {
+		b.Flipbook.Update(elapsed)
+	}
+}
+
+func (b *Billboard) PreRender() {
+	gl.GetIntegerv(gl.POLYGON_MODE, &b.lastPolygonMode[0])
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+}
+
+func (b *Billboard) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	b.shader.Use()
+	b.shader.SetUniform("projection", projection)
+	b.shader.SetUniform("view", view)
+	b.shader.SetUniform("gCenter", model.Mul4x1(b.Position.Vec4(1)).Vec3())
+	b.shader.SetUniform("gSize", b.Size)
+	b.shader.SetUniform("gAxisLock", b.Axis.Len() > 0)
+	b.shader.SetUniform("gAxis", b.Axis)
+	b.shader.SetUniform("gEyeWorldPos", *eyePosition)
+	b.shader.SetUniform("gColor", b.Color)
+	b.shader.SetUniform("gHasTexture", b.texture != 0)
+	alphaCutoff := b.AlphaCutoff
+	if b.Blend {
+		alphaCutoff = 0
+	}
+	b.shader.SetUniform("gAlphaCutoff", alphaCutoff)
+	b.shader.SetUniform("gTexture", int32(0))
+	frameRect, nextRect, blend := mgl32.Vec4{0, 0, 1, 1}, mgl32.Vec4{0, 0, 1, 1}, float32(0)
+	if b.Flipbook != nil {
+		frame, next, t := b.Flipbook.Frame()
+		frameRect, nextRect, blend = b.Flipbook.FrameRect(frame), b.Flipbook.FrameRect(next), t
+	}
+	b.shader.SetUniform("gFrame", frameRect)
+	b.shader.SetUniform("gNextFrame", nextRect)
+	b.shader.SetUniform("gFrameBlend", blend)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, b.texture)
+	gl.BindVertexArray(b.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(billboardCorners)/2))
+	gl.BindVertexArray(0)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	b.shader.UnUse()
+}
+
+func (b *Billboard) PostRender() {
+	gl.PolygonMode(gl.FRONT_AND_BACK, uint32(b.lastPolygonMode[0]))
+}
@@ -1 +1,4 @@
 package model
+
+// defaultMaterialName assimp 为没有材质的模型文件生成的默认材质名称
+const defaultMaterialName = "DefaultMaterial"
@@ -0,0 +1,302 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/job"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/skin"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+const (
+	defaultCrowdCount = 500
+	// 每批最多的实例数, 即骨骼纹理的行数, 超出时分批绘制
+	crowdBatchSize = 4096
+	maxCrowdCount  = 100000
+	// 动画速度为 1 时每秒移动的距离, 与行走动画的步幅一致
+	crowdStride = 1.4
+)
+
+// CrowdStats 角色数, 最近一次绘制的可见角色数和绘制调用数
+type CrowdStats struct {
+	Instances int
+	Visible   int
+	DrawCalls int
+}
+
+type crowdAgent struct {
+	position mgl32.Vec3
+	yaw      float32
+	// 动画时间偏移和速度, 使角色的步伐错开
+	offset float32
+	speed  float32
+	color  mgl32.Vec3
+}
+
+// Crowd 实例化的蒙皮角色群, 在区域内随机分布并沿各自的朝向行走, 走出区域后从另一侧回来
+// 每帧在 CPU 上并行计算可见角色的蒙皮矩阵, 写入骨骼纹理, 每批一次绘制调用
+type Crowd struct {
+	Name string
+	Id   string
+
+	RenderPriority
+
+	// 区域中心和半宽
+	Position mgl32.Vec3
+	Size     float32
+	Count    int
+	Seed     int64
+	Scale    float32
+	// 动画速度的随机范围, 移动速度随动画速度变化
+	SpeedMin float32
+	SpeedMax float32
+	// 原地行走, 不移动
+	InPlace bool
+
+	Stats CrowdStats
+
+	agents   []crowdAgent
+	heightAt HeightFunc
+
+	skeleton *skin.Skeleton
+	mesh     *skin.Mesh
+	clip     *skin.Clip
+	bones    *skin.BoneTexture
+
+	visible    []int
+	transforms []mgl32.Mat4
+	colors     []mgl32.Vec3
+	palettes   []mgl32.Mat4
+
+	effect *technique.LightingTechnique
+	shader *shader.Shader
+	time   float32
+}
+
+func NewCrowd(xmlModel config.XmlModel) (*Crowd, error) {
+	c := &Crowd{
+		Name:           xmlModel.Name,
+		Id:             xmlModel.Id,
+		RenderPriority: NewRenderPriority(xmlModel),
+		Position:       xmlModel.Position.XYZ(),
+		Size:           20,
+		Count:          defaultCrowdCount,
+		Scale:          1,
+		SpeedMin:       0.8,
+		SpeedMax:       1.2,
+		effect:         &technique.LightingTechnique{},
+		shader: &shader.Shader{
+			VertFilePath: "./resource/shader/crowd.vert",
+			FragFilePath: "./resource/shader/crowd.frag",
+		},
+	}
+	if xmlCrowd := xmlModel.Crowd; xmlCrowd != nil {
+		if xmlCrowd.Size > 0 {
+			c.Size = xmlCrowd.Size
+		}
+		if xmlCrowd.Count > 0 {
+			c.Count = min(xmlCrowd.Count, maxCrowdCount)
+		}
+		if xmlCrowd.Scale > 0 {
+			c.Scale = xmlCrowd.Scale
+		}
+		if speed := xmlCrowd.Speed; speed.Max > 0 {
+			c.SpeedMin, c.SpeedMax = speed.Min, max(speed.Max, speed.Min)
+		}
+		c.Seed = xmlCrowd.Seed
+		c.InPlace = xmlCrowd.InPlace
+	}
+
+	if err := c.shader.Init(); err != nil {
+		return nil, err
+	}
+	c.effect.Init(c.shader)
+
+	var err error
+	if c.skeleton, c.mesh, c.clip, err = skin.NewCharacter(); err != nil {
+		return nil, err
+	}
+	c.mesh.Setup()
+	c.bones = skin.NewBoneTexture(len(c.skeleton.Bones), crowdBatchSize)
+	return c, nil
+}
+
+// Spawn 在区域内重新随机分布角色, heightAt 为空时所有角色在 Position 的高度
+func (c *Crowd) Spawn(heightAt HeightFunc) {
+	c.heightAt = heightAt
+	rnd := rand.New(rand.NewSource(c.Seed))
+	c.Count = min(max(c.Count, 0), maxCrowdCount)
+	c.agents = make([]crowdAgent, c.Count)
+	for i := range c.agents {
+		agent := &c.agents[i]
+		agent.position = c.Position.Add(mgl32.Vec3{(rnd.Float32()*2 - 1) * c.Size, 0, (rnd.Float32()*2 - 1) * c.Size})
+		agent.yaw = rnd.Float32() * 2 * math.Pi
+		agent.offset = rnd.Float32() * c.clip.Duration
+		agent.speed = c.SpeedMin + (c.SpeedMax-c.SpeedMin)*rnd.Float32()
+		agent.color = mgl32.Vec3{0.3 + 0.7*rnd.Float32(), 0.3 + 0.7*rnd.Float32(), 0.3 + 0.7*rnd.Float32()}
+		c.snapToSurface(agent)
+	}
+	c.Stats.Instances = len(c.agents)
+}
+
+// Respawn 使用上次的高度函数重新分布, 用于修改数量之后
+func (c *Crowd) Respawn() {
+	c.Spawn(c.heightAt)
+}
+
+func (c *Crowd) snapToSurface(agent *crowdAgent) {
+	if c.heightAt == nil {
+		agent.position[1] = c.Position.Y()
+		return
+	}
+	if h, ok := c.heightAt(agent.position.X(), agent.position.Z()); ok {
+		agent.position[1] = h
+	}
+}
+
+// WorldBounds 角色活动区域的包围盒
+func (c *Crowd) WorldBounds() geometry.AABB {
+	height := c.mesh.Bounds.Max.Y() * c.Scale
+	bounds := geometry.NewAABB()
+	for _, agent := range c.agents {
+		bounds.Extend(agent.position)
+	}
+	if bounds.IsEmpty() {
+		return geometry.AABB{Min: c.Position, Max: c.Position}
+	}
+	bounds.Max[1] += height
+	return bounds
+}
+
+func (c *Crowd) SetPosition(p mgl32.Vec3) {
+	c.Position = p
+}
+
+// Update 推进动画时间, 角色沿朝向行走, 超出区域时从对侧回到区域内
+func (c *Crowd) Update(elapsed float64) {
+	dt := float32(elapsed)
+	c.time += dt
+	if c.InPlace {
+		return
+	}
+	job.Wait(job.ParallelFor(len(c.agents), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			agent := &c.agents[i]
+			step := crowdStride * c.Scale * agent.speed * dt
+			sin, cos := math.Sincos(float64(agent.yaw))
+			agent.position[0] += float32(sin) * step
+			agent.position[2] += float32(cos) * step
+			for _, axis := range []int{0, 2} {
+				if local := agent.position[axis] - c.Position[axis]; local > c.Size {
+					agent.position[axis] -= 2 * c.Size
+				} else if local < -c.Size {
+					agent.position[axis] += 2 * c.Size
+				}
+			}
+			c.snapToSurface(agent)
+		}
+	}))
+}
+
+func (c *Crowd) PreRender() {
+}
+
+// selectAgents 收集在视锥内的角色
+func (c *Crowd) selectAgents(frustum *geometry.Frustum) {
+	c.visible = c.visible[:0]
+	extent := c.mesh.Bounds.MaxExtent() * c.Scale
+	for i, agent := range c.agents {
+		if frustum != nil {
+			bounds := geometry.AABB{
+				Min: agent.position.Sub(mgl32.Vec3{extent, 0, extent}),
+				Max: agent.position.Add(mgl32.Vec3{extent, extent, extent}),
+			}
+			if !frustum.IntersectsAABB(bounds) {
+				continue
+			}
+		}
+		c.visible = append(c.visible, i)
+	}
+	c.Stats.Visible = len(c.visible)
+}
+
+// skinBatch 并行计算一批角色的蒙皮矩阵, 变换和颜色
+func (c *Crowd) skinBatch(batch []int) {
+	bones := len(c.skeleton.Bones)
+	c.palettes = resize(c.palettes, len(batch)*bones)
+	c.transforms = resize(c.transforms, len(batch))
+	c.colors = resize(c.colors, len(batch))
+	job.Wait(job.ParallelFor(len(batch), func(begin, end int) {
+		local := make([]skin.Transform, bones)
+		for i := begin; i < end; i++ {
+			agent := &c.agents[batch[i]]
+			c.clip.Sample(c.skeleton, c.time*agent.speed+agent.offset, local)
+			c.skeleton.Skin(local, c.palettes[i*bones:(i+1)*bones])
+			c.transforms[i] = mgl32.Translate3D(agent.position.X(), agent.position.Y(), agent.position.Z()).
+				Mul4(mgl32.HomogRotate3DY(agent.yaw)).
+				Mul4(mgl32.Scale3D(c.Scale, c.Scale, c.Scale))
+			c.colors[i] = agent.color
+		}
+	}))
+}
+
+func resize[T any](s []T, n int) []T {
+	if cap(s) < n {
+		return make([]T, n)
+	}
+	return s[:n]
+}
+
+func (c *Crowd) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	var frustum *geometry.Frustum
+	if config.Config.FrustumCulling {
+		f := geometry.NewFrustum(projection.Mul4(view))
+		frustum = &f
+	}
+	c.selectAgents(frustum)
+	c.Stats.DrawCalls = 0
+	if len(c.visible) == 0 {
+		return
+	}
+
+	mvp := projection.Mul4(view).Mul4(model)
+	c.effect.Enable()
+	c.effect.SetProjectMatrix(&projection)
+	c.effect.SetViewMatrix(&view)
+	c.effect.SetModelMatrix(&model)
+	c.effect.SetWVP(&mvp)
+	c.effect.SetEyeWorldPos(eyePosition)
+	c.effect.SetPointLight(lights)
+	c.effect.SetFog(config.Config.Fog)
+	c.shader.SetUniform("gBones", int32(0))
+
+	program := c.effect.ShaderObj.Program
+	gl.BindFragDataLocation(program, 0, gl.Str("color\x00"))
+	for begin := 0; begin < len(c.visible); begin += c.bones.Rows() {
+		batch := c.visible[begin:min(begin+c.bones.Rows(), len(c.visible))]
+		c.skinBatch(batch)
+		c.bones.Upload(c.palettes)
+		gl.ActiveTexture(gl.TEXTURE0)
+		gl.BindTexture(gl.TEXTURE_2D, c.bones.Id)
+		c.mesh.DrawInstanced(c.transforms, c.colors)
+		c.Stats.DrawCalls++
+	}
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	c.effect.Disable()
+}
+
+func (c *Crowd) PostRender() {
+}
+
+func (c *Crowd) Dispose() {
+	c.mesh.Dispose()
+	c.bones.Dispose()
+	c.shader.Dispose()
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/huangxiaobo/toy-engine/engine/mesh"
 	"github.com/huangxiaobo/toy-engine/engine/shader"
 	"github.com/huangxiaobo/toy-engine/engine/technique"
-	"github.com/huangxiaobo/toy-engine/engine/utils"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
 	"path/filepath"
 )
 
@@ -22,6 +22,8 @@ type Ground struct {
 	Name string
 	Id   string
 
+	RenderPriority
+
 	Material *material.Material
 	effect   *technique.LightingTechnique
 	shader   *shader.Shader
@@ -30,18 +32,27 @@ type Ground struct {
 	model    mgl32.Mat4
 
 	DrawMode uint32
+
+	// 平面反射, 开启时地面作为透明对象在不透明对象之后绘制
+	Reflection       *PlanarReflection
+	plane            *mesh.Mesh
+	reflectionEffect *technique.LightingTechnique
+	reflectionShader *shader.Shader
+	// 正在绘制反射纹理, 地面自身不出现在反射中
+	reflecting bool
 }
 
 func NewGround(xmlModel config.XmlModel) (Ground, error) {
-	basePath := filepath.Join(utils.GetCurrentDir(), "resource/model", xmlModel.Name)
+	basePath := vfs.Path("model", xmlModel.Name)
 	g := Ground{
-		BasePath: basePath,
-		Position: mgl32.Vec3{0, 0, 0},
-		model:    mgl32.Ident4(),
-		Name:     xmlModel.Name,
-		Id:       xmlModel.Id,
-		FileName: xmlModel.Mesh.File,
-		effect:   &technique.LightingTechnique{},
+		BasePath:       basePath,
+		Position:       mgl32.Vec3{0, 0, 0},
+		model:          mgl32.Ident4(),
+		Name:           xmlModel.Name,
+		Id:             xmlModel.Id,
+		RenderPriority: NewRenderPriority(xmlModel),
+		FileName:       xmlModel.Mesh.File,
+		effect:         &technique.LightingTechnique{},
 		Material: &material.Material{
 			AmbientColor:  xmlModel.Material.AmbientColor.RGB(),
 			DiffuseColor:  xmlModel.Material.DiffuseColor.RGB(),
@@ -51,6 +62,13 @@ func NewGround(xmlModel config.XmlModel) (Ground, error) {
 		shader: &shader.Shader{
 			VertFilePath: filepath.Join(basePath, xmlModel.Shader.VertFile),
 			FragFilePath: filepath.Join(basePath, xmlModel.Shader.FragFile),
+			Defines:      shaderDefines(xmlModel.Shader.Defines).List(),
+		},
+		Reflection:       NewPlanarReflection(xmlModel.Reflection),
+		reflectionEffect: &technique.LightingTechnique{},
+		reflectionShader: &shader.Shader{
+			VertFilePath: filepath.Join(basePath, xmlModel.Shader.VertFile),
+			FragFilePath: filepath.Join(basePath, "reflection.frag"),
 		},
 	}
 
@@ -69,12 +87,43 @@ func (g *Ground) Init() {
 		panic(err)
 	}
 	g.effect.Init(g.shader)
+
+	g.plane = mesh.NewMeshGroundPlane()
+	if err := g.reflectionShader.Init(); err != nil {
+		logger.Error(err)
+		panic(err)
+	}
+	g.reflectionEffect.Init(g.reflectionShader)
 }
 
 func (g *Ground) Dispose() {
 	for i := 0; i < len(g.Meshes); i++ {
 		g.Meshes[i].Dispose()
 	}
+	g.plane.Dispose()
+	g.Reflection.Dispose()
+}
+
+// Transparent 开启反射时, 反射平面需要混合到已绘制的场景上
+func (g *Ground) Transparent() bool {
+	return g.Reflection.Enable
+}
+
+// RenderReflection 使用关于地面镜像的相机调用 draw 绘制反射纹理
+func (g *Ground) RenderReflection(width, height int32, projection, view mgl32.Mat4, eyePosition mgl32.Vec3,
+	draw func(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3)) error {
+	if !g.Reflection.Enable {
+		return nil
+	}
+	mirrorProjection, mirrorView, mirrorEye, err := g.Reflection.Begin(width, height, projection, view, eyePosition, g.Position.Y())
+	if err != nil {
+		return err
+	}
+	g.reflecting = true
+	draw(mirrorProjection, mirrorView, &mirrorEye)
+	g.reflecting = false
+	g.Reflection.End()
+	return nil
 }
 
 func (g *Ground) SetPosition(p mgl32.Vec3) {
@@ -89,10 +138,18 @@ func (g *Ground) PreRender() {
 }
 
 func (g *Ground) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	if g.reflecting {
+		return
+	}
+
 	// RenderObj
 	model = model.Mul4(g.model)
 	mvp := projection.Mul4(view).Mul4(model)
 
+	if g.Reflection.Enable && config.Config.ShadingMode == config.ShadingRendered {
+		g.renderReflectionPlane(projection, model, view, mvp, eyePosition)
+	}
+
 	// Effect
 	g.effect.Enable()
 	g.effect.SetProjectMatrix(&projection)
@@ -100,6 +157,7 @@ func (g *Ground) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.V
 	g.effect.SetModelMatrix(&model)
 	g.effect.SetWVP(&mvp)
 	g.effect.SetEyeWorldPos(eyePosition)
+	g.effect.SetFog(config.Config.Fog)
 
 	gl.BindFragDataLocation(g.effect.ShaderObj.Program, 0, gl.Str("color\x00"))
 
@@ -113,3 +171,38 @@ func (g *Ground) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.V
 func (g *Ground) PostRender() {
 	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
 }
+
+// renderReflectionPlane 按屏幕坐标采样反射纹理绘制地面平面
+func (g *Ground) renderReflectionPlane(projection, model, view, mvp mgl32.Mat4, eyePosition *mgl32.Vec3) {
+	texture := g.Reflection.Texture()
+	if texture == 0 {
+		return
+	}
+	var viewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+	g.reflectionEffect.Enable()
+	g.reflectionEffect.SetProjectMatrix(&projection)
+	g.reflectionEffect.SetViewMatrix(&view)
+	g.reflectionEffect.SetModelMatrix(&model)
+	g.reflectionEffect.SetWVP(&mvp)
+	g.reflectionEffect.SetEyeWorldPos(eyePosition)
+	g.reflectionEffect.SetFog(config.Config.Fog)
+
+	g.reflectionShader.SetUniform("gScreenSize", mgl32.Vec2{float32(viewport[2]), float32(viewport[3])})
+	g.reflectionShader.SetUniform("gTexelSize", g.Reflection.TexelSize())
+	g.reflectionShader.SetUniform("gReflectionStrength", g.Reflection.Strength)
+	g.reflectionShader.SetUniform("gReflectionBlur", g.Reflection.Blur)
+	g.reflectionShader.SetUniform("gReflectionFresnel", g.Reflection.Fresnel)
+	g.reflectionShader.SetUniform("gReflection", int32(0))
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+
+	gl.BindFragDataLocation(g.reflectionEffect.ShaderObj.Program, 0, gl.Str("color\x00"))
+	g.plane.Draw(g.reflectionEffect.ShaderObj.Program)
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	g.reflectionEffect.Disable()
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+const (
+	DefaultLightmapSize    = 512
+	MaxLightmapSize        = 4096
+	DefaultLightmapSamples = 64
+)
+
+// Lightmap 模型的光照贴图, 记录第二套UV上每个像素烘焙的间接光照, 绘制时替代点光源的环境光项
+type Lightmap struct {
+	// 贴图文件的完整路径
+	File      string
+	Size      int32
+	Intensity float32
+	// 每个像素的半球采样数
+	Samples int32
+	// 加载模型时按三角形重新打包第二套UV
+	Pack bool
+
+	Tex uint32
+}
+
+func newLightmap(basePath, id string, xmlLightmap *config.XmlLightmap) *Lightmap {
+	if xmlLightmap == nil {
+		return nil
+	}
+	l := &Lightmap{
+		File:      filepath.Join(basePath, xmlLightmap.Texture),
+		Size:      xmlLightmap.Size,
+		Intensity: xmlLightmap.Intensity,
+		Samples:   xmlLightmap.Samples,
+		Pack:      !xmlLightmap.FileUV,
+	}
+	if xmlLightmap.Texture == "" {
+		l.File = filepath.Join(basePath, fmt.Sprintf("lightmap_%s.png", id))
+	}
+	if l.Size <= 0 {
+		l.Size = DefaultLightmapSize
+	}
+	l.Size = min(l.Size, MaxLightmapSize)
+	if l.Intensity <= 0 {
+		l.Intensity = 1
+	}
+	if l.Samples <= 0 {
+		l.Samples = DefaultLightmapSamples
+	}
+	return l
+}
+
+// packLightmap 打包第二套UV, 需在上传顶点之前调用
+func (m *Model) packLightmap() {
+	if m.Lightmap == nil || !m.Lightmap.Pack {
+		return
+	}
+	if texels, ok := mesh.PackLightmapUV(m.Meshes, m.Lightmap.Size); !ok {
+		logger.Warn(fmt.Sprintf("model %s: %d triangles leave %.1f lightmap pixels per cell, increase the lightmap size",
+			m.Name, m.triangleCount(), texels))
+	}
+}
+
+func (m *Model) triangleCount() int {
+	count := 0
+	for _, mi := range m.Meshes {
+		if mi.DrawMode == gl.TRIANGLES {
+			count += len(mi.Indices) / 3
+		}
+	}
+	return count
+}
+
+// loadLightmap 异步加载已烘焙的光照贴图, 还没有烘焙时不使用光照贴图
+func (m *Model) loadLightmap() {
+	if m.Lightmap == nil || !vfs.Exists(m.Lightmap.File) {
+		return
+	}
+	texture.NewTextureAsync(texture.ClampOptions(), m.Lightmap.File, func(tex uint32, err error) {
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		m.setLightmapTexture(tex)
+	})
+}
+
+// SetLightmapImage 使用烘焙结果替换光照贴图, 需在 GL 线程调用
+func (m *Model) SetLightmapImage(rgba *image.RGBA) {
+	if m.Lightmap == nil {
+		return
+	}
+	m.setLightmapTexture(texture.NewTextureFromImage(texture.ClampOptions(), rgba))
+}
+
+func (m *Model) setLightmapTexture(tex uint32) {
+	if m.Lightmap.Tex != 0 {
+		gl.DeleteTextures(1, &m.Lightmap.Tex)
+	}
+	m.Lightmap.Tex = tex
+}
+
+// setLightmap 绑定光照贴图, 没有烘焙结果时关闭
+func (m *Model) setLightmap() {
+	if m.Lightmap == nil {
+		m.effect.SetLightmap(0, 0)
+		return
+	}
+	m.effect.SetLightmap(m.Lightmap.Tex, m.Lightmap.Intensity)
+}
+
+func (m *Model) disposeLightmap() {
+	if m.Lightmap != nil && m.Lightmap.Tex != 0 {
+		gl.DeleteTextures(1, &m.Lightmap.Tex)
+		m.Lightmap.Tex = 0
+	}
+}
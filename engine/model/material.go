@@ -0,0 +1,203 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// MaterialDir 材质文件的目录, <material ref="name"/> 按名称查找 name.xml 或 name.json
+var MaterialDir = vfs.Path("material")
+
+// materialExts 材质文件的扩展名, 按顺序查找
+var materialExts = []string{".xml", ".json"}
+
+// sharedMaterial 材质文件中定义的材质, 引用它的模型使用同一个 *material.Material
+// 贴图路径相对于材质文件所在的目录, 贴图和随时间变化的属性由注册表管理, 不由模型管理
+type sharedMaterial struct {
+	mat     *material.Material
+	file    string
+	modTime time.Time
+	refs    int
+}
+
+var (
+	materialsMu sync.Mutex
+	materials   = map[string]*sharedMaterial{}
+)
+
+// LoadMaterial 返回名称为 name 的共享材质并增加引用计数, 第一次使用时读取材质文件
+// 修改返回的材质会影响所有引用它的模型, 不再使用时调用 ReleaseMaterial
+func LoadMaterial(name string) (*material.Material, error) {
+	materialsMu.Lock()
+	defer materialsMu.Unlock()
+	if shared, ok := materials[name]; ok {
+		shared.refs++
+		return shared.mat, nil
+	}
+
+	file, err := materialFile(name)
+	if err != nil {
+		return nil, err
+	}
+	mat, err := readMaterial(name, file)
+	if err != nil {
+		return nil, err
+	}
+	shared := &sharedMaterial{mat: mat, file: file, refs: 1}
+	shared.modTime, _ = vfs.ModTime(file)
+	shared.loadTextures()
+	materials[name] = shared
+	return mat, nil
+}
+
+// ReleaseMaterial 减少 LoadMaterial 返回的材质的引用计数, 归零时删除材质的贴图, 必须在主线程调用
+func ReleaseMaterial(name string) {
+	materialsMu.Lock()
+	defer materialsMu.Unlock()
+	shared, ok := materials[name]
+	if !ok {
+		return
+	}
+	shared.refs--
+	if shared.refs > 0 {
+		return
+	}
+	delete(materials, name)
+	deleteMaterialTextures(shared.mat)
+}
+
+// materialFile 在 MaterialDir 中查找名称为 name 的材质文件
+func materialFile(name string) (string, error) {
+	for _, ext := range materialExts {
+		file := filepath.Join(MaterialDir, name+ext)
+		if vfs.Exists(file) {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("material %q not found in %s", name, MaterialDir)
+}
+
+// readMaterial 读取材质文件, 贴图由调用者加载
+func readMaterial(name, file string) (*material.Material, error) {
+	xmlMaterial, err := config.ReadMaterial(file)
+	if err != nil {
+		return nil, err
+	}
+	return newMaterial(name, xmlMaterial), nil
+}
+
+// loadTextures 异步加载材质的贴图, 路径相对于材质文件所在的目录
+func (shared *sharedMaterial) loadTextures() {
+	dir := filepath.Dir(shared.file)
+	loadMaterialTextures(dir, shared.mat)
+	loadFlipbookTexture(dir, shared.mat.Flipbook)
+}
+
+// isSharedMaterial mat 是否是材质文件中定义的共享材质
+func isSharedMaterial(mat *material.Material) bool {
+	materialsMu.Lock()
+	defer materialsMu.Unlock()
+	for _, shared := range materials {
+		if shared.mat == mat {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateMaterials 推进共享材质的序列帧和纹理坐标动画, 每帧调用一次
+// 引用共享材质的模型不再推进它, 多个模型引用时动画速度不变
+func UpdateMaterials(elapsed float64) {
+	materialsMu.Lock()
+	defer materialsMu.Unlock()
+	for _, shared := range materials {
+		shared.mat.Update(elapsed)
+	}
+}
+
+// ReloadChangedMaterials 重新读取修改过的材质文件, 在原地替换材质, 引用它的模型立即使用新的属性
+// 返回重新加载的材质文件, 失败时保留原来的材质并写入日志, 必须在主线程调用
+func ReloadChangedMaterials() []string {
+	materialsMu.Lock()
+	defer materialsMu.Unlock()
+	var reloaded []string
+	for name, shared := range materials {
+		modTime, _ := vfs.ModTime(shared.file)
+		if modTime.Equal(shared.modTime) {
+			continue
+		}
+		shared.modTime = modTime
+		fresh, err := readMaterial(name, shared.file)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+		deleteMaterialTextures(shared.mat)
+		*shared.mat = *fresh
+		shared.loadTextures()
+		reloaded = append(reloaded, shared.file)
+	}
+	return reloaded
+}
+
+// loadMaterialTextures 异步加载材质的细节贴图和自发光贴图, 路径相对于 dir
+// 这些贴图是可选的, 加载完成前不使用
+func loadMaterialTextures(dir string, mat *material.Material) {
+	load := func(file string, id *uint32, opts texture.Options) {
+		if file == "" || *id != 0 {
+			return
+		}
+		texture.NewTextureAsync(opts, filepath.Join(dir, file), func(tex uint32, err error) {
+			if err != nil {
+				logger.Error(err)
+				return
+			}
+			*id = tex
+		})
+	}
+	load(mat.EmissiveMap, &mat.EmissiveTex, texture.ColorOptions())
+	if detail := mat.Detail; detail != nil {
+		load(detail.AlbedoMap, &detail.AlbedoTex, texture.ColorOptions())
+		load(detail.NormalMap, &detail.NormalTex, texture.DefaultOptions())
+	}
+}
+
+// loadFlipbookTexture 异步加载序列帧的图集, 路径相对于 dir
+func loadFlipbookTexture(dir string, flipbook *material.Flipbook) {
+	if flipbook == nil || flipbook.Texture == "" || flipbook.TextureId != 0 {
+		return
+	}
+	texture.NewTextureAsync(texture.ColorOptions(), filepath.Join(dir, flipbook.Texture), func(tex uint32, err error) {
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		flipbook.TextureId = tex
+	})
+}
+
+// deleteMaterialTextures 删除共享材质加载的贴图
+func deleteMaterialTextures(mat *material.Material) {
+	ids := []uint32{mat.EmissiveTex}
+	if mat.Detail != nil {
+		ids = append(ids, mat.Detail.AlbedoTex, mat.Detail.NormalTex)
+	}
+	if mat.Flipbook != nil {
+		ids = append(ids, mat.Flipbook.TextureId)
+	}
+	for _, id := range ids {
+		if id != 0 {
+			gl.DeleteTextures(1, &id)
+		}
+	}
+}
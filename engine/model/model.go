@@ -6,32 +6,57 @@ import (
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/glqueue"
+	"github.com/huangxiaobo/toy-engine/engine/job"
 	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/loader"
 	"github.com/huangxiaobo/toy-engine/engine/logger"
 	"github.com/huangxiaobo/toy-engine/engine/material"
 	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/resource"
 	"github.com/huangxiaobo/toy-engine/engine/shader"
 	"github.com/huangxiaobo/toy-engine/engine/technique"
 	"github.com/huangxiaobo/toy-engine/engine/texture"
-	"github.com/huangxiaobo/toy-engine/engine/utils"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
 	"github.com/rishabh-bector/assimp-golang"
+	"image"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 type Model struct {
-	texturesLoaded  map[string]texture.Texture
-	wg              sync.WaitGroup
 	Meshes          []*mesh.Mesh
 	GammaCorrection bool
 	BasePath        string
 	FileName        string
 
-	Name     string
-	Id       string
+	Name string
+	Id   string
+
+	RenderPriority
+
 	Material *material.Material
-	effect   *technique.LightingTechnique
-	shader   *shader.Shader
+	// 材质槽, 由模型文件中的材质生成, 网格通过 MaterialIndex 引用
+	// 没有对应材质槽的网格使用 Material
+	Materials    []*material.Material
+	xmlMaterials []config.XmlMaterialSlot
+	// 引用的共享材质的名称, Dispose 时释放
+	materialRefs []string
+	// 材质实例, 覆盖 Material 或材质槽的少数属性, 为空时直接使用材质
+	MaterialInstance *material.Instance
+	SlotInstances    map[int]*material.Instance
+	// 材质使用的序列帧, 材质槽可能共享同一个序列帧, 每帧只推进一次
+	flipbooks []*material.Flipbook
+	effect    *technique.LightingTechnique
+	shader    *shader.Shader
+	// 声明的绘制阶段, 为空时使用 technique.DefaultPasses
+	passes []technique.Pass
+	// 自定义着色器的参数, 每次绘制时设置
+	Params []material.Param
 
 	Position   mgl32.Vec3
 	Scale      mgl32.Vec3
@@ -40,36 +65,332 @@ type Model struct {
 	model      mgl32.Mat4
 
 	DrawMode uint32
+
+	// 导入时归一化的目标大小, 0 表示保持原始尺寸
+	NormalizeSize float32
+	// 模型空间包围盒(归一化之后)
+	Bounds geometry.AABB
+
+	// 实例变换矩阵, 非空时一次绘制调用绘制所有实例, 最终变换为 model * instance
+	Instances []mgl32.Mat4
+
+	// 光照贴图, 可为空
+	Lightmap *Lightmap
+
+	// 内置加载器读取的节点树(glTF 节点, OBJ 对象或组), 节点变换已经烘焙到网格, assimp 加载的模型为空
+	Nodes []*loader.Node
+
+	// 从 resource 获取的导入结果和贴图, Dispose 时释放
+	meshKey  string
+	textures []string
+
+	// 创建模型的 xml 描述, NewModelFromFile 创建的模型为空, 重新加载时使用
+	xmlModel *config.XmlModel
+	// 模型文件和它引用的文件(材质库, 外部缓冲)加载时的修改时间
+	sources map[string]time.Time
 }
 
 func NewModel(xmlModel config.XmlModel) (Model, error) {
-	basePath := filepath.Join(utils.GetCurrentDir(), "resource/model", xmlModel.Name)
+	m := newModel(xmlModel)
+	m.Init()
+
+	return m, nil
+}
+
+// NewModelAsync 在任务中读取模型文件, 处理网格并解码贴图, 再由主线程的 GL 命令队列上传网格和编译着色器
+// 完成或失败后在主线程调用 callback
+func NewModelAsync(xmlModel config.XmlModel, callback func(*Model, error)) {
+	m := newModel(xmlModel)
+	job.Schedule(func() {
+		// prepare 中的 panic 转换为加载错误, 保证 callback 被调用, 加载进度不会停在这个模型上
+		var images decodedTextures
+		err := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%v", r)
+				}
+			}()
+			images, err = m.prepare()
+			return err
+		}()
+		if err != nil {
+			glqueue.PostCallback(func() {}, func() { callback(nil, err) })
+			return
+		}
+		glqueue.PostCallback(func() {
+			m.upload(images)
+			err = m.initShader()
+		}, func() {
+			if err != nil {
+				m.Dispose()
+				callback(nil, err)
+				return
+			}
+			callback(&m, nil)
+		})
+	})
+}
+
+// shaderDefines 场景配置中的着色器宏
+func shaderDefines(xmlDefines []config.XmlDefine) shader.Defines {
+	defines := make(shader.Defines, len(xmlDefines))
+	for _, d := range xmlDefines {
+		defines[d.Name] = d.Value
+	}
+	return defines
+}
+
+// modelShader 场景配置中的着色器, 指定了 builtin 或没有 vert 和 frag 时使用内置着色器
+// 内置着色器的宏与配置中的宏合并, 同名时配置中的优先; 名称不存在时使用 shader.DefaultBuiltin
+func modelShader(basePath, name string, xmlShader config.XmlShader) *shader.Shader {
+	defines := shaderDefines(xmlShader.Defines)
+	if xmlShader.Builtin == "" && xmlShader.VertFile != "" && xmlShader.FragFile != "" {
+		return &shader.Shader{
+			VertFilePath: filepath.Join(basePath, xmlShader.VertFile),
+			FragFilePath: filepath.Join(basePath, xmlShader.FragFile),
+			Defines:      defines.List(),
+		}
+	}
+
+	builtin, ok := shader.LookupBuiltin(xmlShader.Builtin)
+	if !ok {
+		if xmlShader.Builtin != "" {
+			logger.Error(fmt.Sprintf("model %s: unknown builtin shader %q, available: %s", name, xmlShader.Builtin, strings.Join(shader.BuiltinNames(), ", ")))
+		}
+		builtin, _ = shader.LookupBuiltin(shader.DefaultBuiltin)
+	}
+	for k, v := range defines {
+		builtin.Defines[k] = v
+	}
+	return &shader.Shader{
+		VertFilePath: builtin.Vert,
+		FragFilePath: builtin.Frag,
+		Defines:      builtin.Defines.List(),
+	}
+}
+
+// modelPasses 场景配置中的绘制阶段, 深度预渲染只写深度, 其他阶段使用默认状态, 名称不存在时忽略
+func modelPasses(name string, kinds []string) []technique.Pass {
+	var passes []technique.Pass
+	for _, kind := range kinds {
+		i := slices.Index(technique.PassKindNames, kind)
+		if i < 0 {
+			logger.Error(fmt.Sprintf("model %s: unknown pass %q, available: %s", name, kind, strings.Join(technique.PassKindNames, ", ")))
+			continue
+		}
+		pass := technique.Pass{Name: kind, Kind: technique.PassKind(i)}
+		if pass.Kind == technique.PassDepthPrepass {
+			pass = technique.DepthPrepass()
+		}
+		passes = append(passes, pass)
+	}
+	return passes
+}
+
+// modelParams 场景配置中的着色器参数, 解析失败的参数写入日志并忽略
+func modelParams(name string, xmlParams []config.XmlParam) []material.Param {
+	var params []material.Param
+	for _, xp := range xmlParams {
+		p, err := material.ParseParam(xp.Name, xp.Type, xp.Value)
+		if err != nil {
+			logger.Error(fmt.Sprintf("model %s: %v", name, err))
+			continue
+		}
+		p.SRGB = xp.SRGB
+		params = append(params, p)
+	}
+	return params
+}
+
+func newModel(xmlModel config.XmlModel) Model {
+	basePath := vfs.Path("model", xmlModel.Name)
 	m := Model{
 		BasePath:        basePath,
 		model:           mgl32.Ident4(),
 		Name:            xmlModel.Name,
 		Id:              xmlModel.Id,
+		RenderPriority:  NewRenderPriority(xmlModel),
 		FileName:        xmlModel.Mesh.File,
 		GammaCorrection: xmlModel.GammaCorrection,
-		texturesLoaded:  make(map[string]texture.Texture),
 		Position:        xmlModel.Position.XYZ(),
 		Scale:           xmlModel.Scale.XYZ(),
 		effect:          &technique.LightingTechnique{},
-		Material: &material.Material{
-			AmbientColor:  xmlModel.Material.AmbientColor.RGB(),
-			DiffuseColor:  xmlModel.Material.DiffuseColor.RGB(),
-			SpecularColor: xmlModel.Material.SpecularColor.RGB(),
-			Shininess:     xmlModel.Material.Shininess,
-		},
+		Material:        newMaterial(xmlModel.Name, xmlModel.Material),
+		xmlMaterials:    xmlModel.Materials,
+		Lightmap:        newLightmap(basePath, xmlModel.Id, xmlModel.Lightmap),
+		shader:          modelShader(basePath, xmlModel.Name, xmlModel.Shader),
+		passes:          modelPasses(xmlModel.Name, xmlModel.Passes),
+		Params:          modelParams(xmlModel.Name, xmlModel.Params),
+		xmlModel:        &xmlModel,
+	}
+
+	if mat, ok := m.sharedMaterial(xmlModel.Material.Ref); ok {
+		m.Material = mat
+	}
+	m.MaterialInstance = materialInstance(m.Material, xmlModel.Material)
+	if xmlModel.Normalize != nil {
+		m.NormalizeSize = xmlModel.Normalize.Size
+	}
+	return m
+}
+
+// sharedMaterial 加载 ref 引用的共享材质并记录引用, ref 为空或加载失败时返回 false, 失败时写入日志
+func (m *Model) sharedMaterial(ref string) (*material.Material, bool) {
+	if ref == "" {
+		return nil, false
+	}
+	mat, err := LoadMaterial(ref)
+	if err != nil {
+		logger.Error(fmt.Sprintf("model %s: %v", m.Name, err))
+		return nil, false
+	}
+	m.materialRefs = append(m.materialRefs, ref)
+	return mat, true
+}
+
+// materialInstance 场景配置中覆盖的材质属性, 没有覆盖时返回空
+// opacity 只在引用共享材质时作为覆盖, 否则已经是材质自身的属性
+func materialInstance(base *material.Material, xmlMaterial config.XmlMaterial) *material.Instance {
+	inst := material.NewInstance(base)
+	if xmlMaterial.Tint != nil {
+		tint := xmlMaterial.Tint.RGB()
+		inst.Tint = &tint
+	}
+	inst.Roughness = xmlMaterial.Roughness
+	if xmlMaterial.Ref != "" {
+		inst.Opacity = xmlMaterial.Opacity
+	}
+	if !inst.Overrides() {
+		return nil
+	}
+	return inst
+}
+
+// setSlotInstance 材质槽 slot 使用材质实例 inst
+func (m *Model) setSlotInstance(slot int, inst *material.Instance) {
+	if m.SlotInstances == nil {
+		m.SlotInstances = make(map[int]*material.Instance)
+	}
+	m.SlotInstances[slot] = inst
+}
+
+// NewModelFromFile 使用默认着色器和材质加载任意模型文件, 不需要 xml 描述, 加载失败时返回错误
+// 模型归一化到 normalize 大小, 0 表示保持原始尺寸
+func NewModelFromFile(path string, normalize float32) (*Model, error) {
+	m := newModelFromFile(path, normalize)
+	if err := m.loadModel(); err != nil {
+		return nil, err
+	}
+	if len(m.Meshes) == 0 {
+		m.Dispose()
+		return nil, fmt.Errorf("model %s has no meshes", path)
+	}
+	if err := m.initShader(); err != nil {
+		m.Dispose()
+		return nil, err
+	}
+	m.Update(0)
+	return m, nil
+}
+
+func newModelFromFile(path string, normalize float32) *Model {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &Model{
+		BasePath:      filepath.Dir(path),
+		FileName:      filepath.Base(path),
+		Name:          name,
+		model:         mgl32.Ident4(),
+		Scale:         mgl32.Vec3{1, 1, 1},
+		effect:        &technique.LightingTechnique{},
+		Material:      newMaterial(name, defaultMaterial),
+		NormalizeSize: normalize,
 		shader: &shader.Shader{
-			VertFilePath: filepath.Join(basePath, xmlModel.Shader.VertFile),
-			FragFilePath: filepath.Join(basePath, xmlModel.Shader.FragFile),
+			VertFilePath: "./resource/shader/model.vert",
+			FragFilePath: "./resource/shader/model.frag",
 		},
 	}
+}
 
-	m.Init()
+// defaultMaterial 没有 xml 描述的模型使用的材质, 模型文件中定义的材质槽覆盖它
+var defaultMaterial = config.XmlMaterial{
+	AmbientColor:  config.XmlRGB{R: 0.15, G: 0.15, B: 0.15},
+	DiffuseColor:  config.XmlRGB{R: 0.6, G: 0.6, B: 0.6},
+	SpecularColor: config.XmlRGB{R: 0.5, G: 0.5, B: 0.5},
+	Shininess:     16,
+}
 
-	return m, nil
+func newMaterial(name string, xmlMaterial config.XmlMaterial) *material.Material {
+	mat := &material.Material{
+		Name:          name,
+		AmbientColor:  xmlMaterial.AmbientColor.RGB(),
+		DiffuseColor:  xmlMaterial.DiffuseColor.RGB(),
+		SpecularColor: xmlMaterial.SpecularColor.RGB(),
+		Shininess:     xmlMaterial.Shininess,
+		Opacity:       1,
+	}
+	if strings.EqualFold(xmlMaterial.Blend, "alpha") {
+		mat.BlendMode = material.BlendAlpha
+	}
+	if xmlMaterial.Opacity != nil {
+		mat.Opacity = *xmlMaterial.Opacity
+	}
+	if xmlDetail := xmlMaterial.Detail; xmlDetail != nil {
+		mat.Detail = &material.Detail{
+			AlbedoMap: xmlDetail.Albedo,
+			NormalMap: xmlDetail.Normal,
+			Tiling:    xmlDetail.Tiling,
+			UVSet:     xmlDetail.UVSet,
+		}
+		if mat.Detail.Tiling <= 0 {
+			mat.Detail.Tiling = 1
+		}
+	}
+	if xmlEmissive := xmlMaterial.Emissive; xmlEmissive != nil {
+		mat.EmissiveColor = xmlEmissive.Color.RGB()
+		mat.EmissiveIntensity = xmlEmissive.Intensity
+		if mat.EmissiveIntensity <= 0 {
+			mat.EmissiveIntensity = 1
+		}
+		mat.EmissiveMap = xmlEmissive.Texture
+	}
+	mat.Flipbook = newFlipbook(xmlMaterial.Flipbook)
+	mat.UV = newUVTransform(xmlMaterial.UV)
+	return mat
+}
+
+func newUVTransform(xmlUV *config.XmlUV) *material.UVTransform {
+	if xmlUV == nil {
+		return nil
+	}
+	uv := material.NewUVTransform()
+	uv.Offset = mgl32.Vec2{xmlUV.Offset.X, xmlUV.Offset.Y}
+	if xmlUV.Scale != nil {
+		uv.Scale = mgl32.Vec2{xmlUV.Scale.X, xmlUV.Scale.Y}
+	}
+	uv.Rotation = mgl32.DegToRad(xmlUV.Rotation)
+	uv.ScrollSpeed = mgl32.Vec2{xmlUV.Scroll.X, xmlUV.Scroll.Y}
+	uv.RotationSpeed = mgl32.DegToRad(xmlUV.Spin)
+	return uv
+}
+
+func newFlipbook(xmlFlipbook *config.XmlFlipbook) *material.Flipbook {
+	if xmlFlipbook == nil {
+		return nil
+	}
+	flipbook := &material.Flipbook{
+		Texture: xmlFlipbook.Texture,
+		Columns: max(xmlFlipbook.Columns, 1),
+		Rows:    max(xmlFlipbook.Rows, 1),
+		Frames:  xmlFlipbook.Frames,
+		FPS:     xmlFlipbook.FPS,
+		Blend:   xmlFlipbook.Blend,
+	}
+	for i, name := range material.LoopModeNames {
+		if strings.EqualFold(xmlFlipbook.Loop, name) {
+			flipbook.Loop = material.LoopMode(i)
+		}
+	}
+	return flipbook
 }
 
 func (m *Model) Init() {
@@ -77,94 +398,491 @@ func (m *Model) Init() {
 		panic(err)
 	}
 
-	// shader
-	if err := m.shader.Init(); err != nil {
+	if err := m.initShader(); err != nil {
 		logger.Error(err)
 		panic(err)
 	}
+}
+
+// initShader 获取共享的着色器程序并应用初始变换, 必须在主线程调用
+func (m *Model) initShader() error {
+	s, err := resource.LoadShader(m.shader.VertFilePath, m.shader.FragFilePath, m.shader.Defines...)
+	if err != nil {
+		return err
+	}
+	m.shader = s
 	m.effect.Init(m.shader)
 
 	m.SetPosition(m.Position)
 	m.SetScale(m.Scale)
+	return nil
 }
 
 func (m *Model) Dispose() {
 	for i := 0; i < len(m.Meshes); i++ {
 		m.Meshes[i].Dispose()
 	}
+	m.disposeLightmap()
+
+	// 共享的资源只减少引用, 最后一个使用者释放时销毁
+	if m.shader != nil && m.shader.Program != 0 {
+		resource.ReleaseShader(m.shader)
+		m.shader = &shader.Shader{VertFilePath: m.shader.VertFilePath, FragFilePath: m.shader.FragFilePath, Defines: m.shader.Defines}
+	}
+	for _, path := range m.textures {
+		resource.Textures.Release(path)
+	}
+	m.textures = nil
+	for _, ref := range m.materialRefs {
+		ReleaseMaterial(ref)
+	}
+	m.materialRefs = nil
+	if m.meshKey != "" {
+		resource.Meshes.Release(m.meshKey)
+		m.meshKey = ""
+	}
 }
 
 // Loads a model with supported ASSIMP extensions from file and stores the resulting meshes in the meshes vector.
 func (m *Model) loadModel() error {
+	images, err := m.prepare()
+	if err != nil {
+		return err
+	}
+	m.upload(images)
+	return nil
+}
+
+// prepare 读取模型文件, 处理材质和顶点并解码贴图, 不调用 GL, 可以在任务中执行
+func (m *Model) prepare() (decodedTextures, error) {
 	if len(m.FileName) == 0 {
-		return nil
+		return decodedTextures{}, nil
 	}
-	// Read file via ASSIMP
+	// 同一模型文件只导入一次, 每个模型修改自己的副本
 	path := filepath.Join(m.BasePath, m.FileName)
-	scene := assimp.ImportFile(path, uint(assimp.Process_Triangulate|assimp.Process_FlipUVs))
+	key := loader.Key(path, m.Material)
+	shared, err := resource.Meshes.Load(key, func() (*loader.Scene, error) {
+		return m.importScene(path)
+	})
+	if err != nil {
+		return decodedTextures{}, err
+	}
+	m.meshKey = key
+	scene := shared.Clone(m.Material)
+	m.sources = make(map[string]time.Time, len(scene.Files))
+	for _, file := range scene.Files {
+		m.sources[file], _ = vfs.ModTime(file)
+	}
+	m.Meshes = scene.Meshes
+	m.Materials = scene.Materials
+	m.Nodes = scene.Nodes
+	// 自发光贴图路径相对于模型文件, 转换为相对于模型目录
+	for _, mat := range m.Materials {
+		if mat.EmissiveMap != m.Material.EmissiveMap {
+			mat.EmissiveMap = filepath.Join(filepath.Dir(m.FileName), mat.EmissiveMap)
+		}
+	}
+	// 模型文件没有定义材质时(assimp 生成的默认材质)直接使用引用的共享材质和它的实例, 修改材质文件后一起更新
+	sharedBase := isSharedMaterial(m.Material)
+	for i, mat := range m.Materials {
+		if mat.Name != defaultMaterialName {
+			continue
+		}
+		if sharedBase {
+			m.Materials[i] = m.Material
+		}
+		if m.MaterialInstance != nil {
+			m.setSlotInstance(i, m.MaterialInstance)
+		}
+	}
+	m.applyMaterialSlots()
+	m.loadDetailTextures(m.Material)
+	for _, mat := range m.Materials {
+		m.loadDetailTextures(mat)
+	}
+	m.collectFlipbooks()
+	m.loadParamTextures()
 
-	// Check for errors
-	if scene.Flags()&assimp.SceneFlags_Incomplete != 0 {
-		fmt.Printf("ERROR::ASSIMP:: %v\n", scene.Flags())
-		return errors.New("shit failed")
+	m.Bounds = m.computeBounds()
+	if m.NormalizeSize > 0 {
+		m.normalize(m.NormalizeSize)
 	}
+	m.packLightmap()
 
-	// Process ASSIMP's root node recursively
-	m.processNode(scene.RootNode(), scene)
-	m.wg.Wait()
-	m.initGL()
-	return nil
+	return m.decodeTextures(scene.Images), nil
+}
+
+// decodedTextures prepare 在任务中读取的贴图, 键为网格 Textures 中的 Path
+type decodedTextures struct {
+	images map[string]*image.RGBA
+	// DDS, KTX 和 KTX2 贴图不解码, 直接上传压缩数据
+	compressed map[string]*texture.CompressedImage
+}
+
+// upload 上传网格和 prepare 解码的贴图, 必须在主线程调用
+func (m *Model) upload(textures decodedTextures) {
+	if len(m.FileName) == 0 {
+		return
+	}
+	m.initGL(textures)
+	m.loadLightmap()
+}
+
+var assimpMu sync.Mutex
+
+// importScene 读取模型文件, 有未过期的网格缓存时直接读取缓存
+// glTF 和 OBJ 使用内置的加载器, 其他格式使用 assimp, 导入后写入缓存
+func (m *Model) importScene(path string) (*loader.Scene, error) {
+	if scene, ok := loader.ReadCache(path, m.Material); ok {
+		logger.Info(fmt.Sprintf("model %s: loaded mesh cache", m.Name))
+		return scene, nil
+	}
+
+	var scene *loader.Scene
+	if loader.Supported(m.FileName) {
+		var err error
+		if scene, err = loader.Load(path, m.Material); err != nil {
+			return nil, err
+		}
+	} else {
+		// Read file via ASSIMP
+		// assimp 只能读取磁盘文件, 资源包中的模型先解压
+		localPath, err := vfs.LocalPath(path)
+		if err != nil {
+			return nil, err
+		}
+		// assimp 的 C 接口不保证并发安全, 异步加载时串行导入
+		assimpMu.Lock()
+		aScene := assimp.ImportFile(localPath, uint(assimp.Process_Triangulate|assimp.Process_FlipUVs))
+		assimpMu.Unlock()
+
+		// Check for errors
+		if aScene.Flags()&assimp.SceneFlags_Incomplete != 0 {
+			fmt.Printf("ERROR::ASSIMP:: %v\n", aScene.Flags())
+			return nil, errors.New("shit failed")
+		}
+
+		// Process ASSIMP's root node recursively
+		m.processNode(aScene.RootNode(), aScene)
+
+		m.processMaterials(aScene)
+		scene = &loader.Scene{Meshes: m.Meshes, Materials: m.Materials, Files: []string{path}}
+	}
+
+	// 缓存写入失败不影响加载
+	if err := loader.WriteCache(path, m.Material, scene); err != nil {
+		logger.Warn(fmt.Sprintf("model %s: write mesh cache: %v", m.Name, err))
+	}
+	return scene, nil
+}
+
+// processMaterials 为模型文件中的每个材质创建材质槽
+// 文件未定义材质时(assimp 生成的默认材质)使用 xml 中配置的材质
+func (m *Model) processMaterials(aScene *assimp.Scene) {
+	m.Materials = make([]*material.Material, 0, aScene.NumMaterials())
+	for _, aMaterial := range aScene.Materials() {
+		m.Materials = append(m.Materials, m.processMaterial(aMaterial))
+	}
+}
+
+// applyMaterialSlots 使用 xml 中的 materials 覆盖指定的材质槽
+func (m *Model) applyMaterialSlots() {
+	for _, xmlSlot := range m.xmlMaterials {
+		if xmlSlot.Slot < 0 || xmlSlot.Slot >= len(m.Materials) {
+			logger.Warn(fmt.Sprintf("model %s: material slot %d out of range", m.Name, xmlSlot.Slot))
+			continue
+		}
+		if mat, ok := m.sharedMaterial(xmlSlot.Ref); ok {
+			m.Materials[xmlSlot.Slot] = mat
+		} else {
+			name := xmlSlot.Name
+			if name == "" {
+				name = m.Materials[xmlSlot.Slot].Name
+			}
+			m.Materials[xmlSlot.Slot] = newMaterial(name, xmlSlot.XmlMaterial)
+		}
+		if inst := materialInstance(m.Materials[xmlSlot.Slot], xmlSlot.XmlMaterial); inst != nil {
+			m.setSlotInstance(xmlSlot.Slot, inst)
+		} else {
+			delete(m.SlotInstances, xmlSlot.Slot)
+		}
+	}
 }
 
-func (m *Model) initGL() {
+func (m *Model) processMaterial(aMaterial *assimp.Material) *material.Material {
+	// 非纹理属性使用 NONE 类型查询
+	none := assimp.TextureType(assimp.TextureMapping_None)
+
+	mat := *m.Material
+	// 纹理坐标动画按材质独立累加
+	mat.UV = m.Material.UV.Clone()
+	name, ret := aMaterial.GetMaterialString(assimp.MatKey_Name, none, 0)
+	if ret == assimp.Return_Success {
+		mat.Name = name
+	}
+	if ret != assimp.Return_Success || name == defaultMaterialName {
+		return &mat
+	}
+
+	if color, ret := aMaterial.GetMaterialColor(assimp.MatKey_ColorAmbient, none, 0); ret == assimp.Return_Success {
+		mat.AmbientColor = mgl32.Vec3{color.R(), color.G(), color.B()}
+	}
+	if color, ret := aMaterial.GetMaterialColor(assimp.MatKey_ColorDiffuse, none, 0); ret == assimp.Return_Success {
+		mat.DiffuseColor = mgl32.Vec3{color.R(), color.G(), color.B()}
+	}
+	if color, ret := aMaterial.GetMaterialColor(assimp.MatKey_ColorSpecular, none, 0); ret == assimp.Return_Success {
+		mat.SpecularColor = mgl32.Vec3{color.R(), color.G(), color.B()}
+	}
+	// 模型文件中的自发光颜色, 黑色表示不发光
+	if color, ret := aMaterial.GetMaterialColor(assimp.MatKey_ColorEmissive, none, 0); ret == assimp.Return_Success &&
+		(color.R() > 0 || color.G() > 0 || color.B() > 0) {
+		mat.EmissiveColor = mgl32.Vec3{color.R(), color.G(), color.B()}
+		mat.EmissiveIntensity = max(mat.EmissiveIntensity, 1)
+	}
+	if shininess, ret := aMaterial.GetMaterialFloat(assimp.MatKey_Shininess, none, 0); ret == assimp.Return_Success {
+		mat.Shininess = shininess
+	}
+	// 模型文件中不透明度小于1的材质使用 alpha 混合
+	if opacity, ret := aMaterial.GetMaterialFloat(assimp.MatKey_Opacity, none, 0); ret == assimp.Return_Success && opacity < 1 {
+		mat.Opacity = opacity
+		mat.BlendMode = material.BlendAlpha
+	}
+	return &mat
+}
+
+// loadDetailTextures 异步加载材质的细节贴图和自发光贴图, 路径相对于模型目录
+// 共享材质的贴图由材质注册表加载
+func (m *Model) loadDetailTextures(mat *material.Material) {
+	if isSharedMaterial(mat) {
+		return
+	}
+	loadMaterialTextures(m.BasePath, mat)
+}
+
+// loadParamTextures 异步加载材质参数中的贴图, 路径相对于模型目录, 加载完成前绑定为 0
+func (m *Model) loadParamTextures() {
+	for i := range m.Params {
+		p := &m.Params[i]
+		if p.Type != material.ParamTexture || p.TextureId != 0 {
+			continue
+		}
+		opts := texture.DefaultOptions()
+		if p.SRGB {
+			opts = texture.ColorOptions()
+		}
+		texture.NewTextureAsync(opts, filepath.Join(m.BasePath, p.Texture), func(tex uint32, err error) {
+			if err != nil {
+				logger.Error(err)
+				return
+			}
+			p.TextureId = tex
+		})
+	}
+}
+
+// collectFlipbooks 收集材质中不重复的序列帧并异步加载图集, 共享材质的序列帧由材质注册表推进
+func (m *Model) collectFlipbooks() {
+	m.flipbooks = m.flipbooks[:0]
+	for _, mat := range append([]*material.Material{m.Material}, m.Materials...) {
+		flipbook := mat.Flipbook
+		if flipbook == nil || slices.Contains(m.flipbooks, flipbook) || isSharedMaterial(mat) {
+			continue
+		}
+		m.flipbooks = append(m.flipbooks, flipbook)
+		loadFlipbookTexture(m.BasePath, flipbook)
+	}
+}
+
+// MeshMaterial 网格使用的材质, 有材质实例时为加上覆盖属性后的材质
+func (m *Model) MeshMaterial(mi *mesh.Mesh) *material.Material {
+	if mi.MaterialIndex >= 0 && mi.MaterialIndex < len(m.Materials) {
+		if inst := m.SlotInstances[mi.MaterialIndex]; inst != nil {
+			return inst.Material()
+		}
+		return m.Materials[mi.MaterialIndex]
+	}
+	if m.MaterialInstance != nil {
+		return m.MaterialInstance.Material()
+	}
+	return m.Material
+}
+
+func (m *Model) computeBounds() geometry.AABB {
+	bounds := geometry.NewAABB()
+	for _, mi := range m.Meshes {
+		mi.ComputeBounds()
+		bounds = bounds.Union(mi.Bounds)
+	}
+	return bounds
+}
+
+// WorldBounds 世界空间包围盒, 包含所有实例
+func (m *Model) WorldBounds() geometry.AABB {
+	if len(m.Instances) == 0 {
+		return m.Bounds.Transform(m.model)
+	}
+	bounds := geometry.NewAABB()
+	for _, instance := range m.Instances {
+		bounds = bounds.Union(m.Bounds.Transform(m.model.Mul4(instance)))
+	}
+	return bounds
+}
+
+// MeshWorldBounds 每个网格(每个实例)的世界空间包围盒
+func (m *Model) MeshWorldBounds() []geometry.AABB {
+	transforms := []mgl32.Mat4{m.model}
+	if len(m.Instances) > 0 {
+		transforms = transforms[:0]
+		for _, instance := range m.Instances {
+			transforms = append(transforms, m.model.Mul4(instance))
+		}
+	}
+	bounds := make([]geometry.AABB, 0, len(m.Meshes)*len(transforms))
+	for _, transform := range transforms {
+		for _, mi := range m.Meshes {
+			bounds = append(bounds, mi.Bounds.Transform(transform))
+		}
+	}
+	return bounds
+}
+
+// normalize 将顶点平移到包围盒中心, 并等比缩放使最长边为 size
+// 直接修改顶点数据, Position 和 Scale 仍可在此基础上调整
+func (m *Model) normalize(size float32) {
+	if m.Bounds.IsEmpty() {
+		return
+	}
+	center := m.Bounds.Center()
+	scale := float32(1.0)
+	if extent := m.Bounds.MaxExtent(); extent > 0 {
+		scale = size / extent
+	}
+
+	for _, mi := range m.Meshes {
+		for i := range mi.Vertices {
+			mi.Vertices[i].Position = mi.Vertices[i].Position.Sub(center).Mul(scale)
+		}
+	}
+	logger.Info(fmt.Sprintf("normalize model %s: center %v, scale %f", m.Name, center, scale))
+
+	m.Bounds = m.computeBounds()
+}
+
+// initGL 上传网格和贴图, textures 为 decodeTextures 解码的贴图
+// 贴图通过 resource 共享, 其他模型已经上传的贴图直接使用
+func (m *Model) initGL(textures decodedTextures) {
+
 	// using a for loop with a range doesnt work here?!
 	// also making a temp var inside the loop doesnt work either?!
 	for i := 0; i < len(m.Meshes); i++ {
 		for j := 0; j < len(m.Meshes[i].Textures); j++ {
-			if val, ok := m.texturesLoaded[m.Meshes[i].Textures[j].Path]; ok {
-				m.Meshes[i].Textures[j].Id = val.Id
-			} else {
-				m.Meshes[i].Textures[j].Id = m.textureFromFile(m.Meshes[i].Textures[j].Path)
-				m.texturesLoaded[m.Meshes[i].Textures[j].Path] = m.Meshes[i].Textures[j]
-			}
+			path := m.Meshes[i].Textures[j].Path
+			opts := texture.OptionsFor(m.Meshes[i].Textures[j].TextureType)
+			id, _ := resource.Textures.Load(path, func() (uint32, error) {
+				if img := textures.compressed[path]; img != nil {
+					return texture.NewTextureFromCompressed(opts, img), nil
+				}
+				rgba := textures.images[path]
+				if rgba == nil {
+					// decodeTextures 之后其他模型释放了这张贴图, 重新读取
+					id, err := texture.NewTexture(opts, path)
+					if err == nil {
+						return id, nil
+					}
+					logger.Error(err)
+					rgba = texture.ErrorImage()
+				}
+				return texture.NewTextureFromImage(opts, rgba), nil
+			})
+			m.Meshes[i].Textures[j].Id = id
+			m.textures = append(m.textures, path)
 		}
 		m.Meshes[i].Setup()
 	}
 }
 
-func (m *Model) processNode(aNode *assimp.Node, aScene *assimp.Scene) {
-	// Process each mesh located at the current node
-	m.wg.Add(aNode.NumMeshes() + aNode.NumChildren())
+// decodeTextures 使用任务系统并行解码网格引用的未加载贴图, 内嵌贴图已经解码, 直接使用
+func (m *Model) decodeTextures(embedded map[string]*image.RGBA) decodedTextures {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, mi := range m.Meshes {
+		for _, tex := range mi.Textures {
+			if _, ok := resource.Textures.Get(tex.Path); ok || seen[tex.Path] || embedded[tex.Path] != nil {
+				continue
+			}
+			seen[tex.Path] = true
+			paths = append(paths, tex.Path)
+		}
+	}
 
-	for i := 0; i < aNode.NumMeshes(); i++ {
-		// The node object only contains indices to index the actual objects in the scene.
-		// The scene contains all the data, node is just to keep stuff organized (like relations between nodes).
-		go func(index int) {
-			defer m.wg.Done()
-			mi := aScene.Meshes()[aNode.Meshes()[index]]
-			ms := m.processMesh(mi, aScene)
-			m.Meshes = append(m.Meshes, ms)
-		}(i)
+	decoded := make([]*image.RGBA, len(paths))
+	compressed := make([]*texture.CompressedImage, len(paths))
+	errs := make([]error, len(paths))
+	job.Wait(job.ParallelFor(len(paths), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			if texture.IsCompressed(paths[i]) {
+				compressed[i], errs[i] = texture.LoadCompressed(paths[i])
+			} else {
+				decoded[i], errs[i] = texture.ImageToPixelData(paths[i])
+			}
+		}
+	}))
 
+	textures := decodedTextures{
+		images:     make(map[string]*image.RGBA, len(paths)+len(embedded)),
+		compressed: make(map[string]*texture.CompressedImage),
 	}
+	for path, rgba := range embedded {
+		textures.images[path] = rgba
+	}
+	for i, path := range paths {
+		switch {
+		case errs[i] != nil:
+			// 缺失或损坏的贴图使用错误贴图, 不影响场景加载
+			logger.Error(errs[i])
+			textures.images[path] = texture.ErrorImage()
+		case compressed[i] != nil:
+			textures.compressed[path] = compressed[i]
+		default:
+			textures.images[path] = decoded[i]
+		}
+	}
+	return textures
+}
+
+func (m *Model) processNode(aNode *assimp.Node, aScene *assimp.Scene) {
+	// 先按节点树顺序收集网格, 再由任务系统并行解析, 网格顺序与文件一致
+	aMeshes := collectMeshes(aNode, aScene, nil)
+	m.Meshes = make([]*mesh.Mesh, len(aMeshes))
+	job.Wait(job.ParallelFor(len(aMeshes), func(begin, end int) {
+		for i := begin; i < end; i++ {
+			m.Meshes[i] = m.processMesh(aMeshes[i], aScene)
+		}
+	}))
+}
 
-	// After we've processed all the meshes (if any) we then recursively process each of the children nodes
-	c := aNode.Children()
-	for j := 0; j < len(c); j++ {
-		go func(n *assimp.Node, s *assimp.Scene) {
-			defer m.wg.Done()
-			m.processNode(n, s)
-		}(c[j], aScene)
+// collectMeshes 递归收集节点及其子节点引用的网格
+func collectMeshes(aNode *assimp.Node, aScene *assimp.Scene, aMeshes []*assimp.Mesh) []*assimp.Mesh {
+	// The node object only contains indices to index the actual objects in the scene.
+	// The scene contains all the data, node is just to keep stuff organized (like relations between nodes).
+	for _, index := range aNode.Meshes() {
+		aMeshes = append(aMeshes, aScene.Meshes()[index])
 	}
+	for _, child := range aNode.Children() {
+		aMeshes = collectMeshes(child, aScene, aMeshes)
+	}
+	return aMeshes
 }
 
 func (m *Model) processMesh(aMesh *assimp.Mesh, aScene *assimp.Scene) *mesh.Mesh {
 	// Return a mesh object created from the extracted mesh data
 
-	return mesh.NewMesh(
+	ms := mesh.NewMesh(
 		m.processMeshVertices(aMesh),
 		m.processMeshIndices(aMesh),
 		m.processMeshTextures(aMesh, aScene))
+	ms.MaterialIndex = aMesh.MaterialIndex()
+	return ms
 }
 
 func (m *Model) ProcessAssimpMesh(aMesh *assimp.Mesh) []mesh.Vertex {
@@ -182,6 +900,9 @@ func (m *Model) ProcessAssimpMesh(aMesh *assimp.Mesh) []mesh.Vertex {
 		useTex = false
 	}
 
+	tex2 := aMesh.TextureCoords(1)
+	useTex2 := len(tex2) > 0
+
 	tangents := aMesh.Tangents()
 	useTangents := len(tangents) > 0
 
@@ -212,6 +933,13 @@ func (m *Model) ProcessAssimpMesh(aMesh *assimp.Mesh) []mesh.Vertex {
 			vertex.TexCoords = mgl32.Vec2{0.0, 0.0}
 		}
 
+		// 第二套UV, 没有时与第一套相同
+		if useTex2 {
+			vertex.TexCoords2 = mgl32.Vec2{tex2[i].X(), tex2[i].Y()}
+		} else {
+			vertex.TexCoords2 = vertex.TexCoords
+		}
+
 		// Tangent
 		if useTangents {
 			vertex.Tangent = mgl32.Vec3{tangents[i].X(), tangents[i].Y(), tangents[i].Z()}
@@ -246,6 +974,9 @@ func (m *Model) processMeshVertices(aMesh *assimp.Mesh) []mesh.Vertex {
 		useTex = false
 	}
 
+	tex2 := aMesh.TextureCoords(1)
+	useTex2 := len(tex2) > 0
+
 	tangents := aMesh.Tangents()
 	useTangents := len(tangents) > 0
 
@@ -280,6 +1011,13 @@ func (m *Model) processMeshVertices(aMesh *assimp.Mesh) []mesh.Vertex {
 			vertex.TexCoords = mgl32.Vec2{0.0, 0.0}
 		}
 
+		// 第二套UV, 没有时与第一套相同
+		if useTex2 {
+			vertex.TexCoords2 = mgl32.Vec2{tex2[i].X(), tex2[i].Y()}
+		} else {
+			vertex.TexCoords2 = vertex.TexCoords
+		}
+
 		// Tangent
 		if useTangents {
 			vertex.Tangent = mgl32.Vec3{tangents[i].X(), tangents[i].Y(), tangents[i].Z()}
@@ -375,6 +1113,15 @@ func (m *Model) SetRotate(rotate float32) {
 }
 
 func (m *Model) Update(elapsed float64) {
+	for _, flipbook := range m.flipbooks {
+		flipbook.Update(elapsed)
+	}
+	// 共享材质由 UpdateMaterials 推进
+	for _, mat := range append([]*material.Material{m.Material}, m.Materials...) {
+		if mat.UV != nil && !isSharedMaterial(mat) {
+			mat.UV.Update(elapsed)
+		}
+	}
 	if m.geoInvalid {
 		m.model = mgl32.Translate3D(m.Position[0], m.Position[1], m.Position[2])
 		m.model = m.model.Mul4(mgl32.HomogRotate3D(m.Rotate, mgl32.Vec3{0, 1, 0}))
@@ -384,11 +1131,62 @@ func (m *Model) Update(elapsed float64) {
 	}
 }
 
+// SetDiffuseTexture 把所有网格的漫反射贴图替换为 path 指向的贴图, 没有漫反射贴图的网格加上这张贴图
+// 贴图通过 resource 共享, 模型释放时一起释放, 必须在主线程调用
+func (m *Model) SetDiffuseTexture(path string) error {
+	path = filepath.Clean(path)
+	id, err := resource.LoadTexture(path, texture.ColorOptions())
+	if err != nil {
+		return err
+	}
+	m.textures = append(m.textures, path)
+	tex := texture.Texture{Id: id, TextureType: texture.TextureDiffuse, Path: path}
+	for _, mi := range m.Meshes {
+		replaced := false
+		for i := range mi.Textures {
+			if mi.Textures[i].TextureType == texture.TextureDiffuse {
+				mi.Textures[i] = tex
+				replaced = true
+			}
+		}
+		if !replaced {
+			mi.Textures = append(mi.Textures, tex)
+		}
+	}
+	return nil
+}
+
+// SetInstances 设置实例变换矩阵并上传到每个网格的实例缓冲
+func (m *Model) SetInstances(transforms []mgl32.Mat4) {
+	m.Instances = transforms
+	for _, mi := range m.Meshes {
+		mi.SetInstances(transforms)
+	}
+}
+
+// AddInstance 追加一个实例
+func (m *Model) AddInstance(transform mgl32.Mat4) {
+	m.SetInstances(append(m.Instances, transform))
+}
+
 func (m *Model) PreRender() {
 	gl.PolygonMode(gl.FRONT, gl.LINE)
 }
 
 func (m *Model) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	if len(m.Instances) > 0 {
+		m.RenderInstanced(projection, model, view, eyePosition, lights)
+		return
+	}
+	m.render(projection, model, view, eyePosition, lights, false)
+}
+
+// RenderInstanced 使用 glDrawElementsInstanced 绘制所有实例
+func (m *Model) RenderInstanced(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	m.render(projection, model, view, eyePosition, lights, true)
+}
+
+func (m *Model) render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight, instanced bool) {
 	// RenderObj
 	model = model.Mul4(m.model)
 	mvp := projection.Mul4(view).Mul4(model)
@@ -402,27 +1200,87 @@ func (m *Model) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Ve
 	m.effect.SetEyeWorldPos(eyePosition)
 
 	m.effect.SetPointLight(lights)
-	m.effect.SetMaterial(m.Material)
+	m.effect.SetAmbientOcclusion(config.SSAOActive())
+	m.effect.SetFog(config.Config.Fog)
+	m.effect.SetInstanced(instanced)
+	m.setLightmap()
+	m.effect.SetParams(m.Params)
 
 	gl.BindFragDataLocation(m.effect.ShaderObj.Program, 0, gl.Str("color\x00"))
 
 	for _, mi := range m.Meshes {
-		mi.Draw(m.effect.ShaderObj.Program)
+		m.effect.SetMaterial(m.MeshMaterial(mi))
+		m.effect.SetDiffuseMap(mi.HasTexture(texture.TextureDiffuse))
+		m.drawMesh(mi, m.effect.ShaderObj.Program, instanced)
 	}
 	m.effect.Disable()
 }
 
-func (m *Model) PostRender() {
-	gl.PolygonMode(gl.FRONT, gl.LINE)
+func (m *Model) drawMesh(mi *mesh.Mesh, program uint32, instanced bool) {
+	if instanced {
+		mi.DrawInstanced(program)
+	} else {
+		mi.Draw(program)
+	}
 }
 
-func (m *Model) textureFromFile(f string) uint32 {
-	//Generate texture ID and load texture data
-	if tex, err := texture.NewTexture(gl.REPEAT, gl.REPEAT, gl.LINEAR_MIPMAP_LINEAR, gl.LINEAR, f); err != nil {
-		panic(err)
-	} else {
-		return tex
+func (m *Model) ModelMatrix() mgl32.Mat4 {
+	return m.model
+}
+
+// Transparent 是否有网格使用 alpha 混合材质
+func (m *Model) Transparent() bool {
+	for _, mi := range m.Meshes {
+		if m.MeshMaterial(mi).Transparent() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Model) GetMaterial() *material.Material {
+	return m.Material
+}
+
+// RenderGeometry 使用外部technique绘制几何体, 投影和视图矩阵由调用方设置
+func (m *Model) RenderGeometry(t *technique.BaseTechnique) {
+	m.RenderSubmeshes(t, nil)
+}
+
+// RenderSubmeshes 与 RenderGeometry 相同, 每个网格绘制前以网格的材质调用 before, before 可为空
+func (m *Model) RenderSubmeshes(t *technique.BaseTechnique, before func(mat *material.Material)) {
+	instanced := len(m.Instances) > 0
+	t.SetModelMatrix(&m.model)
+	t.SetInstanced(instanced)
+	for _, mi := range m.Meshes {
+		if before != nil {
+			before(m.MeshMaterial(mi))
+		}
+		m.drawMesh(mi, t.ShaderObj.Program, instanced)
+	}
+	t.SetInstanced(false)
+}
+
+// Shader 模型使用的着色器, 与使用相同源文件和宏的模型共享
+func (m *Model) Shader() *shader.Shader {
+	return m.shader
+}
+
+// Passes 模型的绘制阶段, 没有声明时为 technique.DefaultPasses
+func (m *Model) Passes() []technique.Pass {
+	if len(m.passes) == 0 {
+		return technique.DefaultPasses()
 	}
+	return m.passes
+}
+
+// SetPasses 替换模型的绘制阶段, 例如加入 technique.DepthPrepass() 或使用自定义着色器的主 pass
+func (m *Model) SetPasses(passes []technique.Pass) {
+	m.passes = passes
+}
+
+func (m *Model) PostRender() {
+	gl.PolygonMode(gl.FRONT, gl.LINE)
 }
 
 // RenderObj 可渲染對象
@@ -432,3 +1290,28 @@ type RenderObj interface {
 	PreRender()
 	PostRender()
 }
+
+// BoundedObj 有包围盒的对象, 可参与视锥剔除
+type BoundedObj interface {
+	WorldBounds() geometry.AABB
+}
+
+// TransformObj 可获取模型矩阵的对象, 用于记录上一帧的变换
+type TransformObj interface {
+	ModelMatrix() mgl32.Mat4
+}
+
+// TransparentObj 需要 alpha 混合的对象, 在不透明物体之后由远及近绘制
+type TransparentObj interface {
+	Transparent() bool
+}
+
+// GeometryObj 可输出几何信息的对象, 用于深度/法线等预渲染pass
+type GeometryObj interface {
+	RenderGeometry(t *technique.BaseTechnique)
+}
+
+// SubmeshObj 网格使用不同材质的对象, 使用外部technique按网格绘制, 每个网格绘制前以它的材质调用 before
+type SubmeshObj interface {
+	RenderSubmeshes(t *technique.BaseTechnique, before func(mat *material.Material))
+}
@@ -0,0 +1,48 @@
+package model
+
+import "github.com/huangxiaobo/toy-engine/engine/config"
+
+// OrderedObj 指定绘制顺序的对象, 没有实现的对象顺序为 0
+// 同一组(不透明, 透明, 最上层)内 RenderOrder 小的先绘制, 相同时保持加入顺序
+type OrderedObj interface {
+	RenderOrder() int
+	// AlwaysOnTop 在所有对象之后关闭深度测试绘制, 用于辅助对象, 不参与遮挡查询
+	AlwaysOnTop() bool
+}
+
+// RenderPriority 嵌入到渲染对象中实现 OrderedObj
+type RenderPriority struct {
+	Order int
+	OnTop bool
+}
+
+func NewRenderPriority(xmlModel config.XmlModel) RenderPriority {
+	return RenderPriority{Order: xmlModel.RenderOrder, OnTop: xmlModel.AlwaysOnTop}
+}
+
+func (p RenderPriority) RenderOrder() int {
+	return p.Order
+}
+
+func (p RenderPriority) AlwaysOnTop() bool {
+	return p.OnTop
+}
+
+// Priority 用于界面编辑
+func (p *RenderPriority) Priority() *RenderPriority {
+	return p
+}
+
+// RenderOrderOf 对象的绘制顺序
+func RenderOrderOf(obj RenderObj) int {
+	if orderedObj, ok := obj.(OrderedObj); ok {
+		return orderedObj.RenderOrder()
+	}
+	return 0
+}
+
+// AlwaysOnTop 对象是否绘制在最上层
+func AlwaysOnTop(obj RenderObj) bool {
+	orderedObj, ok := obj.(OrderedObj)
+	return ok && orderedObj.AlwaysOnTop()
+}
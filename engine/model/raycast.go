@@ -0,0 +1,57 @@
+package model
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+)
+
+// RayHit 射线与模型的交点
+type RayHit struct {
+	Distance  float32    // 世界空间射线参数
+	Position  mgl32.Vec3 // 世界空间交点
+	Normal    mgl32.Vec3 // 模型空间插值法线
+	TexCoords mgl32.Vec2 // 插值UV
+	Mesh      *mesh.Mesh
+	Triangle  int // 三角形在 Mesh.Indices 中的序号
+}
+
+// IntersectRay 求世界空间射线与模型三角形最近的交点, 实例化模型只检测基础变换
+func (m *Model) IntersectRay(ray geometry.Ray) (RayHit, bool) {
+	localRay := ray.Transform(m.model.Inv())
+	if _, ok := localRay.IntersectAABB(m.Bounds); !ok {
+		return RayHit{}, false
+	}
+
+	var hit RayHit
+	found := false
+	for _, mi := range m.Meshes {
+		if mi.DrawMode != gl.TRIANGLES {
+			continue
+		}
+		if _, ok := localRay.IntersectAABB(mi.Bounds); !ok {
+			continue
+		}
+		for tri := 0; tri+2 < len(mi.Indices); tri += 3 {
+			v0 := mi.Vertices[mi.Indices[tri]]
+			v1 := mi.Vertices[mi.Indices[tri+1]]
+			v2 := mi.Vertices[mi.Indices[tri+2]]
+			t, u, v, ok := localRay.IntersectTriangle(v0.Position, v1.Position, v2.Position)
+			if !ok || (found && t >= hit.Distance) {
+				continue
+			}
+			w := 1 - u - v
+			found = true
+			hit = RayHit{
+				Distance:  t,
+				Position:  ray.At(t),
+				Normal:    v0.Normal.Mul(w).Add(v1.Normal.Mul(u)).Add(v2.Normal.Mul(v)).Normalize(),
+				TexCoords: v0.TexCoords.Mul(w).Add(v1.TexCoords.Mul(u)).Add(v2.TexCoords.Mul(v)),
+				Mesh:      mi,
+				Triangle:  tri / 3,
+			}
+		}
+	}
+	return hit, found
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+)
+
+// reflectionClipOffset 裁剪平面略低于反射平面, 避免贴近平面的物体出现缝隙
+const reflectionClipOffset = 0.01
+
+// ReflectiveObj 在主视角之前需要绘制反射或折射纹理的对象, draw 按给定相机绘制场景
+type ReflectiveObj interface {
+	RenderReflection(width, height int32, projection, view mgl32.Mat4, eyePosition mgl32.Vec3,
+		draw func(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3)) error
+}
+
+// PlanarReflection 平面反射, 用关于平面镜像的相机把场景渲染到纹理, 再由平面着色器按屏幕坐标采样
+type PlanarReflection struct {
+	Enable     bool
+	Strength   float32
+	Blur       float32
+	Fresnel    float32
+	Resolution float32
+
+	fb *framebuffer.FrameBuffer
+
+	lastViewport [4]int32
+	lastFbo      int32
+}
+
+func NewPlanarReflection(xmlReflection *config.XmlReflection) *PlanarReflection {
+	r := &PlanarReflection{
+		Strength:   0.5,
+		Blur:       1,
+		Fresnel:    1,
+		Resolution: 0.5,
+	}
+	if xmlReflection == nil {
+		return r
+	}
+	r.Enable = xmlReflection.Enable
+	if xmlReflection.Strength > 0 {
+		r.Strength = xmlReflection.Strength
+	}
+	if xmlReflection.Blur > 0 {
+		r.Blur = xmlReflection.Blur
+	}
+	r.Fresnel = xmlReflection.Fresnel
+	if xmlReflection.Resolution > 0 {
+		r.Resolution = xmlReflection.Resolution
+	}
+	return r
+}
+
+// Begin 绑定反射纹理, 返回镜像相机的投影矩阵, 观察矩阵和位置, 平面为世界空间中 y = height 的水平面
+// 投影矩阵的近裁剪面替换为反射平面, 平面以下的物体不会出现在反射中
+func (r *PlanarReflection) Begin(width, height int32, projection, view mgl32.Mat4, eye mgl32.Vec3, planeHeight float32) (mgl32.Mat4, mgl32.Mat4, mgl32.Vec3, error) {
+	width = max(int32(float32(width)*r.Resolution), 1)
+	height = max(int32(float32(height)*r.Resolution), 1)
+	if r.fb == nil {
+		fb, err := framebuffer.NewFrameBuffer(width, height, true, framebuffer.RGBA8)
+		if err != nil {
+			return projection, view, eye, err
+		}
+		r.fb = fb
+	} else if r.fb.Width != width || r.fb.Height != height {
+		if err := r.fb.Resize(width, height); err != nil {
+			return projection, view, eye, err
+		}
+	}
+
+	mirror := mgl32.Mat4{
+		1, 0, 0, 0,
+		0, -1, 0, 0,
+		0, 0, 1, 0,
+		0, 2 * planeHeight, 0, 1,
+	}
+	mirrorView := view.Mul4(mirror)
+	mirrorEye := mgl32.Vec3{eye.X(), 2*planeHeight - eye.Y(), eye.Z()}
+
+	// 平面变换到观察空间: 乘以观察矩阵的逆转置
+	plane := mgl32.Vec4{0, 1, 0, -(planeHeight - reflectionClipOffset)}
+	viewPlane := mirrorView.Inv().Transpose().Mul4x1(plane)
+	mirrorProjection := obliqueProjection(projection, viewPlane)
+
+	gl.GetIntegerv(gl.VIEWPORT, &r.lastViewport[0])
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &r.lastFbo)
+	r.fb.Bind()
+	clearColor := config.BackgroundColor()
+	gl.ClearColor(clearColor[0], clearColor[1], clearColor[2], 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	// 镜像变换翻转了三角形的环绕方向
+	gl.FrontFace(gl.CW)
+
+	return mirrorProjection, mirrorView, mirrorEye, nil
+}
+
+// End 恢复 Begin 之前的渲染目标
+func (r *PlanarReflection) End() {
+	gl.FrontFace(gl.CCW)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(r.lastFbo))
+	gl.Viewport(r.lastViewport[0], r.lastViewport[1], r.lastViewport[2], r.lastViewport[3])
+}
+
+// Texture 反射纹理, 尚未渲染时为 0
+func (r *PlanarReflection) Texture() uint32 {
+	if r.fb == nil {
+		return 0
+	}
+	return r.fb.ColorTexture(0)
+}
+
+// TexelSize 反射纹理的像素大小
+func (r *PlanarReflection) TexelSize() mgl32.Vec2 {
+	if r.fb == nil {
+		return mgl32.Vec2{}
+	}
+	return mgl32.Vec2{1 / float32(r.fb.Width), 1 / float32(r.fb.Height)}
+}
+
+func (r *PlanarReflection) Dispose() {
+	if r.fb != nil {
+		r.fb.Dispose()
+		r.fb = nil
+	}
+}
+
+// obliqueProjection 修改投影矩阵使近裁剪面与观察空间中的平面 clipPlane 重合 (Lengyel 斜裁剪)
+func obliqueProjection(projection mgl32.Mat4, clipPlane mgl32.Vec4) mgl32.Mat4 {
+	q := projection.Inv().Mul4x1(mgl32.Vec4{sign(clipPlane.X()), sign(clipPlane.Y()), 1, 1})
+	c := clipPlane.Mul(2 / clipPlane.Dot(q))
+	// 第三行替换为 c - 第四行
+	for col := 0; col < 4; col++ {
+		projection.Set(2, col, c[col]-projection.At(3, col))
+	}
+	return projection
+}
+
+func sign(v float32) float32 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/loader"
+	"github.com/huangxiaobo/toy-engine/engine/resource"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// SourcesChanged 模型文件或它引用的文件在上次检查后是否修改过, 同时记录新的修改时间
+// 只检查磁盘上的文件
+func (m *Model) SourcesChanged() bool {
+	changed := false
+	for file, modTime := range m.sources {
+		if t, _ := vfs.ModTime(file); !t.Equal(modTime) {
+			m.sources[file] = t
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Reload 重新读取模型文件, 在原地替换网格, 材质和 GL 缓冲, 必须在主线程调用
+// 位置, 缩放, 旋转和实例保持不变, 失败时保留原来的模型
+func (m *Model) Reload() error {
+	if m.xmlModel != nil {
+		return m.ReloadXml(*m.xmlModel)
+	}
+	return m.reload(*newModelFromFile(filepath.Join(m.BasePath, m.FileName), m.NormalizeSize))
+}
+
+// ReloadXml 按新的 xml 描述重新创建模型, 其他同 Reload
+func (m *Model) ReloadXml(xmlModel config.XmlModel) error {
+	return m.reload(newModel(xmlModel))
+}
+
+func (m *Model) reload(fresh Model) error {
+	if len(fresh.FileName) == 0 {
+		return fmt.Errorf("model %s has no mesh file", m.Name)
+	}
+	// 导入结果可能被其他模型共享, 替换共享的结果, 其他模型使用的是副本, 不受影响
+	path := filepath.Join(fresh.BasePath, fresh.FileName)
+	err := resource.Meshes.Reload(loader.Key(path, fresh.Material), func() (*loader.Scene, error) {
+		return fresh.importScene(path)
+	})
+	if err != nil {
+		return err
+	}
+	images, err := fresh.prepare()
+	if err != nil {
+		fresh.Dispose()
+		return err
+	}
+	fresh.upload(images)
+	if err := fresh.initShader(); err != nil {
+		fresh.Dispose()
+		return err
+	}
+
+	// 选中状态和场景中的引用指向同一个 Model, 原地替换后仍然有效
+	fresh.Id = m.Id
+	fresh.Name = m.Name
+	fresh.Position = m.Position
+	fresh.Scale = m.Scale
+	fresh.Rotate = m.Rotate
+	fresh.geoInvalid = true
+	if len(m.Instances) > 0 {
+		fresh.SetInstances(m.Instances)
+	}
+	old := *m
+	*m = fresh
+	old.Dispose()
+	return nil
+}
@@ -0,0 +1,588 @@
+package model
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/impostor"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+const (
+	defaultVegetationDensity = 4
+	// 散布的实例按格子分组, 按格子做距离和视锥剔除
+	vegetationCellSize = 8
+	// 实例数上限, 避免密度设置过大时卡死
+	maxVegetationInstances  = 200000
+	defaultImpostorDistance = 30
+	defaultImpostorBlend    = 5
+)
+
+// HeightFunc 世界坐标 (x, z) 处的地表高度, 没有地表时返回 false
+type HeightFunc func(x, z float32) (float32, bool)
+
+// VegetationStats 散布和最近一次绘制的实例数
+type VegetationStats struct {
+	Instances int
+	Visible   int
+	Impostors int
+}
+
+type vegetationCell struct {
+	bounds    geometry.AABB
+	instances []mgl32.Mat4
+}
+
+// Vegetation 按密度图在地面或地形上散布的实例化草和植物
+// 顶点着色器按风向摆动, 超出淡出距离的实例按屏幕抖动逐渐镂空, 不需要排序
+// 开启替身时, 超出替身距离的实例使用从多个视角烘焙的四边形绘制, 两者在过渡距离内按抖动交替
+type Vegetation struct {
+	Name string
+	Id   string
+
+	RenderPriority
+
+	// 散布区域中心和半宽
+	Position mgl32.Vec3
+	Size     float32
+	// 每平方单位的实例数, 密度图按比例减少
+	Density float32
+	Seed    int64
+	// 每个实例随机缩放的范围
+	ScaleMin float32
+	ScaleMax float32
+
+	// 与顶点颜色和贴图相乘
+	Color       mgl32.Vec3
+	AlphaCutoff float32
+
+	// 风向使用 x 和 y 表示世界空间的 x 和 z
+	WindDirection mgl32.Vec2
+	WindStrength  float32
+	WindSpeed     float32
+	WindFrequency float32
+
+	// 开始淡出和完全消失的距离, 超出 FadeEnd 的格子不绘制
+	FadeStart float32
+	FadeEnd   float32
+
+	// 超出 ImpostorDistance 的实例完全使用替身, 之前 ImpostorBlend 的距离内逐渐过渡
+	Impostor           bool
+	ImpostorDistance   float32
+	ImpostorBlend      float32
+	ImpostorFrames     int
+	ImpostorResolution int32
+
+	Stats VegetationStats
+
+	densityMap *image.RGBA
+	heightAt   HeightFunc
+	cells      []vegetationCell
+	visible    []mgl32.Mat4
+	impostors  []mgl32.Mat4
+
+	meshes []*mesh.Mesh
+	// 内置草丛网格使用顶点颜色, 模型文件的网格只使用 Color
+	vertexColor bool
+	// 网格的模型空间包围盒, 用于计算格子的包围盒和摆动的高度
+	meshBounds geometry.AABB
+	model      *Model
+
+	effect *technique.LightingTechnique
+	shader *shader.Shader
+	time   float32
+
+	// 替身图集在贴图加载完成或烘焙参数修改后重新烘焙
+	atlas          *impostor.Atlas
+	atlasKey       impostorKey
+	quad           *mesh.Mesh
+	impostorEffect *technique.LightingTechnique
+	impostorShader *shader.Shader
+}
+
+// impostorKey 影响烘焙结果的参数
+type impostorKey struct {
+	textures    int
+	alphaCutoff float32
+	frames      int
+	resolution  int32
+}
+
+func NewVegetation(xmlModel config.XmlModel) (*Vegetation, error) {
+	basePath := vfs.Path("model", xmlModel.Name)
+	v := &Vegetation{
+		Name:               xmlModel.Name,
+		Id:                 xmlModel.Id,
+		RenderPriority:     NewRenderPriority(xmlModel),
+		Position:           xmlModel.Position.XYZ(),
+		Size:               mesh.GroundHalfWidth,
+		Density:            defaultVegetationDensity,
+		ScaleMin:           0.6,
+		ScaleMax:           1.2,
+		Color:              mgl32.Vec3{1, 1, 1},
+		AlphaCutoff:        0.5,
+		WindDirection:      mgl32.Vec2{1, 0.3},
+		WindStrength:       0.2,
+		WindSpeed:          2,
+		WindFrequency:      0.15,
+		FadeStart:          30,
+		FadeEnd:            40,
+		ImpostorDistance:   defaultImpostorDistance,
+		ImpostorBlend:      defaultImpostorBlend,
+		ImpostorFrames:     impostor.DefaultFrames,
+		ImpostorResolution: impostor.DefaultResolution,
+		effect:             &technique.LightingTechnique{},
+		shader: &shader.Shader{
+			VertFilePath: "./resource/shader/vegetation.vert",
+			FragFilePath: "./resource/shader/vegetation.frag",
+		},
+		impostorEffect: &technique.LightingTechnique{},
+		impostorShader: &shader.Shader{
+			VertFilePath: "./resource/shader/impostor.vert",
+			FragFilePath: "./resource/shader/impostor.frag",
+		},
+	}
+
+	textureFile := ""
+	if xmlVegetation := xmlModel.Vegetation; xmlVegetation != nil {
+		if xmlVegetation.Size > 0 {
+			v.Size = xmlVegetation.Size
+		}
+		if xmlVegetation.Density > 0 {
+			v.Density = xmlVegetation.Density
+		}
+		v.Seed = xmlVegetation.Seed
+		if scale := xmlVegetation.ScaleRange; scale.Max > 0 {
+			v.ScaleMin, v.ScaleMax = scale.Min, max(scale.Max, scale.Min)
+		}
+		if xmlVegetation.Color != nil {
+			v.Color = xmlVegetation.Color.RGB()
+		}
+		if xmlVegetation.AlphaCutoff > 0 {
+			v.AlphaCutoff = xmlVegetation.AlphaCutoff
+		}
+		if wind := xmlVegetation.Wind; wind != nil {
+			if direction := wind.Direction.XYZ(); direction.Len() > 0 {
+				v.WindDirection = mgl32.Vec2{direction.X(), direction.Z()}
+			}
+			v.WindStrength = wind.Strength
+			v.WindSpeed = wind.Speed
+			if wind.Frequency > 0 {
+				v.WindFrequency = wind.Frequency
+			}
+		}
+		if fade := xmlVegetation.Fade; fade != nil && fade.Max > 0 {
+			v.FadeStart, v.FadeEnd = fade.Min, max(fade.Max, fade.Min)
+		}
+		if xmlImpostor := xmlVegetation.Impostor; xmlImpostor != nil {
+			v.Impostor = true
+			if xmlImpostor.Distance > 0 {
+				v.ImpostorDistance = xmlImpostor.Distance
+			}
+			if xmlImpostor.Blend > 0 {
+				v.ImpostorBlend = xmlImpostor.Blend
+			}
+			if xmlImpostor.Frames > 0 {
+				v.ImpostorFrames = min(xmlImpostor.Frames, impostor.MaxFrames)
+			}
+			if xmlImpostor.Resolution > 0 {
+				v.ImpostorResolution = xmlImpostor.Resolution
+			}
+		}
+		if xmlVegetation.DensityMap != "" {
+			densityMap, err := texture.ImageToPixelData(filepath.Join(basePath, xmlVegetation.DensityMap))
+			if err != nil {
+				logger.Error(err)
+			} else {
+				v.densityMap = densityMap
+			}
+		}
+		if xmlVegetation.Texture != "" {
+			textureFile = filepath.Join(basePath, xmlVegetation.Texture)
+		}
+	}
+
+	if err := v.shader.Init(); err != nil {
+		return nil, err
+	}
+	v.effect.Init(v.shader)
+	if err := v.impostorShader.Init(); err != nil {
+		return nil, err
+	}
+	v.impostorEffect.Init(v.impostorShader)
+	v.quad = impostor.NewQuad()
+
+	if err := v.loadMeshes(xmlModel); err != nil {
+		return nil, err
+	}
+
+	if textureFile != "" {
+		texture.NewTextureAsync(texture.ColorOptions(), textureFile,
+			func(id uint32, err error) {
+				if err != nil {
+					logger.Error(err)
+					return
+				}
+				for _, mi := range v.meshes {
+					mi.Textures = append(mi.Textures, texture.Texture{Id: id, TextureType: texture.TextureDiffuse, Path: textureFile})
+				}
+			})
+	}
+	return v, nil
+}
+
+// loadMeshes 读取模型文件中的网格, 没有配置网格时使用内置的草丛
+func (v *Vegetation) loadMeshes(xmlModel config.XmlModel) error {
+	if xmlModel.Mesh.File == "" {
+		grass := mesh.NewMeshGrass()
+		v.meshes = []*mesh.Mesh{grass}
+		v.meshBounds = grass.Bounds
+		v.vertexColor = true
+		return nil
+	}
+
+	m := &Model{
+		BasePath: vfs.Path("model", xmlModel.Name),
+		FileName: xmlModel.Mesh.File,
+		Name:     xmlModel.Name,
+		Material: newMaterial(xmlModel.Name, xmlModel.Material),
+		model:    mgl32.Ident4(),
+	}
+	if xmlModel.Normalize != nil {
+		m.NormalizeSize = xmlModel.Normalize.Size
+	}
+	if err := m.loadModel(); err != nil {
+		return err
+	}
+	if len(m.Meshes) == 0 {
+		return fmt.Errorf("vegetation %s: no meshes in %s", v.Name, m.FileName)
+	}
+	v.model = m
+	v.meshes = m.Meshes
+	v.meshBounds = m.Bounds
+	return nil
+}
+
+func (v *Vegetation) Dispose() {
+	if v.model != nil {
+		v.model.Dispose()
+	} else {
+		for _, mi := range v.meshes {
+			mi.Dispose()
+		}
+	}
+	if v.atlas != nil {
+		v.atlas.Dispose()
+	}
+	v.quad.Dispose()
+	v.shader.Dispose()
+	v.impostorShader.Dispose()
+}
+
+// Scatter 按密度图在区域内重新散布实例, heightAt 为空时所有实例放在 Position 的高度
+// 没有地表的位置不放置实例
+func (v *Vegetation) Scatter(heightAt HeightFunc) {
+	v.heightAt = heightAt
+	rnd := rand.New(rand.NewSource(v.Seed))
+
+	// 抖动网格, 每个格子一个候选点, 按密度图的概率保留
+	spacing := 1 / float32(math.Sqrt(float64(max(v.Density, 0.0001))))
+	count := int(math.Ceil(float64(2 * v.Size / spacing)))
+	if count*count > maxVegetationInstances {
+		count = int(math.Sqrt(maxVegetationInstances))
+		spacing = 2 * v.Size / float32(count)
+		logger.Warn(fmt.Sprintf("vegetation %s: density clamped to %d instances", v.Name, maxVegetationInstances))
+	}
+
+	cellCount := max(int(math.Ceil(float64(2*v.Size/vegetationCellSize))), 1)
+	v.cells = make([]vegetationCell, cellCount*cellCount)
+	for i := range v.cells {
+		v.cells[i].bounds = geometry.NewAABB()
+	}
+
+	origin := mgl32.Vec2{v.Position.X() - v.Size, v.Position.Z() - v.Size}
+	v.Stats.Instances = 0
+	for row := 0; row < count; row++ {
+		for col := 0; col < count; col++ {
+			local := mgl32.Vec2{(float32(col) + rnd.Float32()) * spacing, (float32(row) + rnd.Float32()) * spacing}
+			// 无论是否保留都消耗相同数量的随机数, 修改密度图不会打乱其他位置的实例
+			keep, yaw, scaleT := rnd.Float32(), rnd.Float32(), rnd.Float32()
+			if local.X() >= 2*v.Size || local.Y() >= 2*v.Size || keep >= v.density(local) {
+				continue
+			}
+
+			x, z := origin.X()+local.X(), origin.Y()+local.Y()
+			y := v.Position.Y()
+			if heightAt != nil {
+				h, ok := heightAt(x, z)
+				if !ok {
+					continue
+				}
+				y = h
+			}
+
+			scale := v.ScaleMin + (v.ScaleMax-v.ScaleMin)*scaleT
+			transform := mgl32.Translate3D(x, y, z).
+				Mul4(mgl32.HomogRotate3DY(yaw * 2 * math.Pi)).
+				Mul4(mgl32.Scale3D(scale, scale, scale))
+
+			cx := min(int(local.X()/vegetationCellSize), cellCount-1)
+			cz := min(int(local.Y()/vegetationCellSize), cellCount-1)
+			cell := &v.cells[cz*cellCount+cx]
+			cell.instances = append(cell.instances, transform)
+			// 摆动的位移不超过网格高度, 包围盒在水平方向按高度放大
+			bounds := v.meshBounds.Transform(transform)
+			sway := mgl32.Vec3{1, 0, 1}.Mul(bounds.Size().Y())
+			cell.bounds.Extend(bounds.Min.Sub(sway))
+			cell.bounds.Extend(bounds.Max.Add(sway))
+			v.Stats.Instances++
+		}
+	}
+}
+
+// Rescatter 使用上次的高度函数重新散布, 用于修改密度或雕刻地形之后
+func (v *Vegetation) Rescatter() {
+	v.Scatter(v.heightAt)
+}
+
+// density 区域内局部坐标处的密度图取值, 没有密度图时为 1
+func (v *Vegetation) density(local mgl32.Vec2) float32 {
+	if v.densityMap == nil {
+		return 1
+	}
+	size := v.densityMap.Rect.Size()
+	px := min(int(local.X()/(2*v.Size)*float32(size.X)), size.X-1)
+	py := min(int(local.Y()/(2*v.Size)*float32(size.Y)), size.Y-1)
+	c := v.densityMap.RGBAAt(v.densityMap.Rect.Min.X+px, v.densityMap.Rect.Min.Y+py)
+	return (float32(c.R) + float32(c.G) + float32(c.B)) / (3 * 255)
+}
+
+// WorldBounds 所有实例的包围盒
+func (v *Vegetation) WorldBounds() geometry.AABB {
+	bounds := geometry.NewAABB()
+	for _, cell := range v.cells {
+		if len(cell.instances) > 0 {
+			bounds = bounds.Union(cell.bounds)
+		}
+	}
+	if bounds.IsEmpty() {
+		return geometry.AABB{Min: v.Position, Max: v.Position}
+	}
+	return bounds
+}
+
+// selectInstances 收集淡出距离内且在视锥内的格子中的实例
+// 开启替身时按格子到相机的距离分到网格和替身, 跨过过渡距离的格子两边都绘制, 由着色器按实例的距离选择
+func (v *Vegetation) selectInstances(eye mgl32.Vec3, frustum *geometry.Frustum) {
+	v.visible = v.visible[:0]
+	v.impostors = v.impostors[:0]
+	meshEnd := v.FadeEnd
+	if v.Impostor {
+		meshEnd = min(v.ImpostorDistance, v.FadeEnd)
+	}
+	for _, cell := range v.cells {
+		if len(cell.instances) == 0 {
+			continue
+		}
+		near := distanceToBounds(eye, cell.bounds)
+		if near > v.FadeEnd {
+			continue
+		}
+		if frustum != nil && !frustum.IntersectsAABB(cell.bounds) {
+			continue
+		}
+		if near <= meshEnd {
+			v.visible = append(v.visible, cell.instances...)
+		}
+		if v.Impostor && near+cell.bounds.Size().Len() > v.impostorStart() {
+			v.impostors = append(v.impostors, cell.instances...)
+		}
+	}
+	v.Stats.Visible = len(v.visible)
+	v.Stats.Impostors = len(v.impostors)
+}
+
+// impostorStart 开始从网格过渡到替身的距离
+func (v *Vegetation) impostorStart() float32 {
+	return max(v.ImpostorDistance-v.ImpostorBlend, 0)
+}
+
+// updateAtlas 烘焙参数或网格贴图变化后重新烘焙替身图集, 失败时关闭替身
+func (v *Vegetation) updateAtlas() {
+	key := impostorKey{
+		alphaCutoff: v.AlphaCutoff,
+		frames:      min(max(v.ImpostorFrames, 1), impostor.MaxFrames),
+		resolution:  min(max(v.ImpostorResolution, 16), 1024),
+	}
+	for _, mi := range v.meshes {
+		key.textures += len(mi.Textures)
+	}
+	if v.atlas != nil && key == v.atlasKey {
+		return
+	}
+
+	atlas, err := impostor.Bake(v.meshes, v.meshBounds, impostor.Options{
+		Frames:      key.frames,
+		Resolution:  key.resolution,
+		VertexColor: v.vertexColor,
+		AlphaCutoff: key.alphaCutoff,
+	})
+	if err != nil {
+		logger.Error(fmt.Errorf("vegetation %s: bake impostor: %w", v.Name, err))
+		v.Impostor = false
+		return
+	}
+	if v.atlas != nil {
+		v.atlas.Dispose()
+	}
+	v.atlas, v.atlasKey = atlas, key
+}
+
+// distanceToBounds 点到包围盒的最近距离, 点在盒内时为 0
+func distanceToBounds(p mgl32.Vec3, b geometry.AABB) float32 {
+	var d mgl32.Vec3
+	for i := 0; i < 3; i++ {
+		d[i] = max(b.Min[i]-p[i], 0, p[i]-b.Max[i])
+	}
+	return d.Len()
+}
+
+func (v *Vegetation) SetPosition(p mgl32.Vec3) {
+	v.Position = p
+}
+
+func (v *Vegetation) Update(elapsed float64) {
+	v.time += float32(elapsed)
+}
+
+func (v *Vegetation) PreRender() {
+}
+
+func (v *Vegetation) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	var frustum *geometry.Frustum
+	if config.Config.FrustumCulling {
+		f := geometry.NewFrustum(projection.Mul4(view))
+		frustum = &f
+	}
+	v.selectInstances(*eyePosition, frustum)
+	if v.Impostor && len(v.impostors) > 0 {
+		v.updateAtlas()
+		v.renderImpostors(projection, model, view, eyePosition, lights)
+	}
+	if len(v.visible) == 0 {
+		return
+	}
+
+	mvp := projection.Mul4(view).Mul4(model)
+	v.effect.Enable()
+	v.effect.SetProjectMatrix(&projection)
+	v.effect.SetViewMatrix(&view)
+	v.effect.SetModelMatrix(&model)
+	v.effect.SetWVP(&mvp)
+	v.effect.SetEyeWorldPos(eyePosition)
+	v.effect.SetPointLight(lights)
+	v.effect.SetFog(config.Config.Fog)
+
+	windDirection := v.WindDirection
+	if windDirection.Len() > 0 {
+		windDirection = windDirection.Normalize()
+	}
+	v.shader.SetUniform("gTime", v.time)
+	v.shader.SetUniform("gWindDirection", windDirection)
+	v.shader.SetUniform("gWindStrength", v.WindStrength)
+	v.shader.SetUniform("gWindSpeed", v.WindSpeed)
+	v.shader.SetUniform("gWindFrequency", v.WindFrequency)
+	v.shader.SetUniform("gMeshHeight", max(v.meshBounds.Max.Y(), 0.0001))
+	// 开启替身时网格在过渡距离内淡出, 由替身接替
+	fadeStart, fadeEnd := v.FadeStart, v.FadeEnd
+	if v.Impostor && v.ImpostorDistance < v.FadeEnd {
+		fadeStart, fadeEnd = v.impostorStart(), v.ImpostorDistance
+	}
+	v.shader.SetUniform("gFadeStart", fadeStart)
+	v.shader.SetUniform("gFadeEnd", max(fadeEnd, fadeStart+0.0001))
+	v.shader.SetUniform("gColor", v.Color)
+	v.shader.SetUniform("gAlphaCutoff", v.AlphaCutoff)
+	v.shader.SetUniform("gVertexColor", v.vertexColor)
+
+	// 草片两面可见
+	cullFace := gl.IsEnabled(gl.CULL_FACE)
+	gl.Disable(gl.CULL_FACE)
+	program := v.effect.ShaderObj.Program
+	gl.BindFragDataLocation(program, 0, gl.Str("color\x00"))
+	for _, mi := range v.meshes {
+		mi.SetInstances(v.visible)
+		v.shader.SetUniform("gHasTexture", mi.HasTexture(texture.TextureDiffuse))
+		mi.DrawInstanced(program)
+	}
+	if cullFace {
+		gl.Enable(gl.CULL_FACE)
+	}
+	v.effect.Disable()
+}
+
+// renderImpostors 绘制远处实例的替身, 按相机相对实例的方向选择图集中的帧
+func (v *Vegetation) renderImpostors(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	if v.atlas == nil {
+		return
+	}
+
+	mvp := projection.Mul4(view).Mul4(model)
+	v.impostorEffect.Enable()
+	v.impostorEffect.SetProjectMatrix(&projection)
+	v.impostorEffect.SetViewMatrix(&view)
+	v.impostorEffect.SetModelMatrix(&model)
+	v.impostorEffect.SetWVP(&mvp)
+	v.impostorEffect.SetEyeWorldPos(eyePosition)
+	v.impostorEffect.SetPointLight(lights)
+	v.impostorEffect.SetFog(config.Config.Fog)
+
+	v.impostorShader.SetUniform("gFrames", int32(v.atlas.Frames))
+	v.impostorShader.SetUniform("gColumns", int32(v.atlas.Columns))
+	v.impostorShader.SetUniform("gRows", int32(v.atlas.Rows))
+	v.impostorShader.SetUniform("gBase", v.atlas.Base)
+	v.impostorShader.SetUniform("gSize", mgl32.Vec2{v.atlas.Width, v.atlas.Height})
+	v.impostorShader.SetUniform("gImpostorStart", v.impostorStart())
+	v.impostorShader.SetUniform("gImpostorEnd", max(v.ImpostorDistance, v.impostorStart()+0.0001))
+	v.impostorShader.SetUniform("gFadeStart", v.FadeStart)
+	v.impostorShader.SetUniform("gFadeEnd", max(v.FadeEnd, v.FadeStart+0.0001))
+	v.impostorShader.SetUniform("gColor", v.Color)
+	v.impostorShader.SetUniform("gAlbedo", int32(0))
+	v.impostorShader.SetUniform("gNormal", int32(1))
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, v.atlas.AlbedoTexture())
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, v.atlas.NormalTexture())
+
+	// 四边形总是朝向相机, 关闭背面剔除避免相机在正上方时丢失
+	cullFace := gl.IsEnabled(gl.CULL_FACE)
+	gl.Disable(gl.CULL_FACE)
+	program := v.impostorEffect.ShaderObj.Program
+	gl.BindFragDataLocation(program, 0, gl.Str("color\x00"))
+	v.quad.SetInstances(v.impostors)
+	v.quad.DrawInstanced(program)
+	if cullFace {
+		gl.Enable(gl.CULL_FACE)
+	}
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	v.impostorEffect.Disable()
+}
+
+func (v *Vegetation) PostRender() {
+}
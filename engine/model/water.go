@@ -0,0 +1,376 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+const (
+	// MaxWaves 着色器支持的 Gerstner 波数量
+	MaxWaves = 4
+
+	defaultWaterSize       = 25
+	defaultWaterResolution = 128
+)
+
+// Wave Gerstner 波, 水平方向的位移使波峰变尖, 波谷变平
+type Wave struct {
+	// 水平传播方向, 使用 x 和 y 表示世界空间的 x 和 z
+	Direction  mgl32.Vec2
+	Amplitude  float32
+	Wavelength float32
+	// 0 为正弦波, 1 为最尖的波峰, 过大会使波峰自相交
+	Steepness float32
+	// 相对于深水波速的倍数
+	Speed float32
+}
+
+// Water 动画水面, 可替代地面作为场景中的水平面
+// 顶点按 Gerstner 波位移, 法线贴图滚动叠加细节
+// 反射和折射分别渲染到纹理, 按屏幕坐标采样并按菲涅尔混合, 折射深度用于计算水的颜色
+type Water struct {
+	Name string
+	Id   string
+
+	RenderPriority
+
+	Position mgl32.Vec3
+	// 半宽
+	Size float32
+
+	ShallowColor mgl32.Vec3
+	DeepColor    mgl32.Vec3
+	// 颜色达到深水颜色的深度
+	Clarity float32
+	Waves   []Wave
+
+	// 法线贴图的平铺次数和滚动速度, 没有法线贴图时只使用波浪法线
+	NormalTiling   float32
+	NormalScroll   mgl32.Vec2
+	NormalStrength float32
+
+	// 关闭折射时水面按不透明度混合到场景上
+	Refraction bool
+	// 法线对反射和折射采样坐标的扰动强度
+	Distortion float32
+	Reflection *PlanarReflection
+
+	refraction *framebuffer.FrameBuffer
+	// 折射使用的斜裁剪投影, 用于从折射深度还原观察空间位置
+	refractionProjection mgl32.Mat4
+	normalMap            uint32
+
+	plane  *mesh.Mesh
+	effect *technique.LightingTechnique
+	shader *shader.Shader
+
+	time float32
+	// 正在绘制反射或折射纹理, 水面自身不出现在其中
+	rendering bool
+
+	lastViewport [4]int32
+	lastFbo      int32
+}
+
+func NewWater(xmlModel config.XmlModel) (*Water, error) {
+	basePath := vfs.Path("model", xmlModel.Name)
+	w := &Water{
+		Name:           xmlModel.Name,
+		Id:             xmlModel.Id,
+		RenderPriority: NewRenderPriority(xmlModel),
+		Position:       xmlModel.Position.XYZ(),
+		Size:           defaultWaterSize,
+		ShallowColor:   mgl32.Vec3{0.1, 0.45, 0.5},
+		DeepColor:      mgl32.Vec3{0.02, 0.1, 0.2},
+		Clarity:        4,
+		NormalTiling:   8,
+		NormalScroll:   mgl32.Vec2{0.02, 0.01},
+		NormalStrength: 0.5,
+		Refraction:     true,
+		Distortion:     0.02,
+		Reflection:     NewPlanarReflection(xmlModel.Reflection),
+		effect:         &technique.LightingTechnique{},
+		shader: &shader.Shader{
+			VertFilePath: "./resource/shader/water.vert",
+			FragFilePath: "./resource/shader/water.frag",
+		},
+	}
+	if xmlModel.Reflection == nil {
+		w.Reflection.Enable = true
+		w.Reflection.Strength = 1
+	}
+
+	resolution := defaultWaterResolution
+	normalMap := ""
+	if xmlWater := xmlModel.Water; xmlWater != nil {
+		if xmlWater.Size > 0 {
+			w.Size = xmlWater.Size
+		}
+		if xmlWater.Resolution > 0 {
+			resolution = xmlWater.Resolution
+		}
+		if xmlWater.ShallowColor != nil {
+			w.ShallowColor = xmlWater.ShallowColor.RGB()
+		}
+		if xmlWater.DeepColor != nil {
+			w.DeepColor = xmlWater.DeepColor.RGB()
+		}
+		if xmlWater.Clarity > 0 {
+			w.Clarity = xmlWater.Clarity
+		}
+		if xmlWater.Refraction != nil {
+			w.Refraction = *xmlWater.Refraction
+		}
+		if xmlWater.Distortion > 0 {
+			w.Distortion = xmlWater.Distortion
+		}
+		if xmlWater.NormalTiling > 0 {
+			w.NormalTiling = xmlWater.NormalTiling
+		}
+		if scroll := xmlWater.NormalScroll.XYZ(); scroll.Len() > 0 {
+			w.NormalScroll = mgl32.Vec2{scroll.X(), scroll.Z()}
+		}
+		if xmlWater.NormalMap != "" {
+			normalMap = filepath.Join(basePath, xmlWater.NormalMap)
+		}
+		for _, xmlWave := range xmlWater.Waves {
+			w.Waves = append(w.Waves, Wave{
+				Direction:  mgl32.Vec2{xmlWave.Direction.X, xmlWave.Direction.Z},
+				Amplitude:  xmlWave.Amplitude,
+				Wavelength: xmlWave.Wavelength,
+				Steepness:  xmlWave.Steepness,
+				Speed:      xmlWave.Speed,
+			})
+		}
+	}
+	if len(w.Waves) == 0 {
+		w.Waves = []Wave{
+			{Direction: mgl32.Vec2{1, 0.3}, Amplitude: 0.15, Wavelength: 8, Steepness: 0.5, Speed: 1},
+			{Direction: mgl32.Vec2{-0.4, 1}, Amplitude: 0.08, Wavelength: 4, Steepness: 0.4, Speed: 1},
+		}
+	}
+	if len(w.Waves) > MaxWaves {
+		logger.Warn(fmt.Sprintf("water %s: only the first %d waves are used", w.Name, MaxWaves))
+		w.Waves = w.Waves[:MaxWaves]
+	}
+
+	if err := w.shader.Init(); err != nil {
+		return nil, err
+	}
+	w.effect.Init(w.shader)
+	w.plane = mesh.NewMeshPlaneGrid(w.Size, resolution)
+
+	if normalMap != "" {
+		texture.NewTextureAsync(texture.DefaultOptions(), normalMap,
+			func(id uint32, err error) {
+				if err != nil {
+					logger.Error(err)
+					return
+				}
+				w.normalMap = id
+			})
+	}
+	return w, nil
+}
+
+func (w *Water) Dispose() {
+	w.plane.Dispose()
+	w.Reflection.Dispose()
+	if w.refraction != nil {
+		w.refraction.Dispose()
+		w.refraction = nil
+	}
+	if w.normalMap != 0 {
+		gl.DeleteTextures(1, &w.normalMap)
+		w.normalMap = 0
+	}
+	w.shader.Dispose()
+}
+
+// Transparent 关闭折射时需要混合到已绘制的场景上
+func (w *Water) Transparent() bool {
+	return !w.Refraction
+}
+
+// WorldBounds 包含波浪振幅的包围盒
+func (w *Water) WorldBounds() geometry.AABB {
+	var height float32
+	for _, wave := range w.Waves {
+		height += wave.Amplitude
+	}
+	extent := mgl32.Vec3{w.Size + height, height, w.Size + height}
+	return geometry.AABB{Min: w.Position.Sub(extent), Max: w.Position.Add(extent)}
+}
+
+// RenderReflection 绘制反射纹理和折射纹理, draw 按给定相机绘制场景
+func (w *Water) RenderReflection(width, height int32, projection, view mgl32.Mat4, eyePosition mgl32.Vec3,
+	draw func(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3)) error {
+	w.rendering = true
+	defer func() { w.rendering = false }()
+
+	if w.Reflection.Enable {
+		mirrorProjection, mirrorView, mirrorEye, err := w.Reflection.Begin(width, height, projection, view, eyePosition, w.Position.Y())
+		if err != nil {
+			return err
+		}
+		draw(mirrorProjection, mirrorView, &mirrorEye)
+		w.Reflection.End()
+	}
+
+	if w.Refraction {
+		refractionProjection, err := w.beginRefraction(width, height, projection, view, eyePosition)
+		if err != nil {
+			return err
+		}
+		draw(refractionProjection, view, &eyePosition)
+		w.endRefraction()
+		w.refractionProjection = refractionProjection
+	}
+	return nil
+}
+
+// beginRefraction 绑定折射纹理, 相机在水面以上时把近裁剪面替换为水面, 只保留水下的物体
+func (w *Water) beginRefraction(width, height int32, projection, view mgl32.Mat4, eye mgl32.Vec3) (mgl32.Mat4, error) {
+	if w.refraction == nil {
+		fb, err := framebuffer.NewFrameBuffer(width, height, true, framebuffer.RGBA8)
+		if err != nil {
+			return projection, err
+		}
+		w.refraction = fb
+	} else if w.refraction.Width != width || w.refraction.Height != height {
+		if err := w.refraction.Resize(width, height); err != nil {
+			return projection, err
+		}
+	}
+
+	planeHeight := w.Position.Y()
+	if eye.Y() > planeHeight {
+		// 平面法线朝下, 相机位于平面背面
+		plane := mgl32.Vec4{0, -1, 0, planeHeight + reflectionClipOffset}
+		projection = obliqueProjection(projection, view.Inv().Transpose().Mul4x1(plane))
+	}
+
+	gl.GetIntegerv(gl.VIEWPORT, &w.lastViewport[0])
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &w.lastFbo)
+	w.refraction.Bind()
+	clearColor := config.BackgroundColor()
+	gl.ClearColor(clearColor[0], clearColor[1], clearColor[2], 1)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	return projection, nil
+}
+
+func (w *Water) endRefraction() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(w.lastFbo))
+	gl.Viewport(w.lastViewport[0], w.lastViewport[1], w.lastViewport[2], w.lastViewport[3])
+}
+
+func (w *Water) SetPosition(p mgl32.Vec3) {
+	w.Position = p
+}
+
+func (w *Water) Update(elapsed float64) {
+	w.time += float32(elapsed)
+}
+
+func (w *Water) PreRender() {
+}
+
+func (w *Water) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	if w.rendering {
+		return
+	}
+
+	model = model.Mul4(mgl32.Translate3D(w.Position.X(), w.Position.Y(), w.Position.Z()))
+	mvp := projection.Mul4(view).Mul4(model)
+
+	var viewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+
+	w.effect.Enable()
+	w.effect.SetProjectMatrix(&projection)
+	w.effect.SetViewMatrix(&view)
+	w.effect.SetModelMatrix(&model)
+	w.effect.SetWVP(&mvp)
+	w.effect.SetEyeWorldPos(eyePosition)
+	w.effect.SetPointLight(lights)
+	w.effect.SetFog(config.Config.Fog)
+
+	w.shader.SetUniform("gTime", w.time)
+	w.shader.SetUniform("gWaveNum", int32(len(w.Waves)))
+	for i, wave := range w.Waves {
+		direction := wave.Direction
+		if direction.Len() > 0 {
+			direction = direction.Normalize()
+		}
+		prefix := fmt.Sprintf("gWaves[%d].", i)
+		w.shader.SetUniform(prefix+"Direction", direction)
+		w.shader.SetUniform(prefix+"Amplitude", wave.Amplitude)
+		w.shader.SetUniform(prefix+"Wavelength", max(wave.Wavelength, 0.01))
+		w.shader.SetUniform(prefix+"Steepness", wave.Steepness)
+		w.shader.SetUniform(prefix+"Speed", wave.Speed)
+	}
+
+	// 反射和折射纹理只在完整渲染模式下更新
+	rendered := config.Config.ShadingMode == config.ShadingRendered
+	reflection := w.Reflection.Texture()
+	hasReflection := rendered && w.Reflection.Enable && reflection != 0
+	hasRefraction := rendered && w.Refraction && w.refraction != nil
+
+	w.shader.SetUniform("gScreenSize", mgl32.Vec2{float32(viewport[2]), float32(viewport[3])})
+	w.shader.SetUniform("gShallowColor", w.ShallowColor)
+	w.shader.SetUniform("gDeepColor", w.DeepColor)
+	w.shader.SetUniform("gClarity", w.Clarity)
+	w.shader.SetUniform("gDistortion", w.Distortion)
+	w.shader.SetUniform("gReflectionStrength", w.Reflection.Strength)
+	w.shader.SetUniform("gReflectionFresnel", w.Reflection.Fresnel)
+	w.shader.SetUniform("gHasReflection", hasReflection)
+	w.shader.SetUniform("gHasRefraction", hasRefraction)
+	w.shader.SetUniform("gHasNormalMap", w.normalMap != 0)
+	w.shader.SetUniform("gNormalTiling", w.NormalTiling)
+	w.shader.SetUniform("gNormalScroll", w.NormalScroll)
+	w.shader.SetUniform("gNormalStrength", w.NormalStrength)
+	if hasRefraction {
+		w.shader.SetUniform("gRefractionInvProjection", w.refractionProjection.Inv())
+	}
+	w.shader.SetUniform("gReflection", int32(0))
+	w.shader.SetUniform("gRefraction", int32(1))
+	w.shader.SetUniform("gRefractionDepth", int32(2))
+	w.shader.SetUniform("gNormalMap", int32(3))
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, reflection)
+	if w.refraction != nil {
+		gl.ActiveTexture(gl.TEXTURE1)
+		gl.BindTexture(gl.TEXTURE_2D, w.refraction.ColorTexture(0))
+		gl.ActiveTexture(gl.TEXTURE2)
+		gl.BindTexture(gl.TEXTURE_2D, w.refraction.DepthTexture)
+	}
+	gl.ActiveTexture(gl.TEXTURE3)
+	gl.BindTexture(gl.TEXTURE_2D, w.normalMap)
+
+	gl.BindFragDataLocation(w.effect.ShaderObj.Program, 0, gl.Str("color\x00"))
+	w.plane.Draw(w.effect.ShaderObj.Program)
+
+	for unit := uint32(0); unit < 4; unit++ {
+		gl.ActiveTexture(gl.TEXTURE0 + unit)
+		gl.BindTexture(gl.TEXTURE_2D, 0)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	w.effect.Disable()
+}
+
+func (w *Water) PostRender() {
+}
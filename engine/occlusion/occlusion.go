@@ -0,0 +1,168 @@
+package occlusion
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+// Stats 上一帧的遮挡查询统计
+type Stats struct {
+	Tested   int
+	Occluded int
+}
+
+// proxyMargin 代理包围盒按最长边放大的比例, 表面正好在包围盒上的对象(如立方体)不会被自身深度挡住
+const proxyMargin = 0.01
+
+type query struct {
+	id       uint32
+	issued   bool
+	occluded bool
+}
+
+// Culler 基于硬件遮挡查询的遮挡剔除
+// 每帧在场景绘制完成后, 用对象的包围盒作为代理进行遮挡查询, 下一帧绘制对象时使用条件渲染,
+// 查询结果为没有可见像素的对象由GPU跳过, 不需要等待查询结果
+type Culler struct {
+	queries map[model.RenderObj]*query
+
+	boxEffect *technique.BaseTechnique
+	box       *mesh.Mesh
+
+	stats Stats
+}
+
+func NewCuller() (*Culler, error) {
+	c := &Culler{
+		queries:   make(map[model.RenderObj]*query),
+		boxEffect: &technique.BaseTechnique{},
+	}
+
+	boxShader := &shader.Shader{
+		VertFilePath: "./resource/shader/unlit.vert",
+		FragFilePath: "./resource/shader/unlit.frag",
+	}
+	if err := boxShader.Init(); err != nil {
+		return nil, err
+	}
+	c.boxEffect.Init(boxShader)
+	c.box = mesh.NewMeshCube()
+	return c, nil
+}
+
+// BeginRender 对象绘制前调用, 已有查询结果时开始条件渲染
+func (c *Culler) BeginRender(obj model.RenderObj) {
+	if q, ok := c.queries[obj]; ok && q.issued {
+		gl.BeginConditionalRender(q.id, gl.QUERY_NO_WAIT)
+	}
+}
+
+// EndRender 对象绘制后调用
+func (c *Culler) EndRender(obj model.RenderObj) {
+	if q, ok := c.queries[obj]; ok && q.issued {
+		gl.EndConditionalRender()
+	}
+}
+
+// Query 绘制包围盒代理并发起遮挡查询, 需在场景绘制完成后, 深度缓冲仍然绑定时调用
+// 相机(按近平面距离扩展)在包围盒内时代理会被近平面裁掉, 不发起查询, 对象总是绘制
+func (c *Culler) Query(renderObjs []model.RenderObj, projection, view mgl32.Mat4, eyePosition mgl32.Vec3, near float32) {
+	c.stats = Stats{}
+
+	gl.ColorMask(false, false, false, false)
+	gl.DepthMask(false)
+	lastCullFace := gl.IsEnabled(gl.CULL_FACE)
+	gl.Disable(gl.CULL_FACE)
+
+	c.boxEffect.Enable()
+	c.boxEffect.SetProjectMatrix(&projection)
+	c.boxEffect.SetViewMatrix(&view)
+	c.boxEffect.SetInstanced(false)
+
+	for _, renderObj := range renderObjs {
+		boundedObj, ok := renderObj.(model.BoundedObj)
+		if !ok {
+			continue
+		}
+		bounds := boundedObj.WorldBounds()
+		if bounds.IsEmpty() {
+			continue
+		}
+
+		q := c.getQuery(renderObj)
+		if bounds.Expand(near).Contains(eyePosition) {
+			q.issued, q.occluded = false, false
+			continue
+		}
+		c.readResult(q)
+
+		bounds = bounds.Expand(max(bounds.MaxExtent()*proxyMargin, 1e-4))
+		size := bounds.Size()
+		boxModel := mgl32.Translate3D(bounds.Min[0], bounds.Min[1], bounds.Min[2]).Mul4(mgl32.Scale3D(size[0], size[1], size[2]))
+		c.boxEffect.SetModelMatrix(&boxModel)
+
+		gl.BeginQuery(gl.ANY_SAMPLES_PASSED, q.id)
+		c.box.Draw(c.boxEffect.ShaderObj.Program)
+		gl.EndQuery(gl.ANY_SAMPLES_PASSED)
+		q.issued = true
+	}
+
+	c.boxEffect.Disable()
+
+	gl.ColorMask(true, true, true, true)
+	gl.DepthMask(true)
+	if lastCullFace {
+		gl.Enable(gl.CULL_FACE)
+	}
+}
+
+func (c *Culler) getQuery(obj model.RenderObj) *query {
+	q, ok := c.queries[obj]
+	if !ok {
+		q = &query{}
+		gl.GenQueries(1, &q.id)
+		c.queries[obj] = q
+	}
+	return q
+}
+
+// readResult 读取上一帧的查询结果用于统计, 结果未就绪时沿用之前的状态
+func (c *Culler) readResult(q *query) {
+	if !q.issued {
+		return
+	}
+	var available uint32
+	gl.GetQueryObjectuiv(q.id, gl.QUERY_RESULT_AVAILABLE, &available)
+	if available != 0 {
+		var samplesPassed uint32
+		gl.GetQueryObjectuiv(q.id, gl.QUERY_RESULT, &samplesPassed)
+		q.occluded = samplesPassed == 0
+	}
+
+	c.stats.Tested++
+	if q.occluded {
+		c.stats.Occluded++
+	}
+}
+
+// Reset 丢弃所有查询结果, 关闭遮挡剔除后重新开启时使用
+func (c *Culler) Reset() {
+	for obj, q := range c.queries {
+		gl.DeleteQueries(1, &q.id)
+		delete(c.queries, obj)
+	}
+	c.stats = Stats{}
+}
+
+func (c *Culler) Stats() Stats {
+	return c.stats
+}
+
+func (c *Culler) Dispose() {
+	c.Reset()
+	c.box.Dispose()
+}
@@ -0,0 +1,91 @@
+// Package outline 用模板缓冲描出选中对象的轮廓
+package outline
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+// Outline 先把对象写入模板缓冲, 再沿屏幕空间法线外扩绘制, 模板测试只保留对象外的部分
+// 关闭深度测试, 被遮挡的部分也显示轮廓
+type Outline struct {
+	effect *technique.BaseTechnique
+
+	colorUniform      int32
+	widthUniform      int32
+	screenSizeUniform int32
+}
+
+func NewOutline() (*Outline, error) {
+	o := &Outline{effect: &technique.BaseTechnique{}}
+	outlineShader := &shader.Shader{
+		VertFilePath: "./resource/shader/outline.vert",
+		FragFilePath: "./resource/shader/outline.frag",
+	}
+	if err := outlineShader.Init(); err != nil {
+		return nil, err
+	}
+	o.effect.Init(outlineShader)
+	o.colorUniform = o.effect.GetUniformLocation("gColor")
+	o.widthUniform = o.effect.GetUniformLocation("gWidth")
+	o.screenSizeUniform = o.effect.GetUniformLocation("gScreenSize")
+	return o, nil
+}
+
+// Draw 描出 obj 的轮廓, 当前帧缓冲需要有模板附件, 没有几何信息的对象不绘制
+func (o *Outline) Draw(obj interface{}, projection, view mgl32.Mat4) {
+	outline := config.Config.Outline
+	geometryObj, ok := obj.(model.GeometryObj)
+	if !outline.Enable || !ok {
+		return
+	}
+
+	var viewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	var polygonMode [2]int32
+	gl.GetIntegerv(gl.POLYGON_MODE, &polygonMode[0])
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Enable(gl.STENCIL_TEST)
+	gl.StencilMask(0xff)
+	gl.ClearStencil(0)
+	gl.Clear(gl.STENCIL_BUFFER_BIT)
+
+	o.effect.Enable()
+	o.effect.SetProjectMatrix(&projection)
+	o.effect.SetViewMatrix(&view)
+	gl.Uniform2f(o.screenSizeUniform, float32(viewport[2]), float32(viewport[3]))
+	gl.Uniform4f(o.colorUniform, outline.Color.X(), outline.Color.Y(), outline.Color.Z(), outline.Color.W())
+
+	// 写入对象覆盖的像素
+	gl.ColorMask(false, false, false, false)
+	gl.StencilFunc(gl.ALWAYS, 1, 0xff)
+	gl.StencilOp(gl.KEEP, gl.KEEP, gl.REPLACE)
+	gl.Uniform1f(o.widthUniform, 0)
+	geometryObj.RenderGeometry(o.effect)
+
+	// 外扩后只绘制对象以外的像素
+	gl.ColorMask(true, true, true, true)
+	gl.StencilFunc(gl.NOTEQUAL, 1, 0xff)
+	gl.StencilMask(0)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.Uniform1f(o.widthUniform, outline.Width)
+	geometryObj.RenderGeometry(o.effect)
+	o.effect.Disable()
+
+	gl.Disable(gl.BLEND)
+	gl.StencilMask(0xff)
+	gl.Disable(gl.STENCIL_TEST)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.PolygonMode(gl.FRONT_AND_BACK, uint32(polygonMode[0]))
+}
+
+func (o *Outline) Dispose() {
+	o.effect.ShaderObj.Dispose()
+}
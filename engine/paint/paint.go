@@ -0,0 +1,219 @@
+package paint
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// 模型没有漫反射贴图时新建的画布大小
+const defaultCanvasSize = 512
+
+// canvas 网格漫反射贴图在内存中的副本, 绘制后整体上传
+// tex 是画布自己的贴图, 不与其他模型共享
+type canvas struct {
+	model *model.Model
+	image *image.RGBA
+	tex   uint32
+}
+
+// canvasKey 画布按模型和网格当前的漫反射贴图区分, 同一模型中使用同一贴图的网格共用画布
+type canvasKey struct {
+	model *model.Model
+	tex   uint32
+}
+
+// Tool 纹理绘制工具, 按住鼠标左键在模型上绘制, 通过拾取射线和UV确定绘制位置
+type Tool struct {
+	Active bool
+
+	BrushSize float32 // 笔刷半径(纹理像素)
+	Opacity   float32
+	Color     mgl32.Vec3
+
+	target   *model.Model
+	canvases map[canvasKey]*canvas
+	current  *canvas // 最近绘制的画布, Save 保存这张画布
+
+	stroking   bool
+	newStroke  bool
+	pendingPos [2]float32
+}
+
+func NewTool() *Tool {
+	return &Tool{
+		BrushSize: 8,
+		Opacity:   0.5,
+		Color:     mgl32.Vec3{1, 0, 0},
+		canvases:  make(map[canvasKey]*canvas),
+	}
+}
+
+func (t *Tool) SetActive(active bool) {
+	t.Active = active
+	t.stroking = false
+	t.target = nil
+}
+
+// HandleInput 记录鼠标拖动, 需在 imgui.NewFrame 之后调用
+func (t *Tool) HandleInput() {
+	t.stroking = false
+	if !t.Active {
+		return
+	}
+	io := imgui.CurrentIO()
+	if io.WantCaptureMouse() || !imgui.IsMouseDown(0) {
+		return
+	}
+	t.newStroke = imgui.IsMouseClicked(0)
+	pos := imgui.MousePos()
+	t.stroking = true
+	t.pendingPos = [2]float32{pos.X, pos.Y}
+}
+
+// PendingStroke 本帧需要绘制的鼠标位置(窗口坐标), newStroke 表示刚按下鼠标, 需要重新选择模型
+func (t *Tool) PendingStroke() (pos [2]float32, newStroke bool, ok bool) {
+	return t.pendingPos, t.newStroke, t.stroking
+}
+
+// Target 当前绘制的模型
+func (t *Tool) Target() *model.Model {
+	return t.target
+}
+
+func (t *Tool) SetTarget(m *model.Model) {
+	t.target = m
+}
+
+// Paint 在网格 mi 漫反射贴图的 uv 处绘制一笔, mi 和 uv 来自模型的射线交点
+func (t *Tool) Paint(m *model.Model, mi *mesh.Mesh, uv mgl32.Vec2) {
+	c, err := t.getCanvas(m, mi)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	t.current = c
+
+	size := c.image.Rect.Size()
+	// 模型以 FlipUVs 导入, v 方向与图片行方向一致
+	cx := float64(uv.X()-float32(math.Floor(float64(uv.X())))) * float64(size.X)
+	cy := float64(uv.Y()-float32(math.Floor(float64(uv.Y())))) * float64(size.Y)
+	radius := float64(t.BrushSize)
+
+	for y := int(cy - radius); y <= int(cy+radius); y++ {
+		for x := int(cx - radius); x <= int(cx+radius); x++ {
+			if x < 0 || y < 0 || x >= size.X || y >= size.Y {
+				continue
+			}
+			d := math.Hypot(float64(x)-cx, float64(y)-cy)
+			if d > radius {
+				continue
+			}
+			// 边缘渐弱
+			alpha := float64(t.Opacity) * (1 - d/radius)
+			c.image.SetRGBA(x, y, blend(c.image.RGBAAt(x, y), t.Color, alpha))
+		}
+	}
+	c.upload()
+}
+
+func blend(dst color.RGBA, src mgl32.Vec3, alpha float64) color.RGBA {
+	mix := func(d uint8, s float32) uint8 {
+		return uint8(float64(d)*(1-alpha) + float64(s)*255*alpha)
+	}
+	return color.RGBA{R: mix(dst.R, src[0]), G: mix(dst.G, src[1]), B: mix(dst.B, src[2]), A: dst.A}
+}
+
+// getCanvas 返回网格漫反射贴图的画布
+// 贴图可能通过 resource 被其他模型共享, 第一次绘制前复制一份私有贴图, 替换本模型中使用这张贴图的网格
+// 网格没有漫反射贴图时新建白色贴图, 只挂到这个网格上
+func (t *Tool) getCanvas(m *model.Model, mi *mesh.Mesh) (*canvas, error) {
+	for _, tex := range mi.Textures {
+		if tex.TextureType != texture.TextureDiffuse || tex.Id == 0 {
+			continue
+		}
+		if c, ok := t.canvases[canvasKey{m, tex.Id}]; ok {
+			return c, nil
+		}
+		rgba, err := texture.ImageToPixelData(tex.Path)
+		if err != nil {
+			return nil, err
+		}
+		c := &canvas{model: m, image: rgba, tex: texture.NewTextureFromImage(texture.ColorOptions(), rgba)}
+		shared := tex.Id
+		for _, other := range m.Meshes {
+			for i := range other.Textures {
+				if other.Textures[i].TextureType == texture.TextureDiffuse && other.Textures[i].Id == shared {
+					other.Textures[i].Id = c.tex
+				}
+			}
+		}
+		t.canvases[canvasKey{m, c.tex}] = c
+		return c, nil
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, defaultCanvasSize, defaultCanvasSize))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = 0xff
+	}
+	tex := texture.NewTextureFromRGBA(rgba)
+	tex.TextureType = texture.TextureDiffuse
+	mi.Textures = append(mi.Textures, *tex)
+
+	c := &canvas{model: m, image: rgba, tex: tex.Id}
+	t.canvases[canvasKey{m, c.tex}] = c
+	return c, nil
+}
+
+func (c *canvas) upload() {
+	size := c.image.Rect.Size()
+	gl.BindTexture(gl.TEXTURE_2D, c.tex)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, int32(size.X), int32(size.Y), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(c.image.Pix))
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// DefaultSavePath 模型目录下的 <name>_paint.png
+func DefaultSavePath(m *model.Model) string {
+	return filepath.Join(m.BasePath, m.Name+"_paint.png")
+}
+
+// Save 将当前模型的贴图保存为PNG
+func (t *Tool) Save(file string) error {
+	if t.target == nil {
+		return fmt.Errorf("no model painted")
+	}
+	c := t.current
+	if c == nil || c.model != t.target {
+		return fmt.Errorf("model %s has not been painted", t.target.Name)
+	}
+
+	f, err := os.Create(vfs.WritePath(file))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, c.image)
+}
+
+func (t *Tool) Dispose() {
+	// 画布的贴图都是私有的, 共享贴图仍由 resource 管理
+	for _, c := range t.canvases {
+		gl.DeleteTextures(1, &c.tex)
+	}
+	t.canvases = make(map[canvasKey]*canvas)
+	t.current = nil
+}
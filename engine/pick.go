@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/input"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/registry"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// 按下和松开左键的距离在此范围内时视为点击, 像素
+const clickSlop = 4
+
+// PickHit 拾取到的对象和交点
+type PickHit struct {
+	Entity   *registry.Entity
+	Distance float32
+	Position mgl32.Vec3
+}
+
+// Pick 窗口坐标 (x, y) 处最近的对象, 按包围盒求交
+func (w *World) Pick(x, y float32) (PickHit, bool) {
+	return w.PickV(x, y, false)
+}
+
+// PickV triangles 为 true 时模型按三角形求交, 其他对象仍按包围盒求交
+func (w *World) PickV(x, y float32, triangles bool) (PickHit, bool) {
+	ray, ok := w.Camera.ScreenPointToRay(x, y, w.platform.DisplaySize())
+	if !ok {
+		return PickHit{}, false
+	}
+
+	var nearest PickHit
+	found := false
+	for _, e := range w.registry.Entities() {
+		bounds, ok := e.Bounds()
+		if !ok {
+			continue
+		}
+		t, ok := ray.IntersectAABB(bounds)
+		if !ok || (found && t >= nearest.Distance) {
+			continue
+		}
+		if m, ok := e.Obj.(*model.Model); ok && triangles {
+			hit, ok := m.IntersectRay(ray)
+			if !ok || (found && hit.Distance >= nearest.Distance) {
+				continue
+			}
+			t = hit.Distance
+		}
+		nearest = PickHit{Entity: e, Distance: t, Position: ray.At(t)}
+		found = true
+	}
+	return nearest, found
+}
+
+// updateClickSelection 在视口中点击时选中光标下的模型, 点击空白处取消选中
+// 拖动旋转相机, 编辑工具打开或按在手柄和灯光图标上时不选择
+func (w *World) updateClickSelection() {
+	pos := w.Input.MousePosition()
+	if w.Input.WasMousePressed(input.MouseLeft) {
+		w.clickPending = !imgui.CurrentIO().WantCaptureMouse() && !w.editingWithMouse() && !w.Input.RelativeMouse()
+		w.clickStart = pos
+	}
+	// 多指手势不是点击
+	if w.Input.TouchFingers() >= 2 {
+		w.clickPending = false
+	}
+	if !w.clickPending || !w.Input.WasMouseReleased(input.MouseLeft) {
+		return
+	}
+	w.clickPending = false
+	if mgl32.Abs(pos[0]-w.clickStart[0]) > clickSlop || mgl32.Abs(pos[1]-w.clickStart[1]) > clickSlop {
+		return
+	}
+	if hit, ok := w.PickV(pos[0], pos[1], true); ok {
+		w.uiWindowMain.SelectModel(hit.Entity.Obj)
+	} else {
+		w.uiWindowMain.ClearModelSelection()
+	}
+}
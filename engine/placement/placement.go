@@ -0,0 +1,99 @@
+package placement
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// Tool 放置工具, 在光标下预览选中的预制体, 吸附到地面或地形上, 滚轮旋转, 点击放置
+// 光标射线的拾取和对象的创建由外部完成
+type Tool struct {
+	Active bool
+
+	// 可放置的预制体, 来自场景配置中的模型定义
+	Prefabs  []config.XmlModel
+	Selected int
+	// 绕 Y 轴旋转(弧度)
+	Rotation float32
+	// 滚轮每格旋转的角度
+	RotateStep float32
+
+	cursor    [2]float32
+	hasCursor bool
+	pending   bool
+	placed    int
+}
+
+func NewTool(prefabs []config.XmlModel) *Tool {
+	return &Tool{
+		Prefabs:    prefabs,
+		RotateStep: 15,
+	}
+}
+
+func (t *Tool) SetActive(active bool) {
+	t.Active = active
+	t.hasCursor = false
+	t.pending = false
+}
+
+// HandleInput 记录光标位置, 滚轮旋转和点击, 需在 imgui.NewFrame 之后调用
+func (t *Tool) HandleInput() {
+	if !t.Active {
+		return
+	}
+	io := imgui.CurrentIO()
+	if !io.WantCaptureKeyboard() && imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyEscape), false) {
+		t.SetActive(false)
+		return
+	}
+	if io.WantCaptureMouse() {
+		t.hasCursor = false
+		return
+	}
+	pos := imgui.MousePos()
+	t.cursor = [2]float32{pos.X, pos.Y}
+	t.hasCursor = true
+
+	if _, wheel := io.MouseWheel(); wheel != 0 {
+		t.Rotation += mgl32.DegToRad(wheel * t.RotateStep)
+	}
+	if imgui.IsMouseClicked(0) {
+		t.pending = true
+	}
+}
+
+// Cursor 光标在视口中时返回窗口坐标
+func (t *Tool) Cursor() ([2]float32, bool) {
+	return t.cursor, t.Active && t.hasCursor
+}
+
+// TakeClick 是否有待处理的放置点击, 取出后即清除
+func (t *Tool) TakeClick() bool {
+	pending := t.pending
+	t.pending = false
+	return pending
+}
+
+// SelectedPrefab 当前选中的预制体
+func (t *Tool) SelectedPrefab() (config.XmlModel, bool) {
+	if t.Selected < 0 || t.Selected >= len(t.Prefabs) {
+		return config.XmlModel{}, false
+	}
+	return t.Prefabs[t.Selected], true
+}
+
+// Instantiate 以选中的预制体为模板生成放置对象的配置, 位置和编号由放置结果决定
+func (t *Tool) Instantiate(position mgl32.Vec3) (config.XmlModel, bool) {
+	prefab, ok := t.SelectedPrefab()
+	if !ok {
+		return config.XmlModel{}, false
+	}
+	t.placed++
+	prefab.Id = fmt.Sprintf("%s-placed-%d", prefab.Id, t.placed)
+	prefab.Position = config.XmlXYZ{X: position.X(), Y: position.Y(), Z: position.Z()}
+	return prefab, true
+}
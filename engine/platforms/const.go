@@ -6,3 +6,6 @@ const (
 	mouseButtonTertiary  = 2
 	mouseButtonCount     = 3
 )
+
+// defaultDPI is the display DPI that corresponds to a content scale of 1.
+const defaultDPI = 96
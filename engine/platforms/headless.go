@@ -0,0 +1,67 @@
+package platforms
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Headless 隐藏窗口的 OpenGL 上下文, 用于不显示界面的离屏渲染, 结果渲染到帧缓冲后读回
+type Headless struct {
+	window  *sdl.Window
+	context *sdl.GLContext
+}
+
+// NewHeadless 创建隐藏窗口和 OpenGL 4.1 core 上下文, 并设为当前上下文
+func NewHeadless(width, height int32) (*Headless, error) {
+	runtime.LockOSThread()
+
+	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
+		return nil, fmt.Errorf("failed to initialize SDL2: %w", err)
+	}
+
+	_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_MAJOR_VERSION, 4)
+	_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_MINOR_VERSION, 1)
+	_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_FLAGS, sdl.GL_CONTEXT_FORWARD_COMPATIBLE_FLAG)
+	_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_PROFILE_MASK, sdl.GL_CONTEXT_PROFILE_CORE)
+	_ = sdl.GLSetAttribute(sdl.GL_DEPTH_SIZE, 24)
+
+	window, err := sdl.CreateWindow("Toy Engine",
+		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, width, height, sdl.WINDOW_OPENGL|sdl.WINDOW_HIDDEN)
+	if err != nil {
+		sdl.Quit()
+		return nil, fmt.Errorf("failed to create window: %w", err)
+	}
+	h := &Headless{window: window}
+
+	glContext, err := window.GLCreateContext()
+	if err != nil {
+		h.Dispose()
+		return nil, fmt.Errorf("failed to create OpenGL context: %w", err)
+	}
+	h.context = &glContext
+	if err = window.GLMakeCurrent(glContext); err != nil {
+		h.Dispose()
+		return nil, fmt.Errorf("failed to set current OpenGL context: %w", err)
+	}
+	if err = gl.Init(); err != nil {
+		h.Dispose()
+		return nil, fmt.Errorf("failed to initialize OpenGL: %w", err)
+	}
+	return h, nil
+}
+
+// Dispose cleans up the resources.
+func (h *Headless) Dispose() {
+	if h.context != nil {
+		sdl.GLDeleteContext(*h.context)
+		h.context = nil
+	}
+	if h.window != nil {
+		_ = h.window.Destroy()
+		h.window = nil
+	}
+	sdl.Quit()
+}
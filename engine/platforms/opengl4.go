@@ -3,6 +3,7 @@ package platforms
 import (
 	_ "embed" // using embed for the shader sources
 	"fmt"
+	"math"
 	"unsafe"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
@@ -31,8 +32,16 @@ type OpenGL4 struct {
 	attribLocationColor    int32
 	vboHandle              uint32
 	elementsHandle         uint32
+
+	// 按像素大小缓存的默认字体, 缩放变化时切换而不是每次重建
+	fonts    map[int]imgui.Font
+	font     imgui.Font
+	fontSize int
 }
 
+// baseFontSize is the pixel size of the default imgui font at a scale of 1.
+const baseFontSize = 13
+
 // NewOpenGL4 attempts to initialize a renderer.
 // An OpenGL context has to be established before calling this function.
 func NewOpenGL4(io imgui.IO) (*OpenGL4, error) {
@@ -44,6 +53,10 @@ func NewOpenGL4(io imgui.IO) (*OpenGL4, error) {
 	renderer := &OpenGL4{
 		imguiIO:     io,
 		glslVersion: "#version 150",
+		// 图集构建时会自动加入基础大小的默认字体
+		fonts:    map[int]imgui.Font{baseFontSize: imgui.DefaultFont},
+		font:     imgui.DefaultFont,
+		fontSize: baseFontSize,
 	}
 	renderer.createDeviceObjects()
 
@@ -57,6 +70,39 @@ func (renderer *OpenGL4) Dispose() {
 	renderer.invalidateDeviceObjects()
 }
 
+// UpdateFontScale rasterizes the default font for the given framebuffer and UI scale.
+// The font is rendered at framebuffer resolution to stay crisp and scaled back to window coordinates.
+// It has to be called outside of imgui.NewFrame() and imgui.Render().
+func (renderer *OpenGL4) UpdateFontScale(framebufferScale, uiScale float32) {
+	size := int(math.Round(float64(baseFontSize * framebufferScale * uiScale)))
+	size = max(size, 1)
+	if size != renderer.fontSize {
+		font, ok := renderer.fonts[size]
+		if !ok {
+			cfg := imgui.NewFontConfig()
+			cfg.SetSize(float32(size))
+			font = renderer.imguiIO.Fonts().AddFontDefaultV(cfg)
+			cfg.Delete()
+			renderer.fonts[size] = font
+
+			// 图集已变化, 重新上传纹理
+			if renderer.fontTexture != 0 {
+				gl.DeleteTextures(1, &renderer.fontTexture)
+				renderer.fontTexture = 0
+			}
+			renderer.createFontsTexture()
+		}
+		renderer.font = font
+		renderer.fontSize = size
+	}
+	renderer.imguiIO.SetFontGlobalScale(1 / framebufferScale)
+}
+
+// Font returns the default font for the current scale, to be pushed after imgui.NewFrame().
+func (renderer *OpenGL4) Font() imgui.Font {
+	return renderer.font
+}
+
 // PreRender clears the framebuffer.
 func (renderer *OpenGL4) PreRender(clearColor [3]float32) {
 	gl.ClearColor(clearColor[0], clearColor[1], clearColor[2], 1.0)
@@ -114,11 +160,14 @@ func (renderer *OpenGL4) Render(displaySize [2]float32, framebufferSize [2]float
 	lastEnableCullFace := gl.IsEnabled(gl.CULL_FACE)
 	lastEnableDepthTest := gl.IsEnabled(gl.DEPTH_TEST)
 	lastEnableScissorTest := gl.IsEnabled(gl.SCISSOR_TEST)
+	lastEnableFramebufferSRGB := gl.IsEnabled(gl.FRAMEBUFFER_SRGB)
 
 	// Setup render state: alpha-blending enabled, no face culling, no depth testing, scissor enabled, polygon fill
 	gl.Enable(gl.BLEND)
 	gl.BlendEquation(gl.FUNC_ADD)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	// imgui 的颜色是 sRGB 值, 在 sRGB 空间直接混合, 目标 alpha 按预乘方式累积, 避免界面发白
+	gl.BlendFuncSeparate(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA, gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	gl.Disable(gl.FRAMEBUFFER_SRGB)
 	gl.Disable(gl.CULL_FACE)
 	gl.Disable(gl.DEPTH_TEST)
 	gl.Enable(gl.SCISSOR_TEST)
@@ -214,6 +263,9 @@ func (renderer *OpenGL4) Render(displaySize [2]float32, framebufferSize [2]float
 	} else {
 		gl.Disable(gl.SCISSOR_TEST)
 	}
+	if lastEnableFramebufferSRGB {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	}
 	gl.PolygonMode(gl.FRONT_AND_BACK, uint32(lastPolygonMode[0]))
 	gl.Viewport(lastViewport[0], lastViewport[1], lastViewport[2], lastViewport[3])
 	gl.Scissor(lastScissorBox[0], lastScissorBox[1], lastScissorBox[2], lastScissorBox[3])
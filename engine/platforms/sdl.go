@@ -2,8 +2,12 @@ package platforms
 
 import (
 	"fmt"
+	"math"
 	"runtime"
 
+	"github.com/huangxiaobo/toy-engine/engine/event"
+	"github.com/huangxiaobo/toy-engine/engine/gldebug"
+	"github.com/huangxiaobo/toy-engine/engine/input"
 	"github.com/inkyblackness/imgui-go/v4"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
@@ -28,23 +32,53 @@ type SDL struct {
 
 	time        uint64
 	buttonsDown [mouseButtonCount]bool
+
+	// 每帧的键盘鼠标状态, 由事件循环写入
+	input *input.Input
+	// 已连接的手柄
+	gamepads map[sdl.JoystickID]*sdl.GameController
+	// 窗口和按键事件发布到这里, 为 nil 时不发布
+	events *event.Bus
+	// 按下的手指和上一次多指手势的中心(窗口坐标), 用于计算手势的移动
+	fingers       map[touchFinger]struct{}
+	gestureCenter [2]float32
+	gestureActive bool
+}
+
+type touchFinger struct {
+	touch  sdl.TouchID
+	finger sdl.FingerID
 }
 
 // NewSDL attempts to initialize an SDL context.
-func NewSDL(io imgui.IO, clientAPI SDLClientAPI, windowWidth, windowHeight int32) (*SDL, error) {
+// samples > 0 requests a multisampled default framebuffer; if that is not supported the window is created without it.
+func NewSDL(io imgui.IO, clientAPI SDLClientAPI, windowWidth, windowHeight, samples int32) (*SDL, error) {
 	runtime.LockOSThread()
 
 	err := sdl.Init(sdl.INIT_VIDEO)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize SDL2: %w", err)
 	}
+	// 没有手柄支持时仍然可以使用键盘鼠标, 手柄在 CONTROLLERDEVICEADDED 事件中打开
+	_ = sdl.InitSubSystem(sdl.INIT_GAMECONTROLLER)
 
 	if err = ttf.Init(); err != nil {
 		return nil, fmt.Errorf("filed to initialize ttf: %w", err)
 	}
 
+	if samples > 0 {
+		_ = sdl.GLSetAttribute(sdl.GL_MULTISAMPLEBUFFERS, 1)
+		_ = sdl.GLSetAttribute(sdl.GL_MULTISAMPLESAMPLES, int(samples))
+	}
+
 	window, err := sdl.CreateWindow("Toy Engine",
-		sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, windowWidth, windowHeight, sdl.WINDOW_OPENGL)
+		sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, windowWidth, windowHeight, sdl.WINDOW_OPENGL|sdl.WINDOW_ALLOW_HIGHDPI)
+	if err != nil && samples > 0 {
+		_ = sdl.GLSetAttribute(sdl.GL_MULTISAMPLEBUFFERS, 0)
+		_ = sdl.GLSetAttribute(sdl.GL_MULTISAMPLESAMPLES, 0)
+		window, err = sdl.CreateWindow("Toy Engine",
+			sdl.WINDOWPOS_CENTERED, sdl.WINDOWPOS_CENTERED, windowWidth, windowHeight, sdl.WINDOW_OPENGL|sdl.WINDOW_ALLOW_HIGHDPI)
+	}
 	if err != nil {
 		sdl.Quit()
 		return nil, fmt.Errorf("failed to create window: %w", err)
@@ -53,6 +87,10 @@ func NewSDL(io imgui.IO, clientAPI SDLClientAPI, windowWidth, windowHeight int32
 	platform := &SDL{
 		imguiIO: io,
 		window:  window,
+		input:   input.NewInput(),
+
+		gamepads: make(map[sdl.JoystickID]*sdl.GameController),
+		fingers:  make(map[touchFinger]struct{}),
 	}
 	platform.setKeyMapping()
 
@@ -63,12 +101,12 @@ func NewSDL(io imgui.IO, clientAPI SDLClientAPI, windowWidth, windowHeight int32
 	case SDLClientAPIOpenGL3:
 		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_MAJOR_VERSION, 3)
 		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_MINOR_VERSION, 2)
-		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_FLAGS, sdl.GL_CONTEXT_FORWARD_COMPATIBLE_FLAG)
+		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_FLAGS, contextFlags())
 		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_PROFILE_MASK, sdl.GL_CONTEXT_PROFILE_CORE)
 	case SDLClientAPIOpenGL4:
 		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_MAJOR_VERSION, 4)
 		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_MINOR_VERSION, 1)
-		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_FLAGS, sdl.GL_CONTEXT_FORWARD_COMPATIBLE_FLAG)
+		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_FLAGS, contextFlags())
 		_ = sdl.GLSetAttribute(sdl.GL_CONTEXT_PROFILE_MASK, sdl.GL_CONTEXT_PROFILE_CORE)
 	default:
 		platform.Dispose()
@@ -94,8 +132,34 @@ func NewSDL(io imgui.IO, clientAPI SDLClientAPI, windowWidth, windowHeight int32
 	return platform, nil
 }
 
+// contextFlags 核心上下文的标志, GL 调试模式下创建调试上下文
+func contextFlags() int {
+	flags := sdl.GL_CONTEXT_FORWARD_COMPATIBLE_FLAG
+	if gldebug.Enabled {
+		flags |= sdl.GL_CONTEXT_DEBUG_FLAG
+	}
+	return flags
+}
+
+// Samples returns the number of samples of the default framebuffer, 0 if it is not multisampled.
+func (platform *SDL) Samples() int32 {
+	buffers, err := sdl.GLGetAttribute(sdl.GL_MULTISAMPLEBUFFERS)
+	if err != nil || buffers == 0 {
+		return 0
+	}
+	samples, err := sdl.GLGetAttribute(sdl.GL_MULTISAMPLESAMPLES)
+	if err != nil {
+		return 0
+	}
+	return int32(samples)
+}
+
 // Dispose cleans up the resources.
 func (platform *SDL) Dispose() {
+	for id, gamepad := range platform.gamepads {
+		gamepad.Close()
+		delete(platform.gamepads, id)
+	}
 	if platform.window != nil {
 		_ = platform.window.Destroy()
 		platform.window = nil
@@ -114,6 +178,17 @@ func (platform *SDL) ProcessEvents() {
 	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 		platform.processEvent(event)
 	}
+	platform.input.BeginFrame()
+}
+
+// SetEvents sets the bus window resize and key events are published to.
+func (platform *SDL) SetEvents(events *event.Bus) {
+	platform.events = events
+}
+
+// Input returns the keyboard and mouse state of the current frame.
+func (platform *SDL) Input() *input.Input {
+	return platform.input
 }
 
 // DisplaySize returns the dimension of the display.
@@ -128,11 +203,41 @@ func (platform *SDL) FramebufferSize() [2]float32 {
 	return [2]float32{float32(w), float32(h)}
 }
 
+// FramebufferScale returns the ratio of framebuffer pixels to window coordinates.
+// It is greater than 1 on high-DPI displays that scale window coordinates (e.g. macOS Retina).
+func (platform *SDL) FramebufferScale() float32 {
+	displaySize := platform.DisplaySize()
+	if displaySize[0] <= 0 {
+		return 1
+	}
+	return platform.FramebufferSize()[0] / displaySize[0]
+}
+
+// ContentScale returns the scale factor for UI content in window coordinates on the display the window currently is on.
+// Platforms that scale window coordinates already account for the display scale, there it is 1.
+// Otherwise it is derived from the display DPI.
+func (platform *SDL) ContentScale() float32 {
+	if platform.FramebufferScale() > 1 {
+		return 1
+	}
+	index, err := platform.window.GetDisplayIndex()
+	if err != nil {
+		return 1
+	}
+	ddpi, _, _, err := sdl.GetDisplayDPI(index)
+	if err != nil || ddpi <= 0 {
+		return 1
+	}
+	return max(ddpi/defaultDPI, 1)
+}
+
 // NewFrame marks the begin of a render pass. It forwards all current state to imgui.CurrentIO().
 func (platform *SDL) NewFrame() {
 	// Setup display size (every frame to accommodate for window resizing)
 	displaySize := platform.DisplaySize()
 	platform.imguiIO.SetDisplaySize(imgui.Vec2{X: displaySize[0], Y: displaySize[1]})
+	framebufferScale := platform.FramebufferScale()
+	platform.imguiIO.SetDisplayFrameBufferScale(imgui.Vec2{X: framebufferScale, Y: framebufferScale})
 
 	// Setup time step (we don't use SDL_GetTicks() because it is using millisecond resolution)
 	frequency := sdl.GetPerformanceFrequency()
@@ -145,6 +250,16 @@ func (platform *SDL) NewFrame() {
 	}
 	platform.time = currentTime
 
+	// The cursor is hidden in relative mode, keep it away from imgui windows.
+	if platform.RelativeMouse() {
+		platform.imguiIO.SetMousePosition(imgui.Vec2{X: -math.MaxFloat32, Y: -math.MaxFloat32})
+		for i := range platform.buttonsDown {
+			platform.imguiIO.SetMouseButtonDown(i, false)
+			platform.buttonsDown[i] = false
+		}
+		return
+	}
+
 	// If a mouse press event came, always pass it as "mouse held this frame", so we don't miss click-release events that are shorter than 1 frame.
 	x, y, state := sdl.GetMouseState()
 	platform.imguiIO.SetMousePosition(imgui.Vec2{X: float32(x), Y: float32(y)})
@@ -194,6 +309,13 @@ func (platform *SDL) processEvent(event sdl.Event) {
 	switch event.GetType() {
 	case sdl.QUIT:
 		platform.shouldStop = true
+	case sdl.WINDOWEVENT:
+		switch event.(*sdl.WindowEvent).Event {
+		case sdl.WINDOWEVENT_FOCUS_LOST:
+			platform.SetRelativeMouse(false)
+		case sdl.WINDOWEVENT_SIZE_CHANGED:
+			platform.publishResize()
+		}
 	case sdl.MOUSEWHEEL:
 		wheelEvent := event.(*sdl.MouseWheelEvent)
 		var deltaX, deltaY float32
@@ -208,6 +330,10 @@ func (platform *SDL) processEvent(event sdl.Event) {
 			deltaY--
 		}
 		platform.imguiIO.AddMouseWheelDelta(deltaX, deltaY)
+		platform.input.ScrollWheel(deltaX, deltaY)
+	case sdl.MOUSEMOTION:
+		motionEvent := event.(*sdl.MouseMotionEvent)
+		platform.input.MoveMouse(float32(motionEvent.X), float32(motionEvent.Y), float32(motionEvent.XRel), float32(motionEvent.YRel))
 	case sdl.MOUSEBUTTONDOWN:
 		buttonEvent := event.(*sdl.MouseButtonEvent)
 		switch buttonEvent.Button {
@@ -218,17 +344,110 @@ func (platform *SDL) processEvent(event sdl.Event) {
 		case sdl.BUTTON_MIDDLE:
 			platform.buttonsDown[mouseButtonTertiary] = true
 		}
+		platform.setInputMouseButton(buttonEvent, true)
+	case sdl.MOUSEBUTTONUP:
+		platform.setInputMouseButton(event.(*sdl.MouseButtonEvent), false)
+	case sdl.CONTROLLERDEVICEADDED:
+		deviceEvent := event.(*sdl.ControllerDeviceEvent)
+		if gamepad := sdl.GameControllerOpen(int(deviceEvent.Which)); gamepad != nil {
+			platform.gamepads[gamepad.Joystick().InstanceID()] = gamepad
+		}
+	case sdl.CONTROLLERDEVICEREMOVED:
+		deviceEvent := event.(*sdl.ControllerDeviceEvent)
+		if gamepad, ok := platform.gamepads[deviceEvent.Which]; ok {
+			gamepad.Close()
+			delete(platform.gamepads, deviceEvent.Which)
+		}
+	case sdl.CONTROLLERBUTTONDOWN, sdl.CONTROLLERBUTTONUP:
+		buttonEvent := event.(*sdl.ControllerButtonEvent)
+		platform.input.SetGamepadButton(input.GamepadButton(buttonEvent.Button), buttonEvent.State == sdl.PRESSED)
+	case sdl.CONTROLLERAXISMOTION:
+		axisEvent := event.(*sdl.ControllerAxisEvent)
+		platform.input.SetGamepadAxis(input.GamepadAxis(axisEvent.Axis), float32(axisEvent.Value)/32767)
+	case sdl.FINGERDOWN:
+		fingerEvent := event.(*sdl.TouchFingerEvent)
+		platform.fingers[touchFinger{fingerEvent.TouchID, fingerEvent.FingerID}] = struct{}{}
+		platform.input.SetTouchFingers(len(platform.fingers))
+	case sdl.FINGERUP:
+		fingerEvent := event.(*sdl.TouchFingerEvent)
+		delete(platform.fingers, touchFinger{fingerEvent.TouchID, fingerEvent.FingerID})
+		platform.input.SetTouchFingers(len(platform.fingers))
+		if len(platform.fingers) < 2 {
+			platform.gestureActive = false
+		}
+	case sdl.MULTIGESTURE:
+		platform.processGesture(event.(*sdl.MultiGestureEvent))
 	case sdl.TEXTINPUT:
 		inputEvent := event.(*sdl.TextInputEvent)
 		platform.imguiIO.AddInputCharacters(string(inputEvent.Text[:]))
 	case sdl.KEYDOWN:
 		keyEvent := event.(*sdl.KeyboardEvent)
+		// Escape only releases the mouse, it does not reach imgui or the scene
+		if keyEvent.Keysym.Scancode == sdl.SCANCODE_ESCAPE && platform.RelativeMouse() {
+			platform.SetRelativeMouse(false)
+			return
+		}
 		platform.imguiIO.KeyPress(int(keyEvent.Keysym.Scancode))
+		platform.input.SetKey(input.Key(keyEvent.Keysym.Scancode), true)
 		platform.updateKeyModifier()
+		platform.publishKey(keyEvent, true)
 	case sdl.KEYUP:
 		keyEvent := event.(*sdl.KeyboardEvent)
 		platform.imguiIO.KeyRelease(int(keyEvent.Keysym.Scancode))
+		platform.input.SetKey(input.Key(keyEvent.Keysym.Scancode), false)
 		platform.updateKeyModifier()
+		platform.publishKey(keyEvent, false)
+	case sdl.DROPFILE:
+		platform.publishDrop(event.(*sdl.DropEvent).File)
+	}
+}
+
+// processGesture converts the normalized pinch and center of a multi-finger gesture to window coordinates.
+func (platform *SDL) processGesture(gestureEvent *sdl.MultiGestureEvent) {
+	if gestureEvent.NumFingers < 2 {
+		return
+	}
+	size := platform.DisplaySize()
+	center := [2]float32{gestureEvent.X * size[0], gestureEvent.Y * size[1]}
+	var delta [2]float32
+	if platform.gestureActive {
+		delta = [2]float32{center[0] - platform.gestureCenter[0], center[1] - platform.gestureCenter[1]}
+	}
+	platform.gestureCenter, platform.gestureActive = center, true
+	// DDist is normalized to the window diagonal
+	diagonal := float32(math.Hypot(float64(size[0]), float64(size[1])))
+	platform.input.Gesture(delta[0], delta[1], gestureEvent.DDist*diagonal)
+}
+
+func (platform *SDL) publishResize() {
+	if platform.events != nil {
+		event.Publish(platform.events, event.WindowResized{Size: platform.DisplaySize(), FramebufferSize: platform.FramebufferSize()})
+	}
+}
+
+func (platform *SDL) publishKey(keyEvent *sdl.KeyboardEvent, down bool) {
+	if platform.events != nil {
+		event.Publish(platform.events, event.Key{Key: input.Key(keyEvent.Keysym.Scancode), Down: down, Repeat: keyEvent.Repeat != 0})
+	}
+}
+
+// publishDrop 拖放时窗口通常没有焦点, 鼠标移动事件可能没有送达, 直接查询光标位置
+func (platform *SDL) publishDrop(file string) {
+	if platform.events == nil || file == "" {
+		return
+	}
+	x, y, _ := sdl.GetMouseState()
+	event.Publish(platform.events, event.FileDropped{Path: file, Position: [2]float32{float32(x), float32(y)}})
+}
+
+func (platform *SDL) setInputMouseButton(event *sdl.MouseButtonEvent, down bool) {
+	switch event.Button {
+	case sdl.BUTTON_LEFT:
+		platform.input.SetMouseButton(input.MouseLeft, down)
+	case sdl.BUTTON_RIGHT:
+		platform.input.SetMouseButton(input.MouseRight, down)
+	case sdl.BUTTON_MIDDLE:
+		platform.input.SetMouseButton(input.MouseMiddle, down)
 	}
 }
 
@@ -248,6 +467,26 @@ func (platform *SDL) updateKeyModifier() {
 	platform.imguiIO.KeyAlt(mapModifier(sdl.KMOD_LALT, sdl.SCANCODE_LALT, sdl.KMOD_RALT, sdl.SCANCODE_RALT))
 }
 
+// SetRelativeMouse hides the cursor and reports only relative mouse motion while enabled.
+// Escape or losing the window focus releases it again.
+func (platform *SDL) SetRelativeMouse(enabled bool) {
+	if enabled == platform.input.RelativeMouse() {
+		return
+	}
+	if enabled && sdl.SetRelativeMouseMode(true) != 0 {
+		return
+	}
+	if !enabled {
+		_ = sdl.SetRelativeMouseMode(false)
+	}
+	platform.input.SetRelativeMouse(enabled)
+}
+
+// RelativeMouse returns true if the mouse is captured in relative mode.
+func (platform *SDL) RelativeMouse() bool {
+	return platform.input.RelativeMouse()
+}
+
 // ClipboardText returns the current clipboard text, if available.
 func (platform *SDL) ClipboardText() (string, error) {
 	return sdl.GetClipboardText()
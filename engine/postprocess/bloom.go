@@ -0,0 +1,140 @@
+package postprocess
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+)
+
+// BloomName 辉光在效果链中的名称
+const BloomName = "Bloom"
+
+// Bloom 辉光, 场景中亮度超过阈值的部分(自发光材质, 高光)提取到半分辨率的亮部缓冲
+// 经过多次可分离高斯模糊后叠加回场景, 需要放在效果链的最前面, 读取未经其他效果处理的 HDR 颜色
+type Bloom struct {
+	Threshold  float32 // 亮度阈值, 场景缓冲为浮点格式, 超过 1 的部分才会产生辉光
+	Knee       float32 // 阈值附近的软过渡宽度
+	Intensity  float32 // 叠加强度
+	Iterations int32   // 模糊次数, 每次包含水平和垂直两遍
+
+	brightShader    *shader.Shader
+	blurShader      *shader.Shader
+	compositeShader *shader.Shader
+	quad            *mesh.Mesh
+
+	// 亮部缓冲, 模糊时交替作为输入和输出
+	buffers [2]*framebuffer.FrameBuffer
+	width   int32
+	height  int32
+}
+
+func NewBloom() *Bloom {
+	return &Bloom{
+		Threshold:  1.0,
+		Knee:       0.5,
+		Intensity:  0.8,
+		Iterations: 4,
+	}
+}
+
+func (b *Bloom) Name() string {
+	return BloomName
+}
+
+func (b *Bloom) Init(width, height int32) error {
+	b.width, b.height = width, height
+	b.brightShader = &shader.Shader{VertFilePath: "./resource/shader/post.vert", FragFilePath: "./resource/shader/post_bloom_bright.frag"}
+	b.blurShader = &shader.Shader{VertFilePath: "./resource/shader/post.vert", FragFilePath: "./resource/shader/post_bloom_blur.frag"}
+	b.compositeShader = &shader.Shader{VertFilePath: "./resource/shader/post.vert", FragFilePath: "./resource/shader/post_bloom.frag"}
+	for _, s := range []*shader.Shader{b.brightShader, b.blurShader, b.compositeShader} {
+		if err := s.Init(); err != nil {
+			return err
+		}
+	}
+	b.quad = mesh.NewMeshQuad()
+
+	var err error
+	for i := range b.buffers {
+		if b.buffers[i], err = framebuffer.NewFrameBuffer(halfSize(width), halfSize(height), false, framebuffer.RGB16F); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func halfSize(size int32) int32 {
+	return max(size/2, 1)
+}
+
+func (b *Bloom) Resize(width, height int32) error {
+	b.width, b.height = width, height
+	for _, fb := range b.buffers {
+		if err := fb.Resize(halfSize(width), halfSize(height)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bloom) Apply(src, dst *framebuffer.FrameBuffer) {
+	// 提取亮部
+	b.buffers[0].Bind()
+	b.brightShader.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, src.ColorTexture(0))
+	b.brightShader.SetUniform("gScreenTexture", 0)
+	b.brightShader.SetUniform("gThreshold", b.Threshold)
+	b.brightShader.SetUniform("gKnee", b.Knee)
+	b.quad.Draw(b.brightShader.Program)
+
+	// 水平和垂直交替模糊, 偶数遍之后结果回到 buffers[0]
+	texel := mgl32.Vec2{1 / float32(b.buffers[0].Width), 1 / float32(b.buffers[0].Height)}
+	b.blurShader.Use()
+	b.blurShader.SetUniform("gScreenTexture", 0)
+	for i := 0; i < int(max(b.Iterations, 1))*2; i++ {
+		b.buffers[(i+1)%2].Bind()
+		gl.BindTexture(gl.TEXTURE_2D, b.buffers[i%2].ColorTexture(0))
+		direction := mgl32.Vec2{texel.X(), 0}
+		if i%2 == 1 {
+			direction = mgl32.Vec2{0, texel.Y()}
+		}
+		b.blurShader.SetUniform("gDirection", direction)
+		b.quad.Draw(b.blurShader.Program)
+	}
+
+	// 叠加到场景, 恢复 Chain 绑定的目标缓冲
+	if dst != nil {
+		dst.Bind()
+	} else {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		gl.Viewport(0, 0, b.width, b.height)
+	}
+	b.compositeShader.Use()
+	gl.ActiveTexture(gl.TEXTURE1)
+	gl.BindTexture(gl.TEXTURE_2D, b.buffers[0].ColorTexture(0))
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, src.ColorTexture(0))
+	b.compositeShader.SetUniform("gScreenTexture", 0)
+	b.compositeShader.SetUniform("gBloomTexture", 1)
+	b.compositeShader.SetUniform("gIntensity", b.Intensity)
+	b.quad.Draw(b.compositeShader.Program)
+	b.compositeShader.UnUse()
+}
+
+func (b *Bloom) Dispose() {
+	for _, s := range []*shader.Shader{b.brightShader, b.blurShader, b.compositeShader} {
+		if s != nil && s.Program != 0 {
+			s.Dispose()
+		}
+	}
+	if b.quad != nil {
+		b.quad.Dispose()
+	}
+	for _, fb := range b.buffers {
+		if fb != nil {
+			fb.Dispose()
+		}
+	}
+}
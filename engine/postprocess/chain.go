@@ -0,0 +1,250 @@
+package postprocess
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+)
+
+// Entry 效果链中的一项
+type Entry struct {
+	Effect  PostEffect
+	Enabled bool
+}
+
+// Chain 有序的后处理效果链
+// 有启用的效果时, 场景先渲染到 sceneBuffer, 再依次经过每个效果, 最后一个效果直接输出到默认帧缓冲
+type Chain struct {
+	width  int32
+	height int32
+
+	entries []*Entry
+
+	sceneBuffer *framebuffer.FrameBuffer
+	msaaBuffer  *framebuffer.FrameBuffer
+	samples     int32
+	pingPong    [2]*framebuffer.FrameBuffer
+	copyEffect  *ShaderEffect
+
+	// 当前相机对效果开关的覆盖, 为空时使用效果链的设置
+	override func(name string, enabled bool) bool
+}
+
+func NewChain(width, height int32) (*Chain, error) {
+	c := &Chain{
+		width:      width,
+		height:     height,
+		entries:    make([]*Entry, 0),
+		copyEffect: NewShaderEffect("Copy", "./resource/shader/post_copy.frag"),
+	}
+
+	var err error
+	if c.sceneBuffer, err = framebuffer.NewFrameBuffer(width, height, true, framebuffer.RGBA16F); err != nil {
+		return nil, fmt.Errorf("post process scene buffer: %w", err)
+	}
+	for i := range c.pingPong {
+		if c.pingPong[i], err = framebuffer.NewFrameBuffer(width, height, false, framebuffer.RGBA16F); err != nil {
+			return nil, fmt.Errorf("post process buffer: %w", err)
+		}
+	}
+	if err = c.copyEffect.Init(width, height); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add 添加效果到效果链末尾
+func (c *Chain) Add(effect PostEffect, enabled bool) error {
+	if err := effect.Init(c.width, c.height); err != nil {
+		// Init 失败时可能已经创建了部分资源, 着色器也可能已经使用替代程序并加入热重载
+		effect.Dispose()
+		return fmt.Errorf("failed to initialize post effect %s: %w", effect.Name(), err)
+	}
+	c.entries = append(c.entries, &Entry{Effect: effect, Enabled: enabled})
+	return nil
+}
+
+func (c *Chain) Remove(name string) {
+	for i, entry := range c.entries {
+		if entry.Effect.Name() == name {
+			entry.Effect.Dispose()
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *Chain) Get(name string) PostEffect {
+	for _, entry := range c.entries {
+		if entry.Effect.Name() == name {
+			return entry.Effect
+		}
+	}
+	return nil
+}
+
+func (c *Chain) SetEnabled(name string, enabled bool) {
+	for _, entry := range c.entries {
+		if entry.Effect.Name() == name {
+			entry.Enabled = enabled
+		}
+	}
+}
+
+// Enabled 指定名称的效果对当前相机是否启用
+func (c *Chain) Enabled(name string) bool {
+	for _, entry := range c.entries {
+		if entry.Effect.Name() == name {
+			return c.enabled(entry)
+		}
+	}
+	return false
+}
+
+// SetOverride 设置当前相机对效果开关的覆盖, 参数为效果名和效果链中的开关, 返回实际是否启用
+func (c *Chain) SetOverride(override func(name string, enabled bool) bool) {
+	c.override = override
+}
+
+func (c *Chain) enabled(entry *Entry) bool {
+	if c.override == nil {
+		return entry.Enabled
+	}
+	return c.override(entry.Effect.Name(), entry.Enabled)
+}
+
+func (c *Chain) Entries() []*Entry {
+	return c.entries
+}
+
+// Active 是否有启用的效果, 开启多重采样时也需要经过效果链解析
+func (c *Chain) Active() bool {
+	if c.msaaBuffer != nil {
+		return true
+	}
+	for _, entry := range c.entries {
+		if c.enabled(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Chain) Resize(width, height int32) error {
+	if width == c.width && height == c.height {
+		return nil
+	}
+	c.width, c.height = width, height
+
+	if err := c.sceneBuffer.Resize(width, height); err != nil {
+		return err
+	}
+	if c.msaaBuffer != nil {
+		if err := c.msaaBuffer.Resize(width, height); err != nil {
+			return err
+		}
+	}
+	for _, fb := range c.pingPong {
+		if err := fb.Resize(width, height); err != nil {
+			return err
+		}
+	}
+	for _, entry := range c.entries {
+		if err := entry.Effect.Resize(width, height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetSamples 设置场景渲染的多重采样数, 0 表示不使用多重采样
+func (c *Chain) SetSamples(samples int32) error {
+	if samples == c.samples {
+		return nil
+	}
+	c.samples = samples
+
+	if c.msaaBuffer != nil {
+		c.msaaBuffer.Dispose()
+		c.msaaBuffer = nil
+	}
+	if samples <= 0 {
+		return nil
+	}
+
+	var err error
+	c.msaaBuffer, err = framebuffer.NewMultisampleFrameBuffer(c.width, c.height, samples, framebuffer.RGBA16F)
+	return err
+}
+
+// SceneBuffer 场景渲染目标, 需要深度的效果可以直接读取其深度纹理
+func (c *Chain) SceneBuffer() *framebuffer.FrameBuffer {
+	return c.sceneBuffer
+}
+
+// Begin 将场景渲染重定向到离屏缓冲
+func (c *Chain) Begin(clearColor mgl32.Vec3) {
+	if c.msaaBuffer != nil {
+		c.msaaBuffer.Bind()
+	} else {
+		c.sceneBuffer.Bind()
+	}
+	gl.ClearColor(clearColor[0], clearColor[1], clearColor[2], 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+}
+
+// End 依次执行启用的效果, 结果输出到默认帧缓冲
+func (c *Chain) End() {
+	if c.msaaBuffer != nil {
+		c.msaaBuffer.BlitTo(c.sceneBuffer)
+	}
+
+	enabled := make([]PostEffect, 0, len(c.entries))
+	for _, entry := range c.entries {
+		if c.enabled(entry) {
+			enabled = append(enabled, entry.Effect)
+		}
+	}
+	if len(enabled) == 0 {
+		enabled = append(enabled, c.copyEffect)
+	}
+
+	lastDepthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+
+	src := c.sceneBuffer
+	for i, effect := range enabled {
+		var dst *framebuffer.FrameBuffer
+		if i < len(enabled)-1 {
+			dst = c.pingPong[i%2]
+			dst.Bind()
+		} else {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+			gl.Viewport(0, 0, c.width, c.height)
+		}
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+
+		effect.Apply(src, dst)
+		src = dst
+	}
+
+	if lastDepthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+func (c *Chain) Dispose() {
+	for _, entry := range c.entries {
+		entry.Effect.Dispose()
+	}
+	c.copyEffect.Dispose()
+	c.sceneBuffer.Dispose()
+	if c.msaaBuffer != nil {
+		c.msaaBuffer.Dispose()
+	}
+	for _, fb := range c.pingPong {
+		fb.Dispose()
+	}
+}
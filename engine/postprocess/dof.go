@@ -0,0 +1,73 @@
+package postprocess
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+)
+
+const DepthOfFieldName = "Depth of Field"
+
+// DepthOfField 景深, 由场景深度计算弥散圆大小, 在圆盘内做散景模糊
+// 深度取自效果链的场景缓冲, 因此可以放在效果链的任意位置
+type DepthOfField struct {
+	*ShaderEffect
+
+	FocusDistance float32 // 对焦距离(世界单位)
+	Aperture      float32 // 光圈, 越大焦外越模糊
+	MaxBlur       float32 // 最大模糊半径(像素)
+	AutoFocus     bool    // 自动对焦到屏幕中心
+
+	scene  *framebuffer.FrameBuffer
+	width  int32
+	height int32
+
+	// 相机的近远裁剪面, 正交投影时深度缓冲与距离成线性关系
+	near, far    float32
+	orthographic bool
+}
+
+func NewDepthOfField(scene *framebuffer.FrameBuffer) *DepthOfField {
+	d := &DepthOfField{
+		ShaderEffect:  NewShaderEffect(DepthOfFieldName, "./resource/shader/post_dof.frag"),
+		FocusDistance: 100,
+		Aperture:      2,
+		MaxBlur:       12,
+		scene:         scene,
+		near:          config.Config.ClipNear,
+		far:           config.Config.ClipFar,
+	}
+	d.SetUniforms = func(s *shader.Shader) {
+		gl.ActiveTexture(gl.TEXTURE1)
+		gl.BindTexture(gl.TEXTURE_2D, d.scene.DepthTexture)
+		gl.ActiveTexture(gl.TEXTURE0)
+		s.SetUniform("gDepthTexture", 1)
+
+		s.SetUniform("gTexelSize", mgl32.Vec2{1 / float32(d.width), 1 / float32(d.height)})
+		s.SetUniform("gNear", d.near)
+		s.SetUniform("gFar", d.far)
+		s.SetUniform("gFocusDistance", d.FocusDistance)
+		s.SetUniform("gAperture", d.Aperture)
+		s.SetUniform("gMaxBlur", d.MaxBlur)
+		s.SetUniform("gAutoFocus", d.AutoFocus)
+		s.SetUniform("gOrthographic", d.orthographic)
+	}
+	return d
+}
+
+// SetProjection 设置相机的投影参数, 用于把深度缓冲转换为距离
+func (d *DepthOfField) SetProjection(near, far float32, orthographic bool) {
+	d.near, d.far, d.orthographic = near, far, orthographic
+}
+
+func (d *DepthOfField) Init(width, height int32) error {
+	d.width, d.height = width, height
+	return d.ShaderEffect.Init(width, height)
+}
+
+func (d *DepthOfField) Resize(width, height int32) error {
+	d.width, d.height = width, height
+	return d.ShaderEffect.Resize(width, height)
+}
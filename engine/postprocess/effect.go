@@ -0,0 +1,80 @@
+package postprocess
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+)
+
+// PostEffect 后处理效果
+// Apply 调用前目标缓冲已由 Chain 绑定(dst 为 nil 表示默认帧缓冲), 效果只需采样 src 并绘制全屏四边形
+type PostEffect interface {
+	Name() string
+	Init(width, height int32) error
+	Resize(width, height int32) error
+	Apply(src, dst *framebuffer.FrameBuffer)
+	Dispose()
+}
+
+// ShaderEffect 单个片元着色器即可完成的效果, 具体效果通过 SetUniforms 设置参数
+type ShaderEffect struct {
+	name     string
+	fragFile string
+
+	Shader *shader.Shader
+	quad   *mesh.Mesh
+
+	SetUniforms func(s *shader.Shader)
+}
+
+func NewShaderEffect(name, fragFile string) *ShaderEffect {
+	return &ShaderEffect{
+		name:     name,
+		fragFile: fragFile,
+	}
+}
+
+func (e *ShaderEffect) Name() string {
+	return e.name
+}
+
+func (e *ShaderEffect) Init(width, height int32) error {
+	e.Shader = &shader.Shader{
+		VertFilePath: "./resource/shader/post.vert",
+		FragFilePath: e.fragFile,
+	}
+	if err := e.Shader.Init(); err != nil {
+		return err
+	}
+	e.quad = mesh.NewMeshQuad()
+	return nil
+}
+
+func (e *ShaderEffect) Resize(width, height int32) error {
+	return nil
+}
+
+func (e *ShaderEffect) Apply(src, dst *framebuffer.FrameBuffer) {
+	e.Shader.Use()
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, src.ColorTexture(0))
+	e.Shader.SetUniform("gScreenTexture", 0)
+
+	if e.SetUniforms != nil {
+		e.SetUniforms(e.Shader)
+	}
+
+	e.quad.Draw(e.Shader.Program)
+	e.Shader.UnUse()
+}
+
+func (e *ShaderEffect) Dispose() {
+	if e.Shader != nil && e.Shader.Program != 0 {
+		e.Shader.Dispose()
+	}
+	if e.quad != nil {
+		e.quad.Dispose()
+	}
+}
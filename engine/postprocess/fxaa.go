@@ -0,0 +1,9 @@
+package postprocess
+
+// FXAAName FXAA效果在效果链中的名称, 由抗锯齿设置控制启用
+const FXAAName = "FXAA"
+
+// NewFXAA 快速近似抗锯齿, 应放在效果链末尾
+func NewFXAA() *ShaderEffect {
+	return NewShaderEffect(FXAAName, "./resource/shader/post_fxaa.frag")
+}
@@ -0,0 +1,42 @@
+package postprocess
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+)
+
+// MotionBlurName 运动模糊在效果链中的名称, 启用时需要先绘制速度缓冲
+const MotionBlurName = "Motion Blur"
+
+// MotionBlur 运动模糊, 沿速度缓冲中的屏幕空间速度方向采样, 同时包含相机和物体运动
+type MotionBlur struct {
+	*ShaderEffect
+
+	Intensity float32 // 速度缩放, 相当于快门时间
+	Samples   int32   // 沿速度方向的采样数
+	MaxLength float32 // 最大模糊长度(纹理坐标)
+
+	velocity *framebuffer.FrameBuffer
+}
+
+func NewMotionBlur(velocity *framebuffer.FrameBuffer) *MotionBlur {
+	m := &MotionBlur{
+		ShaderEffect: NewShaderEffect(MotionBlurName, "./resource/shader/post_motion_blur.frag"),
+		Intensity:    1.0,
+		Samples:      12,
+		MaxLength:    0.05,
+		velocity:     velocity,
+	}
+	m.SetUniforms = func(s *shader.Shader) {
+		gl.ActiveTexture(gl.TEXTURE1)
+		gl.BindTexture(gl.TEXTURE_2D, m.velocity.ColorTexture(0))
+		gl.ActiveTexture(gl.TEXTURE0)
+		s.SetUniform("gVelocityTexture", 1)
+
+		s.SetUniform("gIntensity", m.Intensity)
+		s.SetUniform("gSamples", m.Samples)
+		s.SetUniform("gMaxLength", m.MaxLength)
+	}
+	return m
+}
@@ -0,0 +1,27 @@
+package postprocess
+
+import "github.com/huangxiaobo/toy-engine/engine/shader"
+
+// Vignette 暗角
+type Vignette struct {
+	*ShaderEffect
+
+	Radius    float32 // 暗角开始的半径(0~1)
+	Softness  float32 // 过渡宽度
+	Intensity float32
+}
+
+func NewVignette() *Vignette {
+	v := &Vignette{
+		ShaderEffect: NewShaderEffect("Vignette", "./resource/shader/post_vignette.frag"),
+		Radius:       0.75,
+		Softness:     0.45,
+		Intensity:    0.8,
+	}
+	v.SetUniforms = func(s *shader.Shader) {
+		s.SetUniform("gRadius", v.Radius)
+		s.SetUniform("gSoftness", v.Softness)
+		s.SetUniform("gIntensity", v.Intensity)
+	}
+	return v
+}
@@ -0,0 +1,248 @@
+package profiler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+)
+
+const (
+	// FrameScope 每帧的根范围, 其他范围都是它的子范围
+	FrameScope         = "Frame"
+	DefaultAlertFrames = 30
+	MaxAlertFrames     = 600
+	// GPU 时间戳延迟读取的帧数, 避免等待 GPU
+	gpuLatency = 3
+)
+
+// Scope 命名的计时范围, 子范围的时间包含在父范围内
+type Scope struct {
+	Name     string
+	Depth    int
+	Parent   *Scope
+	Children []*Scope
+
+	// 最近一帧的 CPU 时间和最近读到的 GPU 时间, 毫秒
+	CPU float32
+	GPU float32
+	// 预算, 毫秒, 0 表示不限制
+	Budget float32
+	// 连续超出预算的帧数
+	Over  int
+	Alert bool
+
+	timed bool
+	start time.Time
+	query uint32
+}
+
+// Time CPU 和 GPU 时间中较大的一个, 与预算比较
+func (s *Scope) Time() float32 {
+	return max(s.CPU, s.GPU)
+}
+
+// Path 从根范围开始的路径
+func (s *Scope) Path() string {
+	if s.Parent == nil {
+		return s.Name
+	}
+	return s.Parent.Path() + "/" + s.Name
+}
+
+type gpuSample struct {
+	scope      *Scope
+	begin, end uint32
+}
+
+// Profiler 按帧记录嵌套范围的 CPU 时间和 GPU 时间
+// 范围超出预算连续 AlertFrames 帧时记录警告并进入报警状态, 回到预算内时解除
+type Profiler struct {
+	Enabled     bool
+	AlertFrames int
+
+	root    *Scope
+	stack   []*Scope
+	running bool
+	// 尚未创建的范围的预算, 创建时应用
+	budgets map[string]float32
+
+	samples [gpuLatency + 1][]gpuSample
+	frame   int
+	queries []uint32
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		AlertFrames: DefaultAlertFrames,
+		root:        &Scope{Name: FrameScope},
+		budgets:     make(map[string]float32),
+	}
+}
+
+// SetBudget 设置所有名为 name 的范围的预算, ms 为 0 时取消
+func (p *Profiler) SetBudget(name string, ms float32) {
+	p.budgets[name] = max(ms, 0)
+	p.walk(p.root, func(s *Scope) {
+		if s.Name == name {
+			s.Budget = max(ms, 0)
+		}
+	})
+}
+
+// Scopes 按树的先序排列的所有范围
+func (p *Profiler) Scopes() []*Scope {
+	var scopes []*Scope
+	p.walk(p.root, func(s *Scope) {
+		scopes = append(scopes, s)
+	})
+	return scopes
+}
+
+// Alerts 处于报警状态的范围
+func (p *Profiler) Alerts() []*Scope {
+	var alerts []*Scope
+	p.walk(p.root, func(s *Scope) {
+		if s.Alert {
+			alerts = append(alerts, s)
+		}
+	})
+	return alerts
+}
+
+func (p *Profiler) walk(s *Scope, fn func(s *Scope)) {
+	fn(s)
+	for _, child := range s.Children {
+		p.walk(child, fn)
+	}
+}
+
+// BeginFrame 读取之前帧的 GPU 时间并开始根范围, 帧内修改 Enabled 从下一帧开始生效
+func (p *Profiler) BeginFrame() {
+	p.running = p.Enabled
+	if !p.running {
+		return
+	}
+	p.frame = (p.frame + 1) % len(p.samples)
+	p.resolve(p.frame)
+	p.stack = p.stack[:0]
+	p.walk(p.root, func(s *Scope) {
+		s.timed = false
+	})
+	p.begin(p.root)
+}
+
+// EndFrame 结束根范围并检查预算
+func (p *Profiler) EndFrame() {
+	if !p.running {
+		return
+	}
+	for len(p.stack) > 0 {
+		p.End()
+	}
+	p.walk(p.root, p.check)
+	p.running = false
+}
+
+// Begin 在当前范围下开始名为 name 的子范围, 必须与 End 成对调用
+func (p *Profiler) Begin(name string) {
+	if !p.running || len(p.stack) == 0 {
+		return
+	}
+	parent := p.stack[len(p.stack)-1]
+	var scope *Scope
+	for _, child := range parent.Children {
+		if child.Name == name {
+			scope = child
+			break
+		}
+	}
+	if scope == nil {
+		scope = &Scope{Name: name, Depth: parent.Depth + 1, Parent: parent, Budget: p.budgets[name]}
+		parent.Children = append(parent.Children, scope)
+	}
+	p.begin(scope)
+}
+
+func (p *Profiler) begin(scope *Scope) {
+	scope.timed = true
+	scope.start = time.Now()
+	scope.query = p.timestamp()
+	p.stack = append(p.stack, scope)
+}
+
+// End 结束最近开始的范围
+func (p *Profiler) End() {
+	if !p.running || len(p.stack) == 0 {
+		return
+	}
+	scope := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	scope.CPU = float32(time.Since(scope.start).Seconds() * 1000)
+	p.samples[p.frame] = append(p.samples[p.frame], gpuSample{scope: scope, begin: scope.query, end: p.timestamp()})
+}
+
+// timestamp 发起 GPU 时间戳查询, 查询对象循环使用
+func (p *Profiler) timestamp() uint32 {
+	var query uint32
+	if n := len(p.queries); n > 0 {
+		query, p.queries = p.queries[n-1], p.queries[:n-1]
+	} else {
+		gl.GenQueries(1, &query)
+	}
+	gl.QueryCounter(query, gl.TIMESTAMP)
+	return query
+}
+
+// resolve 读取 frame 槽位中的时间戳, 结果还不可用时丢弃这一帧的 GPU 时间
+func (p *Profiler) resolve(frame int) {
+	for _, sample := range p.samples[frame] {
+		var available int32
+		gl.GetQueryObjectiv(sample.end, gl.QUERY_RESULT_AVAILABLE, &available)
+		if available != 0 {
+			var begin, end uint64
+			gl.GetQueryObjectui64v(sample.begin, gl.QUERY_RESULT, &begin)
+			gl.GetQueryObjectui64v(sample.end, gl.QUERY_RESULT, &end)
+			if end >= begin {
+				sample.scope.GPU = float32(float64(end-begin) / 1e6)
+			}
+		}
+		p.queries = append(p.queries, sample.begin, sample.end)
+	}
+	p.samples[frame] = p.samples[frame][:0]
+}
+
+// check 更新连续超出预算的帧数, 本帧没有执行的范围重新计数
+func (p *Profiler) check(s *Scope) {
+	if !s.timed {
+		s.Over, s.Alert = 0, false
+		return
+	}
+	if s.Budget <= 0 || s.Time() <= s.Budget {
+		if s.Alert {
+			logger.Info(fmt.Sprintf("profiler: %s back within budget %.2fms (%.2fms)", s.Path(), s.Budget, s.Time()))
+		}
+		s.Over, s.Alert = 0, false
+		return
+	}
+	s.Over++
+	if !s.Alert && s.Over >= max(p.AlertFrames, 1) {
+		s.Alert = true
+		logger.Warn(fmt.Sprintf("profiler: %s over budget %.2fms for %d frames (cpu %.2fms, gpu %.2fms)",
+			s.Path(), s.Budget, s.Over, s.CPU, s.GPU))
+	}
+}
+
+func (p *Profiler) Dispose() {
+	for i := range p.samples {
+		for _, sample := range p.samples[i] {
+			p.queries = append(p.queries, sample.begin, sample.end)
+		}
+		p.samples[i] = nil
+	}
+	if len(p.queries) > 0 {
+		gl.DeleteQueries(int32(len(p.queries)), &p.queries[0])
+		p.queries = nil
+	}
+}
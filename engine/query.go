@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/registry"
+)
+
+// FindById 按 Id 查找场景对象
+func (w *World) FindById(id string) (*registry.Entity, bool) {
+	return w.registry.Get(id)
+}
+
+// FindByName 按名称查找场景对象, 同名时返回第一个
+func (w *World) FindByName(name string) (*registry.Entity, bool) {
+	return w.registry.ByName(name)
+}
+
+// FindByTag 带有标签 tag 的场景对象, 标签在模型配置的 <tags> 中设置
+func (w *World) FindByTag(tag string) []*registry.Entity {
+	return w.registry.ByTag(tag)
+}
+
+// FindWithin 包围盒与以 center 为中心, radius 为半径的球相交的对象, 由近及远排列
+// 使用上一次更新后的空间索引, 本帧更新之前移动的对象要到下一帧才能查到新位置
+func (w *World) FindWithin(center mgl32.Vec3, radius float32) []*registry.Entity {
+	return w.registry.Within(center, radius)
+}
+
+// ForEachOfType 按加入顺序对每个类型为 T 的场景对象调用 fn, T 可以是具体类型或接口
+// 例如 ForEachOfType(w, func(t *terrain.Terrain) {...})
+func ForEachOfType[T any](w *World, fn func(obj T)) {
+	for _, e := range w.registry.Entities() {
+		if obj, ok := e.Obj.(T); ok {
+			fn(obj)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package registry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+)
+
+const (
+	// DefaultCellSize 空间网格单元在 XZ 平面上的边长
+	DefaultCellSize = 16
+	// 包围盒覆盖的单元数超过该值时不放入网格, 每次查询都单独检查(地形, 地面)
+	maxEntityCells = 64
+)
+
+// Entity 场景中的对象及其名称, Id 和标签
+type Entity struct {
+	Id   string
+	Name string
+	Tags []string
+	Obj  model.RenderObj
+
+	bounds  geometry.AABB
+	bounded bool
+}
+
+// HasTag 是否带有标签 tag
+func (e *Entity) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds 最近一次 Rebuild 时的世界空间包围盒, 没有包围盒的对象返回 false
+func (e *Entity) Bounds() (geometry.AABB, bool) {
+	return e.bounds, e.bounded
+}
+
+type cell [2]int32
+
+// Registry 按加入顺序保存场景对象, 提供按 Id, 标签查询
+// 和基于 XZ 平面均匀网格的范围查询, 网格在对象移动后通过 Rebuild 更新
+type Registry struct {
+	CellSize float32
+
+	entities []*Entity
+	byId     map[string]*Entity
+	byTag    map[string][]*Entity
+
+	grid  map[cell][]*Entity
+	large []*Entity
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		CellSize: DefaultCellSize,
+		byId:     make(map[string]*Entity),
+		byTag:    make(map[string][]*Entity),
+		grid:     make(map[cell][]*Entity),
+	}
+}
+
+// Add 加入对象, Id 为空或重复时仍然加入, 但只有第一个可以通过 Get 查到
+func (r *Registry) Add(id, name string, tags []string, obj model.RenderObj) *Entity {
+	e := &Entity{Id: id, Name: name, Tags: tags, Obj: obj}
+	r.entities = append(r.entities, e)
+	if _, ok := r.byId[id]; id != "" && !ok {
+		r.byId[id] = e
+	}
+	for _, tag := range tags {
+		r.byTag[tag] = append(r.byTag[tag], e)
+	}
+	r.insert(e)
+	return e
+}
+
+// Remove 移除对象, 返回是否存在
+func (r *Registry) Remove(obj model.RenderObj) bool {
+	index := -1
+	for i, e := range r.entities {
+		if e.Obj == obj {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return false
+	}
+	e := r.entities[index]
+	r.entities = append(r.entities[:index], r.entities[index+1:]...)
+	if r.byId[e.Id] == e {
+		delete(r.byId, e.Id)
+		for _, other := range r.entities {
+			if other.Id == e.Id {
+				r.byId[e.Id] = other
+				break
+			}
+		}
+	}
+	for _, tag := range e.Tags {
+		r.byTag[tag] = without(r.byTag[tag], e)
+		if len(r.byTag[tag]) == 0 {
+			delete(r.byTag, tag)
+		}
+	}
+	r.Rebuild()
+	return true
+}
+
+func without(entities []*Entity, e *Entity) []*Entity {
+	out := entities[:0]
+	for _, other := range entities {
+		if other != e {
+			out = append(out, other)
+		}
+	}
+	return out
+}
+
+// Entities 所有对象, 按加入顺序
+func (r *Registry) Entities() []*Entity {
+	return r.entities
+}
+
+// Get 按 Id 查找对象
+func (r *Registry) Get(id string) (*Entity, bool) {
+	e, ok := r.byId[id]
+	return e, ok
+}
+
+// ByName 第一个名称为 name 的对象
+func (r *Registry) ByName(name string) (*Entity, bool) {
+	for _, e := range r.entities {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// ByTag 带有标签 tag 的对象, 按加入顺序
+func (r *Registry) ByTag(tag string) []*Entity {
+	return r.byTag[tag]
+}
+
+// Rebuild 重新读取所有对象的包围盒并重建网格, 每帧在更新之后调用
+func (r *Registry) Rebuild() {
+	clear(r.grid)
+	r.large = r.large[:0]
+	for _, e := range r.entities {
+		r.insert(e)
+	}
+}
+
+func (r *Registry) insert(e *Entity) {
+	e.bounded = false
+	bounded, ok := e.Obj.(model.BoundedObj)
+	if !ok {
+		return
+	}
+	e.bounds = bounded.WorldBounds()
+	if e.bounds.IsEmpty() {
+		return
+	}
+	e.bounded = true
+
+	lo, hi := r.cellOf(e.bounds.Min), r.cellOf(e.bounds.Max)
+	if int64(hi[0]-lo[0]+1)*int64(hi[1]-lo[1]+1) > maxEntityCells {
+		r.large = append(r.large, e)
+		return
+	}
+	for x := lo[0]; x <= hi[0]; x++ {
+		for z := lo[1]; z <= hi[1]; z++ {
+			r.grid[cell{x, z}] = append(r.grid[cell{x, z}], e)
+		}
+	}
+}
+
+func (r *Registry) cellOf(p mgl32.Vec3) cell {
+	size := max(r.CellSize, 0.001)
+	return cell{int32(math.Floor(float64(p.X() / size))), int32(math.Floor(float64(p.Z() / size)))}
+}
+
+// Within 包围盒与以 center 为中心, radius 为半径的球相交的对象, 按包围盒中心到 center 的距离由近及远排列
+// 没有包围盒的对象不参与范围查询
+func (r *Registry) Within(center mgl32.Vec3, radius float32) []*Entity {
+	sphere := geometry.Sphere{Center: center, Radius: max(radius, 0)}
+	seen := make(map[*Entity]bool)
+	var found []*Entity
+	test := func(e *Entity) {
+		if seen[e] {
+			return
+		}
+		seen[e] = true
+		if sphere.IntersectsAABB(e.bounds) {
+			found = append(found, e)
+		}
+	}
+
+	offset := mgl32.Vec3{sphere.Radius, 0, sphere.Radius}
+	lo, hi := r.cellOf(center.Sub(offset)), r.cellOf(center.Add(offset))
+	if int64(hi[0]-lo[0]+1)*int64(hi[1]-lo[1]+1) > int64(len(r.entities)) {
+		// 查询范围覆盖的单元比对象还多, 直接遍历所有对象
+		for _, e := range r.entities {
+			if e.bounded {
+				test(e)
+			}
+		}
+	} else {
+		for x := lo[0]; x <= hi[0]; x++ {
+			for z := lo[1]; z <= hi[1]; z++ {
+				for _, e := range r.grid[cell{x, z}] {
+					test(e)
+				}
+			}
+		}
+		for _, e := range r.large {
+			test(e)
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].bounds.Center().Sub(center).Len() < found[j].bounds.Center().Sub(center).Len()
+	})
+	return found
+}
@@ -0,0 +1,405 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/occlusion"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+var (
+	wireframeColor = mgl32.Vec3{0.9, 0.9, 0.9}
+	solidColor     = mgl32.Vec3{0.8, 0.8, 0.8}
+	// 叠加在正常渲染上的线框颜色
+	wireframeOverlayColor = mgl32.Vec3{0.05, 0.05, 0.05}
+)
+
+// RenderQueue 每帧收集需要绘制的对象, 并根据视口着色模式决定使用对象自身的technique还是覆盖technique
+type RenderQueue struct {
+	items []model.RenderObj
+	// 透明对象, 在不透明对象之后按观察空间深度由远及近绘制
+	transparent []model.RenderObj
+	// 最上层的对象, 在所有对象之后关闭深度测试绘制
+	onTop []model.RenderObj
+
+	unlitEffect *technique.UnlitTechnique
+	// 深度预渲染使用的只写深度的着色器
+	depthEffect *technique.BaseTechnique
+	// 调试视图的着色器变体
+	debugEffects map[config.DebugView]*technique.DebugTechnique
+
+	// 遮挡剔除, 为空或未开启时不使用条件渲染
+	culler *occlusion.Culler
+
+	// 相机的近远裁剪面, 深度调试视图使用
+	near, far float32
+}
+
+func NewRenderQueue() (*RenderQueue, error) {
+	q := &RenderQueue{
+		items:       make([]model.RenderObj, 0),
+		transparent: make([]model.RenderObj, 0),
+		onTop:       make([]model.RenderObj, 0),
+		unlitEffect: &technique.UnlitTechnique{},
+		depthEffect: &technique.BaseTechnique{},
+		near:        config.Config.ClipNear,
+		far:         config.Config.ClipFar,
+	}
+
+	unlitShader := &shader.Shader{
+		VertFilePath: "./resource/shader/unlit.vert",
+		FragFilePath: "./resource/shader/unlit.frag",
+	}
+	if err := unlitShader.Init(); err != nil {
+		return nil, err
+	}
+	q.unlitEffect.Init(unlitShader)
+
+	depthOnly, _ := shader.LookupBuiltin("depth-only")
+	depthShader := &shader.Shader{VertFilePath: depthOnly.Vert, FragFilePath: depthOnly.Frag}
+	if err := depthShader.Init(); err != nil {
+		return nil, err
+	}
+	q.depthEffect.Init(depthShader)
+
+	q.debugEffects = make(map[config.DebugView]*technique.DebugTechnique)
+	for _, view := range technique.DebugViewVariants() {
+		effect, err := technique.NewDebugTechnique(view)
+		if err != nil {
+			return nil, err
+		}
+		q.debugEffects[view] = effect
+	}
+
+	return q, nil
+}
+
+func (q *RenderQueue) SetOcclusionCuller(culler *occlusion.Culler) {
+	q.culler = culler
+}
+
+// SetClip 设置相机的近远裁剪面
+func (q *RenderQueue) SetClip(near, far float32) {
+	q.near, q.far = near, far
+}
+
+func (q *RenderQueue) Reset() {
+	q.items = q.items[:0]
+	q.transparent = q.transparent[:0]
+	q.onTop = q.onTop[:0]
+}
+
+func (q *RenderQueue) Push(obj model.RenderObj) {
+	if model.AlwaysOnTop(obj) {
+		q.onTop = append(q.onTop, obj)
+		return
+	}
+	if transparentObj, ok := obj.(model.TransparentObj); ok && transparentObj.Transparent() {
+		q.transparent = append(q.transparent, obj)
+		return
+	}
+	q.items = append(q.items, obj)
+}
+
+// Items 不透明对象, 透明对象不参与 SSAO 和遮挡查询
+func (q *RenderQueue) Items() []model.RenderObj {
+	return q.items
+}
+
+func (q *RenderQueue) Transparent() []model.RenderObj {
+	return q.transparent
+}
+
+// OnTop 最上层的对象
+func (q *RenderQueue) OnTop() []model.RenderObj {
+	return q.onTop
+}
+
+// sortByOrder 按对象的绘制顺序稳定排序, 顺序相同时保持加入顺序
+func sortByOrder(items []model.RenderObj) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return model.RenderOrderOf(items[i]) < model.RenderOrderOf(items[j])
+	})
+}
+
+// sortTransparent 先按绘制顺序, 再按包围盒中心的观察空间深度由远及近排序
+func (q *RenderQueue) sortTransparent(view mgl32.Mat4) {
+	depth := func(obj model.RenderObj) float32 {
+		var center mgl32.Vec3
+		if boundedObj, ok := obj.(model.BoundedObj); ok {
+			center = boundedObj.WorldBounds().Center()
+		}
+		// 观察空间中相机朝向 -z, z 越小越远
+		return view.Mul4x1(center.Vec4(1)).Z()
+	}
+	sort.SliceStable(q.transparent, func(i, j int) bool {
+		oi, oj := model.RenderOrderOf(q.transparent[i]), model.RenderOrderOf(q.transparent[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return depth(q.transparent[i]) < depth(q.transparent[j])
+	})
+}
+
+// Flush 按当前着色模式绘制队列中的对象
+func (q *RenderQueue) Flush(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	sortByOrder(q.items)
+	sortByOrder(q.onTop)
+	// 最上层的对象不受着色模式影响, 始终使用自身的technique
+	defer q.flushOnTop(projection, view, eyePosition, lights)
+
+	if config.DebugViewReplacesShading() {
+		q.flushDebug(projection, view, eyePosition, lights)
+		return
+	}
+
+	switch config.Config.ShadingMode {
+	case config.ShadingWireframe:
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+		q.flushUnlit(q.items, projection, view, eyePosition, lights, true)
+		q.flushUnlit(q.transparent, projection, view, eyePosition, lights, true)
+		gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+	case config.ShadingSolid:
+		q.flushUnlit(q.items, projection, view, eyePosition, lights, false)
+		q.flushUnlit(q.transparent, projection, view, eyePosition, lights, false)
+	default:
+		q.flushPasses(projection, view, eyePosition, lights)
+		q.flushTransparent(projection, view, eyePosition, lights)
+	}
+
+	if config.Config.DebugView == config.DebugViewWireframe {
+		q.flushWireframeOverlay(q.items, projection, view)
+		q.flushWireframeOverlay(q.transparent, projection, view)
+	}
+}
+
+// FlushReflection 完整渲染模式下绘制平面反射, 遮挡查询结果对应主视角, 反射中不使用
+// 对象已按主视角做过视锥剔除, 只出现在反射中的对象不会被绘制
+// 最上层的对象是辅助对象, 不出现在反射中
+func (q *RenderQueue) FlushReflection(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	modelMatrix := mgl32.Ident4()
+	sortByOrder(q.items)
+	for _, obj := range q.items {
+		obj.PreRender()
+		obj.Render(projection, modelMatrix, view, eyePosition, lights)
+		obj.PostRender()
+	}
+	q.flushTransparent(projection, view, eyePosition, lights)
+}
+
+// flushPasses 按阶段绘制不透明对象声明的 pass: 先绘制所有对象的深度预渲染, 再逐个对象绘制主 pass
+// 有深度预渲染的对象, 主 pass 没有指定深度比较时使用 LEQUAL, 只着色最前面的像素
+func (q *RenderQueue) flushPasses(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	for _, obj := range q.items {
+		for _, pass := range technique.PassesOfKind(obj, technique.PassDepthPrepass) {
+			q.renderPass(obj, pass, projection, view, eyePosition, lights)
+		}
+	}
+
+	occlusionCulling := q.culler != nil && config.Config.OcclusionCulling
+	for _, obj := range q.items {
+		prepassed := technique.HasPass(obj, technique.PassDepthPrepass)
+		if occlusionCulling {
+			q.culler.BeginRender(obj)
+		}
+		for _, pass := range technique.PassesOfKind(obj, technique.PassMain) {
+			if prepassed && pass.State.DepthFunc == 0 {
+				pass.State.DepthFunc = gl.LEQUAL
+			}
+			q.renderPass(obj, pass, projection, view, eyePosition, lights)
+		}
+		if occlusionCulling {
+			q.culler.EndRender(obj)
+		}
+	}
+}
+
+// renderPass 按 pass 的状态绘制对象, 状态为零值时不修改 GL 状态
+// 指定了 technique 的 pass 绘制几何体, 否则主 pass 调用对象自身的 Render, 深度预渲染使用只写深度的着色器
+func (q *RenderQueue) renderPass(obj model.RenderObj, pass technique.Pass, projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	if pass.State != (technique.RenderState{}) {
+		restore := pass.State.Apply()
+		defer restore()
+	}
+
+	effect := pass.Technique
+	if effect == nil && pass.Kind == technique.PassDepthPrepass {
+		effect = q.depthEffect
+	}
+	if effect == nil {
+		modelMatrix := mgl32.Ident4()
+		obj.PreRender()
+		obj.Render(projection, modelMatrix, view, eyePosition, lights)
+		obj.PostRender()
+		return
+	}
+	geometryObj, ok := obj.(model.GeometryObj)
+	if !ok {
+		return
+	}
+	effect.Enable()
+	effect.SetProjectMatrix(&projection)
+	effect.SetViewMatrix(&view)
+	effect.SetEyeWorldPos(eyePosition)
+	geometryObj.RenderGeometry(effect)
+	effect.Disable()
+}
+
+// flushOnTop 关闭深度测试和深度写入, 按绘制顺序绘制最上层的对象, 透明对象开启混合
+func (q *RenderQueue) flushOnTop(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	if len(q.onTop) == 0 {
+		return
+	}
+	modelMatrix := mgl32.Ident4()
+	gl.Disable(gl.DEPTH_TEST)
+	gl.DepthMask(false)
+	for _, obj := range q.onTop {
+		transparentObj, ok := obj.(model.TransparentObj)
+		blend := ok && transparentObj.Transparent()
+		if blend {
+			gl.Enable(gl.BLEND)
+			gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+		}
+		obj.PreRender()
+		obj.Render(projection, modelMatrix, view, eyePosition, lights)
+		obj.PostRender()
+		if blend {
+			gl.Disable(gl.BLEND)
+		}
+	}
+	gl.DepthMask(true)
+	gl.Enable(gl.DEPTH_TEST)
+}
+
+// flushTransparent 开启混合并关闭深度写入, 由远及近绘制透明对象
+func (q *RenderQueue) flushTransparent(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	if len(q.transparent) == 0 {
+		return
+	}
+	q.sortTransparent(view)
+
+	modelMatrix := mgl32.Ident4()
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.DepthMask(false)
+	for _, obj := range q.transparent {
+		obj.PreRender()
+		obj.Render(projection, modelMatrix, view, eyePosition, lights)
+		obj.PostRender()
+	}
+	gl.DepthMask(true)
+	gl.Disable(gl.BLEND)
+}
+
+// flushUnlit 使用纯色technique覆盖对象自身的technique, 不支持覆盖的对象(如地面网格)按原方式绘制
+func (q *RenderQueue) flushUnlit(items []model.RenderObj, projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight, wireframe bool) {
+	modelMatrix := mgl32.Ident4()
+
+	for _, obj := range items {
+		geometryObj, ok := obj.(model.GeometryObj)
+		if !ok {
+			obj.PreRender()
+			obj.Render(projection, modelMatrix, view, eyePosition, lights)
+			obj.PostRender()
+			continue
+		}
+
+		color := solidColor
+		if wireframe {
+			color = wireframeColor
+		} else if materialObj, ok := obj.(interface{ GetMaterial() *material.Material }); ok {
+			color = materialObj.GetMaterial().DiffuseColor
+		}
+
+		q.unlitEffect.Enable()
+		q.unlitEffect.SetProjectMatrix(&projection)
+		q.unlitEffect.SetViewMatrix(&view)
+		q.unlitEffect.SetColor(color)
+		// 纯色模式下每个网格使用自己材质的漫反射颜色
+		if submeshObj, ok := obj.(model.SubmeshObj); ok && !wireframe {
+			submeshObj.RenderSubmeshes(&q.unlitEffect.BaseTechnique, func(mat *material.Material) {
+				q.unlitEffect.SetColor(mat.DiffuseColor)
+			})
+		} else {
+			geometryObj.RenderGeometry(&q.unlitEffect.BaseTechnique)
+		}
+		q.unlitEffect.Disable()
+	}
+}
+
+// flushDebug 使用调试视图的着色器变体绘制所有对象, 不支持覆盖的对象按原方式绘制
+// overdraw 关闭深度测试并加法混合, 只统计支持覆盖的对象
+func (q *RenderQueue) flushDebug(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	effect, ok := q.debugEffects[config.Config.DebugView]
+	if !ok {
+		return
+	}
+	overdraw := config.Config.DebugView == config.DebugViewOverdraw
+	if overdraw {
+		gl.ClearColor(0, 0, 0, 1)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		gl.Disable(gl.DEPTH_TEST)
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.ONE, gl.ONE)
+	}
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+
+	modelMatrix := mgl32.Ident4()
+	for _, items := range [][]model.RenderObj{q.items, q.transparent} {
+		for _, obj := range items {
+			geometryObj, ok := obj.(model.GeometryObj)
+			if !ok {
+				if !overdraw {
+					obj.PreRender()
+					obj.Render(projection, modelMatrix, view, eyePosition, lights)
+					obj.PostRender()
+				}
+				continue
+			}
+
+			effect.Enable()
+			effect.SetProjectMatrix(&projection)
+			effect.SetViewMatrix(&view)
+			effect.SetEyeWorldPos(eyePosition)
+			effect.SetPointLight(lights)
+			effect.SetClip(q.near, q.far)
+			geometryObj.RenderGeometry(&effect.BaseTechnique)
+			effect.Disable()
+		}
+	}
+
+	if overdraw {
+		gl.Disable(gl.BLEND)
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+// flushWireframeOverlay 在已绘制的场景上叠加线框, 线段向相机偏移避免与表面深度冲突
+func (q *RenderQueue) flushWireframeOverlay(items []model.RenderObj, projection, view mgl32.Mat4) {
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+	gl.Enable(gl.POLYGON_OFFSET_LINE)
+	gl.PolygonOffset(-1, -1)
+	gl.DepthFunc(gl.LEQUAL)
+
+	q.unlitEffect.Enable()
+	q.unlitEffect.SetProjectMatrix(&projection)
+	q.unlitEffect.SetViewMatrix(&view)
+	q.unlitEffect.SetColor(wireframeOverlayColor)
+	for _, obj := range items {
+		if geometryObj, ok := obj.(model.GeometryObj); ok {
+			geometryObj.RenderGeometry(&q.unlitEffect.BaseTechnique)
+		}
+	}
+	q.unlitEffect.Disable()
+
+	gl.DepthFunc(gl.LESS)
+	gl.Disable(gl.POLYGON_OFFSET_LINE)
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+}
@@ -0,0 +1,140 @@
+package resource
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Cache 按 key 共享的引用计数资源, 同一个 key 只加载一次, 最后一个使用者释放时销毁
+// 可以在多个 goroutine 中使用, 同一个 key 并发加载时后来者等待第一次加载的结果
+type Cache[K comparable, T any] struct {
+	mu      sync.Mutex
+	entries map[K]*entry[T]
+	dispose func(T)
+}
+
+type entry[T any] struct {
+	value T
+	err   error
+	refs  int
+	// 加载完成后关闭
+	done chan struct{}
+}
+
+// NewCache 创建缓存, dispose 在引用计数归零时调用, 可为空
+func NewCache[K comparable, T any](dispose func(T)) *Cache[K, T] {
+	return &Cache[K, T]{
+		entries: make(map[K]*entry[T]),
+		dispose: dispose,
+	}
+}
+
+// Get 返回已加载的资源, 不改变引用计数, 未加载或正在加载时返回 false
+func (c *Cache[K, T]) Get(key K) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		select {
+		case <-e.done:
+			return e.value, true
+		default:
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Load 返回资源并增加引用计数, 第一次使用时调用 load 加载
+// 加载失败时不保留记录, 返回错误, 调用者不需要 Release
+func (c *Cache[K, T]) Load(key K, load func() (T, error)) (T, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		e.refs++
+		c.mu.Unlock()
+		<-e.done
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return e.value, e.err
+	}
+	e := &entry[T]{refs: 1, done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	// load panic 时也要移除记录并唤醒等待者, 否则之后的 Load 永远阻塞
+	defer func() {
+		if r := recover(); r != nil {
+			e.err = fmt.Errorf("resource %v: %v", key, r)
+		}
+		c.mu.Lock()
+		if e.err != nil {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		close(e.done)
+	}()
+	e.value, e.err = load()
+	return e.value, e.err
+}
+
+// Reload 重新加载已加载的资源并替换, 引用计数不变, 旧的资源被销毁, 使用者需要重新 Get
+// 加载失败时保留原来的资源, 未加载时不做任何事
+func (c *Cache[K, T]) Reload(key K, load func() (T, error)) error {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	<-e.done
+	value, err := load()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	old := e.value
+	e.value = value
+	c.mu.Unlock()
+	if c.dispose != nil {
+		c.dispose(old)
+	}
+	return nil
+}
+
+// Release 减少引用计数, 归零时销毁资源
+func (c *Cache[K, T]) Release(key K) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	e.refs--
+	if e.refs > 0 {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.entries, key)
+	c.mu.Unlock()
+
+	<-e.done
+	if e.err == nil && c.dispose != nil {
+		c.dispose(e.value)
+	}
+}
+
+// Refs 资源的引用计数, 未加载时为 0
+func (c *Cache[K, T]) Refs(key K) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		return e.refs
+	}
+	return 0
+}
+
+// Len 缓存的资源数量
+func (c *Cache[K, T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
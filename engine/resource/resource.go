@@ -0,0 +1,86 @@
+// Package resource 按路径共享着色器, 贴图和网格, 多个模型使用的同一资源只加载和上传一次
+// 使用者不再需要时调用 Release, 最后一个使用者释放时销毁
+package resource
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/loader"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+)
+
+// ShaderKey 着色器程序的 key, 宏定义不同的变体是不同的程序
+type ShaderKey struct {
+	Vert    string
+	Frag    string
+	Defines string
+}
+
+// KeyOf 着色器对应的 key, 宏定义的顺序不影响 key
+func KeyOf(s *shader.Shader) ShaderKey {
+	defines := slices.Clone(s.Defines)
+	slices.Sort(defines)
+	return ShaderKey{
+		Vert:    filepath.Clean(s.VertFilePath),
+		Frag:    filepath.Clean(s.FragFilePath),
+		Defines: strings.Join(defines, "\n"),
+	}
+}
+
+var (
+	// Shaders 共享的着色器程序, 必须在主线程加载和释放
+	Shaders = NewCache[ShaderKey, *shader.Shader](func(s *shader.Shader) {
+		s.Dispose()
+	})
+	// Textures 共享的贴图, key 为贴图路径, 必须在主线程加载和释放
+	Textures = NewCache[string, uint32](func(id uint32) {
+		gl.DeleteTextures(1, &id)
+	})
+	// Meshes 模型文件的导入结果, key 由 loader.Key 生成, 只在内存中, 不需要销毁
+	// 导入结果是共享的, 使用者修改前先 Clone
+	Meshes = NewCache[string, *loader.Scene](nil)
+)
+
+// LoadShader 返回共享的着色器程序, 第一次使用时编译
+// 编译失败时错误已写入日志, 返回使用错误着色器的程序, 修复源文件后由热重载替换, 只有错误着色器也不可用时返回错误
+func LoadShader(vert, frag string, defines ...string) (*shader.Shader, error) {
+	s := &shader.Shader{VertFilePath: vert, FragFilePath: frag, Defines: defines}
+	return Shaders.Load(KeyOf(s), func() (*shader.Shader, error) {
+		if err := s.Init(); err != nil && s.Program == 0 {
+			s.Dispose()
+			return nil, err
+		}
+		return s, nil
+	})
+}
+
+// LoadShaderVariant 返回共享的着色器变体, 相同源码和宏组合的变体只编译一次
+func LoadShaderVariant(vert, frag string, defines shader.Defines) (*shader.Shader, error) {
+	if err := defines.Validate(); err != nil {
+		return nil, fmt.Errorf("%s, %s: %w", vert, frag, err)
+	}
+	return LoadShader(vert, frag, defines.List()...)
+}
+
+// ReleaseShader 释放 LoadShader 返回的着色器程序
+func ReleaseShader(s *shader.Shader) {
+	Shaders.Release(KeyOf(s))
+}
+
+// LoadTexture 返回共享的贴图, 第一次使用时从文件读取并按 opts 上传, 之后按路径共享, 不再比较 opts
+func LoadTexture(path string, opts texture.Options) (uint32, error) {
+	path = filepath.Clean(path)
+	return Textures.Load(path, func() (uint32, error) {
+		return texture.NewTexture(opts, path)
+	})
+}
+
+// ReleaseTexture 释放 LoadTexture 返回的贴图
+func ReleaseTexture(path string) {
+	Textures.Release(filepath.Clean(path))
+}
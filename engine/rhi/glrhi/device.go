@@ -0,0 +1,298 @@
+// Package glrhi rhi 的 OpenGL 4.1 实现, 所有调用都必须在 GL 上下文所在的主线程
+package glrhi
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/rhi"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+)
+
+// Device OpenGL 设备, 绘制时应用管线状态并在绘制后恢复, 与尚未迁移到 rhi 的代码共享 GL 全局状态
+type Device struct{}
+
+func NewDevice() *Device {
+	return &Device{}
+}
+
+// Buffer 缓冲对象
+type Buffer struct {
+	id     uint32
+	target uint32
+	usage  uint32
+	size   int
+}
+
+func (d *Device) NewBuffer(desc rhi.BufferDesc) (rhi.Buffer, error) {
+	b := &Buffer{
+		target: gl.ARRAY_BUFFER,
+		usage:  gl.STATIC_DRAW,
+	}
+	if desc.Type == rhi.BufferIndex {
+		b.target = gl.ELEMENT_ARRAY_BUFFER
+	}
+	switch desc.Usage {
+	case rhi.UsageDynamic:
+		b.usage = gl.DYNAMIC_DRAW
+	case rhi.UsageStream:
+		b.usage = gl.STREAM_DRAW
+	}
+	gl.GenBuffers(1, &b.id)
+	if b.id == 0 {
+		return nil, fmt.Errorf("failed to create buffer")
+	}
+	if desc.Size > 0 {
+		b.Upload(nil, desc.Size)
+	}
+	return b, nil
+}
+
+func (b *Buffer) Upload(data unsafe.Pointer, size int) {
+	// 索引缓冲绑定会记录在当前 VAO 中, 上传时解绑 VAO 避免影响其他对象
+	var lastVertexArray int32
+	gl.GetIntegerv(gl.VERTEX_ARRAY_BINDING, &lastVertexArray)
+	gl.BindVertexArray(0)
+
+	gl.BindBuffer(b.target, b.id)
+	if size > b.size || data == nil {
+		gl.BufferData(b.target, size, data, b.usage)
+		b.size = size
+	} else if size > 0 {
+		gl.BufferSubData(b.target, 0, size, data)
+	}
+	gl.BindBuffer(b.target, 0)
+	gl.BindVertexArray(uint32(lastVertexArray))
+}
+
+func (b *Buffer) Size() int {
+	return b.size
+}
+
+func (b *Buffer) Dispose() {
+	gl.DeleteBuffers(1, &b.id)
+	b.id = 0
+}
+
+// Texture 二维纹理对象
+type Texture struct {
+	id     uint32
+	desc   rhi.TextureDesc
+	format textureFormat
+}
+
+type textureFormat struct {
+	internalFormat int32
+	format         uint32
+	xtype          uint32
+}
+
+var textureFormats = map[rhi.TextureFormat]textureFormat{
+	rhi.FormatRGBA8:   {gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE},
+	rhi.FormatRGBA16F: {gl.RGBA16F, gl.RGBA, gl.FLOAT},
+	rhi.FormatR16F:    {gl.R16F, gl.RED, gl.FLOAT},
+	rhi.FormatRG16F:   {gl.RG16F, gl.RG, gl.FLOAT},
+	rhi.FormatDepth24: {gl.DEPTH_COMPONENT24, gl.DEPTH_COMPONENT, gl.FLOAT},
+}
+
+func (d *Device) NewTexture(desc rhi.TextureDesc) (rhi.Texture, error) {
+	format, ok := textureFormats[desc.Format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported texture format %d", desc.Format)
+	}
+	t := &Texture{desc: desc, format: format}
+	gl.GenTextures(1, &t.id)
+	if t.id == 0 {
+		return nil, fmt.Errorf("failed to create texture")
+	}
+
+	minFilter, magFilter := int32(gl.LINEAR), int32(gl.LINEAR)
+	if desc.Filter == rhi.FilterNearest {
+		minFilter, magFilter = gl.NEAREST, gl.NEAREST
+	}
+	if desc.Mipmaps {
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+	}
+	wrap := int32(gl.CLAMP_TO_EDGE)
+	if desc.Wrap == rhi.WrapRepeat {
+		wrap = gl.REPEAT
+	}
+
+	var lastTexture int32
+	gl.GetIntegerv(gl.TEXTURE_BINDING_2D, &lastTexture)
+	gl.BindTexture(gl.TEXTURE_2D, t.id)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, magFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, wrap)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, wrap)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, format.internalFormat, desc.Width, desc.Height, 0, format.format, format.xtype, nil)
+	gl.BindTexture(gl.TEXTURE_2D, uint32(lastTexture))
+	return t, nil
+}
+
+func (t *Texture) Upload(data unsafe.Pointer) {
+	var lastTexture int32
+	gl.GetIntegerv(gl.TEXTURE_BINDING_2D, &lastTexture)
+	gl.BindTexture(gl.TEXTURE_2D, t.id)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, t.desc.Width, t.desc.Height, t.format.format, t.format.xtype, data)
+	if t.desc.Mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	gl.BindTexture(gl.TEXTURE_2D, uint32(lastTexture))
+}
+
+func (t *Texture) Width() int32 {
+	return t.desc.Width
+}
+
+func (t *Texture) Height() int32 {
+	return t.desc.Height
+}
+
+// Id GL 纹理名, 供尚未迁移到 rhi 的代码使用
+func (t *Texture) Id() uint32 {
+	return t.id
+}
+
+func (t *Texture) Dispose() {
+	gl.DeleteTextures(1, &t.id)
+	t.id = 0
+}
+
+// Pipeline 着色器程序, 顶点格式和固定功能状态
+type Pipeline struct {
+	desc   rhi.PipelineDesc
+	shader *shader.Shader
+	vao    uint32
+	mode   uint32
+}
+
+var topologies = map[rhi.Topology]uint32{
+	rhi.TopologyTriangles: gl.TRIANGLES,
+	rhi.TopologyLines:     gl.LINES,
+	rhi.TopologyPoints:    gl.POINTS,
+}
+
+func (d *Device) NewPipeline(desc rhi.PipelineDesc) (rhi.Pipeline, error) {
+	p := &Pipeline{
+		desc: desc,
+		shader: &shader.Shader{
+			VertFilePath: desc.VertFilePath,
+			FragFilePath: desc.FragFilePath,
+		},
+		mode: topologies[desc.Topology],
+	}
+	if err := p.shader.Init(); err != nil {
+		return nil, err
+	}
+	// GL 4.1 没有独立的顶点格式对象, 每个管线持有一个 VAO, 绘制时绑定顶点缓冲
+	gl.GenVertexArrays(1, &p.vao)
+	return p, nil
+}
+
+func (p *Pipeline) SetUniform(name string, value interface{}) {
+	p.shader.Use()
+	p.shader.SetUniform(name, value)
+}
+
+func (p *Pipeline) Dispose() {
+	gl.DeleteVertexArrays(1, &p.vao)
+	p.shader.Dispose()
+}
+
+// bindVertexBuffer 按管线的顶点格式设置顶点属性
+func (p *Pipeline) bindVertexBuffer(vertexBuffer *Buffer, indexBuffer *Buffer) {
+	gl.BindVertexArray(p.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vertexBuffer.id)
+	for _, attrib := range p.desc.Layout.Attributes {
+		gl.EnableVertexAttribArray(attrib.Location)
+		if attrib.Type == rhi.AttribInt {
+			gl.VertexAttribIPointer(attrib.Location, attrib.Components, gl.INT, p.desc.Layout.Stride, gl.PtrOffset(attrib.Offset))
+		} else {
+			gl.VertexAttribPointer(attrib.Location, attrib.Components, gl.FLOAT, false, p.desc.Layout.Stride, gl.PtrOffset(attrib.Offset))
+		}
+	}
+	var index uint32
+	if indexBuffer != nil {
+		index = indexBuffer.id
+	}
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, index)
+}
+
+func (d *Device) Draw(call rhi.DrawCall) {
+	p := call.Pipeline.(*Pipeline)
+	if call.Count <= 0 {
+		return
+	}
+
+	restore := applyState(p.desc)
+	defer restore()
+
+	p.shader.Use()
+	for i, tex := range call.Textures {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, tex.(*Texture).id)
+	}
+
+	var indexBuffer *Buffer
+	if call.IndexBuffer != nil {
+		indexBuffer = call.IndexBuffer.(*Buffer)
+	}
+	p.bindVertexBuffer(call.VertexBuffer.(*Buffer), indexBuffer)
+	if indexBuffer != nil {
+		gl.DrawElements(p.mode, call.Count, gl.UNSIGNED_INT, gl.PtrOffset(int(call.First)*4))
+	} else {
+		gl.DrawArrays(p.mode, call.First, call.Count)
+	}
+	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	for i := range call.Textures {
+		gl.ActiveTexture(gl.TEXTURE0 + uint32(i))
+		gl.BindTexture(gl.TEXTURE_2D, 0)
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	p.shader.UnUse()
+}
+
+// applyState 应用管线的深度和混合状态, 返回恢复之前状态的函数
+func applyState(desc rhi.PipelineDesc) func() {
+	lastDepthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	lastBlend := gl.IsEnabled(gl.BLEND)
+	var lastDepthWrite bool
+	gl.GetBooleanv(gl.DEPTH_WRITEMASK, &lastDepthWrite)
+	var lastBlendFunc [4]int32
+	gl.GetIntegerv(gl.BLEND_SRC_RGB, &lastBlendFunc[0])
+	gl.GetIntegerv(gl.BLEND_DST_RGB, &lastBlendFunc[1])
+	gl.GetIntegerv(gl.BLEND_SRC_ALPHA, &lastBlendFunc[2])
+	gl.GetIntegerv(gl.BLEND_DST_ALPHA, &lastBlendFunc[3])
+
+	setEnabled(gl.DEPTH_TEST, desc.DepthTest)
+	gl.DepthMask(desc.DepthWrite)
+	switch desc.Blend {
+	case rhi.BlendAlpha:
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	case rhi.BlendAdditive:
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.ONE, gl.ONE)
+	default:
+		gl.Disable(gl.BLEND)
+	}
+
+	return func() {
+		setEnabled(gl.DEPTH_TEST, lastDepthTest)
+		gl.DepthMask(lastDepthWrite)
+		setEnabled(gl.BLEND, lastBlend)
+		gl.BlendFuncSeparate(uint32(lastBlendFunc[0]), uint32(lastBlendFunc[1]), uint32(lastBlendFunc[2]), uint32(lastBlendFunc[3]))
+	}
+}
+
+func setEnabled(capability uint32, enabled bool) {
+	if enabled {
+		gl.Enable(capability)
+	} else {
+		gl.Disable(capability)
+	}
+}
@@ -0,0 +1,165 @@
+// Package rhi 渲染硬件接口, 上层通过缓冲, 纹理, 管线和绘制调用描述渲染工作, 不直接调用图形 API
+// 目前只有 OpenGL 4.1 实现 (rhi/glrhi), 接口按 Vulkan/WebGPU 的对象模型设计, 便于以后增加后端
+package rhi
+
+import (
+	"unsafe"
+)
+
+// BufferType 缓冲用途
+type BufferType int
+
+const (
+	BufferVertex BufferType = iota
+	BufferIndex
+)
+
+// BufferUsage 缓冲更新频率, 后端据此选择内存类型
+type BufferUsage int
+
+const (
+	UsageStatic BufferUsage = iota
+	UsageDynamic
+	// UsageStream 每帧重新上传
+	UsageStream
+)
+
+type BufferDesc struct {
+	Type  BufferType
+	Usage BufferUsage
+	// 初始大小(字节), 0 表示创建后再上传
+	Size int
+}
+
+// Buffer GPU 缓冲
+type Buffer interface {
+	// Upload 上传 size 字节数据, 超出当前大小时重新分配
+	Upload(data unsafe.Pointer, size int)
+	Size() int
+	Dispose()
+}
+
+// TextureFormat 纹理像素格式
+type TextureFormat int
+
+const (
+	FormatRGBA8 TextureFormat = iota
+	FormatRGBA16F
+	FormatR16F
+	FormatRG16F
+	FormatDepth24
+)
+
+// TextureFilter 纹理采样过滤方式
+type TextureFilter int
+
+const (
+	FilterLinear TextureFilter = iota
+	FilterNearest
+)
+
+// TextureWrap 纹理坐标超出范围时的处理方式
+type TextureWrap int
+
+const (
+	WrapClamp TextureWrap = iota
+	WrapRepeat
+)
+
+type TextureDesc struct {
+	Width   int32
+	Height  int32
+	Format  TextureFormat
+	Filter  TextureFilter
+	Wrap    TextureWrap
+	Mipmaps bool
+}
+
+// Texture 二维纹理
+type Texture interface {
+	// Upload 上传整张纹理的像素数据, 格式与创建时一致
+	Upload(data unsafe.Pointer)
+	Width() int32
+	Height() int32
+	Dispose()
+}
+
+// AttribType 顶点属性分量类型
+type AttribType int
+
+const (
+	AttribFloat AttribType = iota
+	AttribInt
+)
+
+// VertexAttribute 顶点属性, Location 对应着色器中的 layout(location)
+type VertexAttribute struct {
+	Location   uint32
+	Components int32
+	Type       AttribType
+	Offset     int
+}
+
+// VertexLayout 交错存储的顶点格式
+type VertexLayout struct {
+	Stride     int32
+	Attributes []VertexAttribute
+}
+
+// Topology 图元类型
+type Topology int
+
+const (
+	TopologyTriangles Topology = iota
+	TopologyLines
+	TopologyPoints
+)
+
+// BlendMode 颜色混合方式
+type BlendMode int
+
+const (
+	BlendNone BlendMode = iota
+	// BlendAlpha 按源颜色 alpha 混合
+	BlendAlpha
+	// BlendAdditive 颜色相加
+	BlendAdditive
+)
+
+// PipelineDesc 管线状态: 着色器, 顶点格式和固定功能状态
+type PipelineDesc struct {
+	VertFilePath string
+	FragFilePath string
+	Layout       VertexLayout
+	Topology     Topology
+	DepthTest    bool
+	DepthWrite   bool
+	Blend        BlendMode
+}
+
+// Pipeline 编译好的管线, 绘制时一次性应用全部状态
+type Pipeline interface {
+	// SetUniform 设置着色器参数, 支持 int32, bool, float32, mgl32.Vec2/3/4 和 mgl32.Mat4
+	SetUniform(name string, value interface{})
+	Dispose()
+}
+
+// DrawCall 一次绘制提交
+type DrawCall struct {
+	Pipeline     Pipeline
+	VertexBuffer Buffer
+	// 为空时按顶点顺序绘制
+	IndexBuffer Buffer
+	First       int32
+	Count       int32
+	// Textures[i] 绑定到纹理单元 i
+	Textures []Texture
+}
+
+// Device 创建 GPU 资源并提交绘制
+type Device interface {
+	NewBuffer(desc BufferDesc) (Buffer, error)
+	NewTexture(desc TextureDesc) (Texture, error)
+	NewPipeline(desc PipelineDesc) (Pipeline, error)
+	Draw(call DrawCall)
+}
@@ -0,0 +1,118 @@
+package shader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// CacheDir 链接后的程序二进制的缓存目录, 为空时不使用缓存
+var CacheDir = filepath.Join("cache", "shader")
+
+const (
+	binaryMagic = "TOYPROG\x00"
+	binaryExt   = ".toyprog"
+)
+
+var (
+	driver     string
+	driverOnce sync.Once
+)
+
+// driverString 驱动的厂商, 渲染器和版本, 程序二进制只能由同一驱动读取, 驱动更新后缓存失效
+func driverString() string {
+	driverOnce.Do(func() {
+		var formats int32
+		gl.GetIntegerv(gl.NUM_PROGRAM_BINARY_FORMATS, &formats)
+		// 驱动不支持任何二进制格式时不使用缓存
+		if formats > 0 {
+			driver = gl.GoStr(gl.GetString(gl.VENDOR)) + "\x00" + gl.GoStr(gl.GetString(gl.RENDERER)) + "\x00" + gl.GoStr(gl.GetString(gl.VERSION))
+		}
+	})
+	return driver
+}
+
+// binaryPath 预处理后的源码和驱动对应的缓存文件, 不使用缓存时为空
+func binaryPath(vertexShaderSource, fragmentShaderSource string) string {
+	if CacheDir == "" || driverString() == "" {
+		return ""
+	}
+	h := fnv.New64a()
+	h.Write([]byte(driverString()))
+	h.Write([]byte{0})
+	h.Write([]byte(vertexShaderSource))
+	h.Write([]byte{0})
+	h.Write([]byte(fragmentShaderSource))
+	return filepath.Join(CacheDir, fmt.Sprintf("%016x%s", h.Sum64(), binaryExt))
+}
+
+// loadProgramBinary 从缓存创建程序, 缓存不存在或驱动拒绝时返回 false, 之后按源码重新编译
+func loadProgramBinary(file string) (uint32, bool) {
+	if file == "" {
+		return 0, false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil || len(data) <= len(binaryMagic)+4 || string(data[:len(binaryMagic)]) != binaryMagic {
+		return 0, false
+	}
+	format := binary.LittleEndian.Uint32(data[len(binaryMagic):])
+	data = data[len(binaryMagic)+4:]
+
+	program := gl.CreateProgram()
+	gl.ProgramBinary(program, format, unsafe.Pointer(&data[0]), int32(len(data)))
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		gl.DeleteProgram(program)
+		return 0, false
+	}
+	return program, true
+}
+
+// saveProgramBinary 把链接成功的程序写入缓存, 程序在链接前需要设置 PROGRAM_BINARY_RETRIEVABLE_HINT
+func saveProgramBinary(file string, program uint32) error {
+	if file == "" {
+		return nil
+	}
+	var length int32
+	gl.GetProgramiv(program, gl.PROGRAM_BINARY_LENGTH, &length)
+	if length <= 0 {
+		return errors.New("driver returned an empty program binary")
+	}
+	data := make([]byte, length)
+	var format uint32
+	gl.GetProgramBinary(program, length, &length, &format, unsafe.Pointer(&data[0]))
+
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	buf.Write(binary.LittleEndian.AppendUint32(nil, format))
+	buf.Write(data[:length])
+
+	if err := os.MkdirAll(CacheDir, 0755); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名, 写入中断时不会留下损坏的缓存
+	f, err := os.CreateTemp(CacheDir, filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package shader
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	gl43 "github.com/go-gl/gl/v4.3-core/gl"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// ErrComputeUnsupported 上下文低于 OpenGL 4.3 (例如 macOS 的 4.1), 不能使用计算着色器
+var ErrComputeUnsupported = errors.New("compute shaders require OpenGL 4.3")
+
+const computeShader = gl43.COMPUTE_SHADER
+
+// 屏障类型, 传给 MemoryBarrier
+const (
+	BarrierStorageBuffer = gl43.SHADER_STORAGE_BARRIER_BIT
+	BarrierImageAccess   = gl43.SHADER_IMAGE_ACCESS_BARRIER_BIT
+	BarrierTextureFetch  = gl43.TEXTURE_FETCH_BARRIER_BIT
+	BarrierVertexAttrib  = gl43.VERTEX_ATTRIB_ARRAY_BARRIER_BIT
+	BarrierCommand       = gl43.COMMAND_BARRIER_BIT
+	BarrierAll           = gl43.ALL_BARRIER_BITS
+)
+
+var (
+	computeErr  error
+	computeOnce sync.Once
+)
+
+// ComputeSupported 检查当前上下文能否使用计算着色器, 不支持时返回 ErrComputeUnsupported, 必须在主线程调用
+// GL 4.1 的绑定中没有计算着色器的函数, 版本足够时另外加载 4.3 的函数
+func ComputeSupported() error {
+	computeOnce.Do(func() {
+		var major, minor int32
+		gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+		gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+		if major < 4 || major == 4 && minor < 3 {
+			computeErr = ErrComputeUnsupported
+			return
+		}
+		if err := gl43.Init(); err != nil {
+			computeErr = fmt.Errorf("%w: %v", ErrComputeUnsupported, err)
+		}
+	})
+	return computeErr
+}
+
+// ComputeProgram 计算着色器程序, 用于粒子模拟, 光源剔除和程序化生成
+type ComputeProgram struct {
+	FilePath string
+	Program  uint32
+	// 着色器中 layout(local_size_x, local_size_y, local_size_z) 声明的工作组大小
+	LocalSize [3]int32
+}
+
+// NewComputeProgram 编译计算着色器, defines 插入到 #version 之后
+// 不支持计算着色器时返回 ErrComputeUnsupported, 调用者改用 CPU 实现或关闭对应的功能
+func NewComputeProgram(file string, defines Defines) (*ComputeProgram, error) {
+	if err := ComputeSupported(); err != nil {
+		return nil, err
+	}
+	if err := defines.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Shader{Defines: defines.List()}
+	source := s.preprocess(string(data))
+	if err := s.validate(file, string(data), source, computeShader); err != nil {
+		return nil, err
+	}
+	cs, err := s.CompileShader(source+"\x00", computeShader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	program := gl.CreateProgram()
+	gl.AttachShader(program, cs)
+	gl.LinkProgram(program)
+	gl.DeleteShader(cs)
+
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		log := programInfoLog(program)
+		gl.DeleteProgram(program)
+		return nil, fmt.Errorf("%s: failed to link program: %v", file, log)
+	}
+
+	c := &ComputeProgram{FilePath: file, Program: program}
+	gl43.GetProgramiv(program, gl43.COMPUTE_WORK_GROUP_SIZE, &c.LocalSize[0])
+	return c, nil
+}
+
+func (c *ComputeProgram) Use() {
+	gl.UseProgram(c.Program)
+}
+
+// SetUniform 设置 uniform, 程序需要先 Use
+func (c *ComputeProgram) SetUniform(name string, value interface{}) {
+	setUniform(c.Program, name, value)
+}
+
+// Dispatch 按工作组数量执行, 结果在 MemoryBarrier 之后对后续的读取可见
+func (c *ComputeProgram) Dispatch(x, y, z uint32) {
+	gl.UseProgram(c.Program)
+	gl43.DispatchCompute(max(x, 1), max(y, 1), max(z, 1))
+}
+
+// DispatchSize 按要处理的元素数量执行, 工作组数量按 LocalSize 向上取整
+func (c *ComputeProgram) DispatchSize(width, height, depth int) {
+	groups := func(n int, size int32) uint32 {
+		size = max(size, 1)
+		return uint32((max(n, 1) + int(size) - 1) / int(size))
+	}
+	c.Dispatch(groups(width, c.LocalSize[0]), groups(height, c.LocalSize[1]), groups(depth, c.LocalSize[2]))
+}
+
+func (c *ComputeProgram) Dispose() {
+	gl.DeleteProgram(c.Program)
+	c.Program = 0
+}
+
+// NewStorageBuffer 创建大小为 size 字节的着色器存储缓冲, data 为空时内容未初始化
+func NewStorageBuffer(data unsafe.Pointer, size int) uint32 {
+	var buffer uint32
+	gl.GenBuffers(1, &buffer)
+	gl.BindBuffer(gl43.SHADER_STORAGE_BUFFER, buffer)
+	gl.BufferData(gl43.SHADER_STORAGE_BUFFER, size, data, gl.DYNAMIC_COPY)
+	gl.BindBuffer(gl43.SHADER_STORAGE_BUFFER, 0)
+	return buffer
+}
+
+// BindStorageBuffer 把缓冲绑定到着色器中 layout(std430, binding = N) 的存储块
+// 顶点缓冲等普通缓冲对象也可以直接绑定, 计算结果不需要再复制
+func BindStorageBuffer(binding, buffer uint32) {
+	gl.BindBufferBase(gl43.SHADER_STORAGE_BUFFER, binding, buffer)
+}
+
+// BindImageTexture 把贴图的第 0 层绑定到 layout(binding = N) 的 image 变量
+// access 为 gl.READ_ONLY, gl.WRITE_ONLY 或 gl.READ_WRITE, format 与着色器中声明的格式一致, 例如 gl.RGBA16F
+func BindImageTexture(unit, texture, access, format uint32) {
+	gl43.BindImageTexture(unit, texture, 0, false, 0, access, format)
+}
+
+// MemoryBarrier 等待之前的计算着色器写入完成, barriers 为之后读取数据的方式, 例如 BarrierVertexAttrib
+func MemoryBarrier(barriers uint32) {
+	gl43.MemoryBarrier(barriers)
+}
@@ -0,0 +1,62 @@
+package shader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Defines 着色器宏, 值为空时只定义名称, 例如 {"HAS_NORMAL_MAP": "", "NUM_LIGHTS": "4"}
+// 同一份源码用 #ifdef 区分功能, 不同材质按需要的功能组合编译为不同的变体
+type Defines map[string]string
+
+// List 按名称排序的宏定义, 每项为 "NAME" 或 "NAME VALUE", 可直接赋给 Shader.Defines
+// 相同的宏组合总是得到相同的结果, 可以用作变体的 key
+func (d Defines) List() []string {
+	list := make([]string, 0, len(d))
+	for name, value := range d {
+		if value == "" {
+			list = append(list, name)
+		} else {
+			list = append(list, name+" "+value)
+		}
+	}
+	slices.Sort(list)
+	return list
+}
+
+// Validate 宏名称必须是标识符, 值不能换行
+func (d Defines) Validate() error {
+	for name, value := range d {
+		if !isIdentifier(name) {
+			return fmt.Errorf("invalid define name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("define %s: value must be a single line", name)
+		}
+	}
+	return nil
+}
+
+// InitVariant 按宏组合编译变体, 替换原来的 Defines
+// 需要共享变体时使用 resource.LoadShaderVariant, 相同的组合只编译一次
+func (s *Shader) InitVariant(defines Defines) error {
+	if err := defines.Validate(); err != nil {
+		return fmt.Errorf("%s, %s: %w", s.VertFilePath, s.FragFilePath, err)
+	}
+	s.Defines = defines.List()
+	return s.Init()
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		letter := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
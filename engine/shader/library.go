@@ -0,0 +1,53 @@
+package shader
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// Builtin 内置着色器的源文件和编译时的宏, 都使用模型的顶点格式和 LightingTechnique 设置的 uniform
+type Builtin struct {
+	Vert    string
+	Frag    string
+	Defines Defines
+}
+
+// DefaultBuiltin 模型没有指定着色器时使用的内置着色器
+const DefaultBuiltin = "phong"
+
+// builtins 按名称查找的内置着色器, 多个着色器共用同一份源码时用宏区分
+var builtins = map[string]Builtin{
+	"unlit-color":    {Vert: vfs.Path("shader", "model.vert"), Frag: vfs.Path("shader", "builtin", "unlit.frag")},
+	"unlit-textured": {Vert: vfs.Path("shader", "model.vert"), Frag: vfs.Path("shader", "builtin", "unlit.frag"), Defines: Defines{"DIFFUSE_MAP": ""}},
+	"lambert":        {Vert: vfs.Path("shader", "model.vert"), Frag: vfs.Path("shader", "model.frag"), Defines: Defines{"NO_SPECULAR": ""}},
+	"phong":          {Vert: vfs.Path("shader", "model.vert"), Frag: vfs.Path("shader", "model.frag")},
+	"blinn-phong":    {Vert: vfs.Path("shader", "model.vert"), Frag: vfs.Path("shader", "model.frag"), Defines: Defines{"BLINN_PHONG": ""}},
+	"pbr":            {Vert: vfs.Path("shader", "model.vert"), Frag: vfs.Path("shader", "builtin", "pbr.frag")},
+	"depth-only":     {Vert: vfs.Path("shader", "model.vert"), Frag: vfs.Path("shader", "builtin", "depth_only.frag")},
+	"debug-normals":  {Vert: vfs.Path("shader", "debug_view.vert"), Frag: vfs.Path("shader", "debug_view.frag"), Defines: Defines{"DEBUG_NORMALS": ""}},
+}
+
+// LookupBuiltin 按名称查找内置着色器, 返回的 Defines 是副本, 可以加入其他宏
+func LookupBuiltin(name string) (Builtin, bool) {
+	b, ok := builtins[name]
+	if !ok {
+		return Builtin{}, false
+	}
+	b.Defines = maps.Clone(b.Defines)
+	if b.Defines == nil {
+		b.Defines = Defines{}
+	}
+	return b, true
+}
+
+// BuiltinNames 所有内置着色器的名称, 按字母排序
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
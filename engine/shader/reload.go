@@ -0,0 +1,111 @@
+package shader
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// watched 热重载检查的着色器, Init 后加入, Dispose 时移除, 只在主线程访问
+var watched []*Shader
+
+// watch 记录源文件的修改时间, 在资源包或内嵌文件系统中的源文件不会修改
+func (s *Shader) watch() {
+	s.modTime = s.sourceModTime()
+	if !slices.Contains(watched, s) {
+		watched = append(watched, s)
+	}
+}
+
+func (s *Shader) unwatch() {
+	watched = slices.DeleteFunc(watched, func(w *Shader) bool { return w == s })
+}
+
+func (s *Shader) sourceModTime() [2]time.Time {
+	var modTime [2]time.Time
+	modTime[0], _ = vfs.ModTime(s.VertFilePath)
+	modTime[1], _ = vfs.ModTime(s.FragFilePath)
+	return modTime
+}
+
+// Reload 重新读取源文件并编译, 成功后替换程序, 删除旧程序并增加 Version
+// 失败时保留原来的程序, 错误记录在 Err 中
+func (s *Shader) Reload() error {
+	return s.replace(s.compile())
+}
+
+// CompileSource 用编辑中的源码代替源文件编译, 与 Reload 一样成功后替换程序, 失败时保留原来的程序
+// 源文件不变, 之后源文件修改时仍然按源文件重新编译
+func (s *Shader) CompileSource(vert, frag string) error {
+	return s.replace(s.compileSource(vert, frag))
+}
+
+func (s *Shader) replace(program uint32, err error) error {
+	if err != nil {
+		s.Err = fmt.Errorf("%s, %s: %w", s.VertFilePath, s.FragFilePath, err)
+		return s.Err
+	}
+	s.Err = nil
+	// 在帧之间替换, 正在使用的程序由 GL 延迟删除
+	old := s.Program
+	s.Program = program
+	gl.DeleteProgram(old)
+	s.Version++
+	return nil
+}
+
+// Sources 读取未预处理的顶点和片段着色器源码
+func (s *Shader) Sources() (vert, frag string, err error) {
+	vsData, err := vfs.ReadFile(s.VertFilePath)
+	if err != nil {
+		return "", "", err
+	}
+	fsData, err := vfs.ReadFile(s.FragFilePath)
+	if err != nil {
+		return "", "", err
+	}
+	return string(vsData), string(fsData), nil
+}
+
+// Save 把源码写回源文件, 更新记录的修改时间, 热重载不会再编译一次
+func (s *Shader) Save(vert, frag string) error {
+	if err := os.WriteFile(vfs.WritePath(s.VertFilePath), []byte(vert), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(vfs.WritePath(s.FragFilePath), []byte(frag), 0644); err != nil {
+		return err
+	}
+	s.modTime = s.sourceModTime()
+	return nil
+}
+
+// ReloadChanged 重新编译源文件修改过的着色器, 返回重新编译的着色器, 失败的 Err 不为空
+func ReloadChanged() []*Shader {
+	var reloaded []*Shader
+	for _, s := range watched {
+		modTime := s.sourceModTime()
+		if modTime == s.modTime {
+			continue
+		}
+		s.modTime = modTime
+		s.Reload()
+		reloaded = append(reloaded, s)
+	}
+	return reloaded
+}
+
+// Errors 最近一次编译或重载失败, 仍在使用原来的程序或错误着色器的着色器
+func Errors() []*Shader {
+	var failed []*Shader
+	for _, s := range watched {
+		if s.Err != nil {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
@@ -1,41 +1,124 @@
 package shader
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 
 	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// 错误着色器的源文件
+var (
+	ErrorVertFile = vfs.Path("shader", "error.vert")
+	ErrorFragFile = vfs.Path("shader", "error.frag")
 )
 
 type Shader struct {
 	VertFilePath string
 	FragFilePath string
 	Program      uint32
+	// 插入到 #version 之后的宏定义, 同一份源码按不同的宏编译为多个变体
+	Defines []string
+
+	// 热重载成功的次数, 缓存了 uniform 位置的使用者据此判断是否需要重新获取
+	Version uint32
+	// 最近一次编译或热重载的错误, 出错时继续使用原来的程序或错误着色器
+	Err error
+	// 源文件的修改时间
+	modTime [2]time.Time
 }
 
+// Init 读取源文件并编译, 失败时返回错误并改用品红色的错误着色器, 错误写入日志并记录在 Err 中
+// 使用错误着色器时 Program 不为 0, 修复源文件后由热重载替换; 错误着色器也编译失败时 Program 为 0
 func (s *Shader) Init() error {
-	vsData, err := ioutil.ReadFile(s.VertFilePath)
+	s.Err = nil
+	program, err := s.compile()
+	if err != nil {
+		s.Err = fmt.Errorf("%s, %s: %w", s.VertFilePath, s.FragFilePath, err)
+		logger.Error(s.Err)
+		program = errorProgram()
+	}
+	s.Program = program
+	s.watch()
+	return s.Err
+}
+
+// compile 读取源文件, 插入宏后编译链接
+func (s *Shader) compile() (uint32, error) {
+	vsData, err := vfs.ReadFile(s.VertFilePath)
 	if err != nil {
-		fmt.Println(err)
+		return 0, err
 	}
-	fsData, err := ioutil.ReadFile(s.FragFilePath)
+	fsData, err := vfs.ReadFile(s.FragFilePath)
 	if err != nil {
-		fmt.Println(err)
+		return 0, err
+	}
+	return s.compileSource(string(vsData), string(fsData))
+}
+
+// compileSource 编译未预处理的源码, 插入宏后检查并链接
+func (s *Shader) compileSource(vsData, fsData string) (uint32, error) {
+	vs, fs := s.preprocess(vsData), s.preprocess(fsData)
+	if err := errors.Join(
+		s.validate(s.VertFilePath, vsData, vs, gl.VERTEX_SHADER),
+		s.validate(s.FragFilePath, fsData, fs, gl.FRAGMENT_SHADER),
+	); err != nil {
+		return 0, err
 	}
+	return s.NewProgram(vs+"\x00", fs+"\x00")
+}
 
-	s.Program, err = s.NewProgram(string(vsData)+"\x00", string(fsData)+"\x00")
+// errorProgram 编译失败时的替代程序, 每个失败的着色器各有一个, 热重载成功后随旧程序删除
+func errorProgram() uint32 {
+	fallback := &Shader{VertFilePath: ErrorVertFile, FragFilePath: ErrorFragFile}
+	program, err := fallback.compile()
 	if err != nil {
-		panic(err)
+		logger.Error(err)
+		return 0
+	}
+	return program
+}
+
+// Dispose 删除程序并停止检查源文件
+func (s *Shader) Dispose() {
+	s.unwatch()
+	gl.DeleteProgram(s.Program)
+	s.Program = 0
+}
+
+// preprocess 在 #version 行之后插入宏定义, #version 必须是第一行
+func (s *Shader) preprocess(source string) string {
+	if len(s.Defines) == 0 {
+		return source
+	}
+	var defines strings.Builder
+	for _, define := range s.Defines {
+		defines.WriteString("#define " + define + "\n")
+	}
+	if strings.HasPrefix(strings.TrimSpace(source), "#version") {
+		source = strings.TrimSpace(source)
+		if i := strings.IndexByte(source, '\n'); i >= 0 {
+			return source[:i+1] + defines.String() + source[i+1:]
+		}
+		return source + "\n" + defines.String()
 	}
-	return nil
+	return defines.String() + source
 }
 
+// NewProgram 编译并链接程序, 相同的源码在同一驱动上链接过时直接读取 CacheDir 中的程序二进制
 func (s *Shader) NewProgram(vertexShaderSource, fragmentShaderSource string) (uint32, error) {
+	cacheFile := binaryPath(vertexShaderSource, fragmentShaderSource)
+	if program, ok := loadProgramBinary(cacheFile); ok {
+		return program, nil
+	}
+
 	// 加载并编译shader
 	vertexShader, err := s.CompileShader(vertexShaderSource, gl.VERTEX_SHADER)
 	if err != nil {
@@ -44,6 +127,7 @@ func (s *Shader) NewProgram(vertexShaderSource, fragmentShaderSource string) (ui
 
 	fragmentShader, err := s.CompileShader(fragmentShaderSource, gl.FRAGMENT_SHADER)
 	if err != nil {
+		gl.DeleteShader(vertexShader)
 		return 0, err
 	}
 
@@ -51,26 +135,37 @@ func (s *Shader) NewProgram(vertexShaderSource, fragmentShaderSource string) (ui
 	program := gl.CreateProgram()
 	gl.AttachShader(program, vertexShader)
 	gl.AttachShader(program, fragmentShader)
+	if cacheFile != "" {
+		gl.ProgramParameteri(program, gl.PROGRAM_BINARY_RETRIEVABLE_HINT, gl.TRUE)
+	}
 	gl.LinkProgram(program)
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
 
 	var status int32
 	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
 	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
-
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
-
+		log := programInfoLog(program)
+		gl.DeleteProgram(program)
 		return 0, fmt.Errorf("failed to link program: %v", log)
 	}
 
-	gl.DeleteShader(vertexShader)
-	gl.DeleteShader(fragmentShader)
-
+	if err := saveProgramBinary(cacheFile, program); err != nil {
+		logger.Warn("shader cache: ", err)
+	}
 	return program, nil
 }
 
+// programInfoLog 程序的链接日志
+func programInfoLog(program uint32) string {
+	var logLength int32
+	gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+
+	log := strings.Repeat("\x00", int(logLength+1))
+	gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+	return log
+}
+
 func (s *Shader) CompileShader(source string, shaderType uint32) (uint32, error) {
 	shader := gl.CreateShader(shaderType)
 
@@ -87,8 +182,17 @@ func (s *Shader) CompileShader(source string, shaderType uint32) (uint32, error)
 
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
-
-		return 0, fmt.Errorf("failed to compile %v: %v", source, log)
+		gl.DeleteShader(shader)
+
+		// 源码很长, 错误中只给出着色器类型, 日志中有行号
+		kind := "vertex"
+		switch shaderType {
+		case gl.FRAGMENT_SHADER:
+			kind = "fragment"
+		case computeShader:
+			kind = "compute"
+		}
+		return 0, fmt.Errorf("failed to compile %s shader: %v", kind, strings.TrimRight(log, "\x00\n"))
 	}
 
 	return shader, nil
@@ -105,7 +209,11 @@ func (s *Shader) UnUse() uint32 {
 }
 
 func (s *Shader) SetUniform(name string, value interface{}) {
-	loc := gl.GetUniformLocation(s.Program, gl.Str(name+"\x00"))
+	setUniform(s.Program, name, value)
+}
+
+func setUniform(program uint32, name string, value interface{}) {
+	loc := gl.GetUniformLocation(program, gl.Str(name+"\x00"))
 	if loc < 0 {
 		return
 	}
@@ -114,8 +222,19 @@ func (s *Shader) SetUniform(name string, value interface{}) {
 	getValue := reflect.ValueOf(value)
 
 	switch getType.Name() {
-	case "int":
+	case "int", "int32":
 		gl.Uniform1i(loc, int32(getValue.Int()))
+	case "bool":
+		if getValue.Bool() {
+			gl.Uniform1i(loc, 1)
+		} else {
+			gl.Uniform1i(loc, 0)
+		}
+	case "float32":
+		gl.Uniform1f(loc, float32(getValue.Float()))
+	case "Vec2":
+		v := getValue.Interface().(mgl32.Vec2)
+		gl.Uniform2fv(loc, 1, &v[0])
 	case "Vec3":
 		v := getValue.Interface().(mgl32.Vec3)
 		gl.Uniform3fv(loc, 1, &v[0])
@@ -0,0 +1,96 @@
+package shader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+)
+
+var (
+	// Strict 编译前用 glslangValidator 检查源码, 检查出错误时按编译失败处理
+	// 错误定位到源文件的行号, 比驱动的日志更准确, 插入的宏不影响行号
+	Strict = false
+	// Validator glslangValidator 的路径, 默认在 PATH 中查找
+	Validator = "glslangValidator"
+)
+
+// validatorMissing 找不到 glslangValidator 时只警告一次, 之后不再检查
+var validatorMissing bool
+
+// diagnosticPattern glslangValidator 的输出, 例如 "ERROR: 0:12: 'foo' : undeclared identifier"
+var diagnosticPattern = regexp.MustCompile(`^(ERROR|WARNING): \d+:(\d+): (.*)$`)
+
+// stages glslangValidator 的 -S 参数
+var stages = map[uint32]string{
+	gl.VERTEX_SHADER:   "vert",
+	gl.FRAGMENT_SHADER: "frag",
+	computeShader:      "comp",
+}
+
+// validate 检查 file 预处理后的源码 source, 警告写入日志, 有错误时返回所有错误, 每行一个 "文件:行: 信息"
+func (s *Shader) validate(file, original, source string, shaderType uint32) error {
+	if !Strict || validatorMissing {
+		return nil
+	}
+	cmd := exec.Command(Validator, "--stdin", "-S", stages[shaderType])
+	cmd.Stdin = strings.NewReader(source)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		validatorMissing = true
+		logger.Warn(fmt.Sprintf("glsl validation disabled: %v", runErr))
+		return nil
+	}
+
+	var diagnostics []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		m := diagnosticPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		text := fmt.Sprintf("%s:%d: %s", file, s.sourceLine(original, n), m[3])
+		if m[1] == "WARNING" {
+			logger.Warn(text)
+			continue
+		}
+		diagnostics = append(diagnostics, text)
+	}
+	if len(diagnostics) > 0 {
+		return errors.New(strings.Join(diagnostics, "\n"))
+	}
+	if runErr != nil {
+		// 没有可以解析的错误时给出原始输出
+		return fmt.Errorf("%s: %s", file, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
+
+// sourceLine 预处理后的行号对应的源文件行号, 与 preprocess 插入宏的方式一致
+func (s *Shader) sourceLine(original string, line int) int {
+	if len(s.Defines) == 0 {
+		return line
+	}
+	trimmed := strings.TrimLeft(original, " \t\r\n")
+	if !strings.HasPrefix(trimmed, "#version") {
+		// 宏在文件开头
+		return max(line-len(s.Defines), 1)
+	}
+	// #version 之前的空行被去掉, 宏在 #version 之后
+	leading := strings.Count(original[:len(original)-len(trimmed)], "\n")
+	if line <= 1 {
+		return line + leading
+	}
+	return max(line-len(s.Defines), 2) + leading
+}
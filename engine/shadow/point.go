@@ -0,0 +1,196 @@
+package shadow
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+// 阴影相机的近平面, 距离光源更近的表面不投射阴影
+const shadowNear = 0.05
+
+// 立方体贴图 6 个面的观察方向和上方向, 顺序与 TEXTURE_CUBE_MAP_POSITIVE_X 开始的面一致
+var cubeFaces = [6]struct{ target, up mgl32.Vec3 }{
+	{mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, -1, 0}},
+	{mgl32.Vec3{-1, 0, 0}, mgl32.Vec3{0, -1, 0}},
+	{mgl32.Vec3{0, 1, 0}, mgl32.Vec3{0, 0, 1}},
+	{mgl32.Vec3{0, -1, 0}, mgl32.Vec3{0, 0, -1}},
+	{mgl32.Vec3{0, 0, 1}, mgl32.Vec3{0, -1, 0}},
+	{mgl32.Vec3{0, 0, -1}, mgl32.Vec3{0, -1, 0}},
+}
+
+type cubeMap struct {
+	fbo        uint32
+	texture    uint32
+	resolution int32
+}
+
+// PointShadows 点光源的全向阴影, 每帧把投射阴影的对象分 6 次绘制到光源的立方体深度贴图
+// 深度写入到光源的线性距离除以 Far, 光照着色器按距离比较
+type PointShadows struct {
+	effect *technique.BaseTechnique
+
+	lightPosUniform int32
+	farUniform      int32
+
+	maps map[*light.PointLight]*cubeMap
+}
+
+func NewPointShadows() (*PointShadows, error) {
+	s := &PointShadows{
+		effect: &technique.BaseTechnique{},
+		maps:   make(map[*light.PointLight]*cubeMap),
+	}
+	shadowShader := &shader.Shader{
+		VertFilePath: "./resource/shader/point_shadow.vert",
+		FragFilePath: "./resource/shader/point_shadow.frag",
+	}
+	if err := shadowShader.Init(); err != nil {
+		return nil, err
+	}
+	s.effect.Init(shadowShader)
+	s.lightPosUniform = s.effect.GetUniformLocation("gLightPos")
+	s.farUniform = s.effect.GetUniformLocation("gFar")
+
+	// 在面的交界处跨面过滤, 避免 PCF 在接缝处出现亮线
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+	return s, nil
+}
+
+// Render 绘制开启阴影的点光源的立方体贴图, 超出 light.MaxShadowLights 或关闭阴影的光源释放贴图
+func (s *PointShadows) Render(lights []*light.PointLight, renderObjs []model.RenderObj) error {
+	var lastViewport [4]int32
+	var lastFbo int32
+	gl.GetIntegerv(gl.VIEWPORT, &lastViewport[0])
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &lastFbo)
+	cullFace := gl.IsEnabled(gl.CULL_FACE)
+	defer func() {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(lastFbo))
+		gl.Viewport(lastViewport[0], lastViewport[1], lastViewport[2], lastViewport[3])
+		if cullFace {
+			gl.Enable(gl.CULL_FACE)
+		}
+	}()
+
+	shadowNum := 0
+	for _, l := range lights {
+		if l.Shadow == nil {
+			continue
+		}
+		if !l.Shadow.Enable || shadowNum >= light.MaxShadowLights {
+			s.release(l)
+			continue
+		}
+		shadowNum++
+		cube, err := s.cubeMap(l)
+		if err != nil {
+			s.release(l)
+			return err
+		}
+		s.renderCube(l, cube, renderObjs)
+		l.Shadow.CubeMap = cube.texture
+	}
+	return nil
+}
+
+// cubeMap 光源的立方体贴图, 分辨率变化时重新创建
+func (s *PointShadows) cubeMap(l *light.PointLight) (*cubeMap, error) {
+	resolution := min(max(l.Shadow.Resolution, 16), 4096)
+	if cube, ok := s.maps[l]; ok && cube.resolution == resolution {
+		return cube, nil
+	}
+	s.release(l)
+
+	cube := &cubeMap{resolution: resolution}
+	gl.GenTextures(1, &cube.texture)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, cube.texture)
+	for face := uint32(0); face < 6; face++ {
+		gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+face, 0, gl.DEPTH_COMPONENT24, resolution, resolution, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	}
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, 0)
+
+	gl.GenFramebuffers(1, &cube.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, cube.fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_CUBE_MAP_POSITIVE_X, cube.texture, 0)
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	s.maps[l] = cube
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		return nil, fmt.Errorf("point shadow framebuffer incomplete: 0x%x", status)
+	}
+	return cube, nil
+}
+
+// renderCube 从光源位置向 6 个方向绘制 Far 范围内声明了阴影 pass 的对象
+func (s *PointShadows) renderCube(l *light.PointLight, cube *cubeMap, renderObjs []model.RenderObj) {
+	position := l.Position.Vec3()
+	far := max(l.Shadow.Far, shadowNear*2)
+	reach := geometry.Sphere{Center: position, Radius: far}
+
+	casters := make([]model.GeometryObj, 0, len(renderObjs))
+	for _, renderObj := range renderObjs {
+		geometryObj, ok := renderObj.(model.GeometryObj)
+		if !ok || !technique.HasPass(renderObj, technique.PassShadow) {
+			continue
+		}
+		if boundedObj, ok := renderObj.(model.BoundedObj); ok && !reach.IntersectsAABB(boundedObj.WorldBounds()) {
+			continue
+		}
+		casters = append(casters, geometryObj)
+	}
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, cube.fbo)
+	gl.Viewport(0, 0, cube.resolution, cube.resolution)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthMask(true)
+	// 双面绘制, 薄片和开口的网格也能投射阴影
+	gl.Disable(gl.CULL_FACE)
+
+	projection := mgl32.Perspective(mgl32.DegToRad(90), 1, shadowNear, far)
+	s.effect.Enable()
+	s.effect.SetProjectMatrix(&projection)
+	gl.Uniform3f(s.lightPosUniform, position.X(), position.Y(), position.Z())
+	gl.Uniform1f(s.farUniform, far)
+	for face, dir := range cubeFaces {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(face), cube.texture, 0)
+		gl.Clear(gl.DEPTH_BUFFER_BIT)
+		view := mgl32.LookAtV(position, position.Add(dir.target), dir.up)
+		s.effect.SetViewMatrix(&view)
+		for _, caster := range casters {
+			caster.RenderGeometry(s.effect)
+		}
+	}
+	s.effect.Disable()
+}
+
+func (s *PointShadows) release(l *light.PointLight) {
+	if l.Shadow != nil {
+		l.Shadow.CubeMap = 0
+	}
+	cube, ok := s.maps[l]
+	if !ok {
+		return
+	}
+	gl.DeleteFramebuffers(1, &cube.fbo)
+	gl.DeleteTextures(1, &cube.texture)
+	delete(s.maps, l)
+}
+
+func (s *PointShadows) Dispose() {
+	for l := range s.maps {
+		s.release(l)
+	}
+	s.effect.ShaderObj.Dispose()
+}
@@ -0,0 +1,97 @@
+package shortcut
+
+import (
+	"fmt"
+
+	"github.com/huangxiaobo/toy-engine/engine/input"
+)
+
+// Command 命名的命令, 通过快捷键, 菜单或命令面板执行
+type Command struct {
+	Name     string
+	Shortcut Shortcut
+	Run      func()
+	// 命令当前是否可用, 为 nil 时总是可用
+	Enabled func() bool
+}
+
+// Available 命令当前是否可以执行
+func (c *Command) Available() bool {
+	return c.Run != nil && (c.Enabled == nil || c.Enabled())
+}
+
+// Registry 命令和快捷键的注册表, 每帧按输入触发快捷键
+type Registry struct {
+	commands []*Command
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register 登记命令, 名称不能重复
+// 快捷键已被其他命令使用时命令仍然登记, 但不设置快捷键, 并返回冲突的错误
+func (r *Registry) Register(cmd *Command) error {
+	if r.Command(cmd.Name) != nil {
+		return fmt.Errorf("command %q is already registered", cmd.Name)
+	}
+	r.commands = append(r.commands, cmd)
+	if other := r.Conflict(cmd.Shortcut, cmd); other != nil {
+		sc := cmd.Shortcut
+		cmd.Shortcut = Shortcut{}
+		return fmt.Errorf("shortcut %s of %q is already used by %q", sc, cmd.Name, other.Name)
+	}
+	return nil
+}
+
+// Conflict 使用组合键 s 的其他命令, 没有时返回 nil
+func (r *Registry) Conflict(s Shortcut, except *Command) *Command {
+	if s.Key == 0 {
+		return nil
+	}
+	for _, cmd := range r.commands {
+		if cmd != except && cmd.Shortcut == s {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Commands 按登记顺序排列的命令
+func (r *Registry) Commands() []*Command {
+	return r.commands
+}
+
+// Command 按名称查找命令, 不存在时返回 nil
+func (r *Registry) Command(name string) *Command {
+	for _, cmd := range r.commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Run 按名称执行命令, 返回命令是否存在并且可用
+func (r *Registry) Run(name string) bool {
+	cmd := r.Command(name)
+	if cmd == nil || !cmd.Available() {
+		return false
+	}
+	cmd.Run()
+	return true
+}
+
+// Handle 执行这一帧按下快捷键的命令, 每帧在界面开始前调用
+// 界面正在接收文本输入时, 只触发不会与输入冲突的组合键, 见 Shortcut.TextSafe
+func (r *Registry) Handle(in *input.Input) {
+	typing := in.KeyboardCaptured()
+	for _, cmd := range r.commands {
+		if typing && !cmd.Shortcut.TextSafe() {
+			continue
+		}
+		if cmd.Shortcut.Pressed(in) && cmd.Available() {
+			cmd.Run()
+		}
+	}
+}
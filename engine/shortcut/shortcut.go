@@ -0,0 +1,97 @@
+package shortcut
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/huangxiaobo/toy-engine/engine/input"
+)
+
+// Shortcut 组合键, Key 为 0 时没有快捷键, 修饰键需要与按下的完全一致
+type Shortcut struct {
+	Key   input.Key
+	Ctrl  bool
+	Shift bool
+	Alt   bool
+}
+
+// Parse 解析 "Ctrl+Shift+S" 形式的组合键, 不区分大小写, 空字符串为没有快捷键
+func Parse(s string) (Shortcut, error) {
+	var sc Shortcut
+	if strings.TrimSpace(s) == "" {
+		return sc, nil
+	}
+	parts := strings.Split(s, "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 {
+			switch strings.ToLower(part) {
+			case "ctrl":
+				sc.Ctrl = true
+			case "shift":
+				sc.Shift = true
+			case "alt":
+				sc.Alt = true
+			default:
+				return Shortcut{}, fmt.Errorf("unknown modifier %q in shortcut %q", part, s)
+			}
+			continue
+		}
+		key, ok := input.ParseKey(part)
+		if !ok {
+			return Shortcut{}, fmt.Errorf("unknown key %q in shortcut %q", part, s)
+		}
+		sc.Key = key
+	}
+	return sc, nil
+}
+
+// MustParse 与 Parse 相同, 用于代码中写死的组合键, 解析失败时 panic
+func MustParse(s string) Shortcut {
+	sc, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return sc
+}
+
+// String 菜单和命令面板中显示的名称, 如 Ctrl+S
+func (s Shortcut) String() string {
+	if s.Key == 0 {
+		return ""
+	}
+	var b strings.Builder
+	if s.Ctrl {
+		b.WriteString("Ctrl+")
+	}
+	if s.Shift {
+		b.WriteString("Shift+")
+	}
+	if s.Alt {
+		b.WriteString("Alt+")
+	}
+	b.WriteString(s.Key.String())
+	return b.String()
+}
+
+// 文本框中 Ctrl 加这些键用于编辑文本
+var textEditingKeys = map[input.Key]bool{
+	input.KeyA: true, input.KeyC: true, input.KeyV: true, input.KeyX: true, input.KeyY: true, input.KeyZ: true,
+	input.KeyBackspace: true, input.KeyDelete: true, input.KeyLeft: true, input.KeyRight: true,
+	input.KeyHome: true, input.KeyEnd: true,
+}
+
+// TextSafe 界面正在接收文本输入时是否仍然触发
+// 不带 Ctrl 或 Alt 的按键用于输入文字, Ctrl 加文本编辑键用于编辑文本, 这些组合键在输入时不触发
+func (s Shortcut) TextSafe() bool {
+	if s.Alt {
+		return true
+	}
+	return s.Ctrl && !textEditingKeys[s.Key]
+}
+
+// Pressed 组合键是否在这一帧按下
+func (s Shortcut) Pressed(in *input.Input) bool {
+	return s.Key != 0 && in.WasPressed(s.Key) &&
+		in.CtrlDown() == s.Ctrl && in.ShiftDown() == s.Shift && in.AltDown() == s.Alt
+}
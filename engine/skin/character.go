@@ -0,0 +1,169 @@
+package skin
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// 内置人形角色的骨骼
+const (
+	boneHips = iota
+	boneSpine
+	boneHead
+	boneUpperArmL
+	boneLowerArmL
+	boneUpperArmR
+	boneLowerArmR
+	boneUpperLegL
+	boneLowerLegL
+	boneUpperLegR
+	boneLowerLegR
+)
+
+// characterPart 绑定到一根骨骼的方块, 模型空间的中心和半边长
+type characterPart struct {
+	bone   int
+	center mgl32.Vec3
+	half   mgl32.Vec3
+	color  mgl32.Vec3
+}
+
+var (
+	skinColor  = mgl32.Vec3{0.9, 0.7, 0.55}
+	shirtColor = mgl32.Vec3{1, 1, 1}
+	pantsColor = mgl32.Vec3{0.25, 0.3, 0.45}
+)
+
+// NewCharacter 高约 1.9 的内置人形角色: 骨架, 面向 +z 的方块网格和循环的行走动画
+// 衣服为白色, 与实例颜色相乘后区分不同的角色
+func NewCharacter() (*Skeleton, *Mesh, *Clip, error) {
+	bone := func(name string, parent int, x, y, z float32) Bone {
+		t := IdentityTransform()
+		t.Translation = mgl32.Vec3{x, y, z}
+		return Bone{Name: name, Parent: parent, Bind: t}
+	}
+	skeleton, err := NewSkeleton([]Bone{
+		bone("hips", -1, 0, 1.0, 0),
+		bone("spine", boneHips, 0, 0.2, 0),
+		bone("head", boneSpine, 0, 0.45, 0),
+		bone("upperarm.l", boneSpine, 0.25, 0.45, 0),
+		bone("lowerarm.l", boneUpperArmL, 0, -0.3, 0),
+		bone("upperarm.r", boneSpine, -0.25, 0.45, 0),
+		bone("lowerarm.r", boneUpperArmR, 0, -0.3, 0),
+		bone("upperleg.l", boneHips, 0.1, 0, 0),
+		bone("lowerleg.l", boneUpperLegL, 0, -0.45, 0),
+		bone("upperleg.r", boneHips, -0.1, 0, 0),
+		bone("lowerleg.r", boneUpperLegR, 0, -0.45, 0),
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	parts := []characterPart{
+		{boneHips, mgl32.Vec3{0, 1.0, 0}, mgl32.Vec3{0.17, 0.1, 0.1}, pantsColor},
+		{boneSpine, mgl32.Vec3{0, 1.4, 0}, mgl32.Vec3{0.2, 0.26, 0.12}, shirtColor},
+		{boneHead, mgl32.Vec3{0, 1.78, 0}, mgl32.Vec3{0.11, 0.12, 0.11}, skinColor},
+	}
+	for _, side := range []struct {
+		sign               float32
+		upperArm, lowerArm int
+		upperLeg, lowerLeg int
+	}{
+		{1, boneUpperArmL, boneLowerArmL, boneUpperLegL, boneLowerLegL},
+		{-1, boneUpperArmR, boneLowerArmR, boneUpperLegR, boneLowerLegR},
+	} {
+		parts = append(parts,
+			characterPart{side.upperArm, mgl32.Vec3{0.27 * side.sign, 1.5, 0}, mgl32.Vec3{0.05, 0.15, 0.05}, shirtColor},
+			characterPart{side.lowerArm, mgl32.Vec3{0.27 * side.sign, 1.2, 0}, mgl32.Vec3{0.045, 0.15, 0.045}, skinColor},
+			characterPart{side.upperLeg, mgl32.Vec3{0.1 * side.sign, 0.77, 0}, mgl32.Vec3{0.075, 0.23, 0.075}, pantsColor},
+			characterPart{side.lowerLeg, mgl32.Vec3{0.1 * side.sign, 0.3, 0}, mgl32.Vec3{0.065, 0.25, 0.065}, pantsColor},
+			// 脚
+			characterPart{side.lowerLeg, mgl32.Vec3{0.1 * side.sign, 0.04, 0.05}, mgl32.Vec3{0.065, 0.04, 0.12}, skinColor.Mul(0.4)},
+		)
+	}
+
+	var vertices []Vertex
+	var indices []uint32
+	for _, part := range parts {
+		vertices, indices = appendBox(vertices, indices, part)
+	}
+	return skeleton, NewMesh(vertices, indices), walkClip(), nil
+}
+
+// appendBox 六个面各 4 个顶点的方块, 全部绑定到一根骨骼
+func appendBox(vertices []Vertex, indices []uint32, part characterPart) ([]Vertex, []uint32) {
+	for axis := 0; axis < 3; axis++ {
+		u, v := (axis+1)%3, (axis+2)%3
+		for _, sign := range []float32{-1, 1} {
+			var normal mgl32.Vec3
+			normal[axis] = sign
+			base := uint32(len(vertices))
+			for _, corner := range [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+				var p mgl32.Vec3
+				p[axis] = sign * part.half[axis]
+				p[u] = corner[0] * part.half[u]
+				p[v] = corner[1] * part.half[v]
+				vertices = append(vertices, Vertex{
+					Position: part.center.Add(p),
+					Normal:   normal,
+					Color:    part.color,
+					Bones:    [MaxInfluences]int32{int32(part.bone)},
+					Weights:  [MaxInfluences]float32{1},
+				})
+			}
+			// 正面朝外为逆时针
+			if sign > 0 {
+				indices = append(indices, base, base+1, base+2, base, base+2, base+3)
+			} else {
+				indices = append(indices, base, base+2, base+1, base, base+3, base+2)
+			}
+		}
+	}
+	return vertices, indices
+}
+
+// walkClip 一秒一个周期的行走动画, 腿和手臂前后摆动, 膝盖和手肘弯曲, 髋部上下起伏
+func walkClip() *Clip {
+	const keys = 8
+	const duration = 1.0
+	clip := &Clip{Name: "walk", Duration: duration}
+
+	rotateX := func(angle float64) mgl32.Quat {
+		return mgl32.QuatRotate(float32(angle), mgl32.Vec3{1, 0, 0})
+	}
+	channel := func(bone int, rotation func(phase float64) mgl32.Quat) Channel {
+		c := Channel{Bone: bone}
+		for k := 0; k < keys; k++ {
+			phase := float64(k) / keys * 2 * math.Pi
+			c.Times = append(c.Times, float32(k)/keys*duration)
+			c.Rotations = append(c.Rotations, rotation(phase))
+		}
+		return c
+	}
+
+	// 绕 x 轴负方向旋转时腿向前(+z)摆
+	clip.Channels = []Channel{
+		channel(boneUpperLegL, func(p float64) mgl32.Quat { return rotateX(-0.5 * math.Sin(p)) }),
+		channel(boneUpperLegR, func(p float64) mgl32.Quat { return rotateX(0.5 * math.Sin(p)) }),
+		channel(boneLowerLegL, func(p float64) mgl32.Quat { return rotateX(0.7 * math.Max(math.Sin(p-math.Pi/2), 0)) }),
+		channel(boneLowerLegR, func(p float64) mgl32.Quat { return rotateX(0.7 * math.Max(math.Sin(p+math.Pi/2), 0)) }),
+		channel(boneUpperArmL, func(p float64) mgl32.Quat { return rotateX(0.4 * math.Sin(p)) }),
+		channel(boneUpperArmR, func(p float64) mgl32.Quat { return rotateX(-0.4 * math.Sin(p)) }),
+		channel(boneLowerArmL, func(p float64) mgl32.Quat { return rotateX(-0.3 - 0.2*math.Max(math.Sin(p), 0)) }),
+		channel(boneLowerArmR, func(p float64) mgl32.Quat { return rotateX(-0.3 - 0.2*math.Max(-math.Sin(p), 0)) }),
+		channel(boneSpine, func(p float64) mgl32.Quat {
+			return mgl32.QuatRotate(float32(0.1*math.Sin(p)), mgl32.Vec3{0, 1, 0})
+		}),
+	}
+
+	// 每步落脚时髋部最低, 一个周期两步
+	hips := channel(boneHips, func(float64) mgl32.Quat { return mgl32.QuatIdent() })
+	for k := 0; k < keys; k++ {
+		phase := float64(k) / keys * 2 * math.Pi
+		bob := float32(0.04 * math.Abs(math.Cos(phase)))
+		hips.Translations = append(hips.Translations, mgl32.Vec3{0, 1.0 - 0.04 + bob, 0})
+	}
+	clip.Channels = append(clip.Channels, hips)
+	return clip
+}
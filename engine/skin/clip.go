@@ -0,0 +1,64 @@
+package skin
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Channel 一根骨骼的关键帧, 旋转和平移使用同一组时间, Translations 为空时使用绑定姿势的平移
+type Channel struct {
+	Bone         int
+	Times        []float32
+	Rotations    []mgl32.Quat
+	Translations []mgl32.Vec3
+}
+
+// Clip 循环播放的动画片段, 没有通道的骨骼保持绑定姿势
+type Clip struct {
+	Name     string
+	Duration float32
+	Channels []Channel
+}
+
+// Sample 采样 time 时刻的局部变换, 时间按片段长度循环
+func (c *Clip) Sample(s *Skeleton, time float32, local []Transform) {
+	s.BindPose(local)
+	if c.Duration > 0 {
+		time = float32(math.Mod(float64(time), float64(c.Duration)))
+		if time < 0 {
+			time += c.Duration
+		}
+	}
+	for _, channel := range c.Channels {
+		if len(channel.Times) == 0 {
+			continue
+		}
+		i, t := channel.segment(time, c.Duration)
+		j := (i + 1) % len(channel.Times)
+		transform := &local[channel.Bone]
+		transform.Rotation = mgl32.QuatNlerp(channel.Rotations[i], channel.Rotations[j], t)
+		if len(channel.Translations) > 0 {
+			transform.Translation = channel.Translations[i].Add(channel.Translations[j].Sub(channel.Translations[i]).Mul(t))
+		}
+	}
+}
+
+// segment time 所在的关键帧区间和区间内的插值系数, 最后一帧之后向第一帧插值
+func (channel *Channel) segment(time, duration float32) (int, float32) {
+	n := len(channel.Times)
+	i := sort.Search(n, func(k int) bool { return channel.Times[k] > time }) - 1
+	if i < 0 {
+		return 0, 0
+	}
+	start := channel.Times[i]
+	end := duration
+	if i+1 < n {
+		end = channel.Times[i+1]
+	}
+	if end <= start {
+		return i, 0
+	}
+	return i, (time - start) / (end - start)
+}
@@ -0,0 +1,155 @@
+package skin
+
+import (
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+)
+
+// Vertex 蒙皮顶点, 权重之和为 1
+type Vertex struct {
+	Position mgl32.Vec3
+	Normal   mgl32.Vec3
+	Color    mgl32.Vec3
+	Bones    [MaxInfluences]int32
+	Weights  [MaxInfluences]float32
+}
+
+// 蒙皮顶点的骨骼索引和权重使用的顶点属性位置, 实例矩阵与 mesh.Mesh 相同占用 8~11, 之后为实例颜色
+const (
+	boneLocation   = 4
+	weightLocation = 5
+	colorLocation  = 12
+)
+
+// Mesh 蒙皮网格, 每个实例一个变换矩阵和颜色, 蒙皮矩阵由着色器按 gl_InstanceID 从骨骼纹理中读取
+type Mesh struct {
+	Vertices []Vertex
+	Indices  []uint32
+	Bounds   geometry.AABB
+
+	vao         uint32
+	vbo         uint32
+	ebo         uint32
+	instanceVbo uint32
+	colorVbo    uint32
+}
+
+func NewMesh(vertices []Vertex, indices []uint32) *Mesh {
+	m := &Mesh{Vertices: vertices, Indices: indices, Bounds: geometry.NewAABB()}
+	for _, v := range vertices {
+		m.Bounds.Extend(v.Position)
+	}
+	return m
+}
+
+func (m *Mesh) Setup() {
+	var dummy Vertex
+	stride := int32(unsafe.Sizeof(dummy))
+
+	gl.GenVertexArrays(1, &m.vao)
+	gl.GenBuffers(1, &m.vbo)
+	gl.GenBuffers(1, &m.ebo)
+	gl.GenBuffers(1, &m.instanceVbo)
+	gl.GenBuffers(1, &m.colorVbo)
+	gl.BindVertexArray(m.vao)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(m.Vertices)*int(stride), gl.Ptr(m.Vertices), gl.STATIC_DRAW)
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.ebo)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(m.Indices)*mesh.GL_FLOAT32_SIZE, gl.Ptr(m.Indices), gl.STATIC_DRAW)
+
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, stride, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, stride, gl.PtrOffset(int(unsafe.Offsetof(dummy.Color))))
+	gl.EnableVertexAttribArray(2)
+	gl.VertexAttribPointer(2, 3, gl.FLOAT, false, stride, gl.PtrOffset(int(unsafe.Offsetof(dummy.Normal))))
+	gl.EnableVertexAttribArray(boneLocation)
+	gl.VertexAttribIPointer(boneLocation, MaxInfluences, gl.INT, stride, gl.PtrOffset(int(unsafe.Offsetof(dummy.Bones))))
+	gl.EnableVertexAttribArray(weightLocation)
+	gl.VertexAttribPointer(weightLocation, MaxInfluences, gl.FLOAT, false, stride, gl.PtrOffset(int(unsafe.Offsetof(dummy.Weights))))
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.instanceVbo)
+	matStride := int32(unsafe.Sizeof(mgl32.Mat4{}))
+	for i := uint32(0); i < 4; i++ {
+		location := mesh.InstanceMatrixLocation + i
+		gl.EnableVertexAttribArray(location)
+		gl.VertexAttribPointer(location, 4, gl.FLOAT, false, matStride, gl.PtrOffset(int(i)*4*mesh.GL_FLOAT32_SIZE))
+		gl.VertexAttribDivisor(location, 1)
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.colorVbo)
+	gl.EnableVertexAttribArray(colorLocation)
+	gl.VertexAttribPointer(colorLocation, 3, gl.FLOAT, false, int32(unsafe.Sizeof(mgl32.Vec3{})), gl.PtrOffset(0))
+	gl.VertexAttribDivisor(colorLocation, 1)
+	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+// DrawInstanced 一次绘制调用绘制所有实例, 第 i 个实例使用骨骼纹理的第 i 行, colors 与 instances 一一对应
+func (m *Mesh) DrawInstanced(instances []mgl32.Mat4, colors []mgl32.Vec3) {
+	if len(instances) == 0 {
+		return
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.instanceVbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(instances)*int(unsafe.Sizeof(instances[0])), gl.Ptr(instances), gl.STREAM_DRAW)
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.colorVbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(colors)*int(unsafe.Sizeof(colors[0])), gl.Ptr(colors), gl.STREAM_DRAW)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	gl.BindVertexArray(m.vao)
+	gl.DrawElementsInstanced(gl.TRIANGLES, int32(len(m.Indices)), gl.UNSIGNED_INT, nil, int32(len(instances)))
+	gl.BindVertexArray(0)
+}
+
+func (m *Mesh) Dispose() {
+	gl.DeleteVertexArrays(1, &m.vao)
+	gl.DeleteBuffers(1, &m.vbo)
+	gl.DeleteBuffers(1, &m.ebo)
+	gl.DeleteBuffers(1, &m.instanceVbo)
+	gl.DeleteBuffers(1, &m.colorVbo)
+}
+
+// BoneTexture 保存每个实例蒙皮矩阵的浮点纹理, 每行一个实例, 每个矩阵占 4 个像素(按列)
+type BoneTexture struct {
+	Id     uint32
+	bones  int
+	height int
+}
+
+// NewBoneTexture rows 为每批最多的实例数, 超出时分批上传和绘制
+func NewBoneTexture(bones, rows int) *BoneTexture {
+	t := &BoneTexture{bones: bones, height: rows}
+	gl.GenTextures(1, &t.Id)
+	gl.BindTexture(gl.TEXTURE_2D, t.Id)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA32F, int32(bones*4), int32(rows), 0, gl.RGBA, gl.FLOAT, nil)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return t
+}
+
+// Rows 每批最多的实例数
+func (t *BoneTexture) Rows() int {
+	return t.height
+}
+
+// Upload 上传 len(palettes)/bones 个实例的蒙皮矩阵, 不超过 Rows
+func (t *BoneTexture) Upload(palettes []mgl32.Mat4) {
+	rows := len(palettes) / t.bones
+	if rows == 0 {
+		return
+	}
+	gl.BindTexture(gl.TEXTURE_2D, t.Id)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, int32(t.bones*4), int32(rows), gl.RGBA, gl.FLOAT, gl.Ptr(palettes))
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+func (t *BoneTexture) Dispose() {
+	gl.DeleteTextures(1, &t.Id)
+}
@@ -0,0 +1,81 @@
+// Package skin 骨骼蒙皮动画
+// 骨骼按父节点在前的顺序排列, 动画片段采样出各骨骼的局部变换, 再由骨架计算蒙皮矩阵
+package skin
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// MaxInfluences 每个顶点最多受影响的骨骼数
+const MaxInfluences = 4
+
+// Transform 平移, 旋转和缩放组成的局部变换
+type Transform struct {
+	Translation mgl32.Vec3
+	Rotation    mgl32.Quat
+	Scale       mgl32.Vec3
+}
+
+func IdentityTransform() Transform {
+	return Transform{Rotation: mgl32.QuatIdent(), Scale: mgl32.Vec3{1, 1, 1}}
+}
+
+func (t Transform) Mat4() mgl32.Mat4 {
+	m := mgl32.Translate3D(t.Translation.X(), t.Translation.Y(), t.Translation.Z())
+	m = m.Mul4(t.Rotation.Mat4())
+	return m.Mul4(mgl32.Scale3D(t.Scale.X(), t.Scale.Y(), t.Scale.Z()))
+}
+
+// Bone 骨骼, Bind 为绑定姿势下相对于父骨骼的变换
+type Bone struct {
+	Name   string
+	Parent int // 根骨骼为 -1
+	Bind   Transform
+}
+
+// Skeleton 骨架, 创建时根据绑定姿势计算每根骨骼的逆绑定矩阵
+type Skeleton struct {
+	Bones       []Bone
+	inverseBind []mgl32.Mat4
+}
+
+func NewSkeleton(bones []Bone) (*Skeleton, error) {
+	s := &Skeleton{
+		Bones:       bones,
+		inverseBind: make([]mgl32.Mat4, len(bones)),
+	}
+	global := make([]mgl32.Mat4, len(bones))
+	for i, bone := range bones {
+		if bone.Parent >= i {
+			return nil, fmt.Errorf("bone %s: parent %d must come before it", bone.Name, bone.Parent)
+		}
+		global[i] = bone.Bind.Mat4()
+		if bone.Parent >= 0 {
+			global[i] = global[bone.Parent].Mul4(global[i])
+		}
+		s.inverseBind[i] = global[i].Inv()
+	}
+	return s, nil
+}
+
+// BindPose 绑定姿势的局部变换
+func (s *Skeleton) BindPose(local []Transform) {
+	for i, bone := range s.Bones {
+		local[i] = bone.Bind
+	}
+}
+
+// Skin 由局部变换计算蒙皮矩阵, 把绑定姿势下的模型空间顶点变换到当前姿势, out 同时作为全局变换的临时空间
+func (s *Skeleton) Skin(local []Transform, out []mgl32.Mat4) {
+	for i, bone := range s.Bones {
+		out[i] = local[i].Mat4()
+		if bone.Parent >= 0 {
+			out[i] = out[bone.Parent].Mul4(out[i])
+		}
+	}
+	for i := range s.Bones {
+		out[i] = out[i].Mul4(s.inverseBind[i])
+	}
+}
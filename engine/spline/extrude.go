@@ -0,0 +1,111 @@
+package spline
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+)
+
+// ExtrudeOptions 挤出参数
+type ExtrudeOptions struct {
+	Spacing float32 // 曲线采样间距
+	Tiling  float32 // 纹理沿曲线方向重复一次的长度
+
+	// 不为空时采样点高度贴合地形, 返回 false 表示该位置没有地形
+	HeightAt     func(x, z float32) (float32, bool)
+	HeightOffset float32 // 贴合地形时抬高的距离, 避免与地面深度冲突
+}
+
+// frame 曲线采样点处的局部坐标系
+type frame struct {
+	position mgl32.Vec3
+	right    mgl32.Vec3
+	up       mgl32.Vec3
+	distance float32 // 从起点开始的弧长
+}
+
+// Extrude 沿样条挤出截面生成三角形网格, 控制点不足时返回 nil
+func Extrude(s *Spline, profile Profile, opts ExtrudeOptions) *mesh.Mesh {
+	samples := s.Sample(opts.Spacing)
+	if len(samples) < 2 || len(profile.Edges) == 0 {
+		return nil
+	}
+	if opts.HeightAt != nil {
+		for i, p := range samples {
+			if height, ok := opts.HeightAt(p.X(), p.Z()); ok {
+				samples[i][1] = height + opts.HeightOffset
+			}
+		}
+	}
+	frames := buildFrames(samples)
+
+	tiling := opts.Tiling
+	if tiling <= 0 {
+		tiling = 1
+	}
+
+	vertices := make([]mesh.Vertex, 0, len(frames)*len(profile.Edges)*2)
+	indices := make([]uint32, 0, (len(frames)-1)*len(profile.Edges)*6)
+	for _, edge := range profile.Edges {
+		base := uint32(len(vertices))
+		for _, f := range frames {
+			for _, pv := range edge {
+				normal := f.right.Mul(pv.Normal.X()).Add(f.up.Mul(pv.Normal.Y())).Normalize()
+				tangent := f.right.Mul(edge[1].Position.X() - edge[0].Position.X()).
+					Add(f.up.Mul(edge[1].Position.Y() - edge[0].Position.Y())).Normalize()
+				uv := mgl32.Vec2{pv.U, f.distance / tiling}
+				vertices = append(vertices, mesh.Vertex{
+					Position:   f.position.Add(f.right.Mul(pv.Position.X())).Add(f.up.Mul(pv.Position.Y())),
+					Color:      mgl32.Vec3{1, 1, 1},
+					Normal:     normal,
+					TexCoords:  uv,
+					TexCoords2: uv,
+					Tangent:    tangent,
+					Bitangent:  normal.Cross(tangent),
+				})
+			}
+		}
+		for i := uint32(0); i+1 < uint32(len(frames)); i++ {
+			a0, b0 := base+i*2, base+i*2+1
+			a1, b1 := a0+2, b0+2
+			indices = append(indices, a0, b0, a1, b0, b1, a1)
+		}
+	}
+
+	return mesh.NewMesh(vertices, indices, nil)
+}
+
+// buildFrames 由相邻采样点估算切线, 右方向取切线与世界上方向的叉积
+func buildFrames(samples []mgl32.Vec3) []frame {
+	worldUp := mgl32.Vec3{0, 1, 0}
+	frames := make([]frame, len(samples))
+	var distance float32
+	lastRight := mgl32.Vec3{1, 0, 0}
+	for i, p := range samples {
+		if i > 0 {
+			distance += p.Sub(samples[i-1]).Len()
+		}
+		prev, next := samples[max(i-1, 0)], samples[min(i+1, len(samples)-1)]
+		tangent := next.Sub(prev)
+
+		right := tangent.Cross(worldUp)
+		if right.Len() < 1e-6 {
+			// 竖直方向的曲线段沿用上一个右方向
+			right = lastRight
+		}
+		right = right.Normalize()
+		lastRight = right
+
+		up := right.Cross(tangent)
+		if up.Len() < 1e-6 {
+			up = worldUp
+		}
+
+		frames[i] = frame{
+			position: p,
+			right:    right,
+			up:       up.Normalize(),
+			distance: distance,
+		}
+	}
+	return frames
+}
@@ -0,0 +1,124 @@
+package spline
+
+import (
+	"errors"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+// Extrusion 样条挤出生成的网格对象
+type Extrusion struct {
+	Name string
+	Id   string
+
+	Mesh     *mesh.Mesh
+	Material *material.Material
+
+	effect *technique.LightingTechnique
+	shader *shader.Shader
+
+	Position mgl32.Vec3
+	model    mgl32.Mat4
+}
+
+func NewExtrusion(name, id string, m *mesh.Mesh, mat *material.Material) (*Extrusion, error) {
+	if m == nil || len(m.Vertices) == 0 {
+		return nil, errors.New("spline: empty extrusion mesh")
+	}
+
+	e := &Extrusion{
+		Name:     name,
+		Id:       id,
+		Mesh:     m,
+		Material: mat,
+		model:    mgl32.Ident4(),
+		effect:   &technique.LightingTechnique{},
+		shader: &shader.Shader{
+			VertFilePath: "./resource/model/spline/shader.vert",
+			FragFilePath: "./resource/model/spline/shader.frag",
+		},
+	}
+	if err := e.shader.Init(); err != nil {
+		return nil, err
+	}
+	e.effect.Init(e.shader)
+
+	e.Mesh.Name = name
+	e.Mesh.Setup()
+	e.Mesh.ComputeBounds()
+	return e, nil
+}
+
+// WorldBounds 世界空间包围盒
+func (e *Extrusion) WorldBounds() geometry.AABB {
+	return e.Mesh.Bounds.Transform(e.model)
+}
+
+func (e *Extrusion) Dispose() {
+	e.Mesh.Dispose()
+}
+
+func (e *Extrusion) SetPosition(p mgl32.Vec3) {
+	e.Position = p
+	e.model = mgl32.Translate3D(p.X(), p.Y(), p.Z())
+}
+
+func (e *Extrusion) ModelMatrix() mgl32.Mat4 {
+	return e.model
+}
+
+func (e *Extrusion) Update(elapsed float64) {
+	e.Material.Update(elapsed)
+}
+
+func (e *Extrusion) PreRender() {
+}
+
+func (e *Extrusion) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	model = model.Mul4(e.model)
+	mvp := projection.Mul4(view).Mul4(model)
+
+	e.effect.Enable()
+	e.effect.SetProjectMatrix(&projection)
+	e.effect.SetViewMatrix(&view)
+	e.effect.SetModelMatrix(&model)
+	e.effect.SetWVP(&mvp)
+	e.effect.SetEyeWorldPos(eyePosition)
+
+	e.effect.SetPointLight(lights)
+	e.effect.SetAmbientOcclusion(config.SSAOActive())
+	e.effect.SetFog(config.Config.Fog)
+	e.effect.SetInstanced(false)
+	e.effect.SetMaterial(e.Material)
+	e.effect.SetDiffuseMap(false)
+
+	gl.BindFragDataLocation(e.effect.ShaderObj.Program, 0, gl.Str("color\x00"))
+	e.Mesh.Draw(e.effect.ShaderObj.Program)
+	e.effect.Disable()
+}
+
+func (e *Extrusion) GetMaterial() *material.Material {
+	return e.Material
+}
+
+// Transparent 材质使用 alpha 混合时在透明队列中绘制
+func (e *Extrusion) Transparent() bool {
+	return e.Material.Transparent()
+}
+
+// RenderGeometry 使用外部technique绘制几何体, 投影和视图矩阵由调用方设置
+func (e *Extrusion) RenderGeometry(t *technique.BaseTechnique) {
+	t.SetModelMatrix(&e.model)
+	e.Mesh.Draw(t.ShaderObj.Program)
+}
+
+func (e *Extrusion) PostRender() {
+}
@@ -0,0 +1,73 @@
+package spline
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ProfileType 预设截面类型
+type ProfileType int32
+
+const (
+	ProfileRoad  ProfileType = iota // 道路, 水平带状
+	ProfilePipe                     // 管道, 圆形截面
+	ProfileFence                    // 围栏, 竖直薄墙
+)
+
+var ProfileTypeNames = []string{"Road", "Pipe", "Fence"}
+
+// ProfileVertex 截面顶点, Position 和 Normal 位于垂直于曲线的平面内, X 指向右侧, Y 指向上方
+type ProfileVertex struct {
+	Position mgl32.Vec2
+	Normal   mgl32.Vec2
+	U        float32 // 横向纹理坐标
+}
+
+// Profile 沿曲线挤出的截面, 每条边挤出为一条四边形带, 边之间不共享顶点以保留硬边
+// 边的方向逆时针旋转90°为正面朝向
+type Profile struct {
+	Edges [][2]ProfileVertex
+}
+
+// NewRoadProfile 宽度为 width 的路面
+func NewRoadProfile(width float32) Profile {
+	half := width / 2
+	up := mgl32.Vec2{0, 1}
+	return Profile{Edges: [][2]ProfileVertex{
+		{{Position: mgl32.Vec2{-half, 0}, Normal: up, U: 0}, {Position: mgl32.Vec2{half, 0}, Normal: up, U: 1}},
+	}}
+}
+
+// NewPipeProfile 半径为 radius, sides 个侧面的管道, 管道中心抬高 radius
+func NewPipeProfile(radius float32, sides int) Profile {
+	if sides < 3 {
+		sides = 3
+	}
+	vertex := func(i int) ProfileVertex {
+		angle := 2 * math.Pi * float64(i) / float64(sides)
+		normal := mgl32.Vec2{float32(math.Cos(angle)), float32(math.Sin(angle))}
+		return ProfileVertex{
+			Position: normal.Mul(radius).Add(mgl32.Vec2{0, radius}),
+			Normal:   normal,
+			U:        float32(i) / float32(sides),
+		}
+	}
+
+	p := Profile{Edges: make([][2]ProfileVertex, 0, sides)}
+	for i := 0; i < sides; i++ {
+		p.Edges = append(p.Edges, [2]ProfileVertex{vertex(i + 1), vertex(i)})
+	}
+	return p
+}
+
+// NewFenceProfile 高度为 height, 厚度为 thickness 的围栏
+func NewFenceProfile(height, thickness float32) Profile {
+	half := thickness / 2
+	left, right, up := mgl32.Vec2{-1, 0}, mgl32.Vec2{1, 0}, mgl32.Vec2{0, 1}
+	return Profile{Edges: [][2]ProfileVertex{
+		{{Position: mgl32.Vec2{-half, 0}, Normal: left, U: 0}, {Position: mgl32.Vec2{-half, height}, Normal: left, U: 1}},
+		{{Position: mgl32.Vec2{half, height}, Normal: right, U: 0}, {Position: mgl32.Vec2{half, 0}, Normal: right, U: 1}},
+		{{Position: mgl32.Vec2{-half, height}, Normal: up, U: 0}, {Position: mgl32.Vec2{half, height}, Normal: up, U: 1}},
+	}}
+}
@@ -0,0 +1,92 @@
+package spline
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Spline 经过所有控制点的 Catmull-Rom 样条
+type Spline struct {
+	Points []mgl32.Vec3
+	Closed bool
+}
+
+// Segments 曲线段数
+func (s *Spline) Segments() int {
+	n := len(s.Points)
+	if n < 2 {
+		return 0
+	}
+	if s.Closed && n > 2 {
+		return n
+	}
+	return n - 1
+}
+
+// point 控制点, 开放曲线两端取端点, 闭合曲线首尾相接
+func (s *Spline) point(i int) mgl32.Vec3 {
+	n := len(s.Points)
+	if s.Closed && n > 2 {
+		return s.Points[((i%n)+n)%n]
+	}
+	if i < 0 {
+		i = 0
+	} else if i >= n {
+		i = n - 1
+	}
+	return s.Points[i]
+}
+
+// Evaluate 曲线上的点, t 取值 [0, Segments()], 整数部分为曲线段序号
+func (s *Spline) Evaluate(t float32) mgl32.Vec3 {
+	segments := s.Segments()
+	if segments == 0 {
+		if len(s.Points) == 1 {
+			return s.Points[0]
+		}
+		return mgl32.Vec3{}
+	}
+	if t < 0 {
+		t = 0
+	} else if t > float32(segments) {
+		t = float32(segments)
+	}
+	i := int(math.Floor(float64(t)))
+	if i >= segments {
+		i = segments - 1
+	}
+	f := t - float32(i)
+
+	p0, p1, p2, p3 := s.point(i-1), s.point(i), s.point(i+1), s.point(i+2)
+	f2 := f * f
+	f3 := f2 * f
+	// 0.5 * (2p1 + (-p0 + p2)f + (2p0 - 5p1 + 4p2 - p3)f² + (-p0 + 3p1 - 3p2 + p3)f³)
+	return p1.Mul(2).
+		Add(p2.Sub(p0).Mul(f)).
+		Add(p0.Mul(2).Sub(p1.Mul(5)).Add(p2.Mul(4)).Sub(p3).Mul(f2)).
+		Add(p1.Mul(3).Sub(p0).Sub(p2.Mul(3)).Add(p3).Mul(f3)).
+		Mul(0.5)
+}
+
+// Sample 沿曲线按约 spacing 的间距采样, 每段的采样数由控制点间距估算
+func (s *Spline) Sample(spacing float32) []mgl32.Vec3 {
+	segments := s.Segments()
+	if segments == 0 || spacing <= 0 {
+		return nil
+	}
+
+	samples := make([]mgl32.Vec3, 0)
+	for i := 0; i < segments; i++ {
+		chord := s.point(i + 1).Sub(s.point(i)).Len()
+		steps := int(math.Ceil(float64(chord / spacing)))
+		if steps < 1 {
+			steps = 1
+		}
+		for j := 0; j < steps; j++ {
+			samples = append(samples, s.Evaluate(float32(i)+float32(j)/float32(steps)))
+		}
+	}
+	samples = append(samples, s.Evaluate(float32(segments)))
+	return samples
+}
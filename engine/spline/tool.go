@@ -0,0 +1,194 @@
+package spline
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/debugdraw"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+var (
+	pointColor = mgl32.Vec3{0.0, 1.0, 1.0}
+	curveColor = mgl32.Vec3{1.0, 0.5, 0.0}
+)
+
+// 截面的材质颜色
+var profileColors = []mgl32.Vec3{
+	{0.25, 0.25, 0.25},
+	{0.6, 0.6, 0.65},
+	{0.55, 0.4, 0.25},
+}
+
+// Tool 样条挤出工具, 在场景中点击添加控制点, 构建时沿曲线挤出所选截面
+// 点击位置由外部拾取得到世界坐标后通过 AddPoint 传入
+type Tool struct {
+	Active bool
+
+	Profile   ProfileType
+	Width     float32 // 道路宽度
+	Radius    float32 // 管道半径
+	Sides     int32   // 管道侧面数
+	Height    float32 // 围栏高度
+	Thickness float32 // 围栏厚度
+	Tiling    float32
+	Spacing   float32
+	Closed    bool
+
+	// 贴合地形高度
+	ConformToTerrain bool
+
+	spline Spline
+
+	pending    bool
+	pendingPos [2]float32
+	build      bool
+	built      int
+}
+
+func NewTool() *Tool {
+	return &Tool{
+		Profile:          ProfileRoad,
+		Width:            4,
+		Radius:           0.5,
+		Sides:            12,
+		Height:           1.5,
+		Thickness:        0.1,
+		Tiling:           4,
+		Spacing:          0.5,
+		ConformToTerrain: true,
+	}
+}
+
+func (t *Tool) SetActive(active bool) {
+	t.Active = active
+	t.pending = false
+}
+
+func (t *Tool) Clear() {
+	t.spline.Points = t.spline.Points[:0]
+	t.pending = false
+}
+
+// HandleInput 记录视口中的鼠标点击, Esc 清除控制点, Enter 构建网格, 需在 imgui.NewFrame 之后调用
+func (t *Tool) HandleInput() {
+	if !t.Active {
+		return
+	}
+	io := imgui.CurrentIO()
+	if !io.WantCaptureKeyboard() {
+		if imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyEscape), false) {
+			t.Clear()
+		}
+		if imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyEnter), false) {
+			t.RequestBuild()
+		}
+	}
+	if io.WantCaptureMouse() || !imgui.IsMouseClicked(0) {
+		return
+	}
+	pos := imgui.MousePos()
+	t.pending = true
+	t.pendingPos = [2]float32{pos.X, pos.Y}
+}
+
+// PendingClick 尚未拾取的点击位置(窗口坐标), 取出后即清除
+func (t *Tool) PendingClick() ([2]float32, bool) {
+	if !t.pending {
+		return [2]float32{}, false
+	}
+	t.pending = false
+	return t.pendingPos, true
+}
+
+func (t *Tool) AddPoint(p mgl32.Vec3) {
+	t.spline.Points = append(t.spline.Points, p)
+}
+
+// RemoveLastPoint 撤销最后一个控制点
+func (t *Tool) RemoveLastPoint() {
+	if n := len(t.spline.Points); n > 0 {
+		t.spline.Points = t.spline.Points[:n-1]
+	}
+}
+
+func (t *Tool) NumPoints() int {
+	return len(t.spline.Points)
+}
+
+// RequestBuild 请求在下一帧构建网格, 构建需要 GL 上下文, 由外部在渲染循环中调用 Build
+func (t *Tool) RequestBuild() {
+	t.build = true
+}
+
+// TakeBuildRequest 是否有待处理的构建请求, 取出后即清除
+func (t *Tool) TakeBuildRequest() bool {
+	build := t.build
+	t.build = false
+	return build
+}
+
+// CurrentProfile 按当前参数生成的截面
+func (t *Tool) CurrentProfile() Profile {
+	switch t.Profile {
+	case ProfilePipe:
+		return NewPipeProfile(t.Radius, int(t.Sides))
+	case ProfileFence:
+		return NewFenceProfile(t.Height, t.Thickness)
+	default:
+		return NewRoadProfile(t.Width)
+	}
+}
+
+// Build 沿当前控制点挤出网格, heightAt 为空时不贴合地形, 成功后清除控制点
+func (t *Tool) Build(heightAt func(x, z float32) (float32, bool)) (*Extrusion, error) {
+	t.spline.Closed = t.Closed
+	opts := ExtrudeOptions{
+		Spacing:      t.Spacing,
+		Tiling:       t.Tiling,
+		HeightOffset: 0.02,
+	}
+	if t.ConformToTerrain {
+		opts.HeightAt = heightAt
+	}
+
+	m := Extrude(&t.spline, t.CurrentProfile(), opts)
+	if m == nil {
+		return nil, fmt.Errorf("spline: at least 2 points are required")
+	}
+
+	color := profileColors[t.Profile]
+	mat := &material.Material{
+		Name:          ProfileTypeNames[t.Profile],
+		AmbientColor:  color.Mul(0.3),
+		DiffuseColor:  color,
+		SpecularColor: mgl32.Vec3{0.2, 0.2, 0.2},
+		Shininess:     4,
+		Opacity:       1,
+	}
+
+	t.built++
+	name := fmt.Sprintf("%s_%d", ProfileTypeNames[t.Profile], t.built)
+	e, err := NewExtrusion(name, fmt.Sprintf("spline-%d", t.built), m, mat)
+	if err != nil {
+		return nil, err
+	}
+	t.Clear()
+	return e, nil
+}
+
+// Draw 绘制控制点和曲线预览
+func (t *Tool) Draw(dd *debugdraw.DebugDraw) {
+	if !t.Active {
+		return
+	}
+	for _, p := range t.spline.Points {
+		dd.AddCross(p, 0.5, pointColor)
+	}
+	t.spline.Closed = t.Closed
+	samples := t.spline.Sample(t.Spacing)
+	for i := 1; i < len(samples); i++ {
+		dd.AddOverlayLine(samples[i-1], samples[i], curveColor)
+	}
+}
@@ -0,0 +1,200 @@
+package sprite
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/rhi"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+)
+
+// MaxAtlasSize 图集纹理的最大边长, 放不下时 Add 返回错误
+const MaxAtlasSize = 4096
+
+// 每张图片四周复制一像素的边缘, 线性过滤时不会采样到相邻的图片
+const atlasPadding = 1
+
+// Region 图集中的一张图片, UV0 和 UV1 为左上角和右下角的纹理坐标, Width 和 Height 为像素大小
+type Region struct {
+	UV0    mgl32.Vec2
+	UV1    mgl32.Vec2
+	Width  int32
+	Height int32
+}
+
+// shelf 一行图片, 高度由这一行的第一张图片决定, x 为下一张图片的位置
+type shelf struct {
+	y      int
+	height int
+	x      int
+}
+
+// Atlas 运行时图集, 把精灵, 图标等小图打包到一张纹理中, 使用同一图集的四边形合并为一次绘制
+// 按货架算法逐行摆放, 放不下时边长加倍, 已有图片的位置不变, 纹理坐标在查询时按当前大小计算
+type Atlas struct {
+	device  rhi.Device
+	image   *image.RGBA
+	shelves []shelf
+	rects   map[string]image.Rectangle
+
+	texture rhi.Texture
+	dirty   bool
+}
+
+// NewAtlas 创建边长为 size 的空图集, 纹理在第一次使用时创建
+func NewAtlas(device rhi.Device, size int) *Atlas {
+	size = min(max(size, 64), MaxAtlasSize)
+	return &Atlas{
+		device: device,
+		image:  image.NewRGBA(image.Rect(0, 0, size, size)),
+		rects:  make(map[string]image.Rectangle),
+	}
+}
+
+// Add 把图片加入图集, 同名的图片已存在时替换为新图片, 大小不同时重新摆放
+// 图集可能变大, 本帧已经用这个图集绘制过时在 Flush 之后再添加
+func (a *Atlas) Add(name string, img image.Image) error {
+	size := img.Bounds().Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return fmt.Errorf("atlas image %s is empty", name)
+	}
+	if r, ok := a.rects[name]; ok && r.Size() == size {
+		a.blit(r.Min, img)
+		a.dirty = true
+		return nil
+	}
+
+	at, ok := a.place(size.X, size.Y)
+	for !ok {
+		if err := a.grow(); err != nil {
+			return fmt.Errorf("atlas image %s: %w", name, err)
+		}
+		at, ok = a.place(size.X, size.Y)
+	}
+	a.rects[name] = a.blit(at, img)
+	a.dirty = true
+	return nil
+}
+
+// AddFile 读取图片文件并加入图集
+func (a *Atlas) AddFile(name, file string) error {
+	rgba, err := texture.ImageToPixelData(file)
+	if err != nil {
+		return err
+	}
+	return a.Add(name, rgba)
+}
+
+// Region 图片在图集中的位置, 图集变大后之前返回的纹理坐标失效, 需要重新查询
+func (a *Atlas) Region(name string) (Region, bool) {
+	r, ok := a.rects[name]
+	if !ok {
+		return Region{}, false
+	}
+	size := a.image.Rect.Size()
+	w, h := float32(size.X), float32(size.Y)
+	return Region{
+		UV0:    mgl32.Vec2{float32(r.Min.X) / w, float32(r.Min.Y) / h},
+		UV1:    mgl32.Vec2{float32(r.Max.X) / w, float32(r.Max.Y) / h},
+		Width:  int32(r.Dx()),
+		Height: int32(r.Dy()),
+	}, true
+}
+
+// Len 图集中的图片数量
+func (a *Atlas) Len() int {
+	return len(a.rects)
+}
+
+// Size 图集纹理的边长
+func (a *Atlas) Size() int {
+	return a.image.Rect.Dx()
+}
+
+// Texture 图集的纹理, 有新图片时重新上传, 变大时重新创建, 必须在主线程调用
+func (a *Atlas) Texture() (rhi.Texture, error) {
+	size := int32(a.image.Rect.Dx())
+	if a.texture != nil && a.texture.Width() != size {
+		a.texture.Dispose()
+		a.texture = nil
+	}
+	if a.texture == nil {
+		tex, err := a.device.NewTexture(rhi.TextureDesc{Width: size, Height: size, Format: rhi.FormatRGBA8})
+		if err != nil {
+			return nil, err
+		}
+		a.texture = tex
+		a.dirty = true
+	}
+	if a.dirty {
+		a.texture.Upload(unsafe.Pointer(&a.image.Pix[0]))
+		a.dirty = false
+	}
+	return a.texture, nil
+}
+
+func (a *Atlas) Dispose() {
+	if a.texture != nil {
+		a.texture.Dispose()
+		a.texture = nil
+	}
+}
+
+// place 为 w x h 的图片(加上边缘)找位置, 优先放进高度最接近的已有行, 否则新开一行
+func (a *Atlas) place(w, h int) (image.Point, bool) {
+	w, h = w+2*atlasPadding, h+2*atlasPadding
+	size := a.image.Rect.Size()
+	best := -1
+	for i, s := range a.shelves {
+		if h <= s.height && s.x+w <= size.X && (best < 0 || s.height < a.shelves[best].height) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		s := &a.shelves[best]
+		at := image.Pt(s.x, s.y)
+		s.x += w
+		return at, true
+	}
+
+	y := 0
+	if n := len(a.shelves); n > 0 {
+		y = a.shelves[n-1].y + a.shelves[n-1].height
+	}
+	if w > size.X || y+h > size.Y {
+		return image.Point{}, false
+	}
+	a.shelves = append(a.shelves, shelf{y: y, height: h, x: w})
+	return image.Pt(0, y), true
+}
+
+// grow 边长加倍, 已有的图片保持在原来的像素位置
+func (a *Atlas) grow() error {
+	size := a.image.Rect.Dx() * 2
+	if size > MaxAtlasSize {
+		return fmt.Errorf("atlas exceeds %dx%d", MaxAtlasSize, MaxAtlasSize)
+	}
+	grown := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(grown, a.image.Rect, a.image, image.Point{}, draw.Src)
+	a.image = grown
+	return nil
+}
+
+// blit 把图片复制到 at 处并向外复制一像素边缘, 返回图片本身所在的矩形
+func (a *Atlas) blit(at image.Point, img image.Image) image.Rectangle {
+	b := img.Bounds()
+	r := image.Rectangle{Min: at.Add(image.Pt(atlasPadding, atlasPadding)), Max: at.Add(image.Pt(atlasPadding, atlasPadding)).Add(b.Size())}
+	draw.Draw(a.image, r, img, b.Min, draw.Src)
+	for x := r.Min.X; x < r.Max.X; x++ {
+		a.image.SetRGBA(x, r.Min.Y-1, a.image.RGBAAt(x, r.Min.Y))
+		a.image.SetRGBA(x, r.Max.Y, a.image.RGBAAt(x, r.Max.Y-1))
+	}
+	for y := r.Min.Y - 1; y <= r.Max.Y; y++ {
+		a.image.SetRGBA(r.Min.X-1, y, a.image.RGBAAt(r.Min.X, y))
+		a.image.SetRGBA(r.Max.X, y, a.image.RGBAAt(r.Max.X-1, y))
+	}
+	return r
+}
@@ -0,0 +1,192 @@
+// Package sprite 二维精灵和 HUD 层, 在三维场景之后, imgui 之前以正交投影绘制
+package sprite
+
+import (
+	"unsafe"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/rhi"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+)
+
+type vertex struct {
+	Position mgl32.Vec2
+	TexCoord mgl32.Vec2
+	Color    mgl32.Vec4
+}
+
+// batch 使用同一纹理的连续四边形
+type batch struct {
+	texture rhi.Texture
+	first   int32
+	count   int32
+}
+
+// Layer 二维绘制层, 坐标为窗口像素, 原点在左上角
+// 每帧收集四边形, 按提交顺序绘制, 相邻且纹理相同的四边形合并为一次绘制
+type Layer struct {
+	vertices []vertex
+	batches  []batch
+
+	device   rhi.Device
+	pipeline rhi.Pipeline
+	buffer   rhi.Buffer
+	// 纯色四边形使用的 1x1 白色纹理
+	white rhi.Texture
+}
+
+func NewLayer(device rhi.Device) (*Layer, error) {
+	l := &Layer{
+		vertices: make([]vertex, 0),
+		batches:  make([]batch, 0),
+		device:   device,
+	}
+
+	var dummy vertex
+	var err error
+	l.pipeline, err = device.NewPipeline(rhi.PipelineDesc{
+		VertFilePath: "./resource/shader/sprite.vert",
+		FragFilePath: "./resource/shader/sprite.frag",
+		Layout: rhi.VertexLayout{
+			Stride: int32(unsafe.Sizeof(dummy)),
+			Attributes: []rhi.VertexAttribute{
+				{Location: 0, Components: 2, Type: rhi.AttribFloat, Offset: 0},
+				{Location: 1, Components: 2, Type: rhi.AttribFloat, Offset: int(unsafe.Offsetof(dummy.TexCoord))},
+				{Location: 2, Components: 4, Type: rhi.AttribFloat, Offset: int(unsafe.Offsetof(dummy.Color))},
+			},
+		},
+		Topology: rhi.TopologyTriangles,
+		Blend:    rhi.BlendAlpha,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if l.buffer, err = device.NewBuffer(rhi.BufferDesc{Type: rhi.BufferVertex, Usage: rhi.UsageStream}); err != nil {
+		return nil, err
+	}
+
+	if l.white, err = device.NewTexture(rhi.TextureDesc{Width: 1, Height: 1, Format: rhi.FormatRGBA8, Filter: rhi.FilterNearest}); err != nil {
+		return nil, err
+	}
+	white := [4]uint8{255, 255, 255, 255}
+	l.white.Upload(unsafe.Pointer(&white[0]))
+	return l, nil
+}
+
+// LoadTexture 读取图片文件创建精灵纹理
+func LoadTexture(device rhi.Device, file string) (rhi.Texture, error) {
+	rgba, err := texture.ImageToPixelData(file)
+	if err != nil {
+		return nil, err
+	}
+	size := rgba.Rect.Size()
+	tex, err := device.NewTexture(rhi.TextureDesc{
+		Width:   int32(size.X),
+		Height:  int32(size.Y),
+		Format:  rhi.FormatRGBA8,
+		Mipmaps: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tex.Upload(unsafe.Pointer(&rgba.Pix[0]))
+	return tex, nil
+}
+
+// DrawRect 纯色矩形
+func (l *Layer) DrawRect(x, y, width, height float32, color mgl32.Vec4) {
+	l.DrawTextureRegion(l.white, x, y, width, height, mgl32.Vec2{0, 0}, mgl32.Vec2{1, 1}, color)
+}
+
+// DrawTexture 绘制整张纹理, color 与纹理颜色相乘
+func (l *Layer) DrawTexture(tex rhi.Texture, x, y, width, height float32, color mgl32.Vec4) {
+	l.DrawTextureRegion(tex, x, y, width, height, mgl32.Vec2{0, 0}, mgl32.Vec2{1, 1}, color)
+}
+
+// DrawTextureRegion 绘制纹理的一部分, uv0 和 uv1 为左上角和右下角的纹理坐标, 用于图集
+func (l *Layer) DrawTextureRegion(tex rhi.Texture, x, y, width, height float32, uv0, uv1 mgl32.Vec2, color mgl32.Vec4) {
+	if tex == nil {
+		tex = l.white
+	}
+	first := int32(len(l.vertices))
+	l.vertices = append(l.vertices,
+		vertex{mgl32.Vec2{x, y}, uv0, color},
+		vertex{mgl32.Vec2{x, y + height}, mgl32.Vec2{uv0.X(), uv1.Y()}, color},
+		vertex{mgl32.Vec2{x + width, y + height}, uv1, color},
+		vertex{mgl32.Vec2{x, y}, uv0, color},
+		vertex{mgl32.Vec2{x + width, y + height}, uv1, color},
+		vertex{mgl32.Vec2{x + width, y}, mgl32.Vec2{uv1.X(), uv0.Y()}, color},
+	)
+
+	if n := len(l.batches); n > 0 && l.batches[n-1].texture == tex {
+		l.batches[n-1].count += 6
+		return
+	}
+	l.batches = append(l.batches, batch{texture: tex, first: first, count: 6})
+}
+
+// DrawAtlas 绘制图集中名为 name 的图片, 连续绘制同一图集的图片只需要一次绘制调用
+// 图集中没有这张图片时不绘制, 返回 false
+func (l *Layer) DrawAtlas(atlas *Atlas, name string, x, y, width, height float32, color mgl32.Vec4) bool {
+	region, ok := atlas.Region(name)
+	if !ok {
+		return false
+	}
+	tex, err := atlas.Texture()
+	if err != nil {
+		return false
+	}
+	l.DrawTextureRegion(tex, x, y, width, height, region.UV0, region.UV1, color)
+	return true
+}
+
+// DrawProgressBar 进度条, progress 取值 0 到 1, 从左向右填充
+func (l *Layer) DrawProgressBar(x, y, width, height, progress float32, foreground, background mgl32.Vec4) {
+	progress = mgl32.Clamp(progress, 0, 1)
+	l.DrawRect(x, y, width, height, background)
+	if progress > 0 {
+		l.DrawRect(x, y, width*progress, height, foreground)
+	}
+}
+
+// DrawCrosshair 以 (x, y) 为中心的十字准星, gap 为中心留空的半径
+func (l *Layer) DrawCrosshair(x, y, size, thickness, gap float32, color mgl32.Vec4) {
+	half := thickness / 2
+	arm := size/2 - gap
+	if arm <= 0 {
+		return
+	}
+	l.DrawRect(x-size/2, y-half, arm, thickness, color)
+	l.DrawRect(x+gap, y-half, arm, thickness, color)
+	l.DrawRect(x-half, y-size/2, thickness, arm, color)
+	l.DrawRect(x-half, y+gap, thickness, arm, color)
+}
+
+// Flush 以窗口大小建立正交投影, 绘制并清空本帧收集的四边形
+func (l *Layer) Flush(displaySize [2]float32) {
+	if len(l.vertices) == 0 {
+		return
+	}
+
+	l.buffer.Upload(unsafe.Pointer(&l.vertices[0]), len(l.vertices)*int(unsafe.Sizeof(l.vertices[0])))
+	l.pipeline.SetUniform("projection", mgl32.Ortho(0, displaySize[0], displaySize[1], 0, -1, 1))
+	l.pipeline.SetUniform("gTexture", int32(0))
+	for _, b := range l.batches {
+		l.device.Draw(rhi.DrawCall{
+			Pipeline:     l.pipeline,
+			VertexBuffer: l.buffer,
+			First:        b.first,
+			Count:        b.count,
+			Textures:     []rhi.Texture{b.texture},
+		})
+	}
+
+	l.vertices = l.vertices[:0]
+	l.batches = l.batches[:0]
+}
+
+func (l *Layer) Dispose() {
+	l.pipeline.Dispose()
+	l.buffer.Dispose()
+	l.white.Dispose()
+}
@@ -0,0 +1,240 @@
+package ssao
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+const (
+	MaxKernelSize = 64
+	noiseSize     = 4
+)
+
+// SSAO 屏幕空间环境光遮蔽
+// 1. 几何pass: 输出观察空间法线和深度
+// 2. SSAO pass: 由深度重建观察空间位置, 在法线半球内采样计算遮蔽
+// 3. 模糊pass: 消除噪声纹理带来的条纹
+type SSAO struct {
+	gBuffer    *framebuffer.FrameBuffer
+	aoBuffer   *framebuffer.FrameBuffer
+	blurBuffer *framebuffer.FrameBuffer
+
+	geometryEffect *technique.BaseTechnique
+	ssaoShader     *shader.Shader
+	blurShader     *shader.Shader
+
+	kernel       []mgl32.Vec3
+	noiseTexture uint32
+	quad         *mesh.Mesh
+}
+
+func NewSSAO(width, height int32) (*SSAO, error) {
+	s := &SSAO{
+		geometryEffect: &technique.BaseTechnique{},
+		ssaoShader: &shader.Shader{
+			VertFilePath: "./resource/shader/ssao.vert",
+			FragFilePath: "./resource/shader/ssao.frag",
+		},
+		blurShader: &shader.Shader{
+			VertFilePath: "./resource/shader/ssao.vert",
+			FragFilePath: "./resource/shader/ssao_blur.frag",
+		},
+	}
+	if err := s.Init(width, height); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SSAO) Init(width, height int32) error {
+	var err error
+	if s.gBuffer, err = framebuffer.NewFrameBuffer(width, height, true, framebuffer.RGB16F); err != nil {
+		return fmt.Errorf("ssao gbuffer: %w", err)
+	}
+	if s.aoBuffer, err = framebuffer.NewFrameBuffer(width, height, false, framebuffer.R16F); err != nil {
+		return fmt.Errorf("ssao buffer: %w", err)
+	}
+	if s.blurBuffer, err = framebuffer.NewFrameBuffer(width, height, false, framebuffer.R16F); err != nil {
+		return fmt.Errorf("ssao blur buffer: %w", err)
+	}
+
+	geometryShader := &shader.Shader{
+		VertFilePath: "./resource/shader/ssao_geometry.vert",
+		FragFilePath: "./resource/shader/ssao_geometry.frag",
+	}
+	if err := geometryShader.Init(); err != nil {
+		return err
+	}
+	s.geometryEffect.Init(geometryShader)
+
+	if err := s.ssaoShader.Init(); err != nil {
+		return err
+	}
+	if err := s.blurShader.Init(); err != nil {
+		return err
+	}
+
+	s.kernel = genKernel(MaxKernelSize)
+	s.noiseTexture = genNoiseTexture()
+	s.quad = mesh.NewMeshQuad()
+	return nil
+}
+
+func lerp(a, b, f float32) float32 {
+	return a + f*(b-a)
+}
+
+// genKernel 生成法线方向(z+)半球内的采样点, 越靠近中心越密集
+func genKernel(size int) []mgl32.Vec3 {
+	kernel := make([]mgl32.Vec3, 0, size)
+	for i := 0; i < size; i++ {
+		sample := mgl32.Vec3{
+			rand.Float32()*2.0 - 1.0,
+			rand.Float32()*2.0 - 1.0,
+			rand.Float32(),
+		}.Normalize().Mul(rand.Float32())
+
+		scale := float32(i) / float32(size)
+		scale = lerp(0.1, 1.0, scale*scale)
+		kernel = append(kernel, sample.Mul(scale))
+	}
+	return kernel
+}
+
+// genNoiseTexture 生成绕z轴旋转采样核的随机向量纹理, 平铺到整个屏幕
+func genNoiseTexture() uint32 {
+	noise := make([]float32, 0, noiseSize*noiseSize*3)
+	for i := 0; i < noiseSize*noiseSize; i++ {
+		noise = append(noise, rand.Float32()*2.0-1.0, rand.Float32()*2.0-1.0, 0.0)
+	}
+
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGB16F, noiseSize, noiseSize, 0, gl.RGB, gl.FLOAT, gl.Ptr(noise))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return tex
+}
+
+func (s *SSAO) Resize(width, height int32) error {
+	if err := s.gBuffer.Resize(width, height); err != nil {
+		return err
+	}
+	if err := s.aoBuffer.Resize(width, height); err != nil {
+		return err
+	}
+	return s.blurBuffer.Resize(width, height)
+}
+
+// Render 计算环境光遮蔽, 结果保存在 AOTexture
+func (s *SSAO) Render(renderObjs []model.RenderObj, projection, view mgl32.Mat4) {
+	var lastViewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &lastViewport[0])
+
+	s.renderGeometry(renderObjs, projection, view)
+
+	gl.Disable(gl.DEPTH_TEST)
+	s.renderOcclusion(projection)
+	s.renderBlur()
+	gl.Enable(gl.DEPTH_TEST)
+
+	s.blurBuffer.UnBind()
+	gl.Viewport(lastViewport[0], lastViewport[1], lastViewport[2], lastViewport[3])
+}
+
+func (s *SSAO) renderGeometry(renderObjs []model.RenderObj, projection, view mgl32.Mat4) {
+	s.gBuffer.Bind()
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gl.Enable(gl.DEPTH_TEST)
+
+	s.geometryEffect.Enable()
+	s.geometryEffect.SetProjectMatrix(&projection)
+	s.geometryEffect.SetViewMatrix(&view)
+	for _, renderObj := range renderObjs {
+		if obj, ok := renderObj.(model.GeometryObj); ok {
+			obj.RenderGeometry(s.geometryEffect)
+		}
+	}
+	s.geometryEffect.Disable()
+}
+
+func (s *SSAO) renderOcclusion(projection mgl32.Mat4) {
+	s.aoBuffer.Bind()
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	kernelSize := int(config.Config.SSAO.KernelSize)
+	if kernelSize > MaxKernelSize {
+		kernelSize = MaxKernelSize
+	}
+
+	s.ssaoShader.Use()
+	s.ssaoShader.SetUniform("projection", projection)
+	s.ssaoShader.SetUniform("invProjection", projection.Inv())
+	s.ssaoShader.SetUniform("gKernelSize", kernelSize)
+	s.ssaoShader.SetUniform("gRadius", config.Config.SSAO.Radius)
+	s.ssaoShader.SetUniform("gBias", config.Config.SSAO.Bias)
+	s.ssaoShader.SetUniform("gNoiseScale", mgl32.Vec2{
+		float32(s.aoBuffer.Width) / noiseSize,
+		float32(s.aoBuffer.Height) / noiseSize,
+	})
+	for i := 0; i < kernelSize; i++ {
+		s.ssaoShader.SetUniform(fmt.Sprintf("gSamples[%d]", i), s.kernel[i])
+	}
+
+	s.bindTexture(0, "gNormal", s.ssaoShader, s.gBuffer.ColorTexture(0))
+	s.bindTexture(1, "gDepth", s.ssaoShader, s.gBuffer.DepthTexture)
+	s.bindTexture(2, "gNoise", s.ssaoShader, s.noiseTexture)
+
+	s.quad.Draw(s.ssaoShader.Program)
+	s.ssaoShader.UnUse()
+}
+
+func (s *SSAO) renderBlur() {
+	s.blurBuffer.Bind()
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	s.blurShader.Use()
+	s.bindTexture(0, "gInput", s.blurShader, s.aoBuffer.ColorTexture(0))
+	s.quad.Draw(s.blurShader.Program)
+	s.blurShader.UnUse()
+}
+
+func (s *SSAO) bindTexture(unit uint32, name string, sh *shader.Shader, tex uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	sh.SetUniform(name, int(unit))
+}
+
+// AOTexture 模糊后的遮蔽纹理, 1表示无遮蔽
+func (s *SSAO) AOTexture() uint32 {
+	return s.blurBuffer.ColorTexture(0)
+}
+
+// BindAOTexture 将遮蔽纹理绑定到光照technique约定的纹理单元
+func (s *SSAO) BindAOTexture() {
+	gl.ActiveTexture(gl.TEXTURE0 + technique.TextureUnitAO)
+	gl.BindTexture(gl.TEXTURE_2D, s.AOTexture())
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func (s *SSAO) Dispose() {
+	s.gBuffer.Dispose()
+	s.aoBuffer.Dispose()
+	s.blurBuffer.Dispose()
+	s.quad.Dispose()
+	gl.DeleteTextures(1, &s.noiseTexture)
+}
@@ -15,6 +15,7 @@ type BaseTechnique struct {
 	modelUniform      int32
 	wvpUniform        int32
 	cameraUniform     int32
+	instancedUniform  int32
 }
 
 func (t *BaseTechnique) Init(shader *shader.Shader) {
@@ -26,6 +27,15 @@ func (t *BaseTechnique) Init(shader *shader.Shader) {
 	t.modelUniform = t.GetUniformLocation("model")
 	t.wvpUniform = t.GetUniformLocation("gWVP")
 	t.cameraUniform = t.GetUniformLocation("gViewPos")
+	t.instancedUniform = t.GetUniformLocation("gInstanced")
+}
+
+// Enable 着色器热重载后先重新获取 uniform 位置
+func (t *BaseTechnique) Enable() bool {
+	if t.Stale() {
+		t.Init(t.ShaderObj)
+	}
+	return t.Technique.Enable()
 }
 
 // SetWVP 设置模型-视图矩阵
@@ -59,3 +69,12 @@ func (t *BaseTechnique) SetTextureUnit(TextureUnit uint32) {
 func (t *BaseTechnique) SetEyeWorldPos(EyeWorldPos *mgl32.Vec3) {
 	gl.Uniform3f(t.cameraUniform, EyeWorldPos.X(), EyeWorldPos.Y(), EyeWorldPos.Z())
 }
+
+// SetInstanced 是否使用顶点属性中的实例变换矩阵
+func (t *BaseTechnique) SetInstanced(instanced bool) {
+	var v int32
+	if instanced {
+		v = 1
+	}
+	gl.Uniform1i(t.instancedUniform, v)
+}
@@ -0,0 +1,74 @@
+package technique
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+)
+
+// debugViewDefines 每种调试视图对应的着色器宏, 线框叠加使用纯色着色器, 不需要变体
+var debugViewDefines = map[config.DebugView]string{
+	config.DebugViewNormals:   "DEBUG_NORMALS",
+	config.DebugViewUVChecker: "DEBUG_UV_CHECKER",
+	config.DebugViewDepth:     "DEBUG_DEPTH",
+	config.DebugViewOverdraw:  "DEBUG_OVERDRAW",
+	config.DebugViewLighting:  "DEBUG_LIGHTING",
+}
+
+// DebugTechnique 调试视图, 同一份着色器按视图模式编译为不同的变体
+type DebugTechnique struct {
+	LightingTechnique
+
+	clipUniform int32
+}
+
+// NewDebugTechnique 编译 view 对应的着色器变体
+func NewDebugTechnique(view config.DebugView) (*DebugTechnique, error) {
+	define, ok := debugViewDefines[view]
+	if !ok {
+		return nil, fmt.Errorf("debug view %d has no shader variant", view)
+	}
+	s := &shader.Shader{
+		VertFilePath: "./resource/shader/debug_view.vert",
+		FragFilePath: "./resource/shader/debug_view.frag",
+	}
+	if err := s.InitVariant(shader.Defines{define: ""}); err != nil {
+		return nil, err
+	}
+	t := &DebugTechnique{}
+	t.Init(s)
+	return t, nil
+}
+
+func (t *DebugTechnique) Init(s *shader.Shader) {
+	t.LightingTechnique.Init(s)
+
+	t.clipUniform = t.GetUniformLocation("gClip")
+}
+
+// Enable 与 LightingTechnique.Enable 相同, 还要重新获取 gClip
+func (t *DebugTechnique) Enable() bool {
+	if t.Stale() {
+		t.Init(t.ShaderObj)
+	}
+	return t.Technique.Enable()
+}
+
+// SetClip 近远裁剪面, 用于还原线性深度
+func (t *DebugTechnique) SetClip(near, far float32) {
+	gl.Uniform2f(t.clipUniform, near, far)
+}
+
+// DebugViewVariants 需要着色器变体的调试视图
+func DebugViewVariants() []config.DebugView {
+	views := make([]config.DebugView, 0, len(debugViewDefines))
+	for view := range config.DebugViewNames {
+		if _, ok := debugViewDefines[config.DebugView(view)]; ok {
+			views = append(views, config.DebugView(view))
+		}
+	}
+	return views
+}
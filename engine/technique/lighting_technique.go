@@ -5,11 +5,27 @@ import (
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 
+	"github.com/huangxiaobo/toy-engine/engine/config"
 	"github.com/huangxiaobo/toy-engine/engine/light"
 	"github.com/huangxiaobo/toy-engine/engine/material"
 	"github.com/huangxiaobo/toy-engine/engine/shader"
 )
 
+// 固定用途的纹理单元, 避开网格自身纹理使用的低位单元
+const (
+	TextureUnitParams       = 4  // 材质参数中的贴图, 占用 MaxTextureParams 个单元
+	TextureUnitLightmap     = 8  // 光照贴图
+	TextureUnitEmissive     = 9  // 自发光贴图
+	TextureUnitShadow       = 10 // 点光源阴影立方体贴图, 占用 light.MaxShadowLights 个单元
+	TextureUnitFlipbook     = 12 // 序列帧图集
+	TextureUnitDetailAlbedo = 13 // 细节颜色贴图
+	TextureUnitDetailNormal = 14 // 细节法线贴图
+	TextureUnitAO           = 15 // 环境光遮蔽
+)
+
+// MaxTextureParams 材质参数中贴图的最大数量, 超过的贴图不绑定
+const MaxTextureParams = 4
+
 type LightUniform struct {
 	Color    int32
 	Position int32
@@ -23,6 +39,10 @@ type LightUniform struct {
 		Linear   int32
 		Exp      int32
 	}
+
+	ShadowIndex int32
+	ShadowFar   int32
+	ShadowBias  int32
 }
 
 type MaterialUniform struct {
@@ -30,6 +50,39 @@ type MaterialUniform struct {
 	DiffuseColor  int32 // 漫反射
 	SpecularColor int32 // 镜面反射
 	Shininess     int32 // 镜面反射光泽
+	Opacity       int32 // 不透明度
+	EmissiveColor int32 // 自发光, 已乘以强度
+
+	EmissiveMap       int32
+	EmissiveMapEnable int32
+
+	DetailAlbedoMap    int32
+	DetailNormalMap    int32
+	DetailAlbedoEnable int32
+	DetailNormalEnable int32
+	DetailTiling       int32
+	DetailUVSet        int32
+
+	DiffuseMapEnable int32
+
+	FlipbookMap    int32
+	FlipbookEnable int32
+	FlipbookFrame  int32
+	FlipbookNext   int32
+	FlipbookBlend  int32
+
+	UVTransform int32
+}
+
+type FogUniform struct {
+	Enable        int32
+	Mode          int32
+	Color         int32
+	Density       int32
+	Start         int32
+	End           int32
+	Height        int32
+	HeightFalloff int32
 }
 
 type LightingTechnique struct {
@@ -42,8 +95,21 @@ type LightingTechnique struct {
 
 	lightUniform    [8]LightUniform
 	lightNumUniform int32
+	shadowUniform   [light.MaxShadowLights]int32
 
 	materialUniform MaterialUniform
+
+	aoMapUniform    int32
+	aoEnableUniform int32
+
+	lightmapUniform          int32
+	lightmapEnableUniform    int32
+	lightmapIntensityUniform int32
+
+	fogUniform FogUniform
+
+	// 材质参数的 uniform 位置, 按名称在第一次使用时获取, 着色器重载后清空
+	paramUniforms map[string]int32
 }
 
 func (t *LightingTechnique) Init(s *shader.Shader) {
@@ -84,6 +150,13 @@ func (t *LightingTechnique) Init(s *shader.Shader) {
 
 		name = fmt.Sprintf("gLight[%d].Atten.Exp", i)
 		t.lightUniform[i].Atten.Exp = t.GetUniformLocation(name)
+
+		t.lightUniform[i].ShadowIndex = t.GetUniformLocation(fmt.Sprintf("gLight[%d].ShadowIndex", i))
+		t.lightUniform[i].ShadowFar = t.GetUniformLocation(fmt.Sprintf("gLight[%d].ShadowFar", i))
+		t.lightUniform[i].ShadowBias = t.GetUniformLocation(fmt.Sprintf("gLight[%d].ShadowBias", i))
+	}
+	for i := range t.shadowUniform {
+		t.shadowUniform[i] = t.GetUniformLocation(fmt.Sprintf("gShadowMap%d", i))
 	}
 
 	name = "gMaterial.AmbientColor"
@@ -94,6 +167,52 @@ func (t *LightingTechnique) Init(s *shader.Shader) {
 	t.materialUniform.SpecularColor = t.GetUniformLocation(name)
 	name = "gMaterial.Shininess"
 	t.materialUniform.Shininess = t.GetUniformLocation(name)
+	name = "gMaterial.Opacity"
+	t.materialUniform.Opacity = t.GetUniformLocation(name)
+
+	t.materialUniform.EmissiveColor = t.GetUniformLocation("gMaterial.EmissiveColor")
+	t.materialUniform.EmissiveMap = t.GetUniformLocation("gEmissiveMap")
+	t.materialUniform.EmissiveMapEnable = t.GetUniformLocation("gEmissiveMapEnable")
+
+	t.materialUniform.DetailAlbedoMap = t.GetUniformLocation("gDetailAlbedoMap")
+	t.materialUniform.DetailNormalMap = t.GetUniformLocation("gDetailNormalMap")
+	t.materialUniform.DetailAlbedoEnable = t.GetUniformLocation("gDetailAlbedoEnable")
+	t.materialUniform.DetailNormalEnable = t.GetUniformLocation("gDetailNormalEnable")
+	t.materialUniform.DetailTiling = t.GetUniformLocation("gDetailTiling")
+	t.materialUniform.DetailUVSet = t.GetUniformLocation("gDetailUVSet")
+	t.materialUniform.DiffuseMapEnable = t.GetUniformLocation("gDiffuseMapEnable")
+	t.materialUniform.FlipbookMap = t.GetUniformLocation("gFlipbookMap")
+	t.materialUniform.FlipbookEnable = t.GetUniformLocation("gFlipbookEnable")
+	t.materialUniform.FlipbookFrame = t.GetUniformLocation("gFlipbookFrame")
+	t.materialUniform.FlipbookNext = t.GetUniformLocation("gFlipbookNext")
+	t.materialUniform.FlipbookBlend = t.GetUniformLocation("gFlipbookBlend")
+	t.materialUniform.UVTransform = t.GetUniformLocation("gUVTransform")
+
+	t.aoMapUniform = t.GetUniformLocation("gAOMap")
+	t.aoEnableUniform = t.GetUniformLocation("gAOEnable")
+
+	t.lightmapUniform = t.GetUniformLocation("gLightmap")
+	t.lightmapEnableUniform = t.GetUniformLocation("gLightmapEnable")
+	t.lightmapIntensityUniform = t.GetUniformLocation("gLightmapIntensity")
+
+	t.fogUniform.Enable = t.GetUniformLocation("gFog.Enable")
+	t.fogUniform.Mode = t.GetUniformLocation("gFog.Mode")
+	t.fogUniform.Color = t.GetUniformLocation("gFog.Color")
+	t.fogUniform.Density = t.GetUniformLocation("gFog.Density")
+	t.fogUniform.Start = t.GetUniformLocation("gFog.Start")
+	t.fogUniform.End = t.GetUniformLocation("gFog.End")
+	t.fogUniform.Height = t.GetUniformLocation("gFog.Height")
+	t.fogUniform.HeightFalloff = t.GetUniformLocation("gFog.HeightFalloff")
+
+	t.paramUniforms = make(map[string]int32)
+}
+
+// Enable 热重载后重新获取光照和材质的 uniform 位置
+func (t *LightingTechnique) Enable() bool {
+	if t.Stale() {
+		t.Init(t.ShaderObj)
+	}
+	return t.Technique.Enable()
 }
 
 func (t *LightingTechnique) SetPointLight(lights []*light.PointLight) {
@@ -108,6 +227,30 @@ func (t *LightingTechnique) SetPointLight(lights []*light.PointLight) {
 		gl.Uniform1f(t.lightUniform[i].Atten.Linear, light.Atten.Linear)
 		gl.Uniform1f(t.lightUniform[i].Atten.Exp, light.Atten.Exp)
 	}
+	t.setShadows(lights)
+}
+
+// setShadows 把前 light.MaxShadowLights 个有阴影贴图的点光源绑定到 TextureUnitShadow 开始的纹理单元
+// 没有阴影的光源 ShadowIndex 为 -1
+func (t *LightingTechnique) setShadows(lights []*light.PointLight) {
+	shadowNum := 0
+	for i, l := range lights {
+		index := -1
+		if s := l.Shadow; s != nil && s.Enable && s.CubeMap != 0 && shadowNum < len(t.shadowUniform) {
+			index = shadowNum
+			shadowNum++
+			gl.ActiveTexture(gl.TEXTURE0 + TextureUnitShadow + uint32(index))
+			gl.BindTexture(gl.TEXTURE_CUBE_MAP, s.CubeMap)
+			gl.Uniform1f(t.lightUniform[i].ShadowFar, s.Far)
+			gl.Uniform1f(t.lightUniform[i].ShadowBias, s.Bias)
+		}
+		gl.Uniform1i(t.lightUniform[i].ShadowIndex, int32(index))
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+	// 采样器总是指向阴影单元, 避免与其他类型的采样器共用单元 0
+	for i, uniform := range t.shadowUniform {
+		gl.Uniform1i(uniform, TextureUnitShadow+int32(i))
+	}
 }
 
 func (t *LightingTechnique) SetMaterial(m *material.Material) {
@@ -115,4 +258,152 @@ func (t *LightingTechnique) SetMaterial(m *material.Material) {
 	gl.Uniform3f(t.materialUniform.DiffuseColor, m.DiffuseColor.X(), m.DiffuseColor.Y(), m.DiffuseColor.Z())
 	gl.Uniform3f(t.materialUniform.SpecularColor, m.SpecularColor.X(), m.SpecularColor.Y(), m.SpecularColor.Z())
 	gl.Uniform1f(t.materialUniform.Shininess, m.Shininess)
+
+	var opacity float32 = 1
+	if m.Transparent() {
+		opacity = m.Opacity
+	}
+	gl.Uniform1f(t.materialUniform.Opacity, opacity)
+
+	uvTransform := m.UV.Matrix()
+	gl.UniformMatrix3fv(t.materialUniform.UVTransform, 1, false, &uvTransform[0])
+
+	t.setEmissive(m)
+	t.setDetail(m.Detail)
+	t.setFlipbook(m.Flipbook)
+}
+
+// setEmissive 设置自发光颜色并绑定自发光贴图到 TextureUnitEmissive
+func (t *LightingTechnique) setEmissive(m *material.Material) {
+	emission := m.Emission()
+	gl.Uniform3f(t.materialUniform.EmissiveColor, emission.X(), emission.Y(), emission.Z())
+	gl.Uniform1i(t.materialUniform.EmissiveMapEnable, boolToInt32(m.EmissiveTex != 0))
+	gl.Uniform1i(t.materialUniform.EmissiveMap, TextureUnitEmissive)
+	gl.ActiveTexture(gl.TEXTURE0 + TextureUnitEmissive)
+	gl.BindTexture(gl.TEXTURE_2D, m.EmissiveTex)
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// SetDiffuseMap 是否使用网格的漫反射贴图(texture_diffuse1)
+func (t *LightingTechnique) SetDiffuseMap(enable bool) {
+	gl.Uniform1i(t.materialUniform.DiffuseMapEnable, boolToInt32(enable))
+}
+
+// setFlipbook 绑定序列帧图集到 TextureUnitFlipbook, 并设置当前帧和下一帧在图集中的位置
+func (t *LightingTechnique) setFlipbook(flipbook *material.Flipbook) {
+	var tex uint32
+	if flipbook != nil {
+		tex = flipbook.TextureId
+	}
+	gl.Uniform1i(t.materialUniform.FlipbookEnable, boolToInt32(tex != 0))
+	if tex != 0 {
+		frame, next, blend := flipbook.Frame()
+		frameRect, nextRect := flipbook.FrameRect(frame), flipbook.FrameRect(next)
+		gl.Uniform4fv(t.materialUniform.FlipbookFrame, 1, &frameRect[0])
+		gl.Uniform4fv(t.materialUniform.FlipbookNext, 1, &nextRect[0])
+		gl.Uniform1f(t.materialUniform.FlipbookBlend, blend)
+	}
+
+	gl.Uniform1i(t.materialUniform.FlipbookMap, TextureUnitFlipbook)
+	gl.ActiveTexture(gl.TEXTURE0 + TextureUnitFlipbook)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// setDetail 绑定细节贴图到 TextureUnitDetailAlbedo 和 TextureUnitDetailNormal
+func (t *LightingTechnique) setDetail(detail *material.Detail) {
+	var albedoTex, normalTex uint32
+	if detail != nil {
+		albedoTex, normalTex = detail.AlbedoTex, detail.NormalTex
+		gl.Uniform1f(t.materialUniform.DetailTiling, detail.Tiling)
+		gl.Uniform1i(t.materialUniform.DetailUVSet, detail.UVSet)
+	}
+	gl.Uniform1i(t.materialUniform.DetailAlbedoEnable, boolToInt32(albedoTex != 0))
+	gl.Uniform1i(t.materialUniform.DetailNormalEnable, boolToInt32(normalTex != 0))
+
+	gl.Uniform1i(t.materialUniform.DetailAlbedoMap, TextureUnitDetailAlbedo)
+	gl.ActiveTexture(gl.TEXTURE0 + TextureUnitDetailAlbedo)
+	gl.BindTexture(gl.TEXTURE_2D, albedoTex)
+
+	gl.Uniform1i(t.materialUniform.DetailNormalMap, TextureUnitDetailNormal)
+	gl.ActiveTexture(gl.TEXTURE0 + TextureUnitDetailNormal)
+	gl.BindTexture(gl.TEXTURE_2D, normalTex)
+
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SetAmbientOcclusion 设置环境光遮蔽, 纹理需预先绑定到 TextureUnitAO
+func (t *LightingTechnique) SetAmbientOcclusion(enable bool) {
+	gl.Uniform1i(t.aoMapUniform, TextureUnitAO)
+	if enable {
+		gl.Uniform1i(t.aoEnableUniform, 1)
+	} else {
+		gl.Uniform1i(t.aoEnableUniform, 0)
+	}
+}
+
+// SetLightmap 绑定光照贴图到 TextureUnitLightmap, tex 为 0 时使用点光源的环境光项
+func (t *LightingTechnique) SetLightmap(tex uint32, intensity float32) {
+	gl.Uniform1i(t.lightmapEnableUniform, boolToInt32(tex != 0))
+	gl.Uniform1f(t.lightmapIntensityUniform, intensity)
+	gl.Uniform1i(t.lightmapUniform, TextureUnitLightmap)
+	gl.ActiveTexture(gl.TEXTURE0 + TextureUnitLightmap)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.ActiveTexture(gl.TEXTURE0)
+}
+
+// SetFog 设置距离雾和高度雾参数, 雾的距离相对 gViewPos 计算
+func (t *LightingTechnique) SetFog(fog config.FogConfig) {
+	gl.Uniform1i(t.fogUniform.Enable, boolToInt32(fog.Enable))
+	gl.Uniform1i(t.fogUniform.Mode, int32(fog.Mode))
+	gl.Uniform3f(t.fogUniform.Color, fog.Color.X(), fog.Color.Y(), fog.Color.Z())
+	gl.Uniform1f(t.fogUniform.Density, fog.Density)
+	gl.Uniform1f(t.fogUniform.Start, fog.Start)
+	gl.Uniform1f(t.fogUniform.End, fog.End)
+	gl.Uniform1f(t.fogUniform.Height, fog.Height)
+	gl.Uniform1f(t.fogUniform.HeightFalloff, fog.HeightFalloff)
+}
+
+// SetParams 设置模型声明的材质参数, 着色器中没有同名 uniform 的参数忽略
+// 贴图参数按顺序绑定到 TextureUnitParams 开始的纹理单元, 未加载完成的贴图绑定为 0
+func (t *LightingTechnique) SetParams(params []material.Param) {
+	unit := int32(0)
+	for _, p := range params {
+		loc, ok := t.paramUniforms[p.Name]
+		if !ok {
+			loc = gl.GetUniformLocation(t.ShaderObj.Program, gl.Str(p.Name+"\x00"))
+			t.paramUniforms[p.Name] = loc
+		}
+		if loc < 0 {
+			continue
+		}
+		switch p.Type {
+		case material.ParamFloat:
+			gl.Uniform1f(loc, p.Value[0])
+		case material.ParamInt, material.ParamBool:
+			gl.Uniform1i(loc, int32(p.Value[0]))
+		case material.ParamVec2:
+			gl.Uniform2f(loc, p.Value[0], p.Value[1])
+		case material.ParamVec3:
+			gl.Uniform3f(loc, p.Value[0], p.Value[1], p.Value[2])
+		case material.ParamVec4:
+			gl.Uniform4f(loc, p.Value[0], p.Value[1], p.Value[2], p.Value[3])
+		case material.ParamTexture:
+			if unit >= MaxTextureParams {
+				continue
+			}
+			gl.Uniform1i(loc, TextureUnitParams+unit)
+			gl.ActiveTexture(gl.TEXTURE0 + uint32(TextureUnitParams+unit))
+			gl.BindTexture(gl.TEXTURE_2D, p.TextureId)
+			unit++
+		}
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
 }
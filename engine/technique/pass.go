@@ -0,0 +1,161 @@
+package technique
+
+import (
+	"slices"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// PassKind 绘制阶段, 每帧按 PassDepthPrepass, PassShadow, PassMain, PassOutline 的顺序执行
+type PassKind int32
+
+const (
+	PassDepthPrepass PassKind = iota // 深度预渲染, 只写深度, 之后的主 pass 不再绘制被遮挡的像素
+	PassShadow                       // 投射点光源阴影
+	PassMain                         // 正常着色
+	PassOutline                      // 描边
+)
+
+var PassKindNames = []string{"DepthPrepass", "Shadow", "Main", "Outline"}
+
+// BlendMode pass 的混合方式
+type BlendMode int32
+
+const (
+	BlendNone     BlendMode = iota // 不混合
+	BlendAlpha                     // 按 alpha 混合
+	BlendAdditive                  // 叠加
+)
+
+// RenderState pass 的 GL 状态, 零值为默认状态: 深度测试 LESS, 写深度和颜色, 不混合, 不剔除
+type RenderState struct {
+	NoDepthTest  bool
+	NoDepthWrite bool
+	NoColorWrite bool
+	// DepthFunc 深度比较函数, 0 为 gl.LESS, 深度预渲染之后的主 pass 使用 gl.LEQUAL
+	DepthFunc uint32
+	Blend     BlendMode
+	// CullFace 剔除的面, 0 为不剔除, 可以为 gl.BACK 或 gl.FRONT
+	CullFace uint32
+	// PolygonOffset 深度偏移的 factor 和 units, 都为 0 时不偏移
+	PolygonOffset [2]float32
+}
+
+// Apply 设置 GL 状态, 返回的函数恢复调用前的状态
+func (s RenderState) Apply() (restore func()) {
+	lastDepthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	lastBlend := gl.IsEnabled(gl.BLEND)
+	lastCull := gl.IsEnabled(gl.CULL_FACE)
+	lastOffset := gl.IsEnabled(gl.POLYGON_OFFSET_FILL)
+	var lastDepthWrite bool
+	gl.GetBooleanv(gl.DEPTH_WRITEMASK, &lastDepthWrite)
+	var lastColorWrite [4]bool
+	gl.GetBooleanv(gl.COLOR_WRITEMASK, &lastColorWrite[0])
+	var lastDepthFunc, lastCullFace int32
+	gl.GetIntegerv(gl.DEPTH_FUNC, &lastDepthFunc)
+	gl.GetIntegerv(gl.CULL_FACE_MODE, &lastCullFace)
+	var lastBlendFunc [4]int32
+	gl.GetIntegerv(gl.BLEND_SRC_RGB, &lastBlendFunc[0])
+	gl.GetIntegerv(gl.BLEND_DST_RGB, &lastBlendFunc[1])
+	gl.GetIntegerv(gl.BLEND_SRC_ALPHA, &lastBlendFunc[2])
+	gl.GetIntegerv(gl.BLEND_DST_ALPHA, &lastBlendFunc[3])
+	var lastOffsetFactor, lastOffsetUnits float32
+	gl.GetFloatv(gl.POLYGON_OFFSET_FACTOR, &lastOffsetFactor)
+	gl.GetFloatv(gl.POLYGON_OFFSET_UNITS, &lastOffsetUnits)
+
+	setEnabled(gl.DEPTH_TEST, !s.NoDepthTest)
+	gl.DepthMask(!s.NoDepthWrite)
+	gl.ColorMask(!s.NoColorWrite, !s.NoColorWrite, !s.NoColorWrite, !s.NoColorWrite)
+	if s.DepthFunc != 0 {
+		gl.DepthFunc(s.DepthFunc)
+	} else {
+		gl.DepthFunc(gl.LESS)
+	}
+	switch s.Blend {
+	case BlendAlpha:
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	case BlendAdditive:
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.ONE, gl.ONE)
+	default:
+		gl.Disable(gl.BLEND)
+	}
+	setEnabled(gl.CULL_FACE, s.CullFace != 0)
+	if s.CullFace != 0 {
+		gl.CullFace(s.CullFace)
+	}
+	offset := s.PolygonOffset != [2]float32{}
+	setEnabled(gl.POLYGON_OFFSET_FILL, offset)
+	if offset {
+		gl.PolygonOffset(s.PolygonOffset[0], s.PolygonOffset[1])
+	}
+
+	return func() {
+		setEnabled(gl.DEPTH_TEST, lastDepthTest)
+		gl.DepthMask(lastDepthWrite)
+		gl.ColorMask(lastColorWrite[0], lastColorWrite[1], lastColorWrite[2], lastColorWrite[3])
+		gl.DepthFunc(uint32(lastDepthFunc))
+		setEnabled(gl.BLEND, lastBlend)
+		gl.BlendFuncSeparate(uint32(lastBlendFunc[0]), uint32(lastBlendFunc[1]), uint32(lastBlendFunc[2]), uint32(lastBlendFunc[3]))
+		setEnabled(gl.CULL_FACE, lastCull)
+		gl.CullFace(uint32(lastCullFace))
+		setEnabled(gl.POLYGON_OFFSET_FILL, lastOffset)
+		gl.PolygonOffset(lastOffsetFactor, lastOffsetUnits)
+	}
+}
+
+func setEnabled(capability uint32, enabled bool) {
+	if enabled {
+		gl.Enable(capability)
+	} else {
+		gl.Disable(capability)
+	}
+}
+
+// Pass technique 的一个绘制阶段
+type Pass struct {
+	Name  string
+	Kind  PassKind
+	State RenderState
+	// Technique 这个 pass 使用的着色器, 通过 RenderGeometry 绘制对象的几何体
+	// 为空时使用阶段的默认方式: 主 pass 调用对象自身的 Render, 其他阶段使用渲染器内置的着色器
+	Technique *BaseTechnique
+}
+
+// DefaultPasses 没有声明 pass 的对象使用的 pass: 投射阴影和正常着色
+func DefaultPasses() []Pass {
+	return []Pass{
+		{Name: "Shadow", Kind: PassShadow},
+		{Name: "Main", Kind: PassMain},
+	}
+}
+
+// DepthPrepass 只写深度的预渲染 pass, 声明后主 pass 的深度比较改为 LEQUAL
+func DepthPrepass() Pass {
+	return Pass{Name: "DepthPrepass", Kind: PassDepthPrepass, State: RenderState{NoColorWrite: true}}
+}
+
+// PassObj 声明了多个 pass 的对象, 没有实现的对象使用 DefaultPasses
+type PassObj interface {
+	Passes() []Pass
+}
+
+// PassesOf obj 声明的 pass
+func PassesOf(obj interface{}) []Pass {
+	if passObj, ok := obj.(PassObj); ok {
+		return passObj.Passes()
+	}
+	return DefaultPasses()
+}
+
+// PassesOfKind obj 声明的属于 kind 阶段的 pass, 按声明顺序
+func PassesOfKind(obj interface{}, kind PassKind) []Pass {
+	passes := PassesOf(obj)
+	return slices.DeleteFunc(slices.Clone(passes), func(p Pass) bool { return p.Kind != kind })
+}
+
+// HasPass obj 是否声明了 kind 阶段的 pass
+func HasPass(obj interface{}, kind PassKind) bool {
+	return slices.ContainsFunc(PassesOf(obj), func(p Pass) bool { return p.Kind == kind })
+}
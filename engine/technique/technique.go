@@ -10,10 +10,18 @@ import (
 
 type Technique struct {
 	ShaderObj *shader.Shader
+	// Init 时着色器的 Version
+	version uint32
 }
 
 func (t *Technique) Init(shader *shader.Shader) {
 	t.ShaderObj = shader
+	t.version = shader.Version
+}
+
+// Stale 着色器热重载后缓存的 uniform 位置失效, 需要重新 Init
+func (t *Technique) Stale() bool {
+	return t.ShaderObj.Version != t.version
 }
 
 func (t *Technique) Finalize() {
@@ -0,0 +1,33 @@
+package technique
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+)
+
+// UnlitTechnique 不计算光照, 使用单一颜色输出
+type UnlitTechnique struct {
+	BaseTechnique
+
+	colorUniform int32
+}
+
+func (t *UnlitTechnique) Init(s *shader.Shader) {
+	t.BaseTechnique.Init(s)
+
+	t.colorUniform = t.GetUniformLocation("gColor")
+}
+
+// Enable 热重载后重新获取 gColor 等 uniform 位置
+func (t *UnlitTechnique) Enable() bool {
+	if t.Stale() {
+		t.Init(t.ShaderObj)
+	}
+	return t.Technique.Enable()
+}
+
+func (t *UnlitTechnique) SetColor(color mgl32.Vec3) {
+	gl.Uniform3f(t.colorUniform, color.X(), color.Y(), color.Z())
+}
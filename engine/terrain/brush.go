@@ -0,0 +1,102 @@
+package terrain
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// BrushMode 雕刻笔刷类型
+type BrushMode int32
+
+const (
+	BrushRaise   BrushMode = iota // 抬高
+	BrushLower                    // 降低
+	BrushSmooth                   // 平滑, 向邻域平均值靠拢
+	BrushFlatten                  // 压平到落笔时的高度
+	BrushNoise                    // 叠加噪声
+)
+
+var BrushModeNames = []string{"Raise", "Lower", "Smooth", "Flatten", "Noise"}
+
+// Brush 雕刻笔刷
+type Brush struct {
+	Mode     BrushMode
+	Radius   float32 // 局部空间半径
+	Strength float32 // 每秒高度变化量
+
+	// 压平的目标高度, 由落笔位置确定
+	FlattenHeight float32
+}
+
+// region 高度图中被修改的顶点范围(闭区间)
+type region struct {
+	minX, minZ int
+	maxX, maxZ int
+}
+
+// Apply 以局部坐标 center 为中心应用笔刷, dt 为本次作用时间, 返回被修改的范围
+func (h *Heightmap) Apply(brush *Brush, center mgl32.Vec3, dt float32) (region, bool) {
+	if brush.Radius <= 0 {
+		return region{}, false
+	}
+	gx, gz := h.GridCoord(center.X(), center.Z())
+	cells := brush.Radius / h.CellSize
+
+	r := region{
+		minX: int(math.Floor(float64(gx - cells))),
+		minZ: int(math.Floor(float64(gz - cells))),
+		maxX: int(math.Ceil(float64(gx + cells))),
+		maxZ: int(math.Ceil(float64(gz + cells))),
+	}
+	r.minX, r.minZ = h.clampCoord(r.minX, r.minZ)
+	r.maxX, r.maxZ = h.clampCoord(r.maxX, r.maxZ)
+	if r.minX > r.maxX || r.minZ > r.maxZ {
+		return region{}, false
+	}
+
+	// 平滑需要读取修改前的邻域高度
+	var source []float32
+	if brush.Mode == BrushSmooth {
+		source = make([]float32, len(h.Heights))
+		copy(source, h.Heights)
+	}
+
+	amount := brush.Strength * dt
+	for z := r.minZ; z <= r.maxZ; z++ {
+		for x := r.minX; x <= r.maxX; x++ {
+			d := float32(math.Hypot(float64(float32(x)-gx), float64(float32(z)-gz))) / cells
+			if d > 1 {
+				continue
+			}
+			// 余弦衰减, 中心最强
+			falloff := float32(0.5 + 0.5*math.Cos(float64(d)*math.Pi))
+			height := h.At(x, z)
+
+			switch brush.Mode {
+			case BrushRaise:
+				height += amount * falloff
+			case BrushLower:
+				height -= amount * falloff
+			case BrushSmooth:
+				avg := (source[h.index(h.clampCoord(x-1, z))] + source[h.index(h.clampCoord(x+1, z))] +
+					source[h.index(h.clampCoord(x, z-1))] + source[h.index(h.clampCoord(x, z+1))]) / 4
+				height += (avg - height) * mgl32.Clamp(amount*falloff, 0, 1)
+			case BrushFlatten:
+				height += (brush.FlattenHeight - height) * mgl32.Clamp(amount*falloff, 0, 1)
+			case BrushNoise:
+				height += (valueNoise(x, z)*2 - 1) * amount * falloff
+			}
+			h.Set(x, z, height)
+		}
+	}
+	return r, true
+}
+
+// valueNoise 网格坐标的哈希噪声, 范围 [0, 1)
+func valueNoise(x, z int) float32 {
+	n := uint32(x)*374761393 + uint32(z)*668265263
+	n = (n ^ (n >> 13)) * 1274126177
+	n ^= n >> 16
+	return float32(n&0xffffff) / float32(0x1000000)
+}
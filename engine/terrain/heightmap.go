@@ -0,0 +1,98 @@
+package terrain
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Heightmap 规则网格高度图, 以原点为中心, 顶点按行(z)优先存储
+type Heightmap struct {
+	Width    int     // x 方向顶点数
+	Depth    int     // z 方向顶点数
+	CellSize float32 // 相邻顶点间距
+	Heights  []float32
+}
+
+func NewHeightmap(width, depth int, cellSize float32) *Heightmap {
+	return &Heightmap{
+		Width:    width,
+		Depth:    depth,
+		CellSize: cellSize,
+		Heights:  make([]float32, width*depth),
+	}
+}
+
+func (h *Heightmap) index(x, z int) int {
+	return z*h.Width + x
+}
+
+func (h *Heightmap) clampCoord(x, z int) (int, int) {
+	if x < 0 {
+		x = 0
+	} else if x >= h.Width {
+		x = h.Width - 1
+	}
+	if z < 0 {
+		z = 0
+	} else if z >= h.Depth {
+		z = h.Depth - 1
+	}
+	return x, z
+}
+
+// At 顶点高度, 越界时取边缘值
+func (h *Heightmap) At(x, z int) float32 {
+	x, z = h.clampCoord(x, z)
+	return h.Heights[h.index(x, z)]
+}
+
+func (h *Heightmap) Set(x, z int, height float32) {
+	if x < 0 || z < 0 || x >= h.Width || z >= h.Depth {
+		return
+	}
+	h.Heights[h.index(x, z)] = height
+}
+
+// origin 网格 (0, 0) 顶点的局部坐标
+func (h *Heightmap) origin() (float32, float32) {
+	return -float32(h.Width-1) * h.CellSize / 2, -float32(h.Depth-1) * h.CellSize / 2
+}
+
+// Position 顶点的局部坐标
+func (h *Heightmap) Position(x, z int) mgl32.Vec3 {
+	ox, oz := h.origin()
+	return mgl32.Vec3{ox + float32(x)*h.CellSize, h.At(x, z), oz + float32(z)*h.CellSize}
+}
+
+// GridCoord 局部坐标对应的网格坐标(浮点)
+func (h *Heightmap) GridCoord(localX, localZ float32) (float32, float32) {
+	ox, oz := h.origin()
+	return (localX - ox) / h.CellSize, (localZ - oz) / h.CellSize
+}
+
+// Contains 局部坐标是否在高度图范围内
+func (h *Heightmap) Contains(localX, localZ float32) bool {
+	gx, gz := h.GridCoord(localX, localZ)
+	return gx >= 0 && gz >= 0 && gx <= float32(h.Width-1) && gz <= float32(h.Depth-1)
+}
+
+// HeightAt 局部坐标处的双线性插值高度
+func (h *Heightmap) HeightAt(localX, localZ float32) float32 {
+	gx, gz := h.GridCoord(localX, localZ)
+	x0, z0 := int(math.Floor(float64(gx))), int(math.Floor(float64(gz)))
+	fx, fz := gx-float32(x0), gz-float32(z0)
+
+	h00 := h.At(x0, z0)
+	h10 := h.At(x0+1, z0)
+	h01 := h.At(x0, z0+1)
+	h11 := h.At(x0+1, z0+1)
+	return (h00*(1-fx)+h10*fx)*(1-fz) + (h01*(1-fx)+h11*fx)*fz
+}
+
+// Normal 中心差分计算的顶点法线
+func (h *Heightmap) Normal(x, z int) mgl32.Vec3 {
+	dx := h.At(x+1, z) - h.At(x-1, z)
+	dz := h.At(x, z+1) - h.At(x, z-1)
+	return mgl32.Vec3{-dx, 2 * h.CellSize, -dz}.Normalize()
+}
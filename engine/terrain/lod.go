@@ -0,0 +1,229 @@
+package terrain
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+)
+
+const (
+	defaultChunkCells  = 16
+	defaultLODLevels   = 4
+	defaultLODDistance = 16.0
+	defaultSkirtDepth  = 1.0
+)
+
+// indexRange 一块在某个层级下的索引区间
+type indexRange struct {
+	first int32
+	count int32
+}
+
+// chunk 地形分块, 覆盖网格坐标 [x, x+cellsX] x [z, z+cellsZ]
+type chunk struct {
+	x, z           int
+	cellsX, cellsZ int
+	// 模型空间包围盒, 雕刻后更新
+	bounds geometry.AABB
+	// 各层级的索引区间, 层级数受块大小能否被步长整除限制
+	levels []indexRange
+	// 本帧选择的层级, -1 表示被剔除
+	level int
+}
+
+// LODStats 最近一次选择的统计
+type LODStats struct {
+	Chunks    int
+	Visible   int
+	Triangles int
+	// 各层级的可见块数
+	Levels []int
+}
+
+// LOD 地形分块细节层级
+// 地形按 ChunkCells 划分为方块, 每块预先生成各层级的索引, 第 n 级每隔 2^n 个顶点取一个
+// 每帧按相机到块包围盒的距离选择层级, 块边缘向下延伸的裙边遮住相邻块层级不同产生的裂缝
+type LOD struct {
+	Enable bool
+	// 每块的格子数, 修改后需要重新生成网格
+	ChunkCells int
+	Levels     int
+	// 第一级的切换距离, 之后每级翻倍
+	Distance float32
+	// 裙边向下延伸的深度
+	SkirtDepth float32
+
+	Stats LODStats
+
+	chunks []chunk
+}
+
+func NewLOD(xmlLOD *config.XmlTerrainLOD) *LOD {
+	lod := &LOD{
+		Enable:     true,
+		ChunkCells: defaultChunkCells,
+		Levels:     defaultLODLevels,
+		Distance:   defaultLODDistance,
+		SkirtDepth: defaultSkirtDepth,
+	}
+	if xmlLOD == nil {
+		return lod
+	}
+	lod.Enable = !xmlLOD.Disable
+	if xmlLOD.ChunkSize > 0 {
+		lod.ChunkCells = xmlLOD.ChunkSize
+	}
+	if xmlLOD.Levels > 0 {
+		lod.Levels = xmlLOD.Levels
+	}
+	if xmlLOD.Distance > 0 {
+		lod.Distance = xmlLOD.Distance
+	}
+	if xmlLOD.Skirt > 0 {
+		lod.SkirtDepth = xmlLOD.Skirt
+	}
+	return lod
+}
+
+// build 划分高度图并生成所有块各层级的索引, 同一层级的块连续存放, 相邻的同级块可以合并绘制
+// 裙边顶点位于网格顶点之后, 第 i 个网格顶点对应的裙边顶点为 skirtBase + i
+func (l *LOD) build(h *Heightmap, skirtBase uint32) []uint32 {
+	l.chunks = l.chunks[:0]
+	cells := max(l.ChunkCells, 1)
+	for z := 0; z < h.Depth-1; z += cells {
+		for x := 0; x < h.Width-1; x += cells {
+			l.chunks = append(l.chunks, chunk{
+				x:      x,
+				z:      z,
+				cellsX: min(cells, h.Width-1-x),
+				cellsZ: min(cells, h.Depth-1-z),
+			})
+		}
+	}
+
+	indices := make([]uint32, 0)
+	for level, step := 0, 1; level < max(l.Levels, 1); level, step = level+1, step*2 {
+		for i := range l.chunks {
+			c := &l.chunks[i]
+			// 边缘不完整的块只保留能整除的层级
+			if len(c.levels) < level || c.cellsX%step != 0 || c.cellsZ%step != 0 {
+				continue
+			}
+			first := int32(len(indices))
+			indices = c.appendIndices(indices, h, step, skirtBase)
+			c.levels = append(c.levels, indexRange{first: first, count: int32(len(indices)) - first})
+		}
+	}
+	return indices
+}
+
+// appendIndices 按步长 step 生成块的三角形和四条边的裙边
+func (c *chunk) appendIndices(indices []uint32, h *Heightmap, step int, skirtBase uint32) []uint32 {
+	for z := c.z; z < c.z+c.cellsZ; z += step {
+		for x := c.x; x < c.x+c.cellsX; x += step {
+			i0 := uint32(h.index(x, z))
+			i1 := uint32(h.index(x+step, z))
+			i2 := uint32(h.index(x, z+step))
+			i3 := uint32(h.index(x+step, z+step))
+			// 逆时针, 法线朝上
+			indices = append(indices, i0, i2, i1, i1, i2, i3)
+		}
+	}
+
+	// 裙边为沿块边缘垂直向下的四边形
+	skirt := func(a, b int) {
+		ia, ib := uint32(a), uint32(b)
+		indices = append(indices, ia, skirtBase+ia, ib, ib, skirtBase+ia, skirtBase+ib)
+	}
+	x1, z1 := c.x+c.cellsX, c.z+c.cellsZ
+	for x := c.x; x < x1; x += step {
+		skirt(h.index(x+step, c.z), h.index(x, c.z))
+		skirt(h.index(x, z1), h.index(x+step, z1))
+	}
+	for z := c.z; z < z1; z += step {
+		skirt(h.index(c.x, z), h.index(c.x, z+step))
+		skirt(h.index(x1, z+step), h.index(x1, z))
+	}
+	return indices
+}
+
+// computeBounds 按网格顶点计算各块的包围盒
+func (l *LOD) computeBounds(h *Heightmap) {
+	for i := range l.chunks {
+		l.updateBounds(h, i)
+	}
+}
+
+func (l *LOD) updateBounds(h *Heightmap, i int) {
+	c := &l.chunks[i]
+	c.bounds = geometry.NewAABB()
+	for z := c.z; z <= c.z+c.cellsZ; z++ {
+		for x := c.x; x <= c.x+c.cellsX; x++ {
+			c.bounds.Extend(h.Position(x, z))
+		}
+	}
+	// 裙边在块下方
+	c.bounds.Min = c.bounds.Min.Sub(mgl32.Vec3{0, l.SkirtDepth, 0})
+}
+
+// updateRegion 雕刻修改网格范围后重新计算相交块的包围盒
+func (l *LOD) updateRegion(h *Heightmap, r region) {
+	for i, c := range l.chunks {
+		if c.x > r.maxX || c.x+c.cellsX < r.minX || c.z > r.maxZ || c.z+c.cellsZ < r.minZ {
+			continue
+		}
+		l.updateBounds(h, i)
+	}
+}
+
+// selectChunks 按模型空间的相机位置为每块选择层级, frustum 为模型空间视锥, 为空时不剔除
+// 未开启时所有块使用最高精度
+func (l *LOD) selectChunks(eye mgl32.Vec3, frustum *geometry.Frustum) {
+	l.Stats = LODStats{Chunks: len(l.chunks), Levels: make([]int, max(l.Levels, 1))}
+	for i := range l.chunks {
+		c := &l.chunks[i]
+		c.level = -1
+		if len(c.levels) == 0 || (frustum != nil && !frustum.IntersectsAABB(c.bounds)) {
+			continue
+		}
+
+		level := 0
+		if l.Enable && l.Distance > 0 {
+			distance := distanceToAABB(eye, c.bounds)
+			for threshold := l.Distance; distance > threshold && level < len(c.levels)-1; threshold *= 2 {
+				level++
+			}
+		}
+		c.level = level
+
+		l.Stats.Visible++
+		l.Stats.Triangles += int(c.levels[level].count / 3)
+		l.Stats.Levels[level]++
+	}
+}
+
+// ranges 本帧选中的索引区间, 相邻区间合并以减少绘制调用
+func (l *LOD) ranges(out []indexRange) []indexRange {
+	out = out[:0]
+	for _, c := range l.chunks {
+		if c.level < 0 {
+			continue
+		}
+		r := c.levels[c.level]
+		if n := len(out); n > 0 && out[n-1].first+out[n-1].count == r.first {
+			out[n-1].count += r.count
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// distanceToAABB 点到包围盒的最近距离, 点在盒内时为 0
+func distanceToAABB(p mgl32.Vec3, b geometry.AABB) float32 {
+	var d mgl32.Vec3
+	for i := 0; i < 3; i++ {
+		d[i] = max(b.Min[i]-p[i], 0, p[i]-b.Max[i])
+	}
+	return d.Len()
+}
@@ -0,0 +1,73 @@
+package terrain
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// SculptTool 地形雕刻工具, 按住鼠标左键在拾取射线命中的地形上应用笔刷
+type SculptTool struct {
+	Active bool
+	Brush  Brush
+
+	target *Terrain
+
+	stroking   bool
+	newStroke  bool
+	pendingPos [2]float32
+}
+
+func NewSculptTool() *SculptTool {
+	return &SculptTool{
+		Brush: Brush{
+			Mode:     BrushRaise,
+			Radius:   4,
+			Strength: 4,
+		},
+	}
+}
+
+func (s *SculptTool) SetActive(active bool) {
+	s.Active = active
+	s.stroking = false
+	s.target = nil
+}
+
+// HandleInput 记录鼠标拖动, 需在 imgui.NewFrame 之后调用
+func (s *SculptTool) HandleInput() {
+	s.stroking = false
+	if !s.Active {
+		return
+	}
+	io := imgui.CurrentIO()
+	if io.WantCaptureMouse() || !imgui.IsMouseDown(0) {
+		return
+	}
+	s.newStroke = imgui.IsMouseClicked(0)
+	pos := imgui.MousePos()
+	s.stroking = true
+	s.pendingPos = [2]float32{pos.X, pos.Y}
+}
+
+// PendingStroke 本帧需要雕刻的鼠标位置(窗口坐标), newStroke 表示刚按下鼠标
+func (s *SculptTool) PendingStroke() (pos [2]float32, newStroke bool, ok bool) {
+	return s.pendingPos, s.newStroke, s.stroking
+}
+
+// Target 当前雕刻的地形
+func (s *SculptTool) Target() *Terrain {
+	return s.target
+}
+
+func (s *SculptTool) SetTarget(t *Terrain) {
+	s.target = t
+}
+
+// Sculpt 在地形的世界坐标 pos 处应用笔刷, 落笔时记录压平的目标高度
+func (s *SculptTool) Sculpt(t *Terrain, pos mgl32.Vec3, newStroke bool, dt float32) {
+	if newStroke {
+		local := t.localPosition(pos)
+		s.Brush.FlattenHeight = t.Heightmap.HeightAt(local.X(), local.Z())
+	}
+	t.ApplyBrush(&s.Brush, pos, dt)
+}
@@ -0,0 +1,343 @@
+package terrain
+
+import (
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/job"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+const (
+	defaultSize     = 65
+	defaultCellSize = 1.0
+)
+
+// Terrain 由高度图生成的地形网格, 雕刻后只重新上传被修改的顶点行
+type Terrain struct {
+	Name     string
+	Id       string
+	BasePath string
+
+	model.RenderPriority
+
+	Heightmap *Heightmap
+	Mesh      *mesh.Mesh
+	Material  *material.Material
+
+	effect *technique.LightingTechnique
+	shader *shader.Shader
+
+	Position mgl32.Vec3
+	model    mgl32.Mat4
+
+	// 模型空间包围盒, 雕刻后更新
+	Bounds geometry.AABB
+
+	// 分块细节层级, 每帧由 SelectLOD 选择绘制的索引区间
+	LOD    *LOD
+	ranges []indexRange
+}
+
+func NewTerrain(xmlModel config.XmlModel) (Terrain, error) {
+	basePath := vfs.Path("model", xmlModel.Name)
+
+	size, cellSize := defaultSize, float32(defaultCellSize)
+	var xmlLOD *config.XmlTerrainLOD
+	if xmlModel.Terrain != nil {
+		xmlLOD = xmlModel.Terrain.LOD
+		if xmlModel.Terrain.Size > 1 {
+			size = xmlModel.Terrain.Size
+		}
+		if xmlModel.Terrain.CellSize > 0 {
+			cellSize = xmlModel.Terrain.CellSize
+		}
+	}
+
+	t := Terrain{
+		Name:           xmlModel.Name,
+		Id:             xmlModel.Id,
+		RenderPriority: model.NewRenderPriority(xmlModel),
+		BasePath:       basePath,
+		Heightmap:      NewHeightmap(size, size, cellSize),
+		LOD:            NewLOD(xmlLOD),
+		model:          mgl32.Ident4(),
+		effect:         &technique.LightingTechnique{},
+		Material: &material.Material{
+			Name:          xmlModel.Name,
+			AmbientColor:  xmlModel.Material.AmbientColor.RGB(),
+			DiffuseColor:  xmlModel.Material.DiffuseColor.RGB(),
+			SpecularColor: xmlModel.Material.SpecularColor.RGB(),
+			Shininess:     xmlModel.Material.Shininess,
+		},
+		shader: &shader.Shader{
+			VertFilePath: filepath.Join(basePath, xmlModel.Shader.VertFile),
+			FragFilePath: filepath.Join(basePath, xmlModel.Shader.FragFile),
+		},
+	}
+
+	if err := t.Init(); err != nil {
+		return t, err
+	}
+	t.SetPosition(xmlModel.Position.XYZ())
+
+	return t, nil
+}
+
+func (t *Terrain) Init() error {
+	t.Mesh = t.genMesh()
+	t.Mesh.Setup()
+	t.computeBounds()
+
+	if err := t.shader.Init(); err != nil {
+		logger.Error(err)
+		return err
+	}
+	t.effect.Init(t.shader)
+	return nil
+}
+
+// genMesh 按高度图生成三角形网格, UV 覆盖整个地形
+// 网格顶点之后是同样数量的裙边顶点, 位置向下偏移 SkirtDepth, 索引由 LOD 按块和层级生成
+func (t *Terrain) genMesh() *mesh.Mesh {
+	h := t.Heightmap
+	count := h.Width * h.Depth
+	// 各行顶点互不依赖, 按行并行生成
+	vertices := make([]mesh.Vertex, 2*count)
+	job.Wait(job.ParallelFor(h.Depth, func(begin, end int) {
+		for z := begin; z < end; z++ {
+			for x := 0; x < h.Width; x++ {
+				uv := mgl32.Vec2{float32(x) / float32(h.Width-1), float32(z) / float32(h.Depth-1)}
+				vertices[h.index(x, z)] = mesh.Vertex{
+					Position:   h.Position(x, z),
+					Color:      mgl32.Vec3{1, 1, 1},
+					Normal:     h.Normal(x, z),
+					TexCoords:  uv,
+					TexCoords2: uv,
+					Tangent:    mgl32.Vec3{1, 0, 0},
+					Bitangent:  mgl32.Vec3{0, 0, 1},
+				}
+				t.updateSkirt(vertices, h.index(x, z))
+			}
+		}
+	}))
+
+	m := mesh.NewMesh(vertices, t.LOD.build(h, uint32(count)), nil)
+	m.Name = t.Name
+	return m
+}
+
+// updateSkirt 按网格顶点 i 更新对应的裙边顶点
+func (t *Terrain) updateSkirt(vertices []mesh.Vertex, i int) {
+	skirt := vertices[i]
+	skirt.Position[1] -= t.LOD.SkirtDepth
+	vertices[len(vertices)/2+i] = skirt
+}
+
+// computeBounds 包围盒只包含网格顶点, 裙边不参与拾取
+func (t *Terrain) computeBounds() {
+	t.Mesh.ComputeBounds()
+	t.Bounds = geometry.NewAABB()
+	for _, v := range t.Mesh.Vertices[:len(t.Mesh.Vertices)/2] {
+		t.Bounds.Extend(v.Position)
+	}
+	t.LOD.computeBounds(t.Heightmap)
+}
+
+// WorldBounds 世界空间包围盒
+func (t *Terrain) WorldBounds() geometry.AABB {
+	return t.Bounds.Transform(t.model)
+}
+
+// ApplyBrush 在世界坐标 worldPos 处应用笔刷, 增量更新顶点位置和法线
+func (t *Terrain) ApplyBrush(brush *Brush, worldPos mgl32.Vec3, dt float32) {
+	local := t.localPosition(worldPos)
+	r, ok := t.Heightmap.Apply(brush, local, dt)
+	if !ok {
+		return
+	}
+
+	// 法线依赖相邻顶点, 修改范围外扩一圈
+	h := t.Heightmap
+	r.minX, r.minZ = h.clampCoord(r.minX-1, r.minZ-1)
+	r.maxX, r.maxZ = h.clampCoord(r.maxX+1, r.maxZ+1)
+	job.Wait(job.ParallelFor(r.maxZ-r.minZ+1, func(begin, end int) {
+		for z := r.minZ + begin; z < r.minZ+end; z++ {
+			for x := r.minX; x <= r.maxX; x++ {
+				v := &t.Mesh.Vertices[h.index(x, z)]
+				v.Position = h.Position(x, z)
+				v.Normal = h.Normal(x, z)
+				t.updateSkirt(t.Mesh.Vertices, h.index(x, z))
+			}
+		}
+	}))
+	for z := r.minZ; z <= r.maxZ; z++ {
+		for x := r.minX; x <= r.maxX; x++ {
+			t.Bounds.Extend(t.Mesh.Vertices[h.index(x, z)].Position)
+		}
+	}
+
+	t.LOD.updateRegion(h, r)
+
+	// 顶点按行存储, 上传覆盖修改范围的连续行和对应的裙边顶点
+	first := h.index(0, r.minZ)
+	count := h.index(h.Width-1, r.maxZ) - first + 1
+	t.Mesh.UpdateVertices(first, count)
+	t.Mesh.UpdateVertices(h.Width*h.Depth+first, count)
+}
+
+// SelectLOD 按相机位置为每块选择层级, cull 时剔除 viewProjection 视锥外的块
+func (t *Terrain) SelectLOD(eye mgl32.Vec3, viewProjection mgl32.Mat4, cull bool) {
+	var frustum *geometry.Frustum
+	if cull {
+		f := geometry.NewFrustum(viewProjection.Mul4(t.model))
+		frustum = &f
+	}
+	t.LOD.selectChunks(t.localPosition(eye), frustum)
+	t.ranges = t.LOD.ranges(t.ranges)
+}
+
+// draw 绘制本帧选中的块, 尚未选择时绘制全部块的最高精度
+func (t *Terrain) draw(program uint32) {
+	if t.ranges == nil {
+		t.ranges = t.LOD.ranges(t.ranges)
+	}
+	for _, r := range t.ranges {
+		t.Mesh.DrawRange(program, r.first, r.count)
+	}
+}
+
+func (t *Terrain) localPosition(worldPos mgl32.Vec3) mgl32.Vec3 {
+	return t.model.Inv().Mul4x1(worldPos.Vec4(1)).Vec3()
+}
+
+// HeightAt 世界坐标 (x, z) 处的地形高度, 超出地形范围时 ok 为 false
+func (t *Terrain) HeightAt(x, z float32) (float32, bool) {
+	local := t.localPosition(mgl32.Vec3{x, 0, z})
+	if !t.Heightmap.Contains(local.X(), local.Z()) {
+		return 0, false
+	}
+	height := t.Heightmap.HeightAt(local.X(), local.Z())
+	return t.model.Mul4x1(mgl32.Vec4{local.X(), height, local.Z(), 1}).Y(), true
+}
+
+// IntersectRay 沿射线步进找到穿过高度场的区间, 再二分求交点, 返回世界坐标
+func (t *Terrain) IntersectRay(ray geometry.Ray) (mgl32.Vec3, bool) {
+	localRay := ray.Transform(t.model.Inv())
+
+	// 平坦地形的包围盒厚度为0, 稍微扩大避免漏检
+	bounds := t.Bounds
+	bounds.Min = bounds.Min.Sub(mgl32.Vec3{0, 0.01, 0})
+	bounds.Max = bounds.Max.Add(mgl32.Vec3{0, 0.01, 0})
+	start, ok := localRay.IntersectAABB(bounds)
+	if !ok {
+		return mgl32.Vec3{}, false
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	h := t.Heightmap
+	step := h.CellSize * 0.5
+	maxDistance := bounds.Size().Len()
+
+	above := func(d float32) bool {
+		p := localRay.At(d)
+		return p.Y() >= h.HeightAt(p.X(), p.Z())
+	}
+
+	prev := start
+	for d := start; d <= start+maxDistance; d += step {
+		p := localRay.At(d)
+		if !h.Contains(p.X(), p.Z()) {
+			if d > start {
+				break
+			}
+			prev = d
+			continue
+		}
+		if !above(d) {
+			if d == start {
+				return t.model.Mul4x1(p.Vec4(1)).Vec3(), true
+			}
+			lo, hi := prev, d
+			for i := 0; i < 16; i++ {
+				mid := (lo + hi) / 2
+				if above(mid) {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			return t.model.Mul4x1(localRay.At(hi).Vec4(1)).Vec3(), true
+		}
+		prev = d
+	}
+	return mgl32.Vec3{}, false
+}
+
+func (t *Terrain) Dispose() {
+	t.Mesh.Dispose()
+}
+
+func (t *Terrain) SetPosition(p mgl32.Vec3) {
+	t.Position = p
+	t.model = mgl32.Translate3D(p.X(), p.Y(), p.Z())
+}
+
+func (t *Terrain) ModelMatrix() mgl32.Mat4 {
+	return t.model
+}
+
+func (t *Terrain) Update(elapsed float64) {
+	t.Material.Update(elapsed)
+}
+
+func (t *Terrain) PreRender() {
+}
+
+func (t *Terrain) Render(projection, model, view mgl32.Mat4, eyePosition *mgl32.Vec3, lights []*light.PointLight) {
+	model = model.Mul4(t.model)
+	mvp := projection.Mul4(view).Mul4(model)
+
+	t.effect.Enable()
+	t.effect.SetProjectMatrix(&projection)
+	t.effect.SetViewMatrix(&view)
+	t.effect.SetModelMatrix(&model)
+	t.effect.SetWVP(&mvp)
+	t.effect.SetEyeWorldPos(eyePosition)
+
+	t.effect.SetPointLight(lights)
+	t.effect.SetAmbientOcclusion(config.SSAOActive())
+	t.effect.SetFog(config.Config.Fog)
+	t.effect.SetInstanced(false)
+	t.effect.SetMaterial(t.Material)
+	t.effect.SetDiffuseMap(false)
+
+	gl.BindFragDataLocation(t.effect.ShaderObj.Program, 0, gl.Str("color\x00"))
+	t.draw(t.effect.ShaderObj.Program)
+	t.effect.Disable()
+}
+
+func (t *Terrain) GetMaterial() *material.Material {
+	return t.Material
+}
+
+// RenderGeometry 使用外部technique绘制几何体, 投影和视图矩阵由调用方设置
+func (t *Terrain) RenderGeometry(tech *technique.BaseTechnique) {
+	tech.SetModelMatrix(&t.model)
+	t.draw(tech.ShaderObj.Program)
+}
+
+func (t *Terrain) PostRender() {
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/huangxiaobo/toy-engine/engine/technique"
 	"github.com/huangxiaobo/toy-engine/engine/texture"
 	"github.com/huangxiaobo/toy-engine/engine/utils"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 )
@@ -103,7 +104,11 @@ func (t *Text) Init() {
 func creatSurface(content string, size int, color mgl32.Vec3) (*sdl.Surface, error) {
 	// 使用指定字符和颜色创建surface
 
-	font, err := ttf.OpenFont(FontFile, size)
+	fontFile, err := vfs.LocalPath(FontFile)
+	if err != nil {
+		return nil, err
+	}
+	font, err := ttf.OpenFont(fontFile, size)
 	if err != nil {
 		sdl.Quit()
 		return nil, fmt.Errorf("failed to open font: %w", err)
@@ -155,12 +160,16 @@ func creatSurface(content string, size int, color mgl32.Vec3) (*sdl.Surface, err
 	return surfaceNew, nil
 }
 
-// Render 渲染字符串
+// Render 渲染字符串, 需在后处理(色调映射)之后调用, 文字颜色按 sRGB 值直接混合到默认帧缓冲
 func (t *Text) Render(x, y int) {
+	lastDepthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	lastBlend := gl.IsEnabled(gl.BLEND)
+	lastFramebufferSRGB := gl.IsEnabled(gl.FRAMEBUFFER_SRGB)
 
 	gl.Disable(gl.DEPTH_TEST)
+	gl.Disable(gl.FRAMEBUFFER_SRGB)
 	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.BlendFuncSeparate(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA, gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
 
 	width := float32(config.Config.WindowWidth)
 	height := float32(config.Config.WindowHeight)
@@ -184,6 +193,13 @@ func (t *Text) Render(x, y int) {
 	}
 	t.effect.Disable()
 
-	gl.Enable(gl.DEPTH_TEST)
-	gl.Disable(gl.BLEND)
+	if lastDepthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+	if !lastBlend {
+		gl.Disable(gl.BLEND)
+	}
+	if lastFramebufferSRGB {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	}
 }
@@ -0,0 +1,337 @@
+package texture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// CompressedImage 块压缩(BCn)的贴图数据, Levels 为从大到小的 mip 层级
+// 不解码, 原样交给 glCompressedTexImage2D
+type CompressedImage struct {
+	Width  int
+	Height int
+	// GL 压缩格式, 例如 gl.COMPRESSED_RGBA_S3TC_DXT5_EXT
+	Format uint32
+	Levels [][]byte
+}
+
+// IsCompressed 文件是否是 DDS, KTX 或 KTX2 容器
+func IsCompressed(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".dds", ".ktx", ".ktx2":
+		return true
+	}
+	return false
+}
+
+// LoadCompressed 读取 DDS, KTX 或 KTX2 文件中的 2D 块压缩贴图和 mip 层级
+// 不支持立方体贴图, 贴图数组, 3D 贴图和 KTX2 的超压缩
+func LoadCompressed(file string) (*CompressedImage, error) {
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var img *CompressedImage
+	switch {
+	case bytes.HasPrefix(data, []byte("DDS ")):
+		img, err = decodeDDS(data)
+	case bytes.HasPrefix(data, ktxIdentifier):
+		img, err = decodeKTX(data)
+	case bytes.HasPrefix(data, ktx2Identifier):
+		img, err = decodeKTX2(data)
+	default:
+		err = errors.New("not a dds, ktx or ktx2 file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return img, nil
+}
+
+// blockSize 格式每个 4x4 块的字节数, 不支持的格式返回 0
+// 文件中的 sRGB 格式按线性格式读取, 是否按 sRGB 上传由 Options.SRGB 决定
+func blockSize(format uint32) int {
+	switch format {
+	case gl.COMPRESSED_RGB_S3TC_DXT1_EXT, gl.COMPRESSED_RGBA_S3TC_DXT1_EXT,
+		gl.COMPRESSED_RED_RGTC1, gl.COMPRESSED_SIGNED_RED_RGTC1:
+		return 8
+	case gl.COMPRESSED_RGBA_S3TC_DXT3_EXT, gl.COMPRESSED_RGBA_S3TC_DXT5_EXT,
+		gl.COMPRESSED_RG_RGTC2, gl.COMPRESSED_SIGNED_RG_RGTC2,
+		gl.COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_ARB, gl.COMPRESSED_RGB_BPTC_SIGNED_FLOAT_ARB,
+		gl.COMPRESSED_RGBA_BPTC_UNORM_ARB:
+		return 16
+	}
+	return 0
+}
+
+// S3TC 的 sRGB 格式, gl 包中没有定义
+const (
+	compressedSRGBDXT1      = 0x8C4C
+	compressedSRGBAlphaDXT1 = 0x8C4D
+	compressedSRGBAlphaDXT3 = 0x8C4E
+	compressedSRGBAlphaDXT5 = 0x8C4F
+)
+
+// srgbFormats 线性格式对应的 sRGB 格式, RGTC 和浮点 BPTC 没有 sRGB 格式
+var srgbFormats = map[uint32]uint32{
+	gl.COMPRESSED_RGB_S3TC_DXT1_EXT:   compressedSRGBDXT1,
+	gl.COMPRESSED_RGBA_S3TC_DXT1_EXT:  compressedSRGBAlphaDXT1,
+	gl.COMPRESSED_RGBA_S3TC_DXT3_EXT:  compressedSRGBAlphaDXT3,
+	gl.COMPRESSED_RGBA_S3TC_DXT5_EXT:  compressedSRGBAlphaDXT5,
+	gl.COMPRESSED_RGBA_BPTC_UNORM_ARB: gl.COMPRESSED_SRGB_ALPHA_BPTC_UNORM_ARB,
+}
+
+// levelSize 第 level 层的字节数
+func levelSize(format uint32, width, height, level int) int {
+	w := max(width>>level, 1)
+	h := max(height>>level, 1)
+	return ((w + 3) / 4) * ((h + 3) / 4) * blockSize(format)
+}
+
+// splitLevels 从 data 中依次切出 count 个紧密排列的 mip 层级
+func splitLevels(img *CompressedImage, data []byte, count int) error {
+	for level := 0; level < count; level++ {
+		size := levelSize(img.Format, img.Width, img.Height, level)
+		if size > len(data) {
+			return fmt.Errorf("mip level %d truncated", level)
+		}
+		img.Levels = append(img.Levels, data[:size])
+		data = data[size:]
+	}
+	return nil
+}
+
+func checkSize(width, height int) error {
+	if width <= 0 || height <= 0 || width > 1<<15 || height > 1<<15 {
+		return fmt.Errorf("invalid size %dx%d", width, height)
+	}
+	return nil
+}
+
+// ddsFourCC 旧格式头中的 FourCC
+var ddsFourCC = map[string]uint32{
+	"DXT1": gl.COMPRESSED_RGBA_S3TC_DXT1_EXT,
+	"DXT3": gl.COMPRESSED_RGBA_S3TC_DXT3_EXT,
+	"DXT5": gl.COMPRESSED_RGBA_S3TC_DXT5_EXT,
+	"ATI1": gl.COMPRESSED_RED_RGTC1,
+	"BC4U": gl.COMPRESSED_RED_RGTC1,
+	"BC4S": gl.COMPRESSED_SIGNED_RED_RGTC1,
+	"ATI2": gl.COMPRESSED_RG_RGTC2,
+	"BC5U": gl.COMPRESSED_RG_RGTC2,
+	"BC5S": gl.COMPRESSED_SIGNED_RG_RGTC2,
+}
+
+// ddsDXGIFormat DX10 扩展头中的 DXGI_FORMAT
+var ddsDXGIFormat = map[uint32]uint32{
+	71: gl.COMPRESSED_RGBA_S3TC_DXT1_EXT, // BC1_UNORM
+	72: gl.COMPRESSED_RGBA_S3TC_DXT1_EXT, // BC1_UNORM_SRGB
+	74: gl.COMPRESSED_RGBA_S3TC_DXT3_EXT, // BC2_UNORM
+	75: gl.COMPRESSED_RGBA_S3TC_DXT3_EXT, // BC2_UNORM_SRGB
+	77: gl.COMPRESSED_RGBA_S3TC_DXT5_EXT, // BC3_UNORM
+	78: gl.COMPRESSED_RGBA_S3TC_DXT5_EXT, // BC3_UNORM_SRGB
+	80: gl.COMPRESSED_RED_RGTC1,          // BC4_UNORM
+	81: gl.COMPRESSED_SIGNED_RED_RGTC1,   // BC4_SNORM
+	83: gl.COMPRESSED_RG_RGTC2,           // BC5_UNORM
+	84: gl.COMPRESSED_SIGNED_RG_RGTC2,    // BC5_SNORM
+	95: gl.COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_ARB,
+	96: gl.COMPRESSED_RGB_BPTC_SIGNED_FLOAT_ARB,
+	98: gl.COMPRESSED_RGBA_BPTC_UNORM_ARB,
+	99: gl.COMPRESSED_RGBA_BPTC_UNORM_ARB, // BC7_UNORM_SRGB
+}
+
+func decodeDDS(data []byte) (*CompressedImage, error) {
+	// "DDS " 之后是 124 字节的 DDS_HEADER
+	if len(data) < 128 {
+		return nil, errors.New("truncated header")
+	}
+	le := binary.LittleEndian
+	header := data[4:128]
+	flags := le.Uint32(header[4:])
+	img := &CompressedImage{Height: int(le.Uint32(header[8:])), Width: int(le.Uint32(header[12:]))}
+	if err := checkSize(img.Width, img.Height); err != nil {
+		return nil, err
+	}
+	levels := 1
+	// DDSD_MIPMAPCOUNT
+	if flags&0x20000 != 0 && le.Uint32(header[24:]) > 0 {
+		levels = int(le.Uint32(header[24:]))
+	}
+	// caps2 中的 DDSCAPS2_CUBEMAP 和 DDSCAPS2_VOLUME
+	if le.Uint32(header[108:])&(0x200|0x200000) != 0 {
+		return nil, errors.New("cube maps and volume textures are not supported")
+	}
+
+	pixelFormat := header[72:104]
+	// DDPF_FOURCC
+	if le.Uint32(pixelFormat[4:])&0x4 == 0 {
+		return nil, errors.New("uncompressed dds is not supported")
+	}
+	fourCC := string(pixelFormat[8:12])
+	body := data[128:]
+	if fourCC == "DX10" {
+		if len(body) < 20 {
+			return nil, errors.New("truncated dx10 header")
+		}
+		format, ok := ddsDXGIFormat[le.Uint32(body)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported dxgi format %d", le.Uint32(body))
+		}
+		// resourceDimension 3 为 2D 贴图, arraySize 为 1
+		if le.Uint32(body[4:]) != 3 || le.Uint32(body[12:]) > 1 {
+			return nil, errors.New("only single 2d textures are supported")
+		}
+		img.Format = format
+		body = body[20:]
+	} else {
+		format, ok := ddsFourCC[fourCC]
+		if !ok {
+			return nil, fmt.Errorf("unsupported fourcc %q", fourCC)
+		}
+		img.Format = format
+	}
+	if err := splitLevels(img, body, levels); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+var (
+	ktxIdentifier  = []byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'}
+	ktx2Identifier = []byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+)
+
+func decodeKTX(data []byte) (*CompressedImage, error) {
+	// 标识之后是 13 个 uint32 字段
+	if len(data) < 64 {
+		return nil, errors.New("truncated header")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if binary.LittleEndian.Uint32(data[12:]) != 0x04030201 {
+		order = binary.BigEndian
+	}
+	field := func(i int) uint32 { return order.Uint32(data[12+i*4:]) }
+	glType, internalFormat := field(1), field(4)
+	for linear, srgb := range srgbFormats {
+		if internalFormat == srgb {
+			internalFormat = linear
+		}
+	}
+	if glType != 0 || blockSize(internalFormat) == 0 {
+		return nil, fmt.Errorf("unsupported format 0x%x", internalFormat)
+	}
+	img := &CompressedImage{Width: int(field(6)), Height: int(field(7)), Format: internalFormat}
+	if err := checkSize(img.Width, img.Height); err != nil {
+		return nil, err
+	}
+	// pixelDepth, numberOfArrayElements, numberOfFaces
+	if field(8) > 1 || field(9) > 1 || field(10) > 1 {
+		return nil, errors.New("only single 2d textures are supported")
+	}
+	levels := max(int(field(11)), 1)
+
+	pos := 64 + int(field(12))
+	for level := 0; level < levels; level++ {
+		if pos+4 > len(data) {
+			return nil, fmt.Errorf("mip level %d truncated", level)
+		}
+		size := int(order.Uint32(data[pos:]))
+		pos += 4
+		if size != levelSize(img.Format, img.Width, img.Height, level) || pos+size > len(data) {
+			return nil, fmt.Errorf("mip level %d has invalid size %d", level, size)
+		}
+		img.Levels = append(img.Levels, data[pos:pos+size])
+		// 每层按 4 字节对齐
+		pos += (size + 3) &^ 3
+	}
+	return img, nil
+}
+
+// ktx2VkFormat KTX2 头中的 VkFormat, BC1_RGB 到 BC7_SRGB
+var ktx2VkFormat = map[uint32]uint32{
+	131: gl.COMPRESSED_RGB_S3TC_DXT1_EXT,
+	132: gl.COMPRESSED_RGB_S3TC_DXT1_EXT,
+	133: gl.COMPRESSED_RGBA_S3TC_DXT1_EXT,
+	134: gl.COMPRESSED_RGBA_S3TC_DXT1_EXT,
+	135: gl.COMPRESSED_RGBA_S3TC_DXT3_EXT,
+	136: gl.COMPRESSED_RGBA_S3TC_DXT3_EXT,
+	137: gl.COMPRESSED_RGBA_S3TC_DXT5_EXT,
+	138: gl.COMPRESSED_RGBA_S3TC_DXT5_EXT,
+	139: gl.COMPRESSED_RED_RGTC1,
+	140: gl.COMPRESSED_SIGNED_RED_RGTC1,
+	141: gl.COMPRESSED_RG_RGTC2,
+	142: gl.COMPRESSED_SIGNED_RG_RGTC2,
+	143: gl.COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_ARB,
+	144: gl.COMPRESSED_RGB_BPTC_SIGNED_FLOAT_ARB,
+	145: gl.COMPRESSED_RGBA_BPTC_UNORM_ARB,
+	146: gl.COMPRESSED_RGBA_BPTC_UNORM_ARB,
+}
+
+func decodeKTX2(data []byte) (*CompressedImage, error) {
+	// 标识, 9 个 uint32 字段, 4 个 uint32 和 2 个 uint64 的索引, 之后是层级索引
+	const headerSize = 12 + 9*4 + 4*4 + 2*8
+	if len(data) < headerSize {
+		return nil, errors.New("truncated header")
+	}
+	le := binary.LittleEndian
+	field := func(i int) uint32 { return le.Uint32(data[12+i*4:]) }
+	format, ok := ktx2VkFormat[field(0)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported vkformat %d", field(0))
+	}
+	img := &CompressedImage{Width: int(field(2)), Height: int(field(3)), Format: format}
+	if err := checkSize(img.Width, img.Height); err != nil {
+		return nil, err
+	}
+	// pixelDepth, layerCount, faceCount
+	if field(4) > 1 || field(5) > 1 || field(6) > 1 {
+		return nil, errors.New("only single 2d textures are supported")
+	}
+	if field(8) != 0 {
+		return nil, fmt.Errorf("supercompression scheme %d is not supported", field(8))
+	}
+	levels := max(int(field(7)), 1)
+	if headerSize+levels*24 > len(data) {
+		return nil, errors.New("truncated level index")
+	}
+	for level := 0; level < levels; level++ {
+		entry := data[headerSize+level*24:]
+		offset, length := le.Uint64(entry), le.Uint64(entry[8:])
+		if length != uint64(levelSize(format, img.Width, img.Height, level)) || offset > uint64(len(data)) || length > uint64(len(data))-offset {
+			return nil, fmt.Errorf("mip level %d has invalid size %d", level, length)
+		}
+		img.Levels = append(img.Levels, data[offset:offset+length])
+	}
+	return img, nil
+}
+
+// NewTextureFromCompressed 上传所有 mip 层级, 必须在主线程调用
+// 压缩贴图不能由 GL 生成 mipmap, 使用文件中的层级, 只有一层时缩小过滤不使用 mipmap
+func NewTextureFromCompressed(opts Options, img *CompressedImage) uint32 {
+	format := img.Format
+	if srgb, ok := srgbFormats[format]; ok && opts.SRGB {
+		format = srgb
+	}
+
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+
+	opts.apply(len(img.Levels) > 1)
+	// 文件中的层级可能不完整, 限制最大层级
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, int32(len(img.Levels)-1))
+
+	for level, data := range img.Levels {
+		gl.CompressedTexImage2D(gl.TEXTURE_2D, int32(level), format,
+			int32(max(img.Width>>level, 1)), int32(max(img.Height>>level, 1)), 0, int32(len(data)), gl.Ptr(data))
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return texture
+}
@@ -0,0 +1,361 @@
+package texture
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// OpenEXR 的压缩方式, 只支持无损的逐行压缩
+const (
+	exrNone = 0
+	exrRLE  = 1
+	exrZIPS = 2
+	exrZIP  = 3
+)
+
+const exrMagic = 20000630
+
+// exrChannel 通道描述, pixelType 0 为 uint, 1 为 half, 2 为 float
+type exrChannel struct {
+	name      string
+	pixelType int32
+	xSampling int32
+	ySampling int32
+}
+
+func (c exrChannel) size() int {
+	if c.pixelType == 1 {
+		return 2
+	}
+	return 4
+}
+
+// LoadEXR 读取 OpenEXR 单层逐行图像, 支持 NONE, RLE, ZIPS 和 ZIP 压缩的 half, float 和 uint 通道
+// 使用 R, G, B 通道, 只有 Y 通道时作为灰度图, 不支持分块, 深度和多部分文件
+func LoadEXR(file string) (*HDRImage, error) {
+	data, err := vfs.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	img, err := decodeEXR(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return img, nil
+}
+
+func decodeEXR(data []byte) (*HDRImage, error) {
+	r := &exrReader{data: data}
+	if r.uint32() != exrMagic {
+		return nil, errors.New("not an openexr file")
+	}
+	version := r.uint32()
+	if version&0xff != 2 {
+		return nil, fmt.Errorf("unsupported version %d", version&0xff)
+	}
+	// 0x200 分块, 0x800 深度数据, 0x1000 多部分
+	if version&(0x200|0x800|0x1000) != 0 {
+		return nil, errors.New("tiled, deep and multi-part images are not supported")
+	}
+
+	var channels []exrChannel
+	compression := -1
+	var window [4]int32
+	hasWindow := false
+	for r.err == nil {
+		name := r.cstring()
+		if name == "" {
+			break
+		}
+		r.cstring()
+		value := r.bytes(int(r.uint32()))
+		switch name {
+		case "channels":
+			channels = parseEXRChannels(value)
+		case "compression":
+			if len(value) == 1 {
+				compression = int(value[0])
+			}
+		case "dataWindow":
+			if len(value) == 16 {
+				for i := range window {
+					window[i] = int32(binary.LittleEndian.Uint32(value[i*4:]))
+				}
+				hasWindow = true
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(channels) == 0 || !hasWindow {
+		return nil, errors.New("missing channels or dataWindow")
+	}
+
+	linesPerBlock := 1
+	switch compression {
+	case exrNone, exrRLE, exrZIPS:
+	case exrZIP:
+		linesPerBlock = 16
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", compression)
+	}
+
+	width, height := int(window[2])-int(window[0])+1, int(window[3])-int(window[1])+1
+	if width <= 0 || height <= 0 || width > 1<<15 || height > 1<<15 {
+		return nil, fmt.Errorf("invalid size %dx%d", width, height)
+	}
+
+	// 通道在每行中按名称顺序依次存放, offsets 为通道在行中的偏移, target 为输出的 RGB 分量
+	offsets := make([]int, len(channels))
+	targets := make([][]int, len(channels))
+	lineSize := 0
+	found := false
+	for i, c := range channels {
+		if c.xSampling != 1 || c.ySampling != 1 {
+			return nil, fmt.Errorf("subsampled channel %s is not supported", c.name)
+		}
+		offsets[i] = lineSize
+		lineSize += width * c.size()
+		name := c.name[strings.LastIndexByte(c.name, '.')+1:]
+		switch name {
+		case "R":
+			targets[i] = []int{0}
+		case "G":
+			targets[i] = []int{1}
+		case "B":
+			targets[i] = []int{2}
+		case "Y":
+			targets[i] = []int{0, 1, 2}
+		}
+		found = found || targets[i] != nil
+	}
+	if !found {
+		return nil, errors.New("no R, G, B or Y channel")
+	}
+
+	blocks := (height + linesPerBlock - 1) / linesPerBlock
+	table := make([]uint64, blocks)
+	for i := range table {
+		table[i] = r.uint64()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	img := &HDRImage{Width: width, Height: height, Pixels: make([]float32, width*height*3)}
+	for _, offset := range table {
+		if offset >= uint64(len(data)) {
+			return nil, errors.New("chunk offset out of range")
+		}
+		chunk := &exrReader{data: data, pos: int(offset)}
+		y := int(int32(chunk.uint32())) - int(window[1])
+		payload := chunk.bytes(int(chunk.uint32()))
+		if chunk.err != nil {
+			return nil, chunk.err
+		}
+		if y < 0 || y >= height {
+			return nil, errors.New("chunk line out of range")
+		}
+		lines := min(linesPerBlock, height-y)
+		raw, err := exrDecompress(compression, payload, lines*lineSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for l := 0; l < lines; l++ {
+			line := raw[l*lineSize:]
+			row := img.Pixels[(y+l)*width*3:]
+			for i, c := range channels {
+				if targets[i] == nil {
+					continue
+				}
+				samples := line[offsets[i]:]
+				for x := 0; x < width; x++ {
+					v := exrSample(samples, x, c.pixelType)
+					for _, t := range targets[i] {
+						row[x*3+t] = v
+					}
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+func parseEXRChannels(value []byte) []exrChannel {
+	r := &exrReader{data: value}
+	var channels []exrChannel
+	for r.err == nil {
+		name := r.cstring()
+		if name == "" {
+			break
+		}
+		c := exrChannel{name: name, pixelType: int32(r.uint32())}
+		// pLinear 和 3 个保留字节
+		r.bytes(4)
+		c.xSampling = int32(r.uint32())
+		c.ySampling = int32(r.uint32())
+		channels = append(channels, c)
+	}
+	if r.err != nil {
+		return nil
+	}
+	return channels
+}
+
+func exrSample(samples []byte, x int, pixelType int32) float32 {
+	switch pixelType {
+	case 0:
+		return float32(binary.LittleEndian.Uint32(samples[x*4:]))
+	case 1:
+		return halfToFloat(binary.LittleEndian.Uint16(samples[x*2:]))
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(samples[x*4:]))
+}
+
+// halfToFloat 16 位半精度浮点数转换为 float32
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		// 零和非规格化数
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// exrDecompress 解压一个数据块, 压缩后不比原数据小的块按原样存储
+func exrDecompress(compression int, payload []byte, size int) ([]byte, error) {
+	if len(payload) == size {
+		return payload, nil
+	}
+	var tmp []byte
+	switch compression {
+	case exrZIP, exrZIPS:
+		zr, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		tmp = make([]byte, size)
+		if _, err := io.ReadFull(zr, tmp); err != nil {
+			return nil, err
+		}
+	case exrRLE:
+		var err error
+		if tmp, err = exrRLEDecode(payload, size); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("chunk size mismatch")
+	}
+
+	// 压缩前做了差分预测并把奇偶字节分开存放
+	for i := 1; i < len(tmp); i++ {
+		tmp[i] = tmp[i-1] + tmp[i] - 128
+	}
+	out := make([]byte, size)
+	half := (size + 1) / 2
+	for i := range out {
+		if i%2 == 0 {
+			out[i] = tmp[i/2]
+		} else {
+			out[i] = tmp[half+i/2]
+		}
+	}
+	return out, nil
+}
+
+// exrRLEDecode 负数 n 表示之后 -n 个字节原样复制, 非负数 n 表示下一个字节重复 n+1 次
+func exrRLEDecode(in []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(in); {
+		n := int(int8(in[i]))
+		i++
+		if n < 0 {
+			if i-n > len(in) {
+				return nil, errors.New("bad rle data")
+			}
+			out = append(out, in[i:i-n]...)
+			i -= n
+			continue
+		}
+		if i >= len(in) {
+			return nil, errors.New("bad rle data")
+		}
+		for ; n >= 0; n-- {
+			out = append(out, in[i])
+		}
+		i++
+	}
+	if len(out) != size {
+		return nil, errors.New("rle size mismatch")
+	}
+	return out, nil
+}
+
+// exrReader 小端读取, 越界后之后的读取返回零值
+type exrReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *exrReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data)-r.pos {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *exrReader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *exrReader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (r *exrReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s
+}
@@ -0,0 +1,215 @@
+package texture
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/glqueue"
+	"github.com/huangxiaobo/toy-engine/engine/job"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// HDRImage Radiance .hdr 图像解码后的线性 RGB 数据, 按行从上到下存储
+type HDRImage struct {
+	Width  int
+	Height int
+	Pixels []float32
+}
+
+// At 像素 (x, y) 的线性 RGB
+func (img *HDRImage) At(x, y int) (float32, float32, float32) {
+	i := (y*img.Width + x) * 3
+	return img.Pixels[i], img.Pixels[i+1], img.Pixels[i+2]
+}
+
+// LoadHDR 读取 Radiance RGBE 格式的 .hdr 文件, 支持未压缩和新式游程编码的扫描线
+func LoadHDR(file string) (*HDRImage, error) {
+	f, err := vfs.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	width, height, err := readHDRHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	img := &HDRImage{Width: width, Height: height, Pixels: make([]float32, width*height*3)}
+	scanline := make([]byte, width*4)
+	for y := 0; y < height; y++ {
+		if err := readHDRScanline(r, scanline, width); err != nil {
+			return nil, fmt.Errorf("%s: scanline %d: %w", file, y, err)
+		}
+		for x := 0; x < width; x++ {
+			rgbe := scanline[x*4 : x*4+4]
+			i := (y*width + x) * 3
+			if rgbe[3] == 0 {
+				continue
+			}
+			scale := float32(math.Ldexp(1, int(rgbe[3])-(128+8)))
+			img.Pixels[i] = float32(rgbe[0]) * scale
+			img.Pixels[i+1] = float32(rgbe[1]) * scale
+			img.Pixels[i+2] = float32(rgbe[2]) * scale
+		}
+	}
+	return img, nil
+}
+
+// readHDRHeader 解析文件头, 只支持 -Y height +X width 的标准方向
+func readHDRHeader(r *bufio.Reader) (int, int, error) {
+	magic, err := r.ReadString('\n')
+	if err != nil {
+		return 0, 0, err
+	}
+	if !strings.HasPrefix(magic, "#?") {
+		return 0, 0, fmt.Errorf("not a radiance hdr file")
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return 0, 0, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if format, ok := strings.CutPrefix(line, "FORMAT="); ok && format != "32-bit_rle_rgbe" {
+			return 0, 0, fmt.Errorf("unsupported format %s", format)
+		}
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, 0, err
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(line, "-Y %d +X %d", &height, &width); err != nil {
+		return 0, 0, fmt.Errorf("unsupported resolution %q", strings.TrimSpace(line))
+	}
+	// 损坏的文件头可能给出巨大的尺寸, 分配像素前拒绝
+	if err := checkSize(width, height); err != nil {
+		return 0, 0, err
+	}
+	return width, height, nil
+}
+
+// readHDRScanline 读取一行 RGBE 像素到 scanline
+func readHDRScanline(r *bufio.Reader, scanline []byte, width int) error {
+	if _, err := io.ReadFull(r, scanline[:4]); err != nil {
+		return err
+	}
+	// 新式游程编码: 2, 2, 宽度高位, 宽度低位, 之后按通道分别编码
+	if width < 8 || width > 0x7fff || scanline[0] != 2 || scanline[1] != 2 || scanline[2]&0x80 != 0 {
+		_, err := io.ReadFull(r, scanline[4:])
+		return err
+	}
+	if int(scanline[2])<<8|int(scanline[3]) != width {
+		return fmt.Errorf("scanline width mismatch")
+	}
+
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				// 重复 count-128 次
+				n := int(count) - 128
+				if x+n > width {
+					return fmt.Errorf("bad run length")
+				}
+				value, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				for ; n > 0; n-- {
+					scanline[x*4+c] = value
+					x++
+				}
+				continue
+			}
+			n := int(count)
+			if n == 0 || x+n > width {
+				return fmt.Errorf("bad run length")
+			}
+			for ; n > 0; n-- {
+				value, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				scanline[x*4+c] = value
+				x++
+			}
+		}
+	}
+	return nil
+}
+
+// IsHDR 文件是否是 Radiance .hdr 或 OpenEXR .exr 格式
+func IsHDR(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".hdr", ".exr":
+		return true
+	}
+	return false
+}
+
+// LoadHDRImage 按扩展名读取 .hdr 或 .exr 文件
+func LoadHDRImage(file string) (*HDRImage, error) {
+	if strings.EqualFold(filepath.Ext(file), ".exr") {
+		return LoadEXR(file)
+	}
+	return LoadHDR(file)
+}
+
+// NewTextureFromHDR 上传浮点图像, internalFormat 为 gl.RGB16F 或 gl.RGB32F, 浮点数据已是线性的, 忽略 opts.SRGB
+// 必须在主线程调用
+func NewTextureFromHDR(opts Options, internalFormat int32, img *HDRImage) uint32 {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+
+	opts.apply(opts.Mipmap)
+
+	gl.TexImage2D(gl.TEXTURE_2D, 0, internalFormat, int32(img.Width), int32(img.Height), 0, gl.RGB, gl.FLOAT, gl.Ptr(img.Pixels))
+	if opts.Mipmap {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	return texture
+}
+
+// NewHDRTexture 读取 .hdr 或 .exr 文件并上传为浮点贴图
+func NewHDRTexture(opts Options, internalFormat int32, file string) (uint32, error) {
+	img, err := LoadHDRImage(file)
+	if err != nil {
+		return 0, err
+	}
+	return NewTextureFromHDR(opts, internalFormat, img), nil
+}
+
+// NewHDRTextureAsync 在任务中解码, 由主线程的 GL 命令队列上传, 完成后在主线程调用 callback
+func NewHDRTextureAsync(opts Options, internalFormat int32, file string, callback func(id uint32, err error)) {
+	job.Schedule(func() {
+		img, err := LoadHDRImage(file)
+		if err != nil {
+			glqueue.PostCallback(func() {}, func() { callback(0, err) })
+			return
+		}
+		var id uint32
+		glqueue.PostCallback(func() {
+			id = NewTextureFromHDR(opts, internalFormat, img)
+		}, func() {
+			callback(id, nil)
+		})
+	})
+}
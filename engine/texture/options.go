@@ -0,0 +1,110 @@
+package texture
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+)
+
+// Options 贴图的采样和上传选项
+type Options struct {
+	WrapS     int32
+	WrapT     int32
+	MinFilter int32
+	MagFilter int32
+	// SRGB 颜色贴图按 sRGB 上传, 采样时转换为线性颜色, 法线, 高光等数据贴图不要设置
+	SRGB bool
+	// Anisotropy 各向异性过滤的级别, 不大于 1 时不使用, 超过驱动支持的最大值时取最大值
+	Anisotropy float32
+	// Mipmap 上传后生成 mipmap, 不生成时缩小过滤不使用 mipmap, 压缩贴图使用文件中的层级
+	Mipmap bool
+}
+
+// DefaultOptions 数据贴图(法线, 高光, 高度)的选项, 重复平铺, 三线性过滤, 不使用 sRGB
+// 各向异性级别和是否生成 mipmap 取 config.Config.Texture
+func DefaultOptions() Options {
+	return Options{
+		WrapS:      gl.REPEAT,
+		WrapT:      gl.REPEAT,
+		MinFilter:  gl.LINEAR_MIPMAP_LINEAR,
+		MagFilter:  gl.LINEAR,
+		Anisotropy: config.Config.Texture.Anisotropy,
+		Mipmap:     config.Config.Texture.Mipmap,
+	}
+}
+
+// ColorOptions 颜色贴图(漫反射, 自发光)的选项, 在 DefaultOptions 的基础上按 config.Config.Texture.SRGB 设置 sRGB
+func ColorOptions() Options {
+	opts := DefaultOptions()
+	opts.SRGB = config.Config.Texture.SRGB
+	return opts
+}
+
+// OptionsFor 网格贴图按类型选择选项, 漫反射贴图是颜色贴图, 其他是数据贴图
+func OptionsFor(textureType string) Options {
+	if textureType == TextureDiffuse {
+		return ColorOptions()
+	}
+	return DefaultOptions()
+}
+
+// ClampOptions 边缘截断, 线性过滤, 不生成 mipmap, 用于光照贴图等不平铺也不缩小的贴图
+func ClampOptions() Options {
+	return Options{
+		WrapS:     gl.CLAMP_TO_EDGE,
+		WrapT:     gl.CLAMP_TO_EDGE,
+		MinFilter: gl.LINEAR,
+		MagFilter: gl.LINEAR,
+	}
+}
+
+// minFilter 没有 mipmap 时把 mipmap 过滤方式降级, 否则采样结果为黑色
+func (o Options) minFilter(mipmapped bool) int32 {
+	if mipmapped {
+		return o.MinFilter
+	}
+	switch o.MinFilter {
+	case gl.NEAREST_MIPMAP_NEAREST, gl.NEAREST_MIPMAP_LINEAR:
+		return gl.NEAREST
+	case gl.LINEAR_MIPMAP_NEAREST, gl.LINEAR_MIPMAP_LINEAR:
+		return gl.LINEAR
+	}
+	return o.MinFilter
+}
+
+// apply 设置当前绑定的 2D 贴图的采样参数
+func (o Options) apply(mipmapped bool) {
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, o.WrapS)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, o.WrapT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, o.minFilter(mipmapped))
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, o.MagFilter)
+	if o.Anisotropy > 1 {
+		if limit := MaxAnisotropy(); limit > 1 {
+			gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAX_ANISOTROPY, min(o.Anisotropy, limit))
+		}
+	}
+}
+
+var (
+	maxAnisotropy     float32
+	maxAnisotropyOnce sync.Once
+)
+
+// MaxAnisotropy 驱动支持的最大各向异性级别, 不支持各向异性过滤时为 0, 必须在主线程调用
+// GL 4.1 核心模式中各向异性过滤是扩展, 先检查扩展再查询, 避免产生 GL 错误
+func MaxAnisotropy() float32 {
+	maxAnisotropyOnce.Do(func() {
+		var count int32
+		gl.GetIntegerv(gl.NUM_EXTENSIONS, &count)
+		for i := int32(0); i < count; i++ {
+			name := gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i)))
+			if strings.HasSuffix(name, "_texture_filter_anisotropic") {
+				gl.GetFloatv(gl.MAX_TEXTURE_MAX_ANISOTROPY, &maxAnisotropy)
+				break
+			}
+		}
+	})
+	return maxAnisotropy
+}
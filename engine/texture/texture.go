@@ -5,13 +5,18 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 	_ "golang.org/x/image/bmp"
 	"image"
+	"image/color"
 	"image/draw"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
-	"os"
+	"io"
+	"sync"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/huangxiaobo/toy-engine/engine/glqueue"
+	"github.com/huangxiaobo/toy-engine/engine/job"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
 	"github.com/kardianos/osext"
 )
 
@@ -30,7 +35,7 @@ type Texture struct {
 }
 
 func (tex *Texture) LoadTexture(file string) error {
-	imgFile, err := os.Open(file)
+	imgFile, err := vfs.Open(file)
 	if err != nil {
 		// Get the Folder of the current Executable
 		dir, err := osext.ExecutableFolder()
@@ -40,7 +45,7 @@ func (tex *Texture) LoadTexture(file string) error {
 
 		// Read the file and return content or error
 		var secondErr error
-		imgFile, secondErr = os.Open(fmt.Sprintf("%s/%s", dir, file))
+		imgFile, secondErr = vfs.Open(fmt.Sprintf("%s/%s", dir, file))
 		if secondErr != nil {
 			return secondErr
 		}
@@ -93,18 +98,23 @@ func (tex *Texture) Upload() {
 }
 
 func ImageToPixelData(file string) (*image.RGBA, error) {
-	imgFile, err := os.Open(file)
+	imgFile, err := vfs.Open(file)
 	if err != nil {
-		return nil, fmt.Errorf("texture %q not found on disk: %v", file, err)
+		return nil, fmt.Errorf("texture %q not found: %v", file, err)
 	}
-	defer func(imgFile *os.File) {
+	defer func(imgFile io.ReadCloser) {
 		err := imgFile.Close()
 		if err != nil {
 
 		}
 	}(imgFile)
 
-	img, _, err := image.Decode(imgFile)
+	return DecodeImage(imgFile)
+}
+
+// DecodeImage 解码图片并转换为 RGBA, 用于模型文件内嵌的贴图
+func DecodeImage(r io.Reader) (*image.RGBA, error) {
+	img, _, err := image.Decode(r)
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +127,35 @@ func ImageToPixelData(file string) (*image.RGBA, error) {
 	return rgba, nil
 }
 
+// ErrorTextureFile 贴图加载失败时使用的品红黑色棋盘格
+const ErrorTextureFile = "./resource/texture/error.png"
+
+var (
+	errorImage     *image.RGBA
+	errorImageOnce sync.Once
+)
+
+// ErrorImage 错误贴图的像素, 读取失败时在内存中生成
+func ErrorImage() *image.RGBA {
+	errorImageOnce.Do(func() {
+		rgba, err := ImageToPixelData(ErrorTextureFile)
+		if err != nil {
+			rgba = image.NewRGBA(image.Rect(0, 0, 64, 64))
+			for y := 0; y < 64; y++ {
+				for x := 0; x < 64; x++ {
+					if (x/8+y/8)%2 == 0 {
+						rgba.Set(x, y, color.RGBA{R: 255, B: 255, A: 255})
+					} else {
+						rgba.Set(x, y, color.RGBA{A: 255})
+					}
+				}
+			}
+		}
+		errorImage = rgba
+	})
+	return errorImage
+}
+
 func NewTextureFromRGBA(rgba *image.RGBA) *Texture {
 	tex := &Texture{}
 	gl.GenTextures(1, &tex.Id)
@@ -165,33 +204,79 @@ func NewTextureFromSDLSurface(texType string, surface *sdl.Surface) *Texture {
 	return tex
 }
 
-func NewTexture(texWrapS, texWrapT, texMinFilter, texNagFilter int32, file string) (uint32, error) {
-	rgba, _ := ImageToPixelData(file)
+// NewTexture 读取图片并按 opts 上传, DDS, KTX 和 KTX2 文件直接上传压缩数据
+func NewTexture(opts Options, file string) (uint32, error) {
+	if IsCompressed(file) {
+		img, err := LoadCompressed(file)
+		if err != nil {
+			return 0, err
+		}
+		return NewTextureFromCompressed(opts, img), nil
+	}
+	rgba, err := ImageToPixelData(file)
+	if err != nil {
+		return 0, err
+	}
+	return NewTextureFromImage(opts, rgba), nil
+}
 
+// NewTextureFromImage 上传已解码的图片, 解码可以在任务中完成, 上传必须在主线程
+func NewTextureFromImage(opts Options, rgba *image.RGBA) uint32 {
 	var texture uint32
 	gl.GenTextures(1, &texture)
 	//gl.ActiveTexture(gl.TEXTURE0)
 	gl.BindTexture(gl.TEXTURE_2D, texture)
 
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, texWrapS)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, texWrapT)
-
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, texMinFilter)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, texNagFilter)
+	opts.apply(opts.Mipmap)
 
+	var internalFormat int32 = gl.RGBA
+	if opts.SRGB {
+		internalFormat = gl.SRGB8_ALPHA8
+	}
 	gl.TexImage2D(
 		gl.TEXTURE_2D,
 		0,
-		gl.RGBA,
+		internalFormat,
 		int32(rgba.Rect.Size().X),
 		int32(rgba.Rect.Size().Y),
 		0,
 		gl.RGBA,
 		gl.UNSIGNED_BYTE,
 		gl.Ptr(rgba.Pix))
-	gl.GenerateMipmap(gl.TEXTURE_2D)
+	if opts.Mipmap {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
 
 	gl.BindTexture(gl.TEXTURE_2D, 0)
 
-	return texture, nil
+	return texture
+}
+
+// NewTextureAsync 在任务中解码图片, 再由主线程的 GL 命令队列上传, 完成后在主线程调用 callback
+func NewTextureAsync(opts Options, file string, callback func(id uint32, err error)) {
+	job.Schedule(func() {
+		if IsCompressed(file) {
+			img, err := LoadCompressed(file)
+			var id uint32
+			glqueue.PostCallback(func() {
+				if err == nil {
+					id = NewTextureFromCompressed(opts, img)
+				}
+			}, func() {
+				callback(id, err)
+			})
+			return
+		}
+		rgba, err := ImageToPixelData(file)
+		if err != nil {
+			glqueue.PostCallback(func() {}, func() { callback(0, err) })
+			return
+		}
+		var id uint32
+		glqueue.PostCallback(func() {
+			id = NewTextureFromImage(opts, rgba)
+		}, func() {
+			callback(id, nil)
+		})
+	})
 }
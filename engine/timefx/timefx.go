@@ -0,0 +1,112 @@
+// Package timefx 游戏时间效果: 顿帧, 慢动作渐变和冻结帧
+// 效果按真实时间计时, 不受自身缩放影响, 多个效果同时生效时取最慢的缩放
+package timefx
+
+// Effect 一个时间缩放效果
+// 缩放在 RampIn 内从 1 渐变到 Scale, 保持到 Duration - RampOut 后渐变回 1
+type Effect struct {
+	Scale float32
+	// 持续时间(秒, 真实时间), 0 表示直到 Cancel
+	Duration float32
+	RampIn   float32
+	RampOut  float32
+
+	elapsed float32
+}
+
+// weight 当前时刻的缩放
+func (e *Effect) weight() float32 {
+	t := float32(1)
+	if e.RampIn > 0 && e.elapsed < e.RampIn {
+		t = e.elapsed / e.RampIn
+	}
+	if e.Duration > 0 && e.RampOut > 0 {
+		if remaining := e.Duration - e.elapsed; remaining < e.RampOut {
+			t = min(t, max(remaining, 0)/e.RampOut)
+		}
+	}
+	return 1 + (e.Scale-1)*t
+}
+
+func (e *Effect) finished() bool {
+	return e.Duration > 0 && e.elapsed >= e.Duration
+}
+
+// Effects 当前生效的时间效果, 每帧把真实时间换算为游戏时间
+type Effects struct {
+	// 基础缩放, 与效果的缩放相乘
+	BaseScale float32
+	// 最近一次 Update 得到的缩放
+	Scale float32
+
+	effects []*Effect
+}
+
+func NewEffects() *Effects {
+	return &Effects{
+		BaseScale: 1,
+		Scale:     1,
+		effects:   make([]*Effect, 0),
+	}
+}
+
+// Add 添加效果, 返回值可用于提前取消
+func (f *Effects) Add(effect *Effect) *Effect {
+	f.effects = append(f.effects, effect)
+	return effect
+}
+
+// HitStop 顿帧, 在 duration 内完全停止游戏时间
+func (f *Effects) HitStop(duration float32) *Effect {
+	return f.Add(&Effect{Scale: 0, Duration: duration})
+}
+
+// SlowMotion 慢动作, 在 rampIn 内减速到 scale, 结束前 rampOut 内恢复
+func (f *Effects) SlowMotion(scale, duration, rampIn, rampOut float32) *Effect {
+	return f.Add(&Effect{Scale: max(scale, 0), Duration: duration, RampIn: rampIn, RampOut: rampOut})
+}
+
+// Freeze 冻结帧, 直到 Cancel 为止
+func (f *Effects) Freeze() *Effect {
+	return f.Add(&Effect{Scale: 0})
+}
+
+func (f *Effects) Cancel(effect *Effect) {
+	for i, e := range f.effects {
+		if e == effect {
+			f.effects = append(f.effects[:i], f.effects[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *Effects) Clear() {
+	f.effects = f.effects[:0]
+}
+
+// Active 是否有效果正在生效
+func (f *Effects) Active() bool {
+	return len(f.effects) > 0
+}
+
+// Update 推进所有效果, 返回缩放后的游戏时间
+func (f *Effects) Update(elapsed float64) float64 {
+	scale := float32(1)
+	remaining := f.effects[:0]
+	for _, e := range f.effects {
+		e.elapsed += float32(elapsed)
+		if e.finished() {
+			continue
+		}
+		scale = min(scale, e.weight())
+		remaining = append(remaining, e)
+	}
+	// 释放被移除效果的引用
+	for i := len(remaining); i < len(f.effects); i++ {
+		f.effects[i] = nil
+	}
+	f.effects = remaining
+
+	f.Scale = max(scale, 0) * max(f.BaseScale, 0)
+	return elapsed * float64(f.Scale)
+}
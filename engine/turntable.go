@@ -0,0 +1,257 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/platforms"
+)
+
+const (
+	DefaultTurntableFrames = 16
+	DefaultTurntableSize   = 256
+	MaxTurntableFrames     = 360
+)
+
+// TurntableOptions 转台渲染参数, 零值使用默认值
+type TurntableOptions struct {
+	// 绕 Y 轴均匀分布的帧数
+	Frames int
+	// 每帧的像素尺寸
+	Width  int32
+	Height int32
+	// 相机仰角, 度
+	Elevation float32
+	// 垂直视野, 度, 默认 40
+	FOV float32
+	// 相机到包围盒中心的距离, 0 表示按包围球自动取景
+	Distance float32
+	// MSAA 采样数, 0 表示不使用多重采样
+	Samples int32
+	// 背景颜色, alpha 为 0 时图片背景透明
+	Background mgl32.Vec4
+
+	// 输出目录, 默认 ./output
+	OutputDir string
+	// 文件名前缀, 默认为模型文件名
+	Name string
+	// 所有帧拼成一张精灵表, 否则每帧一张图片
+	SpriteSheet bool
+	// 精灵表的列数, 0 表示接近正方形
+	Columns int
+}
+
+func (opts *TurntableOptions) setDefaults(modelPath string) {
+	if opts.Frames <= 0 {
+		opts.Frames = DefaultTurntableFrames
+	}
+	opts.Frames = min(opts.Frames, MaxTurntableFrames)
+	if opts.Width <= 0 {
+		opts.Width = DefaultTurntableSize
+	}
+	if opts.Height <= 0 {
+		opts.Height = DefaultTurntableSize
+	}
+	if opts.FOV <= 0 {
+		opts.FOV = 40
+	}
+	opts.Elevation = mgl32.Clamp(opts.Elevation, -89, 89)
+	if opts.OutputDir == "" {
+		opts.OutputDir = "./output"
+	}
+	if opts.Name == "" {
+		opts.Name = strings.TrimSuffix(filepath.Base(modelPath), filepath.Ext(modelPath))
+	}
+	if opts.Columns <= 0 {
+		opts.Columns = int(math.Ceil(math.Sqrt(float64(opts.Frames))))
+	}
+}
+
+// RenderTurntable 在隐藏窗口的上下文中加载模型, 相机绕模型旋转一周渲染 Frames 帧
+// 输出 <Name>_000.png ... 或一张 <Name>_sheet.png 精灵表, 返回写入的文件路径
+// 用于资源浏览器的缩略图和文档图片, 编辑器中使用 World.RenderTurntable
+func RenderTurntable(modelPath string, opts TurntableOptions) ([]string, error) {
+	opts.setDefaults(modelPath)
+	headless, err := platforms.NewHeadless(opts.Width, opts.Height)
+	if err != nil {
+		return nil, err
+	}
+	defer headless.Dispose()
+	return renderTurntable(modelPath, opts)
+}
+
+// RenderTurntable 使用编辑器当前的上下文渲染转台, 渲染前后的帧缓冲和视口保持不变
+func (w *World) RenderTurntable(modelPath string, opts TurntableOptions) ([]string, error) {
+	opts.setDefaults(modelPath)
+	return renderTurntable(modelPath, opts)
+}
+
+func renderTurntable(modelPath string, opts TurntableOptions) ([]string, error) {
+	m, err := model.NewModelFromFile(modelPath, 0)
+	if err != nil {
+		return nil, fmt.Errorf("turntable: load %s: %w", modelPath, err)
+	}
+	defer m.Dispose()
+
+	target, err := framebuffer.NewFrameBuffer(opts.Width, opts.Height, opts.Samples <= 0, framebuffer.RGBA8)
+	if err != nil {
+		return nil, err
+	}
+	defer target.Dispose()
+	canvas := target
+	if opts.Samples > 0 {
+		canvas, err = framebuffer.NewMultisampleFrameBuffer(opts.Width, opts.Height, opts.Samples, framebuffer.RGBA8)
+		if err != nil {
+			return nil, err
+		}
+		defer canvas.Dispose()
+	}
+
+	// 雾和环境光遮蔽依赖场景的设置和缓冲, 转台渲染时关闭
+	fog, ssao := config.Config.Fog.Enable, config.Config.SSAO.Enable
+	config.Config.Fog.Enable, config.Config.SSAO.Enable = false, false
+	defer func() {
+		config.Config.Fog.Enable, config.Config.SSAO.Enable = fog, ssao
+	}()
+
+	var lastViewport [4]int32
+	var lastFbo int32
+	gl.GetIntegerv(gl.VIEWPORT, &lastViewport[0])
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &lastFbo)
+	var clearColor [4]float32
+	gl.GetFloatv(gl.COLOR_CLEAR_VALUE, &clearColor[0])
+	blend := gl.IsEnabled(gl.BLEND)
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	defer func() {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(lastFbo))
+		gl.Viewport(lastViewport[0], lastViewport[1], lastViewport[2], lastViewport[3])
+		gl.ClearColor(clearColor[0], clearColor[1], clearColor[2], clearColor[3])
+		if blend {
+			gl.Enable(gl.BLEND)
+		}
+		if !depthTest {
+			gl.Disable(gl.DEPTH_TEST)
+		}
+	}()
+
+	// 按包围球取景, 任意角度下模型都在视野内
+	center := m.Bounds.Center()
+	radius := max(m.Bounds.Size().Len()*0.5, 0.0001)
+	fov := mgl32.DegToRad(opts.FOV)
+	aspect := float32(opts.Width) / float32(opts.Height)
+	distance := opts.Distance
+	if distance <= 0 {
+		halfFov := min(fov, 2*float32(math.Atan(math.Tan(float64(fov)*0.5)*float64(aspect)))) * 0.5
+		distance = radius / float32(math.Sin(float64(halfFov))) * 1.05
+	}
+	near := max(distance-radius*1.5, distance*0.01)
+	projection := mgl32.Perspective(fov, aspect, near, distance+radius*1.5)
+
+	// 头灯, 位于相机的左上方
+	headLight := light.NewPointLight(config.XmlLight{
+		XMLColor:         config.XmlRGB{R: 1, G: 1, B: 1},
+		XMLLightDiffuse:  config.XmlLightDiffuse{XMLColor: config.XmlRGB{R: 1, G: 1, B: 1}, XMLIntensity: 1},
+		XMLLightAmbient:  config.XmlLightAmbient{XMLIntensity: 0.3},
+		XMLLightSpecular: config.XmlLightSpecular{XMLColor: config.XmlRGB{R: 1, G: 1, B: 1}},
+	})
+	lights := []*light.PointLight{headLight}
+
+	gl.Disable(gl.BLEND)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.ClearColor(opts.Background[0], opts.Background[1], opts.Background[2], opts.Background[3])
+
+	elevation := float64(mgl32.DegToRad(opts.Elevation))
+	frames := make([]*image.NRGBA, 0, opts.Frames)
+	for i := 0; i < opts.Frames; i++ {
+		angle := float64(i) * 2 * math.Pi / float64(opts.Frames)
+		dir := mgl32.Vec3{
+			float32(math.Sin(angle) * math.Cos(elevation)),
+			float32(math.Sin(elevation)),
+			float32(math.Cos(angle) * math.Cos(elevation)),
+		}
+		eye := center.Add(dir.Mul(distance))
+		view := mgl32.LookAtV(eye, center, mgl32.Vec3{0, 1, 0})
+		side := mgl32.Vec3{0, 1, 0}.Cross(dir).Normalize()
+		headLight.SetPosition(eye.Add(side.Mul(-radius)).Add(mgl32.Vec3{0, radius, 0}).Vec4(1))
+
+		canvas.Bind()
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		m.Render(projection, mgl32.Ident4(), view, &eye, lights)
+		if canvas != target {
+			canvas.BlitTo(target)
+		}
+		frames = append(frames, readPixels(target))
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0o755); err != nil {
+		return nil, err
+	}
+	if !opts.SpriteSheet {
+		files := make([]string, 0, len(frames))
+		for i, frame := range frames {
+			file := filepath.Join(opts.OutputDir, fmt.Sprintf("%s_%03d.png", opts.Name, i))
+			if err := writePNG(file, frame); err != nil {
+				return files, err
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	}
+
+	// 精灵表按行排列, 第 i 帧在第 i/Columns 行第 i%Columns 列
+	columns := min(opts.Columns, len(frames))
+	rows := (len(frames) + columns - 1) / columns
+	width, height := int(opts.Width), int(opts.Height)
+	sheet := image.NewNRGBA(image.Rect(0, 0, columns*width, rows*height))
+	for i, frame := range frames {
+		x, y := (i%columns)*width, (i/columns)*height
+		draw.Draw(sheet, image.Rect(x, y, x+width, y+height), frame, image.Point{}, draw.Src)
+	}
+	file := filepath.Join(opts.OutputDir, opts.Name+"_sheet.png")
+	if err := writePNG(file, sheet); err != nil {
+		return nil, err
+	}
+	return []string{file}, nil
+}
+
+// readPixels 读取颜色附件, OpenGL 的原点在左下角, 按行翻转
+func readPixels(fb *framebuffer.FrameBuffer) *image.NRGBA {
+	width, height := int(fb.Width), int(fb.Height)
+	pixels := make([]uint8, width*height*4)
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fb.Fbo)
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, fb.Width, fb.Height, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&pixels[0]))
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	stride := width * 4
+	for y := 0; y < height; y++ {
+		copy(img.Pix[y*img.Stride:y*img.Stride+stride], pixels[(height-1-y)*stride:(height-y)*stride])
+	}
+	return img
+}
+
+func writePNG(file string, img image.Image) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/input"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowBindings 查看和重新绑定输入动作, 点击绑定后按下新的按键, 鼠标键或手柄键替换它, Escape 取消
+type WindowBindings struct {
+	visible bool
+	flags   WindowFlags
+
+	actions *input.ActionMap
+	input   *input.Input
+	// 保存绑定的本地文件
+	file string
+
+	// 正在等待新按键的动作和绑定下标, 下标为 -1 时添加新的绑定
+	listenAction  string
+	listenBinding int
+	// 保存的结果
+	status string
+}
+
+func NewWindowBindings() *WindowBindings {
+	return &WindowBindings{
+		flags: WindowFlags{noMenu: true, noCollapse: true},
+	}
+}
+
+const (
+	WindowBindingsWidth  = 360
+	WindowBindingsHeight = 400
+)
+
+func (w *WindowBindings) Show(displaySize [2]float32) {
+	if !w.visible || w.actions == nil {
+		w.stopListening()
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0]/2 - WindowBindingsWidth/2, Y: displaySize[1]/2 - WindowBindingsHeight/2}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: WindowBindingsWidth, Y: WindowBindingsHeight}, imgui.ConditionFirstUseEver)
+
+	defer imgui.End()
+	if !imgui.BeginV("Input Bindings", &w.visible, w.flags.combined()) {
+		return
+	}
+	w.listen()
+
+	for _, action := range w.actions.Actions() {
+		imgui.Text(action.Name)
+		imgui.Indent()
+		for i, b := range action.Bindings {
+			label := b.String()
+			if w.listening(action.Name, i) {
+				label = "..."
+			}
+			if imgui.Button(fmt.Sprintf("%s##bind%s%d", label, action.Name, i)) {
+				w.startListening(action.Name, i)
+			}
+			imgui.SameLine()
+			if imgui.Button(fmt.Sprintf("x##unbind%s%d", action.Name, i)) {
+				w.actions.Unbind(action.Name, i)
+				w.stopListening()
+				break
+			}
+		}
+		label := "+"
+		if w.listening(action.Name, -1) {
+			label = "..."
+		}
+		if imgui.Button(fmt.Sprintf("%s##addbind%s", label, action.Name)) {
+			w.startListening(action.Name, -1)
+		}
+		imgui.Unindent()
+	}
+
+	imgui.Separator()
+	if w.listenAction != "" {
+		imgui.Text("Press a key, mouse or gamepad button (Escape to cancel)")
+	}
+	if imgui.Button("Save##bindings") {
+		w.save()
+	}
+	imgui.SameLine()
+	if imgui.Button("Reset##bindings") {
+		w.actions.Reset()
+		w.stopListening()
+	}
+	if w.status != "" {
+		imgui.Text(w.status)
+	}
+}
+
+func (w *WindowBindings) listening(action string, i int) bool {
+	return w.listenAction == action && w.listenBinding == i
+}
+
+func (w *WindowBindings) startListening(action string, i int) {
+	w.listenAction, w.listenBinding = action, i
+	w.actions.Suspended = true
+}
+
+func (w *WindowBindings) stopListening() {
+	w.listenAction = ""
+	if w.actions != nil {
+		w.actions.Suspended = false
+	}
+}
+
+// listen 等待绑定时使用这一帧按下的输入, 点击按钮的那一帧按下的鼠标键不算
+func (w *WindowBindings) listen() {
+	if w.listenAction == "" || w.input == nil {
+		return
+	}
+	b, ok := w.input.LastPressed()
+	if !ok {
+		return
+	}
+	if b.Device == input.DeviceKey && input.Key(b.Code) == input.KeyEscape {
+		w.stopListening()
+		return
+	}
+	action := w.actions.Action(w.listenAction)
+	if action == nil {
+		w.stopListening()
+		return
+	}
+	if w.listenBinding >= 0 && w.listenBinding < len(action.Bindings) {
+		// 保留原来绑定的方向
+		if scale := action.Bindings[w.listenBinding].Scale; scale < 0 {
+			b.Scale = -b.Scale
+		}
+		action.Bindings[w.listenBinding] = b
+	} else {
+		w.actions.Bind(w.listenAction, b)
+	}
+	w.stopListening()
+}
+
+func (w *WindowBindings) save() {
+	if err := config.SaveInputMap(w.file, w.actions.XML()); err != nil {
+		logger.Error(err)
+		w.status = err.Error()
+		return
+	}
+	w.status = "Saved " + w.file
+}
+
+func (w *WindowBindings) SetActions(actions *input.ActionMap, in *input.Input, file string) {
+	w.actions, w.input, w.file = actions, in, file
+}
+
+func (w *WindowBindings) SetVisible(visible bool) {
+	w.visible = visible
+}
+
+func (w *WindowBindings) Visible() bool {
+	return w.visible
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/light"
 	"github.com/inkyblackness/imgui-go/v4"
 	"reflect"
 	"strings"
@@ -145,6 +146,38 @@ func (w *WindowLight) Show(displaySize [2]float32) {
 	imgui.Unindent()
 
 	imgui.Unindent()
+
+	if l, ok := w.lightObj.(*light.PointLight); ok {
+		w.ShowShadow(l.Shadow)
+	}
+}
+
+// ShowShadow 点光源的全向阴影, 同时最多 light.MaxShadowLights 个光源投射阴影
+func (w *WindowLight) ShowShadow(shadow *light.Shadow) {
+	if shadow == nil {
+		return
+	}
+	imgui.Spacing()
+	imgui.Spacing()
+	imgui.Bullet()
+	imgui.Text("Shadow")
+	imgui.Indent()
+	imgui.Checkbox("Enable##shadow", &shadow.Enable)
+	imgui.SetNextItemWidth(WindowLightItemWidth)
+	resolution := shadow.Resolution
+	if imgui.BeginCombo("Resolution##shadow", fmt.Sprintf("%d", resolution)) {
+		for _, r := range []int32{256, 512, 1024, 2048} {
+			if imgui.SelectableV(fmt.Sprintf("%d", r), r == resolution, 0, imgui.Vec2{}) {
+				shadow.Resolution = r
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.SetNextItemWidth(WindowLightItemWidth)
+	imgui.DragFloatV("Bias##shadow", &shadow.Bias, 0.005, 0, 2, "%.3f", imgui.SliderFlagsNone)
+	imgui.SetNextItemWidth(WindowLightItemWidth)
+	imgui.DragFloatV("Far##shadow", &shadow.Far, 0.5, 1, 1000, "%.1f", imgui.SliderFlagsNone)
+	imgui.Unindent()
 }
 
 func (w *WindowLight) ShowFloat3(rPtrType reflect.Type, rPtrVal reflect.Value, fieldName string) {
@@ -2,6 +2,23 @@ package ui
 
 import (
 	"fmt"
+	"github.com/huangxiaobo/toy-engine/engine/audio"
+	"github.com/huangxiaobo/toy-engine/engine/camera"
+	"github.com/huangxiaobo/toy-engine/engine/gizmo"
+	"github.com/huangxiaobo/toy-engine/engine/input"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/lightmap"
+	"github.com/huangxiaobo/toy-engine/engine/measure"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/occlusion"
+	"github.com/huangxiaobo/toy-engine/engine/paint"
+	"github.com/huangxiaobo/toy-engine/engine/placement"
+	"github.com/huangxiaobo/toy-engine/engine/postprocess"
+	"github.com/huangxiaobo/toy-engine/engine/profiler"
+	"github.com/huangxiaobo/toy-engine/engine/shortcut"
+	"github.com/huangxiaobo/toy-engine/engine/spline"
+	"github.com/huangxiaobo/toy-engine/engine/terrain"
+	"github.com/huangxiaobo/toy-engine/engine/timefx"
 	"github.com/huangxiaobo/toy-engine/engine/utils"
 	"github.com/inkyblackness/imgui-go/v4"
 	"time"
@@ -34,17 +51,39 @@ type WindowMain struct {
 	modelWindow *WindowModel
 	modelItems  []ModelItem
 
-	statusWindow *WindowStatus
+	statusWindow  *WindowStatus
+	renderWindow  *WindowRender
+	toolbarWindow *WindowToolbar
+	paintWindow   *WindowPaint
+	sculptWindow  *WindowSculpt
+	splineWindow  *WindowSpline
+	placeWindow   *WindowPlace
+
+	// 场景中的相机, 下拉框切换当前相机
+	cameras        *camera.Cameras
+	timelineWindow *WindowTimeline
+	bindingsWindow *WindowBindings
+	shaderWindow   *WindowShader
+	// 命令, Menu 菜单中列出, 命令面板中按名称执行
+	commands      *shortcut.Registry
+	paletteWindow *WindowPalette
 }
 
 func NewWindowMain(world interface{}) *WindowMain {
 	wm := &WindowMain{
-		flags:        WindowFlags{noResize: true, noMove: true, noMenu: false, noCollapse: true, noTitlebar: true},
-		World:        world,
-		modelItems:   make([]ModelItem, 0),
-		lightWindow:  NewWindowLight(),
-		modelWindow:  NewWindowModel(),
-		statusWindow: NewWindowStatus(),
+		flags:         WindowFlags{noResize: true, noMove: true, noMenu: false, noCollapse: true, noTitlebar: true},
+		World:         world,
+		modelItems:    make([]ModelItem, 0),
+		lightWindow:   NewWindowLight(),
+		modelWindow:   NewWindowModel(),
+		statusWindow:  NewWindowStatus(),
+		renderWindow:  NewWindowRender(),
+		toolbarWindow: NewWindowToolbar(),
+
+		timelineWindow: NewWindowTimeline(),
+		bindingsWindow: NewWindowBindings(),
+		shaderWindow:   NewWindowShader(),
+		paletteWindow:  NewWindowPalette(),
 	}
 	return wm
 }
@@ -61,6 +100,22 @@ func (mw *WindowMain) Show(displaySize [2]float32) {
 	// MenuBar
 	if imgui.BeginMenuBar() {
 		if imgui.BeginMenu("Menu") {
+			mw.showCommandMenu()
+			imgui.EndMenu()
+		}
+		if imgui.BeginMenu("View") {
+			if imgui.MenuItemV("Render Settings", "", mw.renderWindow.Visible(), true) {
+				mw.renderWindow.SetVisible(!mw.renderWindow.Visible())
+			}
+			if imgui.MenuItemV("Camera Timeline", "", mw.timelineWindow.Visible(), true) {
+				mw.timelineWindow.SetVisible(!mw.timelineWindow.Visible())
+			}
+			if imgui.MenuItemV("Input Bindings", "", mw.bindingsWindow.Visible(), true) {
+				mw.bindingsWindow.SetVisible(!mw.bindingsWindow.Visible())
+			}
+			if imgui.MenuItemV("Shader Editor", "", mw.shaderWindow.Visible(), true) {
+				mw.shaderWindow.SetVisible(!mw.shaderWindow.Visible())
+			}
 			imgui.EndMenu()
 		}
 		if imgui.BeginMenu("Examples") {
@@ -74,6 +129,8 @@ func (mw *WindowMain) Show(displaySize [2]float32) {
 
 	imgui.PushItemWidth(imgui.FontSize() * -12)
 
+	mw.showCameraCombo()
+
 	if !imgui.CollapsingHeaderV("Scene", imgui.TreeNodeFlagsDefaultOpen) {
 		imgui.End()
 		return
@@ -112,7 +169,79 @@ func (mw *WindowMain) Show(displaySize [2]float32) {
 		mw.menuScreenshot = false
 	}
 	mw.statusWindow.Show(displaySize)
+	mw.renderWindow.Show(displaySize)
+	mw.toolbarWindow.Show(displaySize)
+	mw.timelineWindow.Show(displaySize)
+	mw.bindingsWindow.Show(displaySize)
+	mw.shaderWindow.SetObj(mw.SelectedModel())
+	mw.shaderWindow.Show(displaySize)
+	mw.paletteWindow.Show(displaySize)
+	if mw.paintWindow != nil {
+		mw.paintWindow.Show(displaySize)
+	}
+	if mw.sculptWindow != nil {
+		mw.sculptWindow.Show(displaySize)
+	}
+	if mw.splineWindow != nil {
+		mw.splineWindow.Show(displaySize)
+	}
+	if mw.placeWindow != nil {
+		mw.placeWindow.Show(displaySize)
+	}
+
+}
+
+// showCommandMenu 列出所有命令及其快捷键
+func (mw *WindowMain) showCommandMenu() {
+	if mw.commands == nil {
+		return
+	}
+	for _, cmd := range mw.commands.Commands() {
+		if imgui.MenuItemV(cmd.Name, cmd.Shortcut.String(), false, cmd.Available()) {
+			cmd.Run()
+		}
+	}
+}
+
+func (mw *WindowMain) SetCommands(commands *shortcut.Registry) {
+	mw.commands = commands
+	mw.paletteWindow.SetCommands(commands)
+}
+
+// ToggleCommandPalette 打开或关闭命令面板
+func (mw *WindowMain) ToggleCommandPalette() {
+	mw.paletteWindow.Toggle()
+}
+
+// CycleShading 循环切换视口着色模式
+func (mw *WindowMain) CycleShading() {
+	mw.toolbarWindow.CycleShading()
+}
 
+// ToggleWireframe 在线框和上一次的着色模式之间切换
+func (mw *WindowMain) ToggleWireframe() {
+	mw.toolbarWindow.ToggleWireframe()
+}
+
+// ToggleMeasure 开关测量工具
+func (mw *WindowMain) ToggleMeasure() {
+	mw.toolbarWindow.ToggleMeasure()
+}
+
+// showCameraCombo 切换当前相机
+func (mw *WindowMain) showCameraCombo() {
+	if mw.cameras == nil {
+		return
+	}
+	active := mw.cameras.Active()
+	if imgui.BeginCombo("Camera", active.Name) {
+		for _, c := range mw.cameras.List() {
+			if imgui.SelectableV(c.Name, c == active, 0, imgui.Vec2{}) {
+				mw.cameras.SetActive(c.Name)
+			}
+		}
+		imgui.EndCombo()
+	}
 }
 
 func (mw *WindowMain) addLightTreeNode() {
@@ -166,10 +295,46 @@ func (mw *WindowMain) addModelTreeNode() {
 	}
 }
 
+// SelectedModel 模型列表中选中并正在编辑的对象, 没有时返回 nil
+func (mw *WindowMain) SelectedModel() interface{} {
+	if !mw.modelWindow.visible || ShowPanel != ShowModelPanel {
+		return nil
+	}
+	return mw.modelWindow.modelObj
+}
+
+// SelectModel 在模型面板中编辑 obj, 与在模型列表中点击相同
+func (mw *WindowMain) SelectModel(obj interface{}) {
+	mw.modelWindow.SetRenderObj(obj)
+	ShowPanel = ShowModelPanel
+}
+
+// ClearModelSelection 关闭模型面板, 灯光面板不受影响
+func (mw *WindowMain) ClearModelSelection() {
+	if ShowPanel == ShowModelPanel {
+		ShowPanel = 0
+	}
+}
+
 func (mw *WindowMain) SetModelItem(items []ModelItem) {
 	mw.modelItems = items
 }
 
+// SelectLight 在灯光面板中编辑 lightObj, 与在灯光列表中点击相同
+func (mw *WindowMain) SelectLight(lightObj interface{}) {
+	mw.lightWindow.SetLight(lightObj)
+	ShowPanel = ShowLightPanel
+}
+
+// SelectedLight 灯光面板中正在编辑的点光源, 没有时返回 nil
+func (mw *WindowMain) SelectedLight() *light.PointLight {
+	if ShowPanel != ShowLightPanel {
+		return nil
+	}
+	l, _ := mw.lightWindow.lightObj.(*light.PointLight)
+	return l
+}
+
 func (mw *WindowMain) AddLight(light interface{}) {
 	mw.lightObjs = append(mw.lightObjs, light)
 }
@@ -178,6 +343,96 @@ func (mw *WindowMain) AddModelItem(item ModelItem) {
 	mw.modelItems = append(mw.modelItems, item)
 }
 
+// RemoveModelItem 从模型列表中移除 obj, 正在编辑时关闭模型面板
+func (mw *WindowMain) RemoveModelItem(obj interface{}) {
+	for i, item := range mw.modelItems {
+		if item.Obj == obj {
+			mw.modelItems = append(mw.modelItems[:i], mw.modelItems[i+1:]...)
+			break
+		}
+	}
+	if mw.modelWindow.modelObj == obj {
+		mw.ClearModelSelection()
+		mw.modelWindow.SetRenderObj(nil)
+	}
+}
+
+func (mw *WindowMain) SetPostProcess(chain *postprocess.Chain) {
+	mw.renderWindow.SetPostProcess(chain)
+}
+
+func (mw *WindowMain) SetMeasureTool(tool *measure.Tool) {
+	mw.toolbarWindow.SetMeasureTool(tool)
+}
+
+func (mw *WindowMain) SetPaintTool(tool *paint.Tool) {
+	mw.toolbarWindow.SetPaintTool(tool)
+	mw.paintWindow = NewWindowPaint(tool)
+}
+
+func (mw *WindowMain) SetSculptTool(tool *terrain.SculptTool) {
+	mw.toolbarWindow.SetSculptTool(tool)
+	mw.sculptWindow = NewWindowSculpt(tool)
+}
+
+func (mw *WindowMain) SetSplineTool(tool *spline.Tool) {
+	mw.toolbarWindow.SetSplineTool(tool)
+	mw.splineWindow = NewWindowSpline(tool)
+}
+
+func (mw *WindowMain) SetPlacementTool(tool *placement.Tool) {
+	mw.toolbarWindow.SetPlacementTool(tool)
+	mw.placeWindow = NewWindowPlace(tool)
+}
+
+func (mw *WindowMain) SetGizmoTool(tool *gizmo.Tool) {
+	mw.toolbarWindow.SetGizmoTool(tool)
+}
+
+func (mw *WindowMain) SetLightGizmos(gizmos *gizmo.Lights) {
+	mw.toolbarWindow.SetLightGizmos(gizmos)
+}
+
+func (mw *WindowMain) SetOcclusionCuller(culler *occlusion.Culler) {
+	mw.renderWindow.SetOcclusionCuller(culler)
+}
+
+func (mw *WindowMain) SetReflection(reflection *model.PlanarReflection) {
+	mw.renderWindow.SetReflection(reflection)
+}
+
+func (mw *WindowMain) SetCameras(cameras *camera.Cameras) {
+	mw.cameras = cameras
+	mw.renderWindow.SetCameras(cameras)
+	mw.timelineWindow.SetCameras(cameras)
+}
+
+// SetInputActions 设置可重新绑定的输入动作, file 为保存绑定的本地文件
+func (mw *WindowMain) SetInputActions(actions *input.ActionMap, in *input.Input, file string) {
+	mw.bindingsWindow.SetActions(actions, in, file)
+}
+
+func (mw *WindowMain) SetAudio(system *audio.System) {
+	mw.renderWindow.SetAudio(system)
+}
+
+func (mw *WindowMain) SetProfiler(p *profiler.Profiler) {
+	mw.renderWindow.SetProfiler(p)
+	mw.statusWindow.SetProfiler(p)
+}
+
+func (mw *WindowMain) SetTimeEffects(effects *timefx.Effects) {
+	mw.renderWindow.SetTimeEffects(effects)
+}
+
+func (mw *WindowMain) SetSunLight(sun *light.DirectionLight) {
+	mw.renderWindow.SetSunLight(sun)
+}
+
+func (mw *WindowMain) SetLightmapBaker(baker *lightmap.Baker) {
+	mw.renderWindow.SetLightmapBaker(baker)
+}
+
 func (mw *WindowMain) ScreenCat(width, height int) {
 	utils.Screenshot(width, height)
 
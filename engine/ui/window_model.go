@@ -3,6 +3,10 @@ package ui
 import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/impostor"
+	"github.com/huangxiaobo/toy-engine/engine/material"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/terrain"
 	"github.com/inkyblackness/imgui-go/v4"
 	"reflect"
 )
@@ -33,6 +37,9 @@ type WindowModel struct {
 	modelObj interface{}
 	content  string
 
+	// 当前编辑的材质槽
+	materialSlot int
+
 	showDemoWindow bool
 }
 
@@ -75,10 +82,8 @@ func (w *WindowModel) Show(displaySize [2]float32) {
 	rPtrType := reflect.TypeOf(w.modelObj)
 	rPtrVal := reflect.ValueOf(w.modelObj)
 
-	rMatType := rPtrType
 	rMatVal := rPtrVal
 	if rPtrType.Kind() == reflect.Ptr {
-		rMatType = rPtrType.Elem()
 		rMatVal = rPtrVal.Elem()
 	}
 
@@ -141,43 +146,16 @@ func (w *WindowModel) Show(displaySize [2]float32) {
 	}
 
 	imgui.Unindent()
-	imgui.Spacing()
-	imgui.Spacing()
-	imgui.Bullet()
-	imgui.Text("Material")
-	imgui.Indent()
-
-	material := rMatVal.FieldByName("Material").Interface()
-	rMatType = reflect.TypeOf(material)
-	rMatVal = reflect.ValueOf(material)
-	//if rMatType.Kind() == reflect.Ptr {
-	//	rMatType = rMatType.Elem()
-	//	rMatVal = rMatVal.Elem()
-	//}
-
-	if imgui.BeginTableV("tableMaterial", len(tabMaterialHeader), flgs, imgui.Vec2{}, 0.0) {
-		imgui.TableSetupColumnV("tableMaterial.Column1", imgui.TableColumnFlagsWidthFixed, WindowModelTableColumnWidths, 0)
-		imgui.TableSetupColumnV("tableMaterial.Column2", imgui.TableColumnFlagsWidthStretch, WindowModelTableColumn2Width, 0)
-		for row, fieldName := range []string{"AmbientColor", "DiffuseColor", "SpecularColor", "Shininess"} {
-
-			imgui.TableNextRow()
-			imgui.TableSetColumnIndex(0)
-
-			imgui.Text(fieldName)
 
-			imgui.TableSetColumnIndex(1)
-			imgui.SetNextItemWidth(WindowModelItemWidth)
-			if row == 3 {
-				w.ShowFloat(rMatType, rMatVal, fieldName)
-			} else {
-				w.ShowColor3(rMatType, rMatVal, fieldName)
-			}
-		}
-
-		imgui.EndTable()
+	// 公告板, 水面等对象没有材质
+	if rMatVal.FieldByName("Material").IsValid() {
+		w.ShowMaterial(rMatVal, flgs)
 	}
-
-	imgui.Unindent()
+	w.ShowTerrainLOD(rPtrVal.Elem().FieldByName("LOD"))
+	w.ShowWater(w.modelObj)
+	w.ShowVegetation(w.modelObj)
+	w.ShowCrowd(w.modelObj)
+	w.ShowRenderPriority(w.modelObj)
 
 	// End of ShowDemoWindow()
 	imgui.End()
@@ -245,7 +223,343 @@ func (w *WindowModel) ShowColor3(rPtrType reflect.Type, rPtrVal reflect.Value, f
 	}
 }
 
+// ShowMaterial 材质颜色和贴图动画, 有多个材质槽时先选择材质
+func (w *WindowModel) ShowMaterial(rMatVal reflect.Value, flgs imgui.TableFlags) {
+	imgui.Spacing()
+	imgui.Spacing()
+	imgui.Bullet()
+	imgui.Text("Material")
+	imgui.Indent()
+
+	material := rMatVal.FieldByName("Material").Interface()
+	if slots := rMatVal.FieldByName("Materials"); slots.IsValid() && slots.Len() > 0 {
+		material = w.ShowMaterialSlots(slots)
+	}
+	rMatType := reflect.TypeOf(material)
+	rMatVal = reflect.ValueOf(material)
+	//if rMatType.Kind() == reflect.Ptr {
+	//	rMatType = rMatType.Elem()
+	//	rMatVal = rMatVal.Elem()
+	//}
+
+	if imgui.BeginTableV("tableMaterial", len(tabMaterialHeader), flgs, imgui.Vec2{}, 0.0) {
+		imgui.TableSetupColumnV("tableMaterial.Column1", imgui.TableColumnFlagsWidthFixed, WindowModelTableColumnWidths, 0)
+		imgui.TableSetupColumnV("tableMaterial.Column2", imgui.TableColumnFlagsWidthStretch, WindowModelTableColumn2Width, 0)
+		for row, fieldName := range []string{"AmbientColor", "DiffuseColor", "SpecularColor", "EmissiveColor", "Shininess", "Opacity", "EmissiveIntensity"} {
+
+			imgui.TableNextRow()
+			imgui.TableSetColumnIndex(0)
+
+			imgui.Text(fieldName)
+
+			imgui.TableSetColumnIndex(1)
+			imgui.SetNextItemWidth(WindowModelItemWidth)
+			if row >= 4 {
+				w.ShowFloat(rMatType, rMatVal, fieldName)
+			} else {
+				w.ShowColor3(rMatType, rMatVal, fieldName)
+			}
+		}
+
+		imgui.EndTable()
+	}
+	w.ShowFlipbook(rMatVal.Elem().FieldByName("Flipbook"))
+	w.ShowUVTransform(rMatVal.Elem().FieldByName("UV"))
+
+	imgui.Unindent()
+}
+
+// ShowFlipbook 材质的序列帧播放设置
+func (w *WindowModel) ShowFlipbook(rVal reflect.Value) {
+	if !rVal.IsValid() || rVal.IsNil() {
+		return
+	}
+	flipbook := rVal.Interface().(*material.Flipbook)
+
+	imgui.Spacing()
+	imgui.Text("Flipbook")
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatV("FPS##flipbook", &flipbook.FPS, 0.1, 0, 120, "%.1f", imgui.SliderFlagsNone)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.BeginCombo("Loop##flipbook", material.LoopModeNames[flipbook.Loop]) {
+		for i, name := range material.LoopModeNames {
+			if imgui.SelectableV(name, flipbook.Loop == material.LoopMode(i), 0, imgui.Vec2{}) {
+				flipbook.Loop = material.LoopMode(i)
+			}
+		}
+		imgui.EndCombo()
+	}
+	imgui.Checkbox("Blend Frames##flipbook", &flipbook.Blend)
+	if imgui.Button("Restart##flipbook") {
+		flipbook.Reset()
+	}
+}
+
+// ShowUVTransform 材质的纹理坐标变换, 没有时可以添加
+func (w *WindowModel) ShowUVTransform(rVal reflect.Value) {
+	if !rVal.IsValid() {
+		return
+	}
+	imgui.Spacing()
+	if rVal.IsNil() {
+		if imgui.Button("Add UV Transform") && rVal.CanSet() {
+			rVal.Set(reflect.ValueOf(material.NewUVTransform()))
+		}
+		return
+	}
+	uv := rVal.Interface().(*material.UVTransform)
+
+	imgui.Text("UV Transform")
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloat2V("Offset##uv", (*[2]float32)(&uv.Offset), 0.01, -10, 10, "%.2f", imgui.SliderFlagsNone)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloat2V("Scale##uv", (*[2]float32)(&uv.Scale), 0.01, -100, 100, "%.2f", imgui.SliderFlagsNone)
+	rotation := mgl32.RadToDeg(uv.Rotation)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.DragFloatV("Rotation##uv", &rotation, 0.5, -360, 360, "%.1f deg", imgui.SliderFlagsNone) {
+		uv.Rotation = mgl32.DegToRad(rotation)
+	}
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloat2V("Scroll##uv", (*[2]float32)(&uv.ScrollSpeed), 0.01, -10, 10, "%.2f", imgui.SliderFlagsNone)
+	spin := mgl32.RadToDeg(uv.RotationSpeed)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.DragFloatV("Spin##uv", &spin, 0.5, -720, 720, "%.1f deg/s", imgui.SliderFlagsNone) {
+		uv.RotationSpeed = mgl32.DegToRad(spin)
+	}
+	if imgui.Button("Reset Animation##uv") {
+		uv.Reset()
+	}
+	imgui.SameLine()
+	if imgui.Button("Remove##uv") && rVal.CanSet() {
+		rVal.Set(reflect.Zero(rVal.Type()))
+	}
+}
+
+// ShowTerrainLOD 地形分块细节层级的设置和统计
+func (w *WindowModel) ShowTerrainLOD(rVal reflect.Value) {
+	if !rVal.IsValid() || rVal.IsNil() {
+		return
+	}
+	lod, ok := rVal.Interface().(*terrain.LOD)
+	if !ok {
+		return
+	}
+
+	imgui.Spacing()
+	imgui.Spacing()
+	imgui.Bullet()
+	imgui.Text("LOD")
+	imgui.Indent()
+	imgui.Checkbox("Enable##lod", &lod.Enable)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatV("Distance##lod", &lod.Distance, 0.5, 1, 1000, "%.1f", imgui.SliderFlagsNone)
+	imgui.Text(fmt.Sprintf("Chunks: %d  Visible: %d", lod.Stats.Chunks, lod.Stats.Visible))
+	imgui.Text(fmt.Sprintf("Triangles: %d", lod.Stats.Triangles))
+	for level, count := range lod.Stats.Levels {
+		imgui.Text(fmt.Sprintf("Level %d: %d", level, count))
+	}
+	imgui.Unindent()
+}
+
+// ShowWater 水面的颜色, 折射和波浪参数
+func (w *WindowModel) ShowWater(obj interface{}) {
+	water, ok := obj.(*model.Water)
+	if !ok {
+		return
+	}
+
+	imgui.Spacing()
+	imgui.Spacing()
+	imgui.Bullet()
+	imgui.Text("Water")
+	imgui.Indent()
+	shallow := [3]float32(water.ShallowColor)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.ColorEdit3("Shallow##water", &shallow) {
+		water.ShallowColor = shallow
+	}
+	deep := [3]float32(water.DeepColor)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.ColorEdit3("Deep##water", &deep) {
+		water.DeepColor = deep
+	}
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatV("Clarity##water", &water.Clarity, 0.1, 0.1, 100, "%.1f", imgui.SliderFlagsNone)
+	imgui.Checkbox("Refraction##water", &water.Refraction)
+	imgui.Checkbox("Reflection##water", &water.Reflection.Enable)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.SliderFloat("Reflection Strength##water", &water.Reflection.Strength, 0, 1)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.SliderFloat("Distortion##water", &water.Distortion, 0, 0.1)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.SliderFloat("Normal Strength##water", &water.NormalStrength, 0, 2)
+
+	for i := range water.Waves {
+		wave := &water.Waves[i]
+		imgui.Spacing()
+		imgui.Text(fmt.Sprintf("Wave %d", i))
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.DragFloat2V(fmt.Sprintf("Direction##wave%d", i), (*[2]float32)(&wave.Direction), 0.01, -1, 1, "%.2f", imgui.SliderFlagsNone)
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.DragFloatV(fmt.Sprintf("Amplitude##wave%d", i), &wave.Amplitude, 0.01, 0, 5, "%.2f", imgui.SliderFlagsNone)
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.DragFloatV(fmt.Sprintf("Wavelength##wave%d", i), &wave.Wavelength, 0.1, 0.1, 100, "%.1f", imgui.SliderFlagsNone)
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.SliderFloat(fmt.Sprintf("Steepness##wave%d", i), &wave.Steepness, 0, 1)
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.DragFloatV(fmt.Sprintf("Speed##wave%d", i), &wave.Speed, 0.01, 0, 5, "%.2f", imgui.SliderFlagsNone)
+	}
+	imgui.Unindent()
+}
+
+// ShowVegetation 植被的散布, 风和淡出参数, 修改散布参数后需要重新散布
+func (w *WindowModel) ShowVegetation(obj interface{}) {
+	vegetation, ok := obj.(*model.Vegetation)
+	if !ok {
+		return
+	}
+
+	imgui.Spacing()
+	imgui.Spacing()
+	imgui.Bullet()
+	imgui.Text("Vegetation")
+	imgui.Indent()
+	imgui.Text(fmt.Sprintf("Instances: %d, Visible: %d", vegetation.Stats.Instances, vegetation.Stats.Visible))
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatV("Density##vegetation", &vegetation.Density, 0.1, 0.1, 100, "%.1f", imgui.SliderFlagsNone)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatV("Size##vegetation", &vegetation.Size, 0.5, 1, 500, "%.1f", imgui.SliderFlagsNone)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatRange2V("Scale##vegetation", &vegetation.ScaleMin, &vegetation.ScaleMax, 0.01, 0.01, 10, "%.2f", "%.2f", imgui.SliderFlagsNone)
+	seed := int32(vegetation.Seed)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.InputInt("Seed##vegetation", &seed) {
+		vegetation.Seed = int64(seed)
+	}
+	if imgui.Button("Rescatter##vegetation") {
+		vegetation.Rescatter()
+	}
+
+	color := [3]float32(vegetation.Color)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.ColorEdit3("Color##vegetation", &color) {
+		vegetation.Color = color
+	}
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.SliderFloat("Alpha Cutoff##vegetation", &vegetation.AlphaCutoff, 0, 1)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloat2V("Wind Direction##vegetation", (*[2]float32)(&vegetation.WindDirection), 0.01, -1, 1, "%.2f", imgui.SliderFlagsNone)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.SliderFloat("Wind Strength##vegetation", &vegetation.WindStrength, 0, 1)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.SliderFloat("Wind Speed##vegetation", &vegetation.WindSpeed, 0, 10)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.SliderFloat("Wind Frequency##vegetation", &vegetation.WindFrequency, 0, 1)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatRange2V("Fade##vegetation", &vegetation.FadeStart, &vegetation.FadeEnd, 0.5, 0, 500, "%.1f", "%.1f", imgui.SliderFlagsNone)
+
+	imgui.Checkbox("Impostor##vegetation", &vegetation.Impostor)
+	if vegetation.Impostor {
+		imgui.Text(fmt.Sprintf("Impostors: %d", vegetation.Stats.Impostors))
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.DragFloatV("Impostor Distance##vegetation", &vegetation.ImpostorDistance, 0.5, 0, 500, "%.1f", imgui.SliderFlagsNone)
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.DragFloatV("Impostor Blend##vegetation", &vegetation.ImpostorBlend, 0.1, 0, 50, "%.1f", imgui.SliderFlagsNone)
+		frames := int32(vegetation.ImpostorFrames)
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		if imgui.SliderInt("Impostor Frames##vegetation", &frames, 1, impostor.MaxFrames) {
+			vegetation.ImpostorFrames = int(frames)
+		}
+		imgui.SetNextItemWidth(WindowModelItemWidth)
+		imgui.SliderInt("Impostor Resolution##vegetation", &vegetation.ImpostorResolution, 16, 1024)
+	}
+	imgui.Unindent()
+}
+
+// ShowCrowd 角色群的数量和动画速度, 修改数量后需要重新分布
+func (w *WindowModel) ShowCrowd(obj interface{}) {
+	crowd, ok := obj.(*model.Crowd)
+	if !ok {
+		return
+	}
+
+	imgui.Spacing()
+	imgui.Spacing()
+	imgui.Bullet()
+	imgui.Text("Crowd")
+	imgui.Indent()
+	imgui.Text(fmt.Sprintf("Instances: %d, Visible: %d, Draw Calls: %d", crowd.Stats.Instances, crowd.Stats.Visible, crowd.Stats.DrawCalls))
+	count := int32(crowd.Count)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.DragIntV("Count##crowd", &count, 10, 0, 100000, "%d", imgui.SliderFlagsNone) {
+		crowd.Count = int(count)
+	}
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatV("Size##crowd", &crowd.Size, 0.5, 1, 500, "%.1f", imgui.SliderFlagsNone)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	imgui.DragFloatRange2V("Speed##crowd", &crowd.SpeedMin, &crowd.SpeedMax, 0.01, 0, 5, "%.2f", "%.2f", imgui.SliderFlagsNone)
+	seed := int32(crowd.Seed)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.InputInt("Seed##crowd", &seed) {
+		crowd.Seed = int64(seed)
+	}
+	if imgui.Button("Respawn##crowd") {
+		crowd.Respawn()
+	}
+	imgui.Checkbox("In Place##crowd", &crowd.InPlace)
+	imgui.Unindent()
+}
+
+// ShowRenderPriority 绘制顺序和最上层
+func (w *WindowModel) ShowRenderPriority(obj interface{}) {
+	priorityObj, ok := obj.(interface{ Priority() *model.RenderPriority })
+	if !ok {
+		return
+	}
+	priority := priorityObj.Priority()
+
+	imgui.Spacing()
+	imgui.Spacing()
+	imgui.Bullet()
+	imgui.Text("Render Order")
+	imgui.Indent()
+	order := int32(priority.Order)
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.InputInt("Order##priority", &order) {
+		priority.Order = int(order)
+	}
+	imgui.Checkbox("Always On Top##priority", &priority.OnTop)
+	imgui.Unindent()
+}
+
+// ShowMaterialSlots 选择要编辑的材质槽, 返回选中的材质
+func (w *WindowModel) ShowMaterialSlots(slots reflect.Value) interface{} {
+	if w.materialSlot >= slots.Len() {
+		w.materialSlot = 0
+	}
+
+	slotName := func(i int) string {
+		name := slots.Index(i).Elem().FieldByName("Name").String()
+		return fmt.Sprintf("%d: %s", i, name)
+	}
+
+	imgui.SetNextItemWidth(WindowModelItemWidth)
+	if imgui.BeginCombo("Slot##material", slotName(w.materialSlot)) {
+		for i := 0; i < slots.Len(); i++ {
+			if imgui.SelectableV(slotName(i), i == w.materialSlot, 0, imgui.Vec2{}) {
+				w.materialSlot = i
+			}
+		}
+		imgui.EndCombo()
+	}
+
+	return slots.Index(w.materialSlot).Interface()
+}
+
 func (w *WindowModel) SetRenderObj(renderObj interface{}) {
+	if w.modelObj != renderObj {
+		w.materialSlot = 0
+	}
 	w.modelObj = renderObj
 }
 
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/paint"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowPaint 纹理绘制工具的笔刷设置, 绘制工具开启时显示
+type WindowPaint struct {
+	flags WindowFlags
+
+	tool     *paint.Tool
+	savePath string
+}
+
+func NewWindowPaint(tool *paint.Tool) *WindowPaint {
+	return &WindowPaint{
+		flags: WindowFlags{noMenu: true, noCollapse: true, noResize: true},
+		tool:  tool,
+	}
+}
+
+const (
+	WindowPaintWidth = 300
+)
+
+func (w *WindowPaint) Show(displaySize [2]float32) {
+	if w.tool == nil || !w.tool.Active {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0] - WindowPaintWidth - WindowModelWidth, Y: 40}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+
+	visible := w.tool.Active
+	defer imgui.End()
+	if !imgui.BeginV("Texture Paint", &visible, w.flags.combined()|imgui.WindowFlagsAlwaysAutoResize) {
+		return
+	}
+	if !visible {
+		w.tool.SetActive(false)
+	}
+
+	imgui.PushItemWidth(imgui.FontSize() * 12)
+	imgui.DragFloatV("Size##paint", &w.tool.BrushSize, 0.5, 1, 256, "%.1f", imgui.SliderFlagsNone)
+	imgui.SliderFloat("Opacity##paint", &w.tool.Opacity, 0, 1)
+	imgui.ColorEdit3("Color##paint", (*[3]float32)(&w.tool.Color))
+
+	target := w.tool.Target()
+	if target == nil {
+		imgui.Text("Click a model to start painting")
+		imgui.PopItemWidth()
+		return
+	}
+
+	imgui.Text("Model: " + target.Name)
+	if w.savePath == "" {
+		w.savePath = paint.DefaultSavePath(target)
+	}
+	imgui.InputText("File##paint", &w.savePath)
+	if imgui.Button("Save PNG") {
+		if err := w.tool.Save(w.savePath); err != nil {
+			logger.Error(err)
+		} else {
+			logger.Info("saved painted texture to", w.savePath)
+		}
+	}
+	imgui.PopItemWidth()
+}
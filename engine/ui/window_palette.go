@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/huangxiaobo/toy-engine/engine/shortcut"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowPalette 命令面板, 输入名称筛选命令, 上下键选择, Enter 执行, Escape 关闭
+type WindowPalette struct {
+	visible bool
+	flags   WindowFlags
+
+	commands *shortcut.Registry
+	filter   string
+	selected int
+	// 打开后的第一帧把键盘焦点放到输入框
+	focus bool
+}
+
+func NewWindowPalette() *WindowPalette {
+	return &WindowPalette{
+		flags: WindowFlags{noTitlebar: true, noResize: true, noMove: true, noMenu: true, noCollapse: true},
+	}
+}
+
+const WindowPaletteWidth = 420
+
+func (w *WindowPalette) Show(displaySize [2]float32) {
+	if !w.visible || w.commands == nil {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0] / 2, Y: displaySize[1] / 6}, imgui.ConditionAlways, imgui.Vec2{X: 0.5})
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: WindowPaletteWidth, Y: 0}, imgui.ConditionAlways)
+
+	defer imgui.End()
+	if !imgui.BeginV("Command Palette", &w.visible, w.flags.combined()|imgui.WindowFlagsAlwaysAutoResize) {
+		return
+	}
+
+	if w.focus {
+		imgui.SetKeyboardFocusHere()
+		w.focus = false
+	}
+	imgui.PushItemWidth(-1)
+	if imgui.InputTextWithHint("##palettefilter", "Type a command", &w.filter) {
+		w.selected = 0
+	}
+	imgui.PopItemWidth()
+
+	matches := w.matches()
+	if imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyDownArrow), true) && w.selected < len(matches)-1 {
+		w.selected++
+	}
+	if imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyUpArrow), true) && w.selected > 0 {
+		w.selected--
+	}
+	if imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyEscape), false) {
+		w.Close()
+		return
+	}
+
+	run := -1
+	for i, cmd := range matches {
+		if !cmd.Available() {
+			imgui.PushStyleVarFloat(imgui.StyleVarAlpha, 0.5)
+		}
+		if imgui.SelectableV(cmd.Name+"##palette", i == w.selected, 0, imgui.Vec2{}) {
+			run = i
+		}
+		if sc := cmd.Shortcut.String(); sc != "" {
+			// 快捷键右对齐
+			imgui.SameLineV(WindowPaletteWidth-imgui.CalcTextSize(sc, false, 0).X-2*imgui.CurrentStyle().WindowPadding().X, -1)
+			imgui.Text(sc)
+		}
+		if !cmd.Available() {
+			imgui.PopStyleVar()
+		}
+	}
+	if len(matches) == 0 {
+		imgui.Text("No matching command")
+	}
+	if imgui.IsKeyPressedV(imgui.KeyIndex(imgui.KeyEnter), false) && w.selected < len(matches) {
+		run = w.selected
+	}
+	if run >= 0 && matches[run].Available() {
+		w.Close()
+		matches[run].Run()
+	}
+}
+
+// matches 名称包含筛选文字的命令, 不区分大小写
+func (w *WindowPalette) matches() []*shortcut.Command {
+	filter := strings.ToLower(strings.TrimSpace(w.filter))
+	var matches []*shortcut.Command
+	for _, cmd := range w.commands.Commands() {
+		if strings.Contains(strings.ToLower(cmd.Name), filter) {
+			matches = append(matches, cmd)
+		}
+	}
+	return matches
+}
+
+// Toggle 打开命令面板并清空筛选文字, 已打开时关闭
+func (w *WindowPalette) Toggle() {
+	if w.visible {
+		w.Close()
+		return
+	}
+	w.visible, w.focus = true, true
+	w.filter, w.selected = "", 0
+}
+
+func (w *WindowPalette) Close() {
+	w.visible = false
+}
+
+func (w *WindowPalette) SetCommands(commands *shortcut.Registry) {
+	w.commands = commands
+}
+
+func (w *WindowPalette) Visible() bool {
+	return w.visible
+}
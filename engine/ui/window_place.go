@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/placement"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowPlace 放置工具的预制体选择和旋转设置, 工具开启时显示
+type WindowPlace struct {
+	flags WindowFlags
+
+	tool *placement.Tool
+}
+
+func NewWindowPlace(tool *placement.Tool) *WindowPlace {
+	return &WindowPlace{
+		flags: WindowFlags{noMenu: true, noCollapse: true, noResize: true},
+		tool:  tool,
+	}
+}
+
+const (
+	WindowPlaceWidth = 260
+)
+
+func (w *WindowPlace) Show(displaySize [2]float32) {
+	if w.tool == nil || !w.tool.Active {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0] - WindowPlaceWidth - WindowModelWidth, Y: 40}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+
+	visible := w.tool.Active
+	defer imgui.End()
+	if !imgui.BeginV("Place Object", &visible, w.flags.combined()|imgui.WindowFlagsAlwaysAutoResize) {
+		return
+	}
+	if !visible {
+		w.tool.SetActive(false)
+	}
+
+	tool := w.tool
+	if len(tool.Prefabs) == 0 {
+		imgui.Text("No prefabs")
+		return
+	}
+	for i, prefab := range tool.Prefabs {
+		if imgui.SelectableV(fmt.Sprintf("%s##prefab%d", prefab.Name, i), tool.Selected == i, 0, imgui.Vec2{}) {
+			tool.Selected = i
+		}
+	}
+
+	imgui.Separator()
+	imgui.PushItemWidth(imgui.FontSize() * 12)
+	degrees := mgl32.RadToDeg(tool.Rotation)
+	if imgui.DragFloatV("Rotation##place", &degrees, 1, -360, 360, "%.0f deg", imgui.SliderFlagsNone) {
+		tool.Rotation = mgl32.DegToRad(degrees)
+	}
+	imgui.DragFloatV("Wheel Step##place", &tool.RotateStep, 1, 1, 90, "%.0f deg", imgui.SliderFlagsNone)
+	imgui.PopItemWidth()
+	imgui.Text("Scroll to rotate, click to place, Esc to exit")
+}
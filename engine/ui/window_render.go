@@ -0,0 +1,462 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/audio"
+	"github.com/huangxiaobo/toy-engine/engine/camera"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/light"
+	"github.com/huangxiaobo/toy-engine/engine/lightmap"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/occlusion"
+	"github.com/huangxiaobo/toy-engine/engine/postprocess"
+	"github.com/huangxiaobo/toy-engine/engine/profiler"
+	"github.com/huangxiaobo/toy-engine/engine/timefx"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+type WindowRender struct {
+	noClose bool
+	visible bool
+	flags   WindowFlags
+
+	postProcess *postprocess.Chain
+	culler      *occlusion.Culler
+	reflection  *model.PlanarReflection
+	sun         *light.DirectionLight
+	cameras     *camera.Cameras
+	timeEffects *timefx.Effects
+	audio       *audio.System
+	profiler    *profiler.Profiler
+	baker       *lightmap.Baker
+	// 界面开启的冻结帧
+	freeze *timefx.Effect
+}
+
+func NewWindowRender() *WindowRender {
+	return &WindowRender{
+		visible: false,
+		flags:   WindowFlags{noMenu: true, noCollapse: true},
+	}
+}
+
+const (
+	WindowRenderWidth  = 320
+	WindowRenderHeight = 240
+)
+
+func (w *WindowRender) Show(displaySize [2]float32) {
+	if !w.visible {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0]/2 - WindowRenderWidth/2, Y: 40}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: WindowRenderWidth, Y: WindowRenderHeight}, imgui.ConditionFirstUseEver)
+
+	defer imgui.End()
+	if !imgui.BeginV("Render Settings", &w.visible, w.flags.combined()) {
+		return
+	}
+
+	imgui.PushItemWidth(imgui.FontSize() * -8)
+
+	if imgui.CollapsingHeaderV("Debug View", imgui.TreeNodeFlagsDefaultOpen) {
+		current := config.Config.DebugView
+		if imgui.BeginCombo("Mode##debugview", config.DebugViewNames[current]) {
+			for i, name := range config.DebugViewNames {
+				if imgui.SelectableV(name, int(current) == i, 0, imgui.Vec2{}) {
+					config.Config.DebugView = config.DebugView(i)
+				}
+			}
+			imgui.EndCombo()
+		}
+	}
+
+	if imgui.CollapsingHeaderV("Anti-Aliasing", imgui.TreeNodeFlagsDefaultOpen) {
+		current := config.Config.AntiAliasing
+		if imgui.BeginCombo("Mode##aa", config.AntiAliasingNames[current]) {
+			for i, name := range config.AntiAliasingNames {
+				if imgui.SelectableV(name, int(current) == i, 0, imgui.Vec2{}) {
+					config.Config.AntiAliasing = config.AntiAliasing(i)
+				}
+			}
+			imgui.EndCombo()
+		}
+		if config.Config.AntiAliasing == config.AntiAliasingMSAA {
+			imgui.SliderInt("Samples##aa", &config.Config.MSAASamples, 2, 16)
+		}
+	}
+
+	if imgui.CollapsingHeaderV("Culling", imgui.TreeNodeFlagsDefaultOpen) {
+		imgui.Checkbox("Frustum Culling", &config.Config.FrustumCulling)
+		imgui.Checkbox("Occlusion Culling", &config.Config.OcclusionCulling)
+		imgui.Checkbox("Freeze Culling", &config.Config.FreezeCulling)
+		imgui.Checkbox("Show Bounds", &config.Config.Bounds.Show)
+		if config.Config.Bounds.Show {
+			imgui.Checkbox("Spheres##bounds", &config.Config.Bounds.Spheres)
+			imgui.Checkbox("Per Mesh##bounds", &config.Config.Bounds.Meshes)
+		}
+		if w.culler != nil && config.Config.OcclusionCulling {
+			stats := w.culler.Stats()
+			imgui.Text(fmt.Sprintf("Tested: %d  Occluded: %d", stats.Tested, stats.Occluded))
+		}
+	}
+
+	if imgui.CollapsingHeaderV("SSAO", imgui.TreeNodeFlagsDefaultOpen) {
+		ssao := &config.Config.SSAO
+		imgui.Checkbox("Enable##ssao", &ssao.Enable)
+		imgui.DragFloatV("Radius##ssao", &ssao.Radius, 0.01, 0.01, 10, "%.3f", imgui.SliderFlagsNone)
+		imgui.DragFloatV("Bias##ssao", &ssao.Bias, 0.001, 0, 1, "%.3f", imgui.SliderFlagsNone)
+		imgui.SliderInt("Kernel##ssao", &ssao.KernelSize, 1, 64)
+	}
+
+	if imgui.CollapsingHeaderV("Fog", imgui.TreeNodeFlagsDefaultOpen) {
+		fog := &config.Config.Fog
+		imgui.Checkbox("Enable##fog", &fog.Enable)
+		if imgui.BeginCombo("Mode##fog", config.FogModeNames[fog.Mode]) {
+			for i, name := range config.FogModeNames {
+				if imgui.SelectableV(name, int(fog.Mode) == i, 0, imgui.Vec2{}) {
+					fog.Mode = config.FogMode(i)
+				}
+			}
+			imgui.EndCombo()
+		}
+		imgui.ColorEdit3("Color##fog", (*[3]float32)(&fog.Color))
+		if fog.Mode != config.FogLinear {
+			imgui.DragFloatV("Density##fog", &fog.Density, 0.001, 0, 1, "%.4f", imgui.SliderFlagsNone)
+		}
+		imgui.DragFloatV("Start##fog", &fog.Start, 0.5, 0, config.Config.ClipFar, "%.1f", imgui.SliderFlagsNone)
+		if fog.Mode == config.FogLinear {
+			imgui.DragFloatV("End##fog", &fog.End, 0.5, 0, config.Config.ClipFar, "%.1f", imgui.SliderFlagsNone)
+		}
+		imgui.DragFloatV("Height##fog", &fog.Height, 0.1, -100, 100, "%.1f", imgui.SliderFlagsNone)
+		imgui.DragFloatV("Height Falloff##fog", &fog.HeightFalloff, 0.005, 0, 2, "%.3f", imgui.SliderFlagsNone)
+	}
+
+	if w.cameras != nil && imgui.CollapsingHeaderV("Camera", imgui.TreeNodeFlagsDefaultOpen) {
+		c := w.cameras.Active()
+		imgui.Text(c.Name)
+		settings := &c.Settings
+		override := settings.ClearColor != nil
+		if imgui.Checkbox("Clear Color##camera", &override) {
+			if override {
+				clearColor := config.BackgroundColor()
+				settings.ClearColor = &clearColor
+			} else {
+				settings.ClearColor = nil
+			}
+		}
+		if settings.ClearColor != nil {
+			clearColor := [3]float32(*settings.ClearColor)
+			if imgui.ColorEdit3("##cameraclear", &clearColor) {
+				*settings.ClearColor = clearColor
+			}
+		}
+		imgui.Checkbox("Disable Post Processing##camera", &settings.DisablePostProcess)
+
+		imgui.Spacing()
+		imgui.Text("Controls (C to toggle)")
+		if imgui.BeginCombo("Mode##cameracontrols", camera.ModeNames[c.Mode]) {
+			for i, name := range camera.ModeNames {
+				if imgui.SelectableV(name, int(c.Mode) == i, 0, imgui.Vec2{}) && int(c.Mode) != i {
+					c.ToggleMode()
+				}
+			}
+			imgui.EndCombo()
+		}
+		fov := c.Fov()
+		if imgui.SliderFloat("FOV##camera", &fov, 10, 120) {
+			c.SetFov(fov)
+		}
+		clip := [2]float32{c.Near(), c.Far()}
+		if imgui.DragFloat2V("Near/Far##camera", &clip, 0.05, 0.001, 100000, "%.2f", imgui.SliderFlagsLogarithmic) {
+			c.SetClip(clip[0], clip[1])
+		}
+		orthographic := c.Orthographic
+		if imgui.Checkbox("Orthographic##camera", &orthographic) {
+			c.SetOrthographic(orthographic)
+		}
+		if c.Orthographic {
+			imgui.DragFloatV("Ortho Size##camera", &c.OrthoSize, 0.05, 0.01, 1000, "%.2f", imgui.SliderFlagsNone)
+		}
+		if imgui.Button("Zoom Extents##camera") {
+			c.RequestFrame(camera.FrameAll)
+		}
+		imgui.SameLine()
+		if imgui.Button("Zoom Selection##camera") {
+			c.RequestFrame(camera.FrameSelection)
+		}
+		if c.Mode == camera.ModeFly {
+			imgui.DragFloatV("Speed##fly", &c.Fly.Speed, 0.1, 0.1, 1000, "%.1f", imgui.SliderFlagsNone)
+			imgui.DragFloatV("Acceleration##fly", &c.Fly.Acceleration, 0.5, 0, 1000, "%.1f", imgui.SliderFlagsNone)
+			imgui.DragFloatV("Look Speed##fly", &c.Fly.LookSpeed, 0.01, 0.01, 2, "%.2f", imgui.SliderFlagsNone)
+		} else {
+			imgui.DragFloatV("Rotate Speed##orbit", &c.Orbit.RotateSpeed, 0.01, 0.01, 2, "%.2f", imgui.SliderFlagsNone)
+			imgui.DragFloatV("Zoom Speed##orbit", &c.Orbit.ZoomSpeed, 0.01, 0.01, 0.5, "%.2f", imgui.SliderFlagsNone)
+			imgui.DragFloatV("Pan Speed##orbit", &c.Orbit.PanSpeed, 0.0001, 0.0001, 0.01, "%.4f", imgui.SliderFlagsNone)
+			imgui.DragFloatV("Damping##orbit", &c.Orbit.Damping, 0.1, 0, 50, "%.1f", imgui.SliderFlagsNone)
+		}
+		imgui.DragFloatV("Fast (Shift)##camera", &c.Modifier.Fast, 0.05, 1, 20, "%.2fx", imgui.SliderFlagsNone)
+		imgui.DragFloatV("Slow (Ctrl)##camera", &c.Modifier.Slow, 0.01, 0.01, 1, "%.2fx", imgui.SliderFlagsNone)
+		imgui.DragFloatV("Transition Time##camera", &c.TransitionTime, 0.01, 0, 5, "%.2fs", imgui.SliderFlagsNone)
+
+		if follow := c.Follow; follow != nil {
+			imgui.Spacing()
+			imgui.Text("Follow")
+			imgui.InputText("Target##follow", &follow.Target)
+			imgui.DragFloat3V("Offset##follow", (*[3]float32)(&follow.Offset), 0.05, -1000, 1000, "%.2f", imgui.SliderFlagsNone)
+			imgui.Checkbox("Local Offset##follow", &follow.Local)
+			imgui.DragFloatV("Damping##follow", &follow.Damping, 0.1, 0, 50, "%.1f", imgui.SliderFlagsNone)
+			imgui.Checkbox("Look At##follow", &follow.LookAt)
+			if follow.LookAt {
+				imgui.DragFloat3V("Look Offset##follow", (*[3]float32)(&follow.LookOffset), 0.05, -1000, 1000, "%.2f", imgui.SliderFlagsNone)
+				imgui.DragFloatV("Look Damping##follow", &follow.LookDamping, 0.1, 0, 50, "%.1f", imgui.SliderFlagsNone)
+			}
+		}
+
+		if shake := c.Shake; shake != nil {
+			imgui.Spacing()
+			imgui.Text("Shake")
+			imgui.SliderFloat("Trauma##shake", &shake.Trauma, 0, 1)
+			imgui.DragFloatV("Decay##shake", &shake.Decay, 0.05, 0, 10, "%.2f", imgui.SliderFlagsNone)
+			imgui.DragFloatV("Frequency##shake", &shake.Frequency, 0.5, 0, 60, "%.1f", imgui.SliderFlagsNone)
+			imgui.DragFloat3V("Max Offset##shake", (*[3]float32)(&shake.MaxOffset), 0.01, 0, 5, "%.2f", imgui.SliderFlagsNone)
+			imgui.DragFloat3V("Max Angle##shake", (*[3]float32)(&shake.MaxAngle), 0.1, 0, 45, "%.1f", imgui.SliderFlagsNone)
+			if imgui.Button("Test##shake") {
+				shake.AddTrauma(0.5)
+			}
+			imgui.SameLine()
+			if imgui.Button("Test Timed##shake") {
+				shake.Start(0.5, 0, 1)
+			}
+		}
+	}
+
+	if w.postProcess != nil && imgui.CollapsingHeaderV("Post Processing", imgui.TreeNodeFlagsDefaultOpen) {
+		for _, entry := range w.postProcess.Entries() {
+			if entry.Effect.Name() == postprocess.FXAAName {
+				continue
+			}
+			imgui.Checkbox(entry.Effect.Name()+"##post", &entry.Enabled)
+			if blur, ok := entry.Effect.(*postprocess.MotionBlur); ok && entry.Enabled {
+				imgui.Indent()
+				imgui.DragFloatV("Intensity##motionblur", &blur.Intensity, 0.01, 0, 4, "%.2f", imgui.SliderFlagsNone)
+				imgui.SliderInt("Samples##motionblur", &blur.Samples, 2, 32)
+				imgui.DragFloatV("Max Length##motionblur", &blur.MaxLength, 0.001, 0.001, 0.2, "%.3f", imgui.SliderFlagsNone)
+				imgui.Unindent()
+			}
+			if bloom, ok := entry.Effect.(*postprocess.Bloom); ok && entry.Enabled {
+				imgui.Indent()
+				imgui.DragFloatV("Threshold##bloom", &bloom.Threshold, 0.01, 0, 10, "%.2f", imgui.SliderFlagsNone)
+				imgui.DragFloatV("Knee##bloom", &bloom.Knee, 0.01, 0, 2, "%.2f", imgui.SliderFlagsNone)
+				imgui.DragFloatV("Intensity##bloom", &bloom.Intensity, 0.01, 0, 4, "%.2f", imgui.SliderFlagsNone)
+				imgui.SliderInt("Iterations##bloom", &bloom.Iterations, 1, 10)
+				imgui.Unindent()
+			}
+			if dof, ok := entry.Effect.(*postprocess.DepthOfField); ok && entry.Enabled {
+				imgui.Indent()
+				imgui.Checkbox("Auto Focus##dof", &dof.AutoFocus)
+				if !dof.AutoFocus {
+					imgui.DragFloatV("Focus##dof", &dof.FocusDistance, 0.5, config.Config.ClipNear, config.Config.ClipFar, "%.1f", imgui.SliderFlagsNone)
+				}
+				imgui.DragFloatV("Aperture##dof", &dof.Aperture, 0.05, 0, 20, "%.2f", imgui.SliderFlagsNone)
+				imgui.DragFloatV("Max Blur##dof", &dof.MaxBlur, 0.1, 1, 32, "%.1f", imgui.SliderFlagsNone)
+				imgui.Unindent()
+			}
+		}
+	}
+
+	if w.reflection != nil && imgui.CollapsingHeaderV("Ground Reflection", imgui.TreeNodeFlagsDefaultOpen) {
+		reflection := w.reflection
+		imgui.Checkbox("Enable##reflection", &reflection.Enable)
+		if reflection.Enable {
+			imgui.SliderFloat("Strength##reflection", &reflection.Strength, 0, 1)
+			imgui.DragFloatV("Blur##reflection", &reflection.Blur, 0.05, 0, 8, "%.2f", imgui.SliderFlagsNone)
+			imgui.SliderFloat("Fresnel##reflection", &reflection.Fresnel, 0, 1)
+			imgui.SliderFloat("Resolution##reflection", &reflection.Resolution, 0.1, 1)
+		}
+	}
+
+	if w.sun != nil && imgui.CollapsingHeaderV("Sun", imgui.TreeNodeFlagsDefaultOpen) {
+		sun := w.sun
+		if sun.Estimated() {
+			override := sun.Override
+			if imgui.Checkbox("Override Environment##sun", &override) {
+				sun.SetOverride(override)
+			}
+		}
+		editable := sun.Override || !sun.Estimated()
+		if editable {
+			direction := [3]float32(sun.Direction)
+			if imgui.DragFloat3V("Direction##sun", &direction, 0.01, -1, 1, "%.2f", imgui.SliderFlagsNone) {
+				if d := mgl32.Vec3(direction); d.Len() > 0 {
+					sun.Direction = d.Normalize()
+				}
+			}
+			color := [3]float32(sun.Color)
+			if imgui.ColorEdit3("Color##sun", &color) {
+				sun.Color = color
+			}
+			imgui.DragFloatV("Intensity##sun", &sun.Intensity, 0.05, 0, 100, "%.2f", imgui.SliderFlagsNone)
+		} else {
+			imgui.Text(fmt.Sprintf("Direction: %.2f, %.2f, %.2f", sun.Direction.X(), sun.Direction.Y(), sun.Direction.Z()))
+			imgui.Text(fmt.Sprintf("Color: %.2f, %.2f, %.2f", sun.Color.X(), sun.Color.Y(), sun.Color.Z()))
+			imgui.Text(fmt.Sprintf("Intensity: %.2f", sun.Intensity))
+		}
+	}
+
+	if baker := w.baker; baker != nil && imgui.CollapsingHeaderV("Lightmaps", imgui.TreeNodeFlagsDefaultOpen) {
+		// 天空光为 0 时使用点光源的环境光
+		sky := [3]float32(baker.Sky)
+		if imgui.ColorEdit3("Sky##lightmap", &sky) {
+			baker.Sky = sky
+		}
+		imgui.Checkbox("Bounce##lightmap", &baker.Bounce)
+		if imgui.Button("Bake##lightmap") {
+			baker.Request()
+		}
+		if baker.Status != "" {
+			imgui.SameLine()
+			imgui.Text(baker.Status)
+		}
+	}
+
+	if effects := w.timeEffects; effects != nil && imgui.CollapsingHeaderV("Time", imgui.TreeNodeFlagsDefaultOpen) {
+		imgui.SliderFloat("Time Scale##time", &effects.BaseScale, 0, 2)
+		imgui.Text(fmt.Sprintf("Current: %.2f", effects.Scale))
+		if imgui.Button("Hit Stop##time") {
+			effects.HitStop(0.1)
+		}
+		imgui.SameLine()
+		if imgui.Button("Slow Motion##time") {
+			effects.SlowMotion(0.2, 2, 0.2, 0.5)
+		}
+		imgui.SameLine()
+		frozen := w.freeze != nil
+		if imgui.Checkbox("Freeze##time", &frozen) {
+			if frozen {
+				w.freeze = effects.Freeze()
+			} else {
+				effects.Cancel(w.freeze)
+				w.freeze = nil
+			}
+		}
+		if imgui.Button("Clear##time") {
+			effects.Clear()
+			w.freeze = nil
+		}
+	}
+
+	if system := w.audio; system != nil && imgui.CollapsingHeaderV("Audio", imgui.TreeNodeFlagsDefaultOpen) {
+		volume := system.Volume()
+		if imgui.SliderFloat("Volume##audio", &volume, 0, 1) {
+			system.SetVolume(volume)
+		}
+		playing, culled := system.Voices()
+		imgui.Text(fmt.Sprintf("Playing: %d  Culled: %d", playing, culled))
+		for _, name := range system.Bank.Events() {
+			if imgui.Button(name + "##audio") {
+				system.Play2D(name)
+			}
+		}
+		if imgui.Button("Stop All##audio") {
+			system.StopAll()
+		}
+	}
+
+	if p := w.profiler; p != nil && imgui.CollapsingHeaderV("Profiler", imgui.TreeNodeFlagsDefaultOpen) {
+		imgui.Checkbox("Enable##profiler", &p.Enabled)
+		frames := int32(p.AlertFrames)
+		if imgui.SliderInt("Alert Frames##profiler", &frames, 1, profiler.MaxAlertFrames) {
+			p.AlertFrames = int(frames)
+		}
+		// 每行一个范围, 按层级缩进, 报警的范围显示为红色, 预算 0 表示不限制
+		for _, scope := range p.Scopes() {
+			if scope.Alert {
+				imgui.PushStyleColor(imgui.StyleColorText, alertColor)
+			}
+			imgui.Text(fmt.Sprintf("%*s%-10s cpu %6.2f  gpu %6.2f", scope.Depth*2, "", scope.Name, scope.CPU, scope.GPU))
+			if scope.Alert {
+				imgui.PopStyleColor()
+			}
+			imgui.SameLine()
+			budget := scope.Budget
+			imgui.SetNextItemWidth(imgui.FontSize() * 4)
+			if imgui.DragFloatV("##budget"+scope.Path(), &budget, 0.05, 0, 100, "%.2f", imgui.SliderFlagsNone) {
+				p.SetBudget(scope.Name, budget)
+			}
+		}
+	}
+
+	if imgui.CollapsingHeaderV("Interface", imgui.TreeNodeFlagsDefaultOpen) {
+		// 拖动会使界面在鼠标下跳动, 使用固定档位
+		label := "Auto"
+		if config.Config.UIScale > 0 {
+			label = fmt.Sprintf("%.0f%%", config.Config.UIScale*100)
+		}
+		if imgui.BeginCombo("UI Scale", label) {
+			if imgui.SelectableV("Auto", config.Config.UIScale <= 0, 0, imgui.Vec2{}) {
+				config.Config.UIScale = 0
+			}
+			for _, scale := range uiScales {
+				if imgui.SelectableV(fmt.Sprintf("%.0f%%", scale*100), config.Config.UIScale == scale, 0, imgui.Vec2{}) {
+					config.Config.UIScale = scale
+				}
+			}
+			imgui.EndCombo()
+		}
+		imgui.Checkbox("Crosshair", &config.Config.Crosshair)
+		imgui.Checkbox("Selection Outline", &config.Config.Outline.Enable)
+		imgui.ColorEdit4("Outline Color", (*[4]float32)(&config.Config.Outline.Color))
+		imgui.SliderFloat("Outline Width", &config.Config.Outline.Width, 1, 10)
+	}
+
+	imgui.PopItemWidth()
+}
+
+var uiScales = []float32{1, 1.25, 1.5, 1.75, 2, 2.5, 3}
+
+func (w *WindowRender) SetPostProcess(chain *postprocess.Chain) {
+	w.postProcess = chain
+}
+
+func (w *WindowRender) SetReflection(reflection *model.PlanarReflection) {
+	w.reflection = reflection
+}
+
+func (w *WindowRender) SetCameras(cameras *camera.Cameras) {
+	w.cameras = cameras
+}
+
+func (w *WindowRender) SetAudio(system *audio.System) {
+	w.audio = system
+}
+
+func (w *WindowRender) SetProfiler(p *profiler.Profiler) {
+	w.profiler = p
+}
+
+func (w *WindowRender) SetTimeEffects(effects *timefx.Effects) {
+	w.timeEffects = effects
+}
+
+func (w *WindowRender) SetSunLight(sun *light.DirectionLight) {
+	w.sun = sun
+}
+
+func (w *WindowRender) SetLightmapBaker(baker *lightmap.Baker) {
+	w.baker = baker
+}
+
+func (w *WindowRender) SetOcclusionCuller(culler *occlusion.Culler) {
+	w.culler = culler
+}
+
+func (w *WindowRender) SetVisible(visible bool) {
+	w.visible = visible
+}
+
+func (w *WindowRender) Visible() bool {
+	return w.visible
+}
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"github.com/huangxiaobo/toy-engine/engine/terrain"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowSculpt 地形雕刻工具的笔刷设置, 雕刻工具开启时显示
+type WindowSculpt struct {
+	flags WindowFlags
+
+	tool *terrain.SculptTool
+}
+
+func NewWindowSculpt(tool *terrain.SculptTool) *WindowSculpt {
+	return &WindowSculpt{
+		flags: WindowFlags{noMenu: true, noCollapse: true, noResize: true},
+		tool:  tool,
+	}
+}
+
+const (
+	WindowSculptWidth = 300
+)
+
+func (w *WindowSculpt) Show(displaySize [2]float32) {
+	if w.tool == nil || !w.tool.Active {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0] - WindowSculptWidth - WindowModelWidth, Y: 40}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+
+	visible := w.tool.Active
+	defer imgui.End()
+	if !imgui.BeginV("Terrain Sculpt", &visible, w.flags.combined()|imgui.WindowFlagsAlwaysAutoResize) {
+		return
+	}
+	if !visible {
+		w.tool.SetActive(false)
+	}
+
+	brush := &w.tool.Brush
+	for i, name := range terrain.BrushModeNames {
+		if i > 0 {
+			imgui.SameLine()
+		}
+		mode := terrain.BrushMode(i)
+		if imgui.RadioButton(name+"##sculpt", brush.Mode == mode) {
+			brush.Mode = mode
+		}
+	}
+
+	imgui.PushItemWidth(imgui.FontSize() * 12)
+	imgui.DragFloatV("Radius##sculpt", &brush.Radius, 0.1, 0.5, 64, "%.1f", imgui.SliderFlagsNone)
+	imgui.DragFloatV("Strength##sculpt", &brush.Strength, 0.1, 0.1, 64, "%.1f", imgui.SliderFlagsNone)
+	imgui.PopItemWidth()
+
+	if target := w.tool.Target(); target != nil {
+		imgui.Text("Terrain: " + target.Name)
+	} else {
+		imgui.Text("Drag on a terrain to sculpt")
+	}
+}
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// ShaderObj 可以在着色器编辑器中编辑着色器的对象
+type ShaderObj interface {
+	Shader() *shader.Shader
+}
+
+// WindowShader 编辑选中模型的顶点和片段着色器源码, Compile 后立即替换程序
+// 着色器按源文件和宏共享, 使用同一个着色器的模型都会改变
+type WindowShader struct {
+	visible bool
+	flags   WindowFlags
+
+	shader *shader.Shader
+	// 编辑中的源码
+	vert string
+	frag string
+	// 读取或保存的结果
+	status string
+}
+
+func NewWindowShader() *WindowShader {
+	return &WindowShader{
+		flags: WindowFlags{noMenu: true, noCollapse: true},
+	}
+}
+
+const (
+	WindowShaderWidth  = 560
+	WindowShaderHeight = 520
+)
+
+// SetObj 编辑 obj 的着色器, 着色器改变时重新读取源码, 未保存的修改被丢弃
+func (w *WindowShader) SetObj(obj interface{}) {
+	var s *shader.Shader
+	if shaderObj, ok := obj.(ShaderObj); ok {
+		s = shaderObj.Shader()
+	}
+	if s == w.shader {
+		return
+	}
+	w.shader = s
+	w.revert()
+}
+
+// revert 重新读取源文件
+func (w *WindowShader) revert() {
+	w.vert, w.frag, w.status = "", "", ""
+	if w.shader == nil {
+		return
+	}
+	vert, frag, err := w.shader.Sources()
+	if err != nil {
+		w.status = err.Error()
+		return
+	}
+	w.vert, w.frag = vert, frag
+}
+
+func (w *WindowShader) Show(displaySize [2]float32) {
+	if !w.visible {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0]/2 - WindowShaderWidth/2, Y: displaySize[1]/2 - WindowShaderHeight/2}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: WindowShaderWidth, Y: WindowShaderHeight}, imgui.ConditionFirstUseEver)
+
+	defer imgui.End()
+	if !imgui.BeginV("Shader Editor", &w.visible, w.flags.combined()) {
+		return
+	}
+	if w.shader == nil {
+		imgui.Text("Select a model to edit its shader")
+		return
+	}
+
+	if imgui.Button("Compile##shader") {
+		w.compile()
+	}
+	imgui.SameLine()
+	if imgui.Button("Save##shader") {
+		if w.compile() {
+			if err := w.shader.Save(w.vert, w.frag); err != nil {
+				w.status = err.Error()
+			} else {
+				w.status = "saved"
+			}
+		}
+	}
+	imgui.SameLine()
+	if imgui.Button("Revert##shader") {
+		w.revert()
+	}
+	imgui.SameLine()
+	imgui.Text(fmt.Sprintf("version %d", w.shader.Version))
+
+	var errLines []string
+	if w.shader.Err != nil {
+		errLines = strings.Split(w.shader.Err.Error(), "\n")
+	}
+	imgui.PushStyleColor(imgui.StyleColorText, alertColor)
+	for _, line := range errLines {
+		imgui.Text(line)
+	}
+	imgui.PopStyleColor()
+	if w.status != "" {
+		imgui.Text(w.status)
+	}
+
+	if imgui.BeginTabBar("ShaderSources") {
+		w.showSource("Vertex", w.shader.VertFilePath, &w.vert)
+		w.showSource("Fragment", w.shader.FragFilePath, &w.frag)
+		imgui.EndTabBar()
+	}
+}
+
+func (w *WindowShader) showSource(label, file string, source *string) {
+	if !imgui.BeginTabItem(label) {
+		return
+	}
+	imgui.Text(file)
+	imgui.InputTextMultilineV("##"+label, source, imgui.ContentRegionAvail(), imgui.InputTextFlagsAllowTabInput, nil)
+	imgui.EndTabItem()
+}
+
+// compile 用编辑中的源码重新编译, 失败时错误显示在源码上方, 模型继续使用原来的程序
+func (w *WindowShader) compile() bool {
+	w.status = ""
+	return w.shader.CompileSource(w.vert, w.frag) == nil
+}
+
+func (w *WindowShader) SetVisible(visible bool) {
+	w.visible = visible
+}
+
+func (w *WindowShader) Visible() bool {
+	return w.visible
+}
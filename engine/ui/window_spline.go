@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/huangxiaobo/toy-engine/engine/spline"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowSpline 样条挤出工具的截面和参数设置, 工具开启时显示
+type WindowSpline struct {
+	flags WindowFlags
+
+	tool *spline.Tool
+}
+
+func NewWindowSpline(tool *spline.Tool) *WindowSpline {
+	return &WindowSpline{
+		flags: WindowFlags{noMenu: true, noCollapse: true, noResize: true},
+		tool:  tool,
+	}
+}
+
+const (
+	WindowSplineWidth = 300
+)
+
+func (w *WindowSpline) Show(displaySize [2]float32) {
+	if w.tool == nil || !w.tool.Active {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0] - WindowSplineWidth - WindowModelWidth, Y: 40}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+
+	visible := w.tool.Active
+	defer imgui.End()
+	if !imgui.BeginV("Spline Extrude", &visible, w.flags.combined()|imgui.WindowFlagsAlwaysAutoResize) {
+		return
+	}
+	if !visible {
+		w.tool.SetActive(false)
+	}
+
+	tool := w.tool
+	for i, name := range spline.ProfileTypeNames {
+		if i > 0 {
+			imgui.SameLine()
+		}
+		profile := spline.ProfileType(i)
+		if imgui.RadioButton(name+"##spline", tool.Profile == profile) {
+			tool.Profile = profile
+		}
+	}
+
+	imgui.PushItemWidth(imgui.FontSize() * 12)
+	switch tool.Profile {
+	case spline.ProfileRoad:
+		imgui.DragFloatV("Width##spline", &tool.Width, 0.1, 0.1, 64, "%.2f", imgui.SliderFlagsNone)
+	case spline.ProfilePipe:
+		imgui.DragFloatV("Radius##spline", &tool.Radius, 0.05, 0.05, 16, "%.2f", imgui.SliderFlagsNone)
+		imgui.SliderInt("Sides##spline", &tool.Sides, 3, 32)
+	case spline.ProfileFence:
+		imgui.DragFloatV("Height##spline", &tool.Height, 0.05, 0.1, 16, "%.2f", imgui.SliderFlagsNone)
+		imgui.DragFloatV("Thickness##spline", &tool.Thickness, 0.01, 0.01, 2, "%.2f", imgui.SliderFlagsNone)
+	}
+	imgui.DragFloatV("UV Tiling##spline", &tool.Tiling, 0.1, 0.1, 64, "%.1f", imgui.SliderFlagsNone)
+	imgui.DragFloatV("Spacing##spline", &tool.Spacing, 0.05, 0.1, 8, "%.2f", imgui.SliderFlagsNone)
+	imgui.Checkbox("Closed##spline", &tool.Closed)
+	imgui.Checkbox("Conform to terrain##spline", &tool.ConformToTerrain)
+	imgui.PopItemWidth()
+
+	imgui.Text(fmt.Sprintf("Points: %d", tool.NumPoints()))
+	if imgui.Button("Build") {
+		tool.RequestBuild()
+	}
+	imgui.SameLine()
+	if imgui.Button("Undo") {
+		tool.RemoveLastPoint()
+	}
+	imgui.SameLine()
+	if imgui.Button("Clear") {
+		tool.Clear()
+	}
+}
@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/huangxiaobo/toy-engine/engine/camera"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowTimeline 当前相机飞行路径的时间轴, 播放, 拖动时间和编辑关键帧
+type WindowTimeline struct {
+	visible bool
+	flags   WindowFlags
+
+	cameras *camera.Cameras
+	// 选中的关键帧下标, -1 表示没有选中
+	selected int
+}
+
+func NewWindowTimeline() *WindowTimeline {
+	return &WindowTimeline{
+		flags:    WindowFlags{noMenu: true, noCollapse: true},
+		selected: -1,
+	}
+}
+
+const (
+	WindowTimelineWidth  = 420
+	WindowTimelineHeight = 260
+	// 在末尾追加关键帧时与上一个关键帧的间隔, 秒
+	timelineAppendStep = 2
+)
+
+func (w *WindowTimeline) Show(displaySize [2]float32) {
+	if !w.visible || w.cameras == nil {
+		return
+	}
+	imgui.SetNextWindowPosV(imgui.Vec2{X: displaySize[0]/2 - WindowTimelineWidth/2, Y: displaySize[1] - WindowTimelineHeight - WindowToolbarHeight - 10}, imgui.ConditionFirstUseEver, imgui.Vec2{})
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: WindowTimelineWidth, Y: WindowTimelineHeight}, imgui.ConditionFirstUseEver)
+
+	defer imgui.End()
+	if !imgui.BeginV("Camera Timeline", &w.visible, w.flags.combined()) {
+		return
+	}
+
+	c := w.cameras.Active()
+	path := c.Path
+	if w.selected >= len(path.Keyframes) {
+		w.selected = -1
+	}
+	imgui.Text(fmt.Sprintf("%s: %d keys, %.2fs", c.Name, len(path.Keyframes), path.Duration()))
+
+	if path.Playing {
+		if imgui.Button("Pause##timeline") {
+			path.Pause()
+		}
+	} else if imgui.Button("Play##timeline") {
+		path.Play()
+	}
+	imgui.SameLine()
+	if imgui.Button("Stop##timeline") {
+		path.Stop()
+	}
+	imgui.SameLine()
+	imgui.Checkbox("Loop##timeline", &path.Loop)
+	imgui.SameLine()
+	imgui.Checkbox("Show Path##timeline", &path.Show)
+
+	imgui.PushItemWidth(-1)
+	t := path.Time
+	if imgui.SliderFloatV("##timelinetime", &t, 0, path.Duration(), "%.2fs", imgui.SliderFlagsNone) {
+		path.Pause()
+		path.Seek(t)
+	}
+	imgui.PopItemWidth()
+	imgui.DragFloatV("Speed##timeline", &path.Speed, 0.01, 0.01, 10, "%.2fx", imgui.SliderFlagsNone)
+
+	if imgui.Button("Add Key##timeline") {
+		w.selected = path.AddKeyframe(path.Time, c)
+	}
+	imgui.SameLine()
+	if imgui.Button("Append Key##timeline") {
+		time := float32(0)
+		if len(path.Keyframes) > 0 {
+			time = path.Duration() + timelineAppendStep
+		}
+		w.selected = path.AddKeyframe(time, c)
+		path.Time = time
+	}
+
+	if imgui.BeginChildV("keys##timeline", imgui.Vec2{X: 0, Y: -imgui.FrameHeightWithSpacing() * 3}, true, 0) {
+		for i, key := range path.Keyframes {
+			label := fmt.Sprintf("%d: %.2fs %s##key%d", i, key.Time, camera.EasingNames[key.Easing], i)
+			if imgui.SelectableV(label, i == w.selected, imgui.SelectableFlagsAllowDoubleClick, imgui.Vec2{}) {
+				w.selected = i
+				if imgui.IsMouseDoubleClicked(0) {
+					path.Pause()
+					path.Seek(key.Time)
+				}
+			}
+		}
+	}
+	imgui.EndChild()
+
+	if w.selected < 0 {
+		return
+	}
+	key := &path.Keyframes[w.selected]
+	time := key.Time
+	if imgui.DragFloatV("Time##timelinekey", &time, 0.05, 0, 3600, "%.2fs", imgui.SliderFlagsNone) {
+		w.selected = path.SetKeyframeTime(w.selected, time)
+		key = &path.Keyframes[w.selected]
+	}
+	if imgui.BeginCombo("Easing##timelinekey", camera.EasingNames[key.Easing]) {
+		for i, name := range camera.EasingNames {
+			if imgui.SelectableV(name, int(key.Easing) == i, 0, imgui.Vec2{}) {
+				key.Easing = camera.Easing(i)
+			}
+		}
+		imgui.EndCombo()
+	}
+	if imgui.Button("Set From Camera##timelinekey") {
+		key.Position, key.Target = c.Position, c.Target
+	}
+	imgui.SameLine()
+	if imgui.Button("Delete##timelinekey") {
+		path.RemoveKeyframe(w.selected)
+		w.selected = -1
+	}
+}
+
+func (w *WindowTimeline) SetCameras(cameras *camera.Cameras) {
+	w.cameras = cameras
+}
+
+func (w *WindowTimeline) SetVisible(visible bool) {
+	w.visible = visible
+}
+
+func (w *WindowTimeline) Visible() bool {
+	return w.visible
+}
@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/gizmo"
+	"github.com/huangxiaobo/toy-engine/engine/measure"
+	"github.com/huangxiaobo/toy-engine/engine/paint"
+	"github.com/huangxiaobo/toy-engine/engine/placement"
+	"github.com/huangxiaobo/toy-engine/engine/spline"
+	"github.com/huangxiaobo/toy-engine/engine/terrain"
+	"github.com/inkyblackness/imgui-go/v4"
+)
+
+// WindowToolbar 视口工具栏, 切换视口着色模式
+type WindowToolbar struct {
+	visible bool
+	flags   WindowFlags
+
+	// 切换线框时回到的上一次的模式
+	lastShadingMode config.ShadingMode
+
+	measureTool *measure.Tool
+	paintTool   *paint.Tool
+	sculptTool  *terrain.SculptTool
+	splineTool  *spline.Tool
+	placeTool   *placement.Tool
+	gizmoTool   *gizmo.Tool
+	lightGizmos *gizmo.Lights
+}
+
+func NewWindowToolbar() *WindowToolbar {
+	return &WindowToolbar{
+		visible:         true,
+		flags:           WindowFlags{noTitlebar: true, noResize: true, noMove: true, noMenu: true, noCollapse: true, noScrollbar: true},
+		lastShadingMode: config.ShadingRendered,
+	}
+}
+
+const (
+	WindowToolbarX      = 210
+	WindowToolbarHeight = 36
+)
+
+func (w *WindowToolbar) Show(displaySize [2]float32) {
+	if !w.visible {
+		return
+	}
+
+	imgui.SetNextWindowPosV(imgui.Vec2{X: WindowToolbarX, Y: displaySize[1] - WindowToolbarHeight}, imgui.ConditionNone, imgui.Vec2{})
+	imgui.SetNextWindowSizeV(imgui.Vec2{X: 0, Y: WindowToolbarHeight}, imgui.ConditionNone)
+
+	defer imgui.End()
+	if !imgui.BeginV("Toolbar", &w.visible, w.flags.combined()|imgui.WindowFlagsAlwaysAutoResize) {
+		return
+	}
+
+	for i, name := range config.ShadingModeNames {
+		if i > 0 {
+			imgui.SameLine()
+		}
+		mode := config.ShadingMode(i)
+		if imgui.RadioButton(name, config.Config.ShadingMode == mode) {
+			config.Config.ShadingMode = mode
+		}
+	}
+
+	if w.measureTool != nil {
+		imgui.SameLine()
+		imgui.Separator()
+		imgui.SameLine()
+		active := w.measureTool.Active
+		if imgui.Checkbox("Measure", &active) {
+			w.setMeasureActive(active)
+		}
+	}
+
+	if w.paintTool != nil {
+		imgui.SameLine()
+		active := w.paintTool.Active
+		if imgui.Checkbox("Paint", &active) {
+			w.setPaintActive(active)
+		}
+	}
+
+	if w.sculptTool != nil {
+		imgui.SameLine()
+		active := w.sculptTool.Active
+		if imgui.Checkbox("Sculpt", &active) {
+			w.setSculptActive(active)
+		}
+	}
+
+	if w.splineTool != nil {
+		imgui.SameLine()
+		active := w.splineTool.Active
+		if imgui.Checkbox("Spline", &active) {
+			w.setSplineActive(active)
+		}
+	}
+
+	if w.placeTool != nil {
+		imgui.SameLine()
+		active := w.placeTool.Active
+		if imgui.Checkbox("Place", &active) {
+			w.setPlaceActive(active)
+		}
+	}
+
+	if w.gizmoTool != nil {
+		imgui.SameLine()
+		active := w.gizmoTool.Active
+		if imgui.Checkbox("Gizmo", &active) {
+			w.setGizmoActive(active)
+		}
+		if w.gizmoTool.Active {
+			for i, name := range gizmo.ModeNames {
+				imgui.SameLine()
+				mode := gizmo.Mode(i)
+				if imgui.RadioButton(name+"##gizmo", w.gizmoTool.Mode == mode) {
+					w.gizmoTool.Mode = mode
+				}
+			}
+		}
+	}
+
+	// 灯光图标只是显示开关, 点击图标选择灯光, 可与其他工具同时开启
+	if w.lightGizmos != nil {
+		imgui.SameLine()
+		imgui.Checkbox("Lights", &w.lightGizmos.Show)
+	}
+}
+
+// 测量、绘制、雕刻、样条、放置和变换手柄都使用鼠标左键, 同时只开启一个
+func (w *WindowToolbar) setMeasureActive(active bool) {
+	if active {
+		w.deactivateTools()
+	}
+	w.measureTool.SetActive(active)
+}
+
+func (w *WindowToolbar) setPaintActive(active bool) {
+	if active {
+		w.deactivateTools()
+	}
+	w.paintTool.SetActive(active)
+}
+
+func (w *WindowToolbar) setSculptActive(active bool) {
+	if active {
+		w.deactivateTools()
+	}
+	w.sculptTool.SetActive(active)
+}
+
+func (w *WindowToolbar) setSplineActive(active bool) {
+	if active {
+		w.deactivateTools()
+	}
+	w.splineTool.SetActive(active)
+}
+
+func (w *WindowToolbar) setPlaceActive(active bool) {
+	if active {
+		w.deactivateTools()
+	}
+	w.placeTool.SetActive(active)
+}
+
+func (w *WindowToolbar) setGizmoActive(active bool) {
+	if active {
+		w.deactivateTools()
+	}
+	w.gizmoTool.SetActive(active)
+}
+
+func (w *WindowToolbar) deactivateTools() {
+	if w.measureTool != nil && w.measureTool.Active {
+		w.measureTool.SetActive(false)
+	}
+	if w.paintTool != nil && w.paintTool.Active {
+		w.paintTool.SetActive(false)
+	}
+	if w.sculptTool != nil && w.sculptTool.Active {
+		w.sculptTool.SetActive(false)
+	}
+	if w.splineTool != nil && w.splineTool.Active {
+		w.splineTool.SetActive(false)
+	}
+	if w.placeTool != nil && w.placeTool.Active {
+		w.placeTool.SetActive(false)
+	}
+	if w.gizmoTool != nil && w.gizmoTool.Active {
+		w.gizmoTool.SetActive(false)
+	}
+}
+
+func (w *WindowToolbar) SetPaintTool(tool *paint.Tool) {
+	w.paintTool = tool
+}
+
+func (w *WindowToolbar) SetSculptTool(tool *terrain.SculptTool) {
+	w.sculptTool = tool
+}
+
+func (w *WindowToolbar) SetSplineTool(tool *spline.Tool) {
+	w.splineTool = tool
+}
+
+func (w *WindowToolbar) SetPlacementTool(tool *placement.Tool) {
+	w.placeTool = tool
+}
+
+func (w *WindowToolbar) SetGizmoTool(tool *gizmo.Tool) {
+	w.gizmoTool = tool
+}
+
+func (w *WindowToolbar) SetLightGizmos(gizmos *gizmo.Lights) {
+	w.lightGizmos = gizmos
+}
+
+func (w *WindowToolbar) SetMeasureTool(tool *measure.Tool) {
+	w.measureTool = tool
+}
+
+// CycleShading 循环切换着色模式
+func (w *WindowToolbar) CycleShading() {
+	config.Config.ShadingMode = (config.Config.ShadingMode + 1) % config.ShadingMode(len(config.ShadingModeNames))
+}
+
+// ToggleWireframe 在线框和上一次的着色模式之间切换
+func (w *WindowToolbar) ToggleWireframe() {
+	if config.Config.ShadingMode == config.ShadingWireframe {
+		config.Config.ShadingMode = w.lastShadingMode
+	} else {
+		w.lastShadingMode = config.Config.ShadingMode
+		config.Config.ShadingMode = config.ShadingWireframe
+	}
+}
+
+// ToggleMeasure 开关测量工具
+func (w *WindowToolbar) ToggleMeasure() {
+	if w.measureTool != nil {
+		w.setMeasureActive(!w.measureTool.Active)
+	}
+}
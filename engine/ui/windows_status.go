@@ -2,15 +2,23 @@ package ui
 
 import (
 	"fmt"
+	"github.com/huangxiaobo/toy-engine/engine/profiler"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
 	"github.com/inkyblackness/imgui-go/v4"
+	"strings"
 )
 
+// alertColor 超出预算的性能范围使用的文字颜色
+var alertColor = imgui.Vec4{X: 1, Y: 0.3, Z: 0.3, W: 1}
+
 type WindowStatus struct {
 	noClose bool
 	visible bool
 	flags   WindowFlags
 	size    imgui.Vec2
 	height  int32
+
+	profiler *profiler.Profiler
 }
 
 func NewWindowStatus() *WindowStatus {
@@ -27,10 +35,29 @@ func (w *WindowStatus) adjustPost() {
 
 }
 
+func (w *WindowStatus) SetProfiler(p *profiler.Profiler) {
+	w.profiler = p
+}
+
 func (w *WindowStatus) Show(displaySize [2]float32) {
+	var alerts []*profiler.Scope
+	if w.profiler != nil && w.profiler.Enabled {
+		alerts = w.profiler.Alerts()
+	}
+
+	// 编译或热重载失败的着色器, 只显示错误的第一行, 完整的错误在日志中
+	var shaderErrors []string
+	for _, s := range shader.Errors() {
+		text, _, _ := strings.Cut(s.Err.Error(), "\n")
+		shaderErrors = append(shaderErrors, text)
+	}
+
+	// 每个报警的范围和每个错误增加一行
+	size := w.size
+	size.Y += float32(len(alerts)+len(shaderErrors)) * float32(w.height)
 	pos := imgui.Vec2{X: displaySize[0]/2 - w.size.X/2, Y: 0}
 	imgui.SetNextWindowPosV(pos, imgui.ConditionNone, imgui.Vec2{})
-	imgui.SetNextWindowSizeV(w.size, imgui.ConditionNone)
+	imgui.SetNextWindowSizeV(size, imgui.ConditionNone)
 
 	if !imgui.BeginV("WindowStatus", &w.visible, w.flags.combined()) {
 		// Early out if the window is collapsed, as an optimization.
@@ -53,6 +80,22 @@ func (w *WindowStatus) Show(displaySize [2]float32) {
 	imgui.SetCursorPos(imgui.Vec2{X: x})
 	imgui.Text(text)
 
+	for _, scope := range alerts {
+		text := fmt.Sprintf("%s over budget: %.2f ms > %.2f ms", scope.Path(), scope.Time(), scope.Budget)
+		textWidth := imgui.CalcTextSize(text, false, 0).X
+		imgui.SetCursorPos(imgui.Vec2{X: windowWidth/2 - textWidth/2, Y: imgui.CursorPos().Y})
+		imgui.PushStyleColor(imgui.StyleColorText, alertColor)
+		imgui.Text(text)
+		imgui.PopStyleColor()
+	}
+	for _, text := range shaderErrors {
+		textWidth := imgui.CalcTextSize(text, false, 0).X
+		imgui.SetCursorPos(imgui.Vec2{X: max(windowWidth/2-textWidth/2, 0), Y: imgui.CursorPos().Y})
+		imgui.PushStyleColor(imgui.StyleColorText, alertColor)
+		imgui.Text(text)
+		imgui.PopStyleColor()
+	}
+
 	// End of ShowDemoWindow()
 	imgui.End()
 
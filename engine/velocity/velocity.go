@@ -0,0 +1,127 @@
+package velocity
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/framebuffer"
+	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+)
+
+// Velocity 速度缓冲, 记录每个对象上一帧的变换, 输出当前帧到上一帧的屏幕空间位移
+type Velocity struct {
+	buffer *framebuffer.FrameBuffer
+	effect *technique.BaseTechnique
+
+	prevViewProjectionUniform int32
+	prevModelUniform          int32
+	hasPrevModelUniform       int32
+
+	prevViewProjection mgl32.Mat4
+	prevModels         map[model.RenderObj]mgl32.Mat4
+	hasPrev            bool
+}
+
+func NewVelocity(width, height int32) (*Velocity, error) {
+	v := &Velocity{
+		effect:     &technique.BaseTechnique{},
+		prevModels: make(map[model.RenderObj]mgl32.Mat4),
+	}
+
+	var err error
+	if v.buffer, err = framebuffer.NewFrameBuffer(width, height, true, framebuffer.RG16F); err != nil {
+		return nil, fmt.Errorf("velocity buffer: %w", err)
+	}
+
+	velocityShader := &shader.Shader{
+		VertFilePath: "./resource/shader/velocity.vert",
+		FragFilePath: "./resource/shader/velocity.frag",
+	}
+	if err := velocityShader.Init(); err != nil {
+		return nil, err
+	}
+	v.effect.Init(velocityShader)
+	v.prevViewProjectionUniform = v.effect.GetUniformLocation("gPrevViewProjection")
+	v.prevModelUniform = v.effect.GetUniformLocation("gPrevModel")
+	v.hasPrevModelUniform = v.effect.GetUniformLocation("gHasPrevModel")
+	return v, nil
+}
+
+func (v *Velocity) Resize(width, height int32) error {
+	return v.buffer.Resize(width, height)
+}
+
+// Buffer 速度缓冲, 颜色附件0为 RG16F 速度
+func (v *Velocity) Buffer() *framebuffer.FrameBuffer {
+	return v.buffer
+}
+
+// Render 绘制速度缓冲并记录本帧的变换, 供下一帧使用
+func (v *Velocity) Render(renderObjs []model.RenderObj, projection, view mgl32.Mat4) {
+	viewProjection := projection.Mul4(view)
+	if !v.hasPrev {
+		v.prevViewProjection = viewProjection
+		v.hasPrev = true
+	}
+
+	var lastViewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &lastViewport[0])
+
+	v.buffer.Bind()
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gl.Enable(gl.DEPTH_TEST)
+
+	v.effect.Enable()
+	v.effect.SetProjectMatrix(&projection)
+	v.effect.SetViewMatrix(&view)
+	gl.UniformMatrix4fv(v.prevViewProjectionUniform, 1, false, &v.prevViewProjection[0])
+
+	current := make(map[model.RenderObj]mgl32.Mat4, len(renderObjs))
+	for _, renderObj := range renderObjs {
+		geometryObj, ok := renderObj.(model.GeometryObj)
+		if !ok {
+			continue
+		}
+
+		hasPrevModel := false
+		if transformObj, ok := renderObj.(model.TransformObj); ok {
+			modelMatrix := transformObj.ModelMatrix()
+			current[renderObj] = modelMatrix
+
+			prevModel, ok := v.prevModels[renderObj]
+			if !ok {
+				prevModel = modelMatrix
+			}
+			gl.UniformMatrix4fv(v.prevModelUniform, 1, false, &prevModel[0])
+			hasPrevModel = true
+		}
+		if hasPrevModel {
+			gl.Uniform1i(v.hasPrevModelUniform, 1)
+		} else {
+			gl.Uniform1i(v.hasPrevModelUniform, 0)
+		}
+
+		geometryObj.RenderGeometry(v.effect)
+	}
+	v.effect.Disable()
+
+	v.buffer.UnBind()
+	gl.Viewport(lastViewport[0], lastViewport[1], lastViewport[2], lastViewport[3])
+
+	v.prevViewProjection = viewProjection
+	v.prevModels = current
+}
+
+// Reset 清除上一帧记录, 停用后重新开启时不会产生跳变
+func (v *Velocity) Reset() {
+	v.hasPrev = false
+	v.prevModels = make(map[model.RenderObj]mgl32.Mat4)
+}
+
+func (v *Velocity) Dispose() {
+	v.buffer.Dispose()
+}
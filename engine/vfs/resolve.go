@@ -0,0 +1,69 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Root 资源路径的根, 引擎使用的资源路径都在它下面, 由挂载的目录, 资源包和内嵌资源提供
+const Root = "resource"
+
+// Path 资源根目录下的路径, 例如 Path("model", name) 为 resource/model/<name>
+func Path(elem ...string) string {
+	return path.Join(append([]string{Root}, elem...)...)
+}
+
+// MountSearchPath 依次把多个磁盘目录挂载到 point, 排在前面的优先, 不存在的目录跳过
+func MountSearchPath(point string, dirs []string) error {
+	var errs []error
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i] == "" {
+			continue
+		}
+		if err := MountDir(point, dirs[i]); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MountProject 挂载项目目录, 项目目录下的 resource.pak 和 resource 目录覆盖之前挂载的同名资源
+// 项目目录中可以只放需要替换或新增的资源
+func MountProject(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("project %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("project %s: not a directory", dir)
+	}
+	if err := MountArchive("", filepath.Join(dir, Root+".pak")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := MountDir(Root, filepath.Join(dir, Root)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// WritePath 写回资源(光照贴图, 绘制的贴图, 场景和按键绑定)时使用的磁盘路径
+// 为覆盖该路径的优先级最高的磁盘目录中的路径, 没有挂载的磁盘目录时原样返回
+func WritePath(name string) string {
+	key := clean(name)
+	mu.RLock()
+	defer mu.RUnlock()
+	for i := len(mounts) - 1; i >= 0; i-- {
+		rel, ok := mounts[i].relative(key)
+		if !ok {
+			continue
+		}
+		if local, ok := mounts[i].src.localPath(rel); ok {
+			return local
+		}
+	}
+	return name
+}
@@ -0,0 +1,125 @@
+package vfs
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// dirSource 磁盘目录
+type dirSource string
+
+func (d dirSource) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+func (d dirSource) files() []string {
+	return nil
+}
+
+func (d dirSource) localPath(name string) (string, bool) {
+	return filepath.Join(string(d), filepath.FromSlash(name)), true
+}
+
+func (d dirSource) close() error {
+	return nil
+}
+
+func (d dirSource) String() string {
+	return string(d)
+}
+
+// archiveSource zip 格式的资源包, .pak 与 .zip 格式相同
+type archiveSource struct {
+	name    string
+	reader  *zip.ReadCloser
+	entries map[string]*zip.File
+}
+
+func openArchive(name string) (*archiveSource, error) {
+	reader, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	a := &archiveSource{name: name, reader: reader, entries: make(map[string]*zip.File)}
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		a.entries[clean(f.Name)] = f
+	}
+	return a, nil
+}
+
+func (a *archiveSource) open(name string) (io.ReadCloser, error) {
+	f, ok := a.entries[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return f.Open()
+}
+
+func (a *archiveSource) files() []string {
+	names := make([]string, 0, len(a.entries))
+	for name := range a.entries {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (a *archiveSource) localPath(string) (string, bool) {
+	return "", false
+}
+
+func (a *archiveSource) close() error {
+	return a.reader.Close()
+}
+
+func (a *archiveSource) String() string {
+	return a.name
+}
+
+// fsSource 任意 fs.FS, 用于 go:embed 内嵌的默认资源
+type fsSource struct {
+	fsys fs.FS
+}
+
+func (f fsSource) open(name string) (io.ReadCloser, error) {
+	if name == "" {
+		name = "."
+	}
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		file.Close()
+		return nil, fs.ErrNotExist
+	}
+	return file, nil
+}
+
+func (f fsSource) files() []string {
+	var names []string
+	fs.WalkDir(f.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names
+}
+
+func (f fsSource) localPath(string) (string, bool) {
+	return "", false
+}
+
+func (f fsSource) close() error {
+	return nil
+}
+
+func (f fsSource) String() string {
+	return "embedded"
+}
@@ -0,0 +1,280 @@
+// Package vfs 虚拟资源文件系统
+// 资源路径相对于工作目录, 例如 resource/world.xml, 按挂载点映射到磁盘目录, 资源包(.zip/.pak)或内嵌的文件系统
+// 后挂载的优先, 通常依次挂载内嵌的默认资源, 资源包和 resource 目录, 开发时磁盘上的文件覆盖资源包
+// 不在任何挂载点下或挂载点中找不到的路径直接在磁盘上查找
+package vfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// source 挂载的文件来源, 路径相对于挂载点, 使用 / 分隔
+type source interface {
+	open(name string) (io.ReadCloser, error)
+	// files 不在磁盘上的所有文件, 用于解压
+	files() []string
+	// localPath 磁盘上的路径, 不在磁盘上时返回 false
+	localPath(name string) (string, bool)
+	close() error
+	String() string
+}
+
+type mount struct {
+	point string
+	src   source
+}
+
+var (
+	mu     sync.RWMutex
+	mounts []mount
+	// 解压给只能读取磁盘文件的库(assimp, SDL_ttf)使用的目录, 第一次需要时创建
+	cacheDir string
+)
+
+func add(point string, src source) {
+	mu.Lock()
+	mounts = append(mounts, mount{point: clean(point), src: src})
+	mu.Unlock()
+}
+
+// MountDir 把磁盘目录挂载到 point
+func MountDir(point, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("mount %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("mount %s: not a directory", dir)
+	}
+	add(point, dirSource(dir))
+	return nil
+}
+
+// MountArchive 把资源包挂载到 point, 资源包中的路径相对于 point
+func MountArchive(point, file string) error {
+	src, err := openArchive(file)
+	if err != nil {
+		return fmt.Errorf("mount %s: %w", file, err)
+	}
+	add(point, src)
+	return nil
+}
+
+// MountFS 把文件系统(例如 go:embed 内嵌的文件)挂载到 point, 文件系统中的路径相对于 point
+func MountFS(point string, fsys fs.FS) {
+	add(point, fsSource{fsys})
+}
+
+// Mounted 已挂载的来源, 按优先级从高到低
+func Mounted() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(mounts))
+	for i := len(mounts) - 1; i >= 0; i-- {
+		names = append(names, fmt.Sprintf("%s -> %s", mounts[i].src, mounts[i].point))
+	}
+	return names
+}
+
+// Unmount 卸载所有来源并删除解压的缓存
+func Unmount() {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, m := range mounts {
+		m.src.close()
+	}
+	mounts = nil
+	if cacheDir != "" {
+		os.RemoveAll(cacheDir)
+		cacheDir = ""
+	}
+}
+
+// clean 使用 / 分隔的相对路径, 工作目录下的绝对路径转换为相对路径
+func clean(name string) string {
+	if filepath.IsAbs(name) {
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, name); err == nil {
+				name = rel
+			}
+		}
+	}
+	name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+// relative 路径相对于挂载点的部分, 不在挂载点下时返回 false
+func (m mount) relative(key string) (string, bool) {
+	switch {
+	case m.point == "":
+		return key, true
+	case key == m.point:
+		return "", true
+	case strings.HasPrefix(key, m.point+"/"):
+		return key[len(m.point)+1:], true
+	}
+	return "", false
+}
+
+// Open 打开资源文件
+func Open(name string) (io.ReadCloser, error) {
+	key := clean(name)
+	mu.RLock()
+	for i := len(mounts) - 1; i >= 0; i-- {
+		rel, ok := mounts[i].relative(key)
+		if !ok {
+			continue
+		}
+		if r, err := mounts[i].src.open(rel); err == nil {
+			mu.RUnlock()
+			return r, nil
+		}
+	}
+	mu.RUnlock()
+	return os.Open(name)
+}
+
+// ReadFile 读取资源文件的全部内容
+func ReadFile(name string) ([]byte, error) {
+	r, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Exists 资源文件是否存在
+func Exists(name string) bool {
+	r, err := Open(name)
+	if err != nil {
+		return false
+	}
+	r.Close()
+	return true
+}
+
+// ModTime 资源文件在磁盘上的修改时间, 用于热重载, 文件在资源包或内嵌文件系统中时返回 false
+func ModTime(name string) (time.Time, bool) {
+	key := clean(name)
+	mu.RLock()
+	for i := len(mounts) - 1; i >= 0; i-- {
+		rel, ok := mounts[i].relative(key)
+		if !ok {
+			continue
+		}
+		r, err := mounts[i].src.open(rel)
+		if err != nil {
+			continue
+		}
+		r.Close()
+		local, ok := mounts[i].src.localPath(rel)
+		mu.RUnlock()
+		if !ok {
+			return time.Time{}, false
+		}
+		return modTime(local)
+	}
+	mu.RUnlock()
+	return modTime(name)
+}
+
+func modTime(name string) (time.Time, bool) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// LocalPath 返回磁盘上的路径, 文件不在磁盘上时把它所在的目录解压到缓存目录
+// 模型文件引用的材质和贴图通常在同一目录下, 因此整个目录一起解压
+func LocalPath(name string) (string, error) {
+	key := clean(name)
+	mu.Lock()
+	defer mu.Unlock()
+	for i := len(mounts) - 1; i >= 0; i-- {
+		m := mounts[i]
+		rel, ok := m.relative(key)
+		if !ok {
+			continue
+		}
+		r, err := m.src.open(rel)
+		if err != nil {
+			continue
+		}
+		r.Close()
+		if local, ok := m.src.localPath(rel); ok {
+			return local, nil
+		}
+		return extractDir(key)
+	}
+	if _, err := os.Stat(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// extractDir 把 key 所在目录(包括子目录)中不在磁盘上的文件解压到缓存目录
+// 先解压优先级低的来源, 优先级高的覆盖同名文件
+func extractDir(key string) (string, error) {
+	if cacheDir == "" {
+		dir, err := os.MkdirTemp("", "toy-engine-vfs")
+		if err != nil {
+			return "", err
+		}
+		cacheDir = dir
+	}
+	local := filepath.Join(cacheDir, filepath.FromSlash(key))
+	if _, err := os.Stat(local); err == nil {
+		return local, nil
+	}
+
+	prefix := ""
+	if dir := path.Dir(key); dir != "." {
+		prefix = dir + "/"
+	}
+	for _, m := range mounts {
+		for _, file := range m.src.files() {
+			target := path.Join(m.point, file)
+			if !strings.HasPrefix(target, prefix) {
+				continue
+			}
+			if err := extract(m.src, file, filepath.Join(cacheDir, filepath.FromSlash(target))); err != nil {
+				return "", err
+			}
+		}
+	}
+	return local, nil
+}
+
+func extract(src source, name, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	r, err := src.open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -4,20 +4,51 @@ import (
 	"fmt"
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/huangxiaobo/toy-engine/engine/debugdraw"
+	"github.com/huangxiaobo/toy-engine/engine/event"
+	"github.com/huangxiaobo/toy-engine/engine/geometry"
+	"github.com/huangxiaobo/toy-engine/engine/gizmo"
+	"github.com/huangxiaobo/toy-engine/engine/gldebug"
+	"github.com/huangxiaobo/toy-engine/engine/glqueue"
+	"github.com/huangxiaobo/toy-engine/engine/job"
+	"github.com/huangxiaobo/toy-engine/engine/lightmap"
+	"github.com/huangxiaobo/toy-engine/engine/measure"
+	"github.com/huangxiaobo/toy-engine/engine/mesh"
 	"github.com/huangxiaobo/toy-engine/engine/model"
+	"github.com/huangxiaobo/toy-engine/engine/occlusion"
+	"github.com/huangxiaobo/toy-engine/engine/outline"
+	"github.com/huangxiaobo/toy-engine/engine/paint"
+	"github.com/huangxiaobo/toy-engine/engine/placement"
 	"github.com/huangxiaobo/toy-engine/engine/platforms"
+	"github.com/huangxiaobo/toy-engine/engine/postprocess"
+	"github.com/huangxiaobo/toy-engine/engine/profiler"
+	"github.com/huangxiaobo/toy-engine/engine/registry"
+	"github.com/huangxiaobo/toy-engine/engine/rhi"
+	"github.com/huangxiaobo/toy-engine/engine/rhi/glrhi"
+	"github.com/huangxiaobo/toy-engine/engine/shadow"
+	"github.com/huangxiaobo/toy-engine/engine/shortcut"
+	"github.com/huangxiaobo/toy-engine/engine/spline"
+	"github.com/huangxiaobo/toy-engine/engine/sprite"
+	"github.com/huangxiaobo/toy-engine/engine/ssao"
+	"github.com/huangxiaobo/toy-engine/engine/technique"
+	"github.com/huangxiaobo/toy-engine/engine/terrain"
 	"github.com/huangxiaobo/toy-engine/engine/text"
+	"github.com/huangxiaobo/toy-engine/engine/texture"
+	"github.com/huangxiaobo/toy-engine/engine/timefx"
 	"github.com/huangxiaobo/toy-engine/engine/ui"
 	"github.com/huangxiaobo/toy-engine/engine/utils"
+	"github.com/huangxiaobo/toy-engine/engine/velocity"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
 	"github.com/inkyblackness/imgui-go/v4"
 	_ "image/png"
 	"log"
 	"os"
-	"reflect"
 	"time"
 
+	"github.com/huangxiaobo/toy-engine/engine/audio"
 	"github.com/huangxiaobo/toy-engine/engine/camera"
 	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/input"
 	"github.com/huangxiaobo/toy-engine/engine/light"
 	"github.com/huangxiaobo/toy-engine/engine/logger"
 )
@@ -32,12 +63,93 @@ type World struct {
 	platform *platforms.SDL
 	imguiIO  imgui.IO
 	renderer *platforms.OpenGL4
+	// 渲染硬件接口, 已迁移的子系统通过它提交绘制
+	device rhi.Device
+	// 当前已应用到界面样式的缩放
+	uiScale float32
 
-	xmlWorld   *config.XmlWorld
-	Lights     []*light.PointLight
-	renderObjs []model.RenderObj
-	Camera     *camera.Camera
-	Text       *text.Text
+	xmlWorld    *config.XmlWorld
+	ground      *model.Ground
+	Lights      []*light.PointLight
+	Sun         *light.DirectionLight
+	renderObjs  []model.RenderObj
+	renderQueue *RenderQueue
+	Camera      *camera.Camera
+	Text        *text.Text
+	// 场景中的所有相机, Camera 为其中的当前相机
+	Cameras *camera.Cameras
+	// 当前帧的键盘鼠标状态和按名称查询的动作
+	Input   *input.Input
+	Actions *input.ActionMap
+	// 场景对象的 Id, 标签和空间索引, 与 renderObjs 同步
+	registry *registry.Registry
+	// 窗口, 按键, 场景对象和资源的事件, 子系统和用户代码订阅后响应
+	Events *event.Bus
+	// 编辑器命令和快捷键
+	Commands *shortcut.Registry
+	// 场景文件, 保存场景时写回
+	worldFile string
+	// 应用登记的每帧回调
+	updateHooks []*updateHook
+	guiHooks    []*guiHook
+	// 异步加载的进度
+	loading loadingState
+	// 资源热重载
+	reload hotReloadState
+
+	// 遮挡剔除
+	occlusion *occlusion.Culler
+	// 屏幕空间环境光遮蔽
+	ssao *ssao.SSAO
+	// 点光源的全向阴影
+	pointShadows *shadow.PointShadows
+	// 运动模糊使用的速度缓冲
+	velocity *velocity.Velocity
+	// 后处理效果链
+	PostProcess *postprocess.Chain
+	// 调试线段
+	DebugDraw *debugdraw.DebugDraw
+	// 二维 HUD 层, 在场景之后, 界面之前绘制
+	HUD *sprite.Layer
+	// 选中对象的轮廓
+	outline *outline.Outline
+	// 顿帧和慢动作等时间效果, 缩放场景对象的更新时间
+	Time *timefx.Effects
+	// 按名称播放的音频事件
+	Audio *audio.System
+	// 每帧各阶段的耗时和预算报警
+	profiler *profiler.Profiler
+	// 测量工具
+	measureTool *measure.Tool
+	// 纹理绘制工具
+	paintTool *paint.Tool
+	// 地形雕刻工具
+	sculptTool *terrain.SculptTool
+	// 样条挤出工具
+	splineTool *spline.Tool
+	// 预制体放置工具
+	placeTool *placement.Tool
+	// 拖入窗口的模型数量, 用于生成 Id
+	dropped int
+	// 选中模型的变换手柄
+	gizmoTool *gizmo.Tool
+	// 灯光图标和影响范围, 点击图标选中灯光
+	lightGizmos *gizmo.Lights
+	// 光照贴图烘焙
+	lightmapBaker *lightmap.Baker
+	// 放置预览模型及其对应的预制体下标
+	placePreview      *model.Model
+	placePreviewIndex int
+	placeValid        bool
+	// 左键拖动是否在旋转相机, 在按下左键时决定
+	orbitRotating bool
+	// 视口中按下左键的位置, 松开时距离足够近则按点击选择模型
+	clickStart   [2]float32
+	clickPending bool
+
+	// 冻结剔除时使用的 projection * view
+	frozenViewProjection mgl32.Mat4
+	cullingFrozen        bool
 
 	// 界面
 	uiWindowMain *ui.WindowMain
@@ -59,16 +171,26 @@ func (w *World) initSDL() {
 	windowWidth := config.Config.WindowWidth
 	windowHeight := config.Config.WindowHeight
 
-	w.platform, err = platforms.NewSDL(w.imguiIO, platforms.SDLClientAPIOpenGL4, windowWidth, windowHeight)
+	var samples int32
+	if config.Config.AntiAliasing == config.AntiAliasingMSAA {
+		samples = config.Config.MSAASamples
+	}
+
+	w.platform, err = platforms.NewSDL(w.imguiIO, platforms.SDLClientAPIOpenGL4, windowWidth, windowHeight, samples)
 	if err != nil {
 		panic(err)
 	}
+	w.platform.SetEvents(w.Events)
+	w.Input = w.platform.Input()
+	w.Actions = input.NewActionMap(w.Input, defaultActions())
 
 	w.renderer, err = platforms.NewOpenGL4(w.imguiIO)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(-1)
 	}
+	w.uiScale = 1
+	w.device = glrhi.NewDevice()
 
 }
 
@@ -80,13 +202,18 @@ func (w *World) initGL() {
 
 	version := gl.GoStr(gl.GetString(gl.VERSION))
 	logger.Info("OpenGL version", version)
+	gldebug.Init()
 
 	// Configure global settings
 	gl.Enable(gl.DEPTH_TEST)
 	gl.DepthFunc(gl.LESS)
 	gl.ClearColor(1.0, 1.0, 1.0, 1.0)
 
-	gl.Enable(gl.SAMPLES)
+	// 窗口未能创建多重采样缓冲时, 回退到FXAA
+	if config.Config.AntiAliasing == config.AntiAliasingMSAA && w.platform.Samples() == 0 {
+		logger.Warn("MSAA is not supported by the default framebuffer, falling back to FXAA")
+		config.Config.AntiAliasing = config.AntiAliasingFXAA
+	}
 
 	// 只显示正面 , 不显示背面
 	// gl.Enable(gl.CULL_FACE)
@@ -96,6 +223,7 @@ func (w *World) initGL() {
 }
 
 func (w *World) initModels() {
+	w.registry = registry.NewRegistry()
 
 	for _, xmlMode := range w.xmlWorld.XMLModels.XMLModels {
 		resourceClass := xmlMode.XmlResourceClass
@@ -103,11 +231,61 @@ func (w *World) initModels() {
 		switch resourceClass {
 		case "Ground":
 			obj, _ := model.NewGround(xmlMode)
-			w.renderObjs = append(w.renderObjs, &obj)
+			w.ground = &obj
+			w.addRenderObj(&obj, obj.Name, obj.Id, xmlMode.Tags)
 		case "Model":
+			if config.Config.AsyncLoading {
+				w.loadModelAsync(xmlMode)
+				continue
+			}
 			obj, _ := model.NewModel(xmlMode)
-			w.renderObjs = append(w.renderObjs, &obj)
+			w.addRenderObj(&obj, obj.Name, obj.Id, xmlMode.Tags)
+		case "Billboard":
+			obj, err := model.NewBillboardFromXml(xmlMode)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			w.addRenderObj(obj, obj.Name, obj.Id, xmlMode.Tags)
+		case "Terrain":
+			obj, err := terrain.NewTerrain(xmlMode)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			w.addRenderObj(&obj, obj.Name, obj.Id, xmlMode.Tags)
+		case "Water":
+			obj, err := model.NewWater(xmlMode)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			w.addRenderObj(obj, obj.Name, obj.Id, xmlMode.Tags)
+		case "Vegetation":
+			obj, err := model.NewVegetation(xmlMode)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			w.addRenderObj(obj, obj.Name, obj.Id, xmlMode.Tags)
+		case "Crowd":
+			obj, err := model.NewCrowd(xmlMode)
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			w.addRenderObj(obj, obj.Name, obj.Id, xmlMode.Tags)
+
+		}
+	}
 
+	// 植被和角色群依赖地形和地面的高度, 在所有模型创建之后散布
+	for _, renderObj := range w.renderObjs {
+		switch obj := renderObj.(type) {
+		case *model.Vegetation:
+			obj.Scatter(w.surfaceHeightAt)
+		case *model.Crowd:
+			obj.Spawn(w.surfaceHeightAt)
 		}
 	}
 }
@@ -119,33 +297,195 @@ func (w *World) initUI() {
 	imgui.PushStyleVarFloat(imgui.StyleVarFrameBorderSize, 1)
 
 	w.uiWindowMain = ui.NewWindowMain(w)
+	w.uiWindowMain.SetPostProcess(w.PostProcess)
+	w.uiWindowMain.SetMeasureTool(w.measureTool)
+	w.uiWindowMain.SetPaintTool(w.paintTool)
+	w.uiWindowMain.SetSculptTool(w.sculptTool)
+	w.uiWindowMain.SetSplineTool(w.splineTool)
+	w.uiWindowMain.SetPlacementTool(w.placeTool)
+	w.uiWindowMain.SetGizmoTool(w.gizmoTool)
+	w.uiWindowMain.SetLightGizmos(w.lightGizmos)
+	w.uiWindowMain.SetOcclusionCuller(w.occlusion)
+	w.uiWindowMain.SetSunLight(w.Sun)
+	w.uiWindowMain.SetCameras(w.Cameras)
+	w.uiWindowMain.SetTimeEffects(w.Time)
+	w.uiWindowMain.SetAudio(w.Audio)
+	w.uiWindowMain.SetInputActions(w.Actions, w.Input, w.inputBindingsFile())
+	w.uiWindowMain.SetProfiler(w.profiler)
+	w.uiWindowMain.SetLightmapBaker(w.lightmapBaker)
+	if w.ground != nil {
+		w.uiWindowMain.SetReflection(w.ground.Reflection)
+	}
 
 	for _, l := range w.Lights {
 		w.uiWindowMain.AddLight(l)
 	}
 
-	for _, renderObj := range w.renderObjs {
-		name := reflect.ValueOf(renderObj).Elem().FieldByName("Name").String()
-		id := reflect.ValueOf(renderObj).Elem().FieldByName("Id").String()
+	for _, e := range w.registry.Entities() {
+		w.uiWindowMain.AddModelItem(ui.ModelItem{Name: e.Name, Id: e.Id, Obj: e.Obj})
+	}
+	// 之后加入和移除的对象由事件同步到模型列表
+	event.Subscribe(w.Events, func(e event.ObjectAdded) {
+		w.uiWindowMain.AddModelItem(ui.ModelItem{Name: e.Name, Id: e.Id, Obj: e.Obj})
+	})
+	event.Subscribe(w.Events, func(e event.ObjectRemoved) {
+		w.uiWindowMain.RemoveModelItem(e.Obj)
+	})
+	w.initDrop()
+}
+
+// addRenderObj 加入场景对象, 登记到 registry 并发布 ObjectAdded
+func (w *World) addRenderObj(obj model.RenderObj, name, id string, tags []string) {
+	w.renderObjs = append(w.renderObjs, obj)
+	w.registry.Add(id, name, tags, obj)
+	event.Publish(w.Events, event.ObjectAdded{Id: id, Name: name, Obj: obj})
+}
 
-		w.uiWindowMain.AddModelItem(ui.ModelItem{Name: name, Id: id, Obj: renderObj})
+// RemoveObject 从场景中移除对象并发布 ObjectRemoved, 不释放对象的资源, 返回对象是否在场景中
+func (w *World) RemoveObject(obj model.RenderObj) bool {
+	for i, renderObj := range w.renderObjs {
+		if renderObj != obj {
+			continue
+		}
+		var name, id string
+		for _, e := range w.registry.Entities() {
+			if e.Obj == obj {
+				name, id = e.Name, e.Id
+				break
+			}
+		}
+		w.renderObjs = append(w.renderObjs[:i], w.renderObjs[i+1:]...)
+		w.registry.Remove(obj)
+		w.removeXmlModel(id)
+		if obj == model.RenderObj(w.ground) {
+			w.ground = nil
+		}
+		event.Publish(w.Events, event.ObjectRemoved{Id: id, Name: name, Obj: obj})
+		return true
 	}
+	return false
+}
+
+func (w *World) initPostProcess(width, height int32) error {
+	var err error
+	if w.PostProcess, err = postprocess.NewChain(width, height); err != nil {
+		return err
+	}
+	// 辉光读取未经其他效果处理的 HDR 场景颜色, 放在效果链的最前面
+	if err = w.PostProcess.Add(postprocess.NewBloom(), true); err != nil {
+		return err
+	}
+	if err = w.PostProcess.Add(postprocess.NewDepthOfField(w.PostProcess.SceneBuffer()), false); err != nil {
+		return err
+	}
+	if err = w.PostProcess.Add(postprocess.NewMotionBlur(w.velocity.Buffer()), false); err != nil {
+		return err
+	}
+	if err = w.PostProcess.Add(postprocess.NewVignette(), false); err != nil {
+		return err
+	}
+	// FXAA 需要在其他效果之后执行
+	return w.PostProcess.Add(postprocess.NewFXAA(), false)
+}
+
+// applyAntiAliasing 根据当前设置切换多重采样和FXAA, 支持运行时修改
+func (w *World) applyAntiAliasing() {
+	aa := config.Config.AntiAliasing
+
+	var samples int32
+	if aa == config.AntiAliasingMSAA {
+		gl.Enable(gl.MULTISAMPLE)
+		samples = config.Config.MSAASamples
+	} else {
+		gl.Disable(gl.MULTISAMPLE)
+	}
+	if err := w.PostProcess.SetSamples(samples); err != nil {
+		logger.Error(err)
+		config.Config.AntiAliasing = config.AntiAliasingFXAA
+		_ = w.PostProcess.SetSamples(0)
+	}
+
+	w.PostProcess.SetEnabled(postprocess.FXAAName, config.Config.AntiAliasing == config.AntiAliasingFXAA)
 }
 
 func (w *World) Init(configFile string) error {
 	w.xmlWorld = config.InitXML(configFile)
+	w.worldFile = configFile
 	w.context = imgui.CreateContext(nil)
 
 	w.imguiIO = imgui.CurrentIO()
+	w.Events = event.NewBus()
 
 	w.initSDL()
-	//w.initGL()
+	w.initGL()
 	w.initModels()
 
-	// 初始化摄像机
+	var err error
+	if w.renderQueue, err = NewRenderQueue(); err != nil {
+		return fmt.Errorf("failed to initialize render queue: %w", err)
+	}
+
+	if w.occlusion, err = occlusion.NewCuller(); err != nil {
+		return fmt.Errorf("failed to initialize occlusion culler: %w", err)
+	}
+	w.renderQueue.SetOcclusionCuller(w.occlusion)
+
+	fbSize := w.platform.FramebufferSize()
+	if w.ssao, err = ssao.NewSSAO(int32(fbSize[0]), int32(fbSize[1])); err != nil {
+		return fmt.Errorf("failed to initialize ssao: %w", err)
+	}
+	if w.pointShadows, err = shadow.NewPointShadows(); err != nil {
+		return fmt.Errorf("failed to initialize point shadows: %w", err)
+	}
+	if w.velocity, err = velocity.NewVelocity(int32(fbSize[0]), int32(fbSize[1])); err != nil {
+		return fmt.Errorf("failed to initialize velocity buffer: %w", err)
+	}
+	if err = w.initPostProcess(int32(fbSize[0]), int32(fbSize[1])); err != nil {
+		return fmt.Errorf("failed to initialize post process: %w", err)
+	}
+	if w.DebugDraw, err = debugdraw.NewDebugDraw(w.device); err != nil {
+		return fmt.Errorf("failed to initialize debug draw: %w", err)
+	}
+	if w.HUD, err = sprite.NewLayer(w.device); err != nil {
+		return fmt.Errorf("failed to initialize hud: %w", err)
+	}
+	if w.outline, err = outline.NewOutline(); err != nil {
+		return fmt.Errorf("failed to initialize outline: %w", err)
+	}
+	if w.lightGizmos, err = gizmo.NewLights(); err != nil {
+		return fmt.Errorf("failed to initialize light gizmos: %w", err)
+	}
+	w.measureTool = measure.NewTool()
+	w.paintTool = paint.NewTool()
+	w.sculptTool = terrain.NewSculptTool()
+	w.splineTool = spline.NewTool()
+	w.placeTool = placement.NewTool(w.prefabs())
+	w.gizmoTool = gizmo.NewTool()
+	w.lightmapBaker = lightmap.NewBaker()
+	w.placePreviewIndex = -1
+	w.initProfiler()
+
+	// 初始化摄像机, <camera> 为默认的编辑器相机, <cameras> 中为其他可切换的相机
+	w.Cameras = camera.NewCameras()
 	xmlCamera := w.xmlWorld.XMLCamera
-	w.Camera = new(camera.Camera)
-	w.Camera.Init(xmlCamera.XMLPosition.XYZ(), xmlCamera.XMLTarget.XYZ())
+	if xmlCamera.Name == "" {
+		xmlCamera.Name = camera.DefaultCameraName
+	}
+	w.Cameras.Add(camera.NewCamera(xmlCamera))
+	for _, xmlCamera := range w.xmlWorld.XMLCameras {
+		w.Cameras.Add(camera.NewCamera(xmlCamera))
+	}
+	w.Camera = w.Cameras.Active()
+
+	// 时间效果
+	w.Time = timefx.NewEffects()
+
+	// 音频
+	w.Audio = audio.NewSystem()
+	w.loadAudioBank()
+
+	// 按键绑定
+	w.loadInputBindings()
 
 	// 初始化灯光
 
@@ -153,21 +493,38 @@ func (w *World) Init(configFile string) error {
 	for _, xmlLight := range xmlLights {
 		w.Lights = append(w.Lights, light.NewPointLight(xmlLight))
 	}
+	w.Sun = light.NewDirectionLight()
+	w.loadEnvironment()
 
 	// Text
 	w.Text = text.NewText("Toy引擎", 32, mgl32.Vec3{1, 0, 0})
 
 	w.initUI()
+	w.initCommands()
 
 	w.bRun = true
 	return nil
 }
 
 func (w *World) Destroy() {
+	// 先停止后台任务, 再执行它们提交的 GL 命令
+	job.Default().Dispose()
+	glqueue.Default().Flush()
+	w.DebugDraw.Dispose()
+	w.HUD.Dispose()
+	w.outline.Dispose()
+	w.lightGizmos.Dispose()
+	w.Audio.Dispose()
+	w.paintTool.Dispose()
+	w.occlusion.Dispose()
+	w.PostProcess.Dispose()
+	w.ssao.Dispose()
+	w.pointShadows.Dispose()
+	w.velocity.Dispose()
+	w.profiler.Dispose()
 	w.renderer.Dispose()
 	w.context.Destroy()
 	w.platform.Dispose()
-
 }
 
 // Platform covers mouse/keyboard/gamepad inputs, cursor shape, timing, windowing.
@@ -204,79 +561,738 @@ func (board clipboard) SetText(text string) {
 
 var cnt = 0
 
+// glQueueBudget 每帧执行 GL 命令队列的时间预算, 避免大量异步上传造成卡顿
+const glQueueBudget = 2 * time.Millisecond
+
+// crosshairSize 十字准星的大小(窗口像素)
+const crosshairSize = 16
+
+// frozenFrustumColor 冻结剔除时绘制的视锥颜色
+var frozenFrustumColor = mgl32.Vec3{1, 0.6, 0}
+
+// 包围体颜色, 被剔除的对象显示为红色
+var (
+	boundsColor       = mgl32.Vec3{0.2, 1, 0.4}
+	boundsCulledColor = mgl32.Vec3{1, 0.2, 0.2}
+)
+
+// applyUIScale 根据配置或显示器缩放调整界面尺寸和字体
+func (w *World) applyUIScale() {
+	scale := config.Config.UIScale
+	if scale <= 0 {
+		scale = w.platform.ContentScale()
+	}
+	if scale != w.uiScale {
+		imgui.CurrentStyle().ScaleAllSizes(scale / w.uiScale)
+		w.uiScale = scale
+	}
+	w.renderer.UpdateFontScale(w.platform.FramebufferScale(), scale)
+}
+
 func (w *World) Run() {
 	imgui.CurrentIO().SetClipboard(clipboard{platform: w.platform})
 
 	for !w.platform.ShouldStop() {
+		w.profiler.BeginFrame()
 		w.platform.ProcessEvents()
+		// 发布后台协程提交的事件
+		w.Events.Dispatch()
+
+		// 执行其他协程提交的 GL 命令
+		glqueue.Execute(glQueueBudget)
+		w.hotReload()
+
+		// 字体图集只能在帧外修改
+		w.applyUIScale()
 
 		// Signal start of a new frame
+		w.profiler.Begin("UI")
 		w.platform.NewFrame()
+		// 界面是否占用鼠标键盘使用上一帧的结果
+		w.Input.SetCapture(w.imguiIO.WantCaptureMouse(), w.imguiIO.WantCaptureKeyboard())
 		imgui.NewFrame()
+		imgui.PushFont(w.renderer.Font())
+
+		// 相机和编辑工具使用真实时间, 场景对象使用经过时间效果缩放的游戏时间
+		realElapsed := 0.01
+		elapsed := w.Time.Update(realElapsed)
+		// 界面中可能切换了相机
+		w.syncActiveCamera()
+		w.Camera.Update(realElapsed)
+		w.updateCamera(realElapsed)
+		w.Commands.Handle(w.Input)
+
+		// 宽高比跟随帧缓冲大小
+		fbSize := w.platform.FramebufferSize()
+		w.Camera.SetViewport(int32(fbSize[0]), int32(fbSize[1]))
+		projection := w.Camera.Projection()
+		view := w.Camera.GetViewMatrix()
 
 		displaySize := w.platform.DisplaySize()
 		w.uiWindowMain.Show(displaySize)
+		w.measureTool.HandleInput()
+		w.paintTool.HandleInput()
+		w.sculptTool.HandleInput()
+		w.splineTool.HandleInput()
+		w.placeTool.HandleInput()
+		w.gizmoTool.HandleInput()
+		w.lightGizmos.HandleInput()
+		w.measureTool.DrawLabels(projection, view, displaySize)
+		w.drawLoadingProgress(displaySize)
+		w.runGUIHooks()
 
 		// Rendering
+		imgui.PopFont()
 		imgui.Render() // This call only creates the draw data list. Actual rendering to framebuffer is done below.
-
-		w.renderer.PreRender(config.Config.ClearColor.Vec3())
-
-		projection := mgl32.Perspective(
-			mgl32.DegToRad(w.Camera.Zoom),
-			float32(config.Config.WindowHeight/config.Config.WindowHeight),
-			config.Config.ClipNear,
-			config.Config.ClipFar,
-		)
-		view := w.Camera.GetViewMatrix()
-		model := mgl32.Ident4()
-		//mvp := projection.Mul4(view).Mul4(model)
+		w.profiler.End()
 
 		// Update
-		elapsed := 0.01
+		w.profiler.Begin("Update")
+		w.updatePaint(displaySize, projection, view)
+		w.updateSculpt(displaySize, projection, view, float32(realElapsed))
+		w.buildSpline()
+		w.bakeLightmaps()
+		w.updatePlacement(displaySize, projection, view)
+		w.updateGizmo(displaySize, projection, view)
+		w.updateLightGizmos(displaySize, projection, view)
+		w.updateClickSelection()
 
-		//w.DrawAxis()
-		w.DrawLight(elapsed)
+		cullingViewProjection := w.cullingViewProjection(projection.Mul4(view))
+		frustum := geometry.NewFrustum(cullingViewProjection)
 
+		w.runUpdateHooks(elapsed)
+		model.UpdateMaterials(elapsed)
 		for _, renderObj := range w.renderObjs {
 			renderObj.Update(elapsed)
-			renderObj.PreRender()
-			renderObj.Render(projection, model, view, &w.Camera.Position, w.Lights)
-			renderObj.PostRender()
 		}
+		w.registry.Rebuild()
+		for _, l := range w.Lights {
+			l.Update(elapsed)
+		}
+		w.Audio.SetListener(w.Camera.Position, w.Camera.Target.Sub(w.Camera.Position), w.Camera.Up)
+		w.Audio.Update(realElapsed)
+		w.selectTerrainLOD(cullingViewProjection)
+
+		// 视锥剔除只读取包围盒, 并行计算后按原顺序加入渲染队列
+		culled := make([]bool, len(w.renderObjs))
+		job.Wait(job.ParallelFor(len(w.renderObjs), func(begin, end int) {
+			for i := begin; i < end; i++ {
+				culled[i] = w.culled(w.renderObjs[i], frustum)
+			}
+		}))
+
+		w.renderQueue.Reset()
+		w.renderQueue.SetClip(w.Camera.Near(), w.Camera.Far())
+		for i, renderObj := range w.renderObjs {
+			if config.Config.Bounds.Show {
+				w.drawBounds(renderObj, culled[i])
+			}
+			if culled[i] {
+				continue
+			}
+			w.renderQueue.Push(renderObj)
+		}
+		if w.placeValid {
+			w.renderQueue.Push(w.placePreview)
+		}
+		w.profiler.End()
+
+		w.profiler.Begin("Render")
+		w.profiler.Begin("Shadows")
+		if err := w.pointShadows.Render(w.Lights, w.renderObjs); err != nil {
+			logger.Error(err)
+		}
+		gldebug.Check("shadows")
+		w.profiler.End()
 
+		// 窗口移到缩放不同的显示器上时帧缓冲大小会变化, 大小在帧开始时读取
+		gl.Viewport(0, 0, int32(fbSize[0]), int32(fbSize[1]))
+		if config.SSAOActive() {
+			if err := w.ssao.Resize(int32(fbSize[0]), int32(fbSize[1])); err != nil {
+				logger.Error(err)
+			}
+			w.profiler.Begin("SSAO")
+			w.ssao.Render(w.renderQueue.Items(), projection, view)
+			w.ssao.BindAOTexture()
+			w.profiler.End()
+		}
+
+		// 效果开关和清屏颜色按当前相机的设置
+		w.PostProcess.SetOverride(w.Camera.Settings.EffectEnabled)
+		if dof, ok := w.PostProcess.Get(postprocess.DepthOfFieldName).(*postprocess.DepthOfField); ok {
+			dof.SetProjection(w.Camera.Near(), w.Camera.Far(), w.Camera.Orthographic)
+		}
+		background := w.Camera.Settings.Background()
+
+		// 调试视图替换着色时按非完整渲染处理, 不绘制反射和后处理
+		rendered := config.Config.ShadingMode == config.ShadingRendered && !config.DebugViewReplacesShading()
+
+		// 速度缓冲只在运动模糊启用时绘制
+		if rendered && w.PostProcess.Enabled(postprocess.MotionBlurName) {
+			if err := w.velocity.Resize(int32(fbSize[0]), int32(fbSize[1])); err != nil {
+				logger.Error(err)
+			}
+			w.profiler.Begin("Velocity")
+			w.velocity.Render(w.renderQueue.Items(), projection, view)
+			w.profiler.End()
+		} else {
+			w.velocity.Reset()
+		}
+
+		if rendered {
+			w.profiler.Begin("Reflection")
+			w.renderReflection(projection, view, fbSize)
+			w.profiler.End()
+		}
+
+		w.applyAntiAliasing()
+
+		// 后处理仅在完整渲染模式下生效
+		postProcess := rendered && w.PostProcess.Active()
+		if postProcess {
+			if err := w.PostProcess.Resize(int32(fbSize[0]), int32(fbSize[1])); err != nil {
+				logger.Error(err)
+			}
+			w.PostProcess.Begin(background)
+		} else {
+			w.renderer.PreRender(background)
+		}
+
+		//w.DrawAxis()
+		w.profiler.Begin("Scene")
+		w.renderQueue.Flush(projection, view, &w.Camera.Position, w.Lights)
+
+		// 遮挡查询只在使用对象自身technique绘制的模式下进行
+		// 冻结剔除时不再发起查询, 保留冻结时的遮挡结果
+		switch {
+		case !config.Config.OcclusionCulling:
+			w.occlusion.Reset()
+		case config.Config.FreezeCulling:
+		case config.Config.ShadingMode >= config.ShadingMaterial:
+			w.occlusion.Query(w.renderQueue.Items(), projection, view, w.Camera.Position, w.Camera.Near())
+		}
+
+		if config.Config.FreezeCulling {
+			w.DebugDraw.AddFrustum(w.frozenViewProjection, frozenFrustumColor)
+		}
+		w.measureTool.Draw(w.DebugDraw)
+		w.splineTool.Draw(w.DebugDraw)
+		w.gizmoTool.Draw(w.DebugDraw, w.Camera.Position)
+		w.lightGizmos.Draw(w.DebugDraw, w.Lights)
+		w.drawCameraPaths()
+		w.DebugDraw.Flush(projection, view)
+		w.lightGizmos.RenderIcons(projection, view, w.Camera.Position, w.Lights)
+		gldebug.Check("scene")
+		w.profiler.End()
+
+		if postProcess {
+			w.profiler.Begin("PostFX")
+			w.PostProcess.End()
+			gldebug.Check("post-processing")
+			w.profiler.End()
+		}
+
+		// 轮廓在后处理之后绘制到默认帧缓冲, 离屏缓冲没有模板附件
+		// 选中的对象和声明了描边 pass 的对象都描出轮廓
+		for _, obj := range w.renderQueue.Items() {
+			if technique.HasPass(obj, technique.PassOutline) {
+				w.outline.Draw(obj, projection, view)
+			}
+		}
+		w.outline.Draw(w.uiWindowMain.SelectedModel(), projection, view)
+
+		if click, ok := w.measureTool.PendingClick(); ok {
+			if p, hit := w.pickPosition(click, displaySize, projection, view, postProcess); hit {
+				w.measureTool.AddPoint(p)
+			}
+		}
+		if click, ok := w.splineTool.PendingClick(); ok {
+			if p, hit := w.pickPosition(click, displaySize, projection, view, postProcess); hit {
+				w.splineTool.AddPoint(p)
+			}
+		}
+
+		// 文字和界面在后处理之后直接绘制到默认帧缓冲, 不参与色调映射和伽马校正
 		// Logo
 		w.Text.Render(int(displaySize[0]/2-50), 0)
 
+		// HUD
+		if config.Config.Crosshair {
+			w.HUD.DrawCrosshair(displaySize[0]/2, displaySize[1]/2, crosshairSize, 2, 3, mgl32.Vec4{1, 1, 1, 0.8})
+		}
+		w.HUD.Flush(displaySize)
+		w.profiler.End()
+
 		// Maintenance
+		w.profiler.Begin("Present")
 		w.renderer.Render(w.platform.DisplaySize(), w.platform.FramebufferSize(), imgui.RenderedDrawData())
 		w.platform.PostRender()
+		gldebug.Check("present")
+		w.profiler.End()
 
 		if cnt > 0 && cnt%1000 == 0 {
-			utils.Screenshot(int(displaySize[0]), int(displaySize[1]))
+			utils.Screenshot(int(fbSize[0]), int(fbSize[1]))
 		}
 		cnt += 1
+		w.profiler.EndFrame()
 
 		// sleep to avoid 100% CPU usage for this demo
 		<-time.After(sleepDuration)
 	}
 }
 
-func (w *World) DrawLight(elapsed float64) {
-	// RenderObj
-	width := float32(config.Config.WindowWidth)
-	height := float32(config.Config.WindowHeight)
-	projection := mgl32.Perspective(
-		mgl32.DegToRad(w.Camera.Zoom),
-		width/height,
-		config.Config.ClipNear,
-		config.Config.ClipFar,
-	)
-	view := w.Camera.GetViewMatrix()
-	model := mgl32.Ident4()
+// cullingViewProjection 剔除使用的 projection * view, 冻结剔除时返回开启冻结那一帧的矩阵
+func (w *World) cullingViewProjection(viewProjection mgl32.Mat4) mgl32.Mat4 {
+	if !config.Config.FreezeCulling {
+		w.cullingFrozen = false
+		return viewProjection
+	}
+	if !w.cullingFrozen {
+		w.frozenViewProjection = viewProjection
+		w.cullingFrozen = true
+	}
+	return w.frozenViewProjection
+}
+
+// culled 对象是否完全在视锥外, 没有包围盒的对象(如地面网格)总是绘制
+func (w *World) culled(renderObj model.RenderObj, frustum geometry.Frustum) bool {
+	if !config.Config.FrustumCulling {
+		return false
+	}
+	boundedObj, ok := renderObj.(model.BoundedObj)
+	if !ok {
+		return false
+	}
+	return !frustum.IntersectsAABB(boundedObj.WorldBounds())
+}
 
-	for _, l := range w.Lights {
-		l.Update(elapsed)
-		l.Render(projection, view, model)
+// drawBounds 绘制对象的包围盒和包围球, 没有包围盒的对象不绘制
+func (w *World) drawBounds(renderObj model.RenderObj, culled bool) {
+	boundedObj, ok := renderObj.(model.BoundedObj)
+	if !ok {
+		return
+	}
+	color := boundsColor
+	if culled {
+		color = boundsCulledColor
+	}
+	bounds := []geometry.AABB{boundedObj.WorldBounds()}
+	if m, ok := renderObj.(*model.Model); ok && config.Config.Bounds.Meshes {
+		bounds = m.MeshWorldBounds()
+	}
+	for _, b := range bounds {
+		w.DebugDraw.AddAABB(b, color)
+		if config.Config.Bounds.Spheres {
+			w.DebugDraw.AddSphere(b.BoundingSphere(), color)
+		}
+	}
+}
+
+// selectTerrainLOD 按相机位置选择地形各块的层级, 冻结剔除时保持冻结时的选择
+func (w *World) selectTerrainLOD(viewProjection mgl32.Mat4) {
+	if w.cullingFrozen {
+		return
+	}
+	ForEachOfType(w, func(t *terrain.Terrain) {
+		t.SelectLOD(w.Camera.Position, viewProjection, config.Config.FrustumCulling)
+	})
+}
+
+// renderReflection 绘制地面和水面的反射及折射纹理
+func (w *World) renderReflection(projection, view mgl32.Mat4, fbSize [2]float32) {
+	draw := func(projection, view mgl32.Mat4, eyePosition *mgl32.Vec3) {
+		// SSAO 纹理对应主视角, 反射中不使用
+		ssao := config.Config.SSAO.Enable
+		config.Config.SSAO.Enable = false
+		w.renderQueue.FlushReflection(projection, view, eyePosition, w.Lights)
+		config.Config.SSAO.Enable = ssao
+	}
+	for _, renderObj := range w.renderObjs {
+		reflectiveObj, ok := renderObj.(model.ReflectiveObj)
+		if !ok {
+			continue
+		}
+		err := reflectiveObj.RenderReflection(int32(fbSize[0]), int32(fbSize[1]), projection, view, w.Camera.Position, draw)
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
+// screenRay 窗口坐标对应的世界空间拾取射线
+func (w *World) screenRay(pos [2]float32, displaySize [2]float32, projection, view mgl32.Mat4) (geometry.Ray, bool) {
+	fbSize := w.platform.FramebufferSize()
+	x := pos[0] * fbSize[0] / displaySize[0]
+	y := fbSize[1] - pos[1]*fbSize[1]/displaySize[1]
+
+	near, err := mgl32.UnProject(mgl32.Vec3{x, y, 0}, view, projection, 0, 0, int(fbSize[0]), int(fbSize[1]))
+	if err != nil {
+		return geometry.Ray{}, false
+	}
+	far, err := mgl32.UnProject(mgl32.Vec3{x, y, 1}, view, projection, 0, 0, int(fbSize[0]), int(fbSize[1]))
+	if err != nil {
+		return geometry.Ray{}, false
+	}
+	return geometry.Ray{Origin: near, Direction: far.Sub(near).Normalize()}, true
+}
+
+// updatePaint 按下鼠标时选择射线命中的最近模型, 拖动时在该模型上绘制
+func (w *World) updatePaint(displaySize [2]float32, projection, view mgl32.Mat4) {
+	pos, newStroke, ok := w.paintTool.PendingStroke()
+	if !ok {
+		return
+	}
+	ray, ok := w.screenRay(pos, displaySize, projection, view)
+	if !ok {
+		return
+	}
+
+	if newStroke || w.paintTool.Target() == nil {
+		var nearest *model.Model
+		var nearestDistance float32
+		ForEachOfType(w, func(m *model.Model) {
+			if hit, ok := m.IntersectRay(ray); ok && (nearest == nil || hit.Distance < nearestDistance) {
+				nearest, nearestDistance = m, hit.Distance
+			}
+		})
+		if nearest == nil {
+			return
+		}
+		w.paintTool.SetTarget(nearest)
+	}
+
+	target := w.paintTool.Target()
+	if hit, ok := target.IntersectRay(ray); ok {
+		w.paintTool.Paint(target, hit.Mesh, hit.TexCoords)
+	}
+}
+
+// updateSculpt 按下鼠标时选择射线命中的最近地形, 拖动时在该地形上雕刻
+func (w *World) updateSculpt(displaySize [2]float32, projection, view mgl32.Mat4, dt float32) {
+	pos, newStroke, ok := w.sculptTool.PendingStroke()
+	if !ok {
+		return
+	}
+	ray, ok := w.screenRay(pos, displaySize, projection, view)
+	if !ok {
+		return
+	}
+
+	if newStroke || w.sculptTool.Target() == nil {
+		var nearest *terrain.Terrain
+		var nearestDistance float32
+		ForEachOfType(w, func(t *terrain.Terrain) {
+			if p, ok := t.IntersectRay(ray); ok {
+				if d := p.Sub(ray.Origin).Len(); nearest == nil || d < nearestDistance {
+					nearest, nearestDistance = t, d
+				}
+			}
+		})
+		if nearest == nil {
+			return
+		}
+		w.sculptTool.SetTarget(nearest)
+	}
+
+	target := w.sculptTool.Target()
+	if p, ok := target.IntersectRay(ray); ok {
+		w.sculptTool.Sculpt(target, p, newStroke, dt)
+	}
+}
+
+// buildSpline 处理样条工具的构建请求, 生成的网格加入场景和模型列表
+func (w *World) buildSpline() {
+	if !w.splineTool.TakeBuildRequest() {
+		return
+	}
+	obj, err := w.splineTool.Build(w.terrainHeightAt)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	w.addRenderObj(obj, obj.Name, obj.Id, nil)
+}
+
+// bakeLightmaps 处理界面的烘焙请求
+func (w *World) bakeLightmaps() {
+	if !w.lightmapBaker.TakeRequest() {
+		return
+	}
+	if _, err := w.BakeLightmaps(); err != nil {
+		logger.Error(err)
+	}
+}
+
+// BakeLightmaps 为配置了 <lightmap> 的模型烘焙光照贴图并立即使用, 返回写入的文件
+// 场景较大时需要数秒到数分钟, 烘焙期间界面不响应
+func (w *World) BakeLightmaps() ([]string, error) {
+	w.WaitLoading()
+	// 还没有运行过更新时模型矩阵尚未计算
+	for _, renderObj := range w.renderObjs {
+		renderObj.Update(0)
+	}
+	return w.lightmapBaker.Bake(w.renderObjs, w.Lights)
+}
+
+// loadEnvironment 加载环境贴图并估计太阳光的方向, 颜色和强度
+func (w *World) loadEnvironment() {
+	file := w.xmlWorld.XMLEnvironment.File
+	if file == "" {
+		return
+	}
+	env, err := texture.LoadHDRImage(vfs.Path(file))
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	sun, ok := light.EstimateSun(env)
+	if !ok {
+		logger.Error(fmt.Errorf("no sun found in environment %s", file))
+		return
+	}
+	w.Sun.SetEstimate(sun)
+}
+
+// loadAudioBank 读取场景配置中的音频事件库
+func (w *World) loadAudioBank() {
+	file := w.xmlWorld.XMLAudio.Bank
+	if file == "" {
+		return
+	}
+	if err := w.Audio.LoadBank(vfs.Path(file)); err != nil {
+		logger.Error(err)
+	}
+}
+
+// loadInputBindings 读取场景配置中的按键绑定, 覆盖默认绑定, 没有配置时读取保存过的 resource/input.xml
+func (w *World) loadInputBindings() {
+	file := w.inputBindingsFile()
+	if w.xmlWorld.XMLInput.Bindings == "" && !vfs.Exists(file) {
+		return
+	}
+	inputMap, err := config.LoadInputMap(file)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if err := w.Actions.Load(inputMap); err != nil {
+		logger.Error(err)
+	}
+}
+
+// inputBindingsFile 按键绑定文件, 没有配置时保存到 resource/input.xml
+func (w *World) inputBindingsFile() string {
+	file := w.xmlWorld.XMLInput.Bindings
+	if file == "" {
+		file = defaultInputBindingsFile
+	}
+	return vfs.Path(file)
+}
+
+// initProfiler 按场景配置开启性能分析并设置各范围的预算
+func (w *World) initProfiler() {
+	w.profiler = profiler.NewProfiler()
+	xmlProfiler := w.xmlWorld.XMLProfiler
+	if xmlProfiler == nil {
+		return
+	}
+	w.profiler.Enabled = xmlProfiler.XMLEnable
+	if xmlProfiler.XMLFrames > 0 {
+		w.profiler.AlertFrames = min(xmlProfiler.XMLFrames, profiler.MaxAlertFrames)
+	}
+	for _, budget := range xmlProfiler.XMLBudgets {
+		w.profiler.SetBudget(budget.Scope, budget.Ms)
+	}
+}
+
+// prefabs 场景配置中的模型定义作为放置工具的预制体
+func (w *World) prefabs() []config.XmlModel {
+	prefabs := make([]config.XmlModel, 0)
+	for _, xmlModel := range w.xmlWorld.XMLModels.XMLModels {
+		if xmlModel.XmlResourceClass == "Model" {
+			prefabs = append(prefabs, xmlModel)
+		}
+	}
+	return prefabs
+}
+
+// updateGizmo 手柄跟随模型列表中选中的模型, 根据光标射线更新悬停和拖动
+func (w *World) updateGizmo(displaySize [2]float32, projection, view mgl32.Mat4) {
+	w.gizmoTool.SetTarget(w.uiWindowMain.SelectedModel())
+	cursor, ok := w.gizmoTool.Cursor()
+	if !ok {
+		return
+	}
+	ray, ok := w.screenRay(cursor, displaySize, projection, view)
+	if !ok {
+		return
+	}
+	w.gizmoTool.Update(ray, w.Camera.Position)
+}
+
+// updateLightGizmos 根据光标射线更新悬停的灯光图标, 点击图标时在灯光面板中编辑该灯光
+// 拖动模型手柄时不选择灯光
+func (w *World) updateLightGizmos(displaySize [2]float32, projection, view mgl32.Mat4) {
+	w.lightGizmos.SetSelected(w.uiWindowMain.SelectedLight())
+	cursor, ok := w.lightGizmos.Cursor()
+	if !ok || w.gizmoTool.Dragging() {
+		return
+	}
+	ray, ok := w.screenRay(cursor, displaySize, projection, view)
+	if !ok {
+		return
+	}
+	w.lightGizmos.Update(ray, w.Camera.Position, w.Lights)
+	if l, ok := w.lightGizmos.TakeClick(); ok {
+		w.uiWindowMain.SelectLight(l)
+	}
+}
+
+// updatePlacement 把预览模型移动到光标射线与地面或地形的交点, 点击时在该位置创建模型
+func (w *World) updatePlacement(displaySize [2]float32, projection, view mgl32.Mat4) {
+	w.placeValid = false
+	cursor, ok := w.placeTool.Cursor()
+	if !ok {
+		w.placeTool.TakeClick()
+		return
+	}
+	prefab, ok := w.placeTool.SelectedPrefab()
+	if !ok {
+		return
+	}
+	if w.placePreviewIndex != w.placeTool.Selected {
+		if w.placePreview != nil {
+			w.placePreview.Dispose()
+		}
+		preview, err := model.NewModel(prefab)
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		w.placePreview = &preview
+		w.placePreviewIndex = w.placeTool.Selected
+	}
+
+	ray, ok := w.screenRay(cursor, displaySize, projection, view)
+	if !ok {
+		return
+	}
+	hit, ok := w.placementHit(ray)
+	if !ok {
+		w.placeTool.TakeClick()
+		return
+	}
+	position := placeOnSurface(w.placePreview, hit, w.placeTool.Rotation)
+	w.placeValid = true
+
+	if !w.placeTool.TakeClick() {
+		return
+	}
+	xmlModel, ok := w.placeTool.Instantiate(position)
+	if !ok {
+		return
+	}
+	obj, err := model.NewModel(xmlModel)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	obj.SetRotate(w.placeTool.Rotation)
+	obj.Update(0)
+	w.addRenderObj(&obj, obj.Name, obj.Id, xmlModel.Tags)
+	w.xmlWorld.XMLModels.XMLModels = append(w.xmlWorld.XMLModels.XMLModels, xmlModel)
+}
+
+// placementHit 射线与地形和地面网格最近的交点
+func (w *World) placementHit(ray geometry.Ray) (mgl32.Vec3, bool) {
+	var hit mgl32.Vec3
+	var best float32
+	found := false
+	consider := func(p mgl32.Vec3) {
+		if d := p.Sub(ray.Origin).Len(); !found || d < best {
+			hit, best, found = p, d, true
+		}
+	}
+
+	ForEachOfType(w, func(t *terrain.Terrain) {
+		if p, ok := t.IntersectRay(ray); ok {
+			consider(p)
+		}
+	})
+
+	if w.ground != nil && ray.Direction.Y() != 0 {
+		height := w.ground.Position.Y()
+		if d := (height - ray.Origin.Y()) / ray.Direction.Y(); d > 0 {
+			p := ray.Origin.Add(ray.Direction.Mul(d))
+			if mgl32.Abs(p.X()) <= mesh.GroundHalfWidth && mgl32.Abs(p.Z()) <= mesh.GroundHalfWidth {
+				consider(p)
+			}
+		}
+	}
+	return hit, found
+}
+
+// placeOnSurface 旋转模型并使包围盒底部贴合到表面上的点, 返回模型位置
+func placeOnSurface(m *model.Model, surface mgl32.Vec3, rotation float32) mgl32.Vec3 {
+	m.SetRotate(rotation)
+	m.SetPosition(surface)
+	m.Update(0)
+	position := surface.Add(mgl32.Vec3{0, surface.Y() - m.WorldBounds().Min.Y(), 0})
+	m.SetPosition(position)
+	m.Update(0)
+	return position
+}
+
+// terrainHeightAt 世界坐标 (x, z) 处最高的地形高度
+func (w *World) terrainHeightAt(x, z float32) (float32, bool) {
+	var height float32
+	found := false
+	ForEachOfType(w, func(t *terrain.Terrain) {
+		if h, ok := t.HeightAt(x, z); ok && (!found || h > height) {
+			height, found = h, true
+		}
+	})
+	return height, found
+}
+
+// surfaceHeightAt 地形高度, 不在地形上时使用地面网格范围内的地面高度
+func (w *World) surfaceHeightAt(x, z float32) (float32, bool) {
+	if h, ok := w.terrainHeightAt(x, z); ok {
+		return h, true
+	}
+	if w.ground == nil {
+		return 0, false
+	}
+	offset := mgl32.Vec3{x, 0, z}.Sub(w.ground.Position)
+	if mgl32.Abs(offset.X()) > mesh.GroundHalfWidth || mgl32.Abs(offset.Z()) > mesh.GroundHalfWidth {
+		return 0, false
+	}
+	return w.ground.Position.Y(), true
+}
+
+// pickPosition 读取点击位置的深度并反投影得到世界坐标, 点击在背景上时返回 false
+func (w *World) pickPosition(click [2]float32, displaySize [2]float32, projection, view mgl32.Mat4, postProcess bool) (mgl32.Vec3, bool) {
+	fbSize := w.platform.FramebufferSize()
+	x := click[0] * fbSize[0] / displaySize[0]
+	y := fbSize[1] - click[1]*fbSize[1]/displaySize[1]
+
+	// 开启后处理时场景深度保存在效果链的场景缓冲中
+	var readFbo uint32
+	if postProcess {
+		readFbo = w.PostProcess.SceneBuffer().Fbo
+	}
+	var depth float32
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, readFbo)
+	gl.ReadPixels(int32(x), int32(y), 1, 1, gl.DEPTH_COMPONENT, gl.FLOAT, gl.Ptr(&depth))
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+	if depth >= 1.0 {
+		return mgl32.Vec3{}, false
+	}
+
+	p, err := mgl32.UnProject(mgl32.Vec3{x, y, depth}, view, projection, 0, 0, int(fbSize[0]), int(fbSize[1]))
+	if err != nil {
+		logger.Error(err)
+		return mgl32.Vec3{}, false
 	}
+	return p, true
 }
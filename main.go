@@ -1,13 +1,121 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"io/fs"
+	"path/filepath"
+
 	"github.com/huangxiaobo/toy-engine/engine"
+	"github.com/huangxiaobo/toy-engine/engine/config"
+	"github.com/huangxiaobo/toy-engine/engine/gldebug"
+	"github.com/huangxiaobo/toy-engine/engine/loader"
+	"github.com/huangxiaobo/toy-engine/engine/logger"
+	"github.com/huangxiaobo/toy-engine/engine/shader"
+	"github.com/huangxiaobo/toy-engine/engine/vfs"
+)
+
+// 资源按挂载顺序查找, 后挂载的优先: 内嵌的默认资源 < 资源包 < resource 目录 < 项目目录
+// 发布时资源打包为一个资源包, 开发时 resource 目录中的文件优先
+var (
+	archive = flag.String("archive", "resource.pak", "resource archive (.zip/.pak), loose files under resource/ override it")
+	// 多个目录用系统的路径列表分隔符分隔, 排在前面的优先
+	resource = flag.String("resource", "resource", "resource directories mounted at resource/, separated by the path list separator, earlier ones take precedence")
+	// 项目目录下的 resource 目录和 resource.pak 覆盖引擎的资源
+	project = flag.String("project", "", "project directory whose resource/ and resource.pak override the engine resources")
+	// 场景文件, 例如角色群压力测试 ./resource/crowd.xml
+	worldFile = flag.String("world", "./resource/world.xml", "world description file")
+	// 转台渲染, 输出模型的缩略图后退出, 不打开编辑器
+	turntable       = flag.String("turntable", "", "render a turntable of the model file to ./output and exit")
+	turntableFrames = flag.Int("turntable-frames", engine.DefaultTurntableFrames, "number of turntable frames")
+	turntableSize   = flag.Int("turntable-size", engine.DefaultTurntableSize, "turntable frame size in pixels")
+	turntableSheet  = flag.Bool("turntable-sheet", false, "write the turntable frames as a single sprite sheet")
+	// 烘焙场景中配置了 <lightmap> 的模型的光照贴图后退出
+	bake = flag.Bool("bake", false, "bake the lightmaps of the world and exit")
+	// 模型在后台加载, 启动时显示进度
+	asyncLoad = flag.Bool("async-load", false, "load models in the background and show a progress bar instead of blocking startup")
+	// 监视资源文件, 修改后重新加载
+	hotReload = flag.Bool("hot-reload", false, "watch shader sources, model files and the world file and reload them when they change")
+	// 贴图的默认采样设置
+	anisotropy = flag.Float64("anisotropy", float64(config.Config.Texture.Anisotropy), "anisotropic filtering level of textures, 1 disables it")
+	srgb       = flag.Bool("srgb", config.Config.Texture.SRGB, "upload diffuse and emissive textures as sRGB")
+	// 模型导入结果的缓存目录
+	meshCache = flag.String("mesh-cache", loader.CacheDir, "directory of the binary mesh cache, empty disables it")
+	// 链接后的着色器程序的缓存目录
+	shaderCache = flag.String("shader-cache", shader.CacheDir, "directory of the linked shader program cache, empty disables it")
+	// 编译前用 glslangValidator 检查着色器, 错误定位到源文件的行号
+	strictShaders = flag.Bool("strict-shaders", false, "validate shaders with glslangValidator before compiling, errors use the error shader")
+	glslValidator = flag.String("glsl-validator", shader.Validator, "path of glslangValidator used by -strict-shaders")
+	// GL 调试模式, 驱动的调试消息和 glGetError 的错误写入日志
+	glDebug         = flag.Bool("gl-debug", false, "create a debug GL context, log driver debug messages and check glGetError after each render stage")
+	glDebugSeverity = flag.String("gl-debug-severity", gldebug.SeverityNames[gldebug.MinSeverity], "lowest severity of logged GL debug messages: notification, low, medium or high")
 )
 
 func main() {
+	flag.Parse()
+	loader.CacheDir = *meshCache
+	shader.CacheDir = *shaderCache
+	shader.Strict = *strictShaders
+	shader.Validator = *glslValidator
+	gldebug.Enabled = *glDebug
+	if severity, err := gldebug.ParseSeverity(*glDebugSeverity); err != nil {
+		logger.Error(err)
+	} else {
+		gldebug.MinSeverity = severity
+	}
+	config.Config.AsyncLoading = *asyncLoad
+	config.Config.HotReload = *hotReload
+	config.Config.Texture.Anisotropy = float32(*anisotropy)
+	config.Config.Texture.SRGB = *srgb
 
-	world := engine.NewWorld("./resource/world.xml")
+	vfs.MountFS("resource", defaultResource())
+	if err := vfs.MountArchive("", *archive); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Error(err)
+	}
+	if err := vfs.MountSearchPath(vfs.Root, filepath.SplitList(*resource)); err != nil {
+		logger.Error(err)
+	}
+	if *project != "" {
+		if err := vfs.MountProject(*project); err != nil {
+			logger.Error(err)
+		}
+	}
+	defer vfs.Unmount()
+	for _, m := range vfs.Mounted() {
+		logger.Info("mount " + m)
+	}
+
+	if *turntable != "" {
+		files, err := engine.RenderTurntable(*turntable, engine.TurntableOptions{
+			Frames:      *turntableFrames,
+			Width:       int32(*turntableSize),
+			Height:      int32(*turntableSize),
+			Elevation:   20,
+			Samples:     4,
+			SpriteSheet: *turntableSheet,
+		})
+		if err != nil {
+			logger.Error(err)
+		}
+		for _, file := range files {
+			logger.Info("turntable " + file)
+		}
+		return
+	}
+
+	world := engine.NewWorld(*worldFile)
 	defer world.Destroy()
 
+	if *bake {
+		files, err := world.BakeLightmaps()
+		if err != nil {
+			logger.Error(err)
+		}
+		for _, file := range files {
+			logger.Info("lightmap " + file)
+		}
+		return
+	}
+
 	world.Run()
 }
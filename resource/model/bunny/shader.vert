@@ -9,14 +9,22 @@ uniform mat4 gWorld;
 layout (location = 0) in vec3 position;
 layout (location = 1) in vec3 vertcolor;
 layout (location = 2) in vec3 normal;
+layout (location = 3) in vec2 texcoord;
+layout (location = 6) in vec2 texcoord2;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
 
 
 out VsOut {
     vec3 WorldPos0;
     vec3 Normal0;
+    vec2 TexCoord0;
+    vec2 TexCoord1;
 } v2f;
 
 void main() {
+    mat4 model = gInstanced ? model * instanceMatrix : model;
     gl_Position = projection * view * model * vec4(position, 1);
 
 
@@ -31,4 +39,6 @@ void main() {
     v2f.WorldPos0 = (model * position_h).xyz;
     // 将法线向量转化到直接坐标系
     v2f.Normal0 = normalize(normalmatrix * normal);
+    v2f.TexCoord0 = texcoord;
+    v2f.TexCoord1 = texcoord2;
 }
\ No newline at end of file
@@ -0,0 +1,83 @@
+#version 330
+
+uniform vec3 gViewPos;
+
+// 平面反射
+uniform sampler2D gReflection;
+uniform vec2 gScreenSize;
+uniform vec2 gTexelSize;
+uniform float gReflectionStrength;
+uniform float gReflectionBlur;// 模糊半径(反射纹理像素)
+uniform float gReflectionFresnel;// 菲涅尔效果的权重
+
+// 雾
+struct Fog {
+    int Enable;
+    int Mode;// 0 线性, 1 指数, 2 指数平方
+    vec3 Color;
+    float Density;
+    float Start;
+    float End;
+    float Height;// 高度雾基准高度
+    float HeightFalloff;// 高于基准高度后的衰减速率, 0 表示不使用高度雾
+};
+
+uniform Fog gFog;
+
+in VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec3 Color0;
+} v2f;
+
+out vec4 color;
+
+// ApplyFog 按到观察点的距离和高度混合雾的颜色
+vec3 ApplyFog(vec3 Color, vec3 WorldPos) {
+    if (gFog.Enable == 0) {
+        return Color;
+    }
+    float Distance = max(length(gViewPos - WorldPos) - gFog.Start, 0.0);
+    float Factor;
+    if (gFog.Mode == 0) {
+        Factor = Distance / max(gFog.End - gFog.Start, 0.0001);
+    } else if (gFog.Mode == 1) {
+        Factor = 1.0 - exp(-gFog.Density * Distance);
+    } else {
+        float d = gFog.Density * Distance;
+        Factor = 1.0 - exp(-d * d);
+    }
+    if (gFog.HeightFalloff > 0.0) {
+        Factor *= exp(-gFog.HeightFalloff * max(WorldPos.y - gFog.Height, 0.0));
+    }
+    return mix(Color, gFog.Color, clamp(Factor, 0.0, 1.0));
+}
+
+// SampleReflection 反射纹理与屏幕对齐, 按屏幕坐标做 3x3 加权模糊
+vec3 SampleReflection(vec2 uv) {
+    vec3 sum = vec3(0.0);
+    float weightSum = 0.0;
+    for (int x = -1; x <= 1; x++) {
+        for (int y = -1; y <= 1; y++) {
+            float weight = 1.0 / (1.0 + float(x * x + y * y));
+            vec2 offset = vec2(x, y) * gTexelSize * gReflectionBlur;
+            sum += texture(gReflection, uv + offset).rgb * weight;
+            weightSum += weight;
+        }
+    }
+    return sum / weightSum;
+}
+
+void main() {
+    vec2 uv = gl_FragCoord.xy / gScreenSize;
+    vec3 reflection = SampleReflection(uv);
+
+    // Schlick 近似, 掠射角反射更强
+    vec3 N = normalize(v2f.Normal0);
+    vec3 V = normalize(gViewPos - v2f.WorldPos0);
+    float cosTheta = clamp(dot(N, V), 0.0, 1.0);
+    float fresnel = 0.04 + 0.96 * pow(1.0 - cosTheta, 5.0);
+    float reflectivity = gReflectionStrength * mix(1.0, fresnel, gReflectionFresnel);
+
+    color = vec4(ApplyFog(reflection, v2f.WorldPos0), reflectivity);
+}
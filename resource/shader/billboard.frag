@@ -0,0 +1,27 @@
+#version 330
+
+uniform sampler2D gTexture;
+uniform bool gHasTexture;
+uniform vec4 gColor;
+uniform float gAlphaCutoff;
+// 序列帧在图集中的偏移(xy)和大小(zw), 不使用序列帧时为整张贴图
+uniform vec4 gFrame;
+uniform vec4 gNextFrame;
+uniform float gFrameBlend;
+
+in vec2 texCoord;
+
+out vec4 color;
+
+void main() {
+    vec4 c = gColor;
+    if (gHasTexture) {
+        vec4 frame = texture(gTexture, gFrame.xy + texCoord * gFrame.zw);
+        vec4 next = texture(gTexture, gNextFrame.xy + texCoord * gNextFrame.zw);
+        c *= mix(frame, next, gFrameBlend);
+    }
+    if (c.a < gAlphaCutoff) {
+        discard;
+    }
+    color = c;
+}
@@ -0,0 +1,37 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+
+uniform vec3 gCenter;
+uniform vec2 gSize;
+uniform bool gAxisLock;
+uniform vec3 gAxis;
+uniform vec3 gEyeWorldPos;
+
+layout (location = 0) in vec2 corner;
+
+out vec2 texCoord;
+
+void main() {
+    vec3 right;
+    vec3 up;
+    if (gAxisLock) {
+        // 绕锁定轴旋转, 使四边形法线尽量指向相机
+        up = normalize(gAxis);
+        vec3 toEye = gEyeWorldPos - gCenter;
+        right = cross(up, toEye);
+        if (dot(right, right) < 1e-8) {
+            // 沿锁定轴观察时退化, 使用相机的右方向
+            right = vec3(view[0][0], view[1][0], view[2][0]);
+        }
+        right = normalize(right);
+    } else {
+        // 观察矩阵的前两行是相机在世界空间中的右和上方向
+        right = vec3(view[0][0], view[1][0], view[2][0]);
+        up = vec3(view[0][1], view[1][1], view[2][1]);
+    }
+
+    vec3 position = gCenter + right * corner.x * gSize.x + up * corner.y * gSize.y;
+    texCoord = vec2(corner.x + 0.5, 0.5 - corner.y);
+    gl_Position = projection * view * vec4(position, 1);
+}
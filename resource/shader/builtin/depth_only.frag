@@ -0,0 +1,8 @@
+#version 330
+// 内置的只写深度的着色器, 用于深度预渲染和遮挡物, 颜色写入被忽略
+
+out vec4 color;
+
+void main() {
+    color = vec4(0.0);
+}
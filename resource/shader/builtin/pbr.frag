@@ -0,0 +1,113 @@
+#version 330
+// 内置的基于物理的着色器, Cook-Torrance 镜面反射(GGX 法线分布, Smith 几何项, Schlick 菲涅尔)
+// 使用高光工作流: 材质的镜面反射颜色作为 F0, 粗糙度由光泽换算为 sqrt(2 / (Shininess + 2))
+
+uniform vec3 gViewPos;
+
+struct Attenuation
+{
+    float Constant;
+    float Linear;
+    float Exp;
+};
+
+struct PointLight {
+    vec3    Color;
+    vec3    Position;
+
+    float   AmbientIntensity;
+    float   DiffuseIntensity;
+    Attenuation Atten;
+};
+
+uniform PointLight gLight[8];
+uniform int gLightNum;
+
+struct Material {
+    vec3 AmbientColor;
+    vec3 DiffuseColor;
+    vec3 SpecularColor;
+    float Shininess;
+    float Opacity;
+    vec3 EmissiveColor;
+};
+
+uniform Material gMaterial;
+uniform sampler2D gEmissiveMap;
+uniform int gEmissiveMapEnable;
+uniform mat3 gUVTransform;
+
+uniform sampler2D texture_diffuse1;
+uniform int gDiffuseMapEnable;
+
+in VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec2 TexCoord0;
+    vec2 TexCoord1;
+} v2f;
+
+out vec4 color;
+
+const float PI = 3.14159265359;
+
+vec2 BaseUV() {
+    return (gUVTransform * vec3(v2f.TexCoord0, 1.0)).xy;
+}
+
+vec3 CalcAlbedo() {
+    vec3 albedo = gMaterial.DiffuseColor;
+    if (gDiffuseMapEnable != 0) {
+        albedo *= texture(texture_diffuse1, BaseUV()).rgb;
+    }
+    return albedo;
+}
+
+float DistributionGGX(float NdotH, float roughness) {
+    float a2 = roughness * roughness * roughness * roughness;
+    float d = NdotH * NdotH * (a2 - 1.0) + 1.0;
+    return a2 / max(PI * d * d, 0.0001);
+}
+
+float GeometrySchlickGGX(float NdotX, float roughness) {
+    float k = (roughness + 1.0) * (roughness + 1.0) / 8.0;
+    return NdotX / (NdotX * (1.0 - k) + k);
+}
+
+vec3 FresnelSchlick(float cosTheta, vec3 F0) {
+    return F0 + (1.0 - F0) * pow(1.0 - cosTheta, 5.0);
+}
+
+void main() {
+    vec3 N = normalize(v2f.Normal0);
+    vec3 V = normalize(gViewPos - v2f.WorldPos0);
+    vec3 albedo = CalcAlbedo();
+    vec3 F0 = gMaterial.SpecularColor;
+    float roughness = clamp(sqrt(2.0 / (gMaterial.Shininess + 2.0)), 0.04, 1.0);
+    float NdotV = max(dot(N, V), 0.0001);
+
+    vec3 result = vec3(0.0);
+    for (int i = 0; i < gLightNum; i++) {
+        vec3 L = gLight[i].Position - v2f.WorldPos0;
+        float Distance = length(L);
+        L /= Distance;
+        vec3 H = normalize(V + L);
+        float NdotL = max(dot(N, L), 0.0);
+        float Attenuation = max(gLight[i].Atten.Constant + gLight[i].Atten.Linear * Distance + gLight[i].Atten.Exp * Distance * Distance, 0.0001);
+
+        vec3 F = FresnelSchlick(max(dot(H, V), 0.0), F0);
+        float D = DistributionGGX(max(dot(N, H), 0.0), roughness);
+        float G = GeometrySchlickGGX(NdotV, roughness) * GeometrySchlickGGX(NdotL, roughness);
+        vec3 specular = D * G * F / (4.0 * NdotV * max(NdotL, 0.0001));
+        vec3 kD = vec3(1.0) - F;
+
+        vec3 ambient = gLight[i].Color * gMaterial.AmbientColor * gLight[i].AmbientIntensity;
+        result += (ambient + (kD * albedo / PI + specular) * gLight[i].Color * NdotL * PI) / Attenuation;
+    }
+
+    vec3 emissive = gMaterial.EmissiveColor;
+    if (gEmissiveMapEnable != 0) {
+        emissive *= texture(gEmissiveMap, BaseUV()).rgb;
+    }
+    color = vec4(result + emissive, gMaterial.Opacity);
+}
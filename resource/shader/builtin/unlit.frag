@@ -0,0 +1,32 @@
+#version 330
+// 内置的无光照着色器, 输出材质的漫反射颜色和自发光, 定义 DIFFUSE_MAP 时乘以漫反射贴图
+
+struct Material {
+    vec3 DiffuseColor;
+    float Opacity;
+    vec3 EmissiveColor;
+};
+
+uniform Material gMaterial;
+uniform sampler2D texture_diffuse1;
+uniform int gDiffuseMapEnable;
+uniform mat3 gUVTransform;
+
+in VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec2 TexCoord0;
+    vec2 TexCoord1;
+} v2f;
+
+out vec4 color;
+
+void main() {
+    vec4 baseColor = vec4(gMaterial.DiffuseColor, gMaterial.Opacity);
+#ifdef DIFFUSE_MAP
+    if (gDiffuseMapEnable != 0) {
+        baseColor *= texture(texture_diffuse1, (gUVTransform * vec3(v2f.TexCoord0, 1.0)).xy);
+    }
+#endif
+    color = vec4(baseColor.rgb + gMaterial.EmissiveColor, baseColor.a);
+}
@@ -0,0 +1,48 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+// 蒙皮矩阵, 每行一个实例, 每个矩阵按列占 4 个像素
+uniform sampler2D gBones;
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 color;
+layout (location = 2) in vec3 normal;
+layout (location = 4) in ivec4 boneIds;
+layout (location = 5) in vec4 boneWeights;
+layout (location = 8) in mat4 instanceMatrix;
+layout (location = 12) in vec3 instanceColor;
+
+out VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec3 Color0;
+} v2f;
+
+mat4 BoneMatrix(int bone) {
+    int x = bone * 4;
+    return mat4(
+        texelFetch(gBones, ivec2(x, gl_InstanceID), 0),
+        texelFetch(gBones, ivec2(x + 1, gl_InstanceID), 0),
+        texelFetch(gBones, ivec2(x + 2, gl_InstanceID), 0),
+        texelFetch(gBones, ivec2(x + 3, gl_InstanceID), 0)
+    );
+}
+
+void main() {
+    mat4 skin = mat4(0.0);
+    for (int i = 0; i < 4; i++) {
+        if (boneWeights[i] > 0.0) {
+            skin += BoneMatrix(boneIds[i]) * boneWeights[i];
+        }
+    }
+    mat4 world = model * instanceMatrix * skin;
+    vec4 worldPos = world * vec4(position, 1.0);
+    gl_Position = projection * view * worldPos;
+
+    v2f.WorldPos0 = worldPos.xyz;
+    v2f.Normal0 = mat3(world) * normal;
+    // 白色部分(衣服)使用实例颜色
+    v2f.Color0 = color * mix(vec3(1.0), instanceColor, step(0.99, min(color.r, min(color.g, color.b))));
+}
@@ -0,0 +1,13 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 color;
+
+out vec3 Color0;
+
+void main() {
+    gl_Position = projection * view * vec4(position, 1);
+    Color0 = color;
+}
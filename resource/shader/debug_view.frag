@@ -0,0 +1,81 @@
+#version 330
+// 调试视图, 由宏选择模式:
+// DEBUG_NORMALS, DEBUG_UV_CHECKER, DEBUG_DEPTH, DEBUG_OVERDRAW, DEBUG_LIGHTING
+
+uniform vec3 gViewPos;
+uniform vec2 gClip;// 近远裁剪面
+
+struct Attenuation
+{
+    float Constant;
+    float Linear;
+    float Exp;
+};
+
+struct PointLight {
+    vec3    Color;
+    vec3    Position;
+
+    float   AmbientIntensity;
+    float   DiffuseIntensity;
+    Attenuation Atten;
+};
+
+uniform PointLight gLight[8];
+uniform int gLightNum;
+
+in VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec2 TexCoord0;
+    float ViewDepth0;
+} v2f;
+
+out vec4 color;
+
+// 背面朝向相机时翻转法线, 单面网格的背面也能正确显示
+vec3 FacingNormal() {
+    vec3 N = normalize(v2f.Normal0);
+    return gl_FrontFacing ? N : -N;
+}
+
+// CalcLighting 白色材质的漫反射和高光
+vec3 CalcLighting() {
+    vec3 N = FacingNormal();
+    vec3 V = normalize(gViewPos - v2f.WorldPos0);
+    vec3 result = vec3(0.0);
+    for (int i = 0; i < gLightNum; i++) {
+        vec3 L = gLight[i].Position - v2f.WorldPos0;
+        float Distance = length(L);
+        L /= Distance;
+        float Attenuation = gLight[i].Atten.Constant + gLight[i].Atten.Linear * Distance + gLight[i].Atten.Exp * Distance * Distance;
+        vec3 ambient = gLight[i].Color * gLight[i].AmbientIntensity;
+        float diffuse = max(dot(N, L), 0.0);
+        float specular = pow(max(dot(N, normalize(L + V)), 0.0), 32.0) * 0.5;
+        result += ambient + gLight[i].Color * gLight[i].DiffuseIntensity * (diffuse + specular) / max(Attenuation, 0.0001);
+    }
+    return result;
+}
+
+void main() {
+#if defined(DEBUG_NORMALS)
+    color = vec4(FacingNormal() * 0.5 + 0.5, 1.0);
+#elif defined(DEBUG_UV_CHECKER)
+    // 8x8 棋盘格, 颜色随 UV 变化, 便于看出拉伸和接缝
+    vec2 uv = fract(v2f.TexCoord0);
+    vec2 cell = floor(uv * 8.0);
+    float checker = mod(cell.x + cell.y, 2.0);
+    color = vec4(mix(vec3(0.15), vec3(uv, 1.0 - uv.x), checker), 1.0);
+#elif defined(DEBUG_DEPTH)
+    float depth = clamp((v2f.ViewDepth0 - gClip.x) / (gClip.y - gClip.x), 0.0, 1.0);
+    // 开方使近处的层次更明显
+    color = vec4(vec3(1.0 - sqrt(depth)), 1.0);
+#elif defined(DEBUG_OVERDRAW)
+    // 加法混合, 每次绘制累加一层
+    color = vec4(0.1, 0.04, 0.02, 1.0);
+#elif defined(DEBUG_LIGHTING)
+    color = vec4(CalcLighting(), 1.0);
+#else
+    color = vec4(1.0, 0.0, 1.0, 1.0);
+#endif
+}
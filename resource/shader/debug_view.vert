@@ -0,0 +1,30 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+layout (location = 0) in vec3 position;
+layout (location = 2) in vec3 normal;
+layout (location = 3) in vec2 texCoord;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
+
+out VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec2 TexCoord0;
+    float ViewDepth0;
+} v2f;
+
+void main() {
+    mat4 world = gInstanced ? model * instanceMatrix : model;
+    vec4 worldPos = world * vec4(position, 1.0);
+    vec4 viewPos = view * worldPos;
+    gl_Position = projection * viewPos;
+
+    v2f.WorldPos0 = worldPos.xyz;
+    v2f.Normal0 = transpose(inverse(mat3(world))) * normal;
+    v2f.TexCoord0 = texCoord;
+    v2f.ViewDepth0 = -viewPos.z;
+}
@@ -0,0 +1,8 @@
+#version 330
+// 编译失败的着色器显示为品红色, 一眼就能看出
+
+out vec4 color;
+
+void main() {
+    color = vec4(1.0, 0.0, 1.0, 1.0);
+}
@@ -0,0 +1,15 @@
+#version 330
+// 编译失败的着色器的替代程序, 使用模型的顶点格式
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+layout (location = 0) in vec3 position;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
+
+void main() {
+    mat4 world = gInstanced ? model * instanceMatrix : model;
+    gl_Position = projection * view * world * vec4(position, 1);
+}
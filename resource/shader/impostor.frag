@@ -0,0 +1,162 @@
+#version 330
+
+uniform vec3 gViewPos;
+
+struct Attenuation
+{
+    float Constant;
+    float Linear;
+    float Exp;
+};
+
+struct PointLight {
+    vec3    Color;
+    vec3    Position;
+
+    float   AmbientIntensity;
+    float   DiffuseIntensity;
+    Attenuation Atten;
+
+    int     ShadowIndex;// 阴影贴图序号, -1 表示没有阴影
+    float   ShadowFar;
+    float   ShadowBias;
+};
+
+uniform PointLight gLight[8];
+uniform int gLightNum;
+
+// 点光源阴影, 记录到光源的距离除以 ShadowFar
+uniform samplerCube gShadowMap0;
+uniform samplerCube gShadowMap1;
+
+// 雾
+struct Fog {
+    int Enable;
+    int Mode;// 0 线性, 1 指数, 2 指数平方
+    vec3 Color;
+    float Density;
+    float Start;
+    float End;
+    float Height;// 高度雾基准高度
+    float HeightFalloff;// 高于基准高度后的衰减速率, 0 表示不使用高度雾
+};
+
+uniform Fog gFog;
+
+uniform sampler2D gAlbedo;
+uniform sampler2D gNormal;
+uniform vec3 gColor;
+
+in VsOut {
+    vec3 WorldPos0;
+    vec2 TexCoord0;
+    vec2 Rotation0;
+    float Blend0;
+    float Fade0;
+} v2f;
+
+out vec4 color;
+
+// 4x4 Bayer 矩阵, 淡出时按屏幕位置抖动镂空
+const float BAYER[16] = float[](
+    0.0, 8.0, 2.0, 10.0,
+    12.0, 4.0, 14.0, 6.0,
+    3.0, 11.0, 1.0, 9.0,
+    15.0, 7.0, 13.0, 5.0
+);
+
+// ApplyFog 按到观察点的距离和高度混合雾的颜色
+vec3 ApplyFog(vec3 Color, vec3 WorldPos) {
+    if (gFog.Enable == 0) {
+        return Color;
+    }
+    float Distance = max(length(gViewPos - WorldPos) - gFog.Start, 0.0);
+    float Factor;
+    if (gFog.Mode == 0) {
+        Factor = Distance / max(gFog.End - gFog.Start, 0.0001);
+    } else if (gFog.Mode == 1) {
+        Factor = 1.0 - exp(-gFog.Density * Distance);
+    } else {
+        float d = gFog.Density * Distance;
+        Factor = 1.0 - exp(-d * d);
+    }
+    if (gFog.HeightFalloff > 0.0) {
+        Factor *= exp(-gFog.HeightFalloff * max(WorldPos.y - gFog.Height, 0.0));
+    }
+    return mix(Color, gFog.Color, clamp(Factor, 0.0, 1.0));
+}
+
+// 20 个方向的偏移, 对立方体阴影做 PCF
+const vec3 SHADOW_OFFSETS[20] = vec3[](
+    vec3(1, 1, 1), vec3(1, -1, 1), vec3(-1, -1, 1), vec3(-1, 1, 1),
+    vec3(1, 1, -1), vec3(1, -1, -1), vec3(-1, -1, -1), vec3(-1, 1, -1),
+    vec3(1, 1, 0), vec3(1, -1, 0), vec3(-1, -1, 0), vec3(-1, 1, 0),
+    vec3(1, 0, 1), vec3(-1, 0, 1), vec3(1, 0, -1), vec3(-1, 0, -1),
+    vec3(0, 1, 1), vec3(0, -1, 1), vec3(0, -1, -1), vec3(0, 1, -1)
+);
+
+float SampleShadowMap(int Index, vec3 Dir) {
+    if (Index == 0) {
+        return texture(gShadowMap0, Dir).r;
+    }
+    return texture(gShadowMap1, Dir).r;
+}
+
+// CalcShadow 比较到光源的距离和阴影贴图中的最近距离, 1 为完全照亮, 0 为完全在阴影中
+float CalcShadow(PointLight Light, vec3 WorldPos) {
+    if (Light.ShadowIndex < 0) {
+        return 1.0;
+    }
+    vec3 Dir = WorldPos - Light.Position;
+    float Distance = length(Dir);
+    if (Distance >= Light.ShadowFar) {
+        return 1.0;
+    }
+    // 离相机越远采样半径越大, 远处的阴影边缘更柔和
+    float Radius = (1.0 + length(gViewPos - WorldPos) / Light.ShadowFar) * 0.04;
+    float Lit = 0.0;
+    for (int i = 0; i < 20; i++) {
+        float Closest = SampleShadowMap(Light.ShadowIndex, Dir + SHADOW_OFFSETS[i] * Radius) * Light.ShadowFar;
+        Lit += Distance - Light.ShadowBias > Closest ? 0.0 : 1.0;
+    }
+    return Lit / 20.0;
+}
+
+// CalcLight 漫反射, 与植被网格一样法线偏向上方
+vec3 CalcLight(vec3 albedo, vec3 N) {
+    N = normalize(mix(N, vec3(0.0, 1.0, 0.0), 0.5));
+
+    vec3 result = vec3(0.0);
+    for (int i = 0; i < gLightNum; i++) {
+        vec3 L = gLight[i].Position - v2f.WorldPos0;
+        float Distance = length(L);
+        L /= Distance;
+        float Attenuation = gLight[i].Atten.Constant + gLight[i].Atten.Linear * Distance + gLight[i].Atten.Exp * Distance * Distance;
+        vec3 ambient = gLight[i].Color * gLight[i].AmbientIntensity;
+        vec3 diffuse = gLight[i].Color * gLight[i].DiffuseIntensity * max(dot(N, L), 0.0) * CalcShadow(gLight[i], v2f.WorldPos0) / max(Attenuation, 0.0001);
+        result += (ambient + diffuse) * albedo;
+    }
+    return result;
+}
+
+void main() {
+    // 与网格的淡出使用同一个抖动阈值, 网格保留的像素替身正好镂空
+    ivec2 p = ivec2(gl_FragCoord.xy) % 4;
+    float threshold = BAYER[p.y * 4 + p.x] / 16.0;
+    if (1.0 - v2f.Blend0 > threshold || v2f.Fade0 <= threshold) {
+        discard;
+    }
+
+    vec4 albedo = texture(gAlbedo, v2f.TexCoord0);
+    if (albedo.a < 0.5) {
+        discard;
+    }
+
+    // 烘焙的法线在模型空间, 按实例的朝向旋转
+    vec3 n = texture(gNormal, v2f.TexCoord0).xyz * 2.0 - 1.0;
+    float c = v2f.Rotation0.x;
+    float s = v2f.Rotation0.y;
+    vec3 N = vec3(c * n.x + s * n.z, n.y, -s * n.x + c * n.z);
+
+    color = vec4(ApplyFog(CalcLight(albedo.rgb * gColor, N), v2f.WorldPos0), 1.0);
+}
@@ -0,0 +1,63 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+uniform vec3 gViewPos;
+
+// 图集, 第 i 帧的视角在模型空间方向 (sin a, 0, cos a), a = i*2π/gFrames
+uniform int gFrames;
+uniform int gColumns;
+uniform int gRows;
+// 模型空间中四边形底边的中点, 四边形的宽和高
+uniform vec3 gBase;
+uniform vec2 gSize;
+
+// 从网格过渡到替身的距离和淡出距离
+uniform float gImpostorStart;
+uniform float gImpostorEnd;
+uniform float gFadeStart;
+uniform float gFadeEnd;
+
+layout (location = 0) in vec3 position;
+layout (location = 3) in vec2 texCoord;
+layout (location = 8) in mat4 instanceMatrix;
+
+out VsOut {
+    vec3 WorldPos0;
+    vec2 TexCoord0;
+    vec2 Rotation0;// 实例绕 Y 轴旋转的 cos 和 sin, 用于把烘焙的法线转到世界空间
+    float Blend0;// 网格过渡到替身的比例
+    float Fade0;
+} v2f;
+
+const float PI = 3.14159265;
+
+void main() {
+    mat4 world = model * instanceMatrix;
+    vec3 base = (world * vec4(gBase, 1.0)).xyz;
+    float scale = length(world[1].xyz);
+    vec2 axis = normalize(world[0].xz);
+    float yaw = atan(-axis.y, axis.x);
+
+    // 水平方向朝向相机, 相机在正上方时保持上一个方向
+    vec3 toEye = gViewPos - base;
+    vec2 dir = length(toEye.xz) > 0.0001 ? normalize(toEye.xz) : vec2(0.0, 1.0);
+    vec3 right = vec3(dir.y, 0.0, -dir.x);
+    vec3 worldPos = base + right * position.x * gSize.x * scale + vec3(0.0, position.y * gSize.y * scale, 0.0);
+    gl_Position = projection * view * vec4(worldPos, 1.0);
+
+    // 视线方向转到模型空间, 选择最接近的帧
+    float c = cos(yaw);
+    float s = sin(yaw);
+    vec2 local = vec2(c * dir.x - s * dir.y, s * dir.x + c * dir.y);
+    float angle = atan(local.x, local.y);
+    int frame = int(mod(floor(angle / (2.0 * PI) * float(gFrames) + 0.5), float(gFrames)));
+    vec2 cell = vec2(frame % gColumns, frame / gColumns);
+    v2f.TexCoord0 = (cell + texCoord) / vec2(gColumns, gRows);
+
+    float distance = length(toEye);
+    v2f.WorldPos0 = worldPos;
+    v2f.Rotation0 = vec2(c, s);
+    v2f.Blend0 = smoothstep(gImpostorStart, gImpostorEnd, distance);
+    v2f.Fade0 = 1.0 - smoothstep(gFadeStart, gFadeEnd, distance);
+}
@@ -0,0 +1,37 @@
+#version 330
+
+uniform sampler2D texture_diffuse1;
+uniform int gHasTexture;
+uniform int gVertexColor;
+uniform float gAlphaCutoff;
+
+in VsOut {
+    vec3 Normal0;
+    vec3 Color0;
+    vec2 TexCoord0;
+} v2f;
+
+layout (location = 0) out vec4 albedo;
+layout (location = 1) out vec4 normal;
+
+// 只写入反照率, 颜色和光照在绘制替身时计算
+void main() {
+    vec4 c = vec4(1.0);
+    if (gVertexColor != 0) {
+        c.rgb *= v2f.Color0;
+    }
+    if (gHasTexture != 0) {
+        c *= texture(texture_diffuse1, v2f.TexCoord0);
+    }
+    if (c.a < gAlphaCutoff) {
+        discard;
+    }
+
+    vec3 N = normalize(v2f.Normal0);
+    // 双面的叶片, 背面朝向相机时翻转法线
+    if (!gl_FrontFacing) {
+        N = -N;
+    }
+    albedo = vec4(c.rgb, 1.0);
+    normal = vec4(N * 0.5 + 0.5, 1.0);
+}
@@ -0,0 +1,22 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 color;
+layout (location = 2) in vec3 normal;
+layout (location = 3) in vec2 texCoord;
+
+out VsOut {
+    vec3 Normal0;
+    vec3 Color0;
+    vec2 TexCoord0;
+} v2f;
+
+// 在模型空间中烘焙, 法线不做变换
+void main() {
+    gl_Position = projection * view * vec4(position, 1.0);
+    v2f.Normal0 = normal;
+    v2f.Color0 = color;
+    v2f.TexCoord0 = texCoord;
+}
@@ -0,0 +1,305 @@
+#version 330
+// 默认的模型着色器, 定义 NO_SPECULAR 时只有漫反射(Lambert), 定义 BLINN_PHONG 时高光使用半程向量
+
+uniform vec3 gViewPos;
+
+struct Attenuation
+{
+    float Constant;
+    float Linear;
+    float Exp;
+};
+
+struct PointLight {
+    vec3    Color;
+    vec3    Position;
+
+    float   AmbientIntensity;
+    float   DiffuseIntensity;
+    vec3    DiffuseColor;
+    vec3    SpecularColor;
+    Attenuation Atten;
+
+    int     ShadowIndex;// 阴影贴图序号, -1 表示没有阴影
+    float   ShadowFar;
+    float   ShadowBias;
+};
+
+uniform PointLight gLight[8];
+uniform int gLightNum;
+
+// 点光源阴影, 记录到光源的距离除以 ShadowFar
+uniform samplerCube gShadowMap0;
+uniform samplerCube gShadowMap1;
+
+// 材质结构体
+struct Material{
+    vec3 AmbientColor;//环境
+    vec3 DiffuseColor;//漫反射
+    vec3 SpecularColor;//镜面反射
+    float Shininess;//镜面反射光泽
+    float Opacity;//不透明度
+    vec3 EmissiveColor;//自发光, 已乘以强度
+};
+
+uniform Material gMaterial;
+// 自发光贴图, 与 gMaterial.EmissiveColor 相乘
+uniform sampler2D gEmissiveMap;
+uniform int gEmissiveMapEnable;
+// 第一套纹理坐标的变换(偏移, 缩放, 旋转)
+uniform mat3 gUVTransform;
+
+// 雾
+struct Fog {
+    int Enable;
+    int Mode;// 0 线性, 1 指数, 2 指数平方
+    vec3 Color;
+    float Density;
+    float Start;
+    float End;
+    float Height;// 高度雾基准高度
+    float HeightFalloff;// 高于基准高度后的衰减速率, 0 表示不使用高度雾
+};
+
+uniform Fog gFog;
+
+// 漫反射贴图
+uniform sampler2D texture_diffuse1;
+uniform int gDiffuseMapEnable;
+
+// 细节贴图
+uniform sampler2D gDetailAlbedoMap;
+uniform sampler2D gDetailNormalMap;
+uniform int gDetailAlbedoEnable;
+uniform int gDetailNormalEnable;
+uniform float gDetailTiling;
+uniform int gDetailUVSet;
+
+// 序列帧贴图, Frame 和 Next 的 xy 为帧在图集中的偏移, zw 为大小
+uniform sampler2D gFlipbookMap;
+uniform int gFlipbookEnable;
+uniform vec4 gFlipbookFrame;
+uniform vec4 gFlipbookNext;
+uniform float gFlipbookBlend;
+
+// 环境光遮蔽
+uniform sampler2D gAOMap;
+uniform int gAOEnable;
+
+// 光照贴图, 按第二套UV采样, 记录烘焙的间接光照, 替代点光源的环境光项
+uniform sampler2D gLightmap;
+uniform int gLightmapEnable;
+uniform float gLightmapIntensity;
+// 与烘焙时的编码一致: 存储 sqrt(irradiance / LIGHTMAP_RANGE)
+const float LIGHTMAP_RANGE = 4.0;
+
+in VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec2 TexCoord0;
+    vec2 TexCoord1;
+} v2f;
+
+out vec4 color;
+
+float CalcAmbientOcclusion() {
+    if (gAOEnable == 0) {
+        return 1.0;
+    }
+    vec2 uv = gl_FragCoord.xy / vec2(textureSize(gAOMap, 0));
+    return texture(gAOMap, uv).r;
+}
+
+vec2 BaseUV() {
+    return (gUVTransform * vec3(v2f.TexCoord0, 1.0)).xy;
+}
+
+vec2 DetailUV() {
+    vec2 uv = gDetailUVSet == 1 ? v2f.TexCoord1 : BaseUV();
+    return uv * gDetailTiling;
+}
+
+// 细节颜色以 0.5 灰度为中性值, 乘 2 后叠加到漫反射颜色
+vec3 CalcDetailAlbedo(vec3 baseColor) {
+    if (gDetailAlbedoEnable == 0) {
+        return baseColor;
+    }
+    return baseColor * texture(gDetailAlbedoMap, DetailUV()).rgb * 2.0;
+}
+
+// 由屏幕空间导数构造切线空间, 不依赖顶点切线
+vec3 CalcDetailNormal(vec3 N) {
+    if (gDetailNormalEnable == 0) {
+        return N;
+    }
+    vec2 uv = DetailUV();
+    vec3 dp1 = dFdx(v2f.WorldPos0);
+    vec3 dp2 = dFdy(v2f.WorldPos0);
+    vec2 duv1 = dFdx(uv);
+    vec2 duv2 = dFdy(uv);
+
+    vec3 dp2perp = cross(dp2, N);
+    vec3 dp1perp = cross(N, dp1);
+    vec3 T = dp2perp * duv1.x + dp1perp * duv2.x;
+    vec3 B = dp2perp * duv1.y + dp1perp * duv2.y;
+    float invmax = inversesqrt(max(dot(T, T), dot(B, B)));
+    mat3 TBN = mat3(T * invmax, B * invmax, N);
+
+    vec3 detailNormal = texture(gDetailNormalMap, uv).xyz * 2.0 - 1.0;
+    return normalize(TBN * detailNormal);
+}
+
+// 按变换后的第一套UV在当前帧和下一帧之间插值采样
+vec4 CalcFlipbook() {
+    if (gFlipbookEnable == 0) {
+        return vec4(1.0);
+    }
+    vec2 uv = fract(BaseUV());
+    vec4 frame = texture(gFlipbookMap, gFlipbookFrame.xy + uv * gFlipbookFrame.zw);
+    vec4 next = texture(gFlipbookMap, gFlipbookNext.xy + uv * gFlipbookNext.zw);
+    return mix(frame, next, gFlipbookBlend);
+}
+
+vec3 CalcDiffuseColor() {
+    vec3 baseColor = gMaterial.DiffuseColor;
+    if (gDiffuseMapEnable != 0) {
+        baseColor *= texture(texture_diffuse1, BaseUV()).rgb;
+    }
+    baseColor *= CalcFlipbook().rgb;
+    return CalcDetailAlbedo(baseColor);
+}
+
+// 20 个方向的偏移, 对立方体阴影做 PCF
+const vec3 SHADOW_OFFSETS[20] = vec3[](
+    vec3(1, 1, 1), vec3(1, -1, 1), vec3(-1, -1, 1), vec3(-1, 1, 1),
+    vec3(1, 1, -1), vec3(1, -1, -1), vec3(-1, -1, -1), vec3(-1, 1, -1),
+    vec3(1, 1, 0), vec3(1, -1, 0), vec3(-1, -1, 0), vec3(-1, 1, 0),
+    vec3(1, 0, 1), vec3(-1, 0, 1), vec3(1, 0, -1), vec3(-1, 0, -1),
+    vec3(0, 1, 1), vec3(0, -1, 1), vec3(0, -1, -1), vec3(0, 1, -1)
+);
+
+float SampleShadowMap(int Index, vec3 Dir) {
+    if (Index == 0) {
+        return texture(gShadowMap0, Dir).r;
+    }
+    return texture(gShadowMap1, Dir).r;
+}
+
+// CalcShadow 比较到光源的距离和阴影贴图中的最近距离, 1 为完全照亮, 0 为完全在阴影中
+float CalcShadow(PointLight Light, vec3 WorldPos) {
+    if (Light.ShadowIndex < 0) {
+        return 1.0;
+    }
+    vec3 Dir = WorldPos - Light.Position;
+    float Distance = length(Dir);
+    if (Distance >= Light.ShadowFar) {
+        return 1.0;
+    }
+    // 离相机越远采样半径越大, 远处的阴影边缘更柔和
+    float Radius = (1.0 + length(gViewPos - WorldPos) / Light.ShadowFar) * 0.04;
+    float Lit = 0.0;
+    for (int i = 0; i < 20; i++) {
+        float Closest = SampleShadowMap(Light.ShadowIndex, Dir + SHADOW_OFFSETS[i] * Radius) * Light.ShadowFar;
+        Lit += Distance - Light.ShadowBias > Closest ? 0.0 : 1.0;
+    }
+    return Lit / 20.0;
+}
+
+vec4 CalcLightInternal(PointLight Light, vec3 LightDirection, vec3 Normal, float Shadow) {
+    vec4 AmbientColor = vec4(0, 0, 0, 0);
+    if (gLightmapEnable == 0) {
+        AmbientColor = vec4(Light.Color, 1.0f) * vec4(gMaterial.AmbientColor, 1.0) * Light.AmbientIntensity * CalcAmbientOcclusion();
+    }
+    float DiffuseFactor = dot(Normal, -LightDirection);
+
+    vec4 DiffuseColor = vec4(0, 0, 0, 0);
+    vec4 SpecularColor = vec4(0, 0, 0, 0);
+
+    if (DiffuseFactor > 0) {
+        // 漫反射光照
+        DiffuseColor = vec4(Light.Color, 1.0f) * vec4(CalcDiffuseColor(), 1.0) * DiffuseFactor;
+
+#ifndef NO_SPECULAR
+        // 计算眼睛观察方向
+        vec3 VertexToEye = normalize(gViewPos - v2f.WorldPos0);
+#ifdef BLINN_PHONG
+        // 半程向量与法线的夹角
+        float SpecularFactor = dot(Normal, normalize(VertexToEye - LightDirection));
+#else
+        // 计算反射光方向
+        vec3 LightReflect = normalize(reflect(LightDirection, Normal));
+        // 计算反射光与观测方向的夹角
+        float SpecularFactor = dot(VertexToEye, LightReflect);
+#endif
+        // 计算镜面反射强度
+        if (SpecularFactor > 0) {
+            SpecularFactor = pow(SpecularFactor, gMaterial.Shininess);
+            SpecularColor = vec4(Light.Color * gMaterial.SpecularColor * gMaterial.Shininess * SpecularFactor, 1.0f);
+        }
+#endif
+    }
+
+    // 阴影只遮挡漫反射和镜面反射
+    return AmbientColor + (DiffuseColor + SpecularColor) * Shadow;
+}
+
+vec4 CalcPointLight(int Index, vec3 Normal)
+{
+    vec3 LightDirection = v2f.WorldPos0 - gLight[Index].Position;
+    float Distance = length(LightDirection);
+    LightDirection = normalize(LightDirection);
+
+    vec4 Color = CalcLightInternal(gLight[Index], LightDirection, Normal, CalcShadow(gLight[Index], v2f.WorldPos0));
+    float Attenuation = gLight[Index].Atten.Constant + gLight[Index].Atten.Linear * Distance + gLight[Index].Atten.Exp * Distance * Distance;
+
+    return Color / Attenuation;
+}
+
+// CalcLightmap 烘焙的间接光照乘以漫反射颜色
+vec3 CalcLightmap() {
+    if (gLightmapEnable == 0) {
+        return vec3(0.0);
+    }
+    vec3 encoded = texture(gLightmap, v2f.TexCoord1).rgb;
+    return encoded * encoded * LIGHTMAP_RANGE * gLightmapIntensity * CalcDiffuseColor() * CalcAmbientOcclusion();
+}
+
+// CalcEmissive 自发光不受光照和阴影影响, 超过 1 的部分经过 Bloom 产生辉光
+vec3 CalcEmissive() {
+    if (gEmissiveMapEnable == 0) {
+        return gMaterial.EmissiveColor;
+    }
+    return gMaterial.EmissiveColor * texture(gEmissiveMap, BaseUV()).rgb;
+}
+
+// ApplyFog 按到观察点的距离和高度混合雾的颜色
+vec3 ApplyFog(vec3 Color, vec3 WorldPos) {
+    if (gFog.Enable == 0) {
+        return Color;
+    }
+    float Distance = max(length(gViewPos - WorldPos) - gFog.Start, 0.0);
+    float Factor;
+    if (gFog.Mode == 0) {
+        Factor = Distance / max(gFog.End - gFog.Start, 0.0001);
+    } else if (gFog.Mode == 1) {
+        Factor = 1.0 - exp(-gFog.Density * Distance);
+    } else {
+        float d = gFog.Density * Distance;
+        Factor = 1.0 - exp(-d * d);
+    }
+    if (gFog.HeightFalloff > 0.0) {
+        Factor *= exp(-gFog.HeightFalloff * max(WorldPos.y - gFog.Height, 0.0));
+    }
+    return mix(Color, gFog.Color, clamp(Factor, 0.0, 1.0));
+}
+
+void main() {
+    vec3 N = CalcDetailNormal(normalize(v2f.Normal0));
+
+    // 计算多个点光源
+    vec4 pointLightColor = vec4(0, 0, 0, 0);
+    for (int i = 0; i < gLightNum; i++) {
+        pointLightColor += CalcPointLight(i, N);
+    }
+    color = vec4(ApplyFog(pointLightColor.rgb + CalcLightmap() + CalcEmissive(), v2f.WorldPos0), gMaterial.Opacity * CalcFlipbook().a);
+}
\ No newline at end of file
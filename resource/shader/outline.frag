@@ -0,0 +1,9 @@
+#version 330
+
+uniform vec4 gColor;
+
+out vec4 color;
+
+void main() {
+    color = gColor;
+}
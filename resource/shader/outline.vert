@@ -0,0 +1,26 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+// 轮廓的像素宽度, 0 时不外扩
+uniform float gWidth;
+uniform vec2 gScreenSize;
+
+layout (location = 0) in vec3 position;
+layout (location = 2) in vec3 normal;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
+
+void main() {
+    mat4 world = gInstanced ? model * instanceMatrix : model;
+    vec4 clipPos = projection * view * world * vec4(position, 1.0);
+
+    // 法线投影到屏幕上, 按像素宽度外扩, 乘以 w 使宽度不随距离变化
+    vec2 direction = (projection * view * world * vec4(normal, 0.0)).xy;
+    if (gWidth > 0.0 && length(direction) > 0.0001) {
+        clipPos.xy += normalize(direction) * gWidth * 2.0 / gScreenSize * clipPos.w;
+    }
+    gl_Position = clipPos;
+}
@@ -0,0 +1,12 @@
+#version 330
+uniform vec3 gLightPos;
+uniform float gFar;
+
+in VsOut {
+    vec3 WorldPos0;
+} v2f;
+
+// 深度写入到光源的线性距离, 各个面的值可以直接比较
+void main() {
+    gl_FragDepth = length(v2f.WorldPos0 - gLightPos) / gFar;
+}
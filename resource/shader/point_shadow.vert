@@ -0,0 +1,20 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+layout (location = 0) in vec3 position;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
+
+out VsOut {
+    vec3 WorldPos0;
+} v2f;
+
+void main() {
+    mat4 world = gInstanced ? model * instanceMatrix : model;
+    vec4 worldPos = world * vec4(position, 1.0);
+    v2f.WorldPos0 = worldPos.xyz;
+    gl_Position = projection * view * worldPos;
+}
@@ -0,0 +1,13 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+uniform sampler2D gBloomTexture;
+uniform float gIntensity;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+void main() {
+    vec4 scene = texture(gScreenTexture, Texcoord0);
+    color = vec4(scene.rgb + texture(gBloomTexture, Texcoord0).rgb * gIntensity, scene.a);
+}
@@ -0,0 +1,20 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+// 一个纹素的偏移, 水平或垂直
+uniform vec2 gDirection;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+const float WEIGHTS[5] = float[](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+
+// 可分离的 9 点高斯模糊
+void main() {
+    vec3 c = texture(gScreenTexture, Texcoord0).rgb * WEIGHTS[0];
+    for (int i = 1; i < 5; i++) {
+        c += texture(gScreenTexture, Texcoord0 + gDirection * float(i)).rgb * WEIGHTS[i];
+        c += texture(gScreenTexture, Texcoord0 - gDirection * float(i)).rgb * WEIGHTS[i];
+    }
+    color = vec4(c, 1.0);
+}
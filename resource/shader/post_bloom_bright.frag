@@ -0,0 +1,18 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+uniform float gThreshold;
+uniform float gKnee;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+// 提取亮度超过阈值的部分, 阈值附近使用二次曲线软过渡, 避免辉光边缘突变
+void main() {
+    vec3 c = texture(gScreenTexture, Texcoord0).rgb;
+    float brightness = max(c.r, max(c.g, c.b));
+    float soft = clamp(brightness - gThreshold + gKnee, 0.0, 2.0 * gKnee);
+    soft = soft * soft / (4.0 * gKnee + 0.0001);
+    float contribution = max(soft, brightness - gThreshold) / max(brightness, 0.0001);
+    color = vec4(c * contribution, 1.0);
+}
@@ -0,0 +1,10 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+void main() {
+    color = texture(gScreenTexture, Texcoord0);
+}
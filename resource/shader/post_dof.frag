@@ -0,0 +1,67 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+uniform sampler2D gDepthTexture;
+uniform vec2 gTexelSize;
+uniform float gNear;
+uniform float gFar;
+uniform int gOrthographic;
+uniform float gFocusDistance;
+uniform float gAperture;
+uniform float gMaxBlur;
+uniform int gAutoFocus;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+const int SAMPLES = 48;
+const float GOLDEN_ANGLE = 2.39996323;
+
+// 深度缓冲值转换为观察空间距离
+float LinearDepth(vec2 uv) {
+    float depth = texture(gDepthTexture, uv).r;
+    if (gOrthographic != 0) {
+        return gNear + depth * (gFar - gNear);
+    }
+    float z = depth * 2.0 - 1.0;
+    return 2.0 * gNear * gFar / (gFar + gNear - z * (gFar - gNear));
+}
+
+// 弥散圆半径(像素), 与到焦平面的距离成正比
+float CircleOfConfusion(float depth, float focus) {
+    float coc = gAperture * abs(depth - focus) / max(depth, 0.0001);
+    return clamp(coc, 0.0, 1.0) * gMaxBlur;
+}
+
+void main() {
+    float focus = gFocusDistance;
+    if (gAutoFocus != 0) {
+        focus = LinearDepth(vec2(0.5));
+    }
+
+    float centerDepth = LinearDepth(Texcoord0);
+    float centerCoc = CircleOfConfusion(centerDepth, focus);
+    vec4 centerColor = texture(gScreenTexture, Texcoord0);
+    if (centerCoc < 0.5) {
+        color = centerColor;
+        return;
+    }
+
+    // 黄金角螺旋采样圆盘, 样本的弥散圆需覆盖到当前像素才计入, 避免清晰的前景渗入背景
+    vec3 sum = centerColor.rgb;
+    float weight = 1.0;
+    for (int i = 1; i < SAMPLES; i++) {
+        float r = sqrt(float(i) / float(SAMPLES)) * centerCoc;
+        float theta = float(i) * GOLDEN_ANGLE;
+        vec2 uv = Texcoord0 + vec2(cos(theta), sin(theta)) * r * gTexelSize;
+
+        float sampleDepth = LinearDepth(uv);
+        float sampleCoc = CircleOfConfusion(sampleDepth, focus);
+        // 前景样本总是参与模糊, 背景样本只在自身弥散圆足够大时参与
+        float w = sampleDepth < centerDepth ? 1.0 : smoothstep(r - 1.0, r + 1.0, sampleCoc);
+        sum += texture(gScreenTexture, uv).rgb * w;
+        weight += w;
+    }
+
+    color = vec4(sum / weight, centerColor.a);
+}
@@ -0,0 +1,54 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+#define FXAA_REDUCE_MIN (1.0 / 128.0)
+#define FXAA_REDUCE_MUL (1.0 / 8.0)
+#define FXAA_SPAN_MAX   8.0
+
+void main() {
+    vec2 inverseVP = 1.0 / vec2(textureSize(gScreenTexture, 0));
+
+    vec3 rgbNW = texture(gScreenTexture, Texcoord0 + vec2(-1.0, -1.0) * inverseVP).rgb;
+    vec3 rgbNE = texture(gScreenTexture, Texcoord0 + vec2(+1.0, -1.0) * inverseVP).rgb;
+    vec3 rgbSW = texture(gScreenTexture, Texcoord0 + vec2(-1.0, +1.0) * inverseVP).rgb;
+    vec3 rgbSE = texture(gScreenTexture, Texcoord0 + vec2(+1.0, +1.0) * inverseVP).rgb;
+    vec4 rgbaM = texture(gScreenTexture, Texcoord0);
+    vec3 rgbM = rgbaM.rgb;
+
+    // 亮度
+    vec3 luma = vec3(0.299, 0.587, 0.114);
+    float lumaNW = dot(rgbNW, luma);
+    float lumaNE = dot(rgbNE, luma);
+    float lumaSW = dot(rgbSW, luma);
+    float lumaSE = dot(rgbSE, luma);
+    float lumaM  = dot(rgbM,  luma);
+    float lumaMin = min(lumaM, min(min(lumaNW, lumaNE), min(lumaSW, lumaSE)));
+    float lumaMax = max(lumaM, max(max(lumaNW, lumaNE), max(lumaSW, lumaSE)));
+
+    // 沿边缘方向采样
+    vec2 dir;
+    dir.x = -((lumaNW + lumaNE) - (lumaSW + lumaSE));
+    dir.y =  ((lumaNW + lumaSW) - (lumaNE + lumaSE));
+
+    float dirReduce = max((lumaNW + lumaNE + lumaSW + lumaSE) * (0.25 * FXAA_REDUCE_MUL), FXAA_REDUCE_MIN);
+    float rcpDirMin = 1.0 / (min(abs(dir.x), abs(dir.y)) + dirReduce);
+    dir = min(vec2(FXAA_SPAN_MAX), max(vec2(-FXAA_SPAN_MAX), dir * rcpDirMin)) * inverseVP;
+
+    vec3 rgbA = 0.5 * (
+        texture(gScreenTexture, Texcoord0 + dir * (1.0 / 3.0 - 0.5)).rgb +
+        texture(gScreenTexture, Texcoord0 + dir * (2.0 / 3.0 - 0.5)).rgb);
+    vec3 rgbB = rgbA * 0.5 + 0.25 * (
+        texture(gScreenTexture, Texcoord0 + dir * -0.5).rgb +
+        texture(gScreenTexture, Texcoord0 + dir * 0.5).rgb);
+
+    float lumaB = dot(rgbB, luma);
+    if (lumaB < lumaMin || lumaB > lumaMax) {
+        color = vec4(rgbA, rgbaM.a);
+    } else {
+        color = vec4(rgbB, rgbaM.a);
+    }
+}
@@ -0,0 +1,32 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+uniform sampler2D gVelocityTexture;
+uniform float gIntensity;
+uniform int gSamples;
+uniform float gMaxLength;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+void main() {
+    vec4 sceneColor = texture(gScreenTexture, Texcoord0);
+
+    vec2 velocity = texture(gVelocityTexture, Texcoord0).rg * gIntensity;
+    float len = length(velocity);
+    if (len > gMaxLength) {
+        velocity *= gMaxLength / len;
+    }
+    if (len < 0.0001 || gSamples < 2) {
+        color = sceneColor;
+        return;
+    }
+
+    // 以当前像素为中心沿速度方向前后采样
+    vec3 sum = vec3(0.0);
+    for (int i = 0; i < gSamples; i++) {
+        float t = float(i) / float(gSamples - 1) - 0.5;
+        sum += texture(gScreenTexture, Texcoord0 - velocity * t).rgb;
+    }
+    color = vec4(sum / float(gSamples), sceneColor.a);
+}
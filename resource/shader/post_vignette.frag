@@ -0,0 +1,19 @@
+#version 330
+
+uniform sampler2D gScreenTexture;
+uniform float gRadius;
+uniform float gSoftness;
+uniform float gIntensity;
+
+in vec2 Texcoord0;
+out vec4 color;
+
+void main() {
+    vec4 sceneColor = texture(gScreenTexture, Texcoord0);
+
+    // 到屏幕中心的距离, 0.5 为边缘中点
+    float dist = distance(Texcoord0, vec2(0.5)) * 1.41421356;
+    float vignette = smoothstep(gRadius, gRadius - gSoftness, dist);
+
+    color = vec4(mix(sceneColor.rgb, sceneColor.rgb * vignette, gIntensity), sceneColor.a);
+}
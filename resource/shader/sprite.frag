@@ -0,0 +1,12 @@
+#version 330
+
+uniform sampler2D gTexture;
+
+in vec2 TexCoord0;
+in vec4 Color0;
+
+out vec4 color;
+
+void main() {
+    color = Color0 * texture(gTexture, TexCoord0);
+}
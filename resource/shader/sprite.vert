@@ -0,0 +1,15 @@
+#version 330
+uniform mat4 projection;
+
+layout (location = 0) in vec2 position;
+layout (location = 1) in vec2 texCoord;
+layout (location = 2) in vec4 color;
+
+out vec2 TexCoord0;
+out vec4 Color0;
+
+void main() {
+    gl_Position = projection * vec4(position, 0, 1);
+    TexCoord0 = texCoord;
+    Color0 = color;
+}
@@ -0,0 +1,60 @@
+#version 330
+
+uniform sampler2D gNormal;
+uniform sampler2D gDepth;
+uniform sampler2D gNoise;
+
+uniform vec3 gSamples[64];
+uniform int gKernelSize;
+uniform float gRadius;
+uniform float gBias;
+uniform vec2 gNoiseScale;
+
+uniform mat4 projection;
+uniform mat4 invProjection;
+
+in vec2 Texcoord0;
+out float color;
+
+// 由深度重建观察空间坐标
+vec3 ViewPosFromDepth(vec2 uv) {
+    float depth = texture(gDepth, uv).r;
+    vec4 ndc = vec4(uv * 2.0 - 1.0, depth * 2.0 - 1.0, 1.0);
+    vec4 viewPos = invProjection * ndc;
+    return viewPos.xyz / viewPos.w;
+}
+
+void main() {
+    // 背景不计算遮蔽
+    if (texture(gDepth, Texcoord0).r >= 1.0) {
+        color = 1.0;
+        return;
+    }
+
+    vec3 fragPos = ViewPosFromDepth(Texcoord0);
+    vec3 normal = normalize(texture(gNormal, Texcoord0).xyz);
+    vec3 randomVec = normalize(texture(gNoise, Texcoord0 * gNoiseScale).xyz);
+
+    // Gram-Schmidt 构造切线空间, 将半球采样核旋转到法线方向
+    vec3 tangent = normalize(randomVec - normal * dot(randomVec, normal));
+    vec3 bitangent = cross(normal, tangent);
+    mat3 TBN = mat3(tangent, bitangent, normal);
+
+    float occlusion = 0.0;
+    for (int i = 0; i < gKernelSize; i++) {
+        vec3 samplePos = fragPos + TBN * gSamples[i] * gRadius;
+
+        // 投影到屏幕空间, 取得该位置的深度
+        vec4 offset = projection * vec4(samplePos, 1.0);
+        offset.xyz /= offset.w;
+        offset.xyz = offset.xyz * 0.5 + 0.5;
+
+        float sampleDepth = ViewPosFromDepth(offset.xy).z;
+
+        // 距离过远的遮挡物不计入
+        float rangeCheck = smoothstep(0.0, 1.0, gRadius / abs(fragPos.z - sampleDepth));
+        occlusion += (sampleDepth >= samplePos.z + gBias ? 1.0 : 0.0) * rangeCheck;
+    }
+
+    color = 1.0 - occlusion / float(gKernelSize);
+}
@@ -0,0 +1,10 @@
+#version 330
+layout (location = 0) in vec3 position;
+layout (location = 3) in vec2 texcoord;
+
+out vec2 Texcoord0;
+
+void main() {
+    Texcoord0 = texcoord;
+    gl_Position = vec4(position.xy, 0.0, 1.0);
+}
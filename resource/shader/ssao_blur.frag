@@ -0,0 +1,18 @@
+#version 330
+
+uniform sampler2D gInput;
+
+in vec2 Texcoord0;
+out float color;
+
+void main() {
+    vec2 texelSize = 1.0 / vec2(textureSize(gInput, 0));
+    float result = 0.0;
+    for (int x = -2; x < 2; ++x) {
+        for (int y = -2; y < 2; ++y) {
+            vec2 offset = vec2(float(x), float(y)) * texelSize;
+            result += texture(gInput, Texcoord0 + offset).r;
+        }
+    }
+    color = result / 16.0;
+}
@@ -0,0 +1,11 @@
+#version 330
+
+in VsOut {
+    vec3 ViewNormal0;
+} v2f;
+
+layout (location = 0) out vec3 gNormal;
+
+void main() {
+    gNormal = normalize(v2f.ViewNormal0);
+}
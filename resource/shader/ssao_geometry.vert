@@ -0,0 +1,24 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+layout (location = 0) in vec3 position;
+layout (location = 2) in vec3 normal;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
+
+out VsOut {
+    vec3 ViewNormal0;
+} v2f;
+
+void main() {
+    mat4 world = gInstanced ? model * instanceMatrix : model;
+    mat4 mv_matrix = view * world;
+    // 将法线向量转化到观察坐标系
+    mat3 normalmatrix = mat3(transpose(inverse(mv_matrix)));
+    v2f.ViewNormal0 = normalize(normalmatrix * normal);
+
+    gl_Position = projection * mv_matrix * vec4(position, 1);
+}
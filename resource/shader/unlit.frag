@@ -0,0 +1,9 @@
+#version 330
+
+uniform vec3 gColor;
+
+out vec4 color;
+
+void main() {
+    color = vec4(gColor, 1.0);
+}
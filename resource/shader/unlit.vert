@@ -0,0 +1,14 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+layout (location = 0) in vec3 position;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
+
+void main() {
+    mat4 world = gInstanced ? model * instanceMatrix : model;
+    gl_Position = projection * view * world * vec4(position, 1);
+}
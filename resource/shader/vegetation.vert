@@ -0,0 +1,56 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+uniform vec3 gViewPos;
+
+// 风
+uniform float gTime;
+uniform vec2 gWindDirection;
+uniform float gWindStrength;
+uniform float gWindSpeed;
+uniform float gWindFrequency;// 相位沿风向变化的频率
+uniform float gMeshHeight;// 模型空间的网格高度, 根部不动, 顶部摆动最大
+
+// 淡出距离
+uniform float gFadeStart;
+uniform float gFadeEnd;
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 color;
+layout (location = 2) in vec3 normal;
+layout (location = 3) in vec2 texCoord;
+layout (location = 8) in mat4 instanceMatrix;
+
+out VsOut {
+    vec3 WorldPos0;
+    vec3 Normal0;
+    vec3 Color0;
+    vec2 TexCoord0;
+    float Fade0;
+} v2f;
+
+void main() {
+    mat4 world = model * instanceMatrix;
+    vec3 root = world[3].xyz;
+    vec3 worldPos = (world * vec4(position, 1.0)).xyz;
+
+    // 摆动量随高度平方增加, 相位沿风向传播形成阵风, 叠加按实例位置错开的小幅抖动
+    float h = clamp(position.y / gMeshHeight, 0.0, 1.0);
+    float height = length(world[1].xyz) * gMeshHeight;
+    float phase = dot(root.xz, gWindDirection) * gWindFrequency - gTime * gWindSpeed;
+    float gust = sin(phase) * 0.5 + 0.5;
+    float flutter = sin(gTime * gWindSpeed * 3.0 + root.x * 1.7 + root.z * 2.3) * 0.15;
+    vec2 sway = gWindDirection * (gust + flutter) * gWindStrength * height * h * h;
+    worldPos.xz += sway;
+    // 弯曲时顶部下降, 近似保持长度
+    worldPos.y -= 0.5 * dot(sway, sway) / max(height, 0.0001);
+
+    gl_Position = projection * view * vec4(worldPos, 1.0);
+
+    v2f.WorldPos0 = worldPos;
+    v2f.Normal0 = mat3(world) * normal;
+    v2f.Color0 = color;
+    v2f.TexCoord0 = texCoord;
+    v2f.Fade0 = 1.0 - smoothstep(gFadeStart, gFadeEnd, length(gViewPos - root));
+}
@@ -0,0 +1,15 @@
+#version 330
+
+in VsOut {
+    vec4 CurrPos0;
+    vec4 PrevPos0;
+} v2f;
+
+layout (location = 0) out vec2 gVelocity;
+
+void main() {
+    // 屏幕空间(纹理坐标)速度
+    vec2 curr = v2f.CurrPos0.xy / v2f.CurrPos0.w;
+    vec2 prev = v2f.PrevPos0.xy / v2f.PrevPos0.w;
+    gVelocity = (curr - prev) * 0.5;
+}
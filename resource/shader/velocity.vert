@@ -0,0 +1,30 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+// 上一帧的观察投影矩阵和模型矩阵
+uniform mat4 gPrevViewProjection;
+uniform mat4 gPrevModel;
+uniform bool gHasPrevModel;
+
+layout (location = 0) in vec3 position;
+layout (location = 8) in mat4 instanceMatrix;
+
+uniform bool gInstanced;
+
+out VsOut {
+    vec4 CurrPos0;
+    vec4 PrevPos0;
+} v2f;
+
+void main() {
+    mat4 world = gInstanced ? model * instanceMatrix : model;
+    // 没有记录上一帧变换的对象只计算相机运动
+    mat4 prevModel = gHasPrevModel ? gPrevModel : model;
+    mat4 prevWorld = gInstanced ? prevModel * instanceMatrix : prevModel;
+
+    v2f.CurrPos0 = projection * view * world * vec4(position, 1);
+    v2f.PrevPos0 = gPrevViewProjection * prevWorld * vec4(position, 1);
+    gl_Position = v2f.CurrPos0;
+}
@@ -0,0 +1,168 @@
+#version 330
+
+uniform vec3 gViewPos;
+
+struct Attenuation
+{
+    float Constant;
+    float Linear;
+    float Exp;
+};
+
+struct PointLight {
+    vec3    Color;
+    vec3    Position;
+
+    float   AmbientIntensity;
+    float   DiffuseIntensity;
+    vec3    DiffuseColor;
+    vec3    SpecularColor;
+    Attenuation Atten;
+};
+
+uniform PointLight gLight[8];
+uniform int gLightNum;
+
+// 雾
+struct Fog {
+    int Enable;
+    int Mode;// 0 线性, 1 指数, 2 指数平方
+    vec3 Color;
+    float Density;
+    float Start;
+    float End;
+    float Height;// 高度雾基准高度
+    float HeightFalloff;// 高于基准高度后的衰减速率, 0 表示不使用高度雾
+};
+
+uniform Fog gFog;
+
+// 水的颜色
+uniform vec3 gShallowColor;
+uniform vec3 gDeepColor;
+uniform float gClarity;// 颜色达到深水颜色的深度
+
+// 反射和折射纹理与屏幕对齐
+uniform sampler2D gReflection;
+uniform sampler2D gRefraction;
+uniform sampler2D gRefractionDepth;
+uniform mat4 gRefractionInvProjection;
+uniform int gHasReflection;
+uniform int gHasRefraction;
+uniform vec2 gScreenSize;
+uniform float gDistortion;
+uniform float gReflectionStrength;
+uniform float gReflectionFresnel;// 菲涅尔效果的权重
+
+// 滚动的法线贴图
+uniform sampler2D gNormalMap;
+uniform int gHasNormalMap;
+uniform float gNormalTiling;
+uniform vec2 gNormalScroll;
+uniform float gNormalStrength;
+uniform float gTime;
+
+in VsOut {
+    vec3 WorldPos0;
+    vec3 ViewPos0;
+    vec3 Normal0;
+    vec2 TexCoord0;
+} v2f;
+
+out vec4 color;
+
+// ApplyFog 按到观察点的距离和高度混合雾的颜色
+vec3 ApplyFog(vec3 Color, vec3 WorldPos) {
+    if (gFog.Enable == 0) {
+        return Color;
+    }
+    float Distance = max(length(gViewPos - WorldPos) - gFog.Start, 0.0);
+    float Factor;
+    if (gFog.Mode == 0) {
+        Factor = Distance / max(gFog.End - gFog.Start, 0.0001);
+    } else if (gFog.Mode == 1) {
+        Factor = 1.0 - exp(-gFog.Density * Distance);
+    } else {
+        float d = gFog.Density * Distance;
+        Factor = 1.0 - exp(-d * d);
+    }
+    if (gFog.HeightFalloff > 0.0) {
+        Factor *= exp(-gFog.HeightFalloff * max(WorldPos.y - gFog.Height, 0.0));
+    }
+    return mix(Color, gFog.Color, clamp(Factor, 0.0, 1.0));
+}
+
+// CalcNormal 两层方向不同的滚动法线贴图叠加在波浪法线上
+vec3 CalcNormal() {
+    vec3 N = normalize(v2f.Normal0);
+    if (gHasNormalMap == 0) {
+        return N;
+    }
+    vec2 uv = v2f.TexCoord0 * gNormalTiling;
+    vec3 n0 = texture(gNormalMap, uv + gNormalScroll * gTime).rgb * 2.0 - 1.0;
+    vec3 n1 = texture(gNormalMap, uv * 0.7 - gNormalScroll.yx * gTime).rgb * 2.0 - 1.0;
+    // 切线空间 z 朝上, 对应世界空间 y
+    vec2 detail = (n0.xy + n1.xy) * gNormalStrength;
+    return normalize(N + vec3(detail.x, 0.0, detail.y));
+}
+
+// WaterDepth 折射纹理中水下表面到水面的观察空间距离
+float WaterDepth(vec2 uv) {
+    float depth = texture(gRefractionDepth, uv).r;
+    vec4 ndc = vec4(uv * 2.0 - 1.0, depth * 2.0 - 1.0, 1.0);
+    vec4 viewPos = gRefractionInvProjection * ndc;
+    viewPos /= viewPos.w;
+    return max(length(viewPos.xyz) - length(v2f.ViewPos0), 0.0);
+}
+
+// CalcSpecular 点光源在水面上的高光
+vec3 CalcSpecular(vec3 N, vec3 V) {
+    vec3 specular = vec3(0.0);
+    for (int i = 0; i < gLightNum; i++) {
+        vec3 L = gLight[i].Position - v2f.WorldPos0;
+        float Distance = length(L);
+        L /= Distance;
+        vec3 H = normalize(L + V);
+        float Attenuation = gLight[i].Atten.Constant + gLight[i].Atten.Linear * Distance + gLight[i].Atten.Exp * Distance * Distance;
+        specular += gLight[i].SpecularColor * gLight[i].DiffuseIntensity * pow(max(dot(N, H), 0.0), 256.0) / max(Attenuation, 0.0001);
+    }
+    return specular;
+}
+
+void main() {
+    vec3 N = CalcNormal();
+    vec3 V = normalize(gViewPos - v2f.WorldPos0);
+
+    // Schlick 近似, 掠射角反射更强
+    float cosTheta = clamp(dot(N, V), 0.0, 1.0);
+    float fresnel = 0.02 + 0.98 * pow(1.0 - cosTheta, 5.0);
+    float reflectivity = gReflectionStrength * mix(1.0, fresnel, gReflectionFresnel);
+
+    vec2 screenUV = gl_FragCoord.xy / gScreenSize;
+    vec2 distortion = N.xz * gDistortion;
+
+    vec3 reflection = gFog.Color;
+    if (gHasReflection != 0) {
+        reflection = texture(gReflection, screenUV + distortion).rgb;
+    }
+
+    vec3 waterColor = mix(gShallowColor, gDeepColor, fresnel);
+    float alpha = 1.0;
+    vec3 refraction = waterColor;
+    if (gHasRefraction != 0) {
+        vec2 uv = screenUV + distortion;
+        // 扰动后的采样点在水面以上时退回原坐标, 避免水面上的物体出现在折射中
+        if (WaterDepth(uv) <= 0.0) {
+            uv = screenUV;
+        }
+        float absorption = clamp(WaterDepth(uv) / max(gClarity, 0.0001), 0.0, 1.0);
+        waterColor = mix(gShallowColor, gDeepColor, absorption);
+        refraction = mix(texture(gRefraction, uv).rgb * gShallowColor * 2.0, waterColor, absorption);
+    } else {
+        // 没有折射时按菲涅尔决定透明度
+        alpha = mix(0.6, 1.0, fresnel);
+    }
+
+    vec3 result = mix(refraction, reflection, clamp(reflectivity, 0.0, 1.0)) + CalcSpecular(N, V);
+    color = vec4(ApplyFog(result, v2f.WorldPos0), alpha);
+}
@@ -0,0 +1,64 @@
+#version 330
+uniform mat4 projection;
+uniform mat4 view;
+uniform mat4 model;
+
+// Gerstner 波
+struct Wave {
+    vec2 Direction;
+    float Amplitude;
+    float Wavelength;
+    float Steepness;// 0 为正弦波, 1 为最尖的波峰
+    float Speed;// 相对于深水波速的倍数
+};
+
+uniform Wave gWaves[4];
+uniform int gWaveNum;
+uniform float gTime;
+
+layout (location = 0) in vec3 position;
+layout (location = 3) in vec2 texCoord;
+
+out VsOut {
+    vec3 WorldPos0;
+    vec3 ViewPos0;
+    vec3 Normal0;
+    vec2 TexCoord0;
+} v2f;
+
+const float PI = 3.14159265;
+const float GRAVITY = 9.8;
+
+void main() {
+    vec3 worldPos = (model * vec4(position, 1.0)).xyz;
+    vec2 xz = worldPos.xz;
+
+    // 各波的位移和法线按解析式累加
+    vec3 offset = vec3(0.0);
+    vec3 normal = vec3(0.0, 1.0, 0.0);
+    for (int i = 0; i < gWaveNum; i++) {
+        Wave wave = gWaves[i];
+        float k = 2.0 * PI / wave.Wavelength;
+        float omega = sqrt(GRAVITY * k) * wave.Speed;
+        float q = wave.Steepness / max(k * wave.Amplitude * float(gWaveNum), 0.0001);
+        float phase = k * dot(wave.Direction, xz) - omega * gTime;
+        float c = cos(phase);
+        float s = sin(phase);
+
+        offset.xz += q * wave.Amplitude * wave.Direction * c;
+        offset.y += wave.Amplitude * s;
+
+        float wa = k * wave.Amplitude;
+        normal.xz -= wave.Direction * wa * c;
+        normal.y -= q * wa * s;
+    }
+    worldPos += offset;
+
+    vec4 viewPos = view * vec4(worldPos, 1.0);
+    gl_Position = projection * viewPos;
+
+    v2f.WorldPos0 = worldPos;
+    v2f.ViewPos0 = viewPos.xyz;
+    v2f.Normal0 = normalize(normal);
+    v2f.TexCoord0 = texCoord;
+}